@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Rough throughput figures used to estimate how long an ALTER will take.
+// Real numbers depend heavily on hardware and load; the estimate is only
+// meant to tell seconds from hours.
+const (
+	inplaceBytesPerSecond = 80 << 20
+	copyBytesPerSecond    = 30 << 20
+)
+
+type AddColumnParams struct {
+	Database  string     `json:"database"`
+	Table     string     `json:"table"`
+	Column    ColumnSpec `json:"column"`
+	After     string     `json:"after,omitempty"`
+	First     bool       `json:"first,omitempty"`
+	Algorithm string     `json:"algorithm,omitempty"`
+	Lock      string     `json:"lock,omitempty"`
+	Confirm   bool       `json:"confirm,omitempty"`
+}
+
+type AddIndexParams struct {
+	Database  string    `json:"database"`
+	Table     string    `json:"table"`
+	Index     IndexSpec `json:"index"`
+	Algorithm string    `json:"algorithm,omitempty"`
+	Lock      string    `json:"lock,omitempty"`
+	Confirm   bool      `json:"confirm,omitempty"`
+}
+
+type ModifyColumnParams struct {
+	Database  string     `json:"database"`
+	Table     string     `json:"table"`
+	Column    ColumnSpec `json:"column"`
+	Algorithm string     `json:"algorithm,omitempty"`
+	Lock      string     `json:"lock,omitempty"`
+	Confirm   bool       `json:"confirm,omitempty"`
+}
+
+// alterPlan describes how MySQL is expected to execute an ALTER TABLE.
+type alterPlan struct {
+	Algorithm string   `json:"algorithm"`
+	Rebuild   bool     `json:"rebuild"`
+	Blocking  string   `json:"blocking"`
+	Notes     []string `json:"notes,omitempty"`
+}
+
+// tableSize is the storage footprint reported by information_schema.
+type tableSize struct {
+	Rows       int64
+	DataBytes  int64
+	IndexBytes int64
+}
+
+func AddColumn(ctx context.Context, req *mcp.CallToolRequest, args AddColumnParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	def, err := columnDefinition(args.Column)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid column definition: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	clause := "ADD COLUMN " + def
+	switch {
+	case args.First:
+		clause += " FIRST"
+	case args.After != "":
+		clause += " AFTER " + quoteIdentifier(args.After)
+	}
+
+	plan := alterPlan{Algorithm: "INSTANT", Blocking: "none (metadata-only change)"}
+	positioned := args.First || args.After != ""
+	if server := serverInfoFor(ctx, db); !server.instantAddColumn(positioned) {
+		plan = alterPlan{Algorithm: "INPLACE", Rebuild: true, Blocking: "none (concurrent reads and writes allowed, LOCK=NONE)"}
+		plan.Notes = append(plan.Notes, fmt.Sprintf("%s cannot add this column instantly, so the table is rebuilt in place", server))
+	} else if server.TiDB {
+		plan.Notes = append(plan.Notes, "TiDB adds the column as an online schema change; existing rows are not rewritten")
+	} else if server.MariaDB {
+		plan.Notes = append(plan.Notes, "INSTANT ADD COLUMN requires MariaDB 10.3.2+, or 10.4+ at a position other than last")
+	} else if positioned {
+		plan.Notes = append(plan.Notes, "INSTANT ADD COLUMN at a position other than last requires MySQL 8.0.29+; older servers rebuild the table in place")
+	} else {
+		plan.Notes = append(plan.Notes, "INSTANT ADD COLUMN requires MySQL 8.0.12+; 5.7 rebuilds the table in place (concurrent DML allowed)")
+	}
+	if args.Column.AutoIncrement {
+		plan = alterPlan{Algorithm: "COPY", Rebuild: true, Blocking: "writes blocked for the duration (LOCK=SHARED)"}
+		plan.Notes = append(plan.Notes, "Adding an AUTO_INCREMENT column requires a full table copy")
+	}
+
+	return runAlter(ctx, "add_column", args.Database, args.Table, clause, plan, args.Algorithm, args.Lock, args.Confirm,
+		func(cols []ColumnInfo, plan *alterPlan) error {
+			if slices.ContainsFunc(cols, func(c ColumnInfo) bool { return c.ColumnName == args.Column.Name }) {
+				return fmt.Errorf("column %q already exists", args.Column.Name)
+			}
+			if args.Column.AutoIncrement && serverInfoFor(ctx, db).TiDB {
+				return fmt.Errorf("TiDB cannot add an AUTO_INCREMENT column to an existing table")
+			}
+			if args.After != "" && !slices.ContainsFunc(cols, func(c ColumnInfo) bool { return c.ColumnName == args.After }) {
+				return fmt.Errorf("after column %q does not exist", args.After)
+			}
+			return nil
+		})
+}
+
+func AddIndex(ctx context.Context, req *mcp.CallToolRequest, args AddIndexParams) (*mcp.CallToolResult, any, error) {
+	idx := args.Index
+	if len(idx.Columns) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "index.columns is required"},
+			},
+		}, nil, nil
+	}
+
+	kind := "INDEX"
+	plan := alterPlan{Algorithm: "INPLACE", Blocking: "none (concurrent reads and writes allowed, LOCK=NONE)"}
+	plan.Notes = append(plan.Notes, "Building a secondary index scans and sorts the whole table; expect extra I/O and replica lag")
+	switch strings.ToUpper(idx.Kind) {
+	case "", "BTREE":
+		if idx.Unique {
+			kind = "UNIQUE INDEX"
+			plan.Notes = append(plan.Notes, "The build fails if existing rows contain duplicates for the indexed columns")
+		}
+	case "FULLTEXT":
+		kind = "FULLTEXT INDEX"
+		plan.Blocking = "writes blocked for the duration (LOCK=SHARED)"
+		plan.Notes = append(plan.Notes, "The first FULLTEXT index on a table rebuilds the table unless it already has an FTS_DOC_ID column")
+		plan.Rebuild = true
+	case "SPATIAL":
+		kind = "SPATIAL INDEX"
+		plan.Blocking = "writes blocked for the duration (LOCK=SHARED)"
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported index kind %q (use btree, fulltext or spatial)", idx.Kind)},
+			},
+		}, nil, nil
+	}
+
+	clause := "ADD " + kind
+	if idx.Name != "" {
+		if err := checkIdentifier("index", idx.Name); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid index: %v", err)},
+				},
+			}, nil, nil
+		}
+		clause += " " + quoteIdentifier(idx.Name)
+	}
+	clause += " (" + quoteIdentifierList(idx.Columns) + ")"
+
+	return runAlter(ctx, "add_index", args.Database, args.Table, clause, plan, args.Algorithm, args.Lock, args.Confirm,
+		func(cols []ColumnInfo, plan *alterPlan) error {
+			for _, name := range idx.Columns {
+				if !slices.ContainsFunc(cols, func(c ColumnInfo) bool { return c.ColumnName == name }) {
+					return fmt.Errorf("index column %q does not exist", name)
+				}
+			}
+			return nil
+		})
+}
+
+func ModifyColumn(ctx context.Context, req *mcp.CallToolRequest, args ModifyColumnParams) (*mcp.CallToolResult, any, error) {
+	def, err := columnDefinition(args.Column)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid column definition: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	clause := "MODIFY COLUMN " + def
+	plan := alterPlan{Algorithm: "COPY", Rebuild: true, Blocking: "writes blocked for the duration (LOCK=SHARED)"}
+	plan.Notes = append(plan.Notes,
+		"Changing a column's data type copies the whole table",
+		"Only changing the default or extending a VARCHAR within the same length-byte size can run INPLACE/INSTANT; pass algorithm to require that")
+
+	return runAlter(ctx, "modify_column", args.Database, args.Table, clause, plan, args.Algorithm, args.Lock, args.Confirm,
+		func(cols []ColumnInfo, plan *alterPlan) error {
+			i := slices.IndexFunc(cols, func(c ColumnInfo) bool { return c.ColumnName == args.Column.Name })
+			if i < 0 {
+				return fmt.Errorf("column %q does not exist", args.Column.Name)
+			}
+			if strings.EqualFold(cols[i].DataType, strings.SplitN(args.Column.Type, "(", 2)[0]) {
+				plan.Notes = append(plan.Notes, "The base type is unchanged, so MySQL may be able to avoid the copy")
+			}
+			return nil
+		})
+}
+
+// runAlter validates and previews an ALTER TABLE, and executes it only when
+// confirm is set. algorithm and lock, when given, are appended as ALGORITHM=
+// and LOCK= clauses so MySQL refuses to run the change in a more disruptive
+// way than requested.
+func runAlter(ctx context.Context, tool, database, table, clause string, plan alterPlan, algorithm, lock string, confirm bool, validate func([]ColumnInfo, *alterPlan) error) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable(tool); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	cols, err := catalogTable(ctx, db, database, table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	if err := validate(cols, &plan); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	stmt := fmt.Sprintf("ALTER TABLE %s %s", qualifiedTable(database, table), clause)
+	for _, opt := range []struct{ name, value string }{{"ALGORITHM", algorithm}, {"LOCK", lock}} {
+		if opt.value == "" {
+			continue
+		}
+		value := strings.ToUpper(opt.value)
+		valid := []string{"DEFAULT", "INSTANT", "INPLACE", "COPY"}
+		if opt.name == "LOCK" {
+			valid = []string{"DEFAULT", "NONE", "SHARED", "EXCLUSIVE"}
+		}
+		if !slices.Contains(valid, value) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid %s %q (use %s)", strings.ToLower(opt.name), opt.value, strings.Join(valid, ", "))},
+				},
+			}, nil, nil
+		}
+		stmt += fmt.Sprintf(", %s=%s", opt.name, value)
+	}
+
+	size, err := getTableSize(ctx, database, table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read table size: %v", err)},
+			},
+		}, nil, nil
+	}
+	estimate := estimateAlterDuration(plan, size)
+
+	resultText := fmt.Sprintf("%s\n\nTable size: ~%d rows, %s data, %s indexes\n",
+		stmt, size.Rows, formatBytes(size.DataBytes), formatBytes(size.IndexBytes))
+	resultText += fmt.Sprintf("Expected algorithm: %s (table rebuild: %t)\nConcurrency impact: %s\nEstimated duration: %s\n",
+		plan.Algorithm, plan.Rebuild, plan.Blocking, estimate)
+	for _, note := range plan.Notes {
+		resultText += "- " + note + "\n"
+	}
+
+	structured := map[string]any{
+		"statement":         stmt,
+		"plan":              plan,
+		"rows":              size.Rows,
+		"dataBytes":         size.DataBytes,
+		"indexBytes":        size.IndexBytes,
+		"estimatedDuration": estimate,
+	}
+
+	if !confirm {
+		resultText += "\nNothing was changed. Re-run with confirm: true to apply."
+		structured["executed"] = false
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Preview:\n" + resultText},
+			},
+		}, structured, nil
+	}
+
+	start := time.Now()
+	_, err = db.ExecContext(ctx, stmt)
+	audit(tool, stmt, 0, err)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("ALTER TABLE failed: %v\n\n%s", err, stmt)},
+			},
+		}, nil, nil
+	}
+	invalidateTable(database, table)
+
+	elapsed := time.Since(start).Round(time.Millisecond)
+	structured["executed"] = true
+	structured["duration"] = elapsed.String()
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Applied in %s:\n%s", elapsed, resultText)},
+		},
+	}, structured, nil
+}
+
+func getTableSize(ctx context.Context, database, table string) (tableSize, error) {
+	db := currentDB()
+	var size tableSize
+	var rows, data, index *int64
+	err := db.QueryRowContext(ctx, `
+		SELECT TABLE_ROWS, DATA_LENGTH, INDEX_LENGTH
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+	`, database, table).Scan(&rows, &data, &index)
+	if err != nil {
+		return size, err
+	}
+	if rows != nil {
+		size.Rows = *rows
+	}
+	if data != nil {
+		size.DataBytes = *data
+	}
+	if index != nil {
+		size.IndexBytes = *index
+	}
+	return size, nil
+}
+
+// estimateAlterDuration gives an order-of-magnitude duration for plan.
+func estimateAlterDuration(plan alterPlan, size tableSize) string {
+	if plan.Algorithm == "INSTANT" {
+		return "under a second (metadata only)"
+	}
+	rate := int64(inplaceBytesPerSecond)
+	bytes := size.DataBytes
+	if plan.Rebuild {
+		bytes += size.IndexBytes
+	}
+	if plan.Algorithm == "COPY" {
+		rate = copyBytesPerSecond
+	}
+	seconds := bytes / rate
+	if seconds < 1 {
+		return "about a second"
+	}
+	return "roughly " + (time.Duration(seconds) * time.Second).String()
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultAnomalyWindow    = 7
+	defaultAnomalyThreshold = 3.0
+)
+
+type DetectAnomaliesParams struct {
+	Database    string   `json:"database"`
+	Table       string   `json:"table"`
+	TimeColumn  string   `json:"time_column"`
+	ValueColumn string   `json:"value_column,omitempty"`
+	Aggregate   string   `json:"aggregate,omitempty"`
+	Bucket      string   `json:"bucket,omitempty"`
+	Buckets     int      `json:"buckets,omitempty"`
+	Window      int      `json:"window,omitempty"`
+	Seasonal    bool     `json:"seasonal,omitempty"`
+	Threshold   float64  `json:"threshold,omitempty"`
+	Filters     []Filter `json:"filters,omitempty"`
+}
+
+// TimeBucket is the aggregate for one bucket and how it compares with its
+// baseline.
+type TimeBucket struct {
+	Start     string  `json:"start"`
+	Value     float64 `json:"value"`
+	Baseline  float64 `json:"baseline"`
+	StdDev    float64 `json:"stddev"`
+	Score     float64 `json:"score"`
+	Anomalous bool    `json:"anomalous,omitempty"`
+	Partial   bool    `json:"partial,omitempty"`
+}
+
+// bucketSpec describes one bucket size: the SQL truncating a time to its
+// bucket, the Go layout of that value, how to move n buckets and the
+// seasonal period in buckets.
+type bucketSpec struct {
+	expr   string
+	layout string
+	add    func(t time.Time, n int) time.Time
+	period int
+	// season names a bucket's position in its period, and periods the
+	// period itself, for describing seasonal baselines.
+	season, periods string
+	// defaultBuckets is the lookback when buckets is not given.
+	defaultBuckets int
+}
+
+var bucketSpecs = map[string]bucketSpec{
+	"hour": {
+		expr:           "DATE_FORMAT(%s, '%%Y-%%m-%%d %%H:00:00')",
+		layout:         "2006-01-02 15:04:05",
+		add:            func(t time.Time, n int) time.Time { return t.Add(time.Duration(n) * time.Hour) },
+		period:         24,
+		season:         "hour of day",
+		periods:        "days",
+		defaultBuckets: 24 * 7,
+	},
+	"day": {
+		expr:           "DATE_FORMAT(%s, '%%Y-%%m-%%d')",
+		layout:         "2006-01-02",
+		add:            func(t time.Time, n int) time.Time { return t.AddDate(0, 0, n) },
+		period:         7,
+		season:         "weekday",
+		periods:        "weeks",
+		defaultBuckets: 60,
+	},
+	"week": {
+		expr:           "DATE_FORMAT(DATE(%[1]s) - INTERVAL WEEKDAY(%[1]s) DAY, '%%Y-%%m-%%d')",
+		layout:         "2006-01-02",
+		add:            func(t time.Time, n int) time.Time { return t.AddDate(0, 0, 7*n) },
+		defaultBuckets: 26,
+	},
+}
+
+func DetectAnomalies(ctx context.Context, req *mcp.CallToolRequest, args DetectAnomaliesParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if args.TimeColumn == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "time_column is required"},
+			},
+		}, nil, nil
+	}
+	bucket := strings.ToLower(args.Bucket)
+	if bucket == "" {
+		bucket = "day"
+	}
+	spec, ok := bucketSpecs[bucket]
+	if !ok {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported bucket %q (use hour, day or week)", args.Bucket)},
+			},
+		}, nil, nil
+	}
+	aggregate := strings.ToLower(args.Aggregate)
+	if aggregate == "" {
+		aggregate = "count"
+		if args.ValueColumn != "" {
+			aggregate = "sum"
+		}
+	}
+	if aggregate != "count" && aggregate != "sum" && aggregate != "avg" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported aggregate %q (use count, sum or avg)", args.Aggregate)},
+			},
+		}, nil, nil
+	}
+	if aggregate != "count" && args.ValueColumn == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("value_column is required for %s", aggregate)},
+			},
+		}, nil, nil
+	}
+	if args.Seasonal && spec.period == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "seasonal baselines need hour or day buckets"},
+			},
+		}, nil, nil
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	known := make(map[string]bool, len(tableCols))
+	for _, col := range tableCols {
+		known[col.ColumnName] = true
+	}
+	for _, col := range []string{args.TimeColumn, args.ValueColumn} {
+		if col != "" && !known[col] {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Unknown column %q", col)},
+				},
+			}, nil, nil
+		}
+	}
+	where, params := "", []any(nil)
+	if len(args.Filters) > 0 {
+		where, params, err = buildWhere(args.Filters, tableCols)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid filters: %v", err)},
+				},
+			}, nil, nil
+		}
+		where = " AND " + where
+	}
+
+	window := args.Window
+	if window <= 0 {
+		window = defaultAnomalyWindow
+	}
+	threshold := args.Threshold
+	if threshold <= 0 {
+		threshold = defaultAnomalyThreshold
+	}
+	lookback := args.Buckets
+	if lookback <= 0 {
+		lookback = spec.defaultBuckets
+	}
+	// The baseline of the first reported bucket needs history of its own.
+	history := window
+	if args.Seasonal {
+		history = window * spec.period
+	}
+
+	timeCol := quoteIdentifier(args.TimeColumn)
+	bucketExpr := fmt.Sprintf(spec.expr, timeCol)
+	var current string
+	if err := db.QueryRowContext(ctx, "SELECT "+fmt.Sprintf(spec.expr, "NOW()")).Scan(&current); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read the server time: %v", err)},
+			},
+		}, nil, nil
+	}
+	end, err := time.Parse(spec.layout, current)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unexpected bucket value %q: %v", current, err)},
+			},
+		}, nil, nil
+	}
+	first := spec.add(end, -(lookback + history - 1))
+	starts := make([]time.Time, lookback+history)
+	for i := range starts {
+		starts[i] = spec.add(first, i)
+	}
+
+	value := "COUNT(*)"
+	if aggregate != "count" {
+		value = fmt.Sprintf("%s(%s)", strings.ToUpper(aggregate), quoteIdentifier(args.ValueColumn))
+	}
+	query := fmt.Sprintf("SELECT %s AS bucket, %s AS value FROM %s WHERE %s >= ?%s GROUP BY bucket",
+		bucketExpr, value, qualifiedTable(args.Database, args.Table), timeCol, where)
+	rows, err := db.QueryContext(ctx, query, append([]any{starts[0].Format("2006-01-02 15:04:05")}, params...)...)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to aggregate: %v", err)},
+			},
+		}, nil, nil
+	}
+	_, results, err := scanRowMaps(rows)
+	rows.Close()
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read aggregates: %v", err)},
+			},
+		}, nil, nil
+	}
+	values := make(map[string]float64, len(results))
+	for _, r := range results {
+		values[fmt.Sprint(r["bucket"])] = numericValue(r["value"])
+	}
+
+	// Buckets without rows are zero for count and sum; for avg they carry
+	// no information and are skipped in baselines.
+	series := make([]float64, len(starts))
+	present := make([]bool, len(starts))
+	for i, start := range starts {
+		series[i], present[i] = values[start.Format(spec.layout)]
+		if !present[i] && aggregate != "avg" {
+			present[i] = true
+		}
+	}
+
+	var report []TimeBucket
+	var anomalies []TimeBucket
+	for i := history; i < len(starts); i++ {
+		if !present[i] {
+			continue
+		}
+		var baseline []float64
+		for k := 1; k <= window; k++ {
+			j := i - k
+			if args.Seasonal {
+				j = i - k*spec.period
+			}
+			if j >= 0 && present[j] {
+				baseline = append(baseline, series[j])
+			}
+		}
+		b := TimeBucket{Start: starts[i].Format(spec.layout), Value: series[i], Partial: i == len(starts)-1}
+		b.Baseline, b.StdDev = meanStdDev(baseline)
+		// A constant baseline has no spread to measure against; any change
+		// from it is reported.
+		deviates := false
+		switch {
+		case len(baseline) < 2:
+		case b.StdDev > 0:
+			b.Score = (b.Value - b.Baseline) / b.StdDev
+			deviates = math.Abs(b.Score) >= threshold
+		default:
+			deviates = b.Value != b.Baseline
+		}
+		// The current bucket is still filling up, so only unusually high
+		// values count.
+		b.Anomalous = deviates && (!b.Partial || b.Value > b.Baseline)
+		report = append(report, b)
+		if b.Anomalous {
+			anomalies = append(anomalies, b)
+		}
+	}
+
+	label := aggregate + "(*)"
+	if args.ValueColumn != "" {
+		label = fmt.Sprintf("%s(%s)", aggregate, args.ValueColumn)
+	}
+	baselineKind := fmt.Sprintf("previous %d %ss", window, bucket)
+	if args.Seasonal {
+		baselineKind = fmt.Sprintf("same %s in the previous %d %s", spec.season, window, spec.periods)
+	}
+	resultText := fmt.Sprintf("%s of %s.%s per %s by %s, last %d %ss; baseline: %s; threshold %.1f standard deviations\n",
+		label, args.Database, args.Table, bucket, args.TimeColumn, len(report), bucket, baselineKind, threshold)
+	if len(anomalies) == 0 {
+		resultText += "No anomalous buckets.\n"
+	} else {
+		resultText += fmt.Sprintf("%d anomalous bucket(s):\n", len(anomalies))
+		for _, a := range anomalies {
+			resultText += "  " + describeBucket(a) + "\n"
+		}
+	}
+	resultText += "\nRecent buckets:\n"
+	for _, b := range report[max(0, len(report)-10):] {
+		resultText += "  " + describeBucket(b) + "\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"bucket":    bucket,
+		"aggregate": aggregate,
+		"threshold": threshold,
+		"anomalies": anomalies,
+		"buckets":   report,
+	}, nil
+}
+
+func describeBucket(b TimeBucket) string {
+	s := fmt.Sprintf("%s  %g (baseline %.4g ± %.4g", b.Start, b.Value, b.Baseline, b.StdDev)
+	if b.StdDev > 0 {
+		s += fmt.Sprintf(", z=%+.1f)", b.Score)
+	} else {
+		s += ")"
+	}
+	if b.Partial {
+		s += " [in progress]"
+	}
+	if b.Anomalous {
+		s += " <- anomaly"
+	}
+	return s
+}
+
+// meanStdDev returns the mean and population standard deviation of values.
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var squares float64
+	for _, v := range values {
+		squares += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(squares / float64(len(values)))
+}
@@ -0,0 +1,569 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Arrow IPC file writer. Only the subset of the format needed to export
+// query results is implemented: a flat schema of nullable primitive, string,
+// binary and temporal columns written as a sequence of record batches, with
+// no dictionaries or compression. The output is a valid Arrow file (and
+// therefore Feather v2), readable by pyarrow, polars, R arrow and friends.
+
+const (
+	arrowMagic       = "ARROW1"
+	arrowBatchSize   = 65536
+	arrowMetaVersion = 4 // MetadataVersion.V5
+)
+
+// arrowMaxDataLength is the most data a string or binary column holds in
+// one batch: its offsets are signed 32-bit integers.
+var arrowMaxDataLength = math.MaxInt32
+
+type arrowType int
+
+const (
+	arrowUtf8 arrowType = iota
+	arrowBinary
+	arrowInt64
+	arrowUint64
+	arrowFloat64
+	arrowDate32
+	arrowTimestamp
+)
+
+type arrowField struct {
+	Name string
+	Type arrowType
+}
+
+type arrowBlock struct {
+	offset     int64
+	metaLength int32
+	bodyLength int64
+}
+
+// arrowTypeFor maps a MySQL column type to the Arrow type used on export.
+// DECIMAL is kept as a string so no precision is lost.
+func arrowTypeFor(ct *sql.ColumnType) arrowType {
+	switch ct.DatabaseTypeName() {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR",
+		"UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT":
+		return arrowInt64
+	case "UNSIGNED BIGINT":
+		return arrowUint64
+	case "FLOAT", "DOUBLE":
+		return arrowFloat64
+	case "DATE":
+		return arrowDate32
+	case "DATETIME", "TIMESTAMP":
+		return arrowTimestamp
+	case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "GEOMETRY", "BIT":
+		return arrowBinary
+	default:
+		return arrowUtf8
+	}
+}
+
+type arrowColumn struct {
+	typ       arrowType
+	validity  []byte
+	nullCount int
+	offsets   []byte
+	data      []byte
+}
+
+func (c *arrowColumn) reset() {
+	c.validity = c.validity[:0]
+	c.nullCount = 0
+	c.offsets = binary.LittleEndian.AppendUint32(c.offsets[:0], 0)
+	c.data = c.data[:0]
+}
+
+func (c *arrowColumn) appendNull(row int) {
+	if row%8 == 0 {
+		c.validity = append(c.validity, 0)
+	}
+	c.nullCount++
+	switch c.typ {
+	case arrowUtf8, arrowBinary:
+		c.offsets = binary.LittleEndian.AppendUint32(c.offsets, uint32(len(c.data)))
+	case arrowDate32:
+		c.data = binary.LittleEndian.AppendUint32(c.data, 0)
+	default:
+		c.data = binary.LittleEndian.AppendUint64(c.data, 0)
+	}
+}
+
+func (c *arrowColumn) append(row int, val any) error {
+	if val == nil {
+		c.appendNull(row)
+		return nil
+	}
+
+	switch c.typ {
+	case arrowUtf8, arrowBinary:
+		c.data = append(c.data, arrowBytes(val)...)
+		c.offsets = binary.LittleEndian.AppendUint32(c.offsets, uint32(len(c.data)))
+	case arrowInt64:
+		n, err := arrowInt(val)
+		if err != nil {
+			return err
+		}
+		c.data = binary.LittleEndian.AppendUint64(c.data, uint64(n))
+	case arrowUint64:
+		n, err := arrowUint(val)
+		if err != nil {
+			return err
+		}
+		c.data = binary.LittleEndian.AppendUint64(c.data, n)
+	case arrowFloat64:
+		f, err := arrowFloat(val)
+		if err != nil {
+			return err
+		}
+		c.data = binary.LittleEndian.AppendUint64(c.data, math.Float64bits(f))
+	case arrowDate32, arrowTimestamp:
+		t, ok, err := arrowTime(val)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			// Zero dates have no Arrow representation.
+			c.appendNull(row)
+			return nil
+		}
+		if c.typ == arrowDate32 {
+			days := t.Unix() / 86400
+			c.data = binary.LittleEndian.AppendUint32(c.data, uint32(int32(days)))
+		} else {
+			c.data = binary.LittleEndian.AppendUint64(c.data, uint64(t.UnixMicro()))
+		}
+	}
+
+	if row%8 == 0 {
+		c.validity = append(c.validity, 0)
+	}
+	c.validity[row/8] |= 1 << (row % 8)
+	return nil
+}
+
+// arrowBytes returns the bytes a string or binary column stores for val.
+func arrowBytes(val any) []byte {
+	switch v := val.(type) {
+	case []byte:
+		return v
+	case string:
+		return []byte(v)
+	case time.Time:
+		return []byte(v.Format("2006-01-02 15:04:05.999999"))
+	default:
+		return []byte(fmt.Sprint(v))
+	}
+}
+
+func arrowInt(val any) (int64, error) {
+	switch v := val.(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		return int64(v), nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("unexpected integer value %T", val)
+}
+
+func arrowUint(val any) (uint64, error) {
+	switch v := val.(type) {
+	case int64:
+		return uint64(v), nil
+	case uint64:
+		return v, nil
+	case []byte:
+		return strconv.ParseUint(string(v), 10, 64)
+	case string:
+		return strconv.ParseUint(v, 10, 64)
+	}
+	return 0, fmt.Errorf("unexpected unsigned integer value %T", val)
+}
+
+func arrowFloat(val any) (float64, error) {
+	switch v := val.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case []byte:
+		return strconv.ParseFloat(string(v), 64)
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("unexpected float value %T", val)
+}
+
+// arrowTime parses DATE/DATETIME/TIMESTAMP values, which arrive as text
+// unless the DSN sets parseTime=true. ok is false for MySQL zero dates.
+func arrowTime(val any) (t time.Time, ok bool, err error) {
+	var s string
+	switch v := val.(type) {
+	case time.Time:
+		return v, !v.IsZero(), nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return time.Time{}, false, fmt.Errorf("unexpected time value %T", val)
+	}
+
+	if strings.HasPrefix(s, "0000-00-00") {
+		return time.Time{}, false, nil
+	}
+	layout := "2006-01-02 15:04:05.999999"
+	if len(s) == len("2006-01-02") {
+		layout = "2006-01-02"
+	}
+	t, err = time.Parse(layout, s)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t, true, nil
+}
+
+type arrowWriter struct {
+	w       io.Writer
+	offset  int64
+	fields  []arrowField
+	columns []arrowColumn
+	rows    int
+	blocks  []arrowBlock
+}
+
+func newArrowWriter(w io.Writer, fields []arrowField) (*arrowWriter, error) {
+	aw := &arrowWriter{w: w, fields: fields}
+	for _, f := range fields {
+		col := arrowColumn{typ: f.Type}
+		col.reset()
+		aw.columns = append(aw.columns, col)
+	}
+
+	if err := aw.write([]byte(arrowMagic + "\x00\x00")); err != nil {
+		return nil, err
+	}
+	if _, err := aw.writeMessage(1, aw.schema(), nil); err != nil {
+		return nil, err
+	}
+	return aw, nil
+}
+
+// Append adds one row, flushing a record batch once it is full.
+func (aw *arrowWriter) Append(values []any) error {
+	// String and binary columns use 32-bit offsets, so a batch is flushed
+	// early rather than let a column's data grow past them.
+	full := false
+	for i, col := range aw.columns {
+		if values[i] == nil || (col.typ != arrowUtf8 && col.typ != arrowBinary) {
+			continue
+		}
+		n := len(arrowBytes(values[i]))
+		if n > arrowMaxDataLength {
+			return fmt.Errorf("column %q: a value of %d bytes does not fit an Arrow batch", aw.fields[i].Name, n)
+		}
+		if len(col.data)+n > arrowMaxDataLength {
+			full = true
+		}
+	}
+	if full && aw.rows > 0 {
+		if err := aw.flush(); err != nil {
+			return err
+		}
+	}
+
+	for i := range aw.columns {
+		if err := aw.columns[i].append(aw.rows, values[i]); err != nil {
+			return fmt.Errorf("column %q: %w", aw.fields[i].Name, err)
+		}
+	}
+	aw.rows++
+	if aw.rows == arrowBatchSize {
+		return aw.flush()
+	}
+	return nil
+}
+
+// Close flushes the final batch and writes the file footer.
+func (aw *arrowWriter) Close() error {
+	if aw.rows > 0 || len(aw.blocks) == 0 {
+		if err := aw.flush(); err != nil {
+			return err
+		}
+	}
+
+	// End-of-stream marker.
+	if err := aw.write([]byte{0xff, 0xff, 0xff, 0xff, 0, 0, 0, 0}); err != nil {
+		return err
+	}
+
+	var batches []byte
+	for _, b := range aw.blocks {
+		batches = binary.LittleEndian.AppendUint64(batches, uint64(b.offset))
+		batches = binary.LittleEndian.AppendUint32(batches, uint32(b.metaLength))
+		batches = binary.LittleEndian.AppendUint32(batches, 0)
+		batches = binary.LittleEndian.AppendUint64(batches, uint64(b.bodyLength))
+	}
+	footer := encodeFlatbuffer(fbTable{
+		int16(arrowMetaVersion),
+		aw.schema(),
+		fbStructs{},
+		fbStructs{count: len(aw.blocks), data: batches},
+	})
+
+	if err := aw.write(footer); err != nil {
+		return err
+	}
+	return aw.write(append(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))), arrowMagic...))
+}
+
+func (aw *arrowWriter) schema() fbTable {
+	var fields []fbTable
+	for _, f := range aw.fields {
+		var typeID uint8
+		var typ fbTable
+		switch f.Type {
+		case arrowUtf8:
+			typeID, typ = 5, fbTable{}
+		case arrowBinary:
+			typeID, typ = 4, fbTable{}
+		case arrowInt64:
+			typeID, typ = 2, fbTable{int32(64), true}
+		case arrowUint64:
+			typeID, typ = 2, fbTable{int32(64), false}
+		case arrowFloat64:
+			typeID, typ = 3, fbTable{int16(2)}
+		case arrowDate32:
+			typeID, typ = 8, fbTable{int16(0)}
+		case arrowTimestamp:
+			typeID, typ = 10, fbTable{int16(2)}
+		}
+		// name, nullable, type_type, type, dictionary, children
+		fields = append(fields, fbTable{f.Name, true, typeID, typ, nil, []fbTable{}})
+	}
+	// endianness, fields
+	return fbTable{int16(0), fields}
+}
+
+func (aw *arrowWriter) flush() error {
+	var body, nodes, buffers []byte
+	addBuffer := func(b []byte) {
+		buffers = binary.LittleEndian.AppendUint64(buffers, uint64(len(body)))
+		buffers = binary.LittleEndian.AppendUint64(buffers, uint64(len(b)))
+		body = append(body, b...)
+		for len(body)%8 != 0 {
+			body = append(body, 0)
+		}
+	}
+
+	for i := range aw.columns {
+		col := &aw.columns[i]
+		nodes = binary.LittleEndian.AppendUint64(nodes, uint64(aw.rows))
+		nodes = binary.LittleEndian.AppendUint64(nodes, uint64(col.nullCount))
+		addBuffer(col.validity)
+		if col.typ == arrowUtf8 || col.typ == arrowBinary {
+			addBuffer(col.offsets)
+		}
+		addBuffer(col.data)
+	}
+
+	// length, nodes, buffers
+	batch := fbTable{
+		int64(aw.rows),
+		fbStructs{count: len(aw.columns), data: nodes},
+		fbStructs{count: len(buffers) / 16, data: buffers},
+	}
+	block, err := aw.writeMessage(3, batch, body)
+	if err != nil {
+		return err
+	}
+	aw.blocks = append(aw.blocks, block)
+
+	aw.rows = 0
+	for i := range aw.columns {
+		aw.columns[i].reset()
+	}
+	return nil
+}
+
+// writeMessage writes an encapsulated IPC message: continuation marker,
+// metadata length, the flatbuffer Message padded to 8 bytes, then the body.
+func (aw *arrowWriter) writeMessage(headerType uint8, header fbTable, body []byte) (arrowBlock, error) {
+	// version, header_type, header, bodyLength
+	meta := encodeFlatbuffer(fbTable{int16(arrowMetaVersion), headerType, header, int64(len(body))})
+	for len(meta)%8 != 0 {
+		meta = append(meta, 0)
+	}
+
+	block := arrowBlock{offset: aw.offset, metaLength: int32(8 + len(meta)), bodyLength: int64(len(body))}
+	prefix := binary.LittleEndian.AppendUint32([]byte{0xff, 0xff, 0xff, 0xff}, uint32(len(meta)))
+	for _, b := range [][]byte{prefix, meta, body} {
+		if err := aw.write(b); err != nil {
+			return arrowBlock{}, err
+		}
+	}
+	return block, nil
+}
+
+func (aw *arrowWriter) write(b []byte) error {
+	n, err := aw.w.Write(b)
+	aw.offset += int64(n)
+	return err
+}
+
+// Minimal flatbuffer encoder. Tables are written front to back with each
+// vtable placed just before its table and children after their parent, so
+// every uoffset points forward as the format requires.
+
+// fbTable holds field values indexed by field id; nil marks an absent field.
+// Supported values are uint8, bool, int16, int32, int64, string, fbTable,
+// []fbTable and fbStructs.
+type fbTable []any
+
+// fbStructs is a vector of fixed-size structs with 8-byte alignment.
+type fbStructs struct {
+	count int
+	data  []byte
+}
+
+type fbEncoder struct {
+	buf []byte
+}
+
+func encodeFlatbuffer(root fbTable) []byte {
+	e := &fbEncoder{buf: make([]byte, 4)}
+	pos := e.table(root)
+	binary.LittleEndian.PutUint32(e.buf, uint32(pos))
+	return e.buf
+}
+
+func fbInlineSize(v any) int {
+	switch v.(type) {
+	case uint8, bool:
+		return 1
+	case int16:
+		return 2
+	case int64:
+		return 8
+	default:
+		return 4
+	}
+}
+
+func (e *fbEncoder) pad(align int) {
+	for len(e.buf)%align != 0 {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *fbEncoder) table(t fbTable) int {
+	offsets := make([]int, len(t))
+	size := 4
+	for _, width := range []int{8, 4, 2, 1} {
+		for i, v := range t {
+			if v == nil || fbInlineSize(v) != width {
+				continue
+			}
+			for size%width != 0 {
+				size++
+			}
+			offsets[i] = size
+			size += width
+		}
+	}
+
+	e.pad(2)
+	vtable := len(e.buf)
+	e.buf = binary.LittleEndian.AppendUint16(e.buf, uint16(4+2*len(t)))
+	e.buf = binary.LittleEndian.AppendUint16(e.buf, uint16(size))
+	for _, off := range offsets {
+		e.buf = binary.LittleEndian.AppendUint16(e.buf, uint16(off))
+	}
+
+	e.pad(8)
+	start := len(e.buf)
+	e.buf = append(e.buf, make([]byte, size)...)
+	binary.LittleEndian.PutUint32(e.buf[start:], uint32(start-vtable))
+
+	var refs []int
+	for i, v := range t {
+		at := e.buf[start+offsets[i]:]
+		switch v := v.(type) {
+		case nil:
+		case uint8:
+			at[0] = v
+		case bool:
+			if v {
+				at[0] = 1
+			}
+		case int16:
+			binary.LittleEndian.PutUint16(at, uint16(v))
+		case int32:
+			binary.LittleEndian.PutUint32(at, uint32(v))
+		case int64:
+			binary.LittleEndian.PutUint64(at, uint64(v))
+		default:
+			refs = append(refs, i)
+		}
+	}
+
+	for _, i := range refs {
+		slot := start + offsets[i]
+		child := e.ref(t[i])
+		binary.LittleEndian.PutUint32(e.buf[slot:], uint32(child-slot))
+	}
+	return start
+}
+
+func (e *fbEncoder) ref(v any) int {
+	switch v := v.(type) {
+	case string:
+		e.pad(4)
+		pos := len(e.buf)
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(len(v)))
+		e.buf = append(e.buf, v...)
+		e.buf = append(e.buf, 0)
+		return pos
+	case fbTable:
+		return e.table(v)
+	case []fbTable:
+		e.pad(4)
+		pos := len(e.buf)
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(len(v)))
+		e.buf = append(e.buf, make([]byte, 4*len(v))...)
+		for i, t := range v {
+			slot := pos + 4 + 4*i
+			child := e.table(t)
+			binary.LittleEndian.PutUint32(e.buf[slot:], uint32(child-slot))
+		}
+		return pos
+	case fbStructs:
+		for (len(e.buf)+4)%8 != 0 {
+			e.buf = append(e.buf, 0)
+		}
+		pos := len(e.buf)
+		e.buf = binary.LittleEndian.AppendUint32(e.buf, uint32(v.count))
+		e.buf = append(e.buf, v.data...)
+		return pos
+	}
+	panic(fmt.Sprintf("flatbuffer: unsupported value %T", v))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestArrowWriterSplitsLargeColumns(t *testing.T) {
+	saved := arrowMaxDataLength
+	defer func() { arrowMaxDataLength = saved }()
+	arrowMaxDataLength = 10
+
+	var buf bytes.Buffer
+	aw, err := newArrowWriter(&buf, []arrowField{{Name: "s", Type: arrowUtf8}, {Name: "n", Type: arrowInt64}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range [][]any{{"abcd", int64(1)}, {"efgh", int64(2)}, {"ijkl", int64(3)}, {nil, int64(4)}, {"", int64(5)}} {
+		if err := aw.Append(row); err != nil {
+			t.Fatalf("Append(%v) = %v", row, err)
+		}
+	}
+	// 4 + 4 bytes fit a batch; the third value starts a new one.
+	if len(aw.blocks) != 1 || aw.rows != 3 {
+		t.Errorf("%d batches written and %d rows pending, want 1 and 3", len(aw.blocks), aw.rows)
+	}
+	if err := aw.Append([]any{"too long for it", int64(6)}); err == nil || !strings.Contains(err.Error(), `column "s"`) {
+		t.Errorf("Append() of an oversized value = %v", err)
+	}
+	if err := aw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(aw.blocks) != 2 {
+		t.Errorf("%d batches in the file, want 2", len(aw.blocks))
+	}
+	out := buf.Bytes()
+	if !bytes.HasPrefix(out, []byte(arrowMagic)) || !bytes.HasSuffix(out, []byte(arrowMagic)) {
+		t.Error("output is not framed by the Arrow file magic")
+	}
+}
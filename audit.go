@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// readOnly blocks every tool that writes data or changes the schema.
+var readOnly bool
+
+// checkWritable returns an error when the write policy forbids tool.
+func checkWritable(tool string) error {
+	if readOnly {
+		recordPolicyBlock(tool)
+		return fmt.Errorf("%s is disabled: the server is running in read-only mode", tool)
+	}
+	return nil
+}
+
+// checkReadQuery refuses statements other than those that only read data,
+// for queries run repeatedly without review and by tools that run reads in
+// -read-only mode. The statement is tokenized rather than matched on its
+// first word: WITH may precede a DELETE or UPDATE, and a SELECT can still
+// write a file with INTO OUTFILE or lock rows with FOR UPDATE.
+func checkReadQuery(query string) error {
+	if len(splitSQLStatements(query)) != 1 {
+		return errors.New("only a single statement is allowed")
+	}
+	var tokens []sqlToken
+	for _, t := range tokenizeSQL(query) {
+		if t.kind == tokenQuoted && escapedQuote(t.text) {
+			// 'a\' ends the string under NO_BACKSLASH_ESCAPES, so the rest
+			// would be read differently from what was checked.
+			return errors.New(`a backslash before a quote inside a string depends on NO_BACKSLASH_ESCAPES; double the quote ('') instead`)
+		}
+		if t.kind != tokenComment {
+			tokens = append(tokens, t)
+			continue
+		}
+		// The server runs the text of /*! ... */ comments as part of the
+		// statement.
+		if strings.HasPrefix(t.text, "/*!") || strings.HasPrefix(strings.ToUpper(t.text), "/*M!") {
+			return errors.New("executable comments (/*! ... */) are not allowed")
+		}
+	}
+	if err := checkReadTokens(tokens); err != nil {
+		return err
+	}
+	if hasReturningClause(query) || advancesSequence(query) {
+		return errors.New("the query changes data or advances a sequence")
+	}
+	return nil
+}
+
+// queryReadOnly runs a query checkReadQuery admitted in a read-only
+// transaction, so the server refuses any change the tokenizer cannot see,
+// such as one made by a stored function the query calls. done closes the
+// rows and rolls the transaction back.
+func queryReadOnly(ctx context.Context, db *sql.DB, query string, params ...any) (rows *sql.Rows, done func(), err error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err = tx.QueryContext(ctx, query, params...)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	return rows, func() {
+		rows.Close()
+		tx.Rollback()
+	}, nil
+}
+
+// queryRead runs a read built from a query checkReadQuery admitted, such as
+// the statements of summarize and sample: in read-only mode through
+// queryReadOnly, otherwise directly. done closes the rows.
+func queryRead(ctx context.Context, db *sql.DB, query string, params ...any) (rows *sql.Rows, done func(), err error) {
+	if readOnly {
+		return queryReadOnly(ctx, db, query, params...)
+	}
+	rows, err = db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return rows, func() { rows.Close() }, nil
+}
+
+// escapedQuote reports whether a string literal token (with any introducer
+// such as _utf8mb4 or X) escapes its quote character with a backslash.
+func escapedQuote(text string) bool {
+	start := strings.IndexAny(text, `'"`)
+	if start < 0 || text[0] == '`' {
+		return false
+	}
+	quote := text[start]
+	for i := start + 1; i < len(text)-1; i++ {
+		if text[i] == '\\' {
+			if text[i+1] == quote {
+				return true
+			}
+			i++
+		}
+	}
+	return false
+}
+
+// checkReadTokens checks the tokens of one statement, without comments.
+func checkReadTokens(tokens []sqlToken) error {
+	first := 0
+	for first < len(tokens) && tokens[first].text == "(" {
+		first++
+	}
+	if first == len(tokens) || tokens[first].kind != tokenWord {
+		return errReadOnlyQuery
+	}
+	switch strings.ToUpper(tokens[first].text) {
+	case "SELECT", "TABLE", "VALUES":
+		return checkSelectTokens(tokens)
+	case "WITH":
+		if withStatement(tokens[first+1:]) == "SELECT" {
+			return checkSelectTokens(tokens)
+		}
+		return errors.New("WITH is only allowed before a SELECT")
+	case "EXPLAIN", "DESCRIBE", "DESC":
+		// EXPLAIN ANALYZE runs the statement it explains.
+		for i, t := range tokens[first+1:] {
+			if t.kind == tokenWord && strings.EqualFold(t.text, "ANALYZE") {
+				rest := tokens[first+2+i:]
+				if len(rest) >= 3 && strings.EqualFold(rest[0].text, "FORMAT") && rest[1].text == "=" {
+					rest = rest[3:]
+				}
+				return checkReadTokens(rest)
+			}
+		}
+		return nil
+	case "SHOW":
+		return nil
+	}
+	return errReadOnlyQuery
+}
+
+// withStatement returns the keyword of the statement the common table
+// expressions after WITH belong to, in upper case: the first of SELECT,
+// INSERT, REPLACE, UPDATE, DELETE, TABLE and VALUES outside parentheses.
+// CTE names that are keywords must be quoted. It returns "" if there is
+// none.
+func withStatement(tokens []sqlToken) string {
+	depth := 0
+	for _, t := range tokens {
+		switch {
+		case t.text == "(":
+			depth++
+		case t.text == ")":
+			depth--
+		case depth == 0 && t.kind == tokenWord:
+			switch word := strings.ToUpper(t.text); word {
+			case "SELECT", "INSERT", "REPLACE", "UPDATE", "DELETE", "TABLE", "VALUES":
+				return word
+			}
+		}
+	}
+	return ""
+}
+
+// isWithSelect reports whether query is WITH ... SELECT.
+func isWithSelect(query string) bool {
+	var tokens []sqlToken
+	for _, t := range tokenizeSQL(query) {
+		if t.kind != tokenComment {
+			tokens = append(tokens, t)
+		}
+	}
+	return len(tokens) > 0 && strings.EqualFold(tokens[0].text, "WITH") && withStatement(tokens[1:]) == "SELECT"
+}
+
+var errReadOnlyQuery = errors.New("only queries that read data (SELECT, WITH ... SELECT, TABLE, VALUES, SHOW, DESCRIBE, EXPLAIN) are allowed")
+
+// checkResultQuery refuses statements other than a single SELECT, WITH ...
+// SELECT, TABLE or VALUES that passes checkReadQuery: the queries whose
+// result is table data, for tools that write it out.
+func checkResultQuery(query string) error {
+	if err := checkReadQuery(query); err != nil {
+		return err
+	}
+	for _, t := range tokenizeSQL(query) {
+		if t.kind == tokenComment || t.text == "(" {
+			continue
+		}
+		switch strings.ToUpper(t.text) {
+		case "SELECT", "WITH", "TABLE", "VALUES":
+			return nil
+		}
+		break
+	}
+	return errors.New("only SELECT, WITH ... SELECT, TABLE and VALUES statements are allowed")
+}
+
+// checkSelectTokens refuses the clauses that make a SELECT write or lock:
+// INTO (OUTFILE, DUMPFILE or variables), FOR UPDATE, FOR SHARE and LOCK IN
+// SHARE MODE, in the statement or any subquery.
+func checkSelectTokens(tokens []sqlToken) error {
+	for i, t := range tokens {
+		if t.kind != tokenWord {
+			continue
+		}
+		next := ""
+		if i+1 < len(tokens) {
+			next = strings.ToUpper(tokens[i+1].text)
+		}
+		switch word := strings.ToUpper(t.text); {
+		case word == "INTO":
+			return errors.New("SELECT ... INTO (OUTFILE, DUMPFILE or variables) is not allowed")
+		case word == "FOR" && (next == "UPDATE" || next == "SHARE"), word == "LOCK" && next == "IN":
+			return errors.New("locking reads (FOR UPDATE, FOR SHARE, LOCK IN SHARE MODE) are not allowed")
+		}
+	}
+	return nil
+}
+
+// AuditEntry is one line of the audit log.
+type AuditEntry struct {
+	Time          time.Time `json:"time"`
+	Tool          string    `json:"tool"`
+	Statement     string    `json:"statement"`
+	RowsAffected  int64     `json:"rows_affected,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	Client        string    `json:"client,omitempty"`
+	ClientVersion string    `json:"client_version,omitempty"`
+}
+
+// client identifies the MCP client, as named in its initialize request.
+// The server runs over stdio, so there is one client per process.
+var client struct {
+	sync.Mutex
+	name, version string
+}
+
+// clientIdentityMiddleware remembers the client named in the initialize
+// request for the audit log.
+func clientIdentityMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method == "initialize" {
+			if params, ok := req.GetParams().(*mcp.InitializeParams); ok && params.ClientInfo != nil {
+				client.Lock()
+				client.name, client.version = params.ClientInfo.Name, params.ClientInfo.Version
+				client.Unlock()
+				slog.Info("client connected", "client", params.ClientInfo.Name, "version", params.ClientInfo.Version)
+			}
+		}
+		return next(ctx, method, req)
+	}
+}
+
+var auditLog struct {
+	sync.Mutex
+	file *os.File
+}
+
+// openAuditLog starts appending audit entries to path as JSON lines.
+func openAuditLog(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	auditLog.Lock()
+	auditLog.file = f
+	auditLog.Unlock()
+	return nil
+}
+
+// closeAuditLog flushes the audit log to disk and closes it. Later entries
+// are dropped.
+func closeAuditLog() {
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file == nil {
+		return
+	}
+	if err := auditLog.file.Sync(); err != nil {
+		slog.Error("failed to flush audit log", "err", err)
+	}
+	auditLog.file.Close()
+	auditLog.file = nil
+}
+
+// audit records a statement that modified data or schema. It is a no-op
+// unless an audit log was configured.
+func audit(tool, statement string, rowsAffected int64, err error) {
+	client.Lock()
+	clientName, clientVersion := client.name, client.version
+	client.Unlock()
+	slog.Debug("write statement", "tool", tool, "statement", statement, "rows", rowsAffected, "err", err, "client", clientName)
+	if err == nil {
+		recordRowsWritten(rowsAffected)
+	}
+	auditLog.Lock()
+	defer auditLog.Unlock()
+	if auditLog.file == nil {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:          time.Now().UTC(),
+		Tool:          tool,
+		Statement:     statement,
+		RowsAffected:  rowsAffected,
+		Client:        clientName,
+		ClientVersion: clientVersion,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	line, jsonErr := json.Marshal(entry)
+	if jsonErr != nil {
+		return
+	}
+	if _, writeErr := auditLog.file.Write(append(line, '\n')); writeErr != nil {
+		slog.Error("failed to write audit log", "err", writeErr)
+	}
+}
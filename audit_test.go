@@ -0,0 +1,108 @@
+package main
+
+import "testing"
+
+func TestCheckReadQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		ok    bool
+	}{
+		{"SELECT * FROM t", true},
+		{"  select a, b from t where c = 'INTO OUTFILE'", true},
+		{"(SELECT 1) UNION (SELECT 2)", true},
+		{"WITH x AS (SELECT 1 AS a) SELECT a FROM x", true},
+		{"WITH RECURSIVE n (i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 5) SELECT * FROM n", true},
+		{"WITH a AS (SELECT 1), b AS (SELECT 2) SELECT * FROM a, b", true},
+		{"SHOW TABLES", true},
+		{"DESCRIBE t", true},
+		{"EXPLAIN SELECT * FROM t", true},
+		{"EXPLAIN DELETE FROM t", true},
+		{"EXPLAIN ANALYZE SELECT * FROM t", true},
+		{"EXPLAIN ANALYZE FORMAT=TREE SELECT * FROM t", true},
+		{"SELECT * FROM t -- trailing comment", true},
+		{"SELECT * FROM t /* FOR UPDATE */", true},
+		{`SELECT * FROM t WHERE a LIKE 'x\_%' AND b = 'it''s' AND c = 'C:\\'`, true},
+		{"SELECT `it's\\'` FROM t", true},
+		{"SELECT JSON_TABLE('[1]', '$[*]' COLUMNS (n FOR ORDINALITY)) FROM dual", true},
+
+		{"DELETE FROM t", false},
+		{"UPDATE t SET a = 1", false},
+		{"WITH x AS (SELECT 1) DELETE FROM t", false},
+		{"WITH x AS (SELECT 1) UPDATE t JOIN x SET t.a = 1", false},
+		{"WITH x AS (SELECT 1) INSERT INTO t SELECT * FROM x", false},
+		{"WITH `delete` AS (SELECT 1) DELETE FROM t", false},
+		{"SELECT * FROM t INTO OUTFILE '/tmp/x'", false},
+		{"SELECT * INTO DUMPFILE '/tmp/x' FROM t", false},
+		{"SELECT a INTO @v FROM t", false},
+		{"SELECT * FROM t FOR UPDATE", false},
+		{"SELECT * FROM t FOR SHARE", false},
+		{"SELECT * FROM t LOCK IN SHARE MODE", false},
+		{"SELECT * FROM t WHERE a IN (SELECT a FROM u FOR UPDATE)", false},
+		{"WITH x AS (SELECT 1) SELECT * FROM x INTO OUTFILE '/tmp/x'", false},
+		{"SELECT * FROM t /*!50000 INTO OUTFILE '/tmp/x' */", false},
+		{"SELECT * FROM t /*M! FOR UPDATE */", false},
+		{"SELECT 1; DELETE FROM t", false},
+		{"EXPLAIN ANALYZE DELETE FROM t", false},
+		{"EXPLAIN ANALYZE FORMAT=TREE UPDATE t SET a = 1", false},
+		{"ANALYZE TABLE t", false},
+		{"SELECT NEXTVAL(s)", false},
+		{"DELETE FROM t RETURNING *", false},
+		{`SELECT 'a\' INTO OUTFILE '/tmp/x' -- '`, false},
+		{`SELECT "a\" FROM t FOR UPDATE; -- "`, false},
+		{`SELECT _utf8mb4'\'' FROM t`, false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		err := checkReadQuery(tt.query)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkReadQuery(%q) = %v, want ok %v", tt.query, err, tt.ok)
+		}
+	}
+}
+
+func TestIsWithSelect(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"WITH x AS (SELECT 1) SELECT * FROM x", true},
+		{"with recursive n (i) as (select 1 union all select i + 1 from n where i < 3) select * from n", true},
+		{"/* report */ WITH x AS (SELECT 1) SELECT * FROM x", true},
+		{"WITH x AS (SELECT 1) DELETE FROM t", false},
+		{"WITH x AS (SELECT 1) UPDATE t JOIN x SET t.a = 1", false},
+		{"WITH x AS (SELECT 1) INSERT INTO t SELECT * FROM x", false},
+		{"WITH `select` AS (SELECT 1) REPLACE INTO t SELECT * FROM `select`", false},
+		{"SELECT 1", false},
+		{"WITH", false},
+	}
+	for _, tt := range tests {
+		if got := isWithSelect(tt.query); got != tt.want {
+			t.Errorf("isWithSelect(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestCheckResultQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		ok    bool
+	}{
+		{"SELECT * FROM t", true},
+		{"/* report */ (SELECT 1) UNION (SELECT 2)", true},
+		{"WITH x AS (SELECT 1) SELECT * FROM x", true},
+		{"TABLE t ORDER BY a", true},
+		{"VALUES ROW(1, 'a'), ROW(2, 'b')", true},
+		{"TABLE t INTO OUTFILE '/tmp/x'", false},
+		{"SHOW TABLES", false},
+		{"DESCRIBE t", false},
+		{"EXPLAIN SELECT * FROM t", false},
+		{"CALL p()", false},
+		{"DROP TABLE t", false},
+	}
+	for _, tt := range tests {
+		err := checkResultQuery(tt.query)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkResultQuery(%q) = %v, want ok %v", tt.query, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// requireAurora returns an error result for the Aurora-only tools when the
+// server is something else.
+func requireAurora(ctx context.Context, tool string) *mcp.CallToolResult {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}
+	}
+	if server := serverInfoFor(ctx, db); server.AuroraVersion == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("%s needs Amazon Aurora MySQL; the server is %s", tool, server)},
+			},
+		}
+	}
+	return nil
+}
+
+// auroraEndpoint is the parsed DNS name of an Aurora endpoint, e.g.
+// "app.cluster-ro-c9akciq32.eu-west-1.rds.amazonaws.com". Cluster is empty
+// for an instance endpoint, which does not name its cluster.
+type auroraEndpoint struct {
+	Cluster string
+	ID      string
+	Region  string
+	Domain  string
+}
+
+// parseAuroraEndpoint parses the host of an Aurora cluster, reader, custom
+// or instance endpoint.
+func parseAuroraEndpoint(host string) (auroraEndpoint, bool) {
+	labels := strings.Split(strings.ToLower(host), ".")
+	if len(labels) < 5 || labels[3] != "rds" || labels[4] != "amazonaws" {
+		return auroraEndpoint{}, false
+	}
+	e := auroraEndpoint{ID: labels[1], Region: labels[2], Domain: strings.Join(labels[3:], ".")}
+	for _, prefix := range []string{"cluster-ro-", "cluster-custom-", "cluster-"} {
+		if id, ok := strings.CutPrefix(labels[1], prefix); ok {
+			e.Cluster, e.ID = labels[0], id
+			break
+		}
+	}
+	return e, true
+}
+
+// writer returns the cluster endpoint, which always points at the writer.
+func (e auroraEndpoint) writer() string {
+	return fmt.Sprintf("%s.cluster-%s.%s.%s", e.Cluster, e.ID, e.Region, e.Domain)
+}
+
+// reader returns the reader endpoint, which balances across the replicas.
+func (e auroraEndpoint) reader() string {
+	return fmt.Sprintf("%s.cluster-ro-%s.%s.%s", e.Cluster, e.ID, e.Region, e.Domain)
+}
+
+// instance returns the endpoint of a single DB instance.
+func (e auroraEndpoint) instance(serverID string) string {
+	return fmt.Sprintf("%s.%s.%s.%s", strings.ToLower(serverID), e.ID, e.Region, e.Domain)
+}
+
+// connectedAuroraEndpoint parses the host of the default connection.
+func connectedAuroraEndpoint() (auroraEndpoint, bool) {
+	dbConfig := currentDBConfig()
+	if dbConfig == nil {
+		return auroraEndpoint{}, false
+	}
+	host, _, err := net.SplitHostPort(dbConfig.Addr)
+	if err != nil {
+		host = dbConfig.Addr
+	}
+	return parseAuroraEndpoint(host)
+}
+
+// AuroraInstance is a DB instance of the cluster as reported by
+// information_schema.replica_host_status.
+type AuroraInstance struct {
+	ServerID   string `json:"serverId"`
+	Role       string `json:"role"`
+	LagMs      string `json:"lagMs,omitempty"`
+	CPU        string `json:"cpu,omitempty"`
+	LastUpdate string `json:"lastUpdate"`
+	Endpoint   string `json:"endpoint,omitempty"`
+}
+
+func AuroraReplicaStatus(ctx context.Context, req *mcp.CallToolRequest, args struct{}) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if result := requireAurora(ctx, "aurora_replica_status"); result != nil {
+		return result, nil, nil
+	}
+
+	var serverID string
+	var readOnly bool
+	if err := db.QueryRowContext(ctx, "SELECT @@aurora_server_id, @@innodb_read_only").Scan(&serverID, &readOnly); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to identify the connected instance: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	// Every instance reports itself in replica_host_status; rows that have
+	// not been updated for a while belong to instances that are gone.
+	rows, err := db.QueryContext(ctx, `
+		SELECT * FROM information_schema.replica_host_status
+		WHERE LAST_UPDATE_TIMESTAMP > NOW() - INTERVAL 5 MINUTE
+		ORDER BY SESSION_ID = 'MASTER_SESSION_ID' DESC, SERVER_ID`)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read information_schema.replica_host_status: %v", err)},
+			},
+		}, nil, nil
+	}
+	_, results, err := scanRowMaps(rows)
+	rows.Close()
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read information_schema.replica_host_status: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	endpoint, haveEndpoint := connectedAuroraEndpoint()
+	instances := make([]AuroraInstance, 0, len(results))
+	for _, row := range results {
+		instance := AuroraInstance{
+			ServerID:   rowText(row, "SERVER_ID"),
+			Role:       "reader",
+			LagMs:      rowText(row, "REPLICA_LAG_IN_MILLISECONDS"),
+			CPU:        rowText(row, "CPU"),
+			LastUpdate: rowText(row, "LAST_UPDATE_TIMESTAMP"),
+		}
+		if rowText(row, "SESSION_ID") == "MASTER_SESSION_ID" {
+			instance.Role = "writer"
+			instance.LagMs = ""
+		}
+		if haveEndpoint {
+			instance.Endpoint = endpoint.instance(instance.ServerID)
+		}
+		instances = append(instances, instance)
+	}
+
+	role := "the writer"
+	if readOnly {
+		role = "a reader"
+	}
+	resultText := fmt.Sprintf("Connected to instance %s, %s.\n", serverID, role)
+	structured := map[string]any{
+		"connectedInstance": serverID,
+		"connectedReadOnly": readOnly,
+		"instances":         instances,
+	}
+	if haveEndpoint && endpoint.Cluster != "" {
+		resultText += fmt.Sprintf("Cluster %s endpoints:\n  writer: %s\n  reader: %s\n", endpoint.Cluster, endpoint.writer(), endpoint.reader())
+		structured["writerEndpoint"] = endpoint.writer()
+		structured["readerEndpoint"] = endpoint.reader()
+	}
+	resultText += fmt.Sprintf("\nInstances (%d):\n", len(instances))
+	for _, i := range instances {
+		resultText += fmt.Sprintf("  %s  %s", i.ServerID, i.Role)
+		if i.LagMs != "" {
+			resultText += fmt.Sprintf("  lag %s ms", i.LagMs)
+		}
+		if i.CPU != "" {
+			resultText += fmt.Sprintf("  cpu %s%%", i.CPU)
+		}
+		if i.Endpoint != "" {
+			resultText += "  " + i.Endpoint
+		}
+		resultText += "\n"
+	}
+	resultText += "\nAurora replicas read the writer's storage volume instead of replaying a binlog, so SHOW REPLICA STATUS and Seconds_Behind_Source say nothing about them; the lag above is Aurora's own measurement.\n"
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, structured, nil
+}
+
+type AuroraFailoverHistoryParams struct {
+	Cluster string `json:"cluster,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Days    int    `json:"days,omitempty"`
+}
+
+// AuroraEvent is an RDS event of the cluster.
+type AuroraEvent struct {
+	Date    string `json:"date"`
+	Message string `json:"message"`
+}
+
+func AuroraFailoverHistory(ctx context.Context, req *mcp.CallToolRequest, args AuroraFailoverHistoryParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	// The events come from the AWS API, not the MySQL server.
+	if offline {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("aurora_failover_history is unavailable: %v (it calls the AWS API)", errOffline)},
+			},
+		}, nil, nil
+	}
+	if result := requireAurora(ctx, "aurora_failover_history"); result != nil {
+		return result, nil, nil
+	}
+
+	cluster, region := args.Cluster, args.Region
+	if endpoint, ok := connectedAuroraEndpoint(); ok {
+		if cluster == "" {
+			cluster = endpoint.Cluster
+		}
+		if region == "" {
+			region = endpoint.Region
+		}
+	}
+	if cluster == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "cluster is required unless connected through the cluster or reader endpoint"},
+			},
+		}, nil, nil
+	}
+	// RDS keeps events for 14 days.
+	days := min(args.Days, 14)
+	if days <= 0 {
+		days = 14
+	}
+
+	cmdArgs := []string{"rds", "describe-events",
+		"--source-type", "db-cluster",
+		"--source-identifier", cluster,
+		"--event-categories", "failover",
+		"--duration", strconv.Itoa(days * 24 * 60),
+		"--output", "json"}
+	if region != "" {
+		cmdArgs = append(cmdArgs, "--region", region)
+	}
+	output, err := exec.CommandContext(ctx, "aws", cmdArgs...).Output()
+	if err != nil {
+		text := fmt.Sprintf("aws rds describe-events failed: %v", err)
+		var exitErr *exec.ExitError
+		if errors.Is(err, exec.ErrNotFound) {
+			text = "Failover events come from the RDS API; install the AWS CLI (aws) with credentials allowed to call rds:DescribeEvents"
+		} else if errors.As(err, &exitErr) {
+			text += "\n" + strings.TrimSpace(string(exitErr.Stderr))
+		}
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, nil, nil
+	}
+	var response struct {
+		Events []struct {
+			Date    string `json:"Date"`
+			Message string `json:"Message"`
+		} `json:"Events"`
+	}
+	if err := json.Unmarshal(output, &response); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to parse the RDS events: %v", err)},
+			},
+		}, nil, nil
+	}
+	events := make([]AuroraEvent, len(response.Events))
+	for i, e := range response.Events {
+		events[i] = AuroraEvent{Date: e.Date, Message: e.Message}
+	}
+
+	resultText := fmt.Sprintf("Failover events of cluster %s in the last %d days: %d\n", cluster, days, len(events))
+	for _, e := range events {
+		resultText += fmt.Sprintf("  %s  %s\n", e.Date, e.Message)
+	}
+	var uptime int64
+	if err := db.QueryRowContext(ctx, "SELECT VARIABLE_VALUE FROM performance_schema.global_status WHERE VARIABLE_NAME = 'Uptime'").Scan(&uptime); err == nil {
+		started := time.Now().Add(-time.Duration(uptime) * time.Second).UTC().Format(time.RFC3339)
+		resultText += fmt.Sprintf("The connected instance has been up since %s.\n", started)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"cluster": cluster,
+		"events":  events,
+	}, nil
+}
+
+type AuroraDigestExportParams struct {
+	Path   string `json:"path"`
+	Format string `json:"format,omitempty"`
+	Schema string `json:"schema,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+	// Overwrite replaces an existing file at Path instead of refusing.
+	Overwrite bool `json:"overwrite,omitempty"`
+}
+
+func AuroraDigestExport(ctx context.Context, req *mcp.CallToolRequest, args AuroraDigestExportParams) (*mcp.CallToolResult, any, error) {
+	if result := requireAurora(ctx, "aurora_digest_export"); result != nil {
+		return result, nil, nil
+	}
+	if args.Path == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "path is required"},
+			},
+		}, nil, nil
+	}
+	format := strings.ToLower(args.Format)
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "jsonl" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported export format %q (use csv or jsonl)", args.Format)},
+			},
+		}, nil, nil
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 500
+	}
+	path, err := filepath.Abs(args.Path)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid export path: %v", err)},
+			},
+		}, nil, nil
+	}
+	if result := refuseExistingFile(path, args.Overwrite); result != nil {
+		return result, nil, nil
+	}
+
+	// Performance Insights is per instance and tracks statements by the
+	// same digest, so each row carries the instance and digest to join on.
+	// Timers are in picoseconds.
+	query := `
+		SELECT @@aurora_server_id AS instance, SCHEMA_NAME AS schema_name, DIGEST AS digest, DIGEST_TEXT AS digest_text,
+			COUNT_STAR AS calls,
+			ROUND(SUM_TIMER_WAIT / 1e9, 3) AS total_latency_ms,
+			ROUND(AVG_TIMER_WAIT / 1e9, 3) AS avg_latency_ms,
+			ROUND(SUM_LOCK_TIME / 1e9, 3) AS lock_time_ms,
+			SUM_ROWS_EXAMINED AS rows_examined, SUM_ROWS_SENT AS rows_sent, SUM_ROWS_AFFECTED AS rows_affected,
+			SUM_NO_INDEX_USED AS no_index_used, SUM_CREATED_TMP_DISK_TABLES AS tmp_disk_tables,
+			FIRST_SEEN AS first_seen, LAST_SEEN AS last_seen
+		FROM performance_schema.events_statements_summary_by_digest
+		WHERE DIGEST IS NOT NULL`
+	if args.Schema != "" {
+		query += " AND SCHEMA_NAME = " + quoteString(args.Schema)
+	}
+	query += fmt.Sprintf(" ORDER BY SUM_TIMER_WAIT DESC LIMIT %d", limit)
+
+	stats, err := exportQuery(ctx, currentDB(), query, path, format, "", args.Overwrite)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Digest export failed: %v (Performance Insights and performance_schema must be enabled on the instance)", err)},
+			},
+		}, nil, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Exported the top %d statement digests by total latency to %s (%s)", stats.Rows, path, format)},
+		},
+	}, map[string]any{
+		"path":     path,
+		"format":   format,
+		"rowCount": stats.Rows,
+		"columns":  stats.Columns,
+	}, nil
+}
@@ -0,0 +1,1147 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Binlog replication client. Only what change data capture needs is
+// implemented: logging in over the classic protocol (mysql_native_password
+// and caching_sha2_password, optionally over TLS), requesting a binlog dump
+// by file and position, and decoding row events of the ROW binlog format
+// into column values. database/sql has no way to issue COM_BINLOG_DUMP, so
+// the client speaks the protocol itself on its own connection.
+//
+// The replication libraries for Go bring their own MySQL driver, SQL
+// parser and logging along with the binlog reader, and would be the
+// server's largest dependency for one tool. The login reuses the
+// driver's *mysql.Config, so the stream connects with the same DSN,
+// credentials and TLS settings as the pool. binlog_test.go covers the
+// login and event decoding, and a live server when MYSQL_MCP_TEST_DSN is
+// set.
+
+const (
+	clientLongPassword     = 0x00000001
+	clientLongFlag         = 0x00000004
+	clientProtocol41       = 0x00000200
+	clientSSL              = 0x00000800
+	clientTransactions     = 0x00002000
+	clientSecureConnection = 0x00008000
+	clientPluginAuth       = 0x00080000
+
+	comQuery      = 0x03
+	comBinlogDump = 0x12
+
+	maxPacketSize = 1<<24 - 1
+)
+
+// Binlog event types.
+const (
+	binlogRotateEvent             = 4
+	binlogFormatDescriptionEvent  = 15
+	binlogXIDEvent                = 16
+	binlogTableMapEvent           = 19
+	binlogWriteRowsEventV1        = 23
+	binlogUpdateRowsEventV1       = 24
+	binlogDeleteRowsEventV1       = 25
+	binlogWriteRowsEvent          = 30
+	binlogUpdateRowsEvent         = 31
+	binlogDeleteRowsEvent         = 32
+	binlogPartialUpdateRowsEvent  = 39
+	binlogTransactionPayloadEvent = 40
+)
+
+// Column types as they appear in table map events.
+const (
+	fieldTypeDecimal    = 0
+	fieldTypeTiny       = 1
+	fieldTypeShort      = 2
+	fieldTypeLong       = 3
+	fieldTypeFloat      = 4
+	fieldTypeDouble     = 5
+	fieldTypeNull       = 6
+	fieldTypeTimestamp  = 7
+	fieldTypeLongLong   = 8
+	fieldTypeInt24      = 9
+	fieldTypeDate       = 10
+	fieldTypeTime       = 11
+	fieldTypeDateTime   = 12
+	fieldTypeYear       = 13
+	fieldTypeVarChar    = 15
+	fieldTypeBit        = 16
+	fieldTypeTimestamp2 = 17
+	fieldTypeDateTime2  = 18
+	fieldTypeTime2      = 19
+	fieldTypeVector     = 242
+	fieldTypeJSON       = 245
+	fieldTypeNewDecimal = 246
+	fieldTypeEnum       = 247
+	fieldTypeSet        = 248
+	fieldTypeTinyBlob   = 249
+	fieldTypeMediumBlob = 250
+	fieldTypeLongBlob   = 251
+	fieldTypeBlob       = 252
+	fieldTypeVarString  = 253
+	fieldTypeString     = 254
+	fieldTypeGeometry   = 255
+)
+
+// binlogConn is a replication connection to the server.
+type binlogConn struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	sequence byte
+	checksum bool
+	// file and position are where a dump can resume without splitting a
+	// transaction: the last rotation or commit seen.
+	file     string
+	position uint32
+	// postHeaderLengths comes from the format description event, indexed
+	// by event type - 1.
+	postHeaderLengths []byte
+	tables            map[uint64]*binlogTable
+}
+
+// binlogTable is the table a table map event assigns an id to.
+type binlogTable struct {
+	Database string
+	Table    string
+	types    []byte
+	meta     []uint16
+}
+
+// binlogRowsEvent is a decoded write, update or delete rows event. For
+// updates Rows holds before and after images alternately.
+type binlogRowsEvent struct {
+	Table     *binlogTable
+	Kind      string
+	Rows      [][]any
+	Timestamp time.Time
+	Position  uint32
+}
+
+// dialBinlog connects and logs in with the credentials of cfg.
+func dialBinlog(cfg *mysql.Config) (*binlogConn, error) {
+	network := cfg.Net
+	if network == "" {
+		network = "tcp"
+	}
+	conn, err := net.DialTimeout(network, cfg.Addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	c := &binlogConn{conn: conn, reader: bufio.NewReader(conn), tables: make(map[uint64]*binlogTable)}
+	if err := c.handshake(cfg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *binlogConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *binlogConn) readPacket() ([]byte, error) {
+	var payload []byte
+	for {
+		var header [4]byte
+		if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+			return nil, err
+		}
+		length := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+		c.sequence = header[3] + 1
+		chunk := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, chunk); err != nil {
+			return nil, err
+		}
+		payload = append(payload, chunk...)
+		if length < maxPacketSize {
+			return payload, nil
+		}
+	}
+}
+
+func (c *binlogConn) writePacket(payload []byte) error {
+	for {
+		n := min(len(payload), maxPacketSize)
+		packet := make([]byte, 4, 4+n)
+		packet[0], packet[1], packet[2] = byte(n), byte(n>>8), byte(n>>16)
+		packet[3] = c.sequence
+		c.sequence++
+		if _, err := c.conn.Write(append(packet, payload[:n]...)); err != nil {
+			return err
+		}
+		payload = payload[n:]
+		if n < maxPacketSize {
+			return nil
+		}
+	}
+}
+
+// serverError turns an ERR packet into an error.
+func serverError(packet []byte) error {
+	if len(packet) < 3 || packet[0] != 0xff {
+		return errors.New("malformed error packet")
+	}
+	code := binary.LittleEndian.Uint16(packet[1:3])
+	message := packet[3:]
+	if len(message) > 6 && message[0] == '#' {
+		message = message[6:]
+	}
+	return &mysql.MySQLError{Number: code, Message: string(message)}
+}
+
+// serverGreeting is what the initial handshake packet tells the client.
+type serverGreeting struct {
+	capabilities uint32
+	scramble     []byte
+	plugin       string
+}
+
+// parseGreeting parses the server's initial handshake packet (protocol
+// version 10).
+func parseGreeting(packet []byte) (serverGreeting, error) {
+	malformed := errors.New("malformed handshake packet")
+	if len(packet) == 0 {
+		return serverGreeting{}, malformed
+	}
+	if packet[0] == 0xff {
+		return serverGreeting{}, serverError(packet)
+	}
+	if packet[0] != 10 {
+		return serverGreeting{}, fmt.Errorf("unsupported protocol version %d", packet[0])
+	}
+	// Server version, connection id, the first eight bytes of the
+	// scramble, a filler byte and the lower capabilities.
+	end := bytes.IndexByte(packet[1:], 0)
+	if end < 0 {
+		return serverGreeting{}, malformed
+	}
+	pos := 1 + end + 1 + 4
+	if pos+8+1+2 > len(packet) {
+		return serverGreeting{}, malformed
+	}
+	g := serverGreeting{
+		scramble: append([]byte(nil), packet[pos:pos+8]...),
+		plugin:   "mysql_native_password",
+	}
+	pos += 8 + 1
+	g.capabilities = uint32(binary.LittleEndian.Uint16(packet[pos:]))
+	pos += 2
+	if pos == len(packet) {
+		return g, nil
+	}
+	// Character set, status flags, upper capabilities, scramble length
+	// and ten reserved bytes.
+	if pos+16 > len(packet) {
+		return serverGreeting{}, malformed
+	}
+	g.capabilities |= uint32(binary.LittleEndian.Uint16(packet[pos+3:])) << 16
+	scrambleLength := int(packet[pos+5])
+	pos += 16
+	// The rest of the scramble, NUL-terminated.
+	if n := max(13, scrambleLength-8); pos+n <= len(packet) {
+		g.scramble = append(g.scramble, packet[pos:pos+n-1]...)
+		pos += n
+	} else {
+		return serverGreeting{}, malformed
+	}
+	if end := bytes.IndexByte(packet[pos:], 0); end > 0 {
+		g.plugin = string(packet[pos : pos+end])
+	} else if end < 0 && pos < len(packet) {
+		g.plugin = string(packet[pos:])
+	}
+	return g, nil
+}
+
+func (c *binlogConn) handshake(cfg *mysql.Config) error {
+	packet, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	greeting, err := parseGreeting(packet)
+	if err != nil {
+		return err
+	}
+	capabilities, scramble, plugin := greeting.capabilities, greeting.scramble, greeting.plugin
+
+	flags := uint32(clientLongPassword | clientLongFlag | clientProtocol41 | clientTransactions | clientSecureConnection | clientPluginAuth)
+	secure := false
+	// tls=preferred sets AllowFallbackToPlaintext: like the driver's own
+	// connections, the dump then goes unencrypted to a server without TLS.
+	if cfg.TLS != nil && capabilities&clientSSL == 0 && !cfg.AllowFallbackToPlaintext {
+		return errors.New("TLS is configured but the server does not support it")
+	}
+	if cfg.TLS != nil && capabilities&clientSSL != 0 {
+		flags |= clientSSL
+		request := make([]byte, 32)
+		binary.LittleEndian.PutUint32(request, flags)
+		request[8] = 45 // utf8mb4_general_ci
+		if err := c.writePacket(request); err != nil {
+			return err
+		}
+		tlsConn := tls.Client(c.conn, cfg.TLS)
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		c.conn = tlsConn
+		c.reader = bufio.NewReader(tlsConn)
+		secure = true
+	}
+
+	authResponse, err := authScramble(plugin, cfg.Passwd, scramble)
+	if err != nil {
+		return err
+	}
+	response := make([]byte, 32, 64+len(cfg.User)+len(authResponse)+len(plugin))
+	binary.LittleEndian.PutUint32(response, flags)
+	response[8] = 45
+	response = append(response, cfg.User...)
+	response = append(response, 0, byte(len(authResponse)))
+	response = append(response, authResponse...)
+	response = append(response, plugin...)
+	response = append(response, 0)
+	if err := c.writePacket(response); err != nil {
+		return err
+	}
+	return c.authenticate(cfg.Passwd, plugin, scramble, secure)
+}
+
+// authScramble computes the first authentication response for plugin.
+func authScramble(plugin, password string, scramble []byte) ([]byte, error) {
+	if password == "" {
+		return nil, nil
+	}
+	if len(scramble) < 20 {
+		return nil, errors.New("the server sent a short scramble")
+	}
+	switch plugin {
+	case "mysql_native_password":
+		// SHA1(password) XOR SHA1(scramble + SHA1(SHA1(password)))
+		stage1 := sha1.Sum([]byte(password))
+		stage2 := sha1.Sum(stage1[:])
+		h := sha1.New()
+		h.Write(scramble[:20])
+		h.Write(stage2[:])
+		result := h.Sum(nil)
+		for i := range result {
+			result[i] ^= stage1[i]
+		}
+		return result, nil
+	case "caching_sha2_password":
+		// SHA256(password) XOR SHA256(SHA256(SHA256(password)) + scramble)
+		stage1 := sha256.Sum256([]byte(password))
+		stage2 := sha256.Sum256(stage1[:])
+		h := sha256.New()
+		h.Write(stage2[:])
+		h.Write(scramble[:20])
+		result := h.Sum(nil)
+		for i := range result {
+			result[i] ^= stage1[i]
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("unsupported authentication plugin %s", plugin)
+	}
+}
+
+// authenticate follows the server's responses to the handshake until it
+// accepts or rejects the login.
+func (c *binlogConn) authenticate(password, plugin string, scramble []byte, secure bool) error {
+	for {
+		packet, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		switch {
+		case len(packet) == 0:
+			return errors.New("empty authentication response")
+		case packet[0] == 0x00:
+			return nil
+		case packet[0] == 0xff:
+			return serverError(packet)
+		case packet[0] == 0xfe:
+			// Auth switch request: plugin name, then a new scramble.
+			end := bytes.IndexByte(packet[1:], 0)
+			if end < 0 {
+				return errors.New("malformed auth switch request")
+			}
+			plugin = string(packet[1 : 1+end])
+			scramble = bytes.TrimSuffix(packet[2+end:], []byte{0})
+			response, err := authScramble(plugin, password, scramble)
+			if err != nil {
+				return err
+			}
+			if err := c.writePacket(response); err != nil {
+				return err
+			}
+		case packet[0] == 0x01 && plugin == "caching_sha2_password" && len(packet) == 2:
+			switch packet[1] {
+			case 3: // fast authentication succeeded, OK follows
+			case 4: // full authentication
+				if secure {
+					if err := c.writePacket(append([]byte(password), 0)); err != nil {
+						return err
+					}
+					continue
+				}
+				if err := c.writePacket([]byte{2}); err != nil {
+					return err
+				}
+				keyPacket, err := c.readPacket()
+				if err != nil {
+					return err
+				}
+				if len(keyPacket) == 0 || keyPacket[0] != 0x01 {
+					return errors.New("the server did not send its public key")
+				}
+				encrypted, err := encryptPassword(password, scramble, keyPacket[1:])
+				if err != nil {
+					return err
+				}
+				if err := c.writePacket(encrypted); err != nil {
+					return err
+				}
+			default:
+				return fmt.Errorf("unexpected caching_sha2_password state %d", packet[1])
+			}
+		default:
+			return fmt.Errorf("unexpected authentication packet 0x%02x", packet[0])
+		}
+	}
+}
+
+// encryptPassword encrypts the password for caching_sha2_password full
+// authentication over an unencrypted connection.
+func encryptPassword(password string, scramble, pemKey []byte) ([]byte, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("malformed server public key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("the server public key is not an RSA key")
+	}
+	plain := append([]byte(password), 0)
+	for i := range plain {
+		plain[i] ^= scramble[i%len(scramble)]
+	}
+	return rsa.EncryptOAEP(sha1.New(), rand.Reader, rsaKey, plain, nil)
+}
+
+// exec runs a statement that returns no rows.
+func (c *binlogConn) exec(statement string) error {
+	c.sequence = 0
+	if err := c.writePacket(append([]byte{comQuery}, statement...)); err != nil {
+		return err
+	}
+	packet, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	if len(packet) == 0 {
+		return errors.New("empty response packet")
+	}
+	if packet[0] == 0xff {
+		return serverError(packet)
+	}
+	if packet[0] != 0x00 {
+		return fmt.Errorf("%s returned rows", firstWord(statement))
+	}
+	return nil
+}
+
+// startDump asks the server to stream the binlog from file and position,
+// as a replica with serverID. checksum tells whether the server appends
+// CRC32 checksums to events (binlog_checksum).
+func (c *binlogConn) startDump(file string, position uint32, serverID uint32, checksum bool) error {
+	c.checksum = checksum
+	c.file, c.position = file, position
+	if checksum {
+		// A client that does not announce it understands checksums is
+		// refused by servers that write them.
+		if err := c.exec("SET @master_binlog_checksum = @@global.binlog_checksum, @source_binlog_checksum = @@global.binlog_checksum"); err != nil {
+			return err
+		}
+	}
+	command := make([]byte, 11, 11+len(file))
+	command[0] = comBinlogDump
+	binary.LittleEndian.PutUint32(command[1:], position)
+	binary.LittleEndian.PutUint32(command[7:], serverID)
+	command = append(command, file...)
+	c.sequence = 0
+	return c.writePacket(command)
+}
+
+// readEvent returns the next row event, following rotations, commits and
+// table maps on the way.
+func (c *binlogConn) readEvent() (*binlogRowsEvent, error) {
+	for {
+		packet, err := c.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case len(packet) == 0:
+			return nil, errors.New("empty binlog packet")
+		case packet[0] == 0xff:
+			return nil, serverError(packet)
+		case packet[0] == 0xfe && len(packet) < 9:
+			return nil, io.EOF
+		}
+		event := packet[1:]
+		if len(event) < 19 {
+			return nil, errors.New("short binlog event")
+		}
+		eventType := event[4]
+		timestamp := binary.LittleEndian.Uint32(event)
+		position := binary.LittleEndian.Uint32(event[13:])
+		if c.checksum && len(event) >= 23 {
+			body := event[:len(event)-4]
+			if crc32.ChecksumIEEE(body) == binary.LittleEndian.Uint32(event[len(event)-4:]) {
+				event = body
+			} else if eventType != binlogRotateEvent {
+				// The artificial rotate event sent first may lack one.
+				return nil, fmt.Errorf("binlog event checksum mismatch at %s:%d", c.file, position)
+			}
+		}
+		data := event[19:]
+
+		switch eventType {
+		case binlogRotateEvent:
+			if len(data) >= 8 {
+				c.file, c.position = string(data[8:]), uint32(binary.LittleEndian.Uint64(data))
+			}
+		case binlogXIDEvent:
+			c.position = position
+		case binlogFormatDescriptionEvent:
+			// Binlog version, server version, create timestamp, header
+			// length, then one post-header length per event type.
+			if len(data) > 57 {
+				c.postHeaderLengths = append([]byte(nil), data[57:]...)
+			}
+		case binlogTableMapEvent:
+			id, table, err := c.parseTableMap(data)
+			if err != nil {
+				return nil, fmt.Errorf("table map event at %s:%d: %w", c.file, position, err)
+			}
+			c.tables[id] = table
+		case binlogWriteRowsEvent, binlogUpdateRowsEvent, binlogDeleteRowsEvent,
+			binlogWriteRowsEventV1, binlogUpdateRowsEventV1, binlogDeleteRowsEventV1:
+			rows, err := c.parseRows(eventType, data)
+			if err != nil {
+				return nil, fmt.Errorf("rows event at %s:%d: %w", c.file, position, err)
+			}
+			if rows == nil {
+				continue
+			}
+			rows.Timestamp = time.Unix(int64(timestamp), 0).UTC()
+			rows.Position = position
+			return rows, nil
+		case binlogPartialUpdateRowsEvent:
+			return nil, errors.New("partial JSON updates (binlog_row_value_options = PARTIAL_JSON) cannot be decoded; turn the option off")
+		case binlogTransactionPayloadEvent:
+			return nil, errors.New("compressed transactions (binlog_transaction_compression = ON) cannot be decoded; turn the option off")
+		}
+	}
+}
+
+// postHeaderLength returns the post-header length of eventType, falling
+// back to the length current servers use.
+func (c *binlogConn) postHeaderLength(eventType byte, fallback int) int {
+	if int(eventType) <= len(c.postHeaderLengths) && eventType > 0 {
+		return int(c.postHeaderLengths[eventType-1])
+	}
+	return fallback
+}
+
+// tableID reads the 6-byte table id, or the 4-byte one of old servers.
+func tableID(data []byte, postHeader int) uint64 {
+	if postHeader == 6 {
+		return uint64(binary.LittleEndian.Uint32(data))
+	}
+	var b [8]byte
+	copy(b[:], data[:6])
+	return binary.LittleEndian.Uint64(b[:])
+}
+
+func (c *binlogConn) parseTableMap(data []byte) (uint64, *binlogTable, error) {
+	postHeader := c.postHeaderLength(binlogTableMapEvent, 8)
+	if postHeader < 6 || len(data) < postHeader+2 {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	id := tableID(data, postHeader)
+	r := &binlogReader{data: data[postHeader:]}
+	table := &binlogTable{}
+	table.Database = string(r.next(int(r.byte())))
+	r.byte()
+	table.Table = string(r.next(int(r.byte())))
+	r.byte()
+	count := int(r.lengthEncoded())
+	table.types = append([]byte(nil), r.next(count)...)
+	meta := &binlogReader{data: r.next(int(r.lengthEncoded()))}
+	table.meta = make([]uint16, count)
+	for i, t := range table.types {
+		switch t {
+		case fieldTypeFloat, fieldTypeDouble, fieldTypeBlob, fieldTypeTinyBlob, fieldTypeMediumBlob, fieldTypeLongBlob,
+			fieldTypeGeometry, fieldTypeJSON, fieldTypeVector, fieldTypeTimestamp2, fieldTypeDateTime2, fieldTypeTime2:
+			table.meta[i] = uint16(meta.byte())
+		case fieldTypeVarChar, fieldTypeVarString, fieldTypeBit:
+			table.meta[i] = binary.LittleEndian.Uint16(meta.next(2))
+		case fieldTypeString, fieldTypeNewDecimal, fieldTypeEnum, fieldTypeSet:
+			b := meta.next(2)
+			table.meta[i] = uint16(b[0])<<8 | uint16(b[1])
+		}
+	}
+	if r.err != nil || meta.err != nil {
+		return 0, nil, io.ErrUnexpectedEOF
+	}
+	return id, table, nil
+}
+
+func (c *binlogConn) parseRows(eventType byte, data []byte) (*binlogRowsEvent, error) {
+	v2 := eventType >= binlogWriteRowsEvent
+	fallback := 8
+	if v2 {
+		fallback = 10
+	}
+	postHeader := c.postHeaderLength(eventType, fallback)
+	if postHeader < 6 || len(data) < postHeader {
+		return nil, io.ErrUnexpectedEOF
+	}
+	table, ok := c.tables[tableID(data, postHeader)]
+	if !ok {
+		// Rows of a table whose map was sent before the dump started.
+		return nil, nil
+	}
+	r := &binlogReader{data: data[postHeader:]}
+	if v2 && postHeader >= 10 {
+		// The extra data length counts its own two bytes.
+		extra := int(binary.LittleEndian.Uint16(data[postHeader-2:]))
+		r.next(max(extra-2, 0))
+	}
+	count := int(r.lengthEncoded())
+	present := r.next((count + 7) / 8)
+	presentAfter := present
+	kind := map[byte]string{
+		binlogWriteRowsEvent: "insert", binlogWriteRowsEventV1: "insert",
+		binlogUpdateRowsEvent: "update", binlogUpdateRowsEventV1: "update",
+		binlogDeleteRowsEvent: "delete", binlogDeleteRowsEventV1: "delete",
+	}[eventType]
+	if kind == "update" {
+		presentAfter = r.next((count + 7) / 8)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	event := &binlogRowsEvent{Table: table, Kind: kind}
+	for image := 0; r.pos < len(r.data); image++ {
+		bitmap := present
+		if kind == "update" && image%2 == 1 {
+			bitmap = presentAfter
+		}
+		row, err := table.decodeRow(r, bitmap, count)
+		if err != nil {
+			return nil, err
+		}
+		event.Rows = append(event.Rows, row)
+	}
+	return event, nil
+}
+
+// decodeRow reads one row image. Columns absent from the image, as with
+// binlog_row_image = MINIMAL, are left nil.
+func (t *binlogTable) decodeRow(r *binlogReader, present []byte, count int) ([]any, error) {
+	included := 0
+	for i := 0; i < count; i++ {
+		if present[i/8]&(1<<(i%8)) != 0 {
+			included++
+		}
+	}
+	nulls := r.next((included + 7) / 8)
+	if r.err != nil {
+		return nil, r.err
+	}
+	row := make([]any, count)
+	n := 0
+	for i := 0; i < count && i < len(t.types); i++ {
+		if present[i/8]&(1<<(i%8)) == 0 {
+			continue
+		}
+		isNull := nulls != nil && nulls[n/8]&(1<<(n%8)) != 0
+		n++
+		if isNull {
+			continue
+		}
+		row[i] = decodeBinlogValue(r, t.types[i], t.meta[i])
+		if r.err != nil {
+			return nil, fmt.Errorf("column %d of %s.%s: %w", i+1, t.Database, t.Table, r.err)
+		}
+	}
+	return row, nil
+}
+
+// binlogReader reads fields from an event body, remembering the first
+// out-of-range read.
+type binlogReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *binlogReader) next(n int) []byte {
+	if r.err != nil || n < 0 || n > len(r.data)-r.pos {
+		if r.err == nil {
+			r.err = io.ErrUnexpectedEOF
+		}
+		// Zeros for the fixed-size reads, so callers check r.err once
+		// afterwards; a length read from the event may be anything.
+		return make([]byte, min(max(n, 0), 8))
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *binlogReader) byte() byte {
+	return r.next(1)[0]
+}
+
+func (r *binlogReader) uint(n int) uint64 {
+	var v uint64
+	for i, b := range r.next(n) {
+		v |= uint64(b) << (8 * i)
+	}
+	return v
+}
+
+func (r *binlogReader) bigEndian(n int) uint64 {
+	var v uint64
+	for _, b := range r.next(n) {
+		v = v<<8 | uint64(b)
+	}
+	return v
+}
+
+func (r *binlogReader) lengthEncoded() uint64 {
+	switch b := r.byte(); b {
+	case 0xfc:
+		return r.uint(2)
+	case 0xfd:
+		return r.uint(3)
+	case 0xfe:
+		return r.uint(8)
+	default:
+		return uint64(b)
+	}
+}
+
+// decodeBinlogValue reads one non-NULL column value. Integers are read as
+// signed; the caller reinterprets unsigned columns.
+func decodeBinlogValue(r *binlogReader, fieldType byte, meta uint16) any {
+	length := int(meta)
+	if fieldType == fieldTypeString && meta >= 256 {
+		// CHAR, ENUM and SET share the type; the real type and the
+		// upper bits of the length are packed into the first byte.
+		realType, low := byte(meta>>8), int(meta&0xff)
+		if realType&0x30 != 0x30 {
+			length = low | int((realType&0x30)^0x30)<<4
+			realType |= 0x30
+		} else {
+			length = low
+		}
+		fieldType = realType
+	}
+
+	switch fieldType {
+	case fieldTypeTiny:
+		return int64(int8(r.byte()))
+	case fieldTypeShort:
+		return int64(int16(r.uint(2)))
+	case fieldTypeInt24:
+		v := int64(r.uint(3))
+		if v&0x800000 != 0 {
+			v -= 1 << 24
+		}
+		return v
+	case fieldTypeLong:
+		return int64(int32(r.uint(4)))
+	case fieldTypeLongLong:
+		return int64(r.uint(8))
+	case fieldTypeFloat:
+		return float64(math.Float32frombits(uint32(r.uint(4))))
+	case fieldTypeDouble:
+		return math.Float64frombits(r.uint(8))
+	case fieldTypeNewDecimal:
+		return decodeBinlogDecimal(r, int(meta>>8), int(meta&0xff))
+	case fieldTypeYear:
+		if y := r.byte(); y != 0 {
+			return int64(y) + 1900
+		}
+		return int64(0)
+	case fieldTypeBit:
+		bits := int(meta>>8)*8 + int(meta&0xff)
+		return int64(r.bigEndian((bits + 7) / 8))
+	case fieldTypeEnum:
+		return int64(r.uint(length & 0xff))
+	case fieldTypeSet:
+		return int64(r.uint(length & 0xff))
+	case fieldTypeDate:
+		v := r.uint(3)
+		return fmt.Sprintf("%04d-%02d-%02d", v>>9, (v>>5)%16, v%32)
+	case fieldTypeTime:
+		v := r.uint(3)
+		return fmt.Sprintf("%02d:%02d:%02d", v/10000, (v/100)%100, v%100)
+	case fieldTypeDateTime:
+		v := r.uint(8)
+		d, t := v/1000000, v%1000000
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", d/10000, (d/100)%100, d%100, t/10000, (t/100)%100, t%100)
+	case fieldTypeTimestamp:
+		return time.Unix(int64(r.uint(4)), 0).UTC().Format(time.DateTime)
+	case fieldTypeTimestamp2:
+		seconds := int64(r.bigEndian(4))
+		return time.Unix(seconds, 0).UTC().Format(time.DateTime) + binlogFraction(r, int(meta))
+	case fieldTypeDateTime2:
+		v := int64(r.bigEndian(5)) - 0x8000000000
+		if v < 0 {
+			v = -v
+		}
+		ymd, hms := v>>17, v%(1<<17)
+		ym := ymd >> 5
+		return fmt.Sprintf("%04d-%02d-%02d %02d:%02d:%02d", ym/13, ym%13, ymd%(1<<5), hms>>12, (hms>>6)%(1<<6), hms%(1<<6)) +
+			binlogFraction(r, int(meta))
+	case fieldTypeTime2:
+		return decodeBinlogTime2(r, int(meta))
+	case fieldTypeVarChar, fieldTypeVarString, fieldTypeString:
+		size := 1
+		if length > 255 {
+			size = 2
+		}
+		return string(r.next(int(r.uint(size))))
+	case fieldTypeJSON:
+		b := r.next(int(r.uint(length)))
+		if len(b) == 0 {
+			return nil
+		}
+		v, err := decodeBinaryJSON(b)
+		if err != nil {
+			return base64.StdEncoding.EncodeToString(b)
+		}
+		return v
+	case fieldTypeGeometry:
+		return geometryText(r.next(int(r.uint(length))))
+	case fieldTypeVector:
+		return displayBytes("VECTOR", r.next(int(r.uint(length))))
+	case fieldTypeBlob, fieldTypeTinyBlob, fieldTypeMediumBlob, fieldTypeLongBlob:
+		b := r.next(int(r.uint(length)))
+		if utf8.Valid(b) {
+			return string(b)
+		}
+		return "0x" + fmt.Sprintf("%X", b)
+	default:
+		r.err = fmt.Errorf("unsupported column type %d", fieldType)
+		return nil
+	}
+}
+
+// binlogFraction reads the fractional seconds of a temporal column with
+// precision fsp.
+func binlogFraction(r *binlogReader, fsp int) string {
+	if fsp == 0 {
+		return ""
+	}
+	if fsp > 6 {
+		r.err = fmt.Errorf("invalid fractional seconds precision %d", fsp)
+		return ""
+	}
+	size := (fsp + 1) / 2
+	v := r.bigEndian(size)
+	// Stored in whole bytes: two digits per byte.
+	micros := v * uint64(math.Pow10(6-size*2))
+	return "." + fmt.Sprintf("%06d", micros)[:fsp]
+}
+
+func decodeBinlogTime2(r *binlogReader, fsp int) string {
+	if fsp > 6 {
+		r.err = fmt.Errorf("invalid fractional seconds precision %d", fsp)
+		return ""
+	}
+	var value int64
+	switch fsp {
+	case 1, 2:
+		value = int64(r.bigEndian(3)) - 0x800000
+		frac := int64(r.byte())
+		if value < 0 && frac != 0 {
+			value++
+			frac -= 0x100
+		}
+		value = value<<24 + frac*10000
+	case 3, 4:
+		value = int64(r.bigEndian(3)) - 0x800000
+		frac := int64(r.bigEndian(2))
+		if value < 0 && frac != 0 {
+			value++
+			frac -= 0x10000
+		}
+		value = value<<24 + frac*100
+	case 5, 6:
+		value = int64(r.bigEndian(6)) - 0x800000000000
+	default:
+		value = (int64(r.bigEndian(3)) - 0x800000) << 24
+	}
+	sign := ""
+	if value < 0 {
+		sign, value = "-", -value
+	}
+	hms := value >> 24
+	micros := value % (1 << 24)
+	text := fmt.Sprintf("%s%02d:%02d:%02d", sign, (hms>>12)%(1<<10), (hms>>6)%(1<<6), hms%(1<<6))
+	if fsp > 0 {
+		text += "." + fmt.Sprintf("%06d", micros)[:fsp]
+	}
+	return text
+}
+
+// decodeBinlogDecimal reads a DECIMAL(precision, scale) in MySQL's binary
+// format: groups of nine digits in four big-endian bytes, with the leftover
+// digits of each part in fewer bytes, and the sign in the top bit.
+func decodeBinlogDecimal(r *binlogReader, precision, scale int) string {
+	leftover := []int{0, 1, 1, 2, 2, 3, 3, 4, 4, 4}
+	integral := precision - scale
+	if integral < 0 {
+		r.err = fmt.Errorf("invalid DECIMAL(%d, %d)", precision, scale)
+		return ""
+	}
+	fullIntegral, partIntegral := integral/9, integral%9
+	fullFraction, partFraction := scale/9, scale%9
+	size := fullIntegral*4 + leftover[partIntegral] + fullFraction*4 + leftover[partFraction]
+	b := append([]byte(nil), r.next(size)...)
+	if len(b) == 0 {
+		return "0"
+	}
+
+	negative := b[0]&0x80 == 0
+	b[0] ^= 0x80
+	if negative {
+		for i := range b {
+			b[i] = ^b[i]
+		}
+	}
+	d := &binlogReader{data: b}
+	var integer, fraction strings.Builder
+	if partIntegral > 0 {
+		integer.WriteString(strconv.FormatUint(d.bigEndian(leftover[partIntegral]), 10))
+	}
+	for range fullIntegral {
+		fmt.Fprintf(&integer, "%09d", d.bigEndian(4))
+	}
+	for range fullFraction {
+		fmt.Fprintf(&fraction, "%09d", d.bigEndian(4))
+	}
+	if partFraction > 0 {
+		fmt.Fprintf(&fraction, "%0*d", partFraction, d.bigEndian(leftover[partFraction]))
+	}
+
+	text := strings.TrimLeft(integer.String(), "0")
+	if text == "" {
+		text = "0"
+	}
+	if scale > 0 {
+		text += "." + fraction.String()
+	}
+	if negative {
+		text = "-" + text
+	}
+	return text
+}
+
+// decodeBinaryJSON decodes the binary JSON format MySQL stores JSON
+// columns in and writes to the binlog.
+func decodeBinaryJSON(b []byte) (any, error) {
+	if len(b) == 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return decodeJSONValue(b[0], b[1:])
+}
+
+func decodeJSONValue(valueType byte, b []byte) (any, error) {
+	need := func(n int) error {
+		if len(b) < n {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	}
+	switch valueType {
+	case 0x00, 0x01, 0x02, 0x03:
+		return decodeJSONContainer(valueType, b)
+	case 0x04:
+		if err := need(1); err != nil {
+			return nil, err
+		}
+		switch b[0] {
+		case 0x01:
+			return true, nil
+		case 0x02:
+			return false, nil
+		}
+		return nil, nil
+	case 0x05:
+		if err := need(2); err != nil {
+			return nil, err
+		}
+		return int64(int16(binary.LittleEndian.Uint16(b))), nil
+	case 0x06:
+		if err := need(2); err != nil {
+			return nil, err
+		}
+		return int64(binary.LittleEndian.Uint16(b)), nil
+	case 0x07:
+		if err := need(4); err != nil {
+			return nil, err
+		}
+		return int64(int32(binary.LittleEndian.Uint32(b))), nil
+	case 0x08:
+		if err := need(4); err != nil {
+			return nil, err
+		}
+		return int64(binary.LittleEndian.Uint32(b)), nil
+	case 0x09:
+		if err := need(8); err != nil {
+			return nil, err
+		}
+		return int64(binary.LittleEndian.Uint64(b)), nil
+	case 0x0a:
+		if err := need(8); err != nil {
+			return nil, err
+		}
+		return binary.LittleEndian.Uint64(b), nil
+	case 0x0b:
+		if err := need(8); err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+	case 0x0c:
+		n, size := jsonVariableLength(b)
+		if size == 0 || len(b) < size+n {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return string(b[size : size+n]), nil
+	case 0x0f:
+		// Opaque values, such as DATE or DECIMAL, carry their column type
+		// first; they are passed on as the raw text.
+		if err := need(1); err != nil {
+			return nil, err
+		}
+		n, size := jsonVariableLength(b[1:])
+		if size == 0 || len(b) < 1+size+n {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return string(b[1+size : 1+size+n]), nil
+	default:
+		return nil, fmt.Errorf("unknown JSON value type %d", valueType)
+	}
+}
+
+// jsonVariableLength reads a length stored seven bits per byte, returning
+// it and the number of bytes used, or 0 bytes if it is malformed.
+func jsonVariableLength(b []byte) (int, int) {
+	length := 0
+	for i := 0; i < 5 && i < len(b); i++ {
+		length |= int(b[i]&0x7f) << (7 * i)
+		if b[i]&0x80 == 0 {
+			return length, i + 1
+		}
+	}
+	return 0, 0
+}
+
+func decodeJSONContainer(valueType byte, b []byte) (any, error) {
+	large := valueType == 0x01 || valueType == 0x03
+	isObject := valueType == 0x00 || valueType == 0x01
+	offsetSize := 2
+	if large {
+		offsetSize = 4
+	}
+	read := func(pos int) int {
+		if large {
+			return int(binary.LittleEndian.Uint32(b[pos:]))
+		}
+		return int(binary.LittleEndian.Uint16(b[pos:]))
+	}
+	if len(b) < 2*offsetSize {
+		return nil, io.ErrUnexpectedEOF
+	}
+	count := read(0)
+	keyEntries := 0
+	if isObject {
+		keyEntries = count * (offsetSize + 2)
+	}
+	valueEntry := 1 + offsetSize
+	if len(b) < 2*offsetSize+keyEntries+count*valueEntry {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	values := make([]any, count)
+	for i := range count {
+		pos := 2*offsetSize + keyEntries + i*valueEntry
+		entryType := b[pos]
+		inlined := entryType == 0x04 || entryType == 0x05 || entryType == 0x06 ||
+			(large && (entryType == 0x07 || entryType == 0x08))
+		var v any
+		var err error
+		if inlined {
+			v, err = decodeJSONValue(entryType, b[pos+1:pos+1+offsetSize])
+		} else {
+			offset := read(pos + 1)
+			if offset > len(b) {
+				return nil, io.ErrUnexpectedEOF
+			}
+			v, err = decodeJSONValue(entryType, b[offset:])
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	if !isObject {
+		return values, nil
+	}
+
+	object := make(map[string]any, count)
+	for i := range count {
+		pos := 2*offsetSize + i*(offsetSize+2)
+		offset, length := read(pos), int(binary.LittleEndian.Uint16(b[pos+offsetSize:]))
+		if offset+length > len(b) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		object[string(b[offset:offset+length])] = values[i]
+	}
+	return object, nil
+}
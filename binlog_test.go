@@ -0,0 +1,772 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// greetingPacket builds a protocol 10 handshake packet as MySQL 8 sends it.
+func greetingPacket() []byte {
+	var p []byte
+	p = append(p, 10)
+	p = append(p, "8.0.36\x00"...)
+	p = append(p, 1, 0, 0, 0)
+	p = append(p, "abcdefgh"...)
+	p = append(p, 0)
+	p = append(p, 0x00, 0x8a) // lower capabilities: SSL, protocol 4.1, secure connection
+	p = append(p, 45, 2, 0)
+	p = append(p, 0x08, 0x00) // upper capabilities: plugin auth
+	p = append(p, 21)
+	p = append(p, make([]byte, 10)...)
+	p = append(p, "ijklmnopqrst\x00"...)
+	p = append(p, "caching_sha2_password\x00"...)
+	return p
+}
+
+func TestParseGreeting(t *testing.T) {
+	full := greetingPacket()
+	got, err := parseGreeting(full)
+	if err != nil {
+		t.Fatalf("parseGreeting() error = %v", err)
+	}
+	want := serverGreeting{
+		capabilities: clientSSL | clientProtocol41 | clientSecureConnection | clientPluginAuth,
+		scramble:     []byte("abcdefghijklmnopqrst"),
+		plugin:       "caching_sha2_password",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseGreeting() = %+v, want %+v", got, want)
+	}
+
+	// A pre-4.1 greeting ends after the lower capabilities.
+	short := full[:bytes.IndexByte(full, 0)+1+4+8+1+2]
+	got, err = parseGreeting(short)
+	if err != nil {
+		t.Fatalf("parseGreeting(pre-4.1) error = %v", err)
+	}
+	if got.plugin != "mysql_native_password" || string(got.scramble) != "abcdefgh" {
+		t.Errorf("parseGreeting(pre-4.1) = %+v", got)
+	}
+
+	tests := []struct {
+		name   string
+		packet []byte
+	}{
+		{name: "empty", packet: nil},
+		{name: "protocol version 9", packet: append([]byte{9}, full[1:]...)},
+		{name: "unterminated server version", packet: []byte{10, '8', '.', '0'}},
+		{name: "no capabilities", packet: full[:bytes.IndexByte(full, 0)+1+4+8+1]},
+		{name: "cut in the reserved bytes", packet: append(append([]byte(nil), short...), 45, 2, 0, 0x08)},
+	}
+	for _, tt := range tests {
+		if _, err := parseGreeting(tt.packet); err == nil {
+			t.Errorf("%s: parseGreeting() succeeded", tt.name)
+		}
+	}
+
+	errPacket := []byte{0xff, 0x10, 0x04, '#', 'H', 'Y', '0', '0', '0', 't', 'o', 'o', ' ', 'm', 'a', 'n', 'y'}
+	var mysqlErr *mysql.MySQLError
+	if _, err := parseGreeting(errPacket); !errors.As(err, &mysqlErr) || mysqlErr.Number != 1040 {
+		t.Errorf("parseGreeting(ERR) error = %v, want error 1040", err)
+	}
+
+	// Every truncation of a valid greeting fails or parses; none panics.
+	for n := range full {
+		parseGreeting(full[:n])
+	}
+}
+
+func TestBinlogReader(t *testing.T) {
+	r := &binlogReader{data: []byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}}
+	n := r.lengthEncoded()
+	if r.err != nil {
+		t.Fatalf("lengthEncoded() error = %v", r.err)
+	}
+	// A length near the top of the int range must not wrap the bounds
+	// check around.
+	if b := r.next(int(n)); len(b) != 0 && r.err == nil {
+		t.Errorf("next(%d) returned %d bytes without an error", n, len(b))
+	}
+	if r.err != io.ErrUnexpectedEOF {
+		t.Errorf("next(%d) error = %v, want %v", n, r.err, io.ErrUnexpectedEOF)
+	}
+
+	r = &binlogReader{data: []byte{1, 2}}
+	if v := r.uint(2); v != 0x0201 || r.err != nil {
+		t.Errorf("uint(2) = %#x, %v", v, r.err)
+	}
+	if r.byte(); r.err != io.ErrUnexpectedEOF {
+		t.Errorf("byte() past the end: error = %v", r.err)
+	}
+}
+
+func TestDecodeBinlogValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		fieldType byte
+		meta      uint16
+		data      []byte
+		want      any
+		wantErr   bool
+	}{
+		{name: "decimal", fieldType: fieldTypeNewDecimal, meta: 5<<8 | 2, data: []byte{0x80, 0x7b, 0x2d}, want: "123.45"},
+		{name: "negative decimal", fieldType: fieldTypeNewDecimal, meta: 5<<8 | 2, data: []byte{0x7f, 0x84, 0xd2}, want: "-123.45"},
+		{name: "decimal with nine-digit groups", fieldType: fieldTypeNewDecimal, meta: 18<<8 | 9, data: []byte{0x80, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02}, want: "1.000000002"},
+		{name: "decimal scale above precision", fieldType: fieldTypeNewDecimal, meta: 2<<8 | 5, data: []byte{0x80, 0, 0, 0}, wantErr: true},
+		{name: "short decimal", fieldType: fieldTypeNewDecimal, meta: 5<<8 | 2, data: []byte{0x80}, wantErr: true},
+		{name: "time", fieldType: fieldTypeTime2, data: []byte{0x80, 0xc8, 0xb8}, want: "12:34:56"},
+		{name: "time with milliseconds", fieldType: fieldTypeTime2, meta: 3, data: []byte{0x80, 0xc8, 0xb8, 0x04, 0xce}, want: "12:34:56.123"},
+		{name: "time precision above 6", fieldType: fieldTypeTime2, meta: 7, data: []byte{0x80, 0xc8, 0xb8, 0, 0, 0}, wantErr: true},
+		{name: "datetime", fieldType: fieldTypeDateTime2, data: []byte{0x99, 0xb2, 0x5c, 0xc8, 0xb8}, want: "2024-01-14 12:34:56"},
+		{name: "datetime precision above 6", fieldType: fieldTypeDateTime2, meta: 200, data: []byte{0x99, 0xb3, 0x9c, 0xc8, 0xb8, 0, 0, 0}, wantErr: true},
+		{name: "varchar", fieldType: fieldTypeVarChar, meta: 20, data: []byte{3, 'a', 'b', 'c'}, want: "abc"},
+		{name: "varchar longer than the event", fieldType: fieldTypeVarChar, meta: 20, data: []byte{9, 'a'}, wantErr: true},
+		{name: "int24", fieldType: fieldTypeInt24, data: []byte{0xff, 0xff, 0xff}, want: int64(-1)},
+		{name: "json", fieldType: fieldTypeJSON, meta: 1, data: []byte{3, 0x04, 0x01, 0x00}, want: true},
+	}
+	for _, tt := range tests {
+		r := &binlogReader{data: tt.data}
+		got := decodeBinlogValue(r, tt.fieldType, tt.meta)
+		if (r.err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, r.err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: decodeBinlogValue() = %#v, want %#v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeBinaryJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want any
+	}{
+		{
+			name: "object",
+			// Count, size, key entry (offset, length), value entry (int16
+			// inlined), key.
+			data: []byte{0x00, 1, 0, 12, 0, 11, 0, 1, 0, 0x05, 1, 0, 'a'},
+			want: map[string]any{"a": int64(1)},
+		},
+		{
+			name: "array",
+			// Count, size, true inlined, string at offset 10.
+			data: []byte{0x02, 2, 0, 12, 0, 0x04, 0x01, 0, 0x0c, 10, 0, 1, 'x'},
+			want: []any{true, "x"},
+		},
+		{name: "double", data: []byte{0x0b, 0, 0, 0, 0, 0, 0, 0xf8, 0x3f}, want: 1.5},
+		{name: "string", data: []byte{0x0c, 2, 'h', 'i'}, want: "hi"},
+	}
+	for _, tt := range tests {
+		got, err := decodeBinaryJSON(tt.data)
+		if err != nil {
+			t.Errorf("%s: decodeBinaryJSON() error = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: decodeBinaryJSON() = %#v, want %#v", tt.name, got, tt.want)
+		}
+		// Truncated values fail instead of reading past the end.
+		for n := range tt.data {
+			if _, err := decodeBinaryJSON(tt.data[:n]); err == nil {
+				t.Errorf("%s: decodeBinaryJSON of %d of %d bytes succeeded", tt.name, n, len(tt.data))
+			}
+		}
+	}
+}
+
+func TestParseRowsMalformed(t *testing.T) {
+	c := &binlogConn{tables: make(map[uint64]*binlogTable)}
+	// Table id 1, flags, `db`.`t` with one VARCHAR(20) column.
+	tableMap := []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 'd', 'b', 0, 1, 't', 0, 1, fieldTypeVarChar, 2, 20, 0, 0}
+	id, table, err := c.parseTableMap(tableMap)
+	if err != nil {
+		t.Fatalf("parseTableMap() error = %v", err)
+	}
+	if id != 1 || table.Database != "db" || table.Table != "t" || table.meta[0] != 20 {
+		t.Fatalf("parseTableMap() = %d, %+v", id, table)
+	}
+	c.tables[id] = table
+
+	// Table id, flags, extra data length, column count, columns present,
+	// null bitmap, then 'ab'.
+	rows := []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 1, 0x01, 0x00, 2, 'a', 'b'}
+	event, err := c.parseRows(binlogWriteRowsEvent, rows)
+	if err != nil {
+		t.Fatalf("parseRows() error = %v", err)
+	}
+	if want := [][]any{{"ab"}}; event.Kind != "insert" || !reflect.DeepEqual(event.Rows, want) {
+		t.Errorf("parseRows() = %s %#v, want insert %#v", event.Kind, event.Rows, want)
+	}
+	for n := range rows {
+		if n == 12 {
+			// The header alone is an event without rows.
+			continue
+		}
+		if _, err := c.parseRows(binlogWriteRowsEvent, rows[:n]); err == nil {
+			t.Errorf("parseRows of %d of %d bytes succeeded", n, len(rows))
+		}
+	}
+	for n := range tableMap {
+		c.parseTableMap(tableMap[:n])
+	}
+
+	// A format description event announcing post-header lengths too short
+	// for a table id.
+	c.postHeaderLengths = make([]byte, binlogDeleteRowsEvent)
+	if _, _, err := c.parseTableMap(tableMap); err == nil {
+		t.Error("parseTableMap() with a zero post-header length succeeded")
+	}
+	if _, err := c.parseRows(binlogWriteRowsEvent, rows); err == nil {
+		t.Error("parseRows() with a zero post-header length succeeded")
+	}
+}
+
+// frame prefixes payload with a packet header.
+func frame(sequence byte, payload []byte) []byte {
+	n := len(payload)
+	return append([]byte{byte(n), byte(n >> 8), byte(n >> 16), sequence}, payload...)
+}
+
+// binlogEvent builds the dump packet of an event ending at position, with a
+// CRC32 checksum when checksum is set.
+func binlogEvent(eventType byte, position uint32, data []byte, checksum bool) []byte {
+	size := 19 + len(data)
+	if checksum {
+		size += 4
+	}
+	event := make([]byte, 19, size)
+	binary.LittleEndian.PutUint32(event, 1700000000)
+	event[4] = eventType
+	binary.LittleEndian.PutUint32(event[5:], 1)
+	binary.LittleEndian.PutUint32(event[9:], uint32(size))
+	binary.LittleEndian.PutUint32(event[13:], position)
+	event = append(event, data...)
+	if checksum {
+		event = binary.LittleEndian.AppendUint32(event, crc32.ChecksumIEEE(event))
+	}
+	return append([]byte{0}, event...)
+}
+
+// binlogStream returns a connection that reads packets as a dump, then EOF.
+func binlogStream(packets ...[]byte) *binlogConn {
+	var b []byte
+	for i, p := range packets {
+		b = append(b, frame(byte(i+1), p)...)
+	}
+	return &binlogConn{reader: bufio.NewReader(bytes.NewReader(b)), tables: make(map[uint64]*binlogTable)}
+}
+
+// Event bodies for table id 1, `db`.`t` (id INT, name VARCHAR(20)).
+var (
+	testTableMap = []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 'd', 'b', 0, 1, 't', 0, 2, fieldTypeLong, fieldTypeVarChar, 2, 20, 0, 0}
+	// Table id, flags, extra data length, column count, columns present,
+	// then the null bitmap and values of each row.
+	testInsertOne = []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 2, 0x03, 0x00, 1, 0, 0, 0, 3, 'o', 'n', 'e'}
+	testUpdateUno = []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 2, 0x03, 0x03,
+		0x00, 1, 0, 0, 0, 3, 'o', 'n', 'e',
+		0x00, 1, 0, 0, 0, 3, 'u', 'n', 'o'}
+	testDeleteNull = []byte{1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 2, 0x03, 0x02, 1, 0, 0, 0}
+)
+
+// rotateEvent is the body of a rotate event to file at position.
+func rotateEvent(file string, position uint64) []byte {
+	return append(binary.LittleEndian.AppendUint64(nil, position), file...)
+}
+
+func TestReadEvent(t *testing.T) {
+	// Binlog version, server version, create timestamp, header length,
+	// then the post-header lengths of a MySQL 8 server.
+	formatDescription := append([]byte{4, 0}, make([]byte, 50+4)...)
+	formatDescription = append(formatDescription, 19)
+	postHeaders := make([]byte, binlogTransactionPayloadEvent)
+	postHeaders[binlogTableMapEvent-1] = 8
+	for _, eventType := range []byte{binlogWriteRowsEvent, binlogUpdateRowsEvent, binlogDeleteRowsEvent} {
+		postHeaders[eventType-1] = 10
+	}
+	formatDescription = append(formatDescription, postHeaders...)
+
+	c := binlogStream(
+		// The artificial rotate event the server sends first has no
+		// checksum.
+		binlogEvent(binlogRotateEvent, 0, rotateEvent("binlog.000002", 4), false),
+		binlogEvent(binlogFormatDescriptionEvent, 0, formatDescription, true),
+		binlogEvent(binlogTableMapEvent, 300, testTableMap, true),
+		binlogEvent(binlogWriteRowsEvent, 400, testInsertOne, true),
+		binlogEvent(binlogXIDEvent, 431, []byte{9, 0, 0, 0, 0, 0, 0, 0}, true),
+		binlogEvent(binlogTableMapEvent, 480, testTableMap, true),
+		binlogEvent(binlogUpdateRowsEvent, 500, testUpdateUno, true),
+		binlogEvent(binlogDeleteRowsEvent, 560, testDeleteNull, true),
+	)
+	c.checksum = true
+	tests := []struct {
+		kind     string
+		rows     [][]any
+		position uint32
+		// resume is the commit position a reconnect would start from.
+		resume uint32
+	}{
+		{"insert", [][]any{{int64(1), "one"}}, 400, 4},
+		{"update", [][]any{{int64(1), "one"}, {int64(1), "uno"}}, 500, 431},
+		{"delete", [][]any{{int64(1), nil}}, 560, 431},
+	}
+	for _, tt := range tests {
+		event, err := c.readEvent()
+		if err != nil {
+			t.Fatalf("readEvent() error = %v, want the %s at %d", err, tt.kind, tt.position)
+		}
+		if event.Kind != tt.kind || !reflect.DeepEqual(event.Rows, tt.rows) || event.Position != tt.position {
+			t.Errorf("readEvent() = %s %#v at %d, want %s %#v at %d", event.Kind, event.Rows, event.Position, tt.kind, tt.rows, tt.position)
+		}
+		if event.Table.Database != "db" || event.Table.Table != "t" || !event.Timestamp.Equal(time.Unix(1700000000, 0)) {
+			t.Errorf("%s: table %s.%s at %v", tt.kind, event.Table.Database, event.Table.Table, event.Timestamp)
+		}
+		if c.file != "binlog.000002" || c.position != tt.resume {
+			t.Errorf("%s: resume position %s:%d, want binlog.000002:%d", tt.kind, c.file, c.position, tt.resume)
+		}
+	}
+	if _, err := c.readEvent(); err != io.EOF {
+		t.Errorf("readEvent() at the end = %v, want EOF", err)
+	}
+}
+
+func TestReadEventErrors(t *testing.T) {
+	corrupt := binlogEvent(binlogWriteRowsEvent, 400, testInsertOne, true)
+	corrupt[len(corrupt)-1] ^= 0xff
+
+	tests := []struct {
+		name   string
+		packet []byte
+		want   string
+	}{
+		{"checksum mismatch", corrupt, "checksum mismatch at binlog.000001:400"},
+		{"compressed transaction", binlogEvent(binlogTransactionPayloadEvent, 400, []byte{0, 0}, true), "binlog_transaction_compression"},
+		{"partial JSON update", binlogEvent(binlogPartialUpdateRowsEvent, 400, testUpdateUno, true), "PARTIAL_JSON"},
+		{"truncated rows", binlogEvent(binlogWriteRowsEvent, 400, testInsertOne[:16], true), "rows event at binlog.000001:400"},
+		{"server error", append([]byte{0xff, 0x3d, 0x05}, "#HY000Could not find first log file name"...), "Could not find first log file name"},
+		// Rows of a table mapped before the dump started are skipped.
+		{"unmapped table", binlogEvent(binlogWriteRowsEvent, 400, append([]byte{2}, testInsertOne[1:]...), true), "EOF"},
+	}
+	for _, tt := range tests {
+		c := binlogStream(binlogEvent(binlogTableMapEvent, 300, testTableMap, true), tt.packet)
+		c.checksum, c.file = true, "binlog.000001"
+		_, err := c.readEvent()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: readEvent() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestHandshakeTLSFallback(t *testing.T) {
+	// A server without TLS: the SSL capability is cleared.
+	greeting := greetingPacket()
+	greeting[bytes.IndexByte(greeting, 0)+1+4+8+1+1] &^= clientSSL >> 8
+
+	for _, fallback := range []bool{false, true} {
+		client, server := net.Pipe()
+		go func() {
+			defer server.Close()
+			server.Write(frame(0, greeting))
+			// The login request, then OK.
+			c := &binlogConn{conn: server, reader: bufio.NewReader(server)}
+			if _, err := c.readPacket(); err != nil {
+				return
+			}
+			server.Write(frame(c.sequence, []byte{0x00, 0, 0, 2, 0, 0, 0}))
+		}()
+
+		cfg := mysql.NewConfig()
+		cfg.User, cfg.Passwd = "u", "p"
+		cfg.TLS = &tls.Config{ServerName: "db"}
+		cfg.AllowFallbackToPlaintext = fallback
+		c := &binlogConn{conn: client, reader: bufio.NewReader(client)}
+		err := c.handshake(cfg)
+		client.Close()
+		if fallback && err != nil {
+			t.Errorf("tls=preferred: handshake() error = %v", err)
+		}
+		if !fallback && err == nil {
+			t.Error("tls=true: handshake() with a server without TLS succeeded")
+		}
+	}
+}
+
+// fakeLogin is the server side of a login: the account's plugin and
+// password, whether the server offers TLS, and how authentication goes.
+type fakeLogin struct {
+	plugin   string
+	password string
+	tls      *tls.Config // nil: the server does not offer TLS
+	switchTo string      // plugin of an auth switch request, if any
+	fullAuth bool        // caching_sha2_password misses its cache
+	key      *rsa.PrivateKey
+}
+
+// bufferedConn reads a connection through the reader wrapping it.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
+
+// serveLogin plays a MySQL 8 server accepting a login as "u" on conn.
+func serveLogin(conn net.Conn, login fakeLogin) error {
+	c := &binlogConn{conn: conn, reader: bufio.NewReader(conn)}
+	greeting := greetingPacket()
+	greeting = append(greeting[:bytes.Index(greeting, []byte("caching_sha2_password"))], login.plugin+"\x00"...)
+	if login.tls == nil {
+		greeting[bytes.IndexByte(greeting, 0)+1+4+8+1+1] &^= clientSSL >> 8
+	}
+	scramble := []byte("abcdefghijklmnopqrst")
+	if err := c.writePacket(greeting); err != nil {
+		return err
+	}
+	packet, err := c.readPacket()
+	if err != nil {
+		return err
+	}
+	secure := false
+	if len(packet) == 32 && binary.LittleEndian.Uint32(packet)&clientSSL != 0 {
+		if login.tls == nil {
+			return errors.New("SSL request to a server without TLS")
+		}
+		// The reader may already hold the start of the client hello.
+		tlsConn := tls.Server(bufferedConn{conn, c.reader}, login.tls)
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		c.conn, c.reader, secure = tlsConn, bufio.NewReader(tlsConn), true
+		if packet, err = c.readPacket(); err != nil {
+			return err
+		}
+	}
+
+	// Handshake response: capabilities and the rest of the fixed header,
+	// the user, the auth response and the plugin it was computed for.
+	end := -1
+	if len(packet) > 32 {
+		end = bytes.IndexByte(packet[32:], 0)
+	}
+	if end < 0 || 32+end+2 > len(packet) || 32+end+2+int(packet[32+end+1]) > len(packet) {
+		return fmt.Errorf("malformed handshake response %x", packet)
+	}
+	user := string(packet[32 : 32+end])
+	auth := packet[32+end+2 : 32+end+2+int(packet[32+end+1])]
+	plugin := string(bytes.TrimSuffix(packet[32+end+2+len(auth):], []byte{0}))
+	if user != "u" || plugin != login.plugin {
+		return fmt.Errorf("login as %q with %s", user, plugin)
+	}
+	if login.switchTo != "" {
+		plugin, scramble = login.switchTo, []byte("ABCDEFGHIJKLMNOPQRST")
+		request := append([]byte{0xfe}, plugin+"\x00"...)
+		if err := c.writePacket(append(append(request, scramble...), 0)); err != nil {
+			return err
+		}
+		if auth, err = c.readPacket(); err != nil {
+			return err
+		}
+	}
+
+	ok := []byte{0x00, 0, 0, 2, 0, 0, 0}
+	denied := append([]byte{0xff, 0x15, 0x04}, "#28000Access denied for user 'u'"...)
+	switch {
+	case plugin == "mysql_native_password":
+		// The server keeps SHA1(SHA1(password)) and recovers SHA1(password)
+		// from the response.
+		stored := sha1.Sum([]byte(login.password))
+		stored = sha1.Sum(stored[:])
+		h := sha1.Sum(append(append([]byte(nil), scramble...), stored[:]...))
+		valid := login.password == "" && len(auth) == 0
+		if len(auth) == len(h) {
+			for i := range h {
+				h[i] ^= auth[i]
+			}
+			valid = sha1.Sum(h[:]) == stored
+		}
+		if !valid {
+			return c.writePacket(denied)
+		}
+	case plugin == "caching_sha2_password" && !login.fullAuth:
+		stored := sha256.Sum256([]byte(login.password))
+		stored = sha256.Sum256(stored[:])
+		h := sha256.Sum256(append(stored[:], scramble...))
+		valid := login.password == "" && len(auth) == 0
+		if len(auth) == len(h) {
+			for i := range h {
+				h[i] ^= auth[i]
+			}
+			valid = sha256.Sum256(h[:]) == stored
+		}
+		if !valid {
+			return c.writePacket(denied)
+		}
+		if err := c.writePacket([]byte{0x01, 3}); err != nil {
+			return err
+		}
+	case plugin == "caching_sha2_password":
+		if err := c.writePacket([]byte{0x01, 4}); err != nil {
+			return err
+		}
+		password, err := c.readPacket()
+		if err != nil {
+			return err
+		}
+		if !secure {
+			// Without TLS the client asks for the public key and sends the
+			// password XOR the scramble, encrypted with it.
+			if !bytes.Equal(password, []byte{2}) {
+				return fmt.Errorf("got %x instead of a public key request", password)
+			}
+			der, err := x509.MarshalPKIXPublicKey(&login.key.PublicKey)
+			if err != nil {
+				return err
+			}
+			if err := c.writePacket(append([]byte{0x01}, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})...)); err != nil {
+				return err
+			}
+			encrypted, err := c.readPacket()
+			if err != nil {
+				return err
+			}
+			if password, err = rsa.DecryptOAEP(sha1.New(), nil, login.key, encrypted, nil); err != nil {
+				return err
+			}
+			for i := range password {
+				password[i] ^= scramble[i%len(scramble)]
+			}
+		}
+		if string(password) != login.password+"\x00" {
+			return c.writePacket(denied)
+		}
+	default:
+		return fmt.Errorf("unexpected plugin %s", plugin)
+	}
+	return c.writePacket(ok)
+}
+
+// testTLSConfigs returns a server configuration with a self-signed
+// certificate for "db" and a client configuration that trusts it.
+func testTLSConfigs(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "db"},
+		DNSNames:     []string{"db"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	return server, &tls.Config{RootCAs: pool, ServerName: "db"}
+}
+
+func TestDialBinlogLogin(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverTLS, clientTLS := testTLSConfigs(t)
+	untrusted := &tls.Config{RootCAs: x509.NewCertPool(), ServerName: "db"}
+
+	tests := []struct {
+		name     string
+		login    fakeLogin
+		passwd   string
+		tls      *tls.Config
+		fallback bool
+		wantErr  string
+	}{
+		{name: "native password", login: fakeLogin{plugin: "mysql_native_password", password: "p"}, passwd: "p"},
+		{name: "native empty password", login: fakeLogin{plugin: "mysql_native_password"}},
+		{name: "native wrong password", login: fakeLogin{plugin: "mysql_native_password", password: "p"}, passwd: "q", wantErr: "Access denied"},
+		{name: "native over TLS", login: fakeLogin{plugin: "mysql_native_password", password: "p", tls: serverTLS}, passwd: "p", tls: clientTLS},
+		{name: "caching_sha2 fast auth", login: fakeLogin{plugin: "caching_sha2_password", password: "p"}, passwd: "p"},
+		{name: "caching_sha2 fast auth wrong password", login: fakeLogin{plugin: "caching_sha2_password", password: "p"}, passwd: "q", wantErr: "Access denied"},
+		{name: "caching_sha2 fast auth over TLS", login: fakeLogin{plugin: "caching_sha2_password", password: "p", tls: serverTLS}, passwd: "p", tls: clientTLS},
+		{name: "caching_sha2 full auth with the RSA key", login: fakeLogin{plugin: "caching_sha2_password", password: "p", fullAuth: true, key: key}, passwd: "p"},
+		{name: "caching_sha2 full auth with the RSA key, wrong password", login: fakeLogin{plugin: "caching_sha2_password", password: "p", fullAuth: true, key: key}, passwd: "q", wantErr: "Access denied"},
+		{name: "caching_sha2 full auth over TLS", login: fakeLogin{plugin: "caching_sha2_password", password: "p", fullAuth: true, tls: serverTLS}, passwd: "p", tls: clientTLS},
+		{name: "caching_sha2 full auth over TLS, wrong password", login: fakeLogin{plugin: "caching_sha2_password", password: "p", fullAuth: true, tls: serverTLS}, passwd: "q", tls: clientTLS, wantErr: "Access denied"},
+		{name: "switch to native", login: fakeLogin{plugin: "caching_sha2_password", password: "p", switchTo: "mysql_native_password"}, passwd: "p"},
+		{name: "switch to caching_sha2 full auth", login: fakeLogin{plugin: "mysql_native_password", password: "p", switchTo: "caching_sha2_password", fullAuth: true, key: key}, passwd: "p"},
+		{name: "switch to caching_sha2 over TLS", login: fakeLogin{plugin: "mysql_native_password", password: "p", switchTo: "caching_sha2_password", fullAuth: true, tls: serverTLS}, passwd: "p", tls: clientTLS},
+		{name: "tls=true without server TLS", login: fakeLogin{plugin: "caching_sha2_password", password: "p"}, passwd: "p", tls: clientTLS, wantErr: "does not support it"},
+		{name: "tls=preferred without server TLS", login: fakeLogin{plugin: "caching_sha2_password", password: "p", fullAuth: true, key: key}, passwd: "p", tls: clientTLS, fallback: true},
+		{name: "tls=preferred with server TLS", login: fakeLogin{plugin: "caching_sha2_password", password: "p", fullAuth: true, tls: serverTLS}, passwd: "p", tls: clientTLS, fallback: true},
+		{name: "untrusted certificate", login: fakeLogin{plugin: "caching_sha2_password", password: "p", tls: serverTLS}, passwd: "p", tls: untrusted, wantErr: "certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			served := make(chan error, 1)
+			go func() {
+				conn, err := ln.Accept()
+				if err != nil {
+					served <- err
+					return
+				}
+				defer conn.Close()
+				served <- serveLogin(conn, tt.login)
+			}()
+
+			cfg := mysql.NewConfig()
+			cfg.Addr = ln.Addr().String()
+			cfg.User, cfg.Passwd = "u", tt.passwd
+			cfg.TLS = tt.tls
+			cfg.AllowFallbackToPlaintext = tt.fallback
+			c, err := dialBinlog(cfg)
+			if err == nil {
+				c.Close()
+			}
+			serveErr := <-served
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("dialBinlog() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("dialBinlog() error = %v", err)
+			}
+			if serveErr != nil {
+				t.Errorf("server: %v", serveErr)
+			}
+		})
+	}
+}
+
+// TestBinlogDumpIntegration streams an insert from the servers named by
+// MYSQL_MCP_TEST_DSN, a space-separated list of DSNs. Give one per
+// authentication plugin and TLS mode to cover (for example a
+// caching_sha2_password account with tls=false, tls=true and
+// tls=preferred, and a mysql_native_password account). Each account needs
+// REPLICATION SLAVE, REPLICATION CLIENT and CREATE and DROP on its
+// database. Without the variable the test is skipped.
+func TestBinlogDumpIntegration(t *testing.T) {
+	dsns := strings.Fields(os.Getenv("MYSQL_MCP_TEST_DSN"))
+	if len(dsns) == 0 {
+		t.Skip("MYSQL_MCP_TEST_DSN is not set")
+	}
+	for i, dsn := range dsns {
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			t.Fatalf("DSN %d: %v", i+1, err)
+		}
+		t.Run(fmt.Sprintf("%d %s tls=%s", i+1, cfg.User, cmp.Or(cfg.TLSConfig, "false")), func(t *testing.T) {
+			connector, err := mysql.NewConnector(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db := sql.OpenDB(connector)
+			defer db.Close()
+			ctx := t.Context()
+
+			var checksum string
+			if err := db.QueryRowContext(ctx, "SELECT @@global.binlog_checksum").Scan(&checksum); err != nil {
+				t.Fatalf("failed to read binlog_checksum: %v", err)
+			}
+			var file string
+			var position uint32
+			for _, statement := range []string{"SHOW BINARY LOG STATUS", "SHOW MASTER STATUS"} {
+				rows, err := db.QueryContext(ctx, statement)
+				if err != nil {
+					continue
+				}
+				_, results, err := scanRowMaps(rows)
+				rows.Close()
+				if err == nil && len(results) > 0 {
+					file = rowText(results[0], "File")
+					p, _ := strconv.ParseUint(rowText(results[0], "Position"), 10, 32)
+					position = uint32(p)
+					break
+				}
+			}
+			if file == "" {
+				t.Fatal("failed to read the binlog position")
+			}
+
+			c, err := dialBinlog(cfg)
+			if err != nil {
+				t.Fatalf("dialBinlog() error = %v", err)
+			}
+			defer c.Close()
+			serverID := 1<<30 + uint32(time.Now().UnixNano()%(1<<30))
+			if err := c.startDump(file, position, serverID, strings.EqualFold(checksum, "CRC32")); err != nil {
+				t.Fatalf("startDump() error = %v", err)
+			}
+
+			table := fmt.Sprintf("binlog_test_%d", time.Now().UnixNano())
+			if _, err := db.ExecContext(ctx, "CREATE TABLE "+table+" (id INT PRIMARY KEY, name VARCHAR(20))"); err != nil {
+				t.Fatal(err)
+			}
+			defer db.ExecContext(context.Background(), "DROP TABLE "+table)
+			if _, err := db.ExecContext(ctx, "INSERT INTO "+table+" VALUES (1, 'one')"); err != nil {
+				t.Fatal(err)
+			}
+			c.conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+			for {
+				event, err := c.readEvent()
+				if err != nil {
+					t.Fatalf("readEvent() error = %v", err)
+				}
+				if event.Table.Table != table {
+					continue
+				}
+				if want := [][]any{{int64(1), "one"}}; event.Kind != "insert" || !reflect.DeepEqual(event.Rows, want) {
+					t.Errorf("readEvent() = %s %#v, want insert %#v", event.Kind, event.Rows, want)
+				}
+				return
+			}
+		})
+	}
+}
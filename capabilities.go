@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// serverFeature is an SQL feature whose availability depends on the server
+// flavor and version.
+type serverFeature struct {
+	name      string
+	syntax    string
+	requires  string
+	supported func(serverInfo) bool
+	// flavorSpecific marks extensions of one flavor that most servers
+	// lack, which are not worth warning about.
+	flavorSpecific bool
+}
+
+var serverFeatures = []serverFeature{
+	{"common table expressions", "WITH [RECURSIVE] ... AS (...)", "MySQL 8.0+, MariaDB 10.2.2+, TiDB 5.1+", serverInfo.supportsCTEs, false},
+	{"window functions", "ROW_NUMBER() OVER (...)", "MySQL 8.0+, MariaDB 10.2+, TiDB 3.0+", serverInfo.supportsWindowFunctions, false},
+	{"enforced CHECK constraints", "CONSTRAINT ... CHECK (...)", "MySQL 8.0.16+, MariaDB 10.2.1+, TiDB 7.2+", serverInfo.supportsCheckConstraints, false},
+	{"invisible indexes", "ALTER TABLE ... ALTER INDEX ... INVISIBLE", "MySQL 8.0+, TiDB 8.0+", serverInfo.supportsInvisibleIndexes, false},
+	{"JSON_TABLE", "JSON_TABLE(doc, '$[*]' COLUMNS (...))", "MySQL 8.0.4+, MariaDB 10.6+", serverInfo.supportsJSONTable, false},
+	{"EXPLAIN ANALYZE", "EXPLAIN ANALYZE SELECT ...", "MySQL 8.0.18+, TiDB", serverInfo.supportsExplainAnalyze, false},
+	{"RETURNING", "INSERT/DELETE ... RETURNING ...", "MariaDB 10.5+ (DELETE 10.0.5+)", func(s serverInfo) bool { return s.supportsReturning("INSERT") }, true},
+	{"sequences", "CREATE SEQUENCE, NEXTVAL()", "MariaDB 10.3+, TiDB 4.0+", serverInfo.supportsSequences, true},
+	{"resource groups", "SET RESOURCE GROUP name", "MySQL 8.0.3+, TiDB 7.1+", serverInfo.supportsResourceGroups, true},
+	{"VECTOR type", "VECTOR(n)", "MySQL 9.0+, MariaDB 11.7+, TiDB 8.4+", serverInfo.supportsVectors, true},
+}
+
+// ServerCapability reports whether the connected server has a feature.
+type ServerCapability struct {
+	Name      string `json:"name"`
+	Syntax    string `json:"syntax"`
+	Supported bool   `json:"supported"`
+	Requires  string `json:"requires"`
+}
+
+func (s serverInfo) capabilities() []ServerCapability {
+	capabilities := make([]ServerCapability, len(serverFeatures))
+	for i, f := range serverFeatures {
+		capabilities[i] = ServerCapability{Name: f.name, Syntax: f.syntax, Supported: f.supported(s), Requires: f.requires}
+	}
+	return capabilities
+}
+
+// missingFeatures lists the features the server lacks, other than
+// flavor-specific ones.
+func (s serverInfo) missingFeatures() []string {
+	var missing []string
+	for _, f := range serverFeatures {
+		if !f.flavorSpecific && !f.supported(s) {
+			missing = append(missing, f.name)
+		}
+	}
+	return missing
+}
+
+var (
+	cteStatementPattern    = regexp.MustCompile(`(?i)^WITH\s|\bWITH\s+(RECURSIVE\s+)?\w+(\s*\([^)]*\))?\s+AS\s*\(`)
+	windowFunctionPattern  = regexp.MustCompile(`(?i)\)\s*OVER\s*(\(|\w)`)
+	explainAnalyzePattern  = regexp.MustCompile(`(?i)^EXPLAIN\s+ANALYZE\b`)
+	unsupportedSyntaxHints = map[string]string{
+		"common table expressions": "rewrite them as derived tables (subqueries in FROM) or temporary tables",
+		"window functions":         "use a self-join or a correlated subquery instead",
+		"EXPLAIN ANALYZE":          "use plain EXPLAIN for the estimated plan",
+	}
+)
+
+// unsupportedSyntax returns an explanation if query uses syntax the server
+// does not have, so execute_query can say so instead of passing on a bare
+// syntax error.
+func unsupportedSyntax(server serverInfo, query string) string {
+	sanitized := sanitizeStatement(query)
+	var feature string
+	switch {
+	case !server.supportsCTEs() && cteStatementPattern.MatchString(sanitized):
+		feature = "common table expressions"
+	case !server.supportsWindowFunctions() && windowFunctionPattern.MatchString(sanitized):
+		feature = "window functions"
+	case !server.supportsExplainAnalyze() && explainAnalyzePattern.MatchString(sanitized):
+		feature = "EXPLAIN ANALYZE"
+	default:
+		return ""
+	}
+	for _, f := range serverFeatures {
+		if f.name == feature {
+			return fmt.Sprintf("This server (%s) has no %s (%s); %s.", server, feature, f.requires, unsupportedSyntaxHints[feature])
+		}
+	}
+	return ""
+}
+
+func ServerCapabilities(ctx context.Context, req *mcp.CallToolRequest, args struct{}) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	server := serverInfoFor(ctx, db)
+	capabilities := server.capabilities()
+	resultText := fmt.Sprintf("Server: %s\nVersion string: %s\n\n", server, server.Version)
+	for _, c := range capabilities {
+		status := "yes"
+		if !c.Supported {
+			status = "no (needs " + c.Requires + ")"
+		}
+		resultText += fmt.Sprintf("%-28s %s\n", c.Name+":", status)
+	}
+	if missing := server.missingFeatures(); len(missing) > 0 {
+		resultText += fmt.Sprintf("\nDo not write SQL using %s against this server.\n", strings.Join(missing, ", "))
+	}
+	if server.Vitess {
+		resultText += "\nBehind vtgate, queries the MySQL version supports may still be refused if they cannot be planned across shards.\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"flavor":        server.Flavor(),
+		"version":       server.Version,
+		"auroraVersion": server.AuroraVersion,
+		"capabilities":  capabilities,
+	}, nil
+}
@@ -0,0 +1,823 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// cdcBufferSize is how many undelivered changes a subscription keeps;
+	// older ones are dropped.
+	cdcBufferSize = 1000
+	cdcRetryDelay = 5 * time.Second
+)
+
+// cdcServerID is the server_id the binlog stream registers with, set with
+// -cdc-server-id. It must differ from the server's and every replica's:
+// the server drops an existing dump connection with the same id. Zero
+// picks a random one.
+var cdcServerID uint
+
+// CDCChange is one changed row delivered to a subscription. Updates carry
+// both images, inserts only After and deletes only Before.
+type CDCChange struct {
+	Subscription string         `json:"subscription"`
+	Type         string         `json:"type"`
+	Database     string         `json:"database"`
+	Table        string         `json:"table"`
+	Time         string         `json:"time"`
+	Position     string         `json:"position"`
+	Before       map[string]any `json:"before,omitempty"`
+	After        map[string]any `json:"after,omitempty"`
+}
+
+type cdcSubscription struct {
+	id       string
+	database string
+	tables   []string
+	events   []string
+	filters  []Filter
+	// session receives each change as a log notification; nil for
+	// subscriptions that are only polled.
+	session *mcp.ServerSession
+	created time.Time
+
+	mu        sync.Mutex
+	pending   []CDCChange
+	dropped   int
+	delivered int
+	wake      chan struct{}
+}
+
+// matches reports whether a change belongs to the subscription. Filters
+// apply to the row as it is after an insert or update and before a delete.
+func (s *cdcSubscription) matches(change CDCChange) bool {
+	if change.Database != s.database {
+		return false
+	}
+	if len(s.tables) > 0 && !slices.Contains(s.tables, change.Table) {
+		return false
+	}
+	if len(s.events) > 0 && !slices.Contains(s.events, change.Type) {
+		return false
+	}
+	row := change.After
+	if change.Type == "delete" {
+		row = change.Before
+	}
+	for _, f := range s.filters {
+		if !filterMatches(f, row) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *cdcSubscription) deliver(change CDCChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.pending) == cdcBufferSize {
+		s.pending = s.pending[1:]
+		s.dropped++
+	}
+	s.pending = append(s.pending, change)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// cdcColumn is what decoding a binlog row needs to know about a column
+// beyond the table map event: its name, signedness and ENUM or SET values.
+type cdcColumn struct {
+	name     string
+	unsigned bool
+	bits     int
+	values   []string
+	set      bool
+}
+
+// cdcStream reads the binlog for all subscriptions over one replication
+// connection, reconnecting from the last commit after errors.
+type cdcStream struct {
+	cfg      *mysql.Config
+	serverID uint32
+	checksum bool
+	stop     chan struct{}
+
+	mu       sync.Mutex
+	conn     *binlogConn
+	file     string
+	position uint32
+	lastErr  error
+	columns  map[string][]cdcColumn
+	// dispatchedFile and dispatchedPosition are where the last rows event
+	// handed to subscriptions ends. A reconnect resumes from the last
+	// commit, so the rows events of a transaction cut off are read again
+	// and skipped up to there.
+	dispatchedFile     string
+	dispatchedPosition uint32
+}
+
+var cdc = struct {
+	sync.Mutex
+	subscriptions map[string]*cdcSubscription
+	nextID        int
+	stream        *cdcStream
+}{subscriptions: make(map[string]*cdcSubscription)}
+
+// startCDCStream checks that the server writes a row-based binlog and
+// starts streaming from its current end. It returns warnings about
+// settings that make the changes incomplete.
+func startCDCStream(ctx context.Context) (*cdcStream, []string, error) {
+	db := currentDB()
+	dbConfig := currentDBConfig()
+	server := serverInfoFor(ctx, db)
+	if server.TiDB || server.Vitess || server.DoltVersion != "" {
+		return nil, nil, fmt.Errorf("%s does not serve the MySQL binlog to replicas; use its own change feed", server)
+	}
+
+	vars := make(map[string]string)
+	for _, name := range []string{"log_bin", "binlog_format", "binlog_row_image", "binlog_checksum", "server_id"} {
+		var value string
+		if err := db.QueryRowContext(ctx, "SELECT @@GLOBAL."+name).Scan(&value); err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		vars[name] = value
+	}
+	if vars["log_bin"] != "1" && !strings.EqualFold(vars["log_bin"], "ON") {
+		return nil, nil, errors.New("binary logging is disabled; change data capture reads row changes from the binlog")
+	}
+	if !strings.EqualFold(vars["binlog_format"], "ROW") {
+		return nil, nil, fmt.Errorf("binlog_format is %s; change data capture needs ROW", vars["binlog_format"])
+	}
+	// The variable is missing before MySQL 8.0.20 and on MariaDB, which do
+	// not compress transactions.
+	var compression string
+	if db.QueryRowContext(ctx, "SELECT @@GLOBAL.binlog_transaction_compression").Scan(&compression) == nil &&
+		(compression == "1" || strings.EqualFold(compression, "ON")) {
+		return nil, nil, errors.New("binlog_transaction_compression is ON; change data capture cannot decode compressed transactions")
+	}
+	var warnings []string
+	if !strings.EqualFold(vars["binlog_row_image"], "FULL") {
+		warnings = append(warnings, fmt.Sprintf("binlog_row_image is %s, so changes carry only some columns (the others are null) and filters on the missing ones do not match", vars["binlog_row_image"]))
+	}
+
+	serverID := uint32(cdcServerID)
+	if serverID == 0 {
+		serverID = 1<<30 + rand.Uint32N(1<<30)
+		warnings = append(warnings, fmt.Sprintf("no -cdc-server-id is set, so the stream uses the random server_id %d; set one no replica uses", serverID))
+	}
+	if vars["server_id"] == strconv.FormatUint(uint64(serverID), 10) {
+		return nil, nil, fmt.Errorf("-cdc-server-id %d is the server's own server_id", serverID)
+	}
+
+	var file string
+	var position uint32
+	for _, statement := range []string{"SHOW BINARY LOG STATUS", "SHOW MASTER STATUS"} {
+		rows, err := db.QueryContext(ctx, statement)
+		if err != nil {
+			continue
+		}
+		_, results, err := scanRowMaps(rows)
+		rows.Close()
+		if err != nil || len(results) == 0 {
+			continue
+		}
+		file = rowText(results[0], "File")
+		p, _ := strconv.ParseUint(rowText(results[0], "Position"), 10, 32)
+		position = uint32(p)
+		break
+	}
+	if file == "" {
+		return nil, nil, errors.New("failed to read the binlog position; SHOW BINARY LOG STATUS needs the REPLICATION CLIENT privilege")
+	}
+
+	s := &cdcStream{
+		cfg:      dbConfig.Clone(),
+		serverID: serverID,
+		checksum: strings.EqualFold(vars["binlog_checksum"], "CRC32"),
+		stop:     make(chan struct{}),
+		file:     file,
+		position: position,
+		columns:  make(map[string][]cdcColumn),
+	}
+	conn, err := s.connect()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start the binlog dump (the user needs the REPLICATION SLAVE privilege): %w", err)
+	}
+	go s.run(conn)
+	return s, warnings, nil
+}
+
+func (s *cdcStream) connect() (*binlogConn, error) {
+	s.mu.Lock()
+	file, position := s.file, s.position
+	s.mu.Unlock()
+	conn, err := dialBinlog(s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.startDump(file, position, s.serverID, s.checksum); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.stop:
+		conn.Close()
+		return nil, errors.New("the binlog stream is closed")
+	default:
+	}
+	s.conn = conn
+	return conn, nil
+}
+
+func (s *cdcStream) run(conn *binlogConn) {
+	for {
+		err := s.read(conn)
+		conn.Close()
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		s.mu.Lock()
+		s.file, s.position, s.lastErr = conn.file, conn.position, err
+		s.mu.Unlock()
+		slog.Warn("binlog stream interrupted, reconnecting", "file", conn.file, "position", conn.position, "err", err)
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-time.After(cdcRetryDelay):
+			}
+			if conn, err = s.connect(); err == nil {
+				break
+			}
+			s.mu.Lock()
+			s.lastErr = err
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *cdcStream) read(conn *binlogConn) error {
+	for {
+		event, err := conn.readEvent()
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		s.file, s.position, s.lastErr = conn.file, event.Position, nil
+		replayed := conn.file == s.dispatchedFile && event.Position <= s.dispatchedPosition
+		if !replayed {
+			s.dispatchedFile, s.dispatchedPosition = conn.file, event.Position
+		}
+		s.mu.Unlock()
+		if !replayed {
+			s.dispatch(event, conn.file)
+		}
+	}
+}
+
+func (s *cdcStream) close() {
+	close(s.stop)
+	s.mu.Lock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	s.mu.Unlock()
+}
+
+// status describes where the stream is and its last error, if any.
+func (s *cdcStream) status() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fmt.Sprintf("%s:%d", s.file, s.position), s.lastErr
+}
+
+// dispatch turns a rows event into changes and hands them to the matching
+// subscriptions.
+func (s *cdcStream) dispatch(event *binlogRowsEvent, file string) {
+	columns := s.tableColumns(event.Table.Database, event.Table.Table, len(event.Table.types))
+	rowMap := func(values []any) map[string]any {
+		row := make(map[string]any, len(values))
+		for i, v := range values {
+			if i < len(columns) {
+				row[columns[i].name] = columns[i].convert(v)
+			}
+		}
+		return row
+	}
+
+	var changes []CDCChange
+	step := 1
+	if event.Kind == "update" {
+		step = 2
+	}
+	for i := 0; i+step <= len(event.Rows); i += step {
+		change := CDCChange{
+			Type:     event.Kind,
+			Database: event.Table.Database,
+			Table:    event.Table.Table,
+			Time:     event.Timestamp.Format(time.RFC3339),
+			Position: fmt.Sprintf("%s:%d", file, event.Position),
+		}
+		switch event.Kind {
+		case "insert":
+			change.After = rowMap(event.Rows[i])
+		case "delete":
+			change.Before = rowMap(event.Rows[i])
+		case "update":
+			change.Before, change.After = rowMap(event.Rows[i]), rowMap(event.Rows[i+1])
+		}
+		changes = append(changes, change)
+	}
+
+	type notification struct {
+		session *mcp.ServerSession
+		change  CDCChange
+	}
+	var notifications []notification
+	cdc.Lock()
+	for _, sub := range cdc.subscriptions {
+		for _, change := range changes {
+			if !sub.matches(change) {
+				continue
+			}
+			change.Subscription = sub.id
+			sub.deliver(change)
+			if sub.session != nil {
+				notifications = append(notifications, notification{sub.session, change})
+			}
+		}
+	}
+	cdc.Unlock()
+
+	for _, n := range notifications {
+		n.session.Log(context.Background(), &mcp.LoggingMessageParams{
+			Level:  "info",
+			Logger: "cdc",
+			Data:   n.change,
+		})
+	}
+}
+
+// tableColumns returns the columns of a table in binlog order. Columns
+// that cannot be looked up, for example of a table dropped since, are
+// named @1, @2, ... as mysqlbinlog does.
+func (s *cdcStream) tableColumns(database, table string, count int) []cdcColumn {
+	db, release := holdDB()
+	defer release()
+	key := database + "." + table
+	s.mu.Lock()
+	columns, ok := s.columns[key]
+	s.mu.Unlock()
+	if ok && len(columns) == count {
+		return columns
+	}
+
+	columns = nil
+	if db != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		rows, err := db.QueryContext(ctx, `
+			SELECT COLUMN_NAME, COLUMN_TYPE
+			FROM information_schema.COLUMNS
+			WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+			ORDER BY ORDINAL_POSITION
+		`, database, table)
+		if err == nil {
+			for rows.Next() {
+				var name, columnType string
+				if rows.Scan(&name, &columnType) == nil {
+					columns = append(columns, parseCDCColumn(name, columnType))
+				}
+			}
+			rows.Close()
+		}
+		cancel()
+	}
+	if len(columns) != count {
+		columns = make([]cdcColumn, count)
+		for i := range columns {
+			columns[i].name = fmt.Sprintf("@%d", i+1)
+		}
+	}
+	s.mu.Lock()
+	s.columns[key] = columns
+	s.mu.Unlock()
+	return columns
+}
+
+func parseCDCColumn(name, columnType string) cdcColumn {
+	c := cdcColumn{name: name}
+	lower := strings.ToLower(columnType)
+	base, _, _ := strings.Cut(lower, "(")
+	c.unsigned = strings.Contains(lower, " unsigned")
+	c.bits = map[string]int{"tinyint": 8, "smallint": 16, "mediumint": 24, "int": 32, "bigint": 64}[base]
+	if base == "enum" || base == "set" {
+		c.set = base == "set"
+		c.values = columnTypeValues(columnType)
+	}
+	return c
+}
+
+// convert maps a decoded binlog value to what a query would return:
+// unsigned integers and ENUM and SET members by name.
+func (c cdcColumn) convert(v any) any {
+	n, ok := v.(int64)
+	if !ok {
+		return v
+	}
+	switch {
+	case c.values != nil && c.set:
+		var members []string
+		for i, member := range c.values {
+			if n&(1<<i) != 0 {
+				members = append(members, member)
+			}
+		}
+		return strings.Join(members, ",")
+	case c.values != nil:
+		if n >= 1 && int(n) <= len(c.values) {
+			return c.values[n-1]
+		}
+		return ""
+	case c.unsigned && n < 0 && c.bits == 64:
+		return uint64(n)
+	case c.unsigned && n < 0 && c.bits > 0:
+		return n + 1<<c.bits
+	}
+	return v
+}
+
+// filterMatches evaluates a filter against a changed row the way the
+// server would: values are compared as numbers when both are numeric and
+// otherwise as case-insensitive text, and NULL matches only IS NULL.
+func filterMatches(f Filter, row map[string]any) bool {
+	v := row[f.Column]
+	op := strings.ToUpper(strings.Join(strings.Fields(f.Op), " "))
+	switch op {
+	case "IS NULL":
+		return v == nil
+	case "IS NOT NULL":
+		return v != nil
+	}
+	if v == nil {
+		return false
+	}
+	switch op {
+	case "", "=":
+		return compareValues(v, f.Value) == 0
+	case "!=", "<>":
+		return compareValues(v, f.Value) != 0
+	case "<":
+		return compareValues(v, f.Value) < 0
+	case "<=":
+		return compareValues(v, f.Value) <= 0
+	case ">":
+		return compareValues(v, f.Value) > 0
+	case ">=":
+		return compareValues(v, f.Value) >= 0
+	case "IN", "NOT IN":
+		values, _ := f.Value.([]any)
+		found := false
+		for _, candidate := range values {
+			if compareValues(v, candidate) == 0 {
+				found = true
+			}
+		}
+		return found == (op == "IN")
+	case "BETWEEN":
+		values, _ := f.Value.([]any)
+		return len(values) == 2 && compareValues(v, values[0]) >= 0 && compareValues(v, values[1]) <= 0
+	case "LIKE", "NOT LIKE":
+		return likePattern(fmt.Sprint(f.Value)).MatchString(fmt.Sprint(v)) == (op == "LIKE")
+	}
+	return false
+}
+
+func compareValues(a, b any) int {
+	as, bs := fmt.Sprint(a), fmt.Sprint(b)
+	af, aErr := strconv.ParseFloat(as, 64)
+	bf, bErr := strconv.ParseFloat(bs, 64)
+	if aErr == nil && bErr == nil {
+		switch {
+		case af < bf:
+			return -1
+		case af > bf:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(as), strings.ToLower(bs))
+}
+
+// likePattern translates a LIKE pattern, with % and _ wildcards and
+// backslash escapes, to a case-insensitive regular expression.
+func likePattern(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("(?is)^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '%':
+			b.WriteString(".*")
+		case c == '_':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
+
+type CDCSubscribeParams struct {
+	Database string   `json:"database"`
+	Tables   []string `json:"tables,omitempty"`
+	Events   []string `json:"events,omitempty"`
+	Filters  []Filter `json:"filters,omitempty"`
+	PollOnly bool     `json:"poll_only,omitempty"`
+}
+
+func CDCSubscribe(ctx context.Context, req *mcp.CallToolRequest, args CDCSubscribeParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	if args.Database == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "database is required"},
+			},
+		}, nil, nil
+	}
+	var events []string
+	for _, e := range args.Events {
+		e = strings.ToLower(e)
+		if e != "insert" && e != "update" && e != "delete" {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Unknown event %q (use insert, update or delete)", e)},
+				},
+			}, nil, nil
+		}
+		events = append(events, e)
+	}
+	if len(args.Filters) > 0 && len(args.Tables) != 1 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "filters need exactly one table in tables"},
+			},
+		}, nil, nil
+	}
+	for _, table := range args.Tables {
+		tableCols, err := tableColumns(ctx, db, args.Database, table)
+		if err == nil && len(tableCols) == 0 {
+			err = fmt.Errorf("table %s.%s does not exist", args.Database, table)
+		}
+		if err == nil && len(args.Filters) > 0 {
+			// Validates columns and operators the same way as for queries.
+			if _, _, err = buildWhere(args.Filters, tableCols); err != nil {
+				err = fmt.Errorf("invalid filters: %w", err)
+			}
+		}
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: err.Error()},
+				},
+			}, nil, nil
+		}
+	}
+
+	cdc.Lock()
+	defer cdc.Unlock()
+	var warnings []string
+	if cdc.stream == nil {
+		stream, streamWarnings, err := startCDCStream(ctx)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Cannot capture changes: %v", err)},
+				},
+			}, nil, nil
+		}
+		cdc.stream, warnings = stream, streamWarnings
+	}
+	cdc.nextID++
+	sub := &cdcSubscription{
+		id:       fmt.Sprintf("cdc-%d", cdc.nextID),
+		database: args.Database,
+		tables:   args.Tables,
+		events:   events,
+		filters:  args.Filters,
+		created:  time.Now(),
+		wake:     make(chan struct{}, 1),
+	}
+	if !args.PollOnly {
+		sub.session = req.Session
+	}
+	cdc.subscriptions[sub.id] = sub
+	position, _ := cdc.stream.status()
+
+	scope := "all tables of " + args.Database
+	if len(args.Tables) > 0 {
+		scope = args.Database + "." + strings.Join(args.Tables, ", "+args.Database+".")
+	}
+	resultText := fmt.Sprintf("Subscription %s: changes to %s from binlog position %s on.", sub.id, scope, position)
+	if sub.session != nil {
+		resultText += " Each change is sent as a log notification (logger \"cdc\") and kept for cdc_poll."
+	} else {
+		resultText += " Read them with cdc_poll."
+	}
+	resultText += fmt.Sprintf(" Up to %d unread changes are kept; end it with cdc_unsubscribe.\n", cdcBufferSize)
+	for _, w := range warnings {
+		resultText += "Warning: " + w + "\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"subscription": sub.id,
+		"position":     position,
+		"warnings":     warnings,
+	}, nil
+}
+
+type CDCPollParams struct {
+	ID          string `json:"id,omitempty"`
+	Max         int    `json:"max,omitempty"`
+	WaitSeconds int    `json:"wait_seconds,omitempty"`
+}
+
+func CDCPoll(ctx context.Context, req *mcp.CallToolRequest, args CDCPollParams) (*mcp.CallToolResult, any, error) {
+	cdc.Lock()
+	stream := cdc.stream
+	if args.ID == "" {
+		resultText := fmt.Sprintf("%d subscriptions\n", len(cdc.subscriptions))
+		var subscriptions []map[string]any
+		for _, id := range sortedKeys(cdc.subscriptions) {
+			sub := cdc.subscriptions[id]
+			sub.mu.Lock()
+			pending := len(sub.pending)
+			sub.mu.Unlock()
+			resultText += fmt.Sprintf("  %s: %s %v, %d unread, since %s\n", id, sub.database, sub.tables, pending, sub.created.Format(time.RFC3339))
+			subscriptions = append(subscriptions, map[string]any{
+				"id":       id,
+				"database": sub.database,
+				"tables":   sub.tables,
+				"unread":   pending,
+			})
+		}
+		cdc.Unlock()
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, map[string]any{
+			"subscriptions": subscriptions,
+		}, nil
+	}
+	sub, ok := cdc.subscriptions[args.ID]
+	cdc.Unlock()
+	if !ok {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("No subscription %q; cdc_poll without id lists them", args.ID)},
+			},
+		}, nil, nil
+	}
+	limit := args.Max
+	if limit <= 0 {
+		limit = 100
+	}
+
+	if wait := min(args.WaitSeconds, 60); wait > 0 {
+		sub.mu.Lock()
+		empty := len(sub.pending) == 0
+		sub.mu.Unlock()
+		if empty {
+			select {
+			case <-sub.wake:
+			case <-time.After(time.Duration(wait) * time.Second):
+			case <-ctx.Done():
+			}
+		}
+	}
+
+	sub.mu.Lock()
+	n := min(limit, len(sub.pending))
+	changes := append([]CDCChange(nil), sub.pending[:n]...)
+	sub.pending = sub.pending[n:]
+	remaining, dropped := len(sub.pending), sub.dropped
+	sub.dropped = 0
+	sub.delivered += n
+	sub.mu.Unlock()
+
+	position, streamErr := stream.status()
+	resultText := fmt.Sprintf("%d changes (%d more unread), binlog position %s\n", len(changes), remaining, position)
+	if dropped > 0 {
+		resultText += fmt.Sprintf("Warning: %d older changes were dropped because they were not polled in time.\n", dropped)
+	}
+	if streamErr != nil {
+		resultText += fmt.Sprintf("Warning: the binlog stream is reconnecting after: %v\n", streamErr)
+	}
+	for _, c := range changes {
+		resultText += fmt.Sprintf("\n%s %s %s.%s at %s\n", c.Time, strings.ToUpper(c.Type), c.Database, c.Table, c.Position)
+		if c.Before != nil {
+			resultText += "  before: " + formatChangeRow(c.Before) + "\n"
+		}
+		if c.After != nil {
+			resultText += "  after:  " + formatChangeRow(c.After) + "\n"
+		}
+	}
+
+	result := map[string]any{
+		"changes":   changes,
+		"remaining": remaining,
+		"dropped":   dropped,
+		"position":  position,
+	}
+	if streamErr != nil {
+		result["streamError"] = streamErr.Error()
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, result, nil
+}
+
+func formatChangeRow(row map[string]any) string {
+	var parts []string
+	for _, column := range sortedKeys(row) {
+		parts = append(parts, fmt.Sprintf("%s=%s", column, rowText(row, column)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+type CDCUnsubscribeParams struct {
+	ID string `json:"id"`
+}
+
+func CDCUnsubscribe(ctx context.Context, req *mcp.CallToolRequest, args CDCUnsubscribeParams) (*mcp.CallToolResult, any, error) {
+	cdc.Lock()
+	defer cdc.Unlock()
+	sub, ok := cdc.subscriptions[args.ID]
+	if !ok {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("No subscription %q", args.ID)},
+			},
+		}, nil, nil
+	}
+	delete(cdc.subscriptions, args.ID)
+	sub.mu.Lock()
+	resultText := fmt.Sprintf("Ended subscription %s after %d delivered changes", sub.id, sub.delivered)
+	sub.mu.Unlock()
+	if len(cdc.subscriptions) == 0 && cdc.stream != nil {
+		// The last subscription closes the replication connection.
+		cdc.stream.close()
+		cdc.stream = nil
+		resultText += "; the binlog stream is closed"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"io"
+	"slices"
+	"testing"
+)
+
+func TestCDCStreamSkipsReplayedRows(t *testing.T) {
+	sub := &cdcSubscription{id: "test", database: "db"}
+	cdc.Lock()
+	cdc.subscriptions[sub.id] = sub
+	cdc.Unlock()
+	t.Cleanup(func() {
+		cdc.Lock()
+		delete(cdc.subscriptions, sub.id)
+		cdc.Unlock()
+	})
+	s := &cdcStream{columns: map[string][]cdcColumn{"db.t": {{name: "id"}, {name: "name"}}}}
+
+	// The first connection drops in the middle of the second transaction,
+	// after its update was delivered.
+	conns := []*binlogConn{
+		binlogStream(
+			binlogEvent(binlogRotateEvent, 0, rotateEvent("binlog.000001", 4), false),
+			binlogEvent(binlogTableMapEvent, 300, testTableMap, false),
+			binlogEvent(binlogWriteRowsEvent, 400, testInsertOne, false),
+			binlogEvent(binlogXIDEvent, 431, []byte{9, 0, 0, 0, 0, 0, 0, 0}, false),
+			binlogEvent(binlogTableMapEvent, 480, testTableMap, false),
+			binlogEvent(binlogUpdateRowsEvent, 500, testUpdateUno, false),
+		),
+		// The reconnect resumes from the commit before it.
+		binlogStream(
+			binlogEvent(binlogRotateEvent, 0, rotateEvent("binlog.000001", 431), false),
+			binlogEvent(binlogTableMapEvent, 480, testTableMap, false),
+			binlogEvent(binlogUpdateRowsEvent, 500, testUpdateUno, false),
+			binlogEvent(binlogDeleteRowsEvent, 560, testDeleteNull, false),
+			binlogEvent(binlogXIDEvent, 591, []byte{10, 0, 0, 0, 0, 0, 0, 0}, false),
+		),
+		// Positions start over in the next file.
+		binlogStream(
+			binlogEvent(binlogRotateEvent, 0, rotateEvent("binlog.000002", 4), false),
+			binlogEvent(binlogTableMapEvent, 300, testTableMap, false),
+			binlogEvent(binlogWriteRowsEvent, 400, testInsertOne, false),
+		),
+	}
+	resume := []uint32{431, 591, 4}
+	for i, conn := range conns {
+		if err := s.read(conn); err != io.EOF {
+			t.Fatalf("connection %d: read() = %v, want EOF", i+1, err)
+		}
+		if conn.position != resume[i] {
+			t.Errorf("connection %d: resume position %d, want %d", i+1, conn.position, resume[i])
+		}
+	}
+
+	var got []string
+	for _, change := range sub.pending {
+		got = append(got, change.Type+" "+change.Position)
+	}
+	want := []string{"insert binlog.000001:400", "update binlog.000001:500", "delete binlog.000001:560", "insert binlog.000002:400"}
+	if !slices.Equal(got, want) {
+		t.Errorf("delivered %q, want %q", got, want)
+	}
+}
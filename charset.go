@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultCharsetSampleRows = 1000
+	maxCharsetSampleRows     = 100000
+	// charsetExamples is how many mis-encoded values are shown per column.
+	charsetExamples = 3
+)
+
+type DiagnoseCharsetParams struct {
+	Database   string `json:"database"`
+	Table      string `json:"table"`
+	Column     string `json:"column,omitempty"`
+	SampleRows int    `json:"sample_rows,omitempty"`
+}
+
+// CharsetExample is a mis-encoded value as the server returns it and as
+// it should read.
+type CharsetExample struct {
+	Stored   string `json:"stored"`
+	Repaired string `json:"repaired"`
+}
+
+// ColumnCharset is what diagnose_charset found in one column's non-ASCII
+// values.
+type ColumnCharset struct {
+	Column    string `json:"column"`
+	Type      string `json:"type"`
+	Charset   string `json:"charset"`
+	Collation string `json:"collation"`
+	// Sampled is the number of non-ASCII values read.
+	Sampled int `json:"sampled"`
+	// UTF8 counts correctly encoded values of a UTF-8 column.
+	UTF8 int `json:"utf8,omitempty"`
+	// DoubleEncoded counts values of a UTF-8 column whose UTF-8 bytes
+	// were read as latin1 and encoded again, such as "CafÃ©".
+	DoubleEncoded int `json:"doubleEncoded,omitempty"`
+	// UTF8InLatin1 counts values of a latin1 column that hold UTF-8 bytes.
+	UTF8InLatin1 int `json:"utf8InLatin1,omitempty"`
+	// Latin1 counts values of a latin1 column that are latin1 text.
+	Latin1 int `json:"latin1,omitempty"`
+	// Invalid counts values that are not valid in the declared charset.
+	Invalid int `json:"invalid,omitempty"`
+	// Replaced counts values with U+FFFD, a character lost in an earlier
+	// conversion.
+	Replaced  int              `json:"replaced,omitempty"`
+	Examples  []CharsetExample `json:"examples,omitempty"`
+	Diagnosis string           `json:"diagnosis"`
+	// Fix holds the statements that repair the column, in order. They are
+	// never executed by the tool.
+	Fix []string `json:"fix,omitempty"`
+}
+
+// cp1252 maps the characters MySQL's latin1, which is Windows-1252, has
+// in place of the C1 controls to their bytes.
+var cp1252 = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// latin1Bytes encodes s in MySQL's latin1, reporting false when s has a
+// character latin1 cannot hold.
+func latin1Bytes(s string) ([]byte, bool) {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if c, ok := cp1252[r]; ok {
+			b = append(b, c)
+		} else if r < 0x100 {
+			b = append(b, byte(r))
+		} else {
+			return nil, false
+		}
+	}
+	return b, true
+}
+
+// latin1Text decodes MySQL latin1 bytes.
+func latin1Text(b []byte) string {
+	var sb strings.Builder
+	for _, c := range b {
+		r := rune(c)
+		for cr, cb := range cp1252 {
+			if cb == c {
+				r = cr
+				break
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// isMultibyteUTF8 reports whether b is valid UTF-8 with at least one
+// character outside ASCII.
+func isMultibyteUTF8(b []byte) bool {
+	return utf8.Valid(b) && utf8.RuneCount(b) < len(b)
+}
+
+// classifyCharsetValue counts one non-ASCII value, given as its stored
+// bytes, in c.
+func classifyCharsetValue(c *ColumnCharset, b []byte) {
+	c.Sampled++
+	example := func(stored, repaired string) {
+		if len(c.Examples) < charsetExamples {
+			c.Examples = append(c.Examples, CharsetExample{Stored: truncateForLog(stored, 80), Repaired: truncateForLog(repaired, 80)})
+		}
+	}
+	switch charsetFamily(c.Charset) {
+	case "utf8":
+		if !utf8.Valid(b) {
+			c.Invalid++
+			return
+		}
+		s := string(b)
+		if strings.ContainsRune(s, utf8.RuneError) {
+			c.Replaced++
+		}
+		if lb, ok := latin1Bytes(s); ok && isMultibyteUTF8(lb) {
+			c.DoubleEncoded++
+			example(s, string(lb))
+			return
+		}
+		c.UTF8++
+	case "latin1":
+		if isMultibyteUTF8(b) {
+			c.UTF8InLatin1++
+			example(latin1Text(b), string(b))
+			return
+		}
+		c.Latin1++
+	}
+}
+
+// charsetFamily groups the charsets diagnose_charset understands: utf8
+// for utf8mb3 and utf8mb4, latin1, and "" for the others.
+func charsetFamily(charset string) string {
+	switch charset {
+	case "utf8", "utf8mb3", "utf8mb4":
+		return "utf8"
+	case "latin1":
+		return "latin1"
+	}
+	return ""
+}
+
+// binaryColumnType returns the binary type that holds a text column's
+// bytes unchanged, or "" for types without one, such as ENUM.
+func binaryColumnType(columnType string) string {
+	for _, t := range [][2]string{{"varchar", "varbinary"}, {"char", "binary"}, {"tinytext", "tinyblob"}, {"mediumtext", "mediumblob"}, {"longtext", "longblob"}, {"text", "blob"}} {
+		if rest, ok := strings.CutPrefix(columnType, t[0]); ok {
+			return t[1] + rest
+		}
+	}
+	return ""
+}
+
+// utf8mb4Collation returns the collation converted columns get: the
+// server's default for utf8mb4.
+func utf8mb4Collation(server serverInfo) string {
+	switch {
+	case server.TiDB:
+		return "utf8mb4_bin"
+	case server.MariaDB || !server.atLeast(8, 0, 0):
+		return "utf8mb4_unicode_ci"
+	}
+	return "utf8mb4_0900_ai_ci"
+}
+
+// charsetColumn is a text column as information_schema.COLUMNS lists it.
+type charsetColumn struct {
+	name, columnType, charset, collation, extra, comment string
+	nullable                                             bool
+	def                                                  sql.NullString
+}
+
+// definition renders the column for ALTER TABLE ... MODIFY with the given
+// type and character set, keeping its nullability, default and comment.
+func (c charsetColumn) definition(server serverInfo, columnType, charset string) string {
+	def := quoteIdentifier(c.name) + " " + columnType
+	if charset != "" {
+		def += " CHARACTER SET " + charset + " COLLATE " + utf8mb4Collation(server)
+	}
+	if !c.nullable {
+		def += " NOT NULL"
+	}
+	switch {
+	case !c.def.Valid || server.MariaDB && c.def.String == "NULL":
+	case strings.Contains(c.extra, "DEFAULT_GENERATED"):
+		def += " DEFAULT (" + c.def.String + ")"
+	case server.MariaDB:
+		// MariaDB lists defaults as SQL literals.
+		def += " DEFAULT " + c.def.String
+	default:
+		def += " DEFAULT " + quoteString(c.def.String)
+	}
+	if c.comment != "" {
+		def += " COMMENT " + quoteString(c.comment)
+	}
+	return def
+}
+
+// diagnoseCharset explains the counts in c and fills in the statements
+// that repair its column of table.
+func diagnoseCharset(c *ColumnCharset, col charsetColumn, table string, server serverInfo) {
+	alter := "ALTER TABLE " + table + " MODIFY "
+	// The repair reinterprets a value's latin1 encoding as UTF-8. The
+	// guard keeps it to values that survive both conversions intact,
+	// so correctly encoded text is left alone.
+	repair := func(charset string) []string {
+		name := quoteIdentifier(c.Column)
+		asLatin1 := fmt.Sprintf("CONVERT(%s USING latin1)", name)
+		fixed := fmt.Sprintf("CONVERT(CAST(%s AS BINARY) USING %s)", asLatin1, charset)
+		guard := fmt.Sprintf("LENGTH(%s) <> CHAR_LENGTH(%s) AND HEX(%s) = HEX(CONVERT(%s USING %s)) AND HEX(%s) = HEX(%s)", name, name, name, asLatin1, charset, fixed, asLatin1)
+		return []string{
+			fmt.Sprintf("-- Preview: SELECT %s AS stored, %s AS repaired FROM %s WHERE %s LIMIT 20;", name, fixed, table, guard),
+			fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s;", table, name, fixed, guard),
+		}
+	}
+
+	switch {
+	case c.Sampled == 0:
+		c.Diagnosis = "No non-ASCII values."
+	case charsetFamily(c.Charset) == "":
+		c.Diagnosis = fmt.Sprintf("Not analyzed: only UTF-8 and latin1 columns are, not %s.", c.Charset)
+	case c.Invalid > 0:
+		c.Diagnosis = fmt.Sprintf("%d values are not valid %s; they were written with the charset checks off and must be repaired by hand.", c.Invalid, c.Charset)
+	case c.DoubleEncoded > 0:
+		c.Diagnosis = "Double-encoded UTF-8: UTF-8 text was sent by a client declaring latin1 and encoded again."
+		c.Fix = repair(c.Charset)
+	case c.UTF8InLatin1 > 0 && c.Latin1 == 0:
+		c.Diagnosis = "UTF-8 bytes in a latin1 column: the column's charset is wrong, not its data. Converting through a binary type relabels the bytes without changing them."
+		if binary := binaryColumnType(col.columnType); binary != "" {
+			c.Fix = []string{
+				alter + col.definition(server, binary, "") + ";",
+				alter + col.definition(server, col.columnType, "utf8mb4") + ";",
+			}
+		}
+	case c.UTF8InLatin1 > 0:
+		c.Diagnosis = "A mix of UTF-8 bytes and latin1 text in a latin1 column. Converting the column to utf8mb4 turns the UTF-8 values into double-encoded text, which the UPDATE then repairs."
+		c.Fix = append([]string{alter + col.definition(server, col.columnType, "utf8mb4") + ";"}, repair("utf8mb4")...)
+	case c.Charset == "latin1":
+		c.Diagnosis = "latin1 text, as declared."
+	case c.Charset != "utf8mb4":
+		c.Diagnosis = fmt.Sprintf("Correct UTF-8, but %s cannot store characters outside the Basic Multilingual Plane, such as emoji.", c.Charset)
+	default:
+		c.Diagnosis = "Correct UTF-8."
+	}
+	if c.Replaced > 0 {
+		c.Diagnosis += fmt.Sprintf(" %d values contain U+FFFD, a character already lost in an earlier conversion that cannot be recovered.", c.Replaced)
+	}
+}
+
+func DiagnoseCharset(ctx context.Context, req *mcp.CallToolRequest, args DiagnoseCharsetParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	sampleRows := args.SampleRows
+	if sampleRows <= 0 {
+		sampleRows = defaultCharsetSampleRows
+	}
+	sampleRows = min(sampleRows, maxCharsetSampleRows)
+
+	var columns []charsetColumn
+	err := queryEach(ctx, db, `
+		SELECT COLUMN_NAME, COLUMN_TYPE, CHARACTER_SET_NAME, COLLATION_NAME, IS_NULLABLE = 'YES', COLUMN_DEFAULT, EXTRA, COLUMN_COMMENT
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND CHARACTER_SET_NAME IS NOT NULL
+		ORDER BY ORDINAL_POSITION
+	`, []any{args.Database, args.Table}, func(rows *sql.Rows) error {
+		var c charsetColumn
+		if err := rows.Scan(&c.name, &c.columnType, &c.charset, &c.collation, &c.nullable, &c.def, &c.extra, &c.comment); err != nil {
+			return err
+		}
+		if args.Column == "" || strings.EqualFold(c.name, args.Column) {
+			columns = append(columns, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read columns: %v", err)},
+			},
+		}, nil, nil
+	}
+	if len(columns) == 0 {
+		text := fmt.Sprintf("Table %s.%s has no text columns", args.Database, args.Table)
+		if args.Column != "" {
+			text = fmt.Sprintf("Table %s.%s has no text column %s", args.Database, args.Table, args.Column)
+		}
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, nil, nil
+	}
+
+	var client, connection, results sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT @@character_set_client, @@character_set_connection, @@character_set_results").Scan(&client, &connection, &results); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read session character sets: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	server := serverInfoFor(ctx, db)
+	table := qualifiedTable(args.Database, args.Table)
+	var found []ColumnCharset
+	for _, col := range columns {
+		c := ColumnCharset{Column: col.name, Type: col.columnType, Charset: col.charset, Collation: col.collation}
+		name := quoteIdentifier(col.name)
+		// CAST AS BINARY returns the stored bytes instead of converting
+		// them to the connection's charset, and the HEX pattern finds a
+		// byte of 0x80 or above.
+		query := fmt.Sprintf("SELECT CAST(%s AS BINARY) FROM %s WHERE HEX(%s) REGEXP '^(..)*[89A-F]' LIMIT %d", name, table, name, sampleRows)
+		err := queryEach(ctx, db, query, nil, func(rows *sql.Rows) error {
+			var b []byte
+			if err := rows.Scan(&b); err != nil {
+				return err
+			}
+			classifyCharsetValue(&c, b)
+			return nil
+		})
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to sample column %s: %v", col.name, err)},
+				},
+			}, nil, nil
+		}
+		diagnoseCharset(&c, col, table, server)
+		found = append(found, c)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Character sets of %s.%s, from up to %d non-ASCII values per column.\n", args.Database, args.Table, sampleRows)
+	fmt.Fprintf(&b, "Session: character_set_client %s, character_set_connection %s, character_set_results %s. Mojibake is usually written by a client whose declared charset does not match the bytes it sends.\n", client.String, connection.String, results.String)
+	fixes := 0
+	for _, c := range found {
+		fmt.Fprintf(&b, "\n%s %s (%s, %s): %d non-ASCII values. %s\n", c.Column, c.Type, c.Charset, c.Collation, c.Sampled, c.Diagnosis)
+		for _, e := range c.Examples {
+			fmt.Fprintf(&b, "  %q should read %q\n", e.Stored, e.Repaired)
+		}
+		for _, s := range c.Fix {
+			fmt.Fprintf(&b, "  %s\n", s)
+		}
+		fixes += len(c.Fix)
+	}
+	if fixes > 0 {
+		b.WriteString("\nThe statements were not executed. Back up the table, run the previews and check the repaired values before applying them; a sample cannot rule out values the repair does not suit.\n")
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: b.String()},
+		},
+	}, map[string]any{
+		"columns":                found,
+		"characterSetClient":     client.String,
+		"characterSetConnection": connection.String,
+		"characterSetResults":    results.String,
+	}, nil
+}
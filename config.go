@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// serverConfig is the optional JSON file given with -config. Relative paths
+// in it are resolved against the file's directory.
+type serverConfig struct {
+	// Seeds maps an environment name (e.g. "test", "staging") to the seed
+	// data load_seed applies for it.
+	Seeds map[string]seedEnvironment `json:"seeds,omitempty"`
+	// Anonymize maps "database.table" to the strategy anonymize_table
+	// applies to each column.
+	Anonymize map[string]map[string]string `json:"anonymize,omitempty"`
+	// Offline turns on offline mode, like -offline.
+	Offline bool `json:"offline,omitempty"`
+	// CostPolicy sets the query cost limits the flags leave unset.
+	CostPolicy costPolicy `json:"costPolicy,omitempty"`
+	// Hints are optimizer hints execute_query adds to every SELECT, e.g.
+	// MAX_EXECUTION_TIME(30000), unless the statement or call sets them.
+	Hints []string `json:"hints,omitempty"`
+	// Accounts maps a name to a MySQL account execute_as may run queries
+	// as, on the server of the default connection.
+	Accounts map[string]testAccount `json:"accounts,omitempty"`
+	// MySQLRouter is the REST API mysql_router_status queries.
+	MySQLRouter mysqlRouterConfig `json:"mysqlRouter,omitempty"`
+
+	dir   string
+	hints []optimizerHint
+}
+
+var config = &serverConfig{}
+
+// loadConfig reads and validates the config file at path.
+func loadConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	cfg := &serverConfig{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	cfg.dir = filepath.Dir(path)
+	for name, env := range cfg.Seeds {
+		if env.Database == "" {
+			return fmt.Errorf("seed environment %q has no database", name)
+		}
+		for i, source := range env.Sources {
+			if source.Path == "" {
+				return fmt.Errorf("seed environment %q source %d has no path", name, i)
+			}
+		}
+	}
+	for table, columns := range cfg.Anonymize {
+		if !strings.Contains(table, ".") {
+			return fmt.Errorf("anonymize table %q must be written as database.table", table)
+		}
+		for col, strategy := range columns {
+			if anonymizeStrategies[strings.ToLower(strategy)] == "" {
+				return fmt.Errorf("anonymize %s.%s: unknown strategy %q", table, col, strategy)
+			}
+		}
+	}
+	for name, account := range cfg.Accounts {
+		if account.User == "" {
+			return fmt.Errorf("account %q has no user", name)
+		}
+		if account.Password != "" && account.PasswordEnv != "" {
+			return fmt.Errorf("account %q sets both password and passwordEnv", name)
+		}
+	}
+	if u := cfg.MySQLRouter.URL; u != "" && !strings.HasPrefix(u, "https://") && !strings.HasPrefix(u, "http://") {
+		return fmt.Errorf("mysqlRouter url %q must start with https:// or http://", u)
+	}
+	if cfg.MySQLRouter.Password != "" && cfg.MySQLRouter.PasswordEnv != "" {
+		return fmt.Errorf("mysqlRouter sets both password and passwordEnv")
+	}
+	if cfg.hints, err = parseOptimizerHints(cfg.Hints); err != nil {
+		return fmt.Errorf("invalid config %s: %v", path, err)
+	}
+	config = cfg
+	return nil
+}
+
+// configPath resolves a path from the config file.
+func (c *serverConfig) configPath(path string) string {
+	if filepath.IsAbs(path) || c.dir == "" {
+		return path
+	}
+	return filepath.Join(c.dir, path)
+}
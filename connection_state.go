@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// connection is the default connection. connect, use_database and
+// set_session_variables replace it while other tool calls may be running,
+// so it is only read through currentDB and currentDBConfig, and a handler
+// reads it once and keeps using the pool it got.
+//
+// A replaced pool is not closed at once: tool calls that were running when
+// it was replaced may still hold it. It is closed when the last of them
+// returns. Calls are counted by epoch, which each replacement advances.
+var connection = struct {
+	sync.RWMutex
+	db *sql.DB
+	// cfg is the parsed DSN of db, for tools that launch external clients
+	// against the same server.
+	cfg     *mysql.Config
+	epoch   uint64
+	calls   map[uint64]int
+	retired []retiredPool
+}{calls: make(map[uint64]int)}
+
+// retiredPool is a replaced pool waiting for the calls of its epoch and
+// earlier ones to return.
+type retiredPool struct {
+	db    *sql.DB
+	epoch uint64
+}
+
+// currentDB returns the default connection's pool, or nil before connect.
+func currentDB() *sql.DB {
+	connection.RLock()
+	defer connection.RUnlock()
+	return connection.db
+}
+
+// currentDBConfig returns the parsed DSN of the default connection.
+func currentDBConfig() *mysql.Config {
+	connection.RLock()
+	defer connection.RUnlock()
+	return connection.cfg
+}
+
+// setDefaultConnection makes database, opened from cfg, the default
+// connection and retires the pool it replaces.
+func setDefaultConnection(database *sql.DB, cfg *mysql.Config) {
+	connection.Lock()
+	old := connection.db
+	connection.db, connection.cfg = database, cfg
+	if old != nil && old != database {
+		retire(old)
+	}
+	closing := idlePools()
+	connection.Unlock()
+	closePools(closing)
+}
+
+// retirePool closes old, a pool replaced outside the default connection,
+// once no running tool call can still hold it.
+func retirePool(old *sql.DB) {
+	connection.Lock()
+	retire(old)
+	closing := idlePools()
+	connection.Unlock()
+	closePools(closing)
+}
+
+// retire queues old to be closed after the calls of the current epoch and
+// advances the epoch. The caller holds the lock.
+func retire(old *sql.DB) {
+	connection.retired = append(connection.retired, retiredPool{db: old, epoch: connection.epoch})
+	connection.epoch++
+}
+
+// takeRetiredPools removes and returns every retired pool, busy or not, for
+// shutdown.
+func takeRetiredPools() []*sql.DB {
+	connection.Lock()
+	defer connection.Unlock()
+	pools := make([]*sql.DB, len(connection.retired))
+	for i, p := range connection.retired {
+		pools[i] = p.db
+	}
+	connection.retired = nil
+	return pools
+}
+
+// idlePools removes and returns the retired pools no running tool call can
+// still hold. The caller holds the lock.
+func idlePools() []*sql.DB {
+	var idle []*sql.DB
+	kept := connection.retired[:0]
+	for _, p := range connection.retired {
+		busy := false
+		for epoch, n := range connection.calls {
+			if epoch <= p.epoch && n > 0 {
+				busy = true
+				break
+			}
+		}
+		if busy {
+			kept = append(kept, p)
+		} else {
+			idle = append(idle, p.db)
+		}
+	}
+	connection.retired = kept
+	return idle
+}
+
+func closePools(pools []*sql.DB) {
+	for _, p := range pools {
+		if err := closePool(p); err != nil {
+			slog.Error("failed to close replaced connection pool", "err", err)
+		}
+	}
+}
+
+// closePool closes a pool and drops what is cached about it.
+func closePool(database *sql.DB) error {
+	serverInfos.Lock()
+	delete(serverInfos.byDB, database)
+	serverInfos.Unlock()
+	poolAddresses.Lock()
+	delete(poolAddresses.byDB, database)
+	poolAddresses.Unlock()
+	sessionZones.Lock()
+	delete(sessionZones.byDB, database)
+	sessionZones.Unlock()
+	dropSchemaCache(database)
+	return database.Close()
+}
+
+// holdDB returns the default connection's pool, or nil before connect, and
+// counts the caller as using it until release is called, so that the pool
+// is not closed under it when it is replaced. Tool calls are counted by
+// connectionMiddleware; background work such as scheduled queries holds
+// the pool itself.
+func holdDB() (db *sql.DB, release func()) {
+	connection.Lock()
+	epoch := connection.epoch
+	connection.calls[epoch]++
+	db = connection.db
+	connection.Unlock()
+
+	return db, func() {
+		connection.Lock()
+		if connection.calls[epoch]--; connection.calls[epoch] == 0 {
+			delete(connection.calls, epoch)
+		}
+		closing := idlePools()
+		connection.Unlock()
+		closePools(closing)
+	}
+}
+
+// connectionMiddleware counts tool calls by the epoch they started in, so
+// a pool replaced during a call stays open until the call returns.
+func connectionMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != "tools/call" {
+			return next(ctx, method, req)
+		}
+		_, release := holdDB()
+		defer release()
+		return next(ctx, method, req)
+	}
+}
+
+// serializeQueries makes execute_query calls from one client session run
+// one at a time rather than in parallel on the pool, set with
+// -serialize-queries.
+var serializeQueries bool
+
+// querySessions holds a turn for each client session's execute_query calls
+// under -serialize-queries: a channel with room for one call. A session's
+// turn is dropped when the session ends.
+var querySessions = struct {
+	sync.Mutex
+	turns map[*mcp.ServerSession]chan struct{}
+}{turns: make(map[*mcp.ServerSession]chan struct{})}
+
+// awaitQueryTurn waits for the session's earlier execute_query calls to
+// return when -serialize-queries is set, and returns the function that lets
+// the next one run. It fails if ctx ends first.
+func awaitQueryTurn(ctx context.Context, req *mcp.CallToolRequest) (func(), error) {
+	if !serializeQueries || req == nil || req.Session == nil {
+		return func() {}, nil
+	}
+	querySessions.Lock()
+	session := req.Session
+	turn, ok := querySessions.turns[session]
+	if !ok {
+		turn = make(chan struct{}, 1)
+		querySessions.turns[session] = turn
+		go func() {
+			session.Wait()
+			querySessions.Lock()
+			delete(querySessions.turns, session)
+			querySessions.Unlock()
+		}()
+	}
+	querySessions.Unlock()
+	select {
+	case turn <- struct{}{}:
+		return func() { <-turn }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
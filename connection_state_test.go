@@ -0,0 +1,106 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// openIdlePool opens a pool that is never used to connect; nothing here
+// talks to a server.
+func openIdlePool(t *testing.T) *sql.DB {
+	t.Helper()
+	connector, err := mysql.NewConnector(mysql.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sql.OpenDB(connector)
+}
+
+func TestHoldDBKeepsReplacedPoolOpen(t *testing.T) {
+	first, second := openIdlePool(t), openIdlePool(t)
+	defer func() {
+		connection.Lock()
+		connection.db, connection.cfg = nil, nil
+		connection.Unlock()
+		second.Close()
+	}()
+
+	setDefaultConnection(first, nil)
+	serverInfos.Lock()
+	serverInfos.byDB[first] = serverInfo{Version: "8.0.36"}
+	serverInfos.Unlock()
+
+	held, release := holdDB()
+	if held != first {
+		t.Fatal("holdDB() did not return the default connection")
+	}
+	setDefaultConnection(second, nil)
+	if currentDB() != second {
+		t.Fatal("the default connection was not replaced")
+	}
+	connection.RLock()
+	retired := len(connection.retired)
+	connection.RUnlock()
+	if retired != 1 {
+		t.Fatalf("%d retired pools while the first is held, want 1", retired)
+	}
+
+	release()
+	connection.RLock()
+	retired = len(connection.retired)
+	connection.RUnlock()
+	if retired != 0 {
+		t.Errorf("%d retired pools after release, want 0", retired)
+	}
+	if err := first.Ping(); err == nil || err.Error() != "sql: database is closed" {
+		t.Errorf("replaced pool not closed: Ping() = %v", err)
+	}
+	serverInfos.Lock()
+	_, cached := serverInfos.byDB[first]
+	serverInfos.Unlock()
+	if cached {
+		t.Error("the closed pool's server info is still cached")
+	}
+}
+
+func TestRegisterConnectionKeepsReplacedPoolOpen(t *testing.T) {
+	first, second, third := openIdlePool(t), openIdlePool(t), openIdlePool(t)
+	defer func() {
+		namedConnections.Lock()
+		delete(namedConnections.byName, "replica")
+		namedConnections.Unlock()
+		third.Close()
+	}()
+	closed := func(db *sql.DB) bool {
+		err := db.Ping()
+		return err != nil && err.Error() == "sql: database is closed"
+	}
+
+	registerConnection("replica", first)
+	// A tool call running when the connection is replaced keeps using it.
+	_, release := holdDB()
+	registerConnection("replica", second)
+	if conn, err := connectionFor("replica"); err != nil || conn != second {
+		t.Fatalf("connectionFor() = %p, %v; want the new pool", conn, err)
+	}
+	if closed(first) {
+		t.Fatal("the replaced pool was closed while a tool call could hold it")
+	}
+	release()
+	if !closed(first) {
+		t.Error("the replaced pool was not closed after the last call returned")
+	}
+
+	// Shutdown closes pools still waiting for their calls.
+	_, release = holdDB()
+	registerConnection("replica", third)
+	for _, p := range takeRetiredPools() {
+		p.Close()
+	}
+	release()
+	if !closed(second) {
+		t.Error("takeRetiredPools() did not return the busy replaced pool")
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// defaultConnectionName refers to the connection made without a name.
+const defaultConnectionName = "default"
+
+// namedConnections holds the additional connections opened with connect's
+// name parameter.
+var namedConnections = struct {
+	sync.Mutex
+	byName map[string]*sql.DB
+}{byName: make(map[string]*sql.DB)}
+
+// registerConnection stores database under name. A connection previously
+// registered with that name is retired like a replaced default connection:
+// it is closed once the tool calls that may hold it have returned.
+func registerConnection(name string, database *sql.DB) {
+	namedConnections.Lock()
+	old, ok := namedConnections.byName[name]
+	namedConnections.byName[name] = database
+	namedConnections.Unlock()
+	if ok && old != database {
+		retirePool(old)
+	}
+}
+
+// connectionFor returns the named connection, or the default connection
+// when name is empty or "default".
+func connectionFor(name string) (*sql.DB, error) {
+	db := currentDB()
+	if name == "" || name == defaultConnectionName {
+		if db == nil {
+			return nil, errors.New("not connected to a database")
+		}
+		return db, nil
+	}
+	namedConnections.Lock()
+	defer namedConnections.Unlock()
+	conn, ok := namedConnections.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("no connection named %q", name)
+	}
+	return conn, nil
+}
+
+// connectionHint is the text tools show for a connectionFor error.
+func connectionHint(err error) string {
+	return fmt.Sprintf("Connection unavailable: %v. Use the connect tool first, with name for a named connection.", err)
+}
+
+// connectionNames lists the named connections in sorted order.
+func connectionNames() []string {
+	namedConnections.Lock()
+	defer namedConnections.Unlock()
+	names := make([]string, 0, len(namedConnections.byName))
+	for name := range namedConnections.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
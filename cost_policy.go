@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// costPolicy blocks statements the model supplies whose EXPLAIN estimates
+// exceed its limits, for shared servers where a slow query hurts everyone.
+// Zero limits are off.
+type costPolicy struct {
+	// MaxRowsExamined limits the rows the optimizer expects to read,
+	// counting each table of a join once per row of the tables before it.
+	MaxRowsExamined int64 `json:"maxRowsExamined,omitempty"`
+	// MaxFullScanRows is the size above which a full table scan is
+	// refused.
+	MaxFullScanRows int64 `json:"maxFullScanRows,omitempty"`
+	// RequirePartitionPruning refuses statements that read every
+	// partition of a partitioned table.
+	RequirePartitionPruning bool `json:"requirePartitionPruning,omitempty"`
+}
+
+// queryCostPolicy is the policy set by the -max-rows-examined,
+// -max-full-scan-rows and -require-partition-pruning flags and the config
+// file's costPolicy.
+var queryCostPolicy costPolicy
+
+// CostViolation is a limit of the cost policy a statement's plan exceeds.
+type CostViolation struct {
+	// Rule is rows_examined, full_scan or partition_pruning.
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (p costPolicy) enabled() bool {
+	return p.MaxRowsExamined > 0 || p.MaxFullScanRows > 0 || p.RequirePartitionPruning
+}
+
+// merge fills in the limits p leaves unset from other, so that flags take
+// precedence over the config file.
+func (p *costPolicy) merge(other costPolicy) {
+	if p.MaxRowsExamined == 0 {
+		p.MaxRowsExamined = other.MaxRowsExamined
+	}
+	if p.MaxFullScanRows == 0 {
+		p.MaxFullScanRows = other.MaxFullScanRows
+	}
+	p.RequirePartitionPruning = p.RequirePartitionPruning || other.RequirePartitionPruning
+}
+
+// partitionSets returns, for each partitioned table query references, its
+// partitions as EXPLAIN lists them, sorted and joined with commas.
+func partitionSets(ctx context.Context, db *sql.DB, query string) map[string]string {
+	sets := make(map[string]string)
+	for _, ref := range referencedTables(tokenizeSQL(query)) {
+		var schema any
+		if ref[0] != "" {
+			schema = ref[0]
+		}
+		rows, err := db.QueryContext(ctx, `
+			SELECT PARTITION_NAME, SUBPARTITION_NAME
+			FROM information_schema.PARTITIONS
+			WHERE TABLE_SCHEMA = COALESCE(?, DATABASE()) AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL
+		`, schema, ref[1])
+		if err != nil {
+			slog.Debug("failed to read partitions", "table", ref[1], "err", err)
+			continue
+		}
+		var names []string
+		for rows.Next() {
+			var partition string
+			var subpartition *string
+			if err := rows.Scan(&partition, &subpartition); err != nil {
+				break
+			}
+			// EXPLAIN names subpartitions partition_subpartition.
+			if subpartition != nil {
+				partition += "_" + *subpartition
+			}
+			names = append(names, partition)
+		}
+		rows.Close()
+		if len(names) > 1 {
+			slices.Sort(names)
+			sets[ref[1]] = strings.Join(names, ",")
+		}
+	}
+	return sets
+}
+
+// check explains query, run on db with params, and returns the limits its
+// plan exceeds.
+func (p costPolicy) check(ctx context.Context, db *sql.DB, query string, params ...any) ([]CostViolation, error) {
+	explain := "EXPLAIN "
+	if p.RequirePartitionPruning && serverInfoFor(ctx, db).MariaDB {
+		// MariaDB only shows partitions when asked; MySQL 8 always does
+		// and no longer accepts the keyword.
+		explain = "EXPLAIN PARTITIONS "
+	}
+	rows, err := db.QueryContext(ctx, explain+query, params...)
+	if err != nil {
+		return nil, err
+	}
+	columns, plan, err := scanRowMaps(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []CostViolation
+	fullScan := func(table string, rows float64) {
+		if p.MaxFullScanRows > 0 && rows >= float64(p.MaxFullScanRows) {
+			violations = append(violations, CostViolation{"full_scan", fmt.Sprintf("Full scan of %s, about %.0f rows (limit %d)", table, rows, p.MaxFullScanRows)})
+		}
+	}
+	unpruned := func(table string) {
+		violations = append(violations, CostViolation{"partition_pruning", fmt.Sprintf("%s is read from every partition; filter on its partitioning key", table)})
+	}
+
+	examined := 0.0
+	if isTiDBPlan(columns) {
+		for _, row := range plan {
+			name := strings.TrimLeft(planText(row["id"]), " │├└─")
+			if !strings.Contains(name, "Scan") {
+				continue
+			}
+			rows, _ := strconv.ParseFloat(planText(row["estRows"]), 64)
+			examined += rows
+			object := planText(row["access object"])
+			if strings.HasPrefix(name, "TableFullScan") {
+				fullScan(object, rows)
+			}
+			if p.RequirePartitionPruning && strings.Contains(object, "partition:all") {
+				unpruned(object)
+			}
+		}
+	} else {
+		var sets map[string]string
+		if p.RequirePartitionPruning {
+			sets = partitionSets(ctx, db, query)
+		}
+		// Each table of a join is read once per row the tables before it
+		// produce.
+		produced := make(map[string]float64)
+		for _, row := range plan {
+			id := planText(row["id"])
+			rows, _ := strconv.ParseFloat(planText(row["rows"]), 64)
+			filtered, err := strconv.ParseFloat(planText(row["filtered"]), 64)
+			if err != nil {
+				filtered = 100
+			}
+			prefix, ok := produced[id]
+			if !ok {
+				prefix = 1
+			}
+			examined += prefix * rows
+			produced[id] = prefix * rows * filtered / 100
+
+			table := planText(row["table"])
+			if planText(row["type"]) == "ALL" {
+				fullScan(table, rows)
+			}
+			if partitions := planText(row["partitions"]); partitions != "" {
+				listed := strings.Split(partitions, ",")
+				slices.Sort(listed)
+				for name, set := range sets {
+					if set == strings.Join(listed, ",") {
+						unpruned(fmt.Sprintf("%s (%s)", table, name))
+						break
+					}
+				}
+			}
+		}
+	}
+	if p.MaxRowsExamined > 0 && examined > float64(p.MaxRowsExamined) {
+		violations = append([]CostViolation{{"rows_examined", fmt.Sprintf("About %.0f rows examined (limit %d)", examined, p.MaxRowsExamined)}}, violations...)
+	}
+	return violations, nil
+}
+
+// enforceCostPolicy checks query, which tool is about to run on db with
+// params, against the cost policy. Each statement of query EXPLAIN can plan
+// is checked. A statement over the limits runs only if the user approves it
+// when asked through the client; one whose plan cannot be read is refused.
+// Otherwise the returned result reports the block. It returns nil when the
+// statement may run.
+func enforceCostPolicy(ctx context.Context, req *mcp.CallToolRequest, tool string, db *sql.DB, query string, params ...any) *mcp.CallToolResult {
+	if !queryCostPolicy.enabled() {
+		return nil
+	}
+	if db == nil {
+		recordPolicyBlock(tool)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Blocked by the query cost policy: not connected, so the cost of the statement cannot be checked. Use connect tool first."},
+			},
+		}
+	}
+	statements := splitSQLStatements(query)
+	if len(statements) > 1 {
+		// Placeholders cannot be told apart between statements.
+		params = nil
+	}
+	var violations []CostViolation
+	for _, statement := range statements {
+		if checkExplainable(statement) != nil {
+			continue
+		}
+		found, err := queryCostPolicy.check(ctx, db, statement, params...)
+		if err != nil {
+			// Without a plan the statement's cost is unknown, so it is
+			// not run.
+			recordPolicyBlock(tool)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Blocked by the query cost policy: EXPLAIN failed, so the cost of the statement could not be checked: %v", err)},
+				},
+			}
+		}
+		violations = append(violations, found...)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+
+	var lines []string
+	for _, v := range violations {
+		lines = append(lines, "- "+v.Message)
+	}
+	reasons := strings.Join(lines, "\n")
+	blocked := func(note string) *mcp.CallToolResult {
+		recordPolicyBlock(tool)
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Blocked by the query cost policy:\n%s\n%s\nRewrite the query to read less (compare_explain and visualize_plan help), or run it again and have the user approve it.", reasons, note)},
+			},
+		}
+	}
+	if req == nil || req.Session == nil {
+		return blocked("There is no client session to ask for approval.")
+	}
+
+	// The override is asked of the user directly, so the model cannot
+	// approve its own query.
+	res, err := req.Session.Elicit(ctx, &mcp.ElicitParams{
+		Message: fmt.Sprintf("This query exceeds the server's cost limits:\n%s\n\n%s\n\nRun it anyway?", reasons, truncateForLog(query, 500)),
+		RequestedSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"run": {Type: "boolean", Description: "Run the query despite the cost limits"},
+			},
+			Required: []string{"run"},
+		},
+	})
+	if err != nil {
+		return blocked(fmt.Sprintf("Approval could not be asked for (the client may not support elicitation): %v", err))
+	}
+	if run, _ := res.Content["run"].(bool); res.Action != "accept" || !run {
+		return blocked("The user declined to run it.")
+	}
+	slog.Warn("cost policy overridden by the user", "query", truncateForLog(query, 200), "violations", len(violations))
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCostPolicyMerge(t *testing.T) {
+	p := costPolicy{MaxRowsExamined: 10}
+	p.merge(costPolicy{MaxRowsExamined: 20, MaxFullScanRows: 30, RequirePartitionPruning: true})
+	want := costPolicy{MaxRowsExamined: 10, MaxFullScanRows: 30, RequirePartitionPruning: true}
+	if p != want {
+		t.Errorf("merge() = %+v, want %+v", p, want)
+	}
+}
+
+func TestEnforceCostPolicyFailsClosed(t *testing.T) {
+	saved := queryCostPolicy
+	defer func() { queryCostPolicy = saved }()
+
+	queryCostPolicy = costPolicy{}
+	if blocked := enforceCostPolicy(context.Background(), nil, "execute_query", nil, "SELECT 1"); blocked != nil {
+		t.Error("enforceCostPolicy() blocked a query with no policy set")
+	}
+
+	// Without a connection to explain on, the cost is unknown.
+	queryCostPolicy = costPolicy{MaxRowsExamined: 1000}
+	blocked := enforceCostPolicy(context.Background(), nil, "export_query", nil, "SELECT * FROM t")
+	if blocked == nil || !blocked.IsError {
+		t.Fatal("enforceCostPolicy() let an unchecked query run")
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultCountTimeout = 5 * time.Second
+	maxCountTimeout     = time.Minute
+)
+
+type CountEstimateParams struct {
+	Database       string   `json:"database"`
+	Table          string   `json:"table"`
+	Filters        []Filter `json:"filters,omitempty"`
+	Analyze        bool     `json:"analyze,omitempty"`
+	Exact          bool     `json:"exact,omitempty"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+}
+
+// tableRowEstimate returns the row count InnoDB's persistent statistics
+// hold for a table, falling back to information_schema.TABLES, which MySQL
+// 8 caches for information_schema_stats_expiry seconds. source names where
+// the figure came from.
+func tableRowEstimate(ctx context.Context, database, table string) (rows int64, updated sql.NullString, source string, err error) {
+	db := currentDB()
+	// mysql.innodb_table_stats needs the SELECT privilege on the mysql
+	// schema; without it the cached figure is used.
+	err = db.QueryRowContext(ctx, "SELECT n_rows, last_update FROM mysql.innodb_table_stats WHERE database_name = ? AND table_name = ?", database, table).Scan(&rows, &updated)
+	if err == nil {
+		return rows, updated, "InnoDB persistent statistics", nil
+	}
+	var tableRows sql.NullInt64
+	err = db.QueryRowContext(ctx, "SELECT TABLE_ROWS, UPDATE_TIME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?", database, table).Scan(&tableRows, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, updated, "", fmt.Errorf("table %s.%s does not exist", database, table)
+	}
+	return tableRows.Int64, updated, "information_schema.TABLES", err
+}
+
+// isStatementTimeout reports whether err is the server stopping a
+// statement at max_execution_time or max_statement_time.
+func isStatementTimeout(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	// 3024 is MySQL's ER_QUERY_TIMEOUT and 1969 MariaDB's
+	// ER_STATEMENT_TIMEOUT.
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &mysqlErr) && (mysqlErr.Number == 3024 || mysqlErr.Number == 1969)
+}
+
+func CountEstimate(ctx context.Context, req *mcp.CallToolRequest, args CountEstimateParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	if args.Analyze {
+		if err := checkWritable("count_estimate with analyze"); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: err.Error()},
+				},
+			}, nil, nil
+		}
+	}
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	// The condition is built from the filters only, with their values as
+	// parameters.
+	from := qualifiedTable(args.Database, args.Table)
+	var params []any
+	if len(args.Filters) > 0 {
+		where, filterParams, err := buildWhere(args.Filters, tableCols)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid filters: %v", err)},
+				},
+			}, nil, nil
+		}
+		from += " WHERE " + where
+		params = filterParams
+	}
+
+	if args.Analyze {
+		// ANALYZE TABLE samples index pages to refresh the statistics; it
+		// does not read the whole table. It still writes the statistics
+		// tables, and is kept out of the binlog so replicas do not repeat
+		// it; TiDB has no binlog option.
+		analyze := "ANALYZE NO_WRITE_TO_BINLOG TABLE "
+		if serverInfoFor(ctx, db).TiDB {
+			analyze = "ANALYZE TABLE "
+		}
+		if _, _, err := queryRowMaps(ctx, db, analyze+qualifiedTable(args.Database, args.Table)); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("ANALYZE TABLE failed: %v", err)},
+				},
+			}, nil, nil
+		}
+	}
+	estimate, updated, source, err := tableRowEstimate(ctx, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read table statistics: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("%s.%s: about %d rows (%s", args.Database, args.Table, estimate, source)
+	if updated.Valid {
+		resultText += ", updated " + updated.String
+	}
+	resultText += ").\n"
+	structured := map[string]any{
+		"estimate":       estimate,
+		"estimateSource": source,
+	}
+	if updated.Valid {
+		structured["statisticsUpdated"] = updated.String
+	}
+
+	if len(args.Filters) > 0 {
+		matching, err := estimateRows(ctx, db, "SELECT * FROM "+from, params...)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("EXPLAIN failed: %v", err)},
+				},
+			}, nil, nil
+		}
+		resultText += fmt.Sprintf("Matching the condition: about %d rows (the optimizer's estimate, which can be far off for conditions on columns without an index or histogram).\n", int64(math.Round(matching)))
+		structured["conditionEstimate"] = int64(math.Round(matching))
+	}
+
+	if args.Exact {
+		timeout := time.Duration(args.TimeoutSeconds) * time.Second
+		if timeout <= 0 {
+			timeout = defaultCountTimeout
+		}
+		timeout = min(timeout, maxCountTimeout)
+
+		// The server stops the count at the timeout too, since cancelling
+		// the context only drops the connection.
+		query := "SELECT COUNT(*) FROM " + from
+		if server := serverInfoFor(ctx, db); server.MariaDB {
+			query = fmt.Sprintf("SET STATEMENT max_statement_time = %g FOR %s", timeout.Seconds(), query)
+		} else {
+			query = fmt.Sprintf("SELECT /*+ MAX_EXECUTION_TIME(%d) */ COUNT(*) FROM %s", timeout.Milliseconds(), from)
+		}
+		countCtx, cancel := context.WithTimeout(ctx, timeout+time.Second)
+		defer cancel()
+		started := time.Now()
+		var exact int64
+		err := db.QueryRowContext(countCtx, query, params...).Scan(&exact)
+		elapsed := time.Since(started)
+		switch {
+		case isStatementTimeout(err):
+			resultText += fmt.Sprintf("The exact count did not finish within %s; rely on the estimate, or count a narrower range.\n", timeout)
+			structured["exactTimedOut"] = true
+		case err != nil:
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Exact count failed: %v", err)},
+				},
+			}, nil, nil
+		default:
+			resultText += fmt.Sprintf("Exact count: %d rows (%s).\n", exact, elapsed.Round(time.Millisecond))
+			structured["exact"] = exact
+			structured["exactSeconds"] = elapsed.Seconds()
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, structured, nil
+}
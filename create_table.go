@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+var (
+	// columnTypePattern accepts a type name with an optional length or
+	// precision/scale, e.g. INT, VARCHAR(255), DECIMAL(10, 2), DATETIME(6).
+	columnTypePattern = regexp.MustCompile(`^[A-Za-z]+( [A-Za-z]+)?(\s*\(\s*\d+\s*(,\s*\d+\s*)?\))?$`)
+	// currentTimestampPattern matches CURRENT_TIMESTAMP and its synonyms,
+	// which are the only expressions allowed in ON UPDATE.
+	currentTimestampPattern = regexp.MustCompile(`(?i)^(CURRENT_TIMESTAMP|NOW|LOCALTIMESTAMP|LOCALTIME)(\s*\(\s*\d?\s*\))?$`)
+	referenceActions        = map[string]bool{"RESTRICT": true, "CASCADE": true, "SET NULL": true, "NO ACTION": true, "SET DEFAULT": true}
+)
+
+type ColumnSpec struct {
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	Values        []string `json:"values,omitempty"`
+	Unsigned      bool     `json:"unsigned,omitempty"`
+	Nullable      bool     `json:"nullable,omitempty"`
+	Default       any      `json:"default,omitempty"`
+	DefaultExpr   string   `json:"default_expr,omitempty"`
+	OnUpdate      string   `json:"on_update,omitempty"`
+	AutoIncrement bool     `json:"auto_increment,omitempty"`
+	Charset       string   `json:"charset,omitempty"`
+	Collation     string   `json:"collation,omitempty"`
+	Comment       string   `json:"comment,omitempty"`
+}
+
+type IndexSpec struct {
+	Name    string   `json:"name,omitempty"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+	Kind    string   `json:"kind,omitempty"`
+}
+
+type ForeignKeySpec struct {
+	Name        string   `json:"name,omitempty"`
+	Columns     []string `json:"columns"`
+	RefDatabase string   `json:"ref_database,omitempty"`
+	RefTable    string   `json:"ref_table"`
+	RefColumns  []string `json:"ref_columns"`
+	OnDelete    string   `json:"on_delete,omitempty"`
+	OnUpdate    string   `json:"on_update,omitempty"`
+}
+
+type CreateTableParams struct {
+	Database    string           `json:"database"`
+	Table       string           `json:"table"`
+	Columns     []ColumnSpec     `json:"columns"`
+	PrimaryKey  []string         `json:"primary_key,omitempty"`
+	Indexes     []IndexSpec      `json:"indexes,omitempty"`
+	ForeignKeys []ForeignKeySpec `json:"foreign_keys,omitempty"`
+	Engine      string           `json:"engine,omitempty"`
+	Charset     string           `json:"charset,omitempty"`
+	Collation   string           `json:"collation,omitempty"`
+	Comment     string           `json:"comment,omitempty"`
+	IfNotExists bool             `json:"if_not_exists,omitempty"`
+	DryRun      bool             `json:"dry_run,omitempty"`
+}
+
+func CreateTable(ctx context.Context, req *mcp.CallToolRequest, args CreateTableParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	ddl, err := createTableDDL(args)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid table definition: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	if args.DryRun {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Generated DDL (not executed):\n\n" + ddl},
+			},
+		}, map[string]any{"ddl": ddl, "executed": false}, nil
+	}
+
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("create_table"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	_, err = db.ExecContext(ctx, ddl)
+	audit("create_table", ddl, 0, err)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to create table: %v\n\n%s", err, ddl)},
+			},
+		}, nil, nil
+	}
+	invalidateTable(args.Database, args.Table)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Created table %s.%s\n\n%s", args.Database, args.Table, ddl)},
+		},
+	}, map[string]any{"ddl": ddl, "executed": true}, nil
+}
+
+// createTableDDL validates a structured table definition and renders it as
+// a CREATE TABLE statement.
+func createTableDDL(args CreateTableParams) (string, error) {
+	if args.Database == "" || args.Table == "" {
+		return "", fmt.Errorf("database and table are required")
+	}
+	if err := checkIdentifier("database", args.Database); err != nil {
+		return "", err
+	}
+	if err := checkIdentifier("table", args.Table); err != nil {
+		return "", err
+	}
+	if len(args.Columns) == 0 {
+		return "", fmt.Errorf("at least one column is required")
+	}
+
+	columns := make(map[string]bool)
+	var defs []string
+	var autoIncrement string
+	for i, col := range args.Columns {
+		if col.Name == "" {
+			return "", fmt.Errorf("column %d has no name", i)
+		}
+		if columns[col.Name] {
+			return "", fmt.Errorf("duplicate column %q", col.Name)
+		}
+		columns[col.Name] = true
+
+		def, err := columnDefinition(col)
+		if err != nil {
+			return "", fmt.Errorf("column %q: %w", col.Name, err)
+		}
+		defs = append(defs, def)
+		if col.AutoIncrement {
+			if autoIncrement != "" {
+				return "", fmt.Errorf("only one AUTO_INCREMENT column is allowed")
+			}
+			autoIncrement = col.Name
+		}
+	}
+
+	checkColumns := func(what string, names []string) error {
+		if len(names) == 0 {
+			return fmt.Errorf("%s has no columns", what)
+		}
+		for _, name := range names {
+			if !columns[name] {
+				return fmt.Errorf("%s references unknown column %q", what, name)
+			}
+		}
+		return nil
+	}
+
+	autoIncrementKeyed := false
+	if len(args.PrimaryKey) > 0 {
+		if err := checkColumns("primary key", args.PrimaryKey); err != nil {
+			return "", err
+		}
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", quoteIdentifierList(args.PrimaryKey)))
+		autoIncrementKeyed = args.PrimaryKey[0] == autoIncrement
+	}
+
+	for i, idx := range args.Indexes {
+		what := fmt.Sprintf("index %d", i)
+		if idx.Name != "" {
+			what = fmt.Sprintf("index %q", idx.Name)
+		}
+		if err := checkColumns(what, idx.Columns); err != nil {
+			return "", err
+		}
+
+		kind := "INDEX"
+		switch strings.ToUpper(idx.Kind) {
+		case "", "BTREE":
+			if idx.Unique {
+				kind = "UNIQUE INDEX"
+			}
+		case "FULLTEXT":
+			kind = "FULLTEXT INDEX"
+		case "SPATIAL":
+			kind = "SPATIAL INDEX"
+		default:
+			return "", fmt.Errorf("%s: unsupported kind %q (use btree, fulltext or spatial)", what, idx.Kind)
+		}
+
+		def := kind
+		if idx.Name != "" {
+			if err := checkIdentifier("index", idx.Name); err != nil {
+				return "", err
+			}
+			def += " " + quoteIdentifier(idx.Name)
+		}
+		defs = append(defs, fmt.Sprintf("%s (%s)", def, quoteIdentifierList(idx.Columns)))
+		if idx.Columns[0] == autoIncrement {
+			autoIncrementKeyed = true
+		}
+	}
+
+	if autoIncrement != "" && !autoIncrementKeyed {
+		return "", fmt.Errorf("AUTO_INCREMENT column %q must be the first column of the primary key or an index", autoIncrement)
+	}
+
+	for i, fk := range args.ForeignKeys {
+		what := fmt.Sprintf("foreign key %d", i)
+		if fk.Name != "" {
+			what = fmt.Sprintf("foreign key %q", fk.Name)
+		}
+		if err := checkColumns(what, fk.Columns); err != nil {
+			return "", err
+		}
+		if fk.RefTable == "" || len(fk.RefColumns) != len(fk.Columns) {
+			return "", fmt.Errorf("%s needs ref_table and the same number of ref_columns as columns", what)
+		}
+
+		def := ""
+		if fk.Name != "" {
+			if err := checkIdentifier("constraint", fk.Name); err != nil {
+				return "", err
+			}
+			def = "CONSTRAINT " + quoteIdentifier(fk.Name) + " "
+		}
+		refDatabase := fk.RefDatabase
+		if refDatabase == "" {
+			refDatabase = args.Database
+		}
+		def += fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+			quoteIdentifierList(fk.Columns), qualifiedTable(refDatabase, fk.RefTable), quoteIdentifierList(fk.RefColumns))
+		for _, action := range []struct{ clause, value string }{{"ON DELETE", fk.OnDelete}, {"ON UPDATE", fk.OnUpdate}} {
+			if action.value == "" {
+				continue
+			}
+			value := strings.ToUpper(strings.Join(strings.Fields(action.value), " "))
+			if !referenceActions[value] {
+				return "", fmt.Errorf("%s: invalid %s action %q", what, action.clause, action.value)
+			}
+			def += " " + action.clause + " " + value
+		}
+		defs = append(defs, def)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE ")
+	if args.IfNotExists {
+		sb.WriteString("IF NOT EXISTS ")
+	}
+	sb.WriteString(qualifiedTable(args.Database, args.Table))
+	sb.WriteString(" (\n  ")
+	sb.WriteString(strings.Join(defs, ",\n  "))
+	sb.WriteString("\n)")
+
+	engine := args.Engine
+	if engine == "" {
+		engine = "InnoDB"
+	}
+	for _, opt := range []string{engine, args.Charset, args.Collation} {
+		if opt != "" && !charsetNamePattern.MatchString(opt) {
+			return "", fmt.Errorf("invalid table option %q", opt)
+		}
+	}
+	sb.WriteString(" ENGINE=" + engine)
+	if args.Charset != "" {
+		sb.WriteString(" DEFAULT CHARSET=" + args.Charset)
+	}
+	if args.Collation != "" {
+		sb.WriteString(" COLLATE=" + args.Collation)
+	}
+	if args.Comment != "" {
+		sb.WriteString(" COMMENT=" + quoteString(args.Comment))
+	}
+	return sb.String(), nil
+}
+
+// columnDefinition renders one column of a CREATE/ALTER TABLE statement.
+func columnDefinition(col ColumnSpec) (string, error) {
+	if err := checkIdentifier("column", col.Name); err != nil {
+		return "", err
+	}
+	typ := strings.TrimSpace(col.Type)
+	upper := strings.ToUpper(typ)
+
+	switch {
+	case upper == "ENUM" || upper == "SET":
+		if len(col.Values) == 0 {
+			return "", fmt.Errorf("%s requires values", upper)
+		}
+		quoted := make([]string, len(col.Values))
+		for i, v := range col.Values {
+			quoted[i] = quoteString(v)
+		}
+		typ = fmt.Sprintf("%s(%s)", upper, strings.Join(quoted, ", "))
+	case columnTypePattern.MatchString(typ):
+		typ = upper
+	default:
+		return "", fmt.Errorf("invalid type %q", col.Type)
+	}
+
+	def := quoteIdentifier(col.Name) + " " + typ
+	if col.Unsigned {
+		def += " UNSIGNED"
+	}
+	for _, opt := range []string{col.Charset, col.Collation} {
+		if opt != "" && !charsetNamePattern.MatchString(opt) {
+			return "", fmt.Errorf("invalid charset or collation %q", opt)
+		}
+	}
+	if col.Charset != "" {
+		def += " CHARACTER SET " + col.Charset
+	}
+	if col.Collation != "" {
+		def += " COLLATE " + col.Collation
+	}
+
+	if col.Nullable {
+		def += " NULL"
+	} else {
+		def += " NOT NULL"
+	}
+
+	if col.Default != nil && col.DefaultExpr != "" {
+		return "", fmt.Errorf("use either default or default_expr, not both")
+	}
+	switch v := col.Default.(type) {
+	case nil:
+	case string:
+		def += " DEFAULT " + quoteString(v)
+	case bool:
+		if v {
+			def += " DEFAULT 1"
+		} else {
+			def += " DEFAULT 0"
+		}
+	case float64:
+		def += fmt.Sprintf(" DEFAULT %v", sqlValue(v))
+	default:
+		return "", fmt.Errorf("unsupported default value %v", v)
+	}
+	if col.DefaultExpr != "" {
+		if currentTimestampPattern.MatchString(col.DefaultExpr) {
+			def += " DEFAULT " + strings.ToUpper(col.DefaultExpr)
+		} else {
+			// Expression defaults must be parenthesized (MySQL 8.0.13+).
+			def += " DEFAULT (" + col.DefaultExpr + ")"
+		}
+	}
+
+	if col.OnUpdate != "" {
+		if !currentTimestampPattern.MatchString(col.OnUpdate) {
+			return "", fmt.Errorf("on_update only supports CURRENT_TIMESTAMP")
+		}
+		def += " ON UPDATE " + strings.ToUpper(col.OnUpdate)
+	}
+	if col.AutoIncrement {
+		def += " AUTO_INCREMENT"
+	}
+	if col.Comment != "" {
+		def += " COMMENT " + quoteString(col.Comment)
+	}
+	return def, nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"10-5 * * * *",
+		"* * * foo *",
+		"1,,2 * * * *",
+		"@every 5m",
+	} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) accepted an invalid expression", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2026-03-04 was a Wednesday.
+	from := time.Date(2026, 3, 4, 10, 17, 42, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 4, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)},
+		{"17 * * * *", time.Date(2026, 3, 4, 11, 17, 0, 0, time.UTC)},
+		{"0 8-18/2 * * *", time.Date(2026, 3, 4, 12, 0, 0, 0, time.UTC)},
+		{"30 9 * * mon-fri", time.Date(2026, 3, 5, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * SUN", time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{"@HOURLY", time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 jan,jul *", time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either one matches.
+		{"0 0 20 * fri", time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+		// One restricted: only that one counts.
+		{"0 0 20 * *", time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		s, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q) = %v", tt.expr, err)
+			continue
+		}
+		if got := s.next(from); !got.Equal(tt.want) {
+			t.Errorf("parseCron(%q).next(%v) = %v, want %v", tt.expr, from, got, tt.want)
+		}
+	}
+}
+
+func TestCronNextAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	s, err := parseCron("30 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 02:30 does not exist on 2026-03-08; the run moves to the next day
+	// rather than firing at a normalized time.
+	got := s.next(time.Date(2026, 3, 7, 12, 0, 0, 0, loc))
+	if want := time.Date(2026, 3, 9, 2, 30, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("next() = %v, want %v", got, want)
+	}
+
+	// In Havana the clocks go forward at midnight, so 2026-03-08 starts at
+	// 01:00.
+	if loc, err = time.LoadLocation("America/Havana"); err != nil {
+		t.Skip("no time zone database:", err)
+	}
+	if s, err = parseCron("0 12 8 3 *"); err != nil {
+		t.Fatal(err)
+	}
+	got = s.next(time.Date(2026, 3, 7, 23, 30, 0, 0, loc))
+	if want := time.Date(2026, 3, 8, 12, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("next() = %v, want %v", got, want)
+	}
+}
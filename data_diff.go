@@ -0,0 +1,444 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultDataDiffLimit = 100
+	dataDiffChunk        = 1000
+)
+
+type DiffDataParams struct {
+	SourceDatabase   string   `json:"source_database"`
+	SourceTable      string   `json:"source_table"`
+	TargetDatabase   string   `json:"target_database,omitempty"`
+	TargetTable      string   `json:"target_table,omitempty"`
+	SourceConnection string   `json:"source_connection,omitempty"`
+	TargetConnection string   `json:"target_connection,omitempty"`
+	Columns          []string `json:"columns,omitempty"`
+	IgnoreColumns    []string `json:"ignore_columns,omitempty"`
+	Limit            int      `json:"limit,omitempty"`
+	// After resumes a previous diff after the given primary key, as
+	// returned in nextAfter.
+	After map[string]any `json:"after,omitempty"`
+}
+
+// RowDifference is one row that differs between source and target. Change
+// is "inserted" for rows only in the target, "deleted" for rows only in the
+// source and "changed" for rows whose compared columns differ.
+type RowDifference struct {
+	Key     map[string]any          `json:"key"`
+	Change  string                  `json:"change"`
+	Row     map[string]any          `json:"row,omitempty"`
+	Columns map[string]ColumnChange `json:"columns,omitempty"`
+}
+
+type ColumnChange struct {
+	Source any `json:"source"`
+	Target any `json:"target"`
+}
+
+// dataDiffSide is one of the two tables being compared.
+type dataDiffSide struct {
+	conn     *sql.DB
+	database string
+	table    string
+}
+
+func DiffData(ctx context.Context, req *mcp.CallToolRequest, args DiffDataParams) (*mcp.CallToolResult, any, error) {
+	if args.SourceDatabase == "" || args.SourceTable == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "source_database and source_table are required"},
+			},
+		}, nil, nil
+	}
+	targetDatabase, targetTable := args.TargetDatabase, args.TargetTable
+	if targetDatabase == "" {
+		targetDatabase = args.SourceDatabase
+	}
+	if targetTable == "" {
+		targetTable = args.SourceTable
+	}
+	if targetDatabase == args.SourceDatabase && targetTable == args.SourceTable && args.TargetConnection == args.SourceConnection {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Source and target are the same table; give a different target table, database or connection"},
+			},
+		}, nil, nil
+	}
+
+	sourceConn, err := connectionFor(args.SourceConnection)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: connectionHint(err)},
+			},
+		}, nil, nil
+	}
+	targetConn, err := connectionFor(args.TargetConnection)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: connectionHint(err)},
+			},
+		}, nil, nil
+	}
+	source := dataDiffSide{conn: sourceConn, database: args.SourceDatabase, table: args.SourceTable}
+	target := dataDiffSide{conn: targetConn, database: targetDatabase, table: targetTable}
+
+	pk, columns, notes, err := comparableColumns(ctx, source, target, args.Columns, args.IgnoreColumns)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	var cursor []any
+	if len(args.After) > 0 {
+		for _, col := range pk {
+			v, ok := args.After[col]
+			if !ok {
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("after is missing primary key column %q", col)},
+					},
+				}, nil, nil
+			}
+			cursor = append(cursor, sqlValue(v))
+		}
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultDataDiffLimit
+	}
+
+	// The tables are walked together in primary key ranges. A chunk that
+	// would overflow the limit is retried at half the size, so a page ends
+	// close to the limit and after a key both sides were fully compared up
+	// to.
+	var differences []RowDifference
+	var scanned int64
+	complete := false
+	size := dataDiffChunk
+	for len(differences) < limit {
+		diffs, boundary, n, err := diffChunk(ctx, source, target, pk, columns, cursor, size)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to compare rows: %v", err)},
+				},
+			}, nil, nil
+		}
+		if len(differences)+len(diffs) > limit && size > 1 {
+			size = max(1, size/2)
+			continue
+		}
+		differences = append(differences, diffs...)
+		scanned += n
+		if boundary == nil {
+			complete = true
+			break
+		}
+		cursor = boundary
+	}
+
+	counts := map[string]int{}
+	for _, d := range differences {
+		counts[d.Change]++
+	}
+	resultText := fmt.Sprintf("Data diff: source %s.%s vs target %s.%s on (%s)\n",
+		describeSchemaLocation(args.SourceConnection, args.SourceDatabase), quoteIdentifier(args.SourceTable),
+		describeSchemaLocation(args.TargetConnection, targetDatabase), quoteIdentifier(targetTable), strings.Join(pk, ", "))
+	for _, note := range notes {
+		resultText += "Note: " + note + "\n"
+	}
+	resultText += fmt.Sprintf("Compared %d row(s): %d inserted, %d deleted, %d changed\n", scanned, counts["inserted"], counts["deleted"], counts["changed"])
+	for _, d := range differences {
+		resultText += describeRowDifference(pk, d)
+	}
+
+	structured := map[string]any{
+		"primaryKey":  pk,
+		"columns":     columns,
+		"scanned":     scanned,
+		"complete":    complete,
+		"differences": differences,
+	}
+	if complete {
+		if len(differences) == 0 && len(args.After) == 0 {
+			resultText += "The tables hold the same rows.\n"
+		}
+	} else {
+		next := make(map[string]any, len(pk))
+		for i, col := range pk {
+			next[col] = cursor[i]
+		}
+		structured["nextAfter"] = next
+		resultText += fmt.Sprintf("\nMore rows remain; pass after: %s to continue.\n", describeKey(pk, cursor))
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, structured, nil
+}
+
+// comparableColumns returns the shared primary key and the columns to
+// compare, with notes on columns that exist on one side only.
+func comparableColumns(ctx context.Context, source, target dataDiffSide, only, ignore []string) ([]string, []string, []string, error) {
+	sourceCols, sourcePK, err := tableLayout(ctx, source.conn, source.database, source.table)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Failed to read source table: %v", err)
+	}
+	targetCols, targetPK, err := tableLayout(ctx, target.conn, target.database, target.table)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Failed to read target table: %v", err)
+	}
+	if len(sourcePK) == 0 {
+		return nil, nil, nil, fmt.Errorf("%s.%s has no primary key to match rows by", source.database, source.table)
+	}
+	if !slices.Equal(sourcePK, targetPK) {
+		return nil, nil, nil, fmt.Errorf("The primary keys differ: (%s) in the source, (%s) in the target",
+			strings.Join(sourcePK, ", "), strings.Join(targetPK, ", "))
+	}
+
+	var columns, notes []string
+	for _, col := range sourceCols {
+		switch {
+		case slices.Contains(sourcePK, col), slices.Contains(ignore, col):
+		case len(only) > 0 && !slices.Contains(only, col):
+		case !slices.Contains(targetCols, col):
+			notes = append(notes, fmt.Sprintf("column %s exists only in the source and is not compared", col))
+		default:
+			columns = append(columns, col)
+		}
+	}
+	for _, col := range targetCols {
+		if !slices.Contains(sourceCols, col) && !slices.Contains(ignore, col) && (len(only) == 0 || slices.Contains(only, col)) {
+			notes = append(notes, fmt.Sprintf("column %s exists only in the target and is not compared", col))
+		}
+	}
+	for _, col := range only {
+		if !slices.Contains(sourceCols, col) && !slices.Contains(targetCols, col) {
+			return nil, nil, nil, fmt.Errorf("Unknown column %q", col)
+		}
+	}
+	return sourcePK, columns, notes, nil
+}
+
+// tableLayout returns the columns and primary key columns of a table on the
+// given connection.
+func tableLayout(ctx context.Context, conn *sql.DB, database, table string) ([]string, []string, error) {
+	var columns, pk []string
+	err := queryEach(ctx, conn, `SELECT COLUMN_NAME FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`, []any{database, table},
+		func(rows *sql.Rows) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			columns = append(columns, name)
+			return nil
+		})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("table %s.%s does not exist", database, table)
+	}
+	err = queryEach(ctx, conn, `SELECT COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY' ORDER BY ORDINAL_POSITION`, []any{database, table},
+		func(rows *sql.Rows) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			pk = append(pk, name)
+			return nil
+		})
+	return columns, pk, err
+}
+
+// diffChunk compares the rows after cursor up to a boundary key chosen so
+// that both sides are read completely up to it, fetching at most size rows
+// per side. It returns the differences, the boundary (nil once both tables
+// are exhausted) and the number of distinct keys compared.
+func diffChunk(ctx context.Context, source, target dataDiffSide, pk, columns []string, cursor []any, size int) ([]RowDifference, []any, int64, error) {
+	sourceRows, err := fetchKeyRange(ctx, source, pk, columns, cursor, nil, size)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	targetRows, err := fetchKeyRange(ctx, target, pk, columns, cursor, nil, size)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	// A side that returned fewer rows than asked for has been read to its
+	// end. The boundary is the last key of a full side, the smaller one
+	// when both are full; the other side is re-read up to it.
+	var boundary []any
+	sourceFull, targetFull := len(sourceRows) == size, len(targetRows) == size
+	switch {
+	case sourceFull && targetFull:
+		sourceLast, targetLast := rowKey(sourceRows[len(sourceRows)-1], pk), rowKey(targetRows[len(targetRows)-1], pk)
+		less, err := keyLess(ctx, source.conn, targetLast, sourceLast)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		if less {
+			boundary = targetLast
+			sourceRows, err = fetchKeyRange(ctx, source, pk, columns, cursor, boundary, 0)
+		} else {
+			boundary = sourceLast
+			targetRows, err = fetchKeyRange(ctx, target, pk, columns, cursor, boundary, 0)
+		}
+	case sourceFull:
+		boundary = rowKey(sourceRows[len(sourceRows)-1], pk)
+		targetRows, err = fetchKeyRange(ctx, target, pk, columns, cursor, boundary, 0)
+	case targetFull:
+		boundary = rowKey(targetRows[len(targetRows)-1], pk)
+		sourceRows, err = fetchKeyRange(ctx, source, pk, columns, cursor, boundary, 0)
+	}
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	targetByKey := make(map[string]map[string]any, len(targetRows))
+	for _, row := range targetRows {
+		targetByKey[tupleIdentity(rowKey(row, pk))] = row
+	}
+	var diffs []RowDifference
+	seen := make(map[string]bool, len(sourceRows))
+	for _, row := range sourceRows {
+		id := tupleIdentity(rowKey(row, pk))
+		seen[id] = true
+		other, ok := targetByKey[id]
+		if !ok {
+			diffs = append(diffs, RowDifference{Key: keyMap(row, pk), Change: "deleted", Row: row})
+			continue
+		}
+		changes := make(map[string]ColumnChange)
+		for _, col := range columns {
+			if tupleIdentity([]any{row[col]}) != tupleIdentity([]any{other[col]}) {
+				changes[col] = ColumnChange{Source: row[col], Target: other[col]}
+			}
+		}
+		if len(changes) > 0 {
+			diffs = append(diffs, RowDifference{Key: keyMap(row, pk), Change: "changed", Columns: changes})
+		}
+	}
+	compared := int64(len(sourceRows))
+	for _, row := range targetRows {
+		if !seen[tupleIdentity(rowKey(row, pk))] {
+			diffs = append(diffs, RowDifference{Key: keyMap(row, pk), Change: "inserted", Row: row})
+			compared++
+		}
+	}
+	return diffs, boundary, compared, nil
+}
+
+// fetchKeyRange reads the primary key and columns of the rows with a key
+// after after (all rows if nil) and up to upTo (no upper bound if nil), in
+// key order, at most limit rows if limit is positive.
+func fetchKeyRange(ctx context.Context, side dataDiffSide, pk, columns []string, after, upTo []any, limit int) ([]map[string]any, error) {
+	keyList := quoteIdentifierList(pk)
+	tuple := "(" + keyList + ")"
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(pk)), ", ") + ")"
+	var conds []string
+	var params []any
+	if after != nil {
+		conds = append(conds, fmt.Sprintf("%s > %s", tuple, placeholders))
+		params = append(params, after...)
+	}
+	if upTo != nil {
+		conds = append(conds, fmt.Sprintf("%s <= %s", tuple, placeholders))
+		params = append(params, upTo...)
+	}
+	selected := keyList
+	if len(columns) > 0 {
+		selected += ", " + quoteIdentifierList(columns)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", selected, qualifiedTable(side.database, side.table))
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY " + keyList
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	rows, err := side.conn.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	_, results, err := scanRowMaps(rows)
+	return results, err
+}
+
+// keyLess reports whether key a sorts before key b, compared by the server
+// so the order matches ORDER BY.
+func keyLess(ctx context.Context, conn *sql.DB, a, b []any) (bool, error) {
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(a)), ", ") + ")"
+	var less bool
+	err := conn.QueryRowContext(ctx, fmt.Sprintf("SELECT %s < %s", placeholders, placeholders), append(append([]any{}, a...), b...)...).Scan(&less)
+	return less, err
+}
+
+func rowKey(row map[string]any, pk []string) []any {
+	key := make([]any, len(pk))
+	for i, col := range pk {
+		key[i] = row[col]
+	}
+	return key
+}
+
+func keyMap(row map[string]any, pk []string) map[string]any {
+	key := make(map[string]any, len(pk))
+	for _, col := range pk {
+		key[col] = row[col]
+	}
+	return key
+}
+
+func describeKey(pk []string, key []any) string {
+	parts := make([]string, len(pk))
+	for i, col := range pk {
+		parts[i] = fmt.Sprintf("%s=%s", col, sqlLiteral(key[i]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describeRowDifference(pk []string, d RowDifference) string {
+	key := describeKey(pk, rowKey(d.Key, pk))
+	switch d.Change {
+	case "inserted":
+		return fmt.Sprintf("+ %s only in target\n", key)
+	case "deleted":
+		return fmt.Sprintf("- %s only in source\n", key)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "~ %s:\n", key)
+	for _, col := range sortedKeys(d.Columns) {
+		c := d.Columns[col]
+		fmt.Fprintf(&b, "    %s: %s -> %s\n", col, sqlLiteral(c.Source), sqlLiteral(c.Target))
+	}
+	return b.String()
+}
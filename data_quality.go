@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// defaultQualitySampleRows is the table size above which data_quality
+	// samples rows instead of reading all of them.
+	defaultQualitySampleRows = 100000
+	// mismatchShare is the share of non-empty values that must look numeric
+	// (or like dates) before a string column is flagged.
+	mismatchShare = 0.95
+)
+
+type DataQualityParams struct {
+	Database   string   `json:"database"`
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns,omitempty"`
+	SampleRows int      `json:"sample_rows,omitempty"`
+}
+
+// ColumnQuality holds the measurements for one column. Counts that do not
+// apply to the column's type are left at zero.
+type ColumnQuality struct {
+	Column          string   `json:"column"`
+	Type            string   `json:"type"`
+	Nulls           int64    `json:"nulls"`
+	NullRate        float64  `json:"nullRate"`
+	Distinct        int64    `json:"distinct"`
+	Empty           int64    `json:"empty,omitempty"`
+	Whitespace      int64    `json:"whitespace,omitempty"`
+	NumericStrings  int64    `json:"numericStrings,omitempty"`
+	DateStrings     int64    `json:"dateStrings,omitempty"`
+	ZeroDates       int64    `json:"zeroDates,omitempty"`
+	OutOfRangeDates int64    `json:"outOfRangeDates,omitempty"`
+	Min             any      `json:"min,omitempty"`
+	Max             any      `json:"max,omitempty"`
+	Issues          []string `json:"issues,omitempty"`
+}
+
+var (
+	stringTypes   = map[string]bool{"char": true, "varchar": true, "tinytext": true, "text": true, "mediumtext": true, "longtext": true}
+	dateTypes     = map[string]bool{"date": true, "datetime": true, "timestamp": true}
+	rangeableType = map[string]bool{"tinyint": true, "smallint": true, "mediumint": true, "int": true, "bigint": true,
+		"decimal": true, "float": true, "double": true, "date": true, "datetime": true, "timestamp": true, "time": true, "year": true}
+)
+
+func DataQuality(ctx context.Context, req *mcp.CallToolRequest, args DataQualityParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	columns := tableCols
+	if len(args.Columns) > 0 {
+		byName := make(map[string]ColumnInfo, len(tableCols))
+		for _, col := range tableCols {
+			byName[col.ColumnName] = col
+		}
+		columns = nil
+		for _, name := range args.Columns {
+			col, ok := byName[name]
+			if !ok {
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("Unknown column %q", name)},
+					},
+				}, nil, nil
+			}
+			columns = append(columns, col)
+		}
+	}
+
+	sampleRows := args.SampleRows
+	if sampleRows <= 0 {
+		sampleRows = defaultQualitySampleRows
+	}
+	estimates, err := baseTableRows(ctx, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read table size: %v", err)},
+			},
+		}, nil, nil
+	}
+	// Large tables are sampled with a random filter sized to return about
+	// sampleRows rows; the scan still reads the table but the per-column
+	// checks only run on the sample.
+	source := qualifiedTable(args.Database, args.Table)
+	sampled := estimates[args.Table] > int64(sampleRows)
+	if sampled {
+		fraction := float64(sampleRows) / float64(estimates[args.Table])
+		source = fmt.Sprintf("(SELECT * FROM %s WHERE RAND() < %s) AS sampled", source, strconv.FormatFloat(fraction, 'f', 6, 64))
+	}
+
+	exprs := []string{"COUNT(*) AS row_count"}
+	for i, col := range columns {
+		c := quoteIdentifier(col.ColumnName)
+		exprs = append(exprs,
+			fmt.Sprintf("SUM(%s IS NULL) AS n%d", c, i),
+			fmt.Sprintf("COUNT(DISTINCT %s) AS d%d", c, i))
+		if stringTypes[col.DataType] {
+			exprs = append(exprs,
+				fmt.Sprintf("SUM(%s = '') AS e%d", c, i),
+				fmt.Sprintf("SUM(%s REGEXP '^[[:space:]]|[[:space:]]$') AS w%d", c, i),
+				fmt.Sprintf("SUM(%s REGEXP '^[-+]?[0-9]+([.][0-9]+)?$') AS num%d", c, i),
+				fmt.Sprintf("SUM(%s REGEXP '^[0-9]{4}-[0-9]{2}-[0-9]{2}') AS dt%d", c, i))
+		}
+		if dateTypes[col.DataType] {
+			// Comparing through CAST avoids errors for zero dates under
+			// strict SQL modes.
+			exprs = append(exprs,
+				fmt.Sprintf("SUM(CAST(%s AS CHAR) LIKE '0000-00-00%%') AS z%d", c, i),
+				fmt.Sprintf("SUM(CAST(%s AS CHAR) NOT LIKE '0000-00-00%%' AND (YEAR(%s) < 1900 OR YEAR(%s) > 2100)) AS r%d", c, c, c, i))
+		}
+		if rangeableType[col.DataType] {
+			exprs = append(exprs,
+				fmt.Sprintf("MIN(%s) AS min%d", c, i),
+				fmt.Sprintf("MAX(%s) AS max%d", c, i))
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), source))
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to profile table: %v", err)},
+			},
+		}, nil, nil
+	}
+	_, results, err := scanRowMaps(rows)
+	rows.Close()
+	if err != nil || len(results) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read profile: %v", err)},
+			},
+		}, nil, nil
+	}
+	stats := results[0]
+	rowCount := numericValue(stats["row_count"])
+
+	report := make([]ColumnQuality, len(columns))
+	for i, col := range columns {
+		get := func(prefix string) int64 { return int64(numericValue(stats[fmt.Sprintf("%s%d", prefix, i)])) }
+		q := ColumnQuality{
+			Column:          col.ColumnName,
+			Type:            col.DataType,
+			Nulls:           get("n"),
+			Distinct:        get("d"),
+			Empty:           get("e"),
+			Whitespace:      get("w"),
+			NumericStrings:  get("num"),
+			DateStrings:     get("dt"),
+			ZeroDates:       get("z"),
+			OutOfRangeDates: get("r"),
+			Min:             stats[fmt.Sprintf("min%d", i)],
+			Max:             stats[fmt.Sprintf("max%d", i)],
+		}
+		if rowCount > 0 {
+			q.NullRate = float64(q.Nulls) / rowCount
+		}
+		q.Issues = qualityIssues(q, rowCount)
+		report[i] = q
+	}
+
+	resultText := fmt.Sprintf("Data quality for %s.%s: %d rows", args.Database, args.Table, int64(rowCount))
+	if sampled {
+		resultText += fmt.Sprintf(" (random sample of about %d from ~%d)", sampleRows, estimates[args.Table])
+	}
+	resultText += "\n\n"
+	flagged := 0
+	for _, q := range report {
+		resultText += fmt.Sprintf("%s (%s): %.1f%% NULL, %d distinct", q.Column, q.Type, q.NullRate*100, q.Distinct)
+		if q.Min != nil || q.Max != nil {
+			resultText += fmt.Sprintf(", range %v .. %v", q.Min, q.Max)
+		}
+		resultText += "\n"
+		for _, issue := range q.Issues {
+			resultText += "  ! " + issue + "\n"
+		}
+		if len(q.Issues) > 0 {
+			flagged++
+		}
+	}
+	resultText += fmt.Sprintf("\n%d of %d columns have potential issues\n", flagged, len(report))
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"rows":    int64(rowCount),
+		"sampled": sampled,
+		"columns": report,
+	}, nil
+}
+
+// qualityIssues turns the measurements of a column into findings.
+func qualityIssues(q ColumnQuality, rows float64) []string {
+	var issues []string
+	if rows == 0 {
+		return nil
+	}
+	if q.Nulls == int64(rows) {
+		issues = append(issues, "all values are NULL")
+	} else if q.NullRate > 0.5 {
+		issues = append(issues, fmt.Sprintf("mostly NULL (%.1f%%)", q.NullRate*100))
+	}
+	if q.Distinct == 1 && q.Nulls == 0 && rows > 1 {
+		issues = append(issues, "every row has the same value")
+	}
+	if q.Empty > 0 {
+		issues = append(issues, fmt.Sprintf("%d empty string(s); NULL may be intended", q.Empty))
+	}
+	if q.Whitespace > 0 {
+		issues = append(issues, fmt.Sprintf("%d value(s) with leading or trailing whitespace", q.Whitespace))
+	}
+	if q.ZeroDates > 0 {
+		issues = append(issues, fmt.Sprintf("%d zero date(s) (0000-00-00)", q.ZeroDates))
+	}
+	if q.OutOfRangeDates > 0 {
+		issues = append(issues, fmt.Sprintf("%d date(s) before 1900 or after 2100", q.OutOfRangeDates))
+	}
+	filled := float64(int64(rows) - q.Nulls - q.Empty)
+	if filled > 0 && float64(q.NumericStrings) >= filled*mismatchShare {
+		issues = append(issues, fmt.Sprintf("%d of %d values are numeric; a numeric type may fit better", q.NumericStrings, int64(filled)))
+	} else if filled > 0 && float64(q.DateStrings) >= filled*mismatchShare {
+		issues = append(issues, fmt.Sprintf("%d of %d values look like dates; a DATE or DATETIME type may fit better", q.DateStrings, int64(filled)))
+	}
+	return issues
+}
+
+// numericValue converts an aggregate result, which the driver returns as an
+// integer, float or numeric string, to a float64.
+func numericValue(v any) float64 {
+	switch val := v.(type) {
+	case int64:
+		return float64(val)
+	case float64:
+		return val
+	case string:
+		f, _ := strconv.ParseFloat(val, 64)
+		return f
+	}
+	return 0
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// systemSchemas can never be dropped through this server.
+var systemSchemas = map[string]bool{
+	"mysql":              true,
+	"information_schema": true,
+	"performance_schema": true,
+	"sys":                true,
+}
+
+var charsetNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+type CreateDatabaseParams struct {
+	Name        string `json:"name"`
+	Charset     string `json:"charset,omitempty"`
+	Collation   string `json:"collation,omitempty"`
+	IfNotExists bool   `json:"if_not_exists,omitempty"`
+}
+
+type DropDatabaseParams struct {
+	Name        string `json:"name"`
+	ConfirmName string `json:"confirm_name"`
+	Force       bool   `json:"force,omitempty"`
+}
+
+func CreateDatabase(ctx context.Context, req *mcp.CallToolRequest, args CreateDatabaseParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("create_database"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	if strings.TrimSpace(args.Name) == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Database name cannot be empty"},
+			},
+		}, nil, nil
+	}
+
+	if err := checkIdentifier("database", args.Name); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Cannot create the database: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	for _, opt := range []string{args.Charset, args.Collation} {
+		if opt != "" && !charsetNamePattern.MatchString(opt) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid charset or collation name %q", opt)},
+				},
+			}, nil, nil
+		}
+	}
+
+	stmt := "CREATE DATABASE "
+	if args.IfNotExists {
+		stmt += "IF NOT EXISTS "
+	}
+	stmt += quoteIdentifier(args.Name)
+	if args.Charset != "" {
+		stmt += " CHARACTER SET " + args.Charset
+	}
+	if args.Collation != "" {
+		stmt += " COLLATE " + args.Collation
+	}
+
+	_, err := db.ExecContext(ctx, stmt)
+	audit("create_database", stmt, 0, err)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to create database: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Created database '%s'\n%s", args.Name, stmt)},
+		},
+	}, map[string]any{
+		"database":  args.Name,
+		"statement": stmt,
+	}, nil
+}
+
+func DropDatabase(ctx context.Context, req *mcp.CallToolRequest, args DropDatabaseParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("drop_database"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	if systemSchemas[strings.ToLower(args.Name)] {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Refusing to drop system database '%s'", args.Name)},
+			},
+		}, nil, nil
+	}
+
+	if args.ConfirmName != args.Name {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("confirm_name must exactly match the database name '%s' to drop it", args.Name)},
+			},
+		}, nil, nil
+	}
+
+	var exists int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", args.Name).Scan(&exists); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to look up database: %v", err)},
+			},
+		}, nil, nil
+	}
+	if exists == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Database '%s' does not exist", args.Name)},
+			},
+		}, nil, nil
+	}
+
+	var tables, routines int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ?", args.Name).Scan(&tables); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to count tables: %v", err)},
+			},
+		}, nil, nil
+	}
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ?", args.Name).Scan(&routines); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to count routines: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	if (tables > 0 || routines > 0) && !args.Force {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Database '%s' is not empty (%d tables/views, %d routines). Nothing was dropped; pass force: true to drop it anyway.", args.Name, tables, routines)},
+			},
+		}, nil, nil
+	}
+
+	stmt := "DROP DATABASE " + quoteIdentifier(args.Name)
+	_, err := db.ExecContext(ctx, stmt)
+	audit("drop_database", stmt, 0, err)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to drop database: %v", err)},
+			},
+		}, nil, nil
+	}
+	clearSchemaCache()
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Dropped database '%s' (%d tables/views, %d routines)", args.Name, tables, routines)},
+		},
+	}, map[string]any{
+		"database": args.Name,
+		"tables":   tables,
+		"routines": routines,
+	}, nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type DDLProgressParams struct {
+	EnableInstrumentation bool `json:"enable_instrumentation,omitempty"`
+}
+
+// DDLProgress is the state of one running ALTER TABLE or CREATE INDEX.
+type DDLProgress struct {
+	ConnectionID     int64   `json:"connectionId"`
+	User             string  `json:"user"`
+	Database         string  `json:"database,omitempty"`
+	Statement        string  `json:"statement"`
+	Stage            string  `json:"stage,omitempty"`
+	WorkCompleted    int64   `json:"workCompleted,omitempty"`
+	WorkEstimated    int64   `json:"workEstimated,omitempty"`
+	Percent          float64 `json:"percent,omitempty"`
+	ElapsedSeconds   int64   `json:"elapsedSeconds"`
+	RemainingSeconds int64   `json:"remainingSeconds,omitempty"`
+}
+
+// mysqlDDLProgressQuery lists every running DDL statement, whether or not
+// the stage instruments report progress for it.
+const mysqlDDLProgressQuery = `
+		SELECT t.PROCESSLIST_ID, COALESCE(t.PROCESSLIST_USER, ''), COALESCE(t.PROCESSLIST_DB, ''),
+		       LEFT(t.PROCESSLIST_INFO, 500), COALESCE(t.PROCESSLIST_TIME, 0),
+		       s.EVENT_NAME, s.WORK_COMPLETED, s.WORK_ESTIMATED
+		FROM performance_schema.threads t
+		LEFT JOIN performance_schema.events_stages_current s
+		       ON s.THREAD_ID = t.THREAD_ID AND s.EVENT_NAME LIKE 'stage/innodb/alter%'
+		WHERE t.PROCESSLIST_COMMAND = 'Query'
+		  AND (t.PROCESSLIST_INFO LIKE 'ALTER%' OR t.PROCESSLIST_INFO LIKE 'CREATE%INDEX%'
+		       OR t.PROCESSLIST_INFO LIKE 'OPTIMIZE%' OR s.EVENT_NAME IS NOT NULL)
+		ORDER BY t.PROCESSLIST_TIME DESC
+	`
+
+// mariaDBDDLProgressQuery reads the same columns from MariaDB's process
+// list, which numbers the stages and reports progress as a percentage; it
+// is returned as hundredths of a percent out of 10000 work units.
+const mariaDBDDLProgressQuery = `
+		SELECT ID, COALESCE(USER, ''), COALESCE(DB, ''), LEFT(INFO, 500), COALESCE(TIME, 0),
+		       IF(MAX_STAGE > 0, CONCAT('stage ', STAGE, ' of ', MAX_STAGE), NULL),
+		       ROUND(PROGRESS * 100), IF(MAX_STAGE > 0, 10000, NULL)
+		FROM information_schema.PROCESSLIST
+		WHERE COMMAND = 'Query'
+		  AND (INFO LIKE 'ALTER%' OR INFO LIKE 'CREATE%INDEX%' OR INFO LIKE 'OPTIMIZE%')
+		ORDER BY TIME DESC
+	`
+
+// tidbDDLProgressQuery reads TiDB's process list, matching each statement
+// to its DDL job for the schema state and the rows backfilled so far. TiDB
+// does not estimate the total.
+const tidbDDLProgressQuery = `
+		SELECT p.ID, COALESCE(p.USER, ''), COALESCE(p.DB, ''), LEFT(p.INFO, 500), COALESCE(p.TIME, 0),
+		       j.SCHEMA_STATE, j.ROW_COUNT, NULL
+		FROM information_schema.PROCESSLIST p
+		LEFT JOIN information_schema.DDL_JOBS j ON j.QUERY = p.INFO AND j.STATE = 'running'
+		WHERE p.COMMAND = 'Query'
+		  AND (p.INFO LIKE 'ALTER%' OR p.INFO LIKE 'CREATE%INDEX%')
+		ORDER BY p.TIME DESC
+	`
+
+func GetDDLProgress(ctx context.Context, req *mcp.CallToolRequest, args DDLProgressParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	// Through vtgate, performance_schema and the process list come from a
+	// single shard, and schema changes run as Vitess migrations.
+	server := serverInfoFor(ctx, db)
+	if server.Vitess {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "ddl_progress cannot see the shards behind Vitess; run SHOW VITESS_MIGRATIONS with execute_query to follow online DDL"},
+			},
+		}, nil, nil
+	}
+
+	// MariaDB and TiDB report the progress of every ALTER TABLE and have no
+	// stage instruments to enable.
+	instrumented := !server.MariaDB && !server.TiDB
+	if args.EnableInstrumentation && instrumented {
+		if err := checkWritable("ddl_progress"); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: err.Error()},
+				},
+			}, nil, nil
+		}
+		// Only operations started after this point report progress.
+		for _, stmt := range []string{
+			"UPDATE performance_schema.setup_instruments SET ENABLED = 'YES', TIMED = 'YES' WHERE NAME LIKE 'stage/innodb/alter%'",
+			"UPDATE performance_schema.setup_consumers SET ENABLED = 'YES' WHERE NAME LIKE 'events_stages_%'",
+		} {
+			_, err := db.ExecContext(ctx, stmt)
+			audit("ddl_progress", stmt, 0, err)
+			if err != nil {
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("Failed to enable instrumentation: %v", err)},
+					},
+				}, nil, nil
+			}
+		}
+	}
+
+	query, source := mysqlDDLProgressQuery, "performance_schema (is performance_schema enabled?)"
+	switch {
+	case server.MariaDB:
+		query, source = mariaDBDDLProgressQuery, "information_schema.PROCESSLIST"
+	case server.TiDB:
+		query, source = tidbDDLProgressQuery, "information_schema.PROCESSLIST and DDL_JOBS"
+	}
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to query %s: %v", source, err)},
+			},
+		}, nil, nil
+	}
+	defer rows.Close()
+
+	var operations []DDLProgress
+	for rows.Next() {
+		var p DDLProgress
+		var stmt, stage sql.NullString
+		var completed, estimated sql.NullInt64
+		if err := rows.Scan(&p.ConnectionID, &p.User, &p.Database, &stmt, &p.ElapsedSeconds, &stage, &completed, &estimated); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to read progress: %v", err)},
+				},
+			}, nil, nil
+		}
+		p.Statement = stmt.String
+		p.Stage = stage.String
+		p.WorkCompleted = completed.Int64
+		p.WorkEstimated = estimated.Int64
+		if p.WorkEstimated > 0 {
+			p.Percent = 100 * float64(p.WorkCompleted) / float64(p.WorkEstimated)
+			// Assume the remaining work proceeds at the average rate so far.
+			if p.WorkCompleted > 0 && p.ElapsedSeconds > 0 {
+				p.RemainingSeconds = p.ElapsedSeconds * (p.WorkEstimated - p.WorkCompleted) / p.WorkCompleted
+			}
+		}
+		operations = append(operations, p)
+	}
+	if err := rows.Err(); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read progress: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	if len(operations) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "No ALTER TABLE or CREATE INDEX statements are running"},
+			},
+		}, map[string]any{
+			"operations": operations,
+		}, nil
+	}
+
+	resultText := fmt.Sprintf("%d DDL operations running:\n", len(operations))
+	missing := false
+	for _, p := range operations {
+		resultText += fmt.Sprintf("\nConnection %d (%s", p.ConnectionID, p.User)
+		if p.Database != "" {
+			resultText += ", " + p.Database
+		}
+		resultText += fmt.Sprintf("), running %s\n  %s\n", time.Duration(p.ElapsedSeconds)*time.Second, p.Statement)
+		if p.Stage == "" {
+			resultText += "  Progress: not reported\n"
+			missing = true
+			continue
+		}
+		if p.WorkEstimated == 0 {
+			resultText += fmt.Sprintf("  Stage: %s\n  Progress: %d rows processed\n", p.Stage, p.WorkCompleted)
+			continue
+		}
+		resultText += fmt.Sprintf("  Stage: %s\n  Progress: %.1f%% (%d of %d work units)",
+			p.Stage, p.Percent, p.WorkCompleted, p.WorkEstimated)
+		if p.RemainingSeconds > 0 {
+			resultText += fmt.Sprintf(", about %s remaining", time.Duration(p.RemainingSeconds)*time.Second)
+		}
+		resultText += "\n"
+	}
+	if missing && instrumented {
+		resultText += "\nProgress is only reported for InnoDB operations started while the stage/innodb/alter% instruments and events_stages_current consumer are enabled. Call ddl_progress with enable_instrumentation: true to enable them for future operations."
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"operations": operations,
+	}, nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// debugSQL turns on logging of every statement, set with -debug-sql or the
+// set_debug_sql tool.
+var debugSQL atomic.Bool
+
+// debugSQLParams is the policy for bind parameters and literals in the SQL
+// debug log, set with -debug-sql-params. It can only be changed at startup,
+// so an agent cannot widen what is logged.
+//
+//	redact:   literals are replaced by ? and parameters by their type and size
+//	truncate: statements and parameters are logged, cut to debugSQLTruncate
+//	full:     statements and parameters are logged as they are
+var debugSQLParams = "redact"
+
+const debugSQLTruncate = 64
+
+func validateDebugSQLParams() error {
+	switch debugSQLParams {
+	case "redact", "truncate", "full":
+		return nil
+	}
+	return fmt.Errorf("invalid -debug-sql-params %q (use redact, truncate or full)", debugSQLParams)
+}
+
+// logStatement writes one statement to the SQL debug log.
+func logStatement(kind, query string, args []driver.NamedValue, elapsed time.Duration, rows int64, err error) {
+	text := query
+	switch debugSQLParams {
+	case "redact":
+		text = sanitizeStatement(query)
+	case "truncate":
+		text = truncateForLog(query, debugSQLTruncate*16)
+	}
+	params := make([]string, len(args))
+	for i, arg := range args {
+		params[i] = debugParam(arg.Value)
+	}
+	attrs := []any{"kind", kind, "statement", text, "params", params, "duration_ms", milliseconds(elapsed)}
+	if kind == "query" {
+		attrs = append(attrs, "rows", rows)
+	} else {
+		attrs = append(attrs, "rows_affected", rows)
+	}
+	if err != nil {
+		attrs = append(attrs, "err", err)
+	}
+	slog.Info("sql", attrs...)
+}
+
+// debugParam renders a bind parameter under the debugSQLParams policy.
+func debugParam(v driver.Value) string {
+	if v == nil {
+		return "NULL"
+	}
+	if debugSQLParams == "redact" {
+		switch v := v.(type) {
+		case string:
+			return fmt.Sprintf("<string len=%d>", len(v))
+		case []byte:
+			return fmt.Sprintf("<bytes len=%d>", len(v))
+		default:
+			return fmt.Sprintf("<%T>", v)
+		}
+	}
+	var s string
+	switch v := v.(type) {
+	case []byte:
+		s = string(v)
+	case time.Time:
+		s = v.Format(time.RFC3339Nano)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if debugSQLParams == "truncate" {
+		return truncateForLog(s, debugSQLTruncate)
+	}
+	return s
+}
+
+func truncateForLog(s string, n int) string {
+	if t := truncateRunes(s, n); len(t) < len(s) {
+		return t + "..."
+	}
+	return s
+}
+
+type SetDebugSQLParams struct {
+	Enabled bool `json:"enabled"`
+}
+
+func SetDebugSQL(ctx context.Context, req *mcp.CallToolRequest, args SetDebugSQLParams) (*mcp.CallToolResult, any, error) {
+	was := debugSQL.Swap(args.Enabled)
+	state := "off"
+	if args.Enabled {
+		state = "on"
+	}
+	slog.Info("SQL debug logging switched", "enabled", args.Enabled, "params", debugSQLParams)
+
+	resultText := fmt.Sprintf("SQL debug logging is %s", state)
+	if was == args.Enabled {
+		resultText += " (unchanged)"
+	}
+	if args.Enabled {
+		resultText += fmt.Sprintf(". Every statement is logged with its parameters (policy: %s), duration and row count", debugSQLParams)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText + "\n"},
+		},
+	}, map[string]any{
+		"enabled":  args.Enabled,
+		"previous": was,
+		"params":   debugSQLParams,
+	}, nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const deletePreviewSampleSize = 5
+
+type DeleteRowsParams struct {
+	Database      string   `json:"database"`
+	Table         string   `json:"table"`
+	Filters       []Filter `json:"filters"`
+	Confirm       bool     `json:"confirm,omitempty"`
+	ExpectedCount *int64   `json:"expected_count,omitempty"`
+}
+
+func DeleteRows(ctx context.Context, req *mcp.CallToolRequest, args DeleteRowsParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("delete_rows"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	if len(args.Filters) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "At least one filter is required; delete_rows will not delete every row of a table"},
+			},
+		}, nil, nil
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	where, params, err := buildWhere(args.Filters, tableCols)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid filters: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	table := qualifiedTable(args.Database, args.Table)
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE %s", table, where)
+
+	if !args.Confirm {
+		var matched int64
+		countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table, where)
+		if err := db.QueryRowContext(ctx, countSQL, params...).Scan(&matched); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to count matching rows: %v", err)},
+				},
+			}, nil, nil
+		}
+
+		sampleSQL := fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT %d", table, where, deletePreviewSampleSize)
+		rows, err := db.QueryContext(ctx, sampleSQL, params...)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to fetch sample rows: %v", err)},
+				},
+			}, nil, nil
+		}
+		columns, sample, err := scanRowMaps(rows)
+		rows.Close()
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to read sample rows: %v", err)},
+				},
+			}, nil, nil
+		}
+
+		resultText := fmt.Sprintf("Preview: %d rows in %s.%s match and would be deleted.\n\n%s\n",
+			matched, args.Database, args.Table, deleteSQL)
+		if len(sample) > 0 {
+			resultText += fmt.Sprintf("\nSample (%d rows):\n%s", len(sample), formatRowTable(columns, sample))
+		}
+		if matched > 0 {
+			resultText += fmt.Sprintf("\nNothing was deleted. Re-run with confirm: true and expected_count: %d to delete these rows.", matched)
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, map[string]any{
+			"matchedRows": matched,
+			"sample":      sample,
+			"sql":         deleteSQL,
+		}, nil
+	}
+
+	if args.ExpectedCount == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "expected_count is required with confirm: true. Run delete_rows without confirm first to preview the matching rows."},
+			},
+		}, nil, nil
+	}
+
+	// The delete is run again with its transaction after a deadlock, which
+	// rolls the transaction back. Errors name the step that failed.
+	var rowsAffected int64
+	var mismatch bool
+	retries, err := retryTransient(ctx, func() error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(ctx, deleteSQL, params...)
+		if err != nil {
+			audit("delete_rows", deleteSQL, 0, err)
+			return fmt.Errorf("delete rows: %w", err)
+		}
+		rowsAffected, _ = res.RowsAffected()
+
+		// The data may have changed since the preview; never delete a
+		// different number of rows than the caller confirmed.
+		if mismatch = rowsAffected != *args.ExpectedCount; mismatch {
+			return nil
+		}
+
+		if err := tx.Commit(); err != nil {
+			audit("delete_rows", deleteSQL, 0, err)
+			return fmt.Errorf("commit delete: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Failed to " + err.Error() + retryNote(retries)},
+			},
+		}, nil, nil
+	}
+	if mismatch {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Delete rolled back: it matched %d rows but expected_count was %d. Preview again before confirming.", rowsAffected, *args.ExpectedCount)},
+			},
+		}, nil, nil
+	}
+
+	audit("delete_rows", deleteSQL, rowsAffected, nil)
+	structured := map[string]any{"rowsAffected": rowsAffected}
+	if retries > 0 {
+		structured["retries"] = retries
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Deleted %d rows from %s.%s", rowsAffected, args.Database, args.Table) + retryNote(retries)},
+		},
+	}, structured, nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDeleteRows(t *testing.T) {
+	withFastRetries(t)
+	count := func(n int64) *int64 { return &n }
+
+	tests := []struct {
+		name          string
+		filters       []Filter
+		matched       int64
+		deadlocks     int
+		confirm       bool
+		expectedCount *int64
+
+		wantError    string
+		wantText     string
+		wantDeletes  int
+		wantCommit   bool
+		wantRollback bool
+	}{
+		{name: "preview", matched: 7, wantText: "Sample (5 rows)"},
+		{name: "preview without matches", matched: 0, wantText: "0 rows in shop.items match"},
+		{name: "no filters", filters: []Filter{}, matched: 1, wantError: "At least one filter is required"},
+		{name: "unknown column", filters: []Filter{{Column: "colour", Op: "=", Value: "red"}}, matched: 1, wantError: "Invalid filters"},
+		{name: "confirm without expected_count", matched: 2, confirm: true, wantError: "expected_count is required"},
+		{
+			name: "confirmed", matched: 2, confirm: true, expectedCount: count(2),
+			wantText: "Deleted 2 rows from shop.items", wantDeletes: 1, wantCommit: true,
+		},
+		{
+			name: "count changed since preview", matched: 3, confirm: true, expectedCount: count(2),
+			wantError: "Delete rolled back: it matched 3 rows but expected_count was 2", wantDeletes: 1, wantRollback: true,
+		},
+		{
+			name: "deadlock retried", matched: 2, deadlocks: 1, confirm: true, expectedCount: count(2),
+			wantText: "Retried 1 time(s)", wantDeletes: 2, wantCommit: true, wantRollback: true,
+		},
+	}
+	for _, tt := range tests {
+		server := matchServer(tt.matched, tt.deadlocks)
+		withTableColumns(t, withFakeConnection(t, server), "shop", "items", "id", "qty")
+		filters := tt.filters
+		if filters == nil {
+			filters = []Filter{{Column: "qty", Op: "<", Value: float64(10)}}
+		}
+
+		res, _, err := DeleteRows(context.Background(), nil, DeleteRowsParams{
+			Database: "shop", Table: "items", Filters: filters,
+			Confirm: tt.confirm, ExpectedCount: tt.expectedCount,
+		})
+		if err != nil {
+			t.Fatalf("%s: DeleteRows() error = %v", tt.name, err)
+		}
+		text := toolErrorText(res)
+		if res.IsError != (tt.wantError != "") || !strings.Contains(text, tt.wantError+tt.wantText) {
+			t.Errorf("%s: result %q (error %v); want %q", tt.name, text, res.IsError, tt.wantError+tt.wantText)
+		}
+		if deletes := countStatements(server, "DELETE FROM `shop`.`items` WHERE"); deletes != tt.wantDeletes {
+			t.Errorf("%s: %d deletes run, want %d; statements %q", tt.name, deletes, tt.wantDeletes, server.statements())
+		}
+		if server.ran("COMMIT") != tt.wantCommit || server.ran("ROLLBACK") != tt.wantRollback {
+			t.Errorf("%s: statements %q; want commit %v, rollback %v", tt.name, server.statements(), tt.wantCommit, tt.wantRollback)
+		}
+	}
+}
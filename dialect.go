@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// serverInfo identifies the server behind a connection, for the features
+// that differ between Oracle MySQL, MariaDB and TiDB or between versions.
+type serverInfo struct {
+	Version string
+	MariaDB bool
+	TiDB    bool
+	// AuroraVersion is the Amazon Aurora release (@@aurora_version) on
+	// Aurora MySQL, which otherwise reports the MySQL version it is based on.
+	AuroraVersion string
+	// DoltVersion is the Dolt release (dolt_version()) on Dolt, which
+	// reports a MySQL version for compatibility.
+	DoltVersion string
+	// Vitess is set behind vtgate, including PlanetScale; the version is
+	// the MySQL version vtgate reports.
+	Vitess bool
+	// Proxy names the proxy in front of the server, "ProxySQL" or "MySQL
+	// Router", or is empty for a direct connection.
+	Proxy string
+	// major, minor and patch are zero when the version could not be read;
+	// version checks then assume a current server. For TiDB they are the
+	// TiDB release, not the MySQL version it reports compatibility with.
+	major, minor, patch int
+}
+
+// serverInfos caches serverInfo per connection pool.
+var serverInfos = struct {
+	sync.Mutex
+	byDB map[*sql.DB]serverInfo
+}{byDB: make(map[*sql.DB]serverInfo)}
+
+// serverInfoFor returns what the server behind database reports about
+// itself, querying it on first use. A nil database or a failed query
+// yields a MySQL server of unknown version.
+func serverInfoFor(ctx context.Context, database *sql.DB) serverInfo {
+	if database == nil {
+		return serverInfo{}
+	}
+	serverInfos.Lock()
+	info, ok := serverInfos.byDB[database]
+	serverInfos.Unlock()
+	if ok {
+		return info
+	}
+
+	var version string
+	if err := database.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		slog.Debug("failed to read server version", "err", err)
+		return serverInfo{}
+	}
+	info = parseServerVersion(version)
+	if !info.MariaDB && !info.TiDB && !info.Vitess {
+		// Only Aurora has the variable and only Dolt the function;
+		// elsewhere the queries fail.
+		var aurora, dolt string
+		if err := database.QueryRowContext(ctx, "SELECT @@aurora_version").Scan(&aurora); err == nil {
+			info.AuroraVersion = aurora
+		} else if err := database.QueryRowContext(ctx, "SELECT dolt_version()").Scan(&dolt); err == nil {
+			info.DoltVersion = dolt
+		}
+	}
+	if !info.Vitess {
+		info.Proxy = detectProxy(ctx, database)
+	}
+	serverInfos.Lock()
+	serverInfos.byDB[database] = info
+	serverInfos.Unlock()
+	return info
+}
+
+// parseServerVersion parses VERSION(), e.g. "8.0.36",
+// "10.11.6-MariaDB-1:10.11.6+maria~ubu2204-log", "8.0.11-TiDB-v7.5.0" or
+// "8.0.30-Vitess". Replication and some proxies report MariaDB with the
+// "5.5.5-" compatibility prefix.
+func parseServerVersion(version string) serverInfo {
+	lower := strings.ToLower(version)
+	info := serverInfo{
+		Version: version,
+		MariaDB: strings.Contains(lower, "mariadb"),
+		Vitess:  strings.Contains(lower, "vitess") || strings.Contains(lower, "planetscale"),
+	}
+	v := version
+	if i := strings.Index(lower, "-tidb-v"); i >= 0 {
+		info.TiDB = true
+		v = version[i+len("-tidb-v"):]
+	} else if info.MariaDB {
+		v = strings.TrimPrefix(v, "5.5.5-")
+	}
+	v, _, _ = strings.Cut(v, "-")
+	parts := strings.SplitN(v, ".", 3)
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return serverInfo{Version: version, MariaDB: info.MariaDB, TiDB: info.TiDB, Vitess: info.Vitess}
+		}
+		numbers[i] = n
+	}
+	info.major, info.minor, info.patch = numbers[0], numbers[1], numbers[2]
+	return info
+}
+
+// Flavor is "MariaDB", "TiDB", "Vitess", "Aurora MySQL", "Dolt" or "MySQL".
+func (s serverInfo) Flavor() string {
+	switch {
+	case s.MariaDB:
+		return "MariaDB"
+	case s.TiDB:
+		return "TiDB"
+	case s.Vitess:
+		return "Vitess"
+	case s.AuroraVersion != "":
+		return "Aurora MySQL"
+	case s.DoltVersion != "":
+		return "Dolt"
+	}
+	return "MySQL"
+}
+
+// String names the server for messages, e.g. "MariaDB 10.11.6" or
+// "Aurora MySQL 8.0.32 (Aurora 3.05.2)". Dolt is named by its own release.
+func (s serverInfo) String() string {
+	if s.DoltVersion != "" {
+		return "Dolt " + s.DoltVersion
+	}
+	if s.major == 0 {
+		return s.Flavor()
+	}
+	name := fmt.Sprintf("%s %d.%d.%d", s.Flavor(), s.major, s.minor, s.patch)
+	if s.AuroraVersion != "" {
+		name += fmt.Sprintf(" (Aurora %s)", s.AuroraVersion)
+	}
+	return name
+}
+
+// atLeast reports whether the server is the given version or later. An
+// unknown version counts as later.
+func (s serverInfo) atLeast(major, minor, patch int) bool {
+	if s.major == 0 {
+		return true
+	}
+	if s.major != major {
+		return s.major > major
+	}
+	if s.minor != minor {
+		return s.minor > minor
+	}
+	return s.patch >= patch
+}
+
+// supportsReturning reports whether the statement accepts a RETURNING
+// clause: DELETE from MariaDB 10.0.5, INSERT and REPLACE from 10.5.
+func (s serverInfo) supportsReturning(query string) bool {
+	if strings.EqualFold(firstWord(sanitizeStatement(query)), "DELETE") {
+		return s.MariaDB && s.atLeast(10, 0, 5)
+	}
+	return s.MariaDB && s.atLeast(10, 5, 0)
+}
+
+// supportsSequences reports whether CREATE SEQUENCE is available (MariaDB
+// 10.3+, TiDB 4.0+).
+func (s serverInfo) supportsSequences() bool {
+	if s.TiDB {
+		return s.atLeast(4, 0, 0)
+	}
+	return s.MariaDB && s.atLeast(10, 3, 0)
+}
+
+// instantAddColumn reports whether ADD COLUMN can run with
+// ALGORITHM=INSTANT, at the end of the table or, if positioned, with FIRST
+// or AFTER. TiDB adds every column as an online schema change that never
+// rewrites data.
+func (s serverInfo) instantAddColumn(positioned bool) bool {
+	if s.TiDB {
+		return true
+	}
+	if s.MariaDB {
+		return s.atLeast(10, 4, 0) || (!positioned && s.atLeast(10, 3, 2))
+	}
+	return s.atLeast(8, 0, 29) || (!positioned && s.atLeast(8, 0, 12))
+}
+
+// supportsVectors reports whether the VECTOR type is available (MySQL
+// 9.0+, MariaDB 11.7+, TiDB 8.4+).
+func (s serverInfo) supportsVectors() bool {
+	switch {
+	case s.Vitess:
+		return false
+	case s.MariaDB:
+		return s.atLeast(11, 7, 0)
+	case s.TiDB:
+		return s.atLeast(8, 4, 0)
+	}
+	return s.atLeast(9, 0, 0)
+}
+
+// vectorDistance returns the SQL expression for the metric ("cosine" or
+// "euclidean") between a VECTOR column and a vector given as a text
+// parameter, e.g. "[0.1,0.2]". MySQL's DISTANCE() is only in HeatWave and
+// Enterprise Edition; MariaDB and TiDB have one function per metric.
+func (s serverInfo) vectorDistance(metric, column string) string {
+	switch {
+	case s.MariaDB:
+		return fmt.Sprintf("VEC_DISTANCE_%s(%s, VEC_FromText(?))", strings.ToUpper(metric), column)
+	case s.TiDB && metric == "euclidean":
+		return fmt.Sprintf("VEC_L2_DISTANCE(%s, ?)", column)
+	case s.TiDB:
+		return fmt.Sprintf("VEC_COSINE_DISTANCE(%s, ?)", column)
+	}
+	return fmt.Sprintf("DISTANCE(%s, STRING_TO_VECTOR(?), '%s')", column, strings.ToUpper(metric))
+}
+
+// supportsCTEs reports whether WITH common table expressions, including
+// recursive ones, are available (MySQL 8.0+, MariaDB 10.2.2+, TiDB 5.1+).
+func (s serverInfo) supportsCTEs() bool {
+	switch {
+	case s.MariaDB:
+		return s.atLeast(10, 2, 2)
+	case s.TiDB:
+		return s.atLeast(5, 1, 0)
+	}
+	return s.atLeast(8, 0, 0)
+}
+
+// supportsWindowFunctions reports whether OVER (...) window functions are
+// available (MySQL 8.0+, MariaDB 10.2+, TiDB 3.0+).
+func (s serverInfo) supportsWindowFunctions() bool {
+	switch {
+	case s.MariaDB:
+		return s.atLeast(10, 2, 0)
+	case s.TiDB:
+		return s.atLeast(3, 0, 0)
+	}
+	return s.atLeast(8, 0, 0)
+}
+
+// supportsCheckConstraints reports whether CHECK constraints are enforced
+// (MySQL 8.0.16+, MariaDB 10.2.1+, TiDB 7.2+). Older MySQL parses and
+// ignores them, and has no ALTER TABLE ... DROP CHECK.
+func (s serverInfo) supportsCheckConstraints() bool {
+	switch {
+	case s.MariaDB:
+		return s.atLeast(10, 2, 1)
+	case s.TiDB:
+		return s.atLeast(7, 2, 0)
+	}
+	return s.atLeast(8, 0, 16)
+}
+
+// supportsInvisibleIndexes reports whether indexes can be made INVISIBLE
+// (MySQL 8.0+, TiDB 8.0+). MariaDB 10.6+ has IGNORED indexes instead.
+func (s serverInfo) supportsInvisibleIndexes() bool {
+	return !s.MariaDB && s.atLeast(8, 0, 0)
+}
+
+// supportsJSONTable reports whether JSON_TABLE is available (MySQL 8.0.4+,
+// MariaDB 10.6+).
+func (s serverInfo) supportsJSONTable() bool {
+	switch {
+	case s.MariaDB:
+		return s.atLeast(10, 6, 0)
+	case s.TiDB:
+		return false
+	}
+	return s.atLeast(8, 0, 4)
+}
+
+// supportsExplainAnalyze reports whether EXPLAIN ANALYZE runs a query and
+// reports actual row counts and timings (MySQL 8.0.18+, TiDB). MariaDB has
+// the ANALYZE statement instead.
+func (s serverInfo) supportsExplainAnalyze() bool {
+	switch {
+	case s.MariaDB:
+		return false
+	case s.TiDB:
+		return true
+	}
+	return s.atLeast(8, 0, 18)
+}
+
+// supportsResourceGroups reports whether sessions can be assigned to a
+// resource group with SET RESOURCE GROUP (MySQL 8.0.3+, TiDB 7.1+). MySQL
+// groups set CPU affinity and thread priority, TiDB's request unit quotas.
+func (s serverInfo) supportsResourceGroups() bool {
+	switch {
+	case s.MariaDB:
+		return false
+	case s.TiDB:
+		return s.atLeast(7, 1, 0)
+	}
+	return s.atLeast(8, 0, 3)
+}
+
+// statementTimeoutVariable is the session variable limiting statement run
+// time: max_execution_time (milliseconds, SELECT only) on MySQL and TiDB
+// and max_statement_time (seconds) on MariaDB.
+func (s serverInfo) statementTimeoutVariable() string {
+	if s.MariaDB {
+		return "max_statement_time"
+	}
+	return "max_execution_time"
+}
+
+// hasReturningClause reports whether an INSERT, REPLACE or DELETE has a
+// RETURNING clause and so produces a result set.
+func hasReturningClause(query string) bool {
+	upper := strings.ToUpper(sanitizeStatement(query))
+	switch firstWord(upper) {
+	case "INSERT", "REPLACE", "DELETE":
+		return strings.Contains(" "+upper+" ", " RETURNING ")
+	}
+	return false
+}
+
+// advancesSequence reports whether a statement calls NEXTVAL or SETVAL (or
+// NEXT VALUE FOR), which change a sequence even inside a SELECT.
+func advancesSequence(query string) bool {
+	return sequenceCallPattern.MatchString(sanitizeStatement(query))
+}
+
+var sequenceCallPattern = regexp.MustCompile(`(?i)\b(NEXTVAL|SETVAL)\s*\(|\bNEXT\s+VALUE\s+FOR\b`)
+
+// normalizeMariaDBDefault converts a MariaDB COLUMN_DEFAULT to the form
+// MySQL reports. MariaDB quotes literal defaults, reports DEFAULT NULL as
+// the string NULL and gives expressions unquoted; MySQL 8.0 gives literals
+// unquoted, DEFAULT NULL as SQL NULL and marks expressions with
+// DEFAULT_GENERATED in EXTRA.
+func normalizeMariaDBDefault(def string) (value *string, expression bool) {
+	switch {
+	case def == "NULL":
+		return nil, false
+	case len(def) >= 2 && def[0] == '\'' && def[len(def)-1] == '\'':
+		literal := strings.ReplaceAll(def[1:len(def)-1], "''", "'")
+		literal = strings.ReplaceAll(literal, `\\`, `\`)
+		return &literal, false
+	}
+	if _, err := strconv.ParseFloat(def, 64); err == nil {
+		return &def, false
+	}
+	return &def, true
+}
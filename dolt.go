@@ -0,0 +1,377 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// requireDolt returns an error result for the Dolt-only tools when the
+// server is something else.
+func requireDolt(ctx context.Context, tool string) *mcp.CallToolResult {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}
+	}
+	if server := serverInfoFor(ctx, db); server.DoltVersion == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("%s needs a Dolt server; the server is %s", tool, server)},
+			},
+		}
+	}
+	return nil
+}
+
+// doltConn returns a connection using database, since Dolt's system tables
+// and table functions read the current database. The connection must be
+// released with discardConn so the USE does not leak into the pool.
+func doltConn(ctx context.Context, database string) (*sql.Conn, error) {
+	db := currentDB()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if database != "" {
+		if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(database)); err != nil {
+			discardConn(conn)
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// doltQuery runs a query on a connection using database and returns its
+// rows as maps.
+func doltQuery(ctx context.Context, database, query string, params ...any) ([]string, []map[string]any, error) {
+	conn, err := doltConn(ctx, database)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer discardConn(conn)
+	rows, err := conn.QueryContext(ctx, query, params...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	return scanRowMaps(rows)
+}
+
+type DoltLogParams struct {
+	Database string `json:"database,omitempty"`
+	Ref      string `json:"ref,omitempty"`
+	Table    string `json:"table,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+func DoltLog(ctx context.Context, req *mcp.CallToolRequest, args DoltLogParams) (*mcp.CallToolResult, any, error) {
+	if result := requireDolt(ctx, "dolt_log"); result != nil {
+		return result, nil, nil
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	source := "dolt_log"
+	if args.Ref != "" || args.Table != "" {
+		// Table function arguments must be literals.
+		functionArgs := []string{quoteString(cmp.Or(args.Ref, "HEAD"))}
+		if args.Table != "" {
+			functionArgs = append(functionArgs, "'--tables'", quoteString(args.Table))
+		}
+		source = fmt.Sprintf("DOLT_LOG(%s)", strings.Join(functionArgs, ", "))
+	}
+	query := fmt.Sprintf("SELECT commit_hash, committer, date, message FROM %s LIMIT %d", source, limit)
+	columns, results, err := doltQuery(ctx, args.Database, query)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read the commit log: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("%d commits", len(results))
+	if args.Table != "" {
+		resultText += " touching " + args.Table
+	}
+	resultText += ", newest first:\n\n" + formatRowTable(columns, results)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"commits":  results,
+		"rowCount": len(results),
+	}, nil
+}
+
+type DoltDiffParams struct {
+	Database string `json:"database,omitempty"`
+	Table    string `json:"table,omitempty"`
+	FromRef  string `json:"from_ref,omitempty"`
+	ToRef    string `json:"to_ref,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+func DoltDiff(ctx context.Context, req *mcp.CallToolRequest, args DoltDiffParams) (*mcp.CallToolResult, any, error) {
+	if result := requireDolt(ctx, "dolt_diff"); result != nil {
+		return result, nil, nil
+	}
+	from := cmp.Or(args.FromRef, "HEAD")
+	to := cmp.Or(args.ToRef, "WORKING")
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	refs := quoteString(from) + ", " + quoteString(to)
+
+	if args.Table == "" {
+		columns, results, err := doltQuery(ctx, args.Database, fmt.Sprintf("SELECT * FROM DOLT_DIFF_SUMMARY(%s)", refs))
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to diff %s..%s: %v", from, to, err)},
+				},
+			}, nil, nil
+		}
+		resultText := fmt.Sprintf("%d tables changed between %s and %s", len(results), from, to)
+		if len(results) > 0 {
+			resultText += " (pass table to see the changed rows):\n\n" + formatRowTable(columns, results)
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, map[string]any{
+			"from":   from,
+			"to":     to,
+			"tables": results,
+		}, nil
+	}
+
+	diff := fmt.Sprintf("DOLT_DIFF(%s, %s)", refs, quoteString(args.Table))
+	_, counts, err := doltQuery(ctx, args.Database, fmt.Sprintf("SELECT diff_type, COUNT(*) AS count FROM %s GROUP BY diff_type ORDER BY diff_type", diff))
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to diff %s between %s and %s: %v", args.Table, from, to, err)},
+			},
+		}, nil, nil
+	}
+	columns, results, err := doltQuery(ctx, args.Database, fmt.Sprintf("SELECT * FROM %s LIMIT %d", diff, limit))
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to diff %s between %s and %s: %v", args.Table, from, to, err)},
+			},
+		}, nil, nil
+	}
+
+	summary := make(map[string]any, len(counts))
+	var parts []string
+	for _, row := range counts {
+		summary[rowText(row, "diff_type")] = row["count"]
+		parts = append(parts, fmt.Sprintf("%s %s", rowText(row, "count"), rowText(row, "diff_type")))
+	}
+	resultText := fmt.Sprintf("Changes to %s between %s and %s: ", args.Table, from, to)
+	if len(parts) == 0 {
+		resultText += "none\n"
+	} else {
+		resultText += strings.Join(parts, ", ") + fmt.Sprintf("\nFirst %d changed rows (from_ columns are the old values, to_ the new):\n\n", len(results))
+		resultText += formatRowTable(columns, results)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"from":    from,
+		"to":      to,
+		"summary": summary,
+		"rows":    results,
+		"columns": columns,
+	}, nil
+}
+
+type DoltBranchParams struct {
+	Database   string `json:"database,omitempty"`
+	Action     string `json:"action,omitempty"`
+	Name       string `json:"name,omitempty"`
+	StartPoint string `json:"start_point,omitempty"`
+	Force      bool   `json:"force,omitempty"`
+}
+
+func DoltBranch(ctx context.Context, req *mcp.CallToolRequest, args DoltBranchParams) (*mcp.CallToolResult, any, error) {
+	if result := requireDolt(ctx, "dolt_branch"); result != nil {
+		return result, nil, nil
+	}
+
+	action := strings.ToLower(args.Action)
+	switch action {
+	case "", "list":
+		columns, results, err := doltQuery(ctx, args.Database, `
+			SELECT name, hash, latest_committer, latest_commit_date, latest_commit_message,
+				name = active_branch() AS current
+			FROM dolt_branches ORDER BY name`)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to list branches: %v", err)},
+				},
+			}, nil, nil
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("%d branches:\n\n", len(results)) + formatRowTable(columns, results)},
+			},
+		}, map[string]any{
+			"branches": results,
+		}, nil
+	case "create", "delete":
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown action %q (use list, create or delete)", args.Action)},
+			},
+		}, nil, nil
+	}
+
+	if args.Name == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "name is required"},
+			},
+		}, nil, nil
+	}
+	if err := checkWritable("dolt_branch"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	statement := "CALL DOLT_BRANCH(?)"
+	params := []any{args.Name}
+	switch {
+	case action == "create" && args.StartPoint != "":
+		statement = "CALL DOLT_BRANCH(?, ?)"
+		params = append(params, args.StartPoint)
+	case action == "delete" && args.Force:
+		statement = "CALL DOLT_BRANCH('-D', ?)"
+	case action == "delete":
+		statement = "CALL DOLT_BRANCH('-d', ?)"
+	}
+	conn, err := doltConn(ctx, args.Database)
+	if err == nil {
+		_, err = conn.ExecContext(ctx, statement, params...)
+		discardConn(conn)
+	}
+	audit("dolt_branch", fmt.Sprintf("%s -- %v", statement, params), 0, err)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to %s branch %s: %v", action, args.Name, err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("Created branch %s", args.Name)
+	if args.StartPoint != "" {
+		resultText += " at " + args.StartPoint
+	}
+	if action == "delete" {
+		resultText = fmt.Sprintf("Deleted branch %s", args.Name)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, nil, nil
+}
+
+type DoltCheckoutParams struct {
+	Database string `json:"database"`
+	Branch   string `json:"branch"`
+}
+
+func DoltCheckout(ctx context.Context, req *mcp.CallToolRequest, args DoltCheckoutParams) (*mcp.CallToolResult, any, error) {
+	dbConfig := currentDBConfig()
+	if result := requireDolt(ctx, "dolt_checkout"); result != nil {
+		return result, nil, nil
+	}
+	if args.Database == "" || args.Branch == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "database and branch are required"},
+			},
+		}, nil, nil
+	}
+	_, branches, err := doltQuery(ctx, args.Database, "SELECT name FROM dolt_branches WHERE name = ?", args.Branch)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read branches: %v", err)},
+			},
+		}, nil, nil
+	}
+	if len(branches) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("%s has no branch %q; create it with dolt_branch", args.Database, args.Branch)},
+			},
+		}, nil, nil
+	}
+
+	// CALL DOLT_CHECKOUT only switches one session, and the pool has many,
+	// so the pool is reopened on the revision database "database/branch".
+	revision := args.Database + "/" + args.Branch
+	cfg := dbConfig.Clone()
+	cfg.DBName = revision
+	if err := reopenDatabase(ctx, cfg); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to reconnect to %s: %v", revision, err)},
+			},
+		}, nil, nil
+	}
+	clearSchemaCache()
+
+	resultText := fmt.Sprintf("Checked out %s: the default database is now %s, so unqualified table names read and write branch %s. Tools taking a database name need %q to use the branch; %q still means the default branch.",
+		args.Branch, quoteIdentifier(revision), args.Branch, revision, args.Database)
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"database": revision,
+		"branch":   args.Branch,
+	}, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Background drift checking is enabled by -drift-baseline together with
+// -drift-interval.
+var (
+	driftBaseline string
+	driftInterval time.Duration
+)
+
+type CheckDriftParams struct {
+	Baseline   string `json:"baseline,omitempty"`
+	Database   string `json:"database,omitempty"`
+	Connection string `json:"connection,omitempty"`
+}
+
+func CheckDrift(ctx context.Context, req *mcp.CallToolRequest, args CheckDriftParams) (*mcp.CallToolResult, any, error) {
+	path := args.Baseline
+	if path == "" {
+		path = driftBaseline
+	}
+	if path == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "baseline is required (or start the server with -drift-baseline)"},
+			},
+		}, nil, nil
+	}
+
+	baseline, database, differences, err := checkDrift(ctx, path, args.Database, args.Connection)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	if len(differences) > 0 {
+		audit("check_drift", driftSummary(database, path, differences), 0, nil)
+	}
+
+	resultText := fmt.Sprintf("Drift check of %s against baseline %s (taken %s)\n",
+		describeSchemaLocation(args.Connection, database), path, baseline.CreatedAt.Format(time.RFC3339))
+	if len(differences) > 0 {
+		resultText += "(missing = removed from the live schema, only in target = added outside the baseline)\n"
+	}
+	resultText += formatSchemaDifferences(differences)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"baseline":    path,
+		"database":    database,
+		"drifted":     len(differences) > 0,
+		"differences": differences,
+	}, nil
+}
+
+// checkDrift compares the live schema against the baseline snapshot file at
+// path. database defaults to the one the baseline was taken of.
+func checkDrift(ctx context.Context, path, database, connection string) (*schemaSnapshot, string, []SchemaDifference, error) {
+	baseline, err := readSnapshotFile(path)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("Failed to read baseline %s: %v", path, err)
+	}
+	conn, err := connectionFor(connection)
+	if err != nil {
+		return nil, "", nil, errors.New(connectionHint(err))
+	}
+	if database == "" {
+		database = baseline.Database
+	}
+	live, err := loadSchema(ctx, conn, database)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("Failed to read schema: %v", err)
+	}
+	return baseline, database, diffSchemas(baseline.Schema, live), nil
+}
+
+func driftSummary(database, path string, differences []SchemaDifference) string {
+	return fmt.Sprintf("-- schema drift in %s against %s: %d difference(s)\n%s",
+		quoteIdentifier(database), path, len(differences), formatSchemaDifferences(differences))
+}
+
+// monitorDrift checks the default connection against the baseline every
+// interval. A report is sent when drift first appears, when it changes and
+// when it is resolved, as an audit entry and a warning log message to every
+// connected client.
+func monitorDrift(server *mcp.Server, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	reported := ""
+	for range ticker.C {
+		db, release := holdDB()
+		if db == nil {
+			release()
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		_, database, differences, err := checkDrift(ctx, path, "", "")
+		cancel()
+		release()
+		if err != nil {
+			slog.Warn("drift check failed", "baseline", path, "err", err)
+			continue
+		}
+
+		current := formatSchemaDifferences(differences)
+		if len(differences) == 0 {
+			current = ""
+		}
+		if current == reported {
+			continue
+		}
+		reported = current
+
+		level := mcp.LoggingLevel("warning")
+		message := driftSummary(database, path, differences)
+		if len(differences) == 0 {
+			level = "info"
+			message = fmt.Sprintf("schema drift in %s resolved: matches %s again", quoteIdentifier(database), path)
+		}
+		if len(differences) == 0 {
+			slog.Info(message)
+		} else {
+			slog.Warn(message, "database", database, "baseline", path, "differences", len(differences))
+		}
+		audit("drift_monitor", message, 0, nil)
+		for session := range server.Sessions() {
+			session.Log(context.Background(), &mcp.LoggingMessageParams{
+				Level:  level,
+				Logger: "schema-drift",
+				Data: map[string]any{
+					"message":     message,
+					"database":    database,
+					"baseline":    path,
+					"differences": differences,
+				},
+			})
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultDuplicateGroups = 20
+	defaultDuplicateSample = 3
+)
+
+type FindDuplicatesParams struct {
+	Database   string   `json:"database"`
+	Table      string   `json:"table"`
+	Columns    []string `json:"columns"`
+	Filters    []Filter `json:"filters,omitempty"`
+	MaxGroups  int      `json:"max_groups,omitempty"`
+	SampleSize int      `json:"sample_size,omitempty"`
+	// Keep selects which row of each group the DELETE statement keeps:
+	// "first" (lowest primary key, the default) or "last".
+	Keep string `json:"keep,omitempty"`
+}
+
+// DuplicateGroup is one set of rows sharing the candidate key values.
+type DuplicateGroup struct {
+	Key    map[string]any   `json:"key"`
+	Count  int64            `json:"count"`
+	Sample []map[string]any `json:"sample"`
+}
+
+func FindDuplicates(ctx context.Context, req *mcp.CallToolRequest, args FindDuplicatesParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if len(args.Columns) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "columns is required"},
+			},
+		}, nil, nil
+	}
+	keep := strings.ToLower(args.Keep)
+	if keep == "" {
+		keep = "first"
+	}
+	if keep != "first" && keep != "last" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported keep %q (use first or last)", args.Keep)},
+			},
+		}, nil, nil
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	known := make(map[string]bool, len(tableCols))
+	for _, col := range tableCols {
+		known[col.ColumnName] = true
+	}
+	for _, col := range args.Columns {
+		if !known[col] {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Unknown column %q", col)},
+				},
+			}, nil, nil
+		}
+	}
+
+	where, params := "", []any(nil)
+	if len(args.Filters) > 0 {
+		where, params, err = buildWhere(args.Filters, tableCols)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid filters: %v", err)},
+				},
+			}, nil, nil
+		}
+		where = " WHERE " + where
+	}
+
+	maxGroups := args.MaxGroups
+	if maxGroups <= 0 {
+		maxGroups = defaultDuplicateGroups
+	}
+	sampleSize := args.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultDuplicateSample
+	}
+
+	table := qualifiedTable(args.Database, args.Table)
+	keyList := quoteIdentifierList(args.Columns)
+	grouped := fmt.Sprintf("SELECT %s, COUNT(*) AS duplicate_count FROM %s%s GROUP BY %s HAVING COUNT(*) > 1", keyList, table, where, keyList)
+
+	var groupCount, excessRows int64
+	err = db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*), COALESCE(SUM(duplicate_count - 1), 0) FROM (%s) AS d", grouped), params...).Scan(&groupCount, &excessRows)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to count duplicates: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("%s ORDER BY duplicate_count DESC LIMIT %d", grouped, maxGroups), params...)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to find duplicates: %v", err)},
+			},
+		}, nil, nil
+	}
+	_, keyRows, err := scanRowMaps(rows)
+	rows.Close()
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read duplicates: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	// NULL-safe equality matches the way GROUP BY puts NULLs together.
+	conds := make([]string, len(args.Columns))
+	for i, col := range args.Columns {
+		conds[i] = fmt.Sprintf("%s <=> ?", quoteIdentifier(col))
+	}
+	groups := make([]DuplicateGroup, 0, len(keyRows))
+	resultText := fmt.Sprintf("%d duplicate group(s) on (%s) in %s.%s, %d excess row(s)\n",
+		groupCount, strings.Join(args.Columns, ", "), args.Database, args.Table, excessRows)
+	for _, keyRow := range keyRows {
+		group := DuplicateGroup{Key: make(map[string]any, len(args.Columns))}
+		sampleParams := make([]any, 0, len(args.Columns)+len(params))
+		for _, col := range args.Columns {
+			group.Key[col] = keyRow[col]
+			sampleParams = append(sampleParams, keyRow[col])
+		}
+		group.Count, _ = strconv.ParseInt(fmt.Sprint(keyRow["duplicate_count"]), 10, 64)
+
+		sampleWhere := strings.Join(conds, " AND ")
+		if len(args.Filters) > 0 {
+			sampleWhere += " AND " + strings.TrimPrefix(where, " WHERE ")
+			sampleParams = append(sampleParams, params...)
+		}
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s LIMIT %d", table, sampleWhere, sampleSize), sampleParams...)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to fetch sample rows: %v", err)},
+				},
+			}, nil, nil
+		}
+		columns, sample, err := scanRowMaps(rows)
+		rows.Close()
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to read sample rows: %v", err)},
+				},
+			}, nil, nil
+		}
+		group.Sample = sample
+
+		keyParts := make([]string, len(args.Columns))
+		for i, col := range args.Columns {
+			keyParts[i] = fmt.Sprintf("%s=%s", col, sqlLiteral(keyRow[col]))
+		}
+		resultText += fmt.Sprintf("\n%s: %d rows\n%s", strings.Join(keyParts, ", "), group.Count, formatRowTable(columns, sample))
+		groups = append(groups, group)
+	}
+	if groupCount > int64(len(groups)) {
+		resultText += fmt.Sprintf("\n... %d more group(s) not shown (max_groups %d)\n", groupCount-int64(len(groups)), maxGroups)
+	}
+
+	structured := map[string]any{
+		"groupCount": groupCount,
+		"excessRows": excessRows,
+		"groups":     groups,
+	}
+	if groupCount > 0 {
+		stmt, err := duplicateDeleteSQL(ctx, db, args.Database, args.Table, args.Columns, where, keep)
+		if err != nil {
+			resultText += fmt.Sprintf("\nNo keep-one DELETE generated: %v\n", err)
+		} else {
+			if len(params) > 0 {
+				// The filters appear twice: in the grouping and in the
+				// restriction to filtered rows.
+				stmt = inlineParams(stmt, append(append([]any{}, params...), params...))
+			}
+			resultText += fmt.Sprintf("\nKeep-one DELETE for review (not executed; removes %d rows):\n%s;\n", excessRows, stmt)
+			structured["deleteSQL"] = stmt
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, structured, nil
+}
+
+// duplicateDeleteSQL builds a DELETE that removes all but one row of each
+// duplicate group, keeping the lowest (first) or highest (last) primary key.
+// where is the filter clause, with the table's columns unqualified.
+func duplicateDeleteSQL(ctx context.Context, db *sql.DB, database, table string, columns []string, where, keep string) (string, error) {
+	keys, err := uniqueKeys(ctx, db, database, table)
+	if err != nil {
+		return "", err
+	}
+	pk := keys["PRIMARY"]
+	if len(pk) != 1 {
+		return "", fmt.Errorf("the table needs a single-column primary key to tell the rows of a group apart")
+	}
+
+	agg, cmp := "MIN", ">"
+	if keep == "last" {
+		agg, cmp = "MAX", "<"
+	}
+	keyList := quoteIdentifierList(columns)
+	joins := make([]string, len(columns))
+	for i, col := range columns {
+		joins[i] = fmt.Sprintf("t.%s <=> d.%s", quoteIdentifier(col), quoteIdentifier(col))
+	}
+	// The derived table is materialized first, which is what allows
+	// deleting from the table it reads.
+	stmt := fmt.Sprintf("DELETE t FROM %s AS t\nJOIN (SELECT %s, %s(%s) AS keep_id FROM %s%s GROUP BY %s HAVING COUNT(*) > 1) AS d\n  ON %s\nWHERE t.%s %s d.keep_id",
+		qualifiedTable(database, table), keyList, agg, quoteIdentifier(pk[0]), qualifiedTable(database, table), where, keyList,
+		strings.Join(joins, " AND "), quoteIdentifier(pk[0]), cmp)
+	if where != "" {
+		// Rows outside the filters can share a group's key; restrict the
+		// delete to the filtered rows through their primary keys.
+		stmt += fmt.Sprintf("\n  AND t.%s IN (SELECT %s FROM (SELECT %s FROM %s%s) AS f)",
+			quoteIdentifier(pk[0]), quoteIdentifier(pk[0]), quoteIdentifier(pk[0]), qualifiedTable(database, table), where)
+	}
+	return stmt, nil
+}
+
+// inlineParams replaces each ? placeholder in stmt with the literal of the
+// matching parameter, for statements shown to the user rather than run. A ?
+// inside a quoted identifier, string literal or comment is left alone.
+func inlineParams(stmt string, params []any) string {
+	var b strings.Builder
+	n, last := 0, 0
+	for _, t := range tokenizeSQL(stmt) {
+		if t.kind != tokenPunct || t.text != "?" || n == len(params) {
+			continue
+		}
+		b.WriteString(stmt[last : t.end-1])
+		b.WriteString(sqlLiteral(sqlValue(params[n])))
+		last = t.end
+		n++
+	}
+	b.WriteString(stmt[last:])
+	return b.String()
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestInlineParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		stmt   string
+		params []any
+		want   string
+	}{
+		{"placeholders", "SELECT * FROM t WHERE a = ? AND b = ?", []any{"x", "y"}, "SELECT * FROM t WHERE a = 'x' AND b = 'y'"},
+		{"quoted identifier", "SELECT `a?b` FROM t WHERE `a?b` = ?", []any{"x"}, "SELECT `a?b` FROM t WHERE `a?b` = 'x'"},
+		{"string literal", "SELECT 'why?' FROM t WHERE a = ?", []any{"x"}, "SELECT 'why?' FROM t WHERE a = 'x'"},
+		{"comment", "SELECT a /* ? */ FROM t WHERE a = ?", []any{"x"}, "SELECT a /* ? */ FROM t WHERE a = 'x'"},
+		{"parameter containing ?", "WHERE a = ? AND b = ?", []any{"?", "y"}, "WHERE a = '?' AND b = 'y'"},
+		{"more placeholders than parameters", "WHERE a = ? AND b = ?", []any{"x"}, "WHERE a = 'x' AND b = ?"},
+	}
+	for _, tt := range tests {
+		if got := inlineParams(tt.stmt, tt.params); got != tt.want {
+			t.Errorf("%s: inlineParams() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultExecuteAsRows = 100
+	maxExecuteAsRows     = 1000
+)
+
+// testAccount is a MySQL account, configured under accounts in the config
+// file, that execute_as runs queries as.
+type testAccount struct {
+	User     string `json:"user"`
+	Password string `json:"password,omitempty"`
+	// PasswordEnv names an environment variable holding the password, to
+	// keep it out of the file.
+	PasswordEnv string `json:"passwordEnv,omitempty"`
+}
+
+// accessDeniedErrors are the MySQL errors that mean the account lacks a
+// privilege, which execute_as reports as an answer rather than a failure.
+var accessDeniedErrors = map[uint16]string{
+	1044: "database access denied",
+	1045: "login refused",
+	1142: "table privilege missing",
+	1143: "column privilege missing",
+	1227: "global privilege missing",
+	1370: "routine privilege missing",
+	1698: "login refused",
+}
+
+// accountPool is the connection pool of a configured account, opened with
+// the default connection's settings.
+type accountPool struct {
+	base *mysql.Config
+	db   *sql.DB
+}
+
+// accountPools holds the pools execute_as has opened, by account name.
+var accountPools = struct {
+	sync.Mutex
+	byName map[string]accountPool
+}{byName: make(map[string]accountPool)}
+
+// accountConnection returns the pool of the named account, opening it on
+// the default connection's server unless one is open there already.
+func accountConnection(name string, account testAccount) (*sql.DB, error) {
+	dbConfig := currentDBConfig()
+	accountPools.Lock()
+	defer accountPools.Unlock()
+	if pool, ok := accountPools.byName[name]; ok {
+		if pool.base == dbConfig {
+			return pool.db, nil
+		}
+		// connect has moved the default connection since.
+		closePool(pool.db)
+		delete(accountPools.byName, name)
+	}
+	cfg := dbConfig.Clone()
+	cfg.User, cfg.Passwd = account.User, account.Password
+	if account.PasswordEnv != "" {
+		cfg.Passwd = os.Getenv(account.PasswordEnv)
+	}
+	// The account may have no access to the default database; the query
+	// selects it with USE, where a refusal is reported like any other.
+	cfg.DBName = ""
+	database, err := openDatabase(cfg, agentResourceGroup.Load())
+	if err != nil {
+		return nil, err
+	}
+	database.SetMaxOpenConns(2)
+	accountPools.byName[name] = accountPool{base: dbConfig, db: database}
+	return database, nil
+}
+
+type ExecuteAsParams struct {
+	// Account names an entry of accounts in the config file.
+	Account string `json:"account"`
+	Query   string `json:"query"`
+	// Database defaults to the default connection's database.
+	Database string `json:"database,omitempty"`
+	MaxRows  int    `json:"max_rows,omitempty"`
+}
+
+func ExecuteAs(ctx context.Context, req *mcp.CallToolRequest, args ExecuteAsParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	dbConfig := currentDBConfig()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	// The accounts may hold privileges the server's own login does not,
+	// so read-only mode does not log in as them at all.
+	if err := checkWritable("execute_as"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	account, ok := config.Accounts[args.Account]
+	if !ok {
+		configured := "none are configured"
+		if len(config.Accounts) > 0 {
+			configured = "configured: " + strings.Join(sortedKeys(config.Accounts), ", ")
+		}
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("No account %q under accounts in the config file (%s)", args.Account, configured)},
+			},
+		}, nil, nil
+	}
+	query := strings.TrimSpace(args.Query)
+	if query == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "query is required"},
+			},
+		}, nil, nil
+	}
+	if err := checkReadQuery(query); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Cannot run this query as another account: %v", err)},
+			},
+		}, nil, nil
+	}
+	maxRows := args.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultExecuteAsRows
+	}
+	maxRows = min(maxRows, maxExecuteAsRows)
+	database := args.Database
+	if database == "" {
+		database = dbConfig.DBName
+	}
+
+	pool, err := accountConnection(args.Account, account)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to open a connection as %s: %v", account.User, err)},
+			},
+		}, nil, nil
+	}
+
+	structured := map[string]any{"account": args.Account, "user": account.User}
+	denied := func(step string, err error) (*mcp.CallToolResult, any, error) {
+		var mysqlErr *mysql.MySQLError
+		if !errors.As(err, &mysqlErr) || accessDeniedErrors[mysqlErr.Number] == "" {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to %s as %s: %v", step, account.User, err)},
+				},
+			}, nil, nil
+		}
+		structured["allowed"] = false
+		structured["error"] = map[string]any{"code": mysqlErr.Number, "message": mysqlErr.Message}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Denied: account %s cannot %s (%s).\nError %d: %s\n", args.Account, step, accessDeniedErrors[mysqlErr.Number], mysqlErr.Number, mysqlErr.Message)},
+			},
+		}, structured, nil
+	}
+
+	conn, err := pool.Conn(ctx)
+	if err != nil {
+		return denied("log in", err)
+	}
+	defer conn.Close()
+	// The server's name for the account, such as app@%, shows which of
+	// its accounts the login matched.
+	var currentUser string
+	if err := conn.QueryRowContext(ctx, "SELECT CURRENT_USER()").Scan(&currentUser); err != nil {
+		return denied("log in", err)
+	}
+	structured["currentUser"] = currentUser
+	// Any account may list its own grants; they are left out if that
+	// fails all the same.
+	var grants []string
+	if rows, err := conn.QueryContext(ctx, "SHOW GRANTS"); err == nil {
+		for rows.Next() {
+			var grant string
+			if rows.Scan(&grant) == nil {
+				grants = append(grants, grant)
+			}
+		}
+		rows.Close()
+	}
+	structured["grants"] = grants
+	if database != "" {
+		if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(database)); err != nil {
+			return denied("use database "+database, err)
+		}
+	}
+
+	// checkReadQuery is a parser's judgement; a read-only transaction has
+	// the server refuse any change the query would make all the same.
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return denied("start a read-only transaction", err)
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return denied("run the query", err)
+	}
+	defer rows.Close()
+	columns, results, truncated, err := scanRowMapsLimit(rows, maxRows)
+	if err != nil {
+		return denied("read the result", err)
+	}
+
+	resultText := fmt.Sprintf("Allowed: ran as %s (account %s), %d row(s)", currentUser, args.Account, len(results))
+	if truncated {
+		resultText += fmt.Sprintf(", showing the first %d", maxRows)
+	}
+	resultText += "\n\n" + formatRowTable(columns, results)
+	if len(grants) > 0 {
+		resultText += "\nGrants:\n  " + strings.Join(grants, "\n  ") + "\n"
+	}
+	structured["allowed"] = true
+	structured["columns"] = columns
+	structured["rows"] = results
+	structured["truncated"] = truncated
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, structured, nil
+}
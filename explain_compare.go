@@ -0,0 +1,584 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultExplainSampleRows = 10000
+	maxExplainSampleRows     = 1000000
+)
+
+type CompareExplainParams struct {
+	Query string `json:"query"`
+	// RewrittenQuery is explained as the "after" plan; the query itself by
+	// default, for comparing proposed indexes alone.
+	RewrittenQuery string `json:"rewritten_query,omitempty"`
+	Database       string `json:"database,omitempty"`
+	// Indexes are added to copies of the tables in a sandbox schema, so
+	// the real tables are never altered.
+	Indexes    []ProposedIndex `json:"indexes,omitempty"`
+	SampleRows int             `json:"sample_rows,omitempty"`
+}
+
+// ProposedIndex is an index compare_explain tries in its sandbox.
+type ProposedIndex struct {
+	Table string `json:"table"`
+	// Columns are column names, each optionally with a prefix length and
+	// direction, as in name(10) or created_at DESC.
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+	Name    string   `json:"name,omitempty"`
+}
+
+// PlanStep is a row of a traditional EXPLAIN.
+type PlanStep struct {
+	ID           string  `json:"id"`
+	SelectType   string  `json:"selectType"`
+	Table        string  `json:"table"`
+	Access       string  `json:"access"`
+	PossibleKeys string  `json:"possibleKeys,omitempty"`
+	Key          string  `json:"key,omitempty"`
+	Rows         int64   `json:"rows"`
+	Filtered     float64 `json:"filtered,omitempty"`
+	Extra        string  `json:"extra,omitempty"`
+}
+
+// ExplainPlan is the optimizer's plan for a query.
+type ExplainPlan struct {
+	Steps []PlanStep `json:"steps"`
+	// Cost is MySQL's query_cost from EXPLAIN FORMAT=JSON; MariaDB does
+	// not report one.
+	Cost float64 `json:"cost,omitempty"`
+	// RowsEstimate is the sum of the steps' row estimates.
+	RowsEstimate int64 `json:"rowsEstimate"`
+
+	columns []string
+	rows    []map[string]any
+}
+
+// PlanChange is how the access to one table differs between two plans.
+type PlanChange struct {
+	Table string `json:"table"`
+	// Verdict is better, worse, changed, added or removed.
+	Verdict string    `json:"verdict"`
+	Changes []string  `json:"changes"`
+	Before  *PlanStep `json:"before,omitempty"`
+	After   *PlanStep `json:"after,omitempty"`
+}
+
+// accessRank orders EXPLAIN's access types from the cheapest to a full
+// table scan.
+var accessRank = map[string]int{
+	"system": 0, "const": 1, "eq_ref": 2, "ref": 3, "fulltext": 4, "ref_or_null": 5,
+	"index_merge": 6, "unique_subquery": 7, "index_subquery": 8, "range": 9, "index": 10, "ALL": 11,
+}
+
+// proposedColumnPattern matches a ProposedIndex column: a name with an
+// optional prefix length and direction.
+var proposedColumnPattern = regexp.MustCompile(`(?i)^\s*([^\s()]+)\s*(?:\(\s*(\d+)\s*\))?\s*(ASC|DESC)?\s*$`)
+
+// definition renders the index for ALTER TABLE ... ADD.
+func (p ProposedIndex) definition() (string, error) {
+	if len(p.Columns) == 0 {
+		return "", fmt.Errorf("index on %s has no columns", p.Table)
+	}
+	parts := make([]string, len(p.Columns))
+	for i, c := range p.Columns {
+		m := proposedColumnPattern.FindStringSubmatch(c)
+		if m == nil {
+			return "", fmt.Errorf("invalid index column %q", c)
+		}
+		parts[i] = quoteIdentifier(m[1])
+		if m[2] != "" {
+			parts[i] += "(" + m[2] + ")"
+		}
+		if m[3] != "" {
+			parts[i] += " " + strings.ToUpper(m[3])
+		}
+	}
+	kind := "INDEX"
+	if p.Unique {
+		kind = "UNIQUE INDEX"
+	}
+	if p.Name != "" {
+		kind += " " + quoteIdentifier(p.Name)
+	}
+	return fmt.Sprintf("%s (%s)", kind, strings.Join(parts, ", ")), nil
+}
+
+// checkExplainable returns an error unless query is a statement EXPLAIN
+// shows a plan for. EXPLAIN never runs it, so writes are allowed.
+func checkExplainable(query string) error {
+	switch strings.ToUpper(firstWord(sanitizeStatement(query))) {
+	case "SELECT", "WITH", "TABLE", "UPDATE", "DELETE", "INSERT", "REPLACE":
+		return nil
+	}
+	return errors.New("only SELECT, WITH, TABLE, UPDATE, DELETE, INSERT and REPLACE statements can be explained")
+}
+
+// planText renders an EXPLAIN value, with NULL as "".
+func planText(v any) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// explainPlan runs EXPLAIN for query on conn, and EXPLAIN FORMAT=JSON for
+// its cost where the server gives one.
+func explainPlan(ctx context.Context, conn *sql.Conn, query string) (ExplainPlan, error) {
+	var plan ExplainPlan
+	rows, err := conn.QueryContext(ctx, "EXPLAIN "+query)
+	if err != nil {
+		return plan, err
+	}
+	plan.columns, plan.rows, err = scanRowMaps(rows)
+	rows.Close()
+	if err != nil {
+		return plan, err
+	}
+	if isTiDBPlan(plan.columns) {
+		for _, row := range plan.rows {
+			estRows, _ := strconv.ParseFloat(planText(row["estRows"]), 64)
+			step := PlanStep{
+				ID:     strings.TrimLeft(planText(row["id"]), " │├└─"),
+				Table:  planText(row["access object"]),
+				Access: planText(row["task"]),
+				Rows:   int64(estRows),
+				Extra:  planText(row["operator info"]),
+			}
+			plan.Steps = append(plan.Steps, step)
+		}
+	} else {
+		for _, row := range plan.rows {
+			step := PlanStep{
+				ID:           planText(row["id"]),
+				SelectType:   planText(row["select_type"]),
+				Table:        planText(row["table"]),
+				Access:       planText(row["type"]),
+				PossibleKeys: planText(row["possible_keys"]),
+				Key:          planText(row["key"]),
+				Extra:        planText(row["Extra"]),
+			}
+			step.Rows, _ = strconv.ParseInt(planText(row["rows"]), 10, 64)
+			step.Filtered, _ = strconv.ParseFloat(planText(row["filtered"]), 64)
+			plan.Steps = append(plan.Steps, step)
+		}
+	}
+	for _, step := range plan.Steps {
+		plan.RowsEstimate += step.Rows
+	}
+
+	var document string
+	if err := conn.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query).Scan(&document); err == nil {
+		var parsed struct {
+			QueryBlock struct {
+				CostInfo struct {
+					QueryCost string `json:"query_cost"`
+				} `json:"cost_info"`
+			} `json:"query_block"`
+		}
+		if json.Unmarshal([]byte(document), &parsed) == nil {
+			plan.Cost, _ = strconv.ParseFloat(parsed.QueryBlock.CostInfo.QueryCost, 64)
+		}
+	}
+	return plan, nil
+}
+
+// diffPlans pairs the steps of two plans by table, in order of
+// appearance, and describes how each table's access changed. Unchanged
+// tables are left out.
+func diffPlans(before, after ExplainPlan) []PlanChange {
+	key := func(steps []PlanStep) []string {
+		seen := make(map[string]int)
+		keys := make([]string, len(steps))
+		for i, s := range steps {
+			keys[i] = fmt.Sprintf("%s#%d", s.Table, seen[s.Table])
+			seen[s.Table]++
+		}
+		return keys
+	}
+	beforeKeys, afterKeys := key(before.Steps), key(after.Steps)
+	afterByKey := make(map[string]*PlanStep)
+	for i, k := range afterKeys {
+		afterByKey[k] = &after.Steps[i]
+	}
+
+	var changes []PlanChange
+	matched := make(map[string]bool)
+	for i, k := range beforeKeys {
+		b := &before.Steps[i]
+		a, ok := afterByKey[k]
+		if !ok {
+			changes = append(changes, PlanChange{Table: b.Table, Verdict: "removed", Changes: []string{"no longer accessed"}, Before: b})
+			continue
+		}
+		matched[k] = true
+		if change, ok := diffSteps(b, a); ok {
+			changes = append(changes, change)
+		}
+	}
+	for i, k := range afterKeys {
+		if !matched[k] {
+			a := &after.Steps[i]
+			changes = append(changes, PlanChange{Table: a.Table, Verdict: "added", Changes: []string{fmt.Sprintf("newly accessed (%s, about %d rows)", a.Access, a.Rows)}, After: a})
+		}
+	}
+	return changes
+}
+
+// diffSteps compares the access to one table in two plans; ok is false
+// when nothing changed.
+func diffSteps(b, a *PlanStep) (PlanChange, bool) {
+	change := PlanChange{Table: b.Table, Before: b, After: a}
+	orNone := func(s string) string {
+		if s == "" {
+			return "none"
+		}
+		return s
+	}
+	if b.Access != a.Access {
+		change.Changes = append(change.Changes, fmt.Sprintf("access %s → %s", orNone(b.Access), orNone(a.Access)))
+	}
+	if b.Key != a.Key {
+		change.Changes = append(change.Changes, fmt.Sprintf("key %s → %s", orNone(b.Key), orNone(a.Key)))
+	}
+	if b.Rows != a.Rows {
+		change.Changes = append(change.Changes, fmt.Sprintf("rows %d → %d", b.Rows, a.Rows))
+	}
+	if b.Filtered != a.Filtered && b.Filtered > 0 && a.Filtered > 0 {
+		change.Changes = append(change.Changes, fmt.Sprintf("filtered %g%% → %g%%", b.Filtered, a.Filtered))
+	}
+	extras := func(s string) map[string]bool {
+		set := make(map[string]bool)
+		for _, e := range strings.Split(s, "; ") {
+			if e != "" {
+				set[e] = true
+			}
+		}
+		return set
+	}
+	beforeExtra, afterExtra := extras(b.Extra), extras(a.Extra)
+	for _, e := range sortedKeys(beforeExtra) {
+		if !afterExtra[e] {
+			change.Changes = append(change.Changes, "no longer "+e)
+		}
+	}
+	for _, e := range sortedKeys(afterExtra) {
+		if !beforeExtra[e] {
+			change.Changes = append(change.Changes, "now "+e)
+		}
+	}
+	if len(change.Changes) == 0 {
+		return change, false
+	}
+
+	// Access types are compared first; between equal ones the row
+	// estimate decides.
+	beforeRank, knownBefore := accessRank[b.Access]
+	afterRank, knownAfter := accessRank[a.Access]
+	switch {
+	case knownBefore && knownAfter && afterRank < beforeRank:
+		change.Verdict = "better"
+	case knownBefore && knownAfter && afterRank > beforeRank:
+		change.Verdict = "worse"
+	case a.Rows < b.Rows:
+		change.Verdict = "better"
+	case a.Rows > b.Rows:
+		change.Verdict = "worse"
+	default:
+		change.Verdict = "changed"
+	}
+	return change, true
+}
+
+// retargetQuery points the names query qualifies with database at
+// sandbox instead.
+func retargetQuery(query, database, sandbox string) string {
+	tokens := tokenizeSQL(query)
+	changed := false
+	for i := 0; i+1 < len(tokens); i++ {
+		if isIdentifierToken(tokens[i]) && strings.EqualFold(identifierText(tokens[i]), database) && tokens[i+1].text == "." && (i == 0 || tokens[i-1].text != ".") {
+			tokens[i] = sqlToken{kind: tokenQuoted, text: quoteIdentifier(sandbox), space: tokens[i].space}
+			changed = true
+		}
+	}
+	if !changed {
+		return query
+	}
+	return joinTokens(tokens)
+}
+
+// explainSandbox is a scratch schema holding sampled copies of tables.
+type explainSandbox struct {
+	name   string
+	tables []string
+	copied map[string]int64
+}
+
+// createExplainSandbox creates a schema holding copies of database's tables
+// with up to sampleRows rows each, and analyzes them so the optimizer has
+// statistics for the sample.
+func createExplainSandbox(ctx context.Context, db *sql.DB, conn *sql.Conn, database string, tables []string, sampleRows int) (*explainSandbox, error) {
+	b := make([]byte, 4)
+	rand.Read(b)
+	sandbox := &explainSandbox{name: "mcp_explain_" + hex.EncodeToString(b), copied: make(map[string]int64)}
+	if _, err := conn.ExecContext(ctx, "CREATE DATABASE "+quoteIdentifier(sandbox.name)); err != nil {
+		return nil, fmt.Errorf("failed to create sandbox schema: %w", err)
+	}
+	for _, table := range tables {
+		cols, err := tableColumns(ctx, db, database, table)
+		if err != nil {
+			return sandbox, err
+		}
+		if len(cols) == 0 {
+			return sandbox, fmt.Errorf("table %s.%s does not exist", database, table)
+		}
+		target := qualifiedTable(sandbox.name, table)
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s LIKE %s", target, qualifiedTable(database, table))); err != nil {
+			return sandbox, fmt.Errorf("failed to copy %s: %w", table, err)
+		}
+		sandbox.tables = append(sandbox.tables, table)
+		// Generated columns are computed from the copied ones.
+		var names []string
+		for _, c := range cols {
+			if !strings.Contains(strings.ToUpper(c.Extra), "GENERATED") {
+				names = append(names, c.ColumnName)
+			}
+		}
+		list := quoteIdentifierList(names)
+		result, err := conn.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s LIMIT %d", target, list, list, qualifiedTable(database, table), sampleRows))
+		if err != nil {
+			return sandbox, fmt.Errorf("failed to copy rows of %s: %w", table, err)
+		}
+		sandbox.copied[table], _ = result.RowsAffected()
+	}
+	return sandbox, sandbox.analyze(ctx, conn)
+}
+
+// analyze refreshes the statistics of the sandbox's tables.
+func (s *explainSandbox) analyze(ctx context.Context, conn *sql.Conn) error {
+	if len(s.tables) == 0 {
+		return nil
+	}
+	names := make([]string, len(s.tables))
+	for i, t := range s.tables {
+		names[i] = qualifiedTable(s.name, t)
+	}
+	// ANALYZE TABLE returns a result set, which must be read.
+	rows, err := conn.QueryContext(ctx, "ANALYZE TABLE "+strings.Join(names, ", "))
+	if err != nil {
+		return fmt.Errorf("failed to analyze sandbox tables: %w", err)
+	}
+	return rows.Close()
+}
+
+// drop removes the sandbox schema. It runs on its own context, so that a
+// cancelled call still cleans up.
+func (s *explainSandbox) drop() {
+	db := currentDB()
+	if _, err := db.ExecContext(context.Background(), "DROP DATABASE IF EXISTS "+quoteIdentifier(s.name)); err != nil {
+		slog.Warn("failed to drop explain sandbox", "schema", s.name, "err", err)
+	}
+}
+
+// formatPlan renders a plan as EXPLAIN returned it.
+func formatPlan(plan ExplainPlan) string {
+	if isTiDBPlan(plan.columns) {
+		return formatTiDBPlan(plan.columns, plan.rows)
+	}
+	return formatRowTable(plan.columns, plan.rows)
+}
+
+func CompareExplain(ctx context.Context, req *mcp.CallToolRequest, args CompareExplainParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	errorResult := func(format string, a ...any) (*mcp.CallToolResult, any, error) {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf(format, a...)},
+			},
+		}, nil, nil
+	}
+
+	query := strings.TrimSuffix(strings.TrimSpace(args.Query), ";")
+	rewritten := strings.TrimSuffix(strings.TrimSpace(args.RewrittenQuery), ";")
+	if rewritten == "" {
+		rewritten = query
+	}
+	if query == "" {
+		return errorResult("query is required")
+	}
+	if rewritten == query && len(args.Indexes) == 0 {
+		return errorResult("Nothing to compare: give a rewritten_query, indexes, or both")
+	}
+	for _, q := range []string{query, rewritten} {
+		if len(splitSQLStatements(q)) != 1 {
+			return errorResult("query and rewritten_query must each be a single statement")
+		}
+		if err := checkExplainable(q); err != nil {
+			return errorResult("%v", err)
+		}
+	}
+	sampleRows := args.SampleRows
+	if sampleRows <= 0 {
+		sampleRows = defaultExplainSampleRows
+	}
+	if sampleRows > maxExplainSampleRows {
+		return errorResult("sample_rows must be at most %d", maxExplainSampleRows)
+	}
+	definitions := make([]string, len(args.Indexes))
+	for i, index := range args.Indexes {
+		definition, err := index.definition()
+		if err != nil {
+			return errorResult("%v", err)
+		}
+		definitions[i] = definition
+	}
+	if len(args.Indexes) > 0 {
+		// The sandbox only adds a scratch schema, but that is a write.
+		if err := checkWritable("compare_explain with indexes"); err != nil {
+			return errorResult("%v", err)
+		}
+	}
+
+	// USE changes the session's database, so the connection is not
+	// returned to the pool.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return errorResult("Failed to get connection: %v", err)
+	}
+	defer discardConn(conn)
+	database := args.Database
+	if database != "" {
+		if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(database)); err != nil {
+			return errorResult("Failed to select database %s: %v", database, err)
+		}
+	} else {
+		var current sql.NullString
+		if err := conn.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&current); err == nil {
+			database = current.String
+		}
+	}
+
+	var before, after ExplainPlan
+	var sandbox *explainSandbox
+	if len(args.Indexes) == 0 {
+		if before, err = explainPlan(ctx, conn, query); err != nil {
+			return errorResult("EXPLAIN of query failed: %v", err)
+		}
+		if after, err = explainPlan(ctx, conn, rewritten); err != nil {
+			return errorResult("EXPLAIN of rewritten_query failed: %v", err)
+		}
+	} else {
+		if database == "" {
+			return errorResult("database is required to try indexes: no database is selected")
+		}
+		// The sandbox holds the tables of database the queries use and
+		// those the indexes are on; tables of other schemas are read in
+		// place.
+		var tables []string
+		seen := make(map[string]bool)
+		addTable := func(name string) {
+			if !seen[strings.ToLower(name)] {
+				seen[strings.ToLower(name)] = true
+				tables = append(tables, name)
+			}
+		}
+		for _, q := range []string{query, rewritten} {
+			for _, ref := range referencedTables(tokenizeSQL(q)) {
+				if ref[0] == "" || strings.EqualFold(ref[0], database) {
+					addTable(ref[1])
+				}
+			}
+		}
+		for _, index := range args.Indexes {
+			addTable(index.Table)
+		}
+
+		sandbox, err = createExplainSandbox(ctx, db, conn, database, tables, sampleRows)
+		if sandbox != nil {
+			defer sandbox.drop()
+		}
+		if err != nil {
+			return errorResult("Failed to build the sandbox: %v", err)
+		}
+		if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(sandbox.name)); err != nil {
+			return errorResult("Failed to select the sandbox: %v", err)
+		}
+		if before, err = explainPlan(ctx, conn, retargetQuery(query, database, sandbox.name)); err != nil {
+			return errorResult("EXPLAIN of query failed in the sandbox: %v", err)
+		}
+		for i, index := range args.Indexes {
+			if _, err := conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD %s", qualifiedTable(sandbox.name, index.Table), definitions[i])); err != nil {
+				return errorResult("Failed to add index %s on %s: %v", definitions[i], index.Table, err)
+			}
+		}
+		if err := sandbox.analyze(ctx, conn); err != nil {
+			return errorResult("%v", err)
+		}
+		if after, err = explainPlan(ctx, conn, retargetQuery(rewritten, database, sandbox.name)); err != nil {
+			return errorResult("EXPLAIN of the rewritten query failed in the sandbox: %v", err)
+		}
+	}
+
+	changes := diffPlans(before, after)
+	var b strings.Builder
+	if sandbox != nil {
+		var sizes []string
+		for _, t := range sandbox.tables {
+			sizes = append(sizes, fmt.Sprintf("%s (%d rows)", t, sandbox.copied[t]))
+		}
+		fmt.Fprintf(&b, "Both plans were taken in a sandbox schema holding copies of %s, of up to %d rows each; the real tables were not altered and the sandbox has been dropped. Row estimates and costs reflect the sample.\n", strings.Join(sizes, ", "), sampleRows)
+		fmt.Fprintf(&b, "Indexes tried: %s\n\n", strings.Join(definitions, "; "))
+	}
+	switch {
+	case before.Cost > 0 && after.Cost > 0:
+		fmt.Fprintf(&b, "Estimated cost: %g → %g (%+.1f%%)\n", before.Cost, after.Cost, (after.Cost-before.Cost)/before.Cost*100)
+	case before.Cost > 0 || after.Cost > 0:
+		fmt.Fprintf(&b, "Estimated cost: %g → %g\n", before.Cost, after.Cost)
+	}
+	fmt.Fprintf(&b, "Rows examined (sum of EXPLAIN rows): %d → %d\n\n", before.RowsEstimate, after.RowsEstimate)
+
+	if len(changes) == 0 {
+		b.WriteString("The plans have the same structure.\n")
+	} else {
+		b.WriteString("Changes:\n")
+		for _, c := range changes {
+			fmt.Fprintf(&b, "  %s (%s): %s\n", c.Table, c.Verdict, strings.Join(c.Changes, ", "))
+		}
+	}
+	fmt.Fprintf(&b, "\nBefore:\n%s\nAfter:\n%s", formatPlan(before), formatPlan(after))
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: b.String()},
+		},
+	}, map[string]any{
+		"before":    before,
+		"after":     after,
+		"changes":   changes,
+		"sandboxed": sandbox != nil,
+	}, nil
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ExportQueryParams struct {
+	Query    string `json:"query"`
+	Path     string `json:"path"`
+	Format   string `json:"format,omitempty"`
+	Compress string `json:"compress,omitempty"`
+	// Overwrite replaces an existing file at Path instead of refusing.
+	Overwrite bool `json:"overwrite,omitempty"`
+}
+
+// exportStats describes a finished export file.
+type exportStats struct {
+	Rows    int
+	Columns []string
+	// RawBytes is the size of the encoded data before compression.
+	RawBytes int64
+}
+
+// resultWriter streams query rows into an export file.
+type resultWriter interface {
+	WriteRow(values []any) error
+	Close() error
+}
+
+func ExportQuery(ctx context.Context, req *mcp.CallToolRequest, args ExportQueryParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	query := strings.TrimSpace(args.Query)
+	if query == "" || args.Path == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Both query and path are required"},
+			},
+		}, nil, nil
+	}
+
+	format := strings.ToLower(args.Format)
+	switch format {
+	case "":
+		format = "csv"
+	case "feather", "ipc":
+		format = "arrow"
+	case "csv", "jsonl", "arrow":
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported export format %q (use csv, jsonl or arrow)", args.Format)},
+			},
+		}, nil, nil
+	}
+
+	compress := strings.ToLower(args.Compress)
+	switch compress {
+	case "", "none":
+		compress = ""
+	case "gzip", "gz":
+		compress = "gzip"
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported compression %q (use gzip or none)", args.Compress)},
+			},
+		}, nil, nil
+	}
+
+	path, err := filepath.Abs(args.Path)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid export path: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	if compress == "gzip" && !strings.HasSuffix(path, ".gz") {
+		path += ".gz"
+	}
+
+	if result := refuseExistingFile(path, args.Overwrite); result != nil {
+		return result, nil, nil
+	}
+
+	// Only results are exported: the statement runs in a read-only
+	// transaction, but DDL would commit it and run anyway.
+	if err := checkResultQuery(query); err != nil {
+		recordPolicyBlock("export_query")
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("export_query only exports query results: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	if blocked := enforceCostPolicy(ctx, req, "export_query", db, query); blocked != nil {
+		return blocked, nil, nil
+	}
+
+	start := time.Now()
+	stats, err := exportQuery(ctx, db, query, path, format, compress, args.Overwrite)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Export failed: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+
+	resultText := fmt.Sprintf("Exported %d rows (%d columns) to %s\nFormat: %s\n",
+		stats.Rows, len(stats.Columns), path, format)
+	if compress != "" {
+		ratio := 0.0
+		if stats.RawBytes > 0 {
+			ratio = float64(size) / float64(stats.RawBytes) * 100
+		}
+		resultText += fmt.Sprintf("Compression: %s\nSize: %s (uncompressed %s, %.1f%%)\n",
+			compress, formatBytes(size), formatBytes(stats.RawBytes), ratio)
+	} else {
+		resultText += fmt.Sprintf("Size: %s\n", formatBytes(size))
+	}
+	resultText += fmt.Sprintf("Duration: %s", time.Since(start).Round(time.Millisecond))
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"path":              path,
+		"format":            format,
+		"compression":       compress,
+		"rowCount":          stats.Rows,
+		"columns":           stats.Columns,
+		"bytes":             size,
+		"uncompressedBytes": stats.RawBytes,
+	}, nil
+}
+
+// refuseExistingFile reports a file already at path unless the caller asked
+// to overwrite it, so an export never silently replaces unrelated data.
+func refuseExistingFile(path string, overwrite bool) *mcp.CallToolResult {
+	if overwrite {
+		return nil
+	}
+	if _, err := os.Lstat(path); err != nil {
+		return nil
+	}
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("%s already exists; set overwrite to replace it", path)},
+		},
+	}
+}
+
+// exportQuery writes the result of query to path. Without overwrite an
+// existing file is left alone and reported as an error. A file it created
+// or truncated is removed again if the export fails, but one it never got
+// to open is not touched.
+func exportQuery(ctx context.Context, db *sql.DB, query, path, format, compress string, overwrite bool) (_ *exportStats, err error) {
+	rows, done, err := queryReadOnly(ctx, db, query)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	stats := &exportStats{Columns: make([]string, len(columnTypes))}
+	for i, ct := range columnTypes {
+		stats.Columns[i] = ct.Name()
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0o666)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(path)
+		}
+	}()
+
+	buffered := bufio.NewWriterSize(file, 1<<20)
+	var sink io.Writer = buffered
+	var gz *gzip.Writer
+	if compress == "gzip" {
+		gz = gzip.NewWriter(buffered)
+		sink = gz
+	}
+	counter := &countingWriter{w: sink}
+
+	out, err := newResultWriter(counter, format, columnTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(columnTypes))
+	valuePtrs := make([]any, len(columnTypes))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	kinds := resultColumnKinds(rows, len(columnTypes))
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, err
+		}
+		// Vectors are exported in full, as text STRING_TO_VECTOR reads back.
+		// Geometries become WKT, or for Arrow standard WKB without MySQL's
+		// SRID prefix.
+		for i, kind := range kinds {
+			b, ok := values[i].([]byte)
+			switch {
+			case !ok:
+			case kind == "VECTOR":
+				values[i] = vectorText(b)
+			case kind == "GEOMETRY" && format == "arrow" && len(b) >= 4:
+				values[i] = b[4:]
+			case kind == "GEOMETRY":
+				values[i] = geometryText(b)
+			}
+		}
+		if err := out.WriteRow(values); err != nil {
+			return nil, err
+		}
+		stats.Rows++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := buffered.Flush(); err != nil {
+		return nil, err
+	}
+	stats.RawBytes = counter.n
+	return stats, file.Close()
+}
+
+// countingWriter tracks how many bytes pass through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// formatBytes renders a byte count in human-readable binary units.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func newResultWriter(w io.Writer, format string, columnTypes []*sql.ColumnType) (resultWriter, error) {
+	columns := make([]string, len(columnTypes))
+	for i, ct := range columnTypes {
+		columns[i] = ct.Name()
+	}
+
+	switch format {
+	case "jsonl":
+		return &jsonlResultWriter{enc: json.NewEncoder(w), columns: columns}, nil
+	case "arrow":
+		fields := make([]arrowField, len(columnTypes))
+		for i, ct := range columnTypes {
+			fields[i] = arrowField{Name: ct.Name(), Type: arrowTypeFor(ct)}
+		}
+		aw, err := newArrowWriter(w, fields)
+		if err != nil {
+			return nil, err
+		}
+		return &arrowResultWriter{aw}, nil
+	default:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return nil, err
+		}
+		return &csvResultWriter{cw: cw, record: make([]string, len(columns))}, nil
+	}
+}
+
+type csvResultWriter struct {
+	cw     *csv.Writer
+	record []string
+}
+
+func (w *csvResultWriter) WriteRow(values []any) error {
+	for i, val := range values {
+		switch v := val.(type) {
+		case nil:
+			w.record[i] = ""
+		case []byte:
+			w.record[i] = string(v)
+		default:
+			w.record[i] = fmt.Sprint(v)
+		}
+	}
+	return w.cw.Write(w.record)
+}
+
+func (w *csvResultWriter) Close() error {
+	w.cw.Flush()
+	return w.cw.Error()
+}
+
+type jsonlResultWriter struct {
+	enc     *json.Encoder
+	columns []string
+}
+
+func (w *jsonlResultWriter) WriteRow(values []any) error {
+	row := make(map[string]any, len(values))
+	for i, col := range w.columns {
+		if b, ok := values[i].([]byte); ok {
+			row[col] = string(b)
+		} else {
+			row[col] = values[i]
+		}
+	}
+	return w.enc.Encode(row)
+}
+
+func (w *jsonlResultWriter) Close() error {
+	return nil
+}
+
+type arrowResultWriter struct {
+	aw *arrowWriter
+}
+
+func (w *arrowResultWriter) WriteRow(values []any) error {
+	return w.aw.Append(values)
+}
+
+func (w *arrowResultWriter) Close() error {
+	return w.aw.Close()
+}
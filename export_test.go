@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// withIdleConnection makes an unconnected pool the default connection for
+// the test; nothing here talks to a server.
+func withIdleConnection(t *testing.T) {
+	t.Helper()
+	connector, err := mysql.NewConnector(mysql.NewConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := sql.OpenDB(connector)
+	setDefaultConnection(db, nil)
+	t.Cleanup(func() {
+		connection.Lock()
+		connection.db, connection.cfg = nil, nil
+		connection.Unlock()
+		db.Close()
+	})
+}
+
+func TestExportQueryReadOnly(t *testing.T) {
+	withIdleConnection(t)
+	readOnly = true
+	defer func() { readOnly = false }()
+
+	path := filepath.Join(t.TempDir(), "out.csv")
+	for _, query := range []string{
+		"INSERT INTO t VALUES (1)",
+		"UPDATE t SET a = 1",
+		"DROP TABLE t",
+		"CALL p()",
+		"SELECT * FROM t INTO OUTFILE '/tmp/x'",
+		"WITH x AS (SELECT 1) DELETE FROM t",
+	} {
+		result, _, err := ExportQuery(context.Background(), nil, ExportQueryParams{Query: query, Path: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.IsError || !strings.Contains(result.Content[0].(*mcp.TextContent).Text, "only exports query results") {
+			t.Errorf("ExportQuery(%q) was not refused in read-only mode", query)
+		}
+		if _, err := os.Stat(path); err == nil {
+			t.Errorf("ExportQuery(%q) created %s", query, path)
+		}
+	}
+}
+
+func TestExportQueryOnlyExportsResults(t *testing.T) {
+	withIdleConnection(t)
+
+	path := filepath.Join(t.TempDir(), "out.csv")
+	for _, query := range []string{
+		"DELETE FROM t",
+		"DROP TABLE t",
+		"SHOW TABLES",
+		"EXPLAIN SELECT * FROM t",
+		"SELECT 1; DROP TABLE t",
+	} {
+		result, _, err := ExportQuery(context.Background(), nil, ExportQueryParams{Query: query, Path: path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.IsError || !strings.Contains(result.Content[0].(*mcp.TextContent).Text, "only exports query results") {
+			t.Errorf("ExportQuery(%q) was not refused", query)
+		}
+	}
+}
+
+func TestExportQueryKeepsExistingFile(t *testing.T) {
+	withIdleConnection(t)
+
+	path := filepath.Join(t.TempDir(), "out.csv")
+	if err := os.WriteFile(path, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// Without overwrite the file is refused; with it, the query fails
+	// before the file is opened because nothing is listening.
+	for _, overwrite := range []bool{false, true} {
+		result, _, err := ExportQuery(context.Background(), nil, ExportQueryParams{Query: "SELECT 1", Path: path, Overwrite: overwrite})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.IsError {
+			t.Fatalf("ExportQuery(overwrite=%v) succeeded without a server", overwrite)
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != "keep" {
+			t.Errorf("ExportQuery(overwrite=%v) left %q, %v; want the existing file untouched", overwrite, data, err)
+		}
+	}
+}
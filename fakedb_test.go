@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeServer stands in for a MySQL server in tests. It records every
+// statement, transaction boundaries included, and answers each with
+// handle; a nil handle or result answers with no rows and no rows
+// affected.
+type fakeServer struct {
+	handle func(ctx context.Context, query string, args []driver.NamedValue) (*fakeResult, error)
+
+	mu       sync.Mutex
+	log      []string
+	connects int
+}
+
+// fakeResult is the answer to one statement: rows for a query, or the
+// affected row count for anything else.
+type fakeResult struct {
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+}
+
+func (s *fakeServer) Connect(context.Context) (driver.Conn, error) {
+	s.mu.Lock()
+	s.connects++
+	s.mu.Unlock()
+	return &fakeConn{server: s}, nil
+}
+
+func (s *fakeServer) Driver() driver.Driver { return fakeDriver{} }
+
+func (s *fakeServer) record(statement string) {
+	s.mu.Lock()
+	s.log = append(s.log, statement)
+	s.mu.Unlock()
+}
+
+func (s *fakeServer) run(ctx context.Context, query string, args []driver.NamedValue) (*fakeResult, error) {
+	s.record(query)
+	if s.handle == nil {
+		return &fakeResult{}, nil
+	}
+	res, err := s.handle(ctx, query, args)
+	if res == nil && err == nil {
+		res = &fakeResult{}
+	}
+	return res, err
+}
+
+// statements returns the statements run so far.
+func (s *fakeServer) statements() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.log...)
+}
+
+// ran reports whether a statement starting with prefix was run.
+func (s *fakeServer) ran(prefix string) bool {
+	for _, statement := range s.statements() {
+		if strings.HasPrefix(statement, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fakeDriver cannot open connections by name")
+}
+
+type fakeConn struct {
+	server *fakeServer
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fakeConn does not prepare statements")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly {
+		c.server.record("BEGIN READ ONLY")
+	} else {
+		c.server.record("BEGIN")
+	}
+	return fakeTx{c.server}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.server.run(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(res.affected), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.server.run(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: res.columns, rows: res.rows}, nil
+}
+
+type fakeTx struct {
+	server *fakeServer
+}
+
+func (tx fakeTx) Commit() error {
+	tx.server.record("COMMIT")
+	return nil
+}
+
+func (tx fakeTx) Rollback() error {
+	tx.server.record("ROLLBACK")
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// withFakeConnection makes a pool on server the default connection for the
+// test and returns it.
+func withFakeConnection(t *testing.T, server *fakeServer) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(server)
+	setDefaultConnection(db, nil)
+	t.Cleanup(func() {
+		connection.Lock()
+		connection.db, connection.cfg = nil, nil
+		connection.Unlock()
+		closePool(db)
+	})
+	return db
+}
+
+// withTableColumns puts the columns of database.table on db in the schema
+// cache, so tools find the table without querying information_schema.
+func withTableColumns(t *testing.T, db *sql.DB, database, table string, columns ...string) {
+	t.Helper()
+	cols := make([]ColumnInfo, len(columns))
+	for i, name := range columns {
+		cols[i] = ColumnInfo{ColumnName: name, DataType: "int", IsNullable: "YES"}
+	}
+	cacheTableColumns(db, database, table, cols)
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultFederatedMaxRows = 10000
+	maxFederatedRows        = 100000
+	defaultFederatedLimit   = 1000
+	// federatedSourceColumn names the column that tells the sources of a
+	// union apart.
+	federatedSourceColumn = "_source"
+)
+
+// FederatedSource is one query of a federated query and the connection it
+// runs on.
+type FederatedSource struct {
+	Connection string `json:"connection,omitempty"`
+	Alias      string `json:"alias,omitempty"`
+	Query      string `json:"query"`
+}
+
+// FederatedSourceResult describes the rows read from one source.
+type FederatedSourceResult struct {
+	Alias      string `json:"alias"`
+	Connection string `json:"connection"`
+	Rows       int    `json:"rows"`
+	Truncated  bool   `json:"truncated"`
+	// Unmatched counts the rows of a joined source that no row of the
+	// sources before it matched.
+	Unmatched int `json:"unmatched"`
+}
+
+type FederatedQueryParams struct {
+	Sources  []FederatedSource `json:"sources"`
+	Mode     string            `json:"mode,omitempty"`
+	On       []string          `json:"on,omitempty"`
+	JoinType string            `json:"join_type,omitempty"`
+	MaxRows  int               `json:"max_rows,omitempty"`
+	Limit    int               `json:"limit,omitempty"`
+}
+
+// federatedResult is the rows of one source.
+type federatedResult struct {
+	alias   string
+	columns []string
+	rows    []map[string]any
+}
+
+func FederatedQuery(ctx context.Context, req *mcp.CallToolRequest, args FederatedQueryParams) (*mcp.CallToolResult, any, error) {
+	if len(args.Sources) < 2 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "sources needs at least two queries"},
+			},
+		}, nil, nil
+	}
+	mode := strings.ToLower(args.Mode)
+	if mode == "" {
+		mode = "union"
+		if len(args.On) > 0 {
+			mode = "join"
+		}
+	}
+	joinType := strings.ToLower(args.JoinType)
+	if joinType == "" {
+		joinType = "inner"
+	}
+	switch {
+	case mode != "union" && mode != "join":
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown mode %q (use union or join)", args.Mode)},
+			},
+		}, nil, nil
+	case mode == "join" && len(args.On) == 0:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "join needs the key columns to join on in on"},
+			},
+		}, nil, nil
+	case joinType != "inner" && joinType != "left" && joinType != "full":
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown join_type %q (use inner, left or full)", args.JoinType)},
+			},
+		}, nil, nil
+	}
+	maxRows := args.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultFederatedMaxRows
+	}
+	maxRows = min(maxRows, maxFederatedRows)
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultFederatedLimit
+	}
+
+	aliases := make(map[string]bool)
+	var stats []FederatedSourceResult
+	var results []federatedResult
+	for i, source := range args.Sources {
+		connection := source.Connection
+		if connection == "" {
+			connection = defaultConnectionName
+		}
+		alias := source.Alias
+		if alias == "" {
+			alias = connection
+		}
+		if aliases[alias] {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Source %d: alias %q is already used; give each source on the same connection an alias", i, alias)},
+				},
+			}, nil, nil
+		}
+		aliases[alias] = true
+		if err := checkReadQuery(source.Query); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Source %s: %v", alias, err)},
+				},
+			}, nil, nil
+		}
+		conn, err := connectionFor(source.Connection)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Source %s: %s", alias, connectionHint(err))},
+				},
+			}, nil, nil
+		}
+
+		if blocked := enforceCostPolicy(ctx, req, "federated_query", conn, source.Query); blocked != nil {
+			return blocked, nil, nil
+		}
+
+		rows, done, err := queryReadOnly(ctx, conn, source.Query)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Source %s: query failed: %v", alias, err)},
+				},
+			}, nil, nil
+		}
+		columns, sourceRows, truncated, err := scanRowMapsLimit(rows, maxRows)
+		done()
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Source %s: %v", alias, err)},
+				},
+			}, nil, nil
+		}
+		if mode == "join" {
+			for _, key := range args.On {
+				if !slices.Contains(columns, key) {
+					return &mcp.CallToolResult{
+						IsError: true,
+						Content: []mcp.Content{
+							&mcp.TextContent{Text: fmt.Sprintf("Source %s has no column %q to join on (columns: %s)", alias, key, strings.Join(columns, ", "))},
+						},
+					}, nil, nil
+				}
+			}
+		}
+		results = append(results, federatedResult{alias: alias, columns: columns, rows: sourceRows})
+		stats = append(stats, FederatedSourceResult{Alias: alias, Connection: connection, Rows: len(sourceRows), Truncated: truncated})
+	}
+
+	var columns []string
+	var combined []map[string]any
+	capped := false
+	if mode == "union" {
+		columns, combined = federatedUnion(results)
+	} else {
+		columns, combined, capped = federatedJoin(results, args.On, joinType, stats)
+	}
+	total := len(combined)
+	combined = combined[:min(total, limit)]
+
+	var resultText string
+	if mode == "union" {
+		resultText = fmt.Sprintf("Union of %d sources: %d rows", len(results), total)
+	} else {
+		resultText = fmt.Sprintf("%s join of %d sources on %s: %d rows", strings.ToUpper(joinType[:1])+joinType[1:], len(results), strings.Join(args.On, ", "), total)
+	}
+	if capped {
+		resultText += fmt.Sprintf(" (the join stopped at %d rows)", maxFederatedRows)
+	}
+	if total > limit {
+		resultText += fmt.Sprintf(", showing the first %d", limit)
+	}
+	resultText += "\n"
+	for i, s := range stats {
+		resultText += fmt.Sprintf("  %s (%s): %d rows", s.Alias, s.Connection, s.Rows)
+		if s.Truncated {
+			resultText += fmt.Sprintf(", stopped at max_rows %d", maxRows)
+		}
+		if mode == "join" && i > 0 {
+			resultText += fmt.Sprintf(", %d unmatched", s.Unmatched)
+		}
+		resultText += "\n"
+	}
+	if len(combined) > 0 {
+		resultText += "\n" + formatRowTable(columns, combined)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"columns":  columns,
+		"rows":     combined,
+		"rowCount": total,
+		"capped":   capped,
+		"sources":  stats,
+	}, nil
+}
+
+// federatedUnion appends the rows of all sources under the union of their
+// columns, in order of first appearance, after a column naming each row's
+// source.
+func federatedUnion(results []federatedResult) ([]string, []map[string]any) {
+	columns := []string{federatedSourceColumn}
+	for _, r := range results {
+		for _, c := range r.columns {
+			if !slices.Contains(columns, c) {
+				columns = append(columns, c)
+			}
+		}
+	}
+	var combined []map[string]any
+	for _, r := range results {
+		for _, row := range r.rows {
+			out := make(map[string]any, len(columns))
+			for _, c := range columns {
+				out[c] = row[c]
+			}
+			out[federatedSourceColumn] = r.alias
+			combined = append(combined, out)
+		}
+	}
+	return columns, combined
+}
+
+// federatedJoin hash joins the sources in order on the key columns, each
+// with the result of the joins before it. Key values match when their text
+// is equal, so an integer key matches the same key read as a string from
+// another server, and as in SQL a NULL key matches nothing. The key
+// columns appear once, and the other columns are prefixed with their
+// source's alias. Rows of each source that nothing matched are counted in
+// stats. A join stops after maxFederatedRows rows, reported by capped.
+func federatedJoin(results []federatedResult, on []string, joinType string, stats []FederatedSourceResult) (columns []string, combined []map[string]any, capped bool) {
+	columns = append([]string{}, on...)
+	for i, r := range results {
+		var own []string
+		for _, c := range r.columns {
+			if !slices.Contains(on, c) {
+				own = append(own, r.alias+"."+c)
+			}
+		}
+		columns = append(columns, own...)
+		prefixed := func(row map[string]any) map[string]any {
+			out := make(map[string]any, len(columns))
+			for _, c := range r.columns {
+				if slices.Contains(on, c) {
+					out[c] = row[c]
+				} else {
+					out[r.alias+"."+c] = row[c]
+				}
+			}
+			return out
+		}
+		if i == 0 {
+			for _, row := range r.rows {
+				combined = append(combined, prefixed(row))
+			}
+			continue
+		}
+
+		byKey := make(map[string][]map[string]any)
+		for _, row := range r.rows {
+			if k, ok := federatedKey(row, on); ok {
+				byKey[k] = append(byKey[k], row)
+			}
+		}
+		matched := make(map[string]bool)
+		var joined []map[string]any
+		for _, left := range combined {
+			if len(joined) >= maxFederatedRows {
+				capped = true
+				break
+			}
+			k, _ := federatedKey(left, on)
+			partners := byKey[k]
+			if len(partners) == 0 {
+				if joinType != "inner" {
+					joined = append(joined, left)
+				}
+				continue
+			}
+			matched[k] = true
+			for _, right := range partners {
+				out := prefixed(right)
+				for c, v := range left {
+					out[c] = v
+				}
+				joined = append(joined, out)
+			}
+		}
+		for _, row := range r.rows {
+			if k, ok := federatedKey(row, on); ok && matched[k] {
+				continue
+			}
+			stats[i].Unmatched++
+			if joinType == "full" {
+				joined = append(joined, prefixed(row))
+			}
+		}
+		combined = joined
+	}
+
+	// Missing columns are null rather than absent, as in SQL outer joins.
+	for _, row := range combined {
+		for _, c := range columns {
+			if _, ok := row[c]; !ok {
+				row[c] = nil
+			}
+		}
+	}
+	return columns, combined, capped
+}
+
+// federatedKey returns the text of a row's key values, and false if any of
+// them is NULL.
+func federatedKey(row map[string]any, on []string) (string, bool) {
+	var b strings.Builder
+	for _, c := range on {
+		if row[c] == nil {
+			return "", false
+		}
+		fmt.Fprintf(&b, "%v\x00", row[c])
+	}
+	return b.String(), true
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Filter is a structured WHERE condition on a single column.
+type Filter struct {
+	Column string `json:"column"`
+	Op     string `json:"op"`
+	Value  any    `json:"value,omitempty"`
+}
+
+// buildWhere turns filters into a parameterized condition joined with AND.
+// Every column must exist in tableCols.
+func buildWhere(filters []Filter, tableCols []ColumnInfo) (string, []any, error) {
+	known := make(map[string]bool, len(tableCols))
+	for _, col := range tableCols {
+		known[col.ColumnName] = true
+	}
+	return buildConditions(filters, func(column string) (string, error) {
+		if !known[column] {
+			return "", fmt.Errorf("unknown column %q", column)
+		}
+		return quoteIdentifier(column), nil
+	})
+}
+
+// buildConditions is buildWhere with columns turned into SQL by resolve,
+// which returns an error for columns that do not exist.
+func buildConditions(filters []Filter, resolve func(column string) (string, error)) (string, []any, error) {
+	var conds []string
+	var params []any
+	for i, f := range filters {
+		col, err := resolve(f.Column)
+		if err != nil {
+			return "", nil, fmt.Errorf("filter %d: %w", i, err)
+		}
+		op := strings.ToUpper(strings.Join(strings.Fields(f.Op), " "))
+		if op == "" {
+			op = "="
+		}
+
+		switch op {
+		case "=", "!=", "<>", "<", "<=", ">", ">=", "LIKE", "NOT LIKE":
+			if f.Value == nil {
+				return "", nil, fmt.Errorf("filter %d: %s requires a value (use IS NULL to match NULL)", i, op)
+			}
+			conds = append(conds, fmt.Sprintf("%s %s ?", col, op))
+			params = append(params, sqlValue(f.Value))
+		case "IS NULL", "IS NOT NULL":
+			conds = append(conds, fmt.Sprintf("%s %s", col, op))
+		case "IN", "NOT IN":
+			values, ok := f.Value.([]any)
+			if !ok || len(values) == 0 {
+				return "", nil, fmt.Errorf("filter %d: %s requires a non-empty array value", i, op)
+			}
+			placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+			conds = append(conds, fmt.Sprintf("%s %s (%s)", col, op, placeholders))
+			for _, v := range values {
+				params = append(params, sqlValue(v))
+			}
+		case "BETWEEN":
+			values, ok := f.Value.([]any)
+			if !ok || len(values) != 2 {
+				return "", nil, fmt.Errorf("filter %d: BETWEEN requires a two-element array value", i)
+			}
+			conds = append(conds, fmt.Sprintf("%s BETWEEN ? AND ?", col))
+			params = append(params, sqlValue(values[0]), sqlValue(values[1]))
+		default:
+			return "", nil, fmt.Errorf("filter %d: unsupported operator %q", i, f.Op)
+		}
+	}
+	return strings.Join(conds, " AND "), params, nil
+}
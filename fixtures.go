@@ -0,0 +1,476 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultFixtureMaxRows = 10000
+	// fixtureLookupBatch is the number of key tuples per IN (...) lookup.
+	fixtureLookupBatch = 500
+	// fixtureInsertBatch is the number of rows per INSERT in SQL fixtures.
+	fixtureInsertBatch = 100
+)
+
+type CaptureFixtureParams struct {
+	Database     string   `json:"database"`
+	Table        string   `json:"table"`
+	Filters      []Filter `json:"filters"`
+	Path         string   `json:"path"`
+	Format       string   `json:"format,omitempty"`
+	MaxRows      int      `json:"max_rows,omitempty"`
+	SkipChildren bool     `json:"skip_children,omitempty"`
+	Overwrite    bool     `json:"overwrite,omitempty"`
+}
+
+// fixtureTable is the rows captured from one table.
+type fixtureTable struct {
+	Table   string           `json:"table"`
+	Columns []string         `json:"columns"`
+	Rows    []map[string]any `json:"rows"`
+}
+
+// fixture collects rows while following foreign keys, keeping each row
+// once.
+type fixture struct {
+	database string
+	tables   map[string]*schemaTable
+	captured map[string]*fixtureTable
+	seen     map[string]map[string]bool
+	total    int
+	maxRows  int
+}
+
+// fixtureWork is a set of newly captured rows whose references still have
+// to be followed. Children are only followed downwards from the root, so
+// a shared parent (a product, a country) does not pull in every other row
+// that references it.
+type fixtureWork struct {
+	table string
+	rows  []map[string]any
+	down  bool
+}
+
+func CaptureFixture(ctx context.Context, req *mcp.CallToolRequest, args CaptureFixtureParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if args.Database == "" || args.Table == "" || args.Path == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "database, table and path are required"},
+			},
+		}, nil, nil
+	}
+	if len(args.Filters) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "At least one filter is required to select the root rows"},
+			},
+		}, nil, nil
+	}
+	format := strings.ToLower(args.Format)
+	if format == "" {
+		format = "sql"
+	}
+	if format != "sql" && format != "json" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported fixture format %q (use sql or json)", args.Format)},
+			},
+		}, nil, nil
+	}
+	maxRows := args.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultFixtureMaxRows
+	}
+	path, err := filepath.Abs(args.Path)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid path: %v", err)},
+			},
+		}, nil, nil
+	}
+	if refused := refuseExistingFile(path, args.Overwrite); refused != nil {
+		return refused, nil, nil
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	where, params, err := buildWhere(args.Filters, tableCols)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	schema, err := loadSchema(ctx, db, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read foreign keys: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	f := &fixture{
+		database: args.Database,
+		tables:   make(map[string]*schemaTable, len(schema.Tables)),
+		captured: make(map[string]*fixtureTable),
+		seen:     make(map[string]map[string]bool),
+		maxRows:  maxRows,
+	}
+	for i := range schema.Tables {
+		f.tables[schema.Tables[i].Name] = &schema.Tables[i]
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE %s", qualifiedTable(args.Database, args.Table), where), params...)
+	var roots []map[string]any
+	if err == nil {
+		var columns []string
+		columns, roots, err = scanRowMaps(rows)
+		rows.Close()
+		if err == nil {
+			roots, err = f.add(args.Table, columns, roots)
+		}
+	}
+	if err == nil && len(roots) == 0 {
+		err = fmt.Errorf("no rows match the filters")
+	}
+	if err == nil {
+		err = f.follow(ctx, fixtureWork{table: args.Table, rows: roots, down: !args.SkipChildren})
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Fixture capture failed: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	tables := f.insertOrder()
+	if err := writeFixture(path, format, args.Database, args.Table, tables, args.Overwrite); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to write fixture: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	counts := make(map[string]int, len(tables))
+	resultText := fmt.Sprintf("Captured %d rows from %d tables into %s (%s)\n", f.total, len(tables), path, format)
+	for _, t := range tables {
+		counts[t.Table] = len(t.Rows)
+		resultText += fmt.Sprintf("  %s: %d\n", t.Table, len(t.Rows))
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"path":   path,
+		"format": format,
+		"rows":   f.total,
+		"tables": counts,
+	}, nil
+}
+
+// follow captures the parents, and for downward work the children, of the
+// queued rows until no new rows are found.
+func (f *fixture) follow(ctx context.Context, start fixtureWork) error {
+	queue := []fixtureWork{start}
+	for len(queue) > 0 {
+		w := queue[0]
+		queue = queue[1:]
+		table := f.tables[w.table]
+		if table == nil {
+			continue
+		}
+
+		for _, fk := range table.ForeignKeys {
+			if fk.RefDatabase != f.database {
+				continue
+			}
+			found, err := f.lookup(ctx, fk.RefTable, fk.RefColumns, keyTuples(w.rows, fk.Columns))
+			if err != nil {
+				return err
+			}
+			if len(found) > 0 {
+				queue = append(queue, fixtureWork{table: fk.RefTable, rows: found})
+			}
+		}
+
+		if !w.down {
+			continue
+		}
+		for _, name := range sortedKeys(f.tables) {
+			for _, fk := range f.tables[name].ForeignKeys {
+				if fk.RefDatabase != f.database || fk.RefTable != w.table {
+					continue
+				}
+				found, err := f.lookup(ctx, name, fk.Columns, keyTuples(w.rows, fk.RefColumns))
+				if err != nil {
+					return err
+				}
+				if len(found) > 0 {
+					queue = append(queue, fixtureWork{table: name, rows: found, down: true})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// lookup fetches the rows of table whose columns match one of tuples and
+// returns those not captured before.
+func (f *fixture) lookup(ctx context.Context, table string, columns []string, tuples [][]any) ([]map[string]any, error) {
+	db := currentDB()
+	var found []map[string]any
+	for start := 0; start < len(tuples); start += fixtureLookupBatch {
+		batch := tuples[start:min(start+fixtureLookupBatch, len(tuples))]
+		placeholder := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+		var params []any
+		for _, tuple := range batch {
+			params = append(params, tuple...)
+		}
+		query := fmt.Sprintf("SELECT * FROM %s WHERE (%s) IN (%s)", qualifiedTable(f.database, table),
+			quoteIdentifierList(columns), strings.TrimSuffix(strings.Repeat(placeholder+", ", len(batch)), ", "))
+		rows, err := db.QueryContext(ctx, query, params...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", table, err)
+		}
+		resultColumns, results, err := scanRowMaps(rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		added, err := f.add(table, resultColumns, results)
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, added...)
+	}
+	return found, nil
+}
+
+// add records rows of table and returns the ones not seen before. Rows are
+// identified by primary key, or by all their values if there is none.
+func (f *fixture) add(table string, columns []string, rows []map[string]any) ([]map[string]any, error) {
+	var key []string
+	if t := f.tables[table]; t != nil {
+		key = findIndex(*t, "PRIMARY").Columns
+	}
+	if len(key) == 0 {
+		key = columns
+	}
+	if f.seen[table] == nil {
+		f.seen[table] = make(map[string]bool)
+		f.captured[table] = &fixtureTable{Table: table, Columns: columns}
+	}
+
+	var added []map[string]any
+	for _, row := range rows {
+		tuple := make([]any, len(key))
+		for i, col := range key {
+			tuple[i] = row[col]
+		}
+		id := tupleIdentity(tuple)
+		if f.seen[table][id] {
+			continue
+		}
+		f.seen[table][id] = true
+		f.captured[table].Rows = append(f.captured[table].Rows, row)
+		added = append(added, row)
+		f.total++
+	}
+	if f.total > f.maxRows {
+		return nil, fmt.Errorf("more than %d rows reached; narrow the filters, set skip_children or raise max_rows", f.maxRows)
+	}
+	return added, nil
+}
+
+// insertOrder returns the captured tables with referenced tables first.
+// Cycles fall back to name order; fixtures disable foreign key checks while
+// loading anyway.
+func (f *fixture) insertOrder() []*fixtureTable {
+	names := sortedKeys(f.captured)
+	var edges []foreignKeyEdge
+	for _, name := range names {
+		for _, fk := range f.tables[name].ForeignKeys {
+			edges = append(edges, foreignKeyEdge{Constraint: fk.Name, ChildDatabase: f.database, Child: name, ParentDatabase: fk.RefDatabase, Parent: fk.RefTable})
+		}
+	}
+	if order, err := truncateOrder(f.database, names, edges); err == nil {
+		slices.Reverse(order)
+		names = order
+	}
+	tables := make([]*fixtureTable, len(names))
+	for i, name := range names {
+		t := f.captured[name]
+		// Generated columns cannot be inserted and are recomputed on load.
+		t.Columns = slices.DeleteFunc(t.Columns, func(col string) bool {
+			c, _ := findColumn(*f.tables[name], col)
+			if c.Generated == "" {
+				return false
+			}
+			for _, row := range t.Rows {
+				delete(row, col)
+			}
+			return true
+		})
+		tables[i] = t
+	}
+	return tables
+}
+
+// keyTuples returns the distinct values of columns in rows, skipping rows
+// where any of them is NULL.
+func keyTuples(rows []map[string]any, columns []string) [][]any {
+	seen := make(map[string]bool)
+	var tuples [][]any
+	for _, row := range rows {
+		tuple := make([]any, len(columns))
+		complete := true
+		for i, col := range columns {
+			tuple[i] = row[col]
+			if tuple[i] == nil {
+				complete = false
+			}
+		}
+		if !complete {
+			continue
+		}
+		id := tupleIdentity(tuple)
+		if !seen[id] {
+			seen[id] = true
+			tuples = append(tuples, tuple)
+		}
+	}
+	return tuples
+}
+
+// tupleIdentity renders values as a map key. Values are compared by their
+// text so a key read as a string and as an integer is the same row.
+func tupleIdentity(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		if v == nil {
+			parts[i] = "\x00NULL"
+			continue
+		}
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// writeFixture writes the tables as a SQL script of INSERTs with unqualified
+// table names, loadable into any database with the same schema, or as JSON
+// whose rows can be passed to insert_rows. The rows are copies of real data,
+// so a new file is readable by its owner only, and an existing one is only
+// replaced with overwrite.
+func writeFixture(path, format, database, root string, tables []*fixtureTable, overwrite bool) error {
+	var b strings.Builder
+	if format == "json" {
+		data, err := json.MarshalIndent(map[string]any{
+			"database":   database,
+			"root":       root,
+			"capturedAt": time.Now().UTC(),
+			"tables":     tables,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+		b.WriteString("\n")
+		return createFixtureFile(path, b.String(), overwrite)
+	}
+
+	fmt.Fprintf(&b, "-- Fixture captured from %s starting at %s, %s\n", quoteIdentifier(database), quoteIdentifier(root), time.Now().UTC().Format(time.RFC3339))
+	// Literals are escaped for NO_BACKSLASH_ESCAPES, whatever the sql_mode
+	// of the server they were captured from or are loaded into.
+	b.WriteString("SET FOREIGN_KEY_CHECKS = 0;\n")
+	b.WriteString("SET @fixture_sql_mode = @@SESSION.sql_mode;\n")
+	b.WriteString("SET SESSION sql_mode = CONCAT_WS(',', NULLIF(@@SESSION.sql_mode, ''), 'NO_BACKSLASH_ESCAPES');\n")
+	for _, t := range tables {
+		fmt.Fprintf(&b, "\n-- %s: %d rows\n", t.Table, len(t.Rows))
+		for start := 0; start < len(t.Rows); start += fixtureInsertBatch {
+			fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES\n", quoteIdentifier(t.Table), quoteIdentifierList(t.Columns))
+			batch := t.Rows[start:min(start+fixtureInsertBatch, len(t.Rows))]
+			for i, row := range batch {
+				values := make([]string, len(t.Columns))
+				for c, col := range t.Columns {
+					values[c] = sqlLiteralFor(row[col], true)
+				}
+				sep := ",\n"
+				if i == len(batch)-1 {
+					sep = ";\n"
+				}
+				b.WriteString("  (" + strings.Join(values, ", ") + ")" + sep)
+			}
+		}
+	}
+	b.WriteString("\nSET SESSION sql_mode = @fixture_sql_mode;\n")
+	b.WriteString("SET FOREIGN_KEY_CHECKS = 1;\n")
+	return createFixtureFile(path, b.String(), overwrite)
+}
+
+// createFixtureFile writes content to path, refusing an existing file
+// unless overwrite is set. A file that cannot be written completely is
+// removed.
+func createFixtureFile(path, content string, overwrite bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if overwrite {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		return err
+	}
+	// A replaced file keeps its mode otherwise.
+	err = file.Chmod(0o600)
+	if err == nil {
+		_, err = file.WriteString(content)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+	}
+	return err
+}
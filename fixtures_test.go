@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteFixtureFile(t *testing.T) {
+	tables := []*fixtureTable{{Table: "t", Columns: []string{"id"}, Rows: []map[string]any{{"id": int64(1)}}}}
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "existing.sql")
+	if err := os.WriteFile(existing, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		overwrite bool
+		wantErr   bool
+	}{
+		{"new file", filepath.Join(dir, "new.sql"), false, false},
+		{"existing file", existing, false, true},
+		{"existing file with overwrite", existing, true, false},
+	}
+	for _, tt := range tests {
+		err := writeFixture(tt.path, "sql", "db", "t", tables, tt.overwrite)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: writeFixture() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		data, readErr := os.ReadFile(tt.path)
+		if readErr != nil {
+			t.Fatal(readErr)
+		}
+		if tt.wantErr {
+			if string(data) != "keep" {
+				t.Errorf("%s: file changed to %q", tt.name, data)
+			}
+			continue
+		}
+		if !strings.Contains(string(data), "INSERT INTO `t` (`id`) VALUES\n  (1);") {
+			t.Errorf("%s: fixture is %q", tt.name, data)
+		}
+		info, err := os.Stat(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("%s: file mode %v, want 0600", tt.name, info.Mode().Perm())
+		}
+	}
+}
+
+func TestWriteFixtureLiterals(t *testing.T) {
+	defer noBackslashEscapes.Store(false)
+	tables := []*fixtureTable{{Table: "t", Columns: []string{"s"}, Rows: []map[string]any{{"s": `C:\temp\it's`}}}}
+	// The literal is the same whatever the capturing connection's mode.
+	for _, mode := range []bool{false, true} {
+		noBackslashEscapes.Store(mode)
+		path := filepath.Join(t.TempDir(), "fixture.sql")
+		if err := writeFixture(path, "sql", "db", "t", tables, false); err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		script := string(data)
+		for _, want := range []string{
+			"SET SESSION sql_mode = CONCAT_WS(',', NULLIF(@@SESSION.sql_mode, ''), 'NO_BACKSLASH_ESCAPES');\n",
+			`  ('C:\temp\it''s');`,
+			"SET SESSION sql_mode = @fixture_sql_mode;\n",
+		} {
+			if !strings.Contains(script, want) {
+				t.Errorf("NO_BACKSLASH_ESCAPES %v: fixture %q does not contain %q", mode, script, want)
+			}
+		}
+		if strings.Index(script, "NO_BACKSLASH_ESCAPES") > strings.Index(script, "INSERT") {
+			t.Errorf("NO_BACKSLASH_ESCAPES %v: sql_mode is set after the first INSERT", mode)
+		}
+	}
+}
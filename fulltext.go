@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// fulltextModes maps the search modes fulltext_search accepts to their
+// AGAINST modifiers.
+var fulltextModes = map[string]string{
+	"natural":         "IN NATURAL LANGUAGE MODE",
+	"boolean":         "IN BOOLEAN MODE",
+	"query_expansion": "WITH QUERY EXPANSION",
+}
+
+// fulltextIndexes returns the FULLTEXT indexes of database.table, mapping
+// index name to its columns in index order.
+func fulltextIndexes(ctx context.Context, database, table string) (map[string][]string, error) {
+	db := currentDB()
+	query := `
+		SELECT INDEX_NAME, COLUMN_NAME
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND INDEX_TYPE = 'FULLTEXT'
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX
+	`
+	rows, err := db.QueryContext(ctx, query, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := make(map[string][]string)
+	for rows.Next() {
+		var index, column string
+		if err := rows.Scan(&index, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan index info: %w", err)
+		}
+		indexes[index] = append(indexes[index], column)
+	}
+	return indexes, rows.Err()
+}
+
+type FulltextSearchParams struct {
+	Database string   `json:"database"`
+	Table    string   `json:"table"`
+	Query    string   `json:"query"`
+	Mode     string   `json:"mode,omitempty"`
+	Index    string   `json:"index,omitempty"`
+	Columns  []string `json:"columns,omitempty"`
+	Filters  []Filter `json:"filters,omitempty"`
+	MinScore *float64 `json:"min_score,omitempty"`
+	Limit    int      `json:"limit,omitempty"`
+}
+
+func FulltextSearch(ctx context.Context, req *mcp.CallToolRequest, args FulltextSearchParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	if strings.TrimSpace(args.Query) == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "query is required"},
+			},
+		}, nil, nil
+	}
+	mode := strings.ToLower(args.Mode)
+	if mode == "" {
+		mode = "natural"
+	}
+	modifier, ok := fulltextModes[mode]
+	if !ok {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown mode %q (use natural, boolean or query_expansion)", args.Mode)},
+			},
+		}, nil, nil
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	indexes, err := fulltextIndexes(ctx, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read indexes: %v", err)},
+			},
+		}, nil, nil
+	}
+	if len(indexes) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("%s.%s has no FULLTEXT index. Add one with add_index (kind: fulltext) on the text columns to search; a LIKE '%%...%%' scan reads the whole table.", args.Database, args.Table)},
+			},
+		}, nil, nil
+	}
+	index := args.Index
+	if index == "" && len(indexes) == 1 {
+		for name := range indexes {
+			index = name
+		}
+	}
+	indexed, ok := indexes[index]
+	if !ok {
+		var available []string
+		for _, name := range sortedKeys(indexes) {
+			available = append(available, fmt.Sprintf("%s (%s)", name, strings.Join(indexes[name], ", ")))
+		}
+		text := fmt.Sprintf("%s.%s has several FULLTEXT indexes; choose one with index: %s", args.Database, args.Table, strings.Join(available, "; "))
+		if args.Index != "" {
+			text = fmt.Sprintf("%s.%s has no FULLTEXT index %q; its FULLTEXT indexes are %s", args.Database, args.Table, args.Index, strings.Join(available, "; "))
+		}
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, nil, nil
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	selected := args.Columns
+	if len(selected) == 0 {
+		for _, c := range tableCols {
+			selected = append(selected, c.ColumnName)
+		}
+	}
+	for _, name := range selected {
+		if !slices.ContainsFunc(tableCols, func(c ColumnInfo) bool { return c.ColumnName == name }) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Column %q does not exist in %s.%s", name, args.Database, args.Table)},
+				},
+			}, nil, nil
+		}
+	}
+
+	// MATCH must name exactly the columns of one FULLTEXT index.
+	match := fmt.Sprintf("MATCH(%s) AGAINST(? %s)", quoteIdentifierList(indexed), modifier)
+	query := fmt.Sprintf("SELECT %s, %s AS score FROM %s WHERE %s",
+		quoteIdentifierList(selected), match, qualifiedTable(args.Database, args.Table), match)
+	params := []any{args.Query, args.Query}
+	if len(args.Filters) > 0 {
+		where, filterParams, err := buildWhere(args.Filters, tableCols)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid filters: %v", err)},
+				},
+			}, nil, nil
+		}
+		query += " AND " + where
+		params = append(params, filterParams...)
+	}
+	if args.MinScore != nil {
+		query += " HAVING score >= ?"
+		params = append(params, *args.MinScore)
+	}
+	query += fmt.Sprintf(" ORDER BY score DESC LIMIT %d", limit)
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Full-text search failed: %v", err)},
+			},
+		}, nil, nil
+	}
+	defer rows.Close()
+	columns, results, err := scanRowMaps(rows)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Full-text search failed: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("%d rows match in index %s (%s), most relevant first:\n\n", len(results), index, strings.Join(indexed, ", "))
+	if len(results) == 0 {
+		resultText = fmt.Sprintf("No rows match in index %s (%s). Words shorter than the minimum token size (innodb_ft_min_token_size, 3 by default) and stopwords are not indexed", index, strings.Join(indexed, ", "))
+		if mode == "natural" {
+			resultText += "; for prefixes or required words, use boolean mode with operators such as data* or +mysql -oracle"
+		}
+		resultText += ".\n"
+	}
+	resultText += formatRowTable(columns, results)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"rows":     results,
+		"rowCount": len(results),
+		"columns":  columns,
+		"index":    index,
+		"query":    query,
+	}, nil
+}
@@ -0,0 +1,509 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	mathrand "math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	maxGeneratedRows = 100000
+	// parentKeyLimit caps the existing parent keys generated rows refer to.
+	parentKeyLimit = 10000
+)
+
+type GenerateDataParams struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Rows     int    `json:"rows"`
+	Seed     int64  `json:"seed,omitempty"`
+	// Values fixes columns to the given values in every row.
+	Values map[string]any `json:"values,omitempty"`
+	// FillParents generates rows in referenced tables that are empty.
+	FillParents bool `json:"fill_parents,omitempty"`
+	BatchSize   int  `json:"batch_size,omitempty"`
+}
+
+var (
+	fakeCities    = []string{"Springfield", "Riverside", "Fairview", "Madison", "Georgetown", "Salem", "Franklin", "Clinton", "Oslo", "Lyon", "Osaka", "Porto", "Accra", "Pune", "Leeds", "Graz"}
+	fakeCountries = []string{"United States", "Canada", "United Kingdom", "Germany", "France", "Japan", "Brazil", "India", "Australia", "Norway", "Ghana", "Portugal"}
+	fakeStreets   = []string{"Main St", "Oak Ave", "Maple Dr", "Cedar Ln", "Park Rd", "Elm St", "Lake View", "Hill Rd", "Church St", "Mill Ln"}
+	fakeWords     = strings.Fields("lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud exercitation ullamco laboris nisi aliquip ex ea commodo consequat")
+)
+
+func GenerateData(ctx context.Context, req *mcp.CallToolRequest, args GenerateDataParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("generate_data"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	if args.Rows <= 0 || args.Rows > maxGeneratedRows {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("rows must be between 1 and %d", maxGeneratedRows)},
+			},
+		}, nil, nil
+	}
+
+	schema, err := loadSchema(ctx, db, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read schema: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	g := &dataGenerator{
+		schema:      schema,
+		seed:        args.Seed,
+		fillParents: args.FillParents,
+		batchSize:   args.BatchSize,
+		visiting:    make(map[string]bool),
+	}
+	sample, err := g.generate(ctx, args.Table, args.Rows, args.Values)
+	var resultText string
+	for _, r := range g.results {
+		resultText += fmt.Sprintf("Inserted %d of %d generated rows into %s.%s\n", r.Succeeded, r.Attempted, args.Database, r.Table)
+		resultText += formatRowErrors(r.Errors)
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText + fmt.Sprintf("Failed to generate data: %v", err)},
+			},
+		}, map[string]any{"tables": g.results}, nil
+	}
+	if len(sample) > 0 {
+		var columns []string
+		t := findTable(schema, args.Table)
+		for _, c := range t.Columns {
+			if _, ok := sample[0][c.Name]; ok {
+				columns = append(columns, c.Name)
+			}
+		}
+		resultText += fmt.Sprintf("\nFirst generated rows (seed %d):\n%s", args.Seed, formatRowTable(columns, sample[:min(5, len(sample))]))
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"seed":   args.Seed,
+		"tables": g.results,
+	}, nil
+}
+
+// generatedTable is the outcome of generating rows for one table.
+type generatedTable struct {
+	Table string `json:"table"`
+	*rowWriteResult
+}
+
+// dataGenerator inserts fake rows, generating rows for empty parent tables
+// first when fillParents is set.
+type dataGenerator struct {
+	schema      *databaseSchema
+	seed        int64
+	fillParents bool
+	batchSize   int
+	visiting    map[string]bool
+	results     []generatedTable
+}
+
+// generate inserts n rows into table and returns the rows written.
+func (g *dataGenerator) generate(ctx context.Context, table string, n int, fixed map[string]any) ([]map[string]any, error) {
+	db := currentDB()
+	t := findTable(g.schema, table)
+	if t == nil {
+		return nil, fmt.Errorf("table %s.%s does not exist", g.schema.Database, table)
+	}
+	g.visiting[table] = true
+	defer delete(g.visiting, table)
+
+	source := qualifiedTable(g.schema.Database, table)
+	var existing int64
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+source).Scan(&existing); err != nil {
+		return nil, err
+	}
+	// The same seed on the same starting state gives the same rows; mixing
+	// in the row count keeps a second run from repeating the first.
+	h := fnv.New64a()
+	h.Write([]byte(table))
+	rng := mathrand.New(mathrand.NewPCG(uint64(g.seed), h.Sum64()+uint64(existing)))
+
+	// Referencing columns take existing parent keys.
+	references := make(map[string]bool)
+	referenceSets := make(map[string][][]any)
+	for _, fk := range t.ForeignKeys {
+		if _, ok := fixed[fk.Columns[0]]; ok {
+			continue
+		}
+		keys, err := parentKeys(ctx, fk)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 && g.fillParents && fk.RefDatabase == g.schema.Database && !g.visiting[fk.RefTable] {
+			if _, err := g.generate(ctx, fk.RefTable, max(1, n/10), nil); err != nil {
+				return nil, fmt.Errorf("generating parent rows in %s: %v", fk.RefTable, err)
+			}
+			if keys, err = parentKeys(ctx, fk); err != nil {
+				return nil, err
+			}
+		}
+		if len(keys) == 0 {
+			nullable := true
+			for _, col := range fk.Columns {
+				c, _ := findColumn(*t, col)
+				nullable = nullable && c.Nullable
+			}
+			if !nullable {
+				return nil, fmt.Errorf("%s references %s.%s, which has no rows; insert some or set fill_parents", table, fk.RefDatabase, fk.RefTable)
+			}
+		}
+		referenceSets[fk.Name] = keys
+		for _, col := range fk.Columns {
+			references[col] = true
+		}
+	}
+
+	unique := make(map[string]bool)
+	for _, idx := range t.Indexes {
+		if idx.Unique && len(idx.Columns) == 1 {
+			unique[idx.Columns[0]] = true
+		}
+	}
+	// Unique integer columns without auto_increment continue after the
+	// current maximum.
+	next := make(map[string]int64)
+	for _, c := range t.Columns {
+		base, _, _ := parseColumnType(c.Type)
+		if unique[c.Name] && integerRanks[base] > 0 && !strings.Contains(c.Extra, "auto_increment") {
+			var maxValue int64
+			if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COALESCE(MAX(%s), 0) FROM %s", quoteIdentifier(c.Name), source)).Scan(&maxValue); err != nil {
+				return nil, err
+			}
+			next[c.Name] = maxValue + 1
+		}
+	}
+
+	rows := make([]map[string]any, n)
+	for i := range rows {
+		row := make(map[string]any, len(t.Columns))
+		for _, fk := range t.ForeignKeys {
+			keys, ok := referenceSets[fk.Name]
+			if !ok {
+				continue
+			}
+			var tuple []any
+			if len(keys) > 0 {
+				tuple = keys[rng.IntN(len(keys))]
+			}
+			for j, col := range fk.Columns {
+				if tuple == nil {
+					row[col] = nil
+				} else {
+					row[col] = tuple[j]
+				}
+			}
+		}
+		for _, c := range t.Columns {
+			if v, ok := fixed[c.Name]; ok {
+				row[c.Name] = v
+				continue
+			}
+			if references[c.Name] || c.Generated != "" || strings.Contains(c.Extra, "auto_increment") {
+				continue
+			}
+			if c.Nullable && !unique[c.Name] && rng.IntN(10) == 0 {
+				row[c.Name] = nil
+				continue
+			}
+			if start, ok := next[c.Name]; ok {
+				row[c.Name] = start + int64(i)
+				continue
+			}
+			v, err := fakeValue(rng, c, existing+int64(i)+1, unique[c.Name])
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %v", c.Name, err)
+			}
+			row[c.Name] = v
+		}
+		rows[i] = row
+	}
+
+	result, err := writeRows(ctx, db, g.schema.Database, table, rows, rowWriteOptions{
+		Tool:      "generate_data",
+		BatchSize: g.batchSize,
+		Atomic:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	g.results = append(g.results, generatedTable{Table: table, rowWriteResult: result})
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("%d row(s) of %s were rejected and nothing was inserted", len(result.Errors), table)
+	}
+	return rows, nil
+}
+
+// parentKeys returns existing values of the columns fk references, in a
+// stable order so the same seed picks the same parents.
+func parentKeys(ctx context.Context, fk schemaForeignKey) ([][]any, error) {
+	db := currentDB()
+	cols := quoteIdentifierList(fk.RefColumns)
+	notNull := make([]string, len(fk.RefColumns))
+	for i, col := range fk.RefColumns {
+		notNull[i] = quoteIdentifier(col) + " IS NOT NULL"
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s ORDER BY %s LIMIT %d",
+		cols, qualifiedTable(fk.RefDatabase, fk.RefTable), strings.Join(notNull, " AND "), cols, parentKeyLimit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	_, found, err := scanRowMaps(rows)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([][]any, len(found))
+	for i, row := range found {
+		keys[i] = make([]any, len(fk.RefColumns))
+		for j, col := range fk.RefColumns {
+			keys[i][j] = row[col]
+		}
+	}
+	return keys, nil
+}
+
+func findTable(schema *databaseSchema, name string) *schemaTable {
+	for i := range schema.Tables {
+		if schema.Tables[i].Name == name {
+			return &schema.Tables[i]
+		}
+	}
+	return nil
+}
+
+// parseColumnType splits a COLUMN_TYPE such as "decimal(10,2) unsigned"
+// into its base type, the text between the parentheses and whether it is
+// unsigned.
+func parseColumnType(columnType string) (string, string, bool) {
+	t := strings.ToLower(columnType)
+	unsigned := strings.Contains(t, " unsigned")
+	base, rest, found := strings.Cut(t, "(")
+	var params string
+	if found {
+		params = rest[:strings.LastIndex(rest, ")")]
+	}
+	return strings.Fields(base)[0], params, unsigned
+}
+
+// columnTypeValues returns the members of an ENUM or SET column type such
+// as enum('new','paid'), in their original case.
+func columnTypeValues(columnType string) []string {
+	_, params, ok := strings.Cut(columnType, "(")
+	if !ok {
+		return nil
+	}
+	params = params[:strings.LastIndex(params, ")")]
+	var values []string
+	for _, v := range strings.Split(strings.Trim(params, "'"), "','") {
+		values = append(values, strings.ReplaceAll(v, "''", "'"))
+	}
+	return values
+}
+
+// fakeValue makes up a value for column c, guided by the column's name and
+// type. seq numbers the row within the table and keeps unique values
+// distinct.
+func fakeValue(rng *mathrand.Rand, c schemaColumn, seq int64, unique bool) (any, error) {
+	base, params, unsigned := parseColumnType(c.Type)
+	name := strings.ToLower(c.Name)
+	pick := func(words []string) string { return words[rng.IntN(len(words))] }
+
+	switch base {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		if base == "tinyint" && params == "1" {
+			return int64(rng.IntN(2)), nil
+		}
+		limit := int64(10000)
+		switch {
+		case strings.Contains(name, "count") || strings.Contains(name, "qty") || strings.Contains(name, "quantity"):
+			limit = 100
+		case base == "tinyint":
+			limit = 127
+			if unsigned {
+				limit = 255
+			}
+		case strings.HasPrefix(name, "is_") || strings.HasPrefix(name, "has_"):
+			limit = 2
+		}
+		return rng.Int64N(limit), nil
+	case "decimal", "numeric":
+		precision, scale := 10, 0
+		if p, s, ok := strings.Cut(params, ","); ok {
+			precision, _ = strconv.Atoi(p)
+			scale, _ = strconv.Atoi(s)
+		} else if params != "" {
+			precision, _ = strconv.Atoi(params)
+		}
+		limit := math.Min(math.Pow10(precision-scale)-1, 1000)
+		return strconv.FormatFloat(rng.Float64()*limit, 'f', scale, 64), nil
+	case "float", "double", "real":
+		return math.Round(rng.Float64()*100000) / 100, nil
+	case "bit":
+		return int64(rng.IntN(2)), nil
+	case "year":
+		return int64(2000 + rng.IntN(time.Now().Year()-1999)), nil
+	case "date", "datetime", "timestamp":
+		// Dates fall in the year before today.
+		t := time.Now().UTC().Truncate(24*time.Hour).AddDate(-1, 0, 0).Add(time.Duration(rng.Int64N(int64(365 * 24 * time.Hour))))
+		if base == "date" {
+			return t.Format("2006-01-02"), nil
+		}
+		return t.Format("2006-01-02 15:04:05"), nil
+	case "time":
+		return fmt.Sprintf("%02d:%02d:%02d", rng.IntN(24), rng.IntN(60), rng.IntN(60)), nil
+	case "enum", "set":
+		var values []string
+		for _, v := range strings.Split(params, ",") {
+			values = append(values, strings.ReplaceAll(strings.Trim(v, "'"), "''", "'"))
+		}
+		return pick(values), nil
+	case "json":
+		return fmt.Sprintf(`{"n": %d}`, seq), nil
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob":
+		size := 16
+		if n, err := strconv.Atoi(params); err == nil {
+			size = min(size, n)
+		}
+		b := make([]byte, size)
+		for i := range b {
+			b[i] = byte(rng.IntN(256))
+		}
+		return b, nil
+	case "vector":
+		// Random unit vectors, in the binary form VECTOR columns store.
+		dimension, err := strconv.Atoi(params)
+		if err != nil || dimension <= 0 {
+			return nil, fmt.Errorf("cannot generate values of type %s", c.Type)
+		}
+		v := make([]float64, dimension)
+		var norm float64
+		for i := range v {
+			v[i] = rng.NormFloat64()
+			norm += v[i] * v[i]
+		}
+		norm = math.Sqrt(norm)
+		b := make([]byte, 0, 4*dimension)
+		for _, f := range v {
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(f/norm)))
+		}
+		return b, nil
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext":
+	default:
+		if c.Nullable {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot generate values of type %s", c.Type)
+	}
+
+	length := map[string]int{"tinytext": 255, "text": 1000, "mediumtext": 1000, "longtext": 1000}[base]
+	if n, err := strconv.Atoi(params); err == nil {
+		length = n
+	}
+	first, last := pick(fakeFirstNames), pick(fakeLastNames)
+	var s string
+	switch {
+	case strings.Contains(name, "email"):
+		s = fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), seq)
+		unique = false
+	case strings.Contains(name, "uuid") || strings.Contains(name, "guid") || (base == "char" && length == 36):
+		s = fmt.Sprintf("%08x-%04x-4%03x-%04x-%012x", rng.Uint32(), rng.IntN(1<<16), rng.IntN(1<<12), 0x8000|rng.IntN(1<<14), rng.Int64N(1<<48))
+		unique = false
+	case strings.Contains(name, "first_name") || strings.Contains(name, "firstname") || name == "given_name":
+		s = first
+	case strings.Contains(name, "last_name") || strings.Contains(name, "lastname") || name == "surname" || name == "family_name":
+		s = last
+	case strings.Contains(name, "username") || name == "login" || name == "handle":
+		s = fmt.Sprintf("%s%d", strings.ToLower(first), seq)
+		unique = false
+	case strings.Contains(name, "phone") || strings.Contains(name, "mobile"):
+		s = fmt.Sprintf("555-%03d-%04d", rng.IntN(1000), rng.IntN(10000))
+	case strings.Contains(name, "city"):
+		s = pick(fakeCities)
+	case strings.Contains(name, "country"):
+		s = pick(fakeCountries)
+	case strings.Contains(name, "zip") || strings.Contains(name, "postal"):
+		s = fmt.Sprintf("%05d", rng.IntN(100000))
+	case strings.Contains(name, "address") || strings.Contains(name, "street"):
+		s = fmt.Sprintf("%d %s", 1+rng.IntN(9999), pick(fakeStreets))
+	case strings.Contains(name, "url") || strings.Contains(name, "website"):
+		s = fmt.Sprintf("https://example.com/%s/%d", pick(fakeWords), seq)
+		unique = false
+	case strings.Contains(name, "name"):
+		s = first + " " + last
+	case strings.Contains(name, "status") || strings.Contains(name, "state"):
+		s = pick([]string{"active", "pending", "inactive"})
+	default:
+		words := make([]string, 2+rng.IntN(max(1, min(length/8, 12))))
+		for i := range words {
+			words[i] = pick(fakeWords)
+		}
+		s = strings.Join(words, " ")
+	}
+	if strings.Contains(name, "email") && length > 0 && len(s) > length {
+		s = fmt.Sprintf("u%d@example.com", seq)
+	}
+	if unique {
+		suffix := fmt.Sprintf("-%d", seq)
+		switch {
+		case length <= 0 || len(suffix) < length:
+			s = truncateRunes(s, length-len(suffix)) + suffix
+		case len(strconv.FormatInt(seq, 36)) <= length:
+			s = strconv.FormatInt(seq, 36)
+		default:
+			return nil, fmt.Errorf("%s is too short for distinct values", c.Type)
+		}
+	}
+	return truncateRunes(s, length), nil
+}
+
+// truncateRunes shortens s to at most n characters; n <= 0 leaves it as is.
+func truncateRunes(s string, n int) string {
+	if n <= 0 {
+		return s
+	}
+	r := []rune(s)
+	if len(r) > n {
+		return string(r[:n])
+	}
+	return s
+}
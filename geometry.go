@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WKB geometry type codes.
+const (
+	wkbPoint              = 1
+	wkbLineString         = 2
+	wkbPolygon            = 3
+	wkbMultiPoint         = 4
+	wkbMultiLineString    = 5
+	wkbMultiPolygon       = 6
+	wkbGeometryCollection = 7
+)
+
+// geometry is a decoded WKB value. Points and line strings use points,
+// polygons use rings, and multi geometries and collections use parts.
+type geometry struct {
+	kind   uint32
+	points [][2]float64
+	rings  [][][2]float64
+	parts  []geometry
+}
+
+// decodeGeometry reads a GEOMETRY value in the internal format MySQL and
+// MariaDB return to clients: a little-endian SRID followed by WKB.
+// Coordinates are kept in storage order, which for geographic SRIDs such
+// as 4326 is longitude, latitude.
+func decodeGeometry(b []byte) (uint32, geometry, error) {
+	if len(b) < 4 {
+		return 0, geometry{}, fmt.Errorf("geometry value too short")
+	}
+	srid := binary.LittleEndian.Uint32(b)
+	g, rest, err := parseWKB(b[4:])
+	if err == nil && len(rest) != 0 {
+		err = fmt.Errorf("%d trailing bytes after geometry", len(rest))
+	}
+	return srid, g, err
+}
+
+func parseWKB(b []byte) (geometry, []byte, error) {
+	if len(b) < 5 {
+		return geometry{}, nil, fmt.Errorf("truncated WKB")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if b[0] == 0 {
+		order = binary.BigEndian
+	}
+	g := geometry{kind: order.Uint32(b[1:])}
+	b = b[5:]
+
+	count := func() (int, error) {
+		if len(b) < 4 {
+			return 0, fmt.Errorf("truncated WKB")
+		}
+		n := int(order.Uint32(b))
+		b = b[4:]
+		// Every element takes at least 4 bytes, so a larger count is
+		// corrupt data rather than a reason to allocate.
+		if n > len(b)/4 {
+			return 0, fmt.Errorf("invalid WKB element count %d", n)
+		}
+		return n, nil
+	}
+	point := func() ([2]float64, error) {
+		if len(b) < 16 {
+			return [2]float64{}, fmt.Errorf("truncated WKB")
+		}
+		p := [2]float64{math.Float64frombits(order.Uint64(b)), math.Float64frombits(order.Uint64(b[8:]))}
+		b = b[16:]
+		return p, nil
+	}
+	points := func() ([][2]float64, error) {
+		n, err := count()
+		if err != nil {
+			return nil, err
+		}
+		ps := make([][2]float64, n)
+		for i := range ps {
+			if ps[i], err = point(); err != nil {
+				return nil, err
+			}
+		}
+		return ps, nil
+	}
+
+	switch g.kind {
+	case wkbPoint:
+		p, err := point()
+		if err != nil {
+			return g, nil, err
+		}
+		// An empty point is stored as NaN coordinates.
+		if !math.IsNaN(p[0]) {
+			g.points = [][2]float64{p}
+		}
+	case wkbLineString:
+		ps, err := points()
+		if err != nil {
+			return g, nil, err
+		}
+		g.points = ps
+	case wkbPolygon:
+		n, err := count()
+		if err != nil {
+			return g, nil, err
+		}
+		for range n {
+			ring, err := points()
+			if err != nil {
+				return g, nil, err
+			}
+			g.rings = append(g.rings, ring)
+		}
+	case wkbMultiPoint, wkbMultiLineString, wkbMultiPolygon, wkbGeometryCollection:
+		n, err := count()
+		if err != nil {
+			return g, nil, err
+		}
+		for range n {
+			var part geometry
+			part, b, err = parseWKB(b)
+			if err != nil {
+				return g, nil, err
+			}
+			g.parts = append(g.parts, part)
+		}
+	default:
+		return g, nil, fmt.Errorf("unsupported WKB geometry type %d", g.kind)
+	}
+	return g, b, nil
+}
+
+var wktNames = map[uint32]string{
+	wkbPoint:              "POINT",
+	wkbLineString:         "LINESTRING",
+	wkbPolygon:            "POLYGON",
+	wkbMultiPoint:         "MULTIPOINT",
+	wkbMultiLineString:    "MULTILINESTRING",
+	wkbMultiPolygon:       "MULTIPOLYGON",
+	wkbGeometryCollection: "GEOMETRYCOLLECTION",
+}
+
+var geoJSONNames = map[uint32]string{
+	wkbPoint:              "Point",
+	wkbLineString:         "LineString",
+	wkbPolygon:            "Polygon",
+	wkbMultiPoint:         "MultiPoint",
+	wkbMultiLineString:    "MultiLineString",
+	wkbMultiPolygon:       "MultiPolygon",
+	wkbGeometryCollection: "GeometryCollection",
+}
+
+func formatCoordinate(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// body is the WKT of g without its type name, e.g. "(1 2)".
+func (g geometry) body() string {
+	pointList := func(ps [][2]float64) string {
+		parts := make([]string, len(ps))
+		for i, p := range ps {
+			parts[i] = formatCoordinate(p[0]) + " " + formatCoordinate(p[1])
+		}
+		return "(" + strings.Join(parts, ",") + ")"
+	}
+	var parts []string
+	switch g.kind {
+	case wkbPoint, wkbLineString:
+		if len(g.points) == 0 {
+			return " EMPTY"
+		}
+		return pointList(g.points)
+	case wkbPolygon:
+		for _, ring := range g.rings {
+			parts = append(parts, pointList(ring))
+		}
+	case wkbGeometryCollection:
+		for _, part := range g.parts {
+			parts = append(parts, part.wkt())
+		}
+	default:
+		for _, part := range g.parts {
+			parts = append(parts, part.body())
+		}
+	}
+	if len(parts) == 0 {
+		return " EMPTY"
+	}
+	return "(" + strings.Join(parts, ",") + ")"
+}
+
+// wkt renders g as well-known text, e.g. "POINT(1 2)".
+func (g geometry) wkt() string {
+	return wktNames[g.kind] + g.body()
+}
+
+// geoJSON renders g as a GeoJSON geometry object.
+func (g geometry) geoJSON() map[string]any {
+	if g.kind == wkbGeometryCollection {
+		geometries := make([]map[string]any, len(g.parts))
+		for i, part := range g.parts {
+			geometries[i] = part.geoJSON()
+		}
+		return map[string]any{"type": "GeometryCollection", "geometries": geometries}
+	}
+	return map[string]any{"type": geoJSONNames[g.kind], "coordinates": g.coordinates()}
+}
+
+func (g geometry) coordinates() any {
+	switch g.kind {
+	case wkbPoint:
+		if len(g.points) == 0 {
+			return []float64{}
+		}
+		return g.points[0]
+	case wkbLineString:
+		return g.points
+	case wkbPolygon:
+		if g.rings == nil {
+			return [][][2]float64{}
+		}
+		return g.rings
+	}
+	coordinates := make([]any, len(g.parts))
+	for i, part := range g.parts {
+		coordinates[i] = part.coordinates()
+	}
+	return coordinates
+}
+
+// geometryText renders a GEOMETRY value for query results as WKT, prefixed
+// with its SRID when it has one, e.g. "SRID=4326;POINT(13.4 52.5)". Values
+// that do not decode are shown as hex.
+func geometryText(b []byte) string {
+	srid, g, err := decodeGeometry(b)
+	if err != nil {
+		return fmt.Sprintf("0x%X", b)
+	}
+	if srid != 0 {
+		return fmt.Sprintf("SRID=%d;%s", srid, g.wkt())
+	}
+	return g.wkt()
+}
@@ -0,0 +1,423 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// maxGraphQLTables is the most tables generate_graphql describes when none
+// are named.
+const maxGraphQLTables = 100
+
+type GenerateGraphQLParams struct {
+	Database string `json:"database"`
+	// Tables defaults to every base table of the database.
+	Tables []string `json:"tables,omitempty"`
+	// TypeNames is singular (order_items becomes OrderItem) or table
+	// (OrderItems).
+	TypeNames string `json:"type_names,omitempty"`
+	// FieldNames is camel (user_id becomes userId) or column (user_id).
+	FieldNames string `json:"field_names,omitempty"`
+}
+
+// graphQLScalars describes the custom scalars the SDL declares for the
+// columns no built-in scalar fits.
+var graphQLScalars = map[string]string{
+	"BigInt":   "An integer outside the 32-bit range of Int.",
+	"Decimal":  "An exact decimal number, as a string.",
+	"Date":     "A date as YYYY-MM-DD.",
+	"DateTime": "A date and time as YYYY-MM-DD hh:mm:ss.",
+	"Time":     "A time of day or duration as hh:mm:ss.",
+	"JSON":     "Any JSON value.",
+	"Bytes":    "Binary data, base64-encoded.",
+}
+
+// graphQLWords splits a table or column name into the words of a GraphQL
+// name.
+func graphQLWords(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return r > unicode.MaxASCII || !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// graphQLName makes name valid in GraphQL, which allows only ASCII
+// letters, digits and underscores and reserves a leading __.
+func graphQLName(name string) string {
+	if name == "" || unicode.IsDigit(rune(name[0])) || strings.HasPrefix(name, "__") {
+		name = "_" + strings.TrimLeft(name, "_")
+	}
+	return name
+}
+
+// graphQLNamer names the types and fields of the SDL after the tables and
+// columns.
+type graphQLNamer struct {
+	singularTypes bool
+	camelFields   bool
+}
+
+// typeName names the type of a table's rows.
+func (n graphQLNamer) typeName(table string) string {
+	if n.singularTypes {
+		table = singular(table)
+	}
+	var b strings.Builder
+	for _, w := range graphQLWords(table) {
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+	return graphQLName(b.String())
+}
+
+// fieldName names the field of a column or a table.
+func (n graphQLNamer) fieldName(name string) string {
+	words := graphQLWords(name)
+	if !n.camelFields {
+		return graphQLName(strings.Join(words, "_"))
+	}
+	var b strings.Builder
+	for i, w := range words {
+		if i == 0 {
+			b.WriteString(strings.ToLower(w[:1]) + w[1:])
+		} else {
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	return graphQLName(b.String())
+}
+
+// graphQLDescription renders a description as a block string on its own
+// line, indented by indent.
+func graphQLDescription(indent, text string) string {
+	text = strings.ReplaceAll(strings.TrimSpace(text), `"""`, `\"""`)
+	if text == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s\"\"\"%s\"\"\"\n", indent, strings.ReplaceAll(text, "\n", " "))
+}
+
+// graphQLType is the object type of a table's rows as it is built.
+type graphQLType struct {
+	table  *schemaTable
+	name   string
+	key    []string
+	fields []string
+	used   map[string]bool
+	// columnTypes holds the GraphQL type of each column, for the
+	// arguments of the Query fields.
+	columnTypes map[string]string
+}
+
+// addField adds a field taking params to t under name, or under name
+// followed by a number if t already has a field named so.
+func (t *graphQLType) addField(name, params, typ, description string) {
+	unique := name
+	for n := 2; t.used[unique]; n++ {
+		unique = fmt.Sprintf("%s%d", name, n)
+	}
+	t.used[unique] = true
+	if params != "" {
+		params = "(" + params + ")"
+	}
+	t.fields = append(t.fields, graphQLDescription("  ", description)+fmt.Sprintf("  %s%s: %s\n", unique, params, typ))
+}
+
+// graphQLEnum renders an enum type for the members of an ENUM column, or
+// returns false if they do not make distinct GraphQL names.
+func graphQLEnum(name string, values []string) (string, bool) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "enum %s {\n", name)
+	seen := make(map[string]bool)
+	for _, v := range values {
+		member := graphQLName(strings.ToUpper(strings.Join(graphQLWords(v), "_")))
+		if seen[member] || member == "_" {
+			return "", false
+		}
+		seen[member] = true
+		if member != strings.ToUpper(v) {
+			b.WriteString(graphQLDescription("  ", fmt.Sprintf("Stored as %q.", v)))
+		}
+		fmt.Fprintf(&b, "  %s\n", member)
+	}
+	b.WriteString("}\n")
+	return b.String(), true
+}
+
+// graphQLSchema renders the SDL for tables of database, with notes on what
+// it leaves out.
+func graphQLSchema(database string, tables []*schemaTable, namer graphQLNamer) (string, []string) {
+	// Type names must not clash with each other or the built-in names.
+	typeNames := map[string]bool{"Query": true, "Int": true, "Float": true, "String": true, "Boolean": true, "ID": true}
+	for scalar := range graphQLScalars {
+		typeNames[scalar] = true
+	}
+	uniqueTypeName := func(name string) string {
+		unique := name
+		for n := 2; typeNames[unique]; n++ {
+			unique = fmt.Sprintf("%s%d", name, n)
+		}
+		typeNames[unique] = true
+		return unique
+	}
+	types := make([]*graphQLType, len(tables))
+	typeOf := make(map[string]*graphQLType, len(tables))
+	for i, t := range tables {
+		types[i] = &graphQLType{table: t, name: uniqueTypeName(namer.typeName(t.Name)), used: make(map[string]bool), columnTypes: make(map[string]string)}
+		for _, index := range t.Indexes {
+			if index.Name == "PRIMARY" {
+				types[i].key = index.Columns
+			}
+		}
+		typeOf[t.Name] = types[i]
+	}
+
+	var enums []string
+	scalars := make(map[string]bool)
+	for _, typ := range types {
+		for _, c := range typ.table.Columns {
+			f := newModelField(modelColumn{name: c.Name, typ: c.Type, nullable: c.Nullable})
+			var fieldType string
+			switch f.kind {
+			case "integer":
+				fieldType = "Int"
+				if f.min < -1<<31 || f.max > 1<<31-1 {
+					fieldType = "BigInt"
+				}
+			case "number":
+				fieldType = "Float"
+			case "boolean":
+				fieldType = "Boolean"
+			case "decimal":
+				fieldType = "Decimal"
+			case "date":
+				fieldType = "Date"
+			case "datetime":
+				fieldType = "DateTime"
+			case "time":
+				fieldType = "Time"
+			case "json":
+				fieldType = "JSON"
+			case "binary":
+				fieldType = "Bytes"
+			case "enum":
+				fieldType = "String"
+				name := uniqueTypeName(typ.name + namer.typeName(c.Name))
+				if enum, ok := graphQLEnum(name, f.values); ok {
+					enums = append(enums, enum)
+					fieldType = name
+				} else {
+					delete(typeNames, name)
+				}
+			default:
+				fieldType = "String"
+			}
+			if slices.Equal(typ.key, []string{c.Name}) {
+				fieldType = "ID"
+			}
+			if graphQLScalars[fieldType] != "" {
+				scalars[fieldType] = true
+			}
+			typ.columnTypes[c.Name] = fieldType + "!"
+			if !c.Nullable {
+				fieldType += "!"
+			}
+			description := c.Comment
+			if f.kind == "set" {
+				description = strings.TrimSpace(description + " " + fieldNote(modelField{kind: "set", values: f.values}))
+			}
+			typ.addField(namer.fieldName(c.Name), "", fieldType, description)
+		}
+	}
+
+	// Each foreign key between the tables gives the referencing type a
+	// field for the referenced row and the referenced type a field listing
+	// the referencing rows.
+	var notes []string
+	for _, typ := range types {
+		for _, fk := range typ.table.ForeignKeys {
+			ref := typeOf[fk.RefTable]
+			if fk.RefDatabase != database || ref == nil {
+				notes = append(notes, fmt.Sprintf("%s.%s references %s.%s, which is not included", typ.table.Name, fk.Name, fk.RefDatabase, fk.RefTable))
+				continue
+			}
+			name := fk.RefTable
+			if len(fk.Columns) == 1 {
+				if lower := strings.ToLower(fk.Columns[0]); strings.HasSuffix(lower, "_id") && len(lower) > 3 {
+					name = fk.Columns[0][:len(lower)-3]
+				} else if namer.singularTypes {
+					name = singular(fk.RefTable)
+				}
+			} else if namer.singularTypes {
+				name = singular(fk.RefTable)
+			}
+			nullable := slices.ContainsFunc(typ.table.Columns, func(c schemaColumn) bool {
+				return c.Nullable && slices.Contains(fk.Columns, c.Name)
+			})
+			fieldType := ref.name
+			if !nullable {
+				fieldType += "!"
+			}
+			typ.addField(namer.fieldName(name), "", fieldType, fmt.Sprintf("The %s row %s references.", fk.RefTable, strings.Join(fk.Columns, ", ")))
+
+			reverse := typ.table.Name
+			if ref.used[namer.fieldName(reverse)] {
+				reverse += "_by_" + strings.Join(fk.Columns, "_")
+			}
+			ref.addField(namer.fieldName(reverse), "", "["+typ.name+"!]!", fmt.Sprintf("The %s rows that reference this row through %s.", typ.table.Name, strings.Join(fk.Columns, ", ")))
+		}
+	}
+
+	// The Query type lists each table's rows and looks one up by primary
+	// key.
+	query := &graphQLType{name: "Query", used: make(map[string]bool)}
+	for _, typ := range types {
+		list := namer.fieldName(typ.table.Name)
+		query.addField(list, "limit: Int = 100, offset: Int = 0", "["+typ.name+"!]!", fmt.Sprintf("Rows of %s.", typ.table.Name))
+		if len(typ.key) == 0 {
+			notes = append(notes, fmt.Sprintf("%s has no primary key, so Query has no field to look up one of its rows", typ.table.Name))
+			continue
+		}
+		single := namer.fieldName(singular(typ.table.Name))
+		if single == list {
+			single += "ByKey"
+		}
+		params := make([]string, len(typ.key))
+		for i, col := range typ.key {
+			params[i] = namer.fieldName(col) + ": " + typ.columnTypes[col]
+		}
+		query.addField(single, strings.Join(params, ", "), typ.name, fmt.Sprintf("The %s row with this primary key.", typ.table.Name))
+	}
+
+	var b strings.Builder
+	for _, scalar := range sortedKeys(scalars) {
+		fmt.Fprintf(&b, "%sscalar %s\n\n", graphQLDescription("", graphQLScalars[scalar]), scalar)
+	}
+	for _, enum := range enums {
+		b.WriteString(enum + "\n")
+	}
+	for _, typ := range types {
+		b.WriteString(graphQLDescription("", strings.TrimSpace(fmt.Sprintf("A row of the %s table. %s", typ.table.Name, typ.table.Comment))))
+		fmt.Fprintf(&b, "type %s {\n%s}\n\n", typ.name, strings.Join(typ.fields, ""))
+	}
+	fmt.Fprintf(&b, "type Query {\n%s}\n", strings.Join(query.fields, ""))
+	return b.String(), notes
+}
+
+func GenerateGraphQL(ctx context.Context, req *mcp.CallToolRequest, args GenerateGraphQLParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	if args.Database == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "database is required"},
+			},
+		}, nil, nil
+	}
+	var namer graphQLNamer
+	switch strings.ToLower(args.TypeNames) {
+	case "", "singular":
+		namer.singularTypes = true
+	case "table":
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown type_names %q; use singular or table", args.TypeNames)},
+			},
+		}, nil, nil
+	}
+	switch strings.ToLower(args.FieldNames) {
+	case "", "camel":
+		namer.camelFields = true
+	case "column":
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown field_names %q; use camel or column", args.FieldNames)},
+			},
+		}, nil, nil
+	}
+
+	schema, err := loadSchema(ctx, db, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read schema: %v", err)},
+			},
+		}, nil, nil
+	}
+	byTable := make(map[string]*schemaTable, len(schema.Tables))
+	for i := range schema.Tables {
+		byTable[schema.Tables[i].Name] = &schema.Tables[i]
+	}
+	var tables []*schemaTable
+	if len(args.Tables) == 0 {
+		for i := range schema.Tables {
+			tables = append(tables, &schema.Tables[i])
+		}
+		if len(tables) == 0 {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Database %s has no tables", args.Database)},
+				},
+			}, nil, nil
+		}
+		if len(tables) > maxGraphQLTables {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Database %s has %d tables; name at most %d in tables", args.Database, len(tables), maxGraphQLTables)},
+				},
+			}, nil, nil
+		}
+	}
+	for _, name := range args.Tables {
+		t, ok := byTable[name]
+		if !ok {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Table %s.%s does not exist", args.Database, name)},
+				},
+			}, nil, nil
+		}
+		tables = append(tables, t)
+	}
+
+	sdl, notes := graphQLSchema(args.Database, tables, namer)
+
+	tableNames := make([]string, len(tables))
+	for i, t := range tables {
+		tableNames[i] = t.Name
+	}
+	resultText := fmt.Sprintf("GraphQL schema for %d table(s) of %s:\n", len(tables), quoteIdentifier(args.Database))
+	for _, note := range notes {
+		resultText += "Note: " + note + "\n"
+	}
+	resultText += "\n```graphql\n" + sdl + "```\n"
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"tables": tableNames,
+		"notes":  notes,
+		"sdl":    sdl,
+	}, nil
+}
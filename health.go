@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// readyTimeout bounds the database ping behind /readyz, so a hung server
+// fails the probe instead of stalling it.
+const readyTimeout = 2 * time.Second
+
+// handleHealthz reports that the process is up and serving.
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz reports whether the default connection is open and the
+// database answers a ping.
+func handleReadyz(w http.ResponseWriter, r *http.Request) {
+	db := currentDB()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if db == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintln(w, "not connected to a database")
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, "database unreachable: %v\n", err)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// quoteIdentifier backtick-quotes a MySQL identifier, doubling any embedded
+// backticks so the name cannot break out of the quotes.
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// maxIdentifierLength is the longest database, table, column or index name
+// MySQL accepts, in characters.
+const maxIdentifierLength = 64
+
+// checkIdentifier rejects a name given for kind (database, table, column,
+// ...) that MySQL would refuse or store differently: an empty name, one
+// longer than 64 characters, or one with a NUL character or a trailing
+// space. Quoting keeps any other name from changing the statement.
+func checkIdentifier(kind, name string) error {
+	var problem string
+	switch {
+	case name == "":
+		problem = "it is empty"
+	case !utf8.ValidString(name):
+		problem = "it is not valid UTF-8"
+	case utf8.RuneCountInString(name) > maxIdentifierLength:
+		problem = fmt.Sprintf("it is longer than %d characters", maxIdentifierLength)
+	case strings.ContainsRune(name, 0):
+		problem = "it contains a NUL character"
+	case strings.HasSuffix(name, " "):
+		problem = "it ends with a space"
+	default:
+		return nil
+	}
+	return fmt.Errorf("invalid %s name %q: %s", kind, name, problem)
+}
+
+// quoteIdentifierList quotes each name and joins them with commas.
+func quoteIdentifierList(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = quoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// qualifiedTable returns the quoted `database`.`table` form of a table name.
+func qualifiedTable(database, table string) string {
+	if database == "" {
+		return quoteIdentifier(table)
+	}
+	return quoteIdentifier(database) + "." + quoteIdentifier(table)
+}
+
+// noBackslashEscapes is set while the default connection's sql_mode has
+// NO_BACKSLASH_ESCAPES, under which a backslash in a string literal is an
+// ordinary character rather than an escape.
+var noBackslashEscapes atomic.Bool
+
+// recordSQLMode reads the sql_mode of database, about to become the default
+// connection, for quoteString. If it cannot be read the previous mode is
+// kept.
+func recordSQLMode(ctx context.Context, database *sql.DB) {
+	var mode string
+	if err := database.QueryRowContext(ctx, "SELECT @@SESSION.sql_mode").Scan(&mode); err != nil {
+		slog.Warn("failed to read sql_mode", "err", err)
+		return
+	}
+	noBackslashEscapes.Store(slices.Contains(strings.Split(strings.ToUpper(mode), ","), "NO_BACKSLASH_ESCAPES"))
+}
+
+// quoteString renders s as a single-quoted SQL string literal for the rare
+// places a value cannot be passed as a parameter (DDL defaults, comments,
+// passwords). Quotes are doubled, and backslashes escaped unless the
+// default connection's sql_mode has NO_BACKSLASH_ESCAPES, so the literal
+// reads back as s.
+func quoteString(s string) string {
+	return quoteStringFor(s, noBackslashEscapes.Load())
+}
+
+// quoteStringFor renders s as quoteString does, for a session whose
+// sql_mode has NO_BACKSLASH_ESCAPES when noBackslash is set.
+func quoteStringFor(s string, noBackslash bool) string {
+	if !noBackslash {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	s = strings.ReplaceAll(s, "'", "''")
+	return "'" + s + "'"
+}
+
+// sqlValue converts a decoded JSON argument into a value suitable for a
+// query parameter. Whole numbers become int64 so large keys keep their
+// precision, and objects/arrays are re-encoded for JSON columns.
+func sqlValue(v any) any {
+	switch val := v.(type) {
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1<<53 {
+			return int64(val)
+		}
+		return val
+	case map[string]any, []any:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return nil
+		}
+		return string(b)
+	default:
+		return v
+	}
+}
+
+// sqlLiteral renders a scanned column value as a SQL literal for generated
+// scripts. Strings that are not valid UTF-8 are written as hex literals.
+func sqlLiteral(v any) string {
+	return sqlLiteralFor(v, noBackslashEscapes.Load())
+}
+
+// sqlLiteralFor renders v as sqlLiteral does, for a session whose sql_mode
+// has NO_BACKSLASH_ESCAPES when noBackslash is set. Scripts run elsewhere
+// set the mode themselves rather than rely on the default connection's.
+func sqlLiteralFor(v any, noBackslash bool) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		if !utf8.ValidString(val) {
+			return "0x" + hex.EncodeToString([]byte(val))
+		}
+		return quoteStringFor(val, noBackslash)
+	case []byte:
+		return sqlLiteralFor(string(val), noBackslash)
+	case int64:
+		return strconv.FormatInt(val, 10)
+	case float64:
+		return strconv.FormatFloat(val, 'g', -1, 64)
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return quoteStringFor(val.Format("2006-01-02 15:04:05.999999"), noBackslash)
+	}
+	return quoteStringFor(fmt.Sprint(v), noBackslash)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckIdentifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{name: "orders"},
+		{name: "order items"},
+		{name: "ünïcödé"},
+		{name: "a`b"},
+		{name: strings.Repeat("é", maxIdentifierLength)},
+		{name: "", wantErr: true},
+		{name: strings.Repeat("a", maxIdentifierLength+1), wantErr: true},
+		{name: "bad\xff", wantErr: true},
+		{name: "a\x00b", wantErr: true},
+		{name: "trailing ", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := checkIdentifier("table", tt.name); (err != nil) != tt.wantErr {
+			t.Errorf("checkIdentifier(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if got, want := qualifiedTable("my`db", "t"), "`my``db`.`t`"; got != want {
+		t.Errorf("qualifiedTable() = %s, want %s", got, want)
+	}
+}
+
+func TestQuoteString(t *testing.T) {
+	defer noBackslashEscapes.Store(false)
+	tests := []struct {
+		s                  string
+		noBackslashEscapes bool
+		want               string
+	}{
+		{s: "plain", want: "'plain'"},
+		{s: "it's", want: "'it''s'"},
+		{s: `C:\temp`, want: `'C:\\temp'`},
+		{s: `\' OR 1=1 -- `, want: `'\\'' OR 1=1 -- '`},
+		{s: "it's", noBackslashEscapes: true, want: "'it''s'"},
+		// A backslash is an ordinary character in this mode, so doubling it
+		// would change the value.
+		{s: `C:\temp`, noBackslashEscapes: true, want: `'C:\temp'`},
+		{s: `\' OR 1=1 -- `, noBackslashEscapes: true, want: `'\'' OR 1=1 -- '`},
+	}
+	for _, tt := range tests {
+		noBackslashEscapes.Store(tt.noBackslashEscapes)
+		if got := quoteString(tt.s); got != tt.want {
+			t.Errorf("quoteString(%q) with NO_BACKSLASH_ESCAPES %v = %s, want %s", tt.s, tt.noBackslashEscapes, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type KillIdleConnectionsParams struct {
+	IdleMinutes int    `json:"idle_minutes"`
+	User        string `json:"user,omitempty"`
+	Host        string `json:"host,omitempty"`
+	Database    string `json:"database,omitempty"`
+	Confirm     bool   `json:"confirm,omitempty"`
+}
+
+// IdleConnection is a sleeping client connection.
+type IdleConnection struct {
+	ID          int64  `json:"id"`
+	User        string `json:"user"`
+	Host        string `json:"host"`
+	Database    string `json:"database,omitempty"`
+	IdleSeconds int64  `json:"idleSeconds"`
+}
+
+func KillIdleConnections(ctx context.Context, req *mcp.CallToolRequest, args KillIdleConnectionsParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if args.IdleMinutes <= 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "idle_minutes must be greater than 0"},
+			},
+		}, nil, nil
+	}
+
+	// Behind vtgate the process list is one tablet's, whose idle connections
+	// are vttablet's own pool rather than clients.
+	if server := serverInfoFor(ctx, db); server.Vitess {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "kill_idle_connections is not supported through Vitess; client connections end at vtgate, and the connections a shard lists belong to vttablet's pool"},
+			},
+		}, nil, nil
+	}
+
+	if args.Confirm {
+		if err := checkWritable("kill_idle_connections"); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: err.Error()},
+				},
+			}, nil, nil
+		}
+	}
+
+	// Only plain client sessions in the Sleep state are candidates; system
+	// threads and replication connections are never listed.
+	query := `
+		SELECT ID, USER, HOST, COALESCE(DB, ''), TIME
+		FROM information_schema.PROCESSLIST
+		WHERE COMMAND = 'Sleep' AND TIME >= ? AND ID <> CONNECTION_ID()
+		  AND USER NOT IN ('system user', 'event_scheduler')`
+	params := []any{args.IdleMinutes * 60}
+	if args.User != "" {
+		query += " AND USER = ?"
+		params = append(params, args.User)
+	}
+	if args.Host != "" {
+		// HOST includes the client port, e.g. 10.0.0.5:51234.
+		query += " AND SUBSTRING_INDEX(HOST, ':', 1) = ?"
+		params = append(params, args.Host)
+	}
+	if args.Database != "" {
+		query += " AND DB = ?"
+		params = append(params, args.Database)
+	}
+	query += " ORDER BY TIME DESC"
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to list connections: %v", err)},
+			},
+		}, nil, nil
+	}
+	var idle []IdleConnection
+	for rows.Next() {
+		var c IdleConnection
+		if err := rows.Scan(&c.ID, &c.User, &c.Host, &c.Database, &c.IdleSeconds); err != nil {
+			rows.Close()
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to read connections: %v", err)},
+				},
+			}, nil, nil
+		}
+		idle = append(idle, c)
+	}
+	rows.Close()
+
+	var maxConnections, connected int64
+	db.QueryRowContext(ctx, "SELECT @@max_connections").Scan(&maxConnections)
+	db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.PROCESSLIST").Scan(&connected)
+
+	resultText := fmt.Sprintf("Connections: %d of max_connections %d\n", connected, maxConnections)
+	resultText += fmt.Sprintf("%d connections idle for at least %d minutes:\n", len(idle), args.IdleMinutes)
+	for _, c := range idle {
+		resultText += fmt.Sprintf("  %d  %s@%s  db=%s  idle %ds\n", c.ID, c.User, c.Host, c.Database, c.IdleSeconds)
+	}
+
+	if !args.Confirm || len(idle) == 0 {
+		if len(idle) > 0 {
+			resultText += "\nNothing was killed. Re-run with confirm: true to kill these connections."
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, map[string]any{
+			"connections":    idle,
+			"connected":      connected,
+			"maxConnections": maxConnections,
+			"killed":         0,
+		}, nil
+	}
+
+	// Before global kill (TiDB 6.1) TiDB ignores a plain KILL, which behind
+	// a load balancer could reach the wrong instance; KILL TIDB always works.
+	killStatement := "KILL CONNECTION %d"
+	if serverInfoFor(ctx, db).TiDB {
+		killStatement = "KILL TIDB CONNECTION %d"
+	}
+	var killed int
+	var failures []string
+	for _, c := range idle {
+		stmt := fmt.Sprintf(killStatement, c.ID)
+		_, err := db.ExecContext(ctx, stmt)
+		if err != nil && strings.Contains(err.Error(), "Unknown thread id") {
+			// The client disconnected on its own since it was listed.
+			continue
+		}
+		audit("kill_idle_connections", fmt.Sprintf("%s -- %s@%s idle %ds", stmt, c.User, c.Host, c.IdleSeconds), 0, err)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%d: %v", c.ID, err))
+			continue
+		}
+		killed++
+	}
+
+	resultText += fmt.Sprintf("\nKilled %d connections.", killed)
+	if len(failures) > 0 {
+		resultText += "\nFailed:\n  " + strings.Join(failures, "\n  ")
+	}
+	return &mcp.CallToolResult{
+		IsError: killed == 0 && len(failures) > 0,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"connections":    idle,
+		"connected":      connected,
+		"maxConnections": maxConnections,
+		"killed":         killed,
+	}, nil
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const defaultInsertBatchSize = 500
+
+type InsertRowsParams struct {
+	Database  string           `json:"database"`
+	Table     string           `json:"table"`
+	Rows      []map[string]any `json:"rows"`
+	BatchSize int              `json:"batch_size,omitempty"`
+	Atomic    bool             `json:"atomic,omitempty"`
+}
+
+// RowError reports why a single input row was rejected.
+type RowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// rowWriteResult summarizes a structured write over many input rows.
+type rowWriteResult struct {
+	Attempted    int        `json:"attempted"`
+	Succeeded    int        `json:"succeeded"`
+	RowsAffected int64      `json:"rowsAffected"`
+	Errors       []RowError `json:"errors,omitempty"`
+	// Retries counts statements, or whole atomic loads, run again after a
+	// deadlock or lock wait timeout.
+	Retries int `json:"retries,omitempty"`
+}
+
+func InsertRows(ctx context.Context, req *mcp.CallToolRequest, args InsertRowsParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("insert_rows"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	result, err := writeRows(ctx, db, args.Database, args.Table, args.Rows, rowWriteOptions{
+		Tool:      "insert_rows",
+		BatchSize: args.BatchSize,
+		Atomic:    args.Atomic,
+	})
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to insert rows: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("Inserted %d of %d rows into %s.%s",
+		result.Succeeded, result.Attempted, args.Database, args.Table)
+
+	return &mcp.CallToolResult{
+		IsError: result.Succeeded == 0 && len(result.Errors) > 0,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText + retryNote(result.Retries) + formatRowErrors(result.Errors)},
+		},
+	}, result, nil
+}
+
+type rowWriteOptions struct {
+	// Tool names the calling tool in the audit log.
+	Tool      string
+	BatchSize int
+	// Atomic runs the whole load in one transaction, rolled back on the
+	// first error.
+	Atomic bool
+	// Replace uses REPLACE INTO instead of INSERT INTO.
+	Replace bool
+	// Required lists columns every row must provide.
+	Required []string
+	// Suffix, if set, receives the column list and returns a clause
+	// appended to each statement (used for ON DUPLICATE KEY UPDATE).
+	Suffix func(columns []string) string
+}
+
+// writeRows validates rows against the table definition and writes them with
+// batched, parameterized INSERT statements. A failing batch is retried row by
+// row so that every bad row is reported individually while the rest are
+// still written.
+func writeRows(ctx context.Context, db *sql.DB, database, table string, input []map[string]any, opts rowWriteOptions) (*rowWriteResult, error) {
+	if database == "" || table == "" {
+		return nil, fmt.Errorf("database and table are required")
+	}
+	if len(input) == 0 {
+		return nil, fmt.Errorf("no rows provided")
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultInsertBatchSize
+	}
+	atomic := opts.Atomic
+
+	tableCols, err := catalogTable(ctx, db, database, table)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool, len(tableCols))
+	for _, col := range tableCols {
+		known[col.ColumnName] = true
+	}
+
+	result := &rowWriteResult{Attempted: len(input)}
+	used := make(map[string]bool)
+	var valid []int
+	for i, row := range input {
+		var unknown []string
+		for name := range row {
+			if !known[name] {
+				unknown = append(unknown, name)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			result.Errors = append(result.Errors, RowError{Row: i, Error: fmt.Sprintf("unknown column(s): %s", strings.Join(unknown, ", "))})
+			continue
+		}
+		if len(row) == 0 {
+			result.Errors = append(result.Errors, RowError{Row: i, Error: "row has no columns"})
+			continue
+		}
+		var missing []string
+		for _, name := range opts.Required {
+			if _, ok := row[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			result.Errors = append(result.Errors, RowError{Row: i, Error: fmt.Sprintf("missing key column(s): %s", strings.Join(missing, ", "))})
+			continue
+		}
+		for name := range row {
+			used[name] = true
+		}
+		valid = append(valid, i)
+	}
+
+	if atomic && len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	// Keep the table's column order so generated SQL is predictable.
+	var columns []string
+	for _, col := range tableCols {
+		if used[col.ColumnName] {
+			columns = append(columns, col.ColumnName)
+		}
+	}
+
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = quoteIdentifier(col)
+	}
+	verb := "INSERT INTO"
+	if opts.Replace {
+		verb = "REPLACE INTO"
+	}
+	prefix := fmt.Sprintf("%s %s (%s) VALUES ", verb, qualifiedTable(database, table), strings.Join(quoted, ", "))
+	tail := ""
+	if opts.Suffix != nil {
+		tail = opts.Suffix(columns)
+	}
+
+	build := func(indexes []int) (string, []any) {
+		var sb strings.Builder
+		var params []any
+		sb.WriteString(prefix)
+		for n, idx := range indexes {
+			if n > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString("(")
+			for c, col := range columns {
+				if c > 0 {
+					sb.WriteString(", ")
+				}
+				val, ok := input[idx][col]
+				if !ok {
+					sb.WriteString("DEFAULT")
+					continue
+				}
+				sb.WriteString("?")
+				params = append(params, sqlValue(val))
+			}
+			sb.WriteString(")")
+		}
+		if tail != "" {
+			sb.WriteString(" " + tail)
+		}
+		return sb.String(), params
+	}
+
+	if atomic {
+		// A deadlock rolls back the transaction, so the whole load is run
+		// again rather than the failing batch.
+		var failed *RowError
+		result.Retries, err = retryTransient(ctx, func() error {
+			result.Succeeded, result.RowsAffected, failed = 0, 0, nil
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				return fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			defer tx.Rollback()
+			for start := 0; start < len(valid); start += batchSize {
+				batch := valid[start:min(start+batchSize, len(valid))]
+				query, params := build(batch)
+				res, err := tx.ExecContext(ctx, query, params...)
+				if err != nil {
+					audit(opts.Tool, prefix+"...", 0, err)
+					failed = &RowError{Row: batch[0], Error: fmt.Sprintf("batch starting at row %d failed: %v", batch[0], err)}
+					return err
+				}
+				n, _ := res.RowsAffected()
+				result.RowsAffected += n
+				result.Succeeded += len(batch)
+			}
+			if err := tx.Commit(); err != nil {
+				audit(opts.Tool, prefix+"...", 0, err)
+				return fmt.Errorf("failed to commit: %w", err)
+			}
+			return nil
+		})
+		if failed != nil {
+			result.Errors = append(result.Errors, *failed)
+			result.Succeeded = 0
+			result.RowsAffected = 0
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		exec := retryingExecer{execer: db, retries: &result.Retries}
+		for start := 0; start < len(valid); start += batchSize {
+			end := min(start+batchSize, len(valid))
+			batch := valid[start:end]
+
+			query, params := build(batch)
+			res, err := exec.ExecContext(ctx, query, params...)
+			if err == nil {
+				n, _ := res.RowsAffected()
+				result.RowsAffected += n
+				result.Succeeded += len(batch)
+				continue
+			}
+
+			if len(batch) == 1 {
+				result.Errors = append(result.Errors, RowError{Row: batch[0], Error: err.Error()})
+				continue
+			}
+			for _, idx := range batch {
+				query, params := build([]int{idx})
+				res, err := exec.ExecContext(ctx, query, params...)
+				if err != nil {
+					result.Errors = append(result.Errors, RowError{Row: idx, Error: err.Error()})
+					continue
+				}
+				n, _ := res.RowsAffected()
+				result.RowsAffected += n
+				result.Succeeded++
+			}
+		}
+	}
+
+	audit(opts.Tool, fmt.Sprintf("%s... (%d of %d rows written)", prefix, result.Succeeded, result.Attempted), result.RowsAffected, nil)
+	return result, nil
+}
+
+func formatRowErrors(errs []RowError) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	const maxShown = 20
+	text := fmt.Sprintf("\n\n%d row(s) failed:\n", len(errs))
+	for i, e := range errs {
+		if i == maxShown {
+			text += fmt.Sprintf("... and %d more\n", len(errs)-maxShown)
+			break
+		}
+		text += fmt.Sprintf("- row %d: %s\n", e.Row, e.Error)
+	}
+	return text
+}
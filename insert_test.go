@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// rowServer is a fakeServer for the row tools. An INSERT containing a value
+// in failing fails with its error; any other reports one affected row per
+// row in its VALUES list. deadlocks is the number of writes that fail with
+// a deadlock before any succeeds.
+func rowServer(failing map[int64]error, deadlocks int) *fakeServer {
+	return &fakeServer{handle: func(ctx context.Context, query string, args []driver.NamedValue) (*fakeResult, error) {
+		if !strings.HasPrefix(query, "INSERT") {
+			return nil, nil
+		}
+		if deadlocks > 0 {
+			deadlocks--
+			return nil, &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+		}
+		for _, arg := range args {
+			if err := failing[arg.Value.(int64)]; err != nil {
+				return nil, err
+			}
+		}
+		return &fakeResult{affected: int64(strings.Count(query, "(") - 1)}, nil
+	}}
+}
+
+func TestInsertRows(t *testing.T) {
+	withFastRetries(t)
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '2' for key 'PRIMARY'"}
+	rows := func(ids ...int) []map[string]any {
+		var out []map[string]any
+		for _, id := range ids {
+			out = append(out, map[string]any{"id": float64(id), "qty": float64(1)})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		rows      []map[string]any
+		atomic    bool
+		failing   map[int64]error
+		deadlocks int
+
+		wantSucceeded int
+		wantErrorRows []int
+		wantRetries   int
+		wantInserts   int
+		wantRollback  bool
+	}{
+		{name: "one batch", rows: rows(1, 2, 3), wantSucceeded: 3, wantInserts: 1},
+		{
+			name: "failing batch retried row by row", rows: rows(1, 2, 3), failing: map[int64]error{2: duplicate},
+			wantSucceeded: 2, wantErrorRows: []int{1}, wantInserts: 4,
+		},
+		{
+			name: "unknown column", rows: append(rows(1), map[string]any{"id": float64(2), "colour": "red"}),
+			wantSucceeded: 1, wantErrorRows: []int{1}, wantInserts: 1,
+		},
+		{
+			name: "atomic validation runs nothing", rows: append(rows(1), map[string]any{"colour": "red"}), atomic: true,
+			wantErrorRows: []int{1},
+		},
+		{
+			name: "atomic failure rolls back", rows: rows(1, 2, 3), atomic: true, failing: map[int64]error{2: duplicate},
+			wantErrorRows: []int{0}, wantInserts: 1, wantRollback: true,
+		},
+		{name: "deadlock retried", rows: rows(1, 2), deadlocks: 1, wantSucceeded: 2, wantRetries: 1, wantInserts: 2},
+		{
+			name: "atomic deadlock retries the load", rows: rows(1, 2), atomic: true, deadlocks: 1,
+			wantSucceeded: 2, wantRetries: 1, wantInserts: 2, wantRollback: true,
+		},
+	}
+	for _, tt := range tests {
+		server := rowServer(tt.failing, tt.deadlocks)
+		withTableColumns(t, withFakeConnection(t, server), "shop", "items", "id", "qty")
+
+		res, out, err := InsertRows(context.Background(), nil, InsertRowsParams{
+			Database: "shop", Table: "items", Rows: tt.rows, Atomic: tt.atomic,
+		})
+		if err != nil || out == nil {
+			t.Errorf("%s: InsertRows() = %v, %v", tt.name, toolErrorText(res), err)
+			continue
+		}
+		result := out.(*rowWriteResult)
+		var errorRows []int
+		for _, e := range result.Errors {
+			errorRows = append(errorRows, e.Row)
+		}
+		if result.Succeeded != tt.wantSucceeded || !slices.Equal(errorRows, tt.wantErrorRows) || result.Retries != tt.wantRetries {
+			t.Errorf("%s: %d succeeded, error rows %v, %d retries; want %d, %v, %d",
+				tt.name, result.Succeeded, errorRows, result.Retries, tt.wantSucceeded, tt.wantErrorRows, tt.wantRetries)
+		}
+		inserts := 0
+		for _, statement := range server.statements() {
+			if strings.HasPrefix(statement, "INSERT INTO `shop`.`items` (`id`, `qty`) VALUES ") {
+				inserts++
+			}
+		}
+		if inserts != tt.wantInserts || server.ran("ROLLBACK") != tt.wantRollback {
+			t.Errorf("%s: statements %q; want %d inserts, rollback %v", tt.name, server.statements(), tt.wantInserts, tt.wantRollback)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// mcpClient describes where an MCP client keeps its server configuration.
+type mcpClient struct {
+	title string
+	// serversKey is the top-level object the servers are listed under.
+	serversKey string
+	// stdioType is set for clients that want "type": "stdio" in each entry.
+	stdioType bool
+	// userPath and projectPath return the configuration file for the
+	// current user and for the project in the current directory; nil if the
+	// client has no such file.
+	userPath    func() (string, error)
+	projectPath func() (string, error)
+}
+
+var mcpClients = map[string]mcpClient{
+	"claude": {
+		title:      "Claude Desktop",
+		serversKey: "mcpServers",
+		userPath: func() (string, error) {
+			dir, err := os.UserConfigDir()
+			return filepath.Join(dir, "Claude", "claude_desktop_config.json"), err
+		},
+	},
+	"cursor": {
+		title:      "Cursor",
+		serversKey: "mcpServers",
+		userPath: func() (string, error) {
+			dir, err := os.UserHomeDir()
+			return filepath.Join(dir, ".cursor", "mcp.json"), err
+		},
+		projectPath: func() (string, error) {
+			return filepath.Abs(filepath.Join(".cursor", "mcp.json"))
+		},
+	},
+	"vscode": {
+		title:      "VS Code",
+		serversKey: "servers",
+		stdioType:  true,
+		userPath: func() (string, error) {
+			dir, err := os.UserConfigDir()
+			return filepath.Join(dir, "Code", "User", "mcp.json"), err
+		},
+		projectPath: func() (string, error) {
+			return filepath.Abs(filepath.Join(".vscode", "mcp.json"))
+		},
+	},
+}
+
+// runInstall implements the install subcommand, which adds this binary to
+// an MCP client's configuration or updates the entry it added before.
+// Other servers and settings in the file are kept.
+func runInstall(args []string) error {
+	fs := flag.NewFlagSet("install", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: %s install -client claude|cursor|vscode [options] [-- server flags]\n", filepath.Base(os.Args[0]))
+		fs.PrintDefaults()
+	}
+	clientName := fs.String("client", "", "MCP client to configure: claude, cursor or vscode")
+	name := fs.String("name", "mysql", "Name of the server in the client configuration; use different names to install several setups, e.g. mysql-staging")
+	dsn := fs.String("dsn", "", "MySQL DSN the server connects to on startup")
+	configFile := fs.String("config", "", "JSON config file the server loads")
+	readOnlyFlag := fs.Bool("read-only", false, "Run the server with -read-only")
+	project := fs.Bool("project", false, "Write the configuration of the project in the current directory instead of the user's (cursor, vscode)")
+	printOnly := fs.Bool("print", false, "Print the resulting configuration file instead of writing it")
+	env := map[string]string{}
+	fs.Func("env", "Environment variable for the server as KEY=VALUE (repeatable)", func(s string) error {
+		key, value, ok := strings.Cut(s, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("expected KEY=VALUE, got %q", s)
+		}
+		env[key] = value
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	client, ok := mcpClients[*clientName]
+	if !ok {
+		return fmt.Errorf("unknown -client %q (use claude, cursor or vscode)", *clientName)
+	}
+	pathFunc := client.userPath
+	if *project {
+		if client.projectPath == nil {
+			return fmt.Errorf("%s has no per-project configuration", client.title)
+		}
+		pathFunc = client.projectPath
+	}
+	path, err := pathFunc()
+	if err != nil {
+		return fmt.Errorf("failed to locate the %s configuration: %w", client.title, err)
+	}
+
+	command, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(command); err == nil {
+		command = resolved
+	}
+	serverArgs := []string{}
+	if *dsn != "" {
+		serverArgs = append(serverArgs, "-dsn="+*dsn)
+	}
+	if *configFile != "" {
+		abs, err := filepath.Abs(*configFile)
+		if err != nil {
+			return err
+		}
+		serverArgs = append(serverArgs, "-config="+abs)
+	}
+	if *readOnlyFlag {
+		serverArgs = append(serverArgs, "-read-only")
+	}
+	serverArgs = append(serverArgs, fs.Args()...)
+
+	entry := map[string]any{"command": command, "args": serverArgs}
+	if client.stdioType {
+		entry["type"] = "stdio"
+	}
+	if len(env) > 0 {
+		entry["env"] = env
+	}
+
+	original, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	settings := map[string]any{}
+	if len(strings.TrimSpace(string(original))) > 0 {
+		if err := json.Unmarshal(original, &settings); err != nil {
+			return fmt.Errorf("%s is not plain JSON (%v); add the server by hand", path, err)
+		}
+	}
+	servers, _ := settings[client.serversKey].(map[string]any)
+	if servers == nil {
+		servers = map[string]any{}
+	}
+	_, replacing := servers[*name]
+	servers[*name] = entry
+	settings[client.serversKey] = servers
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if *printOnly {
+		fmt.Printf("%s:\n%s", path, data)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if original != nil {
+		if err := os.WriteFile(path+".bak", original, 0o600); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+	}
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return err
+	}
+
+	if replacing {
+		fmt.Printf("Updated %q in %s\n", *name, path)
+	} else {
+		fmt.Printf("Added %q to %s\n", *name, path)
+	}
+	if original != nil {
+		fmt.Printf("The previous file was saved as %s.bak\n", path)
+	}
+	if *dsn != "" {
+		fmt.Println("The DSN, including any password, is stored in that file in plain text.")
+	}
+	fmt.Printf("Restart %s to load the server.\n", client.title)
+	return nil
+}
@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// openDatabase opens a connection pool for cfg. Its connections report
+// every statement to trackStatement and are registered in liveConns, so
+// statements still running at shutdown can be killed.
+func openDatabase(cfg *mysql.Config, group *resourceGroupSetting) (*sql.DB, error) {
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	database := sql.OpenDB(instrumentedConnector{Connector: connector, group: group})
+	poolAddresses.Lock()
+	poolAddresses.byDB[database] = cfg.Addr
+	poolAddresses.Unlock()
+	return database, nil
+}
+
+// liveConns holds every open connection of the pools opened with
+// openDatabase.
+var liveConns = struct {
+	sync.Mutex
+	conns map[*instrumentedConn]struct{}
+}{conns: make(map[*instrumentedConn]struct{})}
+
+// The wrappers below forward every optional driver interface the MySQL
+// driver implements, so database/sql behaves as it does with the driver
+// itself: statements with arguments still fall back to prepared statements
+// on driver.ErrSkip, and broken connections are still dropped from the pool.
+
+type instrumentedConnector struct {
+	driver.Connector
+	// group is the resource group the pool's connections join.
+	group *resourceGroupSetting
+}
+
+func (c instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ic := &instrumentedConn{Conn: conn, connector: c.Connector, id: connectionID(ctx, conn)}
+	joinResourceGroup(ctx, conn, c.group)
+	liveConns.Lock()
+	liveConns.conns[ic] = struct{}{}
+	liveConns.Unlock()
+	return ic, nil
+}
+
+// connectionID returns the server's thread ID for conn, or 0 if it cannot
+// be read.
+func connectionID(ctx context.Context, conn driver.Conn) uint64 {
+	q, ok := conn.(driver.QueryerContext)
+	if !ok {
+		return 0
+	}
+	rows, err := q.QueryContext(ctx, "SELECT CONNECTION_ID()", nil)
+	if err != nil {
+		return 0
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	if rows.Next(dest) != nil {
+		return 0
+	}
+	switch v := dest[0].(type) {
+	case int64:
+		return uint64(v)
+	case uint64:
+		return v
+	case []byte:
+		id, _ := strconv.ParseUint(string(v), 10, 64)
+		return id
+	}
+	return 0
+}
+
+type instrumentedConn struct {
+	driver.Conn
+	connector driver.Connector
+	id        uint64
+	// busy counts the statements running or with open results on the
+	// connection.
+	busy atomic.Int32
+}
+
+func (c *instrumentedConn) Close() error {
+	liveConns.Lock()
+	delete(liveConns.conns, c)
+	liveConns.Unlock()
+	return c.Conn.Close()
+}
+
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, conn: c, query: query}, nil
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	return c.Conn.Begin()
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	c.busy.Add(1)
+	defer c.busy.Add(-1)
+	start := time.Now()
+	watch := c.watchStatement(ctx)
+	res, err := e.ExecContext(ctx, query, args)
+	if err == driver.ErrSkip {
+		watch.finish(nil)
+		return nil, err
+	}
+	err = watch.finish(err)
+	trackStatement(ctx, "exec", query, args, start)(rowsAffected(res, err), err)
+	return res, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	c.busy.Add(1)
+	start := time.Now()
+	watch := c.watchStatement(ctx)
+	rows, err := q.QueryContext(ctx, query, args)
+	if err == driver.ErrSkip {
+		c.busy.Add(-1)
+		watch.finish(nil)
+		return nil, err
+	}
+	done := trackStatement(ctx, "query", query, args, start)
+	if err != nil {
+		c.busy.Add(-1)
+		err = watch.finish(err)
+		done(0, err)
+		return nil, err
+	}
+	return &instrumentedRows{Rows: rows, conn: c, done: done, watch: watch}, nil
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *instrumentedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type instrumentedStmt struct {
+	driver.Stmt
+	conn  *instrumentedConn
+	query string
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	s.conn.busy.Add(1)
+	defer s.conn.busy.Add(-1)
+	done := trackStatement(ctx, "exec", s.query, args, time.Now())
+	watch := s.conn.watchStatement(ctx)
+	var res driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(namedValues(args))
+	}
+	err = watch.finish(err)
+	done(rowsAffected(res, err), err)
+	return res, err
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	s.conn.busy.Add(1)
+	done := trackStatement(ctx, "query", s.query, args, time.Now())
+	watch := s.conn.watchStatement(ctx)
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(namedValues(args))
+	}
+	if err != nil {
+		s.conn.busy.Add(-1)
+		err = watch.finish(err)
+		done(0, err)
+		return nil, err
+	}
+	return &instrumentedRows{Rows: rows, conn: s.conn, done: done, watch: watch}, nil
+}
+
+func (s *instrumentedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// rowsAffected returns the rows an exec changed, or 0 if it failed.
+func rowsAffected(res driver.Result, err error) int64 {
+	if err != nil {
+		return 0
+	}
+	n, _ := res.RowsAffected()
+	return n
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// instrumentedRows counts the rows read and completes the statement when
+// the result is closed, so the recorded duration includes fetching and a
+// statement cancelled while its rows are read is killed as well.
+type instrumentedRows struct {
+	driver.Rows
+	conn  *instrumentedConn
+	done  func(rows int64, err error)
+	watch *statementWatch
+	count int64
+	err   error
+}
+
+func (r *instrumentedRows) Next(dest []driver.Value) error {
+	err := r.Rows.Next(dest)
+	switch {
+	case err == nil:
+		r.count++
+	case err != io.EOF:
+		err = r.watch.wrap(err)
+		r.err = err
+	}
+	return err
+}
+
+func (r *instrumentedRows) Close() error {
+	err := r.Rows.Close()
+	if r.done != nil {
+		r.watch.finish(nil)
+		r.conn.busy.Add(-1)
+		r.done(r.count, r.err)
+		r.done = nil
+	}
+	return err
+}
+
+func (r *instrumentedRows) HasNextResultSet() bool {
+	if n, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return n.HasNextResultSet()
+	}
+	return false
+}
+
+func (r *instrumentedRows) NextResultSet() error {
+	if n, ok := r.Rows.(driver.RowsNextResultSet); ok {
+		return n.NextResultSet()
+	}
+	return io.EOF
+}
+
+func (r *instrumentedRows) ColumnTypeDatabaseTypeName(index int) string {
+	if t, ok := r.Rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		return t.ColumnTypeDatabaseTypeName(index)
+	}
+	return ""
+}
+
+func (r *instrumentedRows) ColumnTypeScanType(index int) reflect.Type {
+	if t, ok := r.Rows.(driver.RowsColumnTypeScanType); ok {
+		return t.ColumnTypeScanType(index)
+	}
+	return reflect.TypeFor[any]()
+}
+
+func (r *instrumentedRows) ColumnTypeNullable(index int) (bool, bool) {
+	if t, ok := r.Rows.(driver.RowsColumnTypeNullable); ok {
+		return t.ColumnTypeNullable(index)
+	}
+	return false, false
+}
+
+func (r *instrumentedRows) ColumnTypeLength(index int) (int64, bool) {
+	if t, ok := r.Rows.(driver.RowsColumnTypeLength); ok {
+		return t.ColumnTypeLength(index)
+	}
+	return 0, false
+}
+
+func (r *instrumentedRows) ColumnTypePrecisionScale(index int) (int64, int64, bool) {
+	if t, ok := r.Rows.(driver.RowsColumnTypePrecisionScale); ok {
+		return t.ColumnTypePrecisionScale(index)
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// jsonInlineWidth is the longest JSON value, in compact form, that query
+// results keep on one line; longer ones are pretty-printed.
+const jsonInlineWidth = 40
+
+// maxInferredPaths caps the paths json_schema_infer reports, since objects
+// used as maps (keyed by ids, dates...) produce a path per key.
+const maxInferredPaths = 200
+
+// jsonText renders a JSON column value for query results: compact when
+// short, indented otherwise. Values that do not parse are returned as is.
+func jsonText(b []byte) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, b); err != nil {
+		return string(b)
+	}
+	if compact.Len() <= jsonInlineWidth {
+		return compact.String()
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, compact.Bytes(), "", "  "); err != nil {
+		return compact.String()
+	}
+	return indented.String()
+}
+
+// jsonPathKey renders an object key as a JSON path member, e.g. ".name" or
+// ".\"first name\"". Keys that are not ECMAScript identifiers must be
+// double-quoted in MySQL paths.
+func jsonPathKey(key string) string {
+	plain := key != ""
+	for i, r := range key {
+		if !(r == '_' || r == '$' || unicode.IsLetter(r) || i > 0 && unicode.IsDigit(r)) {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return "." + key
+	}
+	key = strings.ReplaceAll(key, `\`, `\\`)
+	key = strings.ReplaceAll(key, `"`, `\"`)
+	return `."` + key + `"`
+}
+
+// jsonPath builds a MySQL JSON path from segments: strings are object keys,
+// whole numbers are array indexes and "[*]" means every array element.
+func jsonPath(segments []any) (string, error) {
+	path := "$"
+	for i, segment := range segments {
+		switch s := segment.(type) {
+		case string:
+			if s == "[*]" {
+				path += "[*]"
+			} else {
+				path += jsonPathKey(s)
+			}
+		case float64:
+			if s < 0 || s != math.Trunc(s) {
+				return "", fmt.Errorf("path segment %d: array index %v is not a whole number", i, s)
+			}
+			path += fmt.Sprintf("[%d]", int64(s))
+		default:
+			return "", fmt.Errorf("path segment %d: expected a key or an array index, got %v", i, segment)
+		}
+	}
+	return path, nil
+}
+
+// jsonValueType names the type of a value decoded with UseNumber, using
+// the names of JSON Schema.
+func jsonValueType(v any) string {
+	switch val := v.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if strings.ContainsAny(val.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	}
+	return "null"
+}
+
+type JSONSchemaInferParams struct {
+	Database string   `json:"database"`
+	Table    string   `json:"table"`
+	Column   string   `json:"column"`
+	Sample   int      `json:"sample,omitempty"`
+	Filters  []Filter `json:"filters,omitempty"`
+}
+
+// JSONPathInfo describes one path found in the sampled documents. Present
+// counts the documents that have it, and Types how often each value type
+// occurs there.
+type JSONPathInfo struct {
+	Path    string         `json:"path"`
+	Types   map[string]int `json:"types"`
+	Present int            `json:"present"`
+	Example string         `json:"example,omitempty"`
+}
+
+func JSONSchemaInfer(ctx context.Context, req *mcp.CallToolRequest, args JSONSchemaInferParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	sample := args.Sample
+	if sample <= 0 {
+		sample = 500
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	if !slices.ContainsFunc(tableCols, func(c ColumnInfo) bool { return c.ColumnName == args.Column }) {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Column %q does not exist in %s.%s", args.Column, args.Database, args.Table)},
+			},
+		}, nil, nil
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s IS NOT NULL", quoteIdentifier(args.Column), qualifiedTable(args.Database, args.Table), quoteIdentifier(args.Column))
+	var params []any
+	if len(args.Filters) > 0 {
+		where, filterParams, err := buildWhere(args.Filters, tableCols)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid filters: %v", err)},
+				},
+			}, nil, nil
+		}
+		query += " AND " + where
+		params = filterParams
+	}
+	query += fmt.Sprintf(" LIMIT %d", sample)
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to sample %s: %v", args.Column, err)},
+			},
+		}, nil, nil
+	}
+	defer rows.Close()
+
+	paths := map[string]*JSONPathInfo{}
+	var documents, invalid int
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to scan row: %v", err)},
+				},
+			}, nil, nil
+		}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var doc any
+		if err := decoder.Decode(&doc); err != nil {
+			invalid++
+			continue
+		}
+		documents++
+		seen := map[string]bool{}
+		var walk func(path string, v any)
+		walk = func(path string, v any) {
+			info := paths[path]
+			if info == nil {
+				if len(paths) >= maxInferredPaths {
+					return
+				}
+				info = &JSONPathInfo{Path: path, Types: map[string]int{}}
+				paths[path] = info
+			}
+			kind := jsonValueType(v)
+			info.Types[kind]++
+			if !seen[path] {
+				seen[path] = true
+				info.Present++
+			}
+			switch val := v.(type) {
+			case map[string]any:
+				for _, key := range sortedKeys(val) {
+					walk(path+jsonPathKey(key), val[key])
+				}
+			case []any:
+				for _, elem := range val {
+					walk(path+"[*]", elem)
+				}
+			default:
+				if info.Example == "" && kind != "null" {
+					example, _ := json.Marshal(v)
+					info.Example = string(example)
+					if len(info.Example) > jsonInlineWidth {
+						info.Example = info.Example[:jsonInlineWidth] + "..."
+					}
+				}
+			}
+		}
+		walk("$", doc)
+	}
+	if err := rows.Err(); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Row iteration error: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	fields := slices.SortedFunc(maps.Values(paths), func(a, b *JSONPathInfo) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+	resultText := fmt.Sprintf("Sampled %d documents from %s.%s.%s", documents, args.Database, args.Table, args.Column)
+	if invalid > 0 {
+		resultText += fmt.Sprintf(" (%d more are not valid JSON)", invalid)
+	}
+	resultText += fmt.Sprintf(", %d paths:\n\n", len(fields))
+	if len(paths) >= maxInferredPaths {
+		resultText = fmt.Sprintf("Only the first %d paths are listed; some objects are probably used as maps with data-dependent keys.\n", maxInferredPaths) + resultText
+	}
+	tableRows := make([]map[string]any, len(fields))
+	for i, f := range fields {
+		types := slices.SortedFunc(maps.Keys(f.Types), func(a, b string) int {
+			return cmp.Or(cmp.Compare(f.Types[b], f.Types[a]), cmp.Compare(a, b))
+		})
+		tableRows[i] = map[string]any{
+			"path":    f.Path,
+			"types":   strings.Join(types, "|"),
+			"present": fmt.Sprintf("%d%%", f.Present*100/max(documents, 1)),
+			"example": f.Example,
+		}
+	}
+	resultText += formatRowTable([]string{"path", "types", "present", "example"}, tableRows)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"documents": documents,
+		"invalid":   invalid,
+		"paths":     fields,
+	}, nil
+}
+
+// JSONField is a value read from a JSON column by build_json_query. Path
+// is a list of segments as for jsonPath; with JSON_TABLE it is relative to
+// each row and Type is the SQL type of the column.
+type JSONField struct {
+	Path  []any  `json:"path"`
+	Alias string `json:"alias,omitempty"`
+	Type  string `json:"type,omitempty"`
+}
+
+type BuildJSONQueryParams struct {
+	Database string      `json:"database"`
+	Table    string      `json:"table"`
+	Column   string      `json:"column"`
+	Fields   []JSONField `json:"fields"`
+	RowsPath []any       `json:"rows_path,omitempty"`
+	Columns  []string    `json:"columns,omitempty"`
+	Filters  []Filter    `json:"filters,omitempty"`
+	Limit    int         `json:"limit,omitempty"`
+	Execute  bool        `json:"execute,omitempty"`
+}
+
+// jsonTableColumnType matches the column types build_json_query accepts
+// for JSON_TABLE, such as INT, DECIMAL(10,2) or VARCHAR(255).
+var jsonTableColumnType = regexp.MustCompile(`^(?i)[a-z]+( ?\(\d+( ?, ?\d+)?\))?( unsigned)?$`)
+
+func BuildJSONQuery(ctx context.Context, req *mcp.CallToolRequest, args BuildJSONQueryParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	if len(args.Fields) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "At least one field is required"},
+			},
+		}, nil, nil
+	}
+	server := serverInfoFor(ctx, db)
+	jsonTable := len(args.RowsPath) > 0
+	if jsonTable && !server.supportsJSONTable() {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("JSON_TABLE needs MySQL 8.0.4+ or MariaDB 10.6+; the server is %s", server)},
+			},
+		}, nil, nil
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	for _, name := range append([]string{args.Column}, args.Columns...) {
+		if !slices.ContainsFunc(tableCols, func(c ColumnInfo) bool { return c.ColumnName == name }) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Column %q does not exist in %s.%s", name, args.Database, args.Table)},
+				},
+			}, nil, nil
+		}
+	}
+
+	var selected []string
+	for _, name := range args.Columns {
+		selected = append(selected, "t."+quoteIdentifier(name))
+	}
+	var definitions []string
+	for i, f := range args.Fields {
+		path, err := jsonPath(f.Path)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Field %d: %v", i, err)},
+				},
+			}, nil, nil
+		}
+		alias := f.Alias
+		if alias == "" {
+			alias = "value"
+			for _, segment := range slices.Backward(f.Path) {
+				if key, ok := segment.(string); ok && key != "[*]" {
+					alias = key
+					break
+				}
+			}
+		}
+		if !jsonTable {
+			// A wildcard path returns an array, which stays JSON; single
+			// values are unquoted so strings come back without quotes.
+			expr := fmt.Sprintf("JSON_EXTRACT(t.%s, %s)", quoteIdentifier(args.Column), quoteString(path))
+			if !strings.Contains(path, "[*]") {
+				expr = "JSON_UNQUOTE(" + expr + ")"
+			}
+			selected = append(selected, expr+" AS "+quoteIdentifier(alias))
+			continue
+		}
+		columnType := f.Type
+		if columnType == "" {
+			columnType = "VARCHAR(255)"
+		}
+		if !jsonTableColumnType.MatchString(columnType) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Field %d: invalid column type %q", i, f.Type)},
+				},
+			}, nil, nil
+		}
+		definitions = append(definitions, fmt.Sprintf("%s %s PATH %s", quoteIdentifier(alias), strings.ToUpper(columnType), quoteString(path)))
+		selected = append(selected, "jt."+quoteIdentifier(alias))
+	}
+
+	query := "SELECT " + strings.Join(selected, ", ") + " FROM " + qualifiedTable(args.Database, args.Table) + " AS t"
+	if jsonTable {
+		rowsPath, err := jsonPath(args.RowsPath)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("rows_path: %v", err)},
+				},
+			}, nil, nil
+		}
+		query += fmt.Sprintf(", JSON_TABLE(t.%s, %s COLUMNS (%s)) AS jt", quoteIdentifier(args.Column), quoteString(rowsPath), strings.Join(definitions, ", "))
+	}
+	var params []any
+	if len(args.Filters) > 0 {
+		where, filterParams, err := buildWhere(args.Filters, tableCols)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid filters: %v", err)},
+				},
+			}, nil, nil
+		}
+		query += " WHERE " + where
+		params = filterParams
+	}
+	if args.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", args.Limit)
+	}
+
+	if !args.Execute {
+		resultText := query + ";\n"
+		if len(params) > 0 {
+			resultText += fmt.Sprintf("\nParameters: %v\n", params)
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, map[string]any{
+			"query":  query,
+			"params": params,
+		}, nil
+	}
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to execute query: %v\n\n%s", err, query)},
+			},
+		}, nil, nil
+	}
+	defer rows.Close()
+	columns, results, err := scanRowMaps(rows)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to execute query: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("%s\n\nReturned %d rows:\n\n", query, len(results))
+	resultText += formatRowTable(columns, results)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"rows":     results,
+		"rowCount": len(results),
+		"columns":  columns,
+		"query":    query,
+		"params":   params,
+	}, nil
+}
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// lintOffsetLimit is the OFFSET lint_sql flags on any table.
+	lintOffsetLimit = 10000
+	// lintLargeTable is the estimated row count above which any OFFSET is
+	// flagged.
+	lintLargeTable = 1000000
+	// lintSmallTable is the estimated row count below which a WHERE clause
+	// no index serves is not worth flagging.
+	lintSmallTable = 1000
+)
+
+type LintSQLParams struct {
+	SQL string `json:"sql"`
+	// Database resolves unqualified table names; the connection's current
+	// database by default.
+	Database string `json:"database,omitempty"`
+}
+
+// LintFinding is an anti-pattern lint_sql found in a statement.
+type LintFinding struct {
+	// Statement is the 1-based position of the statement in the script.
+	Statement int    `json:"statement"`
+	Rule      string `json:"rule"`
+	// Severity is warning, or info for findings that depend on data or
+	// schema lint_sql could not see.
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// lintTable is a table a query reads, with what lint_sql knows of it.
+type lintTable struct {
+	database, name, alias string
+	// known is false when the table's columns could not be read, as for
+	// common table expressions, derived tables and missing tables.
+	known   bool
+	columns map[string]bool
+	// indexes are the column lists of the table's indexes.
+	indexes [][]string
+	rows    int64
+}
+
+// label names the table as the query does.
+func (t *lintTable) label() string {
+	if t.alias != "" && !strings.EqualFold(t.alias, t.name) {
+		return fmt.Sprintf("%s (%s)", t.name, t.alias)
+	}
+	return t.name
+}
+
+// indexed reports whether column is part of any index, and leads is
+// whether it is the first column of one.
+func (t *lintTable) indexed(column string) (indexed, leads bool) {
+	for _, cols := range t.indexes {
+		for i, c := range cols {
+			if strings.EqualFold(c, column) {
+				indexed = true
+				leads = leads || i == 0
+			}
+		}
+	}
+	return indexed, leads
+}
+
+// sqlLinter collects the findings of the statements of one script.
+type sqlLinter struct {
+	ctx context.Context
+	// db is the connection the schema is read from, nil without one.
+	db        *sql.DB
+	database  string
+	statement int
+	findings  []LintFinding
+	tables    map[[2]string]*lintTable
+}
+
+func (l *sqlLinter) add(rule, severity, format string, args ...any) {
+	l.findings = append(l.findings, LintFinding{Statement: l.statement, Rule: rule, Severity: severity, Message: fmt.Sprintf(format, args...)})
+}
+
+// table returns what the schema says of database.name, reading it once
+// per script. Without a connection nothing is known.
+func (l *sqlLinter) table(database, name string) lintTable {
+	if database == "" {
+		database = l.database
+	}
+	key := [2]string{strings.ToLower(database), strings.ToLower(name)}
+	if t, ok := l.tables[key]; ok {
+		return *t
+	}
+	t := &lintTable{database: database, name: name}
+	l.tables[key] = t
+	if l.db == nil || database == "" {
+		return *t
+	}
+	cols, err := tableColumns(l.ctx, l.db, database, name)
+	if err != nil || len(cols) == 0 {
+		return *t
+	}
+	t.known = true
+	t.columns = make(map[string]bool, len(cols))
+	for _, c := range cols {
+		t.columns[strings.ToLower(c.ColumnName)] = true
+	}
+	indexes, err := tableIndexes(l.ctx, l.db, database, name)
+	if err != nil {
+		slog.Debug("failed to read indexes", "table", database+"."+name, "err", err)
+	}
+	for _, index := range sortedKeys(indexes) {
+		t.indexes = append(t.indexes, indexes[index])
+	}
+	if rows, _, _, err := tableRowEstimate(l.ctx, database, name); err == nil {
+		t.rows = rows
+	}
+	return *t
+}
+
+// tableRef reads the table a FROM list entry or join names, with its
+// alias. Derived tables and table functions give nil.
+func (l *sqlLinter) tableRef(tokens []sqlToken) *lintTable {
+	if len(tokens) == 0 || !isIdentifierToken(tokens[0]) {
+		return nil
+	}
+	database, name := "", identifierText(tokens[0])
+	i := 1
+	if i+1 < len(tokens) && tokens[i].text == "." && isIdentifierToken(tokens[i+1]) {
+		database, name = name, identifierText(tokens[i+1])
+		i += 2
+	}
+	if i < len(tokens) && tokens[i].text == "(" {
+		return nil
+	}
+	t := l.table(database, name)
+	if i < len(tokens) && strings.EqualFold(tokens[i].text, "AS") {
+		i++
+	}
+	if i < len(tokens) && isIdentifierToken(tokens[i]) {
+		t.alias = identifierText(tokens[i])
+	}
+	return &t
+}
+
+// withoutSubqueries returns tokens with the contents of subqueries left
+// out, so that a clause's own predicates can be read.
+func withoutSubqueries(tokens []sqlToken) []sqlToken {
+	var out []sqlToken
+	for i := 0; i < len(tokens); i++ {
+		out = append(out, tokens[i])
+		if tokens[i].text == "(" {
+			if end := matchParen(tokens, i); end > i && tokens[end].text == ")" && isSubquery(tokens[i+1:end]) {
+				out = append(out, tokens[end])
+				i = end
+			}
+		}
+	}
+	return out
+}
+
+// columnRef reads a possibly qualified column name at tokens[i], returning
+// the qualifier, the column and the index after it; ok is false when
+// tokens[i] is not a column.
+func columnRef(tokens []sqlToken, i int) (qualifier, column string, next int, ok bool) {
+	if i >= len(tokens) || !isIdentifierToken(tokens[i]) || i+1 < len(tokens) && tokens[i+1].text == "(" {
+		return "", "", i, false
+	}
+	if i > 0 && tokens[i-1].text == "." {
+		return "", "", i, false
+	}
+	column, next = identifierText(tokens[i]), i+1
+	for next+1 < len(tokens) && tokens[next].text == "." && isIdentifierToken(tokens[next+1]) {
+		qualifier, column = column, identifierText(tokens[next+1])
+		next += 2
+	}
+	return qualifier, column, next, true
+}
+
+// resolve returns the table of tables a column belongs to, or nil when it
+// cannot tell.
+func resolve(tables []*lintTable, qualifier, column string) *lintTable {
+	if qualifier != "" {
+		for _, t := range tables {
+			if strings.EqualFold(t.alias, qualifier) || t.alias == "" && strings.EqualFold(t.name, qualifier) {
+				return t
+			}
+		}
+		return nil
+	}
+	if len(tables) == 1 {
+		return tables[0]
+	}
+	for _, t := range tables {
+		if t.columns[strings.ToLower(column)] {
+			return t
+		}
+	}
+	return nil
+}
+
+// isComparison reports whether t compares two values.
+func isComparison(t sqlToken) bool {
+	switch strings.ToUpper(t.text) {
+	case "=", "<", ">", "<=", ">=", "<>", "!=", "<=>", "LIKE", "IN", "BETWEEN", "REGEXP", "RLIKE":
+		return true
+	}
+	return false
+}
+
+// notFunctions are words followed by a parenthesis that are not function
+// calls wrapping their arguments.
+var notFunctions = wordSet("IN EXISTS AND OR NOT XOR ANY ALL SOME ROW VALUES USING ON AS BETWEEN IS LIKE MATCH AGAINST INTERVAL")
+
+// intervalUnits are the units of INTERVAL expressions and EXTRACT, which
+// read as columns when the schema is unknown.
+var intervalUnits = wordSet("MICROSECOND SECOND MINUTE HOUR DAY WEEK MONTH QUARTER YEAR")
+
+// lint checks one query: a statement, or a subquery's tokens. inExists is
+// set for the subquery of EXISTS, whose select list is not read.
+func (l *sqlLinter) lint(tokens []sqlToken, inExists bool) {
+	// Subqueries, wherever they are, are queries of their own.
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].text != "(" {
+			continue
+		}
+		end := matchParen(tokens, i)
+		if end > i && tokens[end].text == ")" && isSubquery(tokens[i+1:end]) {
+			l.lint(tokens[i+1:end], i > 0 && strings.EqualFold(tokens[i-1].text, "EXISTS"))
+			i = end
+		}
+	}
+
+	// A UNION's queries are linted one at a time.
+	var block []sqlPart
+	for _, part := range splitClauses(tokens) {
+		switch part.name() {
+		case "UNION", "EXCEPT", "INTERSECT":
+			l.block(block, inExists)
+			block = nil
+			continue
+		}
+		block = append(block, part)
+	}
+	l.block(block, inExists)
+}
+
+// block checks the clauses of one query block.
+func (l *sqlLinter) block(parts []sqlPart, inExists bool) {
+	var tables []*lintTable
+	var selectList, where, having, limit []sqlToken
+	for _, part := range parts {
+		body := withoutSubqueries(part.body)
+		switch name := part.name(); name {
+		case "SELECT":
+			selectList = body
+		case "FROM":
+			items := splitTopLevel(body, false)
+			for _, item := range items {
+				if t := l.tableRef(item); t != nil {
+					tables = append(tables, t)
+				}
+			}
+			if len(items) > 1 {
+				severity, detail := "info", "the join condition sits in the WHERE clause, where it is easy to lose"
+				if !containsPart(parts, "WHERE") {
+					severity, detail = "warning", "there is no WHERE clause, so this is a Cartesian product"
+				}
+				l.add("implicit_join", severity, "FROM lists %d tables separated by commas; %s. Use explicit JOIN ... ON.", len(items), detail)
+			}
+		case "UPDATE", "DELETE":
+			for _, item := range splitTopLevel(body, false) {
+				if t := l.tableRef(item); t != nil {
+					tables = append(tables, t)
+				}
+			}
+		case "WHERE":
+			where = body
+		case "HAVING":
+			having = body
+		case "LIMIT":
+			limit = body
+		default:
+			if !strings.HasSuffix(name, "JOIN") {
+				continue
+			}
+			t := l.tableRef(body)
+			if t != nil {
+				tables = append(tables, t)
+			}
+			if strings.HasPrefix(name, "CROSS") || strings.HasPrefix(name, "NATURAL") || containsWord(body, "ON") || containsWord(body, "USING") {
+				continue
+			}
+			target := "a derived table"
+			if t != nil {
+				target = t.label()
+			}
+			l.add("join_without_condition", "warning", "%s with %s has no ON or USING condition, so it joins every row with every row. Add the join condition, or write CROSS JOIN if that is intended.", name, target)
+		}
+	}
+
+	if !inExists {
+		for _, item := range splitTopLevel(selectList, false) {
+			if n := len(item); n > 0 && item[n-1].text == "*" && (n == 1 || n == 3 && item[1].text == ".") {
+				l.add("select_star", "warning", "SELECT %s reads every column: it cannot be served by a covering index, sends unused data and changes shape when the table does. List the columns needed.", joinTokens(item))
+			}
+		}
+	}
+	l.leadingWildcards(where)
+	l.leadingWildcards(having)
+	l.nonSargable(where, tables)
+	l.unindexedWhere(where, tables)
+	l.offset(limit, tables)
+}
+
+// containsPart reports whether a query block has the named clause.
+func containsPart(parts []sqlPart, name string) bool {
+	for _, p := range parts {
+		if p.name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// joinTokens renders tokens as written, with the spacing format_sql uses.
+func joinTokens(tokens []sqlToken) string {
+	var b strings.Builder
+	for i, t := range tokens {
+		if i > 0 && spaceBefore(tokens, i) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.text)
+	}
+	return b.String()
+}
+
+// leadingWildcards flags LIKE patterns that start with a wildcard.
+func (l *sqlLinter) leadingWildcards(tokens []sqlToken) {
+	for i := 0; i+1 < len(tokens); i++ {
+		if !strings.EqualFold(tokens[i].text, "LIKE") || tokens[i+1].kind != tokenQuoted {
+			continue
+		}
+		pattern := tokens[i+1].text
+		if strings.HasPrefix(pattern, "`") || len(pattern) < 2 || pattern[1] != '%' && pattern[1] != '_' {
+			continue
+		}
+		subject := "the column"
+		if i > 0 && tokens[i-1].kind != tokenPunct && !strings.EqualFold(tokens[i-1].text, "NOT") {
+			subject = identifierText(tokens[i-1])
+		} else if i > 1 && strings.EqualFold(tokens[i-1].text, "NOT") {
+			subject = identifierText(tokens[i-2])
+		}
+		l.add("leading_wildcard", "warning", "LIKE %s starts with a wildcard, so no index on %s can narrow the search and every row is scanned. Anchor the pattern at the start, or use a FULLTEXT index for word searches.", pattern, subject)
+	}
+}
+
+// nonSargable flags functions applied to columns in comparisons, which
+// keep the server from using an index on the column.
+func (l *sqlLinter) nonSargable(where []sqlToken, tables []*lintTable) {
+	for i := 0; i+1 < len(where); i++ {
+		t := where[i]
+		if t.kind != tokenWord || where[i+1].text != "(" || notFunctions[strings.ToUpper(t.text)] {
+			continue
+		}
+		end := matchParen(where, i+1)
+		compared := end+1 < len(where) && (isComparison(where[end+1]) || strings.EqualFold(where[end+1].text, "NOT")) ||
+			i > 0 && isComparison(where[i-1])
+		if !compared {
+			continue
+		}
+		for j := i + 2; j < end; {
+			qualifier, column, next, ok := columnRef(where, j)
+			if !ok {
+				j++
+				continue
+			}
+			// The charset of CONVERT(x USING cs) is no column either.
+			afterUsing := strings.EqualFold(where[j-1].text, "USING")
+			j = next
+			call := strings.ToUpper(t.text) + "(" + joinTokens(where[i+2:end]) + ")"
+			table := resolve(tables, qualifier, column)
+			switch {
+			case (table == nil || !table.known) && (intervalUnits[strings.ToUpper(column)] || afterUsing):
+			case table == nil || !table.known:
+				l.add("non_sargable", "info", "%s applies a function to %s, which keeps any index on it from being used. Compare the bare column with a transformed value instead, e.g. a date range rather than YEAR(col) = 2024.", call, column)
+			case !table.columns[strings.ToLower(column)]:
+			default:
+				if indexed, _ := table.indexed(column); indexed {
+					l.add("non_sargable", "warning", "%s applies a function to the indexed column %s.%s, so its index cannot be used. Compare the bare column with a transformed value instead, e.g. a date range rather than YEAR(col) = 2024, or index the expression.", call, table.name, column)
+				}
+			}
+		}
+		i = end
+	}
+}
+
+// unindexedWhere flags tables of some size on which no index starts with a
+// column the WHERE clause filters on. Clauses with a top-level OR are left
+// alone, since each branch would need its own index.
+func (l *sqlLinter) unindexedWhere(where []sqlToken, tables []*lintTable) {
+	if len(where) == 0 {
+		return
+	}
+	conditions := splitTopLevel(where, true)
+	filtered := make(map[*lintTable][]string)
+	var order []*lintTable
+	for _, cond := range conditions {
+		if len(cond) == 0 {
+			continue
+		}
+		switch strings.ToUpper(cond[0].text) {
+		case "AND", "&&":
+			cond = cond[1:]
+		case "OR", "XOR", "||":
+			return
+		}
+		qualifier, column, next, ok := columnRef(cond, 0)
+		if !ok || next >= len(cond) || !isComparison(cond[next]) && !strings.EqualFold(cond[next].text, "IS") {
+			continue
+		}
+		if next+1 < len(cond) && cond[next+1].kind == tokenQuoted && strings.EqualFold(cond[next].text, "LIKE") && len(cond[next+1].text) > 1 && strings.ContainsAny(cond[next+1].text[1:2], "%_") {
+			continue
+		}
+		table := resolve(tables, qualifier, column)
+		if table == nil || !table.known || !table.columns[strings.ToLower(column)] {
+			continue
+		}
+		if _, ok := filtered[table]; !ok {
+			order = append(order, table)
+		}
+		filtered[table] = append(filtered[table], column)
+	}
+	for _, table := range order {
+		if table.rows < lintSmallTable {
+			continue
+		}
+		served := false
+		for _, column := range filtered[table] {
+			if _, leads := table.indexed(column); leads {
+				served = true
+			}
+		}
+		if !served {
+			columns := filtered[table]
+			l.add("unindexed_where", "warning", "No index on %s starts with a column the WHERE clause filters on (%s), so about %d rows are scanned. Consider an index on (%s).", table.label(), strings.Join(columns, ", "), table.rows, strings.Join(columns, ", "))
+		}
+	}
+}
+
+// offset flags OFFSET pagination deep enough, or on a table large enough,
+// that reading and discarding the skipped rows is costly.
+func (l *sqlLinter) offset(limit []sqlToken, tables []*lintTable) {
+	var offset int64 = -1
+	switch {
+	case len(limit) == 3 && limit[1].text == ",":
+		offset, _ = strconv.ParseInt(limit[0].text, 10, 64)
+	case len(limit) == 3 && strings.EqualFold(limit[1].text, "OFFSET"):
+		offset, _ = strconv.ParseInt(limit[2].text, 10, 64)
+	}
+	if offset <= 0 {
+		return
+	}
+	var largest *lintTable
+	for _, t := range tables {
+		if largest == nil || t.rows > largest.rows {
+			largest = t
+		}
+	}
+	switch {
+	case largest != nil && largest.rows >= lintLargeTable:
+		l.add("offset_pagination", "warning", "OFFSET %d makes the server read and discard %d rows for every page of %s (about %d rows), growing with each page. Paginate by key instead: WHERE key > last_seen ORDER BY key LIMIT n.", offset, offset, largest.label(), largest.rows)
+	case offset >= lintOffsetLimit:
+		l.add("offset_pagination", "warning", "OFFSET %d makes the server read and discard %d rows before returning any. Paginate by key instead: WHERE key > last_seen ORDER BY key LIMIT n.", offset, offset)
+	}
+}
+
+// lintSQL lints each statement of a script.
+func lintSQL(ctx context.Context, db *sql.DB, statements []string, database string) []LintFinding {
+	l := &sqlLinter{ctx: ctx, db: db, database: database, tables: make(map[[2]string]*lintTable)}
+	for i, stmt := range statements {
+		l.statement = i + 1
+		var tokens []sqlToken
+		for _, t := range tokenizeSQL(stmt) {
+			if t.kind != tokenComment {
+				tokens = append(tokens, t)
+			}
+		}
+		l.lint(tokens, false)
+	}
+	return l.findings
+}
+
+func LintSQL(ctx context.Context, req *mcp.CallToolRequest, args LintSQLParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	statements := splitSQLStatements(args.SQL)
+	if len(statements) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "No SQL to lint"},
+			},
+		}, nil, nil
+	}
+
+	database := args.Database
+	if database == "" && db != nil {
+		var current sql.NullString
+		if err := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&current); err == nil {
+			database = current.String
+		}
+	}
+	findings := lintSQL(ctx, db, statements, database)
+
+	var b strings.Builder
+	warnings := 0
+	for _, f := range findings {
+		if f.Severity == "warning" {
+			warnings++
+		}
+	}
+	if len(findings) == 0 {
+		fmt.Fprintf(&b, "No issues found in %d statement(s).\n", len(statements))
+	} else {
+		fmt.Fprintf(&b, "%d issue(s) found in %d statement(s), %d warning(s):\n", len(findings), len(statements), warnings)
+		for _, f := range findings {
+			fmt.Fprintf(&b, "  Statement %d: %s [%s] %s\n", f.Statement, f.Severity, f.Rule, f.Message)
+		}
+	}
+	if db == nil {
+		b.WriteString("Not connected, so checks that need the schema (indexed columns, index coverage and table sizes) were skipped.\n")
+	} else if database == "" {
+		b.WriteString("No database selected, so unqualified tables could not be checked against the schema.\n")
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: b.String()},
+		},
+	}, map[string]any{
+		"statements": len(statements),
+		"warnings":   warnings,
+		"findings":   findings,
+		"connected":  db != nil,
+	}, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestLintSQLOffline(t *testing.T) {
+	tests := []struct {
+		sql   string
+		rules []string
+	}{
+		{"SELECT * FROM t", []string{"select_star"}},
+		{"SELECT a FROM t WHERE name LIKE '%x'", []string{"leading_wildcard"}},
+		{"SELECT a FROM t WHERE name LIKE 'x%'", nil},
+		{"SELECT a FROM t ORDER BY a LIMIT 10 OFFSET 50000", []string{"offset_pagination"}},
+		{"SELECT a FROM t ORDER BY a LIMIT 50000, 10", []string{"offset_pagination"}},
+		{"SELECT a FROM t ORDER BY a LIMIT 10 OFFSET 20", nil},
+		{"SELECT a FROM t, u", []string{"implicit_join"}},
+		{"SELECT a FROM t JOIN u", []string{"join_without_condition"}},
+		{"SELECT a FROM t JOIN u ON t.id = u.id", nil},
+		{"SELECT a FROM t WHERE YEAR(created) = 2020", []string{"non_sargable"}},
+		{"SELECT a FROM t WHERE created >= '2020-01-01'", nil},
+		// A comment is not code.
+		{"SELECT a FROM t /* SELECT * FROM u */", nil},
+	}
+	for _, tt := range tests {
+		var rules []string
+		for _, f := range lintSQL(context.Background(), nil, []string{tt.sql}, "") {
+			if f.Statement != 1 {
+				t.Errorf("lintSQL(%q) reported statement %d", tt.sql, f.Statement)
+			}
+			rules = append(rules, f.Rule)
+		}
+		if !slices.Equal(rules, tt.rules) {
+			t.Errorf("lintSQL(%q) = %v, want %v", tt.sql, rules, tt.rules)
+		}
+	}
+}
+
+func TestLintSQLNumbersStatements(t *testing.T) {
+	findings := lintSQL(context.Background(), nil, []string{"SELECT a FROM t", "SELECT * FROM u"}, "")
+	if len(findings) != 1 || findings[0].Statement != 2 || findings[0].Rule != "select_star" {
+		t.Errorf("lintSQL() = %+v, want select_star in statement 2", findings)
+	}
+}
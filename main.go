@@ -1,22 +1,18 @@
 package main
 
 import (
-	"archive/tar"
-	"compress/gzip"
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
-	"log"
-	"net/http"
+	"log/slog"
+	"math"
 	"os"
-	"path/filepath"
-	"runtime"
 	"strings"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -24,24 +20,45 @@ var (
 	version = "1.0.0"
 	commit  = "dev"
 	date    = "unknown"
-	db      *sql.DB
 )
 
 type ConnectParams struct {
-	DSN string `json:"dsn"`
+	DSN             string `json:"dsn"`
+	Name            string `json:"name,omitempty"`
+	TimeZone        string `json:"time_zone,omitempty"`
+	DisplayTimeZone string `json:"display_time_zone,omitempty"`
+	// ResourceGroup and ThreadPriority replace -resource-group and
+	// -thread-priority for this connection and, once it succeeds, those
+	// opened after it.
+	ResourceGroup  string `json:"resource_group,omitempty"`
+	ThreadPriority *int   `json:"thread_priority,omitempty"`
 }
 
 type ListTablesParams struct {
-	Database string `json:"database"`
+	// Database defaults to the one chosen with use_database.
+	Database string `json:"database,omitempty"`
 }
 
 type DescribeTableParams struct {
-	Database string `json:"database"`
+	Database string `json:"database,omitempty"`
 	Table    string `json:"table"`
 }
 
 type ExecuteQueryParams struct {
-	Query string `json:"query"`
+	Query     string `json:"query"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
+	MaxChars  int    `json:"max_chars,omitempty"`
+	Summarize bool   `json:"summarize,omitempty"`
+	// Sample asks for about this many random rows instead of the whole
+	// result, drawn by SampleMethod.
+	Sample       int    `json:"sample,omitempty"`
+	SampleMethod string `json:"sample_method,omitempty"`
+	// Format renders numbers and dates in the result's text for reading;
+	// structured content keeps the raw values.
+	Format ResultFormat `json:"format,omitempty"`
+	// Hints are optimizer hints added to a SELECT, e.g.
+	// MAX_EXECUTION_TIME(5000), on top of the config file's hints.
+	Hints []string `json:"hints,omitempty"`
 }
 
 type DatabaseInfo struct {
@@ -63,7 +80,56 @@ type ColumnInfo struct {
 }
 
 func Connect(ctx context.Context, req *mcp.CallToolRequest, args ConnectParams) (*mcp.CallToolResult, any, error) {
-	database, err := sql.Open("mysql", args.DSN)
+	cfg, err := mysql.ParseDSN(args.DSN)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to open database: %v", err)},
+			},
+		}, nil, nil
+	}
+	var display *time.Location
+	if args.DisplayTimeZone != "" {
+		if display, err = parseTimeZone(args.DisplayTimeZone); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid display_time_zone: %v", err)},
+				},
+			}, nil, nil
+		}
+	}
+	switch {
+	case args.TimeZone != "":
+		setConnectionTimeZone(cfg, args.TimeZone)
+	case connectionTimeZone != "" && cfg.Params["time_zone"] == "":
+		setConnectionTimeZone(cfg, connectionTimeZone)
+	}
+	// The group is only made the one later pools join once this
+	// connection has succeeded.
+	group := agentResourceGroup.Load()
+	if args.ResourceGroup != "" || args.ThreadPriority != nil {
+		if args.ResourceGroup != "" {
+			if err := checkIdentifier("resource group", args.ResourceGroup); err != nil {
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("Invalid resource_group: %v", err)},
+					},
+				}, nil, nil
+			}
+		}
+		if group, err = newResourceGroupSetting(args.ResourceGroup, args.ThreadPriority); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid thread_priority: %v", err)},
+				},
+			}, nil, nil
+		}
+	}
+	database, err := openDatabase(cfg, group)
 	if err != nil {
 		return &mcp.CallToolResult{
 			IsError: true,
@@ -73,7 +139,8 @@ func Connect(ctx context.Context, req *mcp.CallToolRequest, args ConnectParams)
 		}, nil, nil
 	}
 
-	if err := database.Ping(); err != nil {
+	if err := database.PingContext(ctx); err != nil {
+		closePool(database)
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
@@ -81,16 +148,48 @@ func Connect(ctx context.Context, req *mcp.CallToolRequest, args ConnectParams)
 			},
 		}, nil, nil
 	}
+	if display != nil {
+		displayTimeZone.Store(display)
+	}
+	if args.ResourceGroup != "" || args.ThreadPriority != nil {
+		agentResourceGroup.Store(group)
+	}
+
+	server := serverInfoFor(ctx, database)
+	var limitations string
+	if server.Proxy != "" {
+		limitations = fmt.Sprintf("\nConnected through %s; statements relying on session state (temporary tables, user variables, locks) may not behave as on a direct connection.", server.Proxy)
+	}
+	if missing := server.missingFeatures(); len(missing) > 0 {
+		limitations += fmt.Sprintf("\nNot available on this server: %s. See server_capabilities.", strings.Join(missing, ", "))
+	}
+	limitations += resourceGroupNote(ctx, database, group)
+
+	if args.Name != "" && args.Name != defaultConnectionName {
+		registerConnection(args.Name, database)
+		slog.Info("connected to MySQL", "connection", args.Name, "dsn", redactDSN(args.DSN), "server", server.String())
+		recordConnection(args.Name, cfg.User, cfg.Addr)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Successfully connected to %s database as connection %q", server, args.Name) + limitations},
+			},
+		}, nil, nil
+	}
 
-	db = database
+	recordSQLMode(ctx, database)
+	setDefaultConnection(database, cfg)
+	clearSchemaCache()
+	slog.Info("connected to MySQL", "dsn", redactDSN(args.DSN), "server", server.String())
+	recordConnection(defaultConnectionName, cfg.User, cfg.Addr)
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
-			&mcp.TextContent{Text: "Successfully connected to MySQL database"},
+			&mcp.TextContent{Text: fmt.Sprintf("Successfully connected to %s database", server) + limitations},
 		},
 	}, nil, nil
 }
 
 func ListDatabases(ctx context.Context, req *mcp.CallToolRequest, args struct{}) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
 	if db == nil {
 		return &mcp.CallToolResult{
 			IsError: true,
@@ -147,6 +246,7 @@ func ListDatabases(ctx context.Context, req *mcp.CallToolRequest, args struct{})
 }
 
 func ListTables(ctx context.Context, req *mcp.CallToolRequest, args ListTablesParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
 	if db == nil {
 		return &mcp.CallToolResult{
 			IsError: true,
@@ -155,6 +255,16 @@ func ListTables(ctx context.Context, req *mcp.CallToolRequest, args ListTablesPa
 			},
 		}, nil, nil
 	}
+	database, err := contextDatabase(ctx, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	args.Database = database
 
 	query := `
 		SELECT TABLE_NAME, TABLE_TYPE, TABLE_SCHEMA
@@ -208,6 +318,7 @@ func ListTables(ctx context.Context, req *mcp.CallToolRequest, args ListTablesPa
 }
 
 func DescribeTable(ctx context.Context, req *mcp.CallToolRequest, args DescribeTableParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
 	if db == nil {
 		return &mcp.CallToolResult{
 			IsError: true,
@@ -216,9 +327,19 @@ func DescribeTable(ctx context.Context, req *mcp.CallToolRequest, args DescribeT
 			},
 		}, nil, nil
 	}
+	database, err := contextDatabase(ctx, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	args.Database = database
 
 	query := `
-		SELECT COLUMN_NAME, DATA_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA
+		SELECT COLUMN_NAME, IF(DATA_TYPE = 'vector', COLUMN_TYPE, DATA_TYPE), IS_NULLABLE, COLUMN_DEFAULT, EXTRA
 		FROM information_schema.COLUMNS
 		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
 		ORDER BY ORDINAL_POSITION
@@ -257,6 +378,9 @@ func DescribeTable(ctx context.Context, req *mcp.CallToolRequest, args DescribeT
 		}, nil, nil
 	}
 
+	if len(columns) > 0 {
+		touchTable(args.Database, args.Table)
+	}
 	result := fmt.Sprintf("Table '%s.%s' has %d columns:\n\n", args.Database, args.Table, len(columns))
 	result += fmt.Sprintf("%-20s %-15s %-10s %-15s %s\n", "Column", "Type", "Nullable", "Default", "Extra")
 	result += strings.Repeat("-", 80) + "\n"
@@ -278,6 +402,7 @@ func DescribeTable(ctx context.Context, req *mcp.CallToolRequest, args DescribeT
 }
 
 func ExecuteQuery(ctx context.Context, req *mcp.CallToolRequest, args ExecuteQueryParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
 	if db == nil {
 		return &mcp.CallToolResult{
 			IsError: true,
@@ -297,22 +422,174 @@ func ExecuteQuery(ctx context.Context, req *mcp.CallToolRequest, args ExecuteQue
 		}, nil, nil
 	}
 
+	if hint := unsupportedSyntax(serverInfoFor(ctx, db), query); hint != "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: hint},
+			},
+		}, nil, nil
+	}
+
+	if args.Summarize && args.Sample > 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Use either summarize or sample, not both"},
+			},
+		}, nil, nil
+	}
+	formatted, err := args.Format.resolve()
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid format: %v", err)},
+			},
+		}, nil, nil
+	}
+	if !formatted {
+		args.Format = ResultFormat{}
+	}
+
+	hints, err := parseOptimizerHints(args.Hints)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid hints: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	if blocked := enforceCostPolicy(ctx, req, "execute_query", db, query); blocked != nil {
+		return blocked, nil, nil
+	}
+
+	done, err := awaitQueryTurn(ctx, req)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Cancelled while waiting for earlier queries: %v", err)},
+			},
+		}, nil, nil
+	}
+	defer done()
+
+	if args.Summarize {
+		return summarizeResult(ctx, db, query)
+	}
+	if args.Sample > 0 {
+		return sampleResult(ctx, db, query, args.Sample, args.SampleMethod)
+	}
+
+	// The call's hints come first, so they win over the config file's.
+	result, structured, err := executeQuery(ctx, db, query, args.Format, append(hints, config.hints...))
+	if budget := resultBudget(args.MaxTokens, args.MaxChars); err == nil && !result.IsError && budget > 0 {
+		result, structured = shapeResult(result, structured, query, budget)
+	}
+	if err == nil && !result.IsError {
+		touchQueryObjects(ctx, db, query)
+	}
+	if server := serverInfoFor(ctx, db); err == nil && server.Vitess {
+		annotateVitessResult(ctx, query, result, structured)
+	} else if err == nil && server.Proxy != "" && !result.IsError {
+		annotateProxyResult(server, query, result, structured)
+	}
+	return result, structured, err
+}
+
+// executeQuery runs a non-empty statement for execute_query on db, checking
+// it against read-only mode first. Hints are added to SELECT statements.
+func executeQuery(ctx context.Context, db *sql.DB, query string, format ResultFormat, hints []optimizerHint) (*mcp.CallToolResult, any, error) {
 	upperQuery := strings.ToUpper(query)
 	isSelect := strings.HasPrefix(upperQuery, "SELECT") ||
 		strings.HasPrefix(upperQuery, "SHOW") ||
 		strings.HasPrefix(upperQuery, "DESCRIBE") ||
-		strings.HasPrefix(upperQuery, "EXPLAIN")
+		strings.HasPrefix(upperQuery, "EXPLAIN") ||
+		(strings.HasPrefix(upperQuery, "WITH") && isWithSelect(query))
+
+	// MariaDB statements that change data but return rows: DML with
+	// RETURNING, and SELECTs that advance a sequence.
+	if hasReturningClause(query) || (isSelect && advancesSequence(query)) {
+		if err := checkWritable("execute_query"); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("%v (the statement changes data or a sequence)", err)},
+				},
+			}, nil, nil
+		}
+		if server := serverInfoFor(ctx, db); hasReturningClause(query) && !server.supportsReturning(query) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("This server (%s) does not support RETURNING here: it needs MariaDB 10.5+ (10.0.5+ for DELETE)", server)},
+				},
+			}, nil, nil
+		}
+		return executeSelectQuery(ctx, db, query, true, format)
+	}
 
 	if isSelect {
-		return executeSelectQuery(ctx, query)
+		if readOnly {
+			// A read can still write a file (INTO OUTFILE), lock rows or
+			// be followed by other statements.
+			if err := checkReadQuery(query); err != nil {
+				recordPolicyBlock("execute_query")
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("execute_query is running in read-only mode: %v", err)},
+					},
+				}, nil, nil
+			}
+		}
+		hinted, skipped := injectHints(serverInfoFor(ctx, db), query, hints)
+		result, structured, err := executeSelectQuery(ctx, db, hinted, false, format)
+		if len(skipped) > 0 && err == nil && !result.IsError {
+			names := make([]string, len(skipped))
+			for i, h := range skipped {
+				names[i] = h.String()
+			}
+			if text, ok := result.Content[0].(*mcp.TextContent); ok {
+				text.Text += fmt.Sprintf("\n\nNote: hints not applied, as this server has no equivalent: %s", strings.Join(names, ", "))
+			}
+			if m, ok := structured.(map[string]any); ok {
+				m["skippedHints"] = names
+			}
+		}
+		return result, structured, err
 	} else {
-		return executeModifyQuery(ctx, query)
+		if err := checkWritable("execute_query"); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("%v (only SELECT, SHOW, DESCRIBE and EXPLAIN are allowed)", err)},
+				},
+			}, nil, nil
+		}
+		return executeModifyQuery(ctx, db, query)
 	}
 }
 
-func executeSelectQuery(ctx context.Context, query string) (*mcp.CallToolResult, any, error) {
-	rows, err := db.QueryContext(ctx, query)
+// executeSelectQuery runs a statement that returns rows. With audited set,
+// the statement changes data too and is written to the audit log with the
+// number of rows it returned.
+func executeSelectQuery(ctx context.Context, db *sql.DB, query string, audited bool, format ResultFormat) (*mcp.CallToolResult, any, error) {
+	var rows *sql.Rows
+	var err error
+	done := func() { rows.Close() }
+	if readOnly && !audited {
+		rows, done, err = queryReadOnly(ctx, db, query)
+	} else {
+		rows, err = db.QueryContext(ctx, query)
+	}
 	if err != nil {
+		if audited {
+			audit("execute_query", query, 0, err)
+		}
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
@@ -320,7 +597,7 @@ func executeSelectQuery(ctx context.Context, query string) (*mcp.CallToolResult,
 			},
 		}, nil, nil
 	}
-	defer rows.Close()
+	defer done()
 
 	columns, err := rows.Columns()
 	if err != nil {
@@ -332,6 +609,7 @@ func executeSelectQuery(ctx context.Context, query string) (*mcp.CallToolResult,
 		}, nil, nil
 	}
 
+	kinds := resultColumnKinds(rows, len(columns))
 	var results []map[string]any
 	for rows.Next() {
 		values := make([]any, len(columns))
@@ -353,7 +631,7 @@ func executeSelectQuery(ctx context.Context, query string) (*mcp.CallToolResult,
 		for i, col := range columns {
 			val := values[i]
 			if b, ok := val.([]byte); ok {
-				row[col] = string(b)
+				row[col] = displayBytes(kinds[i], b)
 			} else {
 				row[col] = val
 			}
@@ -362,6 +640,9 @@ func executeSelectQuery(ctx context.Context, query string) (*mcp.CallToolResult,
 	}
 
 	if err := rows.Err(); err != nil {
+		if audited {
+			audit("execute_query", query, int64(len(results)), err)
+		}
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
@@ -370,50 +651,56 @@ func executeSelectQuery(ctx context.Context, query string) (*mcp.CallToolResult,
 		}, nil, nil
 	}
 
-	resultText := fmt.Sprintf("Query executed successfully. Returned %d rows:\n\n", len(results))
+	if audited {
+		audit("execute_query", query, int64(len(results)), nil)
+	}
 
+	structured := map[string]any{
+		"rows":     results,
+		"rowCount": len(results),
+		"columns":  columns,
+	}
+	var zones string
 	if len(results) > 0 {
-		for i, col := range columns {
-			resultText += fmt.Sprintf("%-20s", col)
-			if i < len(columns)-1 {
-				resultText += " | "
-			}
-		}
-		resultText += "\n" + strings.Repeat("-", len(columns)*23) + "\n"
-
-		for _, row := range results {
-			for i, col := range columns {
-				val := row[col]
-				if val == nil {
-					val = "NULL"
-				}
-				resultText += fmt.Sprintf("%-20v", val)
-				if i < len(columns)-1 {
-					resultText += " | "
-				}
-			}
-			resultText += "\n"
+		if note, ok := applyTimeZones(ctx, columns, kinds, results); ok {
+			zones = "\n" + note.Text() + "\n"
+			structured["timeZones"] = note
 		}
 	}
 
+	resultText := fmt.Sprintf("Query executed successfully. Returned %d rows:\n\n", len(results))
+
+	if len(results) > 0 && isTiDBPlan(columns) && serverInfoFor(ctx, db).TiDB {
+		resultText += formatTiDBPlan(columns, results)
+	} else if format != (ResultFormat{}) {
+		resultText += formatRowTable(columns, format.apply(columns, kinds, results))
+	} else {
+		resultText += formatRowTable(columns, results)
+	}
+	resultText += zones
+
 	return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{Text: resultText},
-			},
-		}, map[string]any{
-			"rows":     results,
-			"rowCount": len(results),
-			"columns":  columns,
-		}, nil
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, structured, nil
 }
 
-func executeModifyQuery(ctx context.Context, query string) (*mcp.CallToolResult, any, error) {
-	result, err := db.ExecContext(ctx, query)
+func executeModifyQuery(ctx context.Context, db *sql.DB, query string) (*mcp.CallToolResult, any, error) {
+	// A script is not run again, since its earlier statements may have
+	// been committed before the one that failed.
+	var exec execer = db
+	var retries int
+	if len(splitSQLStatements(query)) == 1 {
+		exec = retryingExecer{execer: db, retries: &retries}
+	}
+	result, err := exec.ExecContext(ctx, query)
 	if err != nil {
+		audit("execute_query", query, 0, err)
 		return &mcp.CallToolResult{
 			IsError: true,
 			Content: []mcp.Content{
-				&mcp.TextContent{Text: fmt.Sprintf("Failed to execute query: %v", err)},
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to execute query: %v", err) + retryNote(retries)},
 			},
 		}, nil, nil
 	}
@@ -428,6 +715,11 @@ func executeModifyQuery(ctx context.Context, query string) (*mcp.CallToolResult,
 		}, nil, nil
 	}
 
+	audit("execute_query", query, rowsAffected, nil)
+	if isDDL(query) {
+		clearSchemaCache()
+	}
+
 	lastInsertId, err := result.LastInsertId()
 	if err != nil {
 		lastInsertId = -1
@@ -437,194 +729,176 @@ func executeModifyQuery(ctx context.Context, query string) (*mcp.CallToolResult,
 	if lastInsertId != -1 {
 		resultText += fmt.Sprintf("\nLast insert ID: %d", lastInsertId)
 	}
+	resultText += retryNote(retries)
 
+	structured := map[string]any{
+		"rowsAffected": rowsAffected,
+		"lastInsertId": lastInsertId,
+	}
+	if retries > 0 {
+		structured["retries"] = retries
+	}
 	return &mcp.CallToolResult{
-			Content: []mcp.Content{
-				&mcp.TextContent{Text: resultText},
-			},
-		}, map[string]any{
-			"rowsAffected": rowsAffected,
-			"lastInsertId": lastInsertId,
-		}, nil
-}
-
-type GitHubRelease struct {
-	TagName string `json:"tag_name"`
-	Assets  []struct {
-		Name               string `json:"name"`
-		BrowserDownloadURL string `json:"browser_download_url"`
-	} `json:"assets"`
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, structured, nil
 }
 
-func updateSelf() error {
-	fmt.Println("Checking for updates...")
-
-	// Get latest release from GitHub API
-	resp, err := http.Get("https://api.github.com/repos/josiah-hester/mysql-mcp/releases/latest")
-	if err != nil {
-		return fmt.Errorf("failed to check for updates: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to get release info: HTTP %d", resp.StatusCode)
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "install" {
+		if err := runInstall(os.Args[2:]); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Install failed: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	var release GitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return fmt.Errorf("failed to parse release info: %w", err)
-	}
+	dsn := flag.String("dsn", "", "MySQL DSN (e.g., user:password@tcp(localhost:3306)/database)")
+	versionFlag := flag.Bool("version", false, "Print version information")
+	var updateFlag updateTarget
+	flag.Var(&updateFlag, "update", "Update to the latest version from GitHub, or to a given release with -update=v1.2.3")
+	listReleasesFlag := flag.Bool("list-releases", false, "List the releases available to -update")
+	flag.BoolVar(&allowUnsignedUpdate, "allow-unsigned", false, "Let -update install a release without a signature, such as one published before releases were signed, checking only its SHA-256 checksum")
+	flag.BoolVar(&offline, "offline", false, "Make no outbound connections except to MySQL: disables -update, the update check and trace export")
+	flag.BoolVar(&noUpdateCheck, "no-update-check", false, "Do not check for a newer release at startup or in -version")
+	flag.StringVar(&updateChannel, "update-channel", updateChannel, "Releases -update installs: stable, or prerelease to include prereleases")
+	flag.BoolVar(&readOnly, "read-only", false, "Reject all statements and tools that modify data or schema")
+	configPath := flag.String("config", "", "JSON config file (seed environments)")
+	auditLogPath := flag.String("audit-log", "", "Append every data- or schema-modifying statement to this file as JSON lines")
+	flag.StringVar(&migrationsDir, "migrations-dir", migrationsDir, "Directory of versioned .sql migration files")
+	flag.StringVar(&migrationsFormat, "migrations-format", migrationsFormat, "Migration version table format: auto, native, golang-migrate or goose")
+	flag.StringVar(&snapshotsDir, "snapshots-dir", snapshotsDir, "Directory schema snapshots are saved in")
+	flag.StringVar(&driftBaseline, "drift-baseline", "", "Baseline schema snapshot file used by check_drift")
+	flag.DurationVar(&driftInterval, "drift-interval", 0, "Check the default connection against -drift-baseline this often in the background (e.g. 15m)")
+	flag.IntVar(&confirmThreshold, "confirm-threshold", confirmThreshold, "Number of rows update_rows may change without confirm: true")
+	flag.Int64Var(&queryCostPolicy.MaxRowsExamined, "max-rows-examined", 0, "Block queries EXPLAIN expects to examine more rows than this unless the user approves (0: no limit)")
+	flag.Int64Var(&queryCostPolicy.MaxFullScanRows, "max-full-scan-rows", 0, "Block queries that fully scan a table of at least this many rows unless the user approves (0: no limit)")
+	flag.BoolVar(&queryCostPolicy.RequirePartitionPruning, "require-partition-pruning", false, "Block queries that read every partition of a partitioned table unless the user approves")
+	flag.StringVar(&logLevel, "log-level", logLevel, "Log level: debug, info, warn or error")
+	flag.StringVar(&logFormat, "log-format", logFormat, "Log format: text or json")
+	flag.StringVar(&logFile, "log-file", "", "Write logs to this file instead of stderr")
+	flag.IntVar(&logMaxSizeMB, "log-max-size", logMaxSizeMB, "Rotate the log file when it reaches this many megabytes")
+	flag.IntVar(&logMaxBackups, "log-max-backups", logMaxBackups, "Number of rotated log files to keep")
+	debugSQLFlag := flag.Bool("debug-sql", false, "Log every SQL statement with its parameters, duration and row count (can be toggled with set_debug_sql)")
+	flag.StringVar(&debugSQLParams, "debug-sql-params", debugSQLParams, "How -debug-sql logs literals and parameters: redact, truncate or full")
+	flag.StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics at /metrics and health checks at /healthz and /readyz on this address (e.g. :9090)")
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", shutdownTimeout, "On SIGINT or SIGTERM, wait this long for running tool calls before killing their queries")
+	flag.StringVar(&schedulesFile, "schedules-file", schedulesFile, "File scheduled queries and their stored results are kept in")
+	flag.StringVar(&workspaceFile, "workspace-file", workspaceFile, "File bookmarks are kept in")
+	flag.StringVar(&workspaceProfile, "profile", workspaceProfile, "Workspace profile whose bookmarks bookmark and workspace use")
+	flag.StringVar(&connectionTimeZone, "time-zone", "", "Session time_zone for connections whose DSN sets none (e.g. +00:00 or Europe/Berlin)")
+	resourceGroupFlag := flag.String("resource-group", "", "Run every session in this MySQL resource group so agent queries can be throttled against application traffic")
+	var threadPriority *int
+	flag.Func("thread-priority", "Run sessions at this thread priority, 0 (normal) to 19 (lowest), creating or altering the -resource-group (default "+managedResourceGroup+") to match; needs RESOURCE_GROUP_ADMIN", threadPriorityFlag(&threadPriority))
+	displayTimeZoneFlag := flag.String("display-time-zone", "", "Show TIMESTAMP values in execute_query results in this time zone instead of the session's (e.g. UTC or America/New_York)")
+	flag.IntVar(&transientRetries, "transient-retries", transientRetries, "Times a write that hit a deadlock or lock wait timeout is run again (0: never)")
+	flag.DurationVar(&retryBackoff, "retry-backoff", retryBackoff, "Wait before the first retry of a deadlocked write; each later retry waits twice as long")
+	flag.UintVar(&cdcServerID, "cdc-server-id", 0, "server_id cdc_subscribe's binlog stream registers with; must be unique among the server and its replicas (0: random)")
+	flag.BoolVar(&serializeQueries, "serialize-queries", false, "Run each client session's execute_query calls one at a time instead of in parallel on the connection pool")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "", "Export traces over OTLP/HTTP to this collector URL (e.g. http://localhost:4318)")
+	flag.Parse()
 
-	if release.TagName == "v"+version {
-		fmt.Printf("Already up to date (version %s)\n", version)
-		return nil
+	if err := setupLogging(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to set up logging: %v\n", err)
+		os.Exit(1)
 	}
 
-	fmt.Printf("Found newer version: %s (current: %s)\n", release.TagName, version)
-
-	// Find the correct asset for current OS and architecture
-	osName := runtime.GOOS
-	if osName == "darwin" {
-		osName = "Darwin"
-	} else if osName == "linux" {
-		osName = "Linux"
-	} else if osName == "windows" {
-		osName = "Windows"
+	if err := validateDebugSQLParams(); err != nil {
+		fatal("invalid flag", "err", err)
 	}
-
-	archName := runtime.GOARCH
-	if archName == "amd64" {
-		archName = "x86_64"
+	debugSQL.Store(*debugSQLFlag)
+	group, err := newResourceGroupSetting(*resourceGroupFlag, threadPriority)
+	if err != nil {
+		fatal("invalid flag", "err", err)
 	}
-
-	var downloadURL string
-	expectedName := fmt.Sprintf("mysql-mcp_%s_%s", osName, archName)
-
-	for _, asset := range release.Assets {
-		if strings.Contains(asset.Name, expectedName) {
-			downloadURL = asset.BrowserDownloadURL
-			break
+	agentResourceGroup.Store(group)
+	if *displayTimeZoneFlag != "" {
+		loc, err := parseTimeZone(*displayTimeZoneFlag)
+		if err != nil {
+			fatal("invalid flag", "err", err)
 		}
+		displayTimeZone.Store(loc)
 	}
-
-	if downloadURL == "" {
-		return fmt.Errorf("no compatible release found for %s %s", osName, archName)
+	if err := validateUpdateChannel(); err != nil {
+		fatal("invalid flag", "err", err)
 	}
-
-	fmt.Printf("Downloading %s...\n", downloadURL)
-
-	// Download the release
-	resp, err = http.Get(downloadURL)
-	if err != nil {
-		return fmt.Errorf("failed to download update: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download update: HTTP %d", resp.StatusCode)
+	if cdcServerID > math.MaxUint32 {
+		fatal("invalid flag", "err", fmt.Errorf("-cdc-server-id %d is larger than %d", cdcServerID, uint64(math.MaxUint32)))
 	}
 
-	// Get current executable path
-	currentExe, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get current executable path: %w", err)
+	// The config file is read before anything else so that its offline
+	// setting applies to -version and the updater too.
+	if *configPath != "" {
+		if err := loadConfig(*configPath); err != nil {
+			fatal("failed to load config", "path", *configPath, "err", err)
+		}
 	}
-
-	// Create temporary file for the new binary
-	tempFile := currentExe + ".new"
-
-	// Extract and save the new binary
-	if err := extractBinary(resp.Body, tempFile); err != nil {
-		return fmt.Errorf("failed to extract update: %w", err)
+	if config.Offline {
+		offline = true
 	}
-
-	// Make the new binary executable
-	if err := os.Chmod(tempFile, 0755); err != nil {
-		os.Remove(tempFile)
-		return fmt.Errorf("failed to make new binary executable: %w", err)
+	queryCostPolicy.merge(config.CostPolicy)
+	if offline {
+		noUpdateCheck = true
 	}
 
-	// Replace the current binary
-	if err := os.Rename(tempFile, currentExe); err != nil {
-		os.Remove(tempFile)
-		return fmt.Errorf("failed to replace binary: %w", err)
-	}
+	removeOldExecutable()
 
-	fmt.Printf("Successfully updated to version %s\n", release.TagName)
-	fmt.Println("Please restart the application to use the new version.")
-
-	return nil
-}
-
-func extractBinary(src io.Reader, destPath string) error {
-	// Create destination file
-	destFile, err := os.Create(destPath)
-	if err != nil {
-		return err
+	if *versionFlag {
+		fmt.Printf("mysql-mcp-server version %s\n", version)
+		fmt.Printf("Commit: %s\n", commit)
+		fmt.Printf("Built: %s\n", date)
+		if !noUpdateCheck {
+			if tag, err := newerRelease(context.Background()); err == nil && tag != "" {
+				fmt.Printf("Update available: %s (install it with -update)\n", tag)
+			}
+		}
+		return
 	}
-	defer destFile.Close()
 
-	// Check if it's a gzipped tar archive
-	gzReader, err := gzip.NewReader(src)
-	if err != nil {
-		// If it's not gzipped, assume it's a raw binary and copy directly
-		_, copyErr := io.Copy(destFile, src)
-		return copyErr
+	if offline && (*listReleasesFlag || updateFlag != "") {
+		fatal("-update and -list-releases are not available in offline mode")
 	}
-	defer gzReader.Close()
-
-	tarReader := tar.NewReader(gzReader)
 
-	// Find the binary file in the tar archive
-	for {
-		header, err := tarReader.Next()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return err
+	if *listReleasesFlag {
+		if err := listReleases(); err != nil {
+			fatal("failed to list releases", "err", err)
 		}
+		return
+	}
 
-		// Look for the binary (usually the file without extension or with .exe)
-		filename := filepath.Base(header.Name)
-		if strings.HasPrefix(filename, "mysql-mcp") && header.Typeflag == tar.TypeReg {
-			// Copy the binary content
-			_, err := io.Copy(destFile, tarReader)
-			return err
+	if updateFlag != "" {
+		if err := updateSelf(string(updateFlag)); err != nil {
+			fatal("update failed", "err", err)
 		}
+		return
 	}
 
-	return fmt.Errorf("binary not found in archive")
-}
-
-func main() {
-	dsn := flag.String("dsn", "", "MySQL DSN (e.g., user:password@tcp(localhost:3306)/database)")
-	versionFlag := flag.Bool("version", false, "Print version information")
-	updateFlag := flag.Bool("update", false, "Update to the latest version from GitHub")
-	flag.Parse()
-
-	if *versionFlag {
-		fmt.Printf("mysql-mcp-server version %s\n", version)
-		fmt.Printf("Commit: %s\n", commit)
-		fmt.Printf("Built: %s\n", date)
-		return
+	shutdownTracing, err := setupTracing(context.Background())
+	if err != nil {
+		fatal("failed to set up tracing", "err", err)
 	}
 
-	if *updateFlag {
-		if err := updateSelf(); err != nil {
-			log.Fatalf("Update failed: %v", err)
+	if *auditLogPath != "" {
+		if err := openAuditLog(*auditLogPath); err != nil {
+			fatal("failed to open audit log", "path", *auditLogPath, "err", err)
 		}
-		return
 	}
 
 	server := mcp.NewServer(&mcp.Implementation{
 		Name:    "mysql-mcp-server",
 		Version: "1.0.0",
-	}, nil)
+	}, &mcp.ServerOptions{
+		CompletionHandler: CompleteArgument,
+	})
+	server.AddReceivingMiddleware(clientIdentityMiddleware, updateNoticeMiddleware(server), activityMiddleware, toolMetricsMiddleware, toolTracingMiddleware, shutdownMiddleware, connectionMiddleware, recoverMiddleware)
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "connect",
-		Description: "Connect to MySQL database using DSN (e.g., user:password@tcp(localhost:3306)/)",
+		Description: "Connect to MySQL database using DSN (e.g., user:password@tcp(localhost:3306)/). With name, the connection is kept as an additional named connection (e.g. \"staging\") for tools that compare servers, and the default connection is unchanged. time_zone sets the session time zone; display_time_zone shows TIMESTAMP values in execute_query results in another zone",
 	}, Connect)
 
 	mcp.AddTool(server, &mcp.Tool{
@@ -634,35 +908,577 @@ func main() {
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "list_tables",
-		Description: "List all tables in a specific database",
+		Description: "List all tables in a database, by default the one chosen with use_database",
 	}, ListTables)
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "describe_table",
-		Description: "Describe the structure of a specific table",
+		Description: "Describe the structure of a specific table; database defaults to the one chosen with use_database",
 	}, DescribeTable)
 
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "execute_query",
-		Description: "Execute a SQL query (SELECT queries return data, other queries return affected row count)",
+		Description: "Execute a SQL query (SELECT queries return data, other queries return affected row count). With max_tokens or max_chars, a larger result is summarized as column statistics and first and last rows, with advice on paging. With summarize, a SELECT returns statistics of its result computed on the server instead of rows. With sample, a single-table SELECT returns about that many random rows, drawn by primary key seeks or a pre-sized random filter instead of ORDER BY RAND(). When the server sets a cost policy, statements whose EXPLAIN exceeds it are refused unless the user approves them. hints adds optimizer hints such as MAX_EXECUTION_TIME(5000) or SET_VAR(...) to a SELECT",
 	}, ExecuteQuery)
 
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "export_query",
+		Description: "Run a SELECT query and stream the full result to a file (format: csv, jsonl, or arrow for Arrow IPC/Feather files readable by pyarrow, polars and R; compress: gzip to shrink large extracts)",
+	}, ExportQuery)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "insert_rows",
+		Description: "Insert rows given as JSON objects (column -> value) into a table. Columns are validated against the table schema and rows are written with batched parameterized INSERTs; per-row errors are reported. Set atomic to make the load all-or-nothing",
+	}, InsertRows)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "upsert_rows",
+		Description: "Insert or update rows given as JSON objects, matching existing rows on key_columns (which must form a PRIMARY or UNIQUE index). mode 'update' (default) generates INSERT ... ON DUPLICATE KEY UPDATE; mode 'replace' uses REPLACE INTO",
+	}, UpsertRows)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "update_rows",
+		Description: "Update rows matching a key (map of column -> value) with a map of column changes using a parameterized UPDATE. Reports the matched row count; if more rows match than the confirmation threshold, nothing is changed until the call is repeated with confirm: true and expected_count set to the previewed count",
+	}, UpdateRows)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "delete_rows",
+		Description: "Delete rows matching structured filters ({column, op, value}; ops: =, !=, <, <=, >, >=, LIKE, NOT LIKE, IN, NOT IN, BETWEEN, IS NULL, IS NOT NULL). Without confirm it only returns the matching count and sample rows; deleting requires confirm: true plus expected_count equal to the previewed count",
+	}, DeleteRows)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "create_database",
+		Description: "Create a database with optional charset and collation",
+	}, CreateDatabase)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "drop_database",
+		Description: "Drop a database. confirm_name must repeat the database name exactly, and non-empty databases are refused unless force is true. System schemas can never be dropped",
+	}, DropDatabase)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "create_table",
+		Description: "Create a table from a structured definition (columns with type, nullability, defaults, auto_increment; primary key; indexes; foreign keys). Generates correctly quoted MySQL DDL; set dry_run to only return the DDL",
+	}, CreateTable)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "add_column",
+		Description: "Add a column to a table (same column spec as create_table, optional after/first). Without confirm it previews the DDL, the expected ALGORITHM/LOCK behaviour and a duration estimate from the table size; pass algorithm/lock to make MySQL refuse a more disruptive plan",
+	}, AddColumn)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "add_index",
+		Description: "Add a btree, unique, fulltext or spatial index. Without confirm it previews the DDL, locking implications and a duration estimate; set confirm: true to run it",
+	}, AddIndex)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "modify_column",
+		Description: "Change a column's definition with MODIFY COLUMN using a full column spec. Without confirm it previews the DDL, warns about table rebuilds and estimates the duration; set confirm: true to run it",
+	}, ModifyColumn)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "truncate_tables",
+		Description: "Empty a set of tables (or every table in a database) in foreign-key-safe order: children first, with DELETE for tables other tables still reference. Alternatively set disable_foreign_key_checks to TRUNCATE everything with FOREIGN_KEY_CHECKS=0. Returns the plan unless confirm is true",
+	}, TruncateTables)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "rename_table",
+		Description: "Rename one or more tables in a single atomic RENAME TABLE statement, including moving tables between databases on the same server. Returns the statement as a preview unless confirm is true",
+	}, RenameTable)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "create_user",
+		Description: "Create a MySQL account ('user'@'host', host defaults to %). The password is never echoed or written to the audit log. Returns a preview unless confirm is true",
+	}, CreateUser)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "drop_user",
+		Description: "Drop a MySQL account. root and mysql.* accounts are refused. Returns a preview unless confirm is true",
+	}, DropUser)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "grant",
+		Description: "Grant privileges to an account from a structured spec: privileges (e.g. SELECT, INSERT, ALL), database and table (default * for all), optional columns and grant_option. Returns the GRANT statement as a preview unless confirm is true",
+	}, Grant)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "revoke",
+		Description: "Revoke privileges from an account using the same spec as grant; grant_option also revokes GRANT OPTION. Returns the REVOKE statement as a preview unless confirm is true",
+	}, Revoke)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "audit_privileges",
+		Description: "Enumerate all accounts and report risky settings and grants as prioritized findings: anonymous users, empty passwords, SUPER/FILE and other powerful global privileges, GRANT OPTION, privileges on the mysql schema and accounts reachable from any host (%)",
+	}, AuditPrivileges)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "rotate_password",
+		Description: "Rotate an account's password, either generating a strong random one (source: generate) or asking the user for it via elicitation (source: elicit). retain_current keeps the old password valid (MySQL 8.0.14+ dual passwords) until a later call with discard_old: true. Returns a preview unless confirm is true",
+	}, RotatePassword)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "online_alter",
+		Description: "Alter a large table without blocking writes by driving gh-ost or pt-online-schema-change (whichever is installed). action: check (default) validates prerequisites (keys, triggers, foreign keys, binlog settings) and runs the tool's dry run; start (with confirm) copies the table, streaming progress notifications; with gh-ost the cut-over waits for action: cut_over with confirm. status and abort manage a running migration",
+	}, OnlineAlter)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "ddl_progress",
+		Description: "Report running ALTER TABLE / CREATE INDEX operations with percent complete and estimated remaining time from performance_schema stage events. Set enable_instrumentation to turn on the required stage/innodb/alter% instruments for future operations",
+	}, GetDDLProgress)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "kill_idle_connections",
+		Description: "List client connections sleeping for at least idle_minutes (optionally filtered by user, host and database) along with current connection usage, and kill them all when confirm is true. System and replication threads are never included",
+	}, KillIdleConnections)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "connection_status",
+		Description: "Show the current connection: server address and version, authenticated user, default database, read-only mode, pool usage and the active session settings (sql_mode, time_zone, max_execution_time, transaction isolation, ...)",
+	}, ConnectionStatus)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_session_variables",
+		Description: "Show session variables of the MCP connection (default: sql_mode, time_zone, max_execution_time, transaction_isolation, autocommit, character set and collation)",
+	}, GetSessionVariables)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "set_session_variables",
+		Description: "Set session variables (e.g. {\"sql_mode\": \"TRADITIONAL\", \"time_zone\": \"+00:00\", \"max_execution_time\": 5000}) for every connection the server uses from now on; null restores the server default",
+	}, SetSessionVariables)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "use_database",
+		Description: "Set the default database for the rest of the session, so list_tables, describe_table and other tools can leave out database and queries can use unqualified table names",
+	}, UseDatabase)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "migrate_status",
+		Description: "List versioned migrations (<version>_<name>.up.sql / .down.sql files, or goose <version>_<name>.sql files, in the migrations directory) with their state: applied, pending, dirty or missing file. Existing golang-migrate and goose version tables are detected and used as-is",
+	}, MigrateStatus)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "migrate_up",
+		Description: "Apply pending migrations in version order (all, or the next steps). Each migration is marked dirty while it runs so a partial failure is detected; dry_run lists what would run",
+	}, MigrateUp)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "migrate_down",
+		Description: "Roll back the most recently applied migrations (default 1 step) using their .down.sql files. Returns the list as a preview unless confirm is true",
+	}, MigrateDown)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "diff_schemas",
+		Description: "Compare tables, columns, indexes, foreign keys, check constraints, views, routines and triggers between two databases, optionally on different named connections, and return a structured diff",
+	}, DiffSchemas)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "generate_migration",
+		Description: "Generate the CREATE/ALTER/DROP statements that bring a target database in line with a source database (see diff_schemas). Destructive statements are listed separately, and nothing is executed",
+	}, GenerateMigration)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "snapshot_schema",
+		Description: "Save a named snapshot of a database's full schema (tables, indexes, constraints, views, routines, triggers) as JSON plus equivalent DDL in the snapshots directory",
+	}, SnapshotSchema)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_snapshots",
+		Description: "List saved schema snapshots, newest first, optionally for one database",
+	}, ListSnapshots)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "diff_snapshot",
+		Description: "Compare the live schema against a saved snapshot to show what changed since it was taken",
+	}, DiffSnapshot)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "check_drift",
+		Description: "Compare the live schema against a committed baseline snapshot file and report drift, e.g. hot patches applied directly to production. Drift is also written to the audit log",
+	}, CheckDrift)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "load_seed",
+		Description: "Load the seed data (SQL scripts and CSV files) configured for an environment in the -config file. Sources already loaded with the same checksum are skipped and a manifest of loaded sources is kept in the seed_manifest table, so the tool can be re-run safely",
+	}, LoadSeed)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "capture_fixture",
+		Description: "Extract a referentially consistent subset of a database into a fixture file: the rows of a root table matching filters, every row they reference through foreign keys, and (unless skip_children) the rows that reference them. Writes a SQL script of INSERTs (default) or JSON",
+	}, CaptureFixture)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "find_duplicates",
+		Description: "Find rows that share the same values in candidate key columns, reporting duplicate groups with counts and sample rows, plus a keep-one DELETE statement for review (never executed)",
+	}, FindDuplicates)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "find_orphans",
+		Description: "Check foreign key relationships (declared, and optionally inferred from column names like customer_id) for orphaned child rows whose parent row is missing, with counts and sample rows per relationship",
+	}, FindOrphans)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "data_quality",
+		Description: "Profile the columns of a table for data quality: NULL rates, distinct counts, empty strings, leading/trailing whitespace, zero and out-of-range dates, and string columns holding numbers or dates. Large tables are sampled",
+	}, DataQuality)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "validate_constraints",
+		Description: "Re-validate CHECK constraints and foreign keys against the existing rows, e.g. after a bulk load with checks disabled, and report the violating rows of each constraint",
+	}, ValidateConstraints)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "detect_anomalies",
+		Description: "Aggregate a table per hour, day or week of a time column (row count, or sum/avg of a numeric column) and flag buckets that deviate from the preceding buckets or the same weekday/hour in earlier periods, without exporting the rows",
+	}, DetectAnomalies)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "anonymize_table",
+		Description: "Sanitize a production copy by anonymizing columns in place with per-column strategies (hash, shuffle, null, fake name/first_name/last_name/email/phone), from the arguments or the config file. Runs batched updates with progress; previews unless confirm is true",
+	}, AnonymizeTable)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "generate_data",
+		Description: "Insert N realistic fake rows into a table for load testing or demo environments. Values follow column types and names (email, name, phone, city, dates, enums...), foreign keys point at existing parent rows, and the same seed gives the same rows",
+	}, GenerateData)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "diff_data",
+		Description: "Compare the rows of two tables (on the same or different connections) by primary key and report rows inserted, deleted or changed in the target, with column-level differences. Results are limited and pageable with after",
+	}, DiffData)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "verify_copy",
+		Description: "Verify a table copy by comparing row counts and CRC32 checksums of primary key chunks on the source and target (same or different connections), e.g. after a data copy or replication repair, and report the mismatching chunks",
+	}, VerifyCopy)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "server_stats",
+		Description: "Show per-tool call counts, error rates and latency (total, average, p50/p90/p99, max) since the server started, busiest tools first. Optionally filter to one tool or reset the statistics",
+	}, ServerStats)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "session_summary",
+		Description: "Summarize this session as a closing report: connections made, tool calls, statements run by category (read, write, DDL, other), rows read and written, tables touched, and tools blocked by read-only mode",
+	}, SessionSummary)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "set_debug_sql",
+		Description: "Turn SQL debug logging on or off. While on, the server log gets every statement with its parameters (redacted according to -debug-sql-params), duration and row count, to troubleshoot unexpected query behaviour",
+	}, SetDebugSQL)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_sequences",
+		Description: "List the sequences in a MariaDB (10.3+) or TiDB (4.0+) database with their increment, range, cache size and cycle setting, and on MariaDB their next value, without advancing them",
+	}, ListSequences)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tidb_ddl_jobs",
+		Description: "TiDB only: list running, queued and recent DDL jobs (ADMIN SHOW DDL JOBS) with their schema state and rows processed, optionally for one database; cancel lists job IDs to roll back",
+	}, TiDBDDLJobs)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tidb_regions",
+		Description: "TiDB only: show read and write hot spots from PD and, for a table, its region count, size, leader distribution across TiKV stores and busiest regions",
+	}, TiDBRegions)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "vitess_vschema",
+		Description: "Vitess/PlanetScale only: list keyspaces and whether they are sharded, the tables in a keyspace's VSchema, and a table's vindexes and sharding key. Use it before querying sharded tables, so queries filter on the sharding key instead of scattering across all shards",
+	}, VitessVSchema)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "vector_search",
+		Description: "Find the rows nearest to a query vector in a VECTOR column (MySQL 9 HeatWave/Enterprise DISTANCE(), MariaDB 11.7+, TiDB 8.4+), by cosine or euclidean distance, optionally filtered; returns the rows with their distance and the SQL used",
+	}, VectorSearch)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "spatial_search",
+		Description: "Find rows whose spatial column intersects, lies within, contains or is within a distance of a geometry given as WKT or GeoJSON; returns a GeoJSON FeatureCollection (or rows with WKT) and the SQL used",
+	}, SpatialSearch)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "json_schema_infer",
+		Description: "Sample a JSON column and report the structure of its documents: every path, the value types found there, how many documents have it and an example value",
+	}, JSONSchemaInfer)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "build_json_query",
+		Description: "Build (and optionally run) a SELECT reading fields from a JSON column with JSON_EXTRACT, or unnesting an array into rows with JSON_TABLE, from paths given as lists of keys and indexes so keys are quoted correctly",
+	}, BuildJSONQuery)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "fulltext_search",
+		Description: "Search text columns through a table's FULLTEXT index with MATCH ... AGAINST (natural language, boolean or query expansion mode), returning rows ranked by relevance score. Use it instead of LIKE '%...%', which scans the whole table",
+	}, FulltextSearch)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "server_capabilities",
+		Description: "Show the server flavor and version (MySQL 5.7/8.0/8.4, MariaDB, Aurora, TiDB, Vitess) and which version-dependent SQL features it has: CTEs, window functions, enforced CHECK constraints, invisible indexes, JSON_TABLE, EXPLAIN ANALYZE and more. Check it before writing SQL that needs MySQL 8.0",
+	}, ServerCapabilities)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "proxysql_stats",
+		Description: "Read ProxySQL's admin interface through a named connection (port 6032): backend servers per hostgroup with connection pool usage, errors, queries and latency; active query rules with their hit counts; and connection multiplexing counters",
+	}, ProxySQLStats)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "mysql_router_status",
+		Description: "Query MySQL Router's REST API for its routes: whether each is alive, active and total connections, blocked hosts and destination servers",
+	}, MySQLRouterStatus)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "aurora_replica_status",
+		Description: "Aurora MySQL only: list the cluster's instances with their writer/reader role, replica lag and CPU from information_schema.replica_host_status, plus the writer, reader and instance endpoints. Use it instead of SHOW REPLICA STATUS, which says nothing about Aurora replicas",
+	}, AuroraReplicaStatus)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "aurora_failover_history",
+		Description: "Aurora MySQL only: list the cluster's failover events of the last 14 days from the RDS API (through the AWS CLI)",
+	}, AuroraFailoverHistory)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "aurora_digest_export",
+		Description: "Aurora MySQL only: export the top statement digests by total latency from performance_schema to CSV or JSONL, keyed by instance and digest so they line up with Performance Insights' top SQL",
+	}, AuroraDigestExport)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "dolt_log",
+		Description: "Dolt only: show the commit history of a database, a branch or commit, or the commits that changed one table",
+	}, DoltLog)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "dolt_diff",
+		Description: "Dolt only: show which tables changed between two commits, branches or the working set, or the changed rows of one table with their old and new values",
+	}, DoltDiff)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "dolt_branch",
+		Description: "Dolt only: list branches with their latest commit and the current one, or create or delete a branch",
+	}, DoltBranch)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "dolt_checkout",
+		Description: "Dolt only: switch the connection to a branch by reconnecting to its revision database (database/branch), so queries read and write that branch",
+	}, DoltCheckout)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "percona_query_digest",
+		Description: "Run Percona Toolkit's pt-query-digest on the slow query log and return the heaviest query classes with calls, total, average and p95 time and rows examined (requires pt-query-digest on the PATH)",
+	}, PerconaQueryDigest)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "percona_duplicate_keys",
+		Description: "Run Percona Toolkit's pt-duplicate-key-checker and return the redundant indexes with the reason, the overlapping definitions and the ALTER TABLE that drops each one, for review (never executed; requires pt-duplicate-key-checker on the PATH)",
+	}, PerconaDuplicateKeys)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "cdc_subscribe",
+		Description: "Subscribe to row changes (inserts, updates, deletes) on tables of a database by reading the binlog as a replica, optionally filtered on column values. Changes arrive as log notifications and are kept for cdc_poll",
+	}, CDCSubscribe)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "cdc_poll",
+		Description: "Read and remove the pending row changes of a cdc_subscribe subscription, optionally waiting for the next one; without id, list the subscriptions",
+	}, CDCPoll)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "cdc_unsubscribe",
+		Description: "End a cdc_subscribe subscription; the binlog stream stops with the last one",
+	}, CDCUnsubscribe)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "tail_table",
+		Description: "Return the most recent rows of a table, ordered by its auto-increment key or a timestamp column (detected automatically). With follow, later calls return only rows added since the previous one, optionally waiting for them, like tail -f",
+	}, TailTable)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "schedule_query",
+		Description: "Register a read-only query to run on a cron schedule (e.g. \"0 8 * * mon-fri\" or @hourly) while the server runs, storing its result, exporting it to a file or sending it to clients as a notification. An existing name is replaced",
+	}, ScheduleQuery)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_scheduled_queries",
+		Description: "List the scheduled queries with their schedule, action, next run and the outcome of the last run",
+	}, ListScheduledQueries)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "scheduled_query_results",
+		Description: "Show the results stored by the latest runs of a scheduled query, or their errors",
+	}, ScheduledQueryResults)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "unschedule_query",
+		Description: "Remove a scheduled query and its stored results",
+	}, UnscheduleQuery)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "watch_query",
+		Description: "Re-run a read-only query on an interval for a bounded time and report when its result changes. Stops early when the result changes, stays unchanged for a number of runs (e.g. a migration's row count stops increasing) or a row matches a condition. Changes are sent as progress notifications",
+	}, WatchQuery)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "federated_query",
+		Description: "Run a read-only query on each of several connections (e.g. prod and staging) and combine the results in the server: a union with a column naming each row's source, or a hash join on key columns (inner, left or full). Rows read per source are capped",
+	}, FederatedQuery)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "build_select",
+		Description: "Build and run a SELECT from structured JSON instead of SQL text: table, columns, joins, filters, aggregates, group_by, having, order_by and limit. Tables and columns are checked against the schema and values are passed as parameters. dry_run returns the SQL without running it",
+	}, BuildSelect)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "schema_context",
+		Description: "Summarize a database compactly for use as model context: one line per table with its approximate row count, columns, primary key and foreign key references, shortened to fit a token budget. Cheaper than dumping DDL",
+	}, SchemaContext)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "count_estimate",
+		Description: "Estimate a table's row count instantly from table statistics instead of running COUNT(*), and the rows matching structured filters from EXPLAIN. Optionally refresh the statistics with ANALYZE TABLE (not in read-only mode), or run an exact COUNT(*) that gives up after a timeout",
+	}, CountEstimate)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "diagnose_charset",
+		Description: "Check a table's text columns for mojibake: compare each column's declared charset with the bytes actually stored, detect double-encoded UTF-8 and UTF-8 stored in latin1 columns, and generate the ALTER and UPDATE statements that repair them. The statements are returned for review, never executed",
+	}, DiagnoseCharset)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "format_sql",
+		Description: "Pretty-print MySQL SQL for review: a clause per line, long select lists and conditions broken one item per line, subqueries indented, and keywords in upper or lower case. Works without a connection and does not run the SQL",
+	}, FormatSQL)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "validate_sql",
+		Description: "Check SQL without running it: each statement is prepared by the server (PREPARE, never executed), reporting syntax errors with their line and column and unknown tables or columns, and the tables it references are checked against the schema",
+	}, ValidateSQL)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "lint_sql",
+		Description: "Flag common SQL anti-patterns without running the SQL: SELECT *, implicit cross joins and joins without a condition, functions on indexed columns that defeat their index, LIKE patterns starting with a wildcard, deep OFFSET pagination or OFFSET on huge tables, and WHERE clauses no index serves. Schema checks use the connection when there is one",
+	}, LintSQL)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "compare_explain",
+		Description: "Compare the EXPLAIN plans of a query before and after a proposed change: a rewritten query, new indexes, or both. Indexes are tried on sampled copies of the tables in a temporary sandbox schema, never on the real tables. Reports per-table changes in access type, key, row estimates and Extra flags, the estimated cost where the server gives one, and both plans side by side",
+	}, CompareExplain)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "visualize_plan",
+		Description: "Draw a query's EXPLAIN plan as an annotated ASCII tree or a Mermaid flowchart, showing join order, access types, keys, row estimates and full table scans at a glance. The drawing is also returned as a resource (mysql://query-plan/{n}, .mmd for Mermaid). The query is not run",
+	}, VisualizePlan)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "compare_query_results",
+		Description: "Run one read-only query on two connections at once (e.g. primary and replica, or before and after a migration) and report the rows only one of them returns and, when key_columns identify rows, the columns that differ. Row order is ignored",
+	}, CompareQueryResults)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "bookmark",
+		Description: "Bookmark a table (database and table) or a query (name and query) with a note, kept across sessions in the server's workspace profile. Bookmarking the same name again updates it",
+	}, BookmarkObject)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "remove_bookmark",
+		Description: "Remove a bookmark from the workspace profile",
+	}, RemoveBookmark)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "workspace",
+		Description: "List the bookmarked tables and queries of the workspace profile with their notes, and whether each table still exists and its estimated size. Call it at the start of a session to pick up earlier investigations instead of exploring the schema again",
+	}, Workspace)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "generate_model",
+		Description: "Generate a Go struct for a table, with db and json tags and Go types that scan its columns, nullable ones as database/sql Null types or pointers. orm: gorm adds gorm tags and a TableName method; orm: sqlc adds annotated CRUD queries for sqlc. lang: typescript, zod or json_schema instead writes a TypeScript interface, a Zod schema or a JSON Schema document for the table's rows, with ENUM columns as unions of their members",
+	}, GenerateModel)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "generate_openapi",
+		Description: "Generate an OpenAPI 3.1 document with CRUD endpoints for tables of a database (all base tables by default): list and create on /table, get, update and delete by primary key on /table/{key}, and lookups by single-column unique keys, with request and response schemas derived from the columns",
+	}, GenerateOpenAPI)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "generate_graphql",
+		Description: "Generate a GraphQL schema (SDL) for tables of a database (all base tables by default): an object type per table with a field per column, enums for ENUM columns, fields in both directions for each foreign key, and a Query type listing rows and looking them up by primary key. type_names and field_names choose the naming conventions",
+	}, GenerateGraphQL)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "execute_as",
+		Description: "Run a read-only query as another MySQL account configured under accounts in the config file, on the default connection's server, to check what that account can and cannot see. A missing privilege is reported as Denied with the server's error rather than as a failure; allowed queries return their rows with the account's grants",
+	}, ExecuteAs)
+
+	server.AddResource(&mcp.Resource{
+		Name:        "schema-context",
+		URI:         schemaContextURIPrefix,
+		Description: "Compact summary of the current database's tables, keys, relationships and sizes",
+		MIMEType:    "text/plain",
+	}, ReadSchemaContext)
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "schema-context-database",
+		URITemplate: schemaContextURIPrefix + "{database}",
+		Description: "Compact summary of a database's tables, keys, relationships and sizes",
+		MIMEType:    "text/plain",
+	}, ReadSchemaContext)
+
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "query-plan",
+		URITemplate: queryPlanURIPrefix + "{plan}",
+		Description: "A query plan drawn by visualize_plan: an ASCII tree, or a Mermaid flowchart when the name ends in .mmd. The last 20 plans are kept",
+		MIMEType:    "text/plain",
+	}, ReadQueryPlan)
+
 	// Auto-connect if DSN is provided
 	if *dsn != "" {
-		database, err := sql.Open("mysql", *dsn)
+		cfg, err := mysql.ParseDSN(*dsn)
 		if err != nil {
-			log.Fatalf("Failed to open database: %v", err)
+			fatal("failed to parse DSN", "err", err)
+		}
+		if connectionTimeZone != "" && cfg.Params["time_zone"] == "" {
+			setConnectionTimeZone(cfg, connectionTimeZone)
+		}
+		database, err := openDatabase(cfg, agentResourceGroup.Load())
+		if err != nil {
+			fatal("failed to open database", "dsn", redactDSN(*dsn), "err", err)
 		}
 
 		if err := database.Ping(); err != nil {
-			log.Fatalf("Failed to ping database: %v", err)
+			fatal("failed to ping database", "dsn", redactDSN(*dsn), "err", err)
 		}
 
-		db = database
-		log.Printf("Successfully connected to MySQL database with DSN: %s", *dsn)
+		recordSQLMode(context.Background(), database)
+		setDefaultConnection(database, cfg)
+		slog.Info("connected to MySQL", "dsn", redactDSN(*dsn))
+		recordConnection(defaultConnectionName, cfg.User, cfg.Addr)
+	}
+
+	if metricsAddr != "" {
+		go serveMetrics(metricsAddr)
+	}
+
+	if !noUpdateCheck {
+		go checkForUpdate(server)
 	}
 
-	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
-		log.Printf("Server failed: %v", err)
+	if driftBaseline != "" && driftInterval > 0 {
+		go monitorDrift(server, driftBaseline, driftInterval)
+	}
+
+	if err := loadWorkspace(); err != nil {
+		fatal("failed to load workspace", "file", workspaceFile, "err", err)
+	}
+	if err := loadScheduledQueries(); err != nil {
+		fatal("failed to load scheduled queries", "file", schedulesFile, "err", err)
+	}
+	go runScheduler(server)
+
+	ctx, stop := context.WithCancel(context.Background())
+	go handleShutdownSignals(stop)
+	if err := server.Run(ctx, &mcp.StdioTransport{}); err != nil && ctx.Err() == nil {
+		slog.Error("server failed", "err", err)
+	}
+	closeDatabases()
+	closeAuditLog()
+	if err := shutdownTracing(context.Background()); err != nil {
+		slog.Error("failed to flush traces", "err", err)
 	}
 }
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// metricsAddr is the address the Prometheus /metrics endpoint and the
+// health checks listen on, set with -metrics-addr. Nothing is served when it
+// is empty.
+var metricsAddr string
+
+// durationBuckets are the histogram upper bounds, in seconds, for tool and
+// statement durations.
+var durationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+var (
+	toolCallsTotal = newCounterVec("mysql_mcp_tool_calls_total",
+		"Tool calls by tool and status (ok or error).", "tool", "status")
+	toolDuration = newHistogramVec("mysql_mcp_tool_duration_seconds",
+		"Tool call duration.", "tool")
+	queryDuration = newHistogramVec("mysql_mcp_query_duration_seconds",
+		"SQL statement duration, including fetching the rows of queries.", "kind")
+	rowsReturnedTotal = newCounterVec("mysql_mcp_rows_returned_total",
+		"Rows read from query results.")
+	errorsTotal = newCounterVec("mysql_mcp_errors_total",
+		"Errors by type: tool_result, protocol, panic, canceled, timeout, connection, mysql_<code> or other.", "type")
+	schemaCacheRequests = newCounterVec("mysql_mcp_schema_cache_requests_total",
+		"Schema cache lookups by result (hit or miss).", "result")
+)
+
+// counterVec is a counter with a fixed set of labels, keyed by the joined
+// label values.
+type counterVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]float64
+}
+
+func newCounterVec(name, help string, labels ...string) *counterVec {
+	return &counterVec{name: name, help: help, labels: labels, values: make(map[string]float64)}
+}
+
+func (c *counterVec) add(delta float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	c.mu.Lock()
+	c.values[key] += delta
+	c.mu.Unlock()
+}
+
+func (c *counterVec) inc(labelValues ...string) {
+	c.add(1, labelValues...)
+}
+
+func (c *counterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, key := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, key, "", ""), formatFloat(c.values[key]))
+	}
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// histogramVec is a histogram over durationBuckets with a fixed set of
+// labels.
+type histogramVec struct {
+	name, help string
+	labels     []string
+	mu         sync.Mutex
+	values     map[string]*histogram
+}
+
+func newHistogramVec(name, help string, labels ...string) *histogramVec {
+	return &histogramVec{name: name, help: help, labels: labels, values: make(map[string]*histogram)}
+}
+
+func (h *histogramVec) observe(v float64, labelValues ...string) {
+	key := strings.Join(labelValues, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hist, ok := h.values[key]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(durationBuckets))}
+		h.values[key] = hist
+	}
+	for i, bound := range durationBuckets {
+		if v <= bound {
+			hist.counts[i]++
+		}
+	}
+	hist.sum += v
+	hist.count++
+}
+
+func (h *histogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for _, key := range sortedKeys(h.values) {
+		hist := h.values[key]
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "le", formatFloat(bound)), hist.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(h.labels, key, "le", "+Inf"), hist.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(h.labels, key, "", ""), formatFloat(hist.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(h.labels, key, "", ""), hist.count)
+	}
+}
+
+// labelEscaper escapes a label value for the Prometheus text format, which
+// knows only these three escapes (Go's %q would add others).
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels renders the label set for key, with an optional extra label
+// (the histogram bucket's le) appended.
+func formatLabels(names []string, key, extraName, extraValue string) string {
+	var pairs []string
+	if len(names) > 0 {
+		for i, value := range strings.Split(key, "\xff") {
+			pairs = append(pairs, names[i]+`="`+labelEscaper.Replace(value)+`"`)
+		}
+	}
+	if extraName != "" {
+		pairs = append(pairs, extraName+`="`+labelEscaper.Replace(extraValue)+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// trackStatement records a SQL statement that started at start, in the
+// metrics, as a span under ctx's tool call and in the SQL debug log. The
+// returned function completes it with the number of rows read (for a query)
+// or affected (for an exec) and the statement's error.
+func trackStatement(ctx context.Context, kind, query string, args []driver.NamedValue, start time.Time) func(rows int64, err error) {
+	span := startStatementSpan(ctx, kind, query, start)
+	return func(rows int64, err error) {
+		elapsed := time.Since(start)
+		queryDuration.observe(elapsed.Seconds(), kind)
+		read := int64(0)
+		if kind == "query" {
+			read = rows
+		}
+		if read > 0 {
+			rowsReturnedTotal.add(float64(read))
+		}
+		if err != nil {
+			errorsTotal.inc(errorType(err))
+		}
+		recordStatementActivity(query, read)
+		endStatementSpan(span, kind, rows, err)
+		if debugSQL.Load() {
+			logStatement(kind, query, args, elapsed, rows, err)
+		}
+	}
+}
+
+// errorType classifies a statement error for mysql_mcp_errors_total.
+func errorType(err error) string {
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, mysql.ErrInvalidConn):
+		return "connection"
+	case errors.As(err, &mysqlErr):
+		return fmt.Sprintf("mysql_%d", mysqlErr.Number)
+	}
+	return "other"
+}
+
+// toolMetricsMiddleware records the count, status and duration of every
+// tools/call request, for /metrics and server_stats.
+func toolMetricsMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if method != "tools/call" || !ok {
+			return next(ctx, method, req)
+		}
+		start := time.Now()
+		result, err := next(ctx, method, req)
+		status := "ok"
+		if err != nil {
+			status = "error"
+			errorsTotal.inc("protocol")
+		} else if r, ok := result.(*mcp.CallToolResult); ok && r.IsError {
+			status = "error"
+			errorsTotal.inc("tool_result")
+		}
+		elapsed := time.Since(start)
+		toolCallsTotal.inc(call.Params.Name, status)
+		toolDuration.observe(elapsed.Seconds(), call.Params.Name)
+		recordToolCall(call.Params.Name, elapsed, status == "error")
+		return result, err
+	}
+}
+
+// writePoolStats writes connection pool gauges for the default connection
+// and every named connection.
+func writePoolStats(w io.Writer) {
+	db := currentDB()
+	pools := map[string]*sql.DB{}
+	if db != nil {
+		pools[defaultConnectionName] = db
+	}
+	for _, name := range connectionNames() {
+		if conn, err := connectionFor(name); err == nil {
+			pools[name] = conn
+		}
+	}
+	stats := make(map[string]sql.DBStats, len(pools))
+	for name, pool := range pools {
+		stats[name] = pool.Stats()
+	}
+	names := sortedKeys(stats)
+
+	gauges := []struct {
+		name, help string
+		value      func(sql.DBStats) float64
+	}{
+		{"mysql_mcp_pool_open_connections", "Open connections, in use and idle.", func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"mysql_mcp_pool_in_use_connections", "Connections currently in use.", func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"mysql_mcp_pool_idle_connections", "Idle connections.", func(s sql.DBStats) float64 { return float64(s.Idle) }},
+		{"mysql_mcp_pool_max_open_connections", "Maximum open connections (0 is unlimited).", func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s%s %s\n", g.name, formatLabels([]string{"connection"}, name, "", ""), formatFloat(g.value(stats[name])))
+		}
+	}
+	counters := []struct {
+		name, help string
+		value      func(sql.DBStats) float64
+	}{
+		{"mysql_mcp_pool_wait_count_total", "Connections waited for.", func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"mysql_mcp_pool_wait_duration_seconds_total", "Time spent waiting for a connection.", func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels([]string{"connection"}, name, "", ""), formatFloat(c.value(stats[name])))
+		}
+	}
+}
+
+// writeMetrics writes every metric in the Prometheus text format.
+func writeMetrics(w io.Writer) {
+	toolCallsTotal.write(w)
+	toolDuration.write(w)
+	queryDuration.write(w)
+	rowsReturnedTotal.write(w)
+	errorsTotal.write(w)
+	schemaCacheRequests.write(w)
+	writePoolStats(w)
+}
+
+// serveMetrics serves /metrics and the /healthz and /readyz probes on addr
+// until the listener fails.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w)
+	})
+	mux.HandleFunc("/healthz", handleHealthz)
+	mux.HandleFunc("/readyz", handleReadyz)
+	slog.Info("serving metrics", "addr", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		slog.Error("metrics server failed", "addr", addr, "err", err)
+	}
+}
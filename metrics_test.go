@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCounterVecWrite(t *testing.T) {
+	c := newCounterVec("test_total", "Test counter.", "tool", "status")
+	c.inc("query", "ok")
+	c.add(2.5, "query", "ok")
+	c.inc(`a"b\c`+"\n", "error")
+
+	var buf bytes.Buffer
+	c.write(&buf)
+	want := "# HELP test_total Test counter.\n" +
+		"# TYPE test_total counter\n" +
+		`test_total{tool="a\"b\\c\n",status="error"} 1` + "\n" +
+		`test_total{tool="query",status="ok"} 3.5` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("write() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistogramVecWrite(t *testing.T) {
+	h := newHistogramVec("test_seconds", "Test histogram.", "kind")
+	h.observe(0.003, "read")
+	h.observe(7, "read")
+	h.observe(120, "read")
+
+	var buf bytes.Buffer
+	h.write(&buf)
+	for _, line := range []string{
+		`test_seconds_bucket{kind="read",le="0.001"} 0`,
+		`test_seconds_bucket{kind="read",le="0.005"} 1`,
+		`test_seconds_bucket{kind="read",le="5"} 1`,
+		`test_seconds_bucket{kind="read",le="10"} 2`,
+		`test_seconds_bucket{kind="read",le="60"} 2`,
+		`test_seconds_bucket{kind="read",le="+Inf"} 3`,
+		`test_seconds_sum{kind="read"} 127.003`,
+		`test_seconds_count{kind="read"} 3`,
+	} {
+		if !bytes.Contains(buf.Bytes(), []byte(line+"\n")) {
+			t.Errorf("write() is missing %q:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestFormatLabels(t *testing.T) {
+	tests := []struct {
+		names             []string
+		key, extra, value string
+		want              string
+	}{
+		{nil, "", "", "", ""},
+		{nil, "", "le", "0.5", `{le="0.5"}`},
+		{[]string{"connection"}, "émoji ✓", "", "", `{connection="émoji ✓"}`},
+		{[]string{"tool", "status"}, "q\xffok", "", "", `{tool="q",status="ok"}`},
+	}
+	for _, tt := range tests {
+		if got := formatLabels(tt.names, tt.key, tt.extra, tt.value); got != tt.want {
+			t.Errorf("formatLabels(%q, %q, %q, %q) = %s, want %s", tt.names, tt.key, tt.extra, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type GenerateMigrationParams struct {
+	SourceDatabase   string `json:"source_database"`
+	TargetDatabase   string `json:"target_database"`
+	SourceConnection string `json:"source_connection,omitempty"`
+	TargetConnection string `json:"target_connection,omitempty"`
+}
+
+// migrationPhase orders generated statements so each one only depends on
+// objects created by earlier phases.
+type migrationPhase int
+
+const (
+	phaseDropReplaced migrationPhase = iota
+	phaseCreateTables
+	phaseAlterTables
+	phaseForeignKeys
+	phaseViews
+	phaseRoutines
+	phaseTriggers
+)
+
+type migrationStatement struct {
+	phase migrationPhase
+	sql   string
+	// compound statements (routines and triggers) need a DELIMITER change
+	// when run through the mysql client.
+	compound bool
+}
+
+// migrationPlan holds the generated DDL. Destructive statements drop
+// objects or may lose data and are kept apart from the rest.
+type migrationPlan struct {
+	safe        []migrationStatement
+	destructive []migrationStatement
+	notes       []string
+}
+
+var (
+	indexColumnPattern = regexp.MustCompile(`^(.*)\((\d+)\)$`)
+	typeLengthPattern  = regexp.MustCompile(`^(\w+)\((\d+)\)(.*)$`)
+)
+
+// integerRanks orders integer types by width for detecting widening changes.
+var integerRanks = map[string]int{"tinyint": 1, "smallint": 2, "mediumint": 3, "int": 4, "bigint": 5}
+
+func GenerateMigration(ctx context.Context, req *mcp.CallToolRequest, args GenerateMigrationParams) (*mcp.CallToolResult, any, error) {
+	if args.SourceDatabase == "" || args.TargetDatabase == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "source_database and target_database are required"},
+			},
+		}, nil, nil
+	}
+
+	source, target, err := loadSchemaPair(ctx, args.SourceConnection, args.SourceDatabase, args.TargetConnection, args.TargetDatabase)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	differences := diffSchemas(source, target)
+	// CHECK constraints cannot be added or dropped on servers that do not
+	// enforce them.
+	planned := differences
+	targetConn, _ := connectionFor(args.TargetConnection)
+	server := serverInfoFor(ctx, targetConn)
+	if !server.supportsCheckConstraints() {
+		planned = slices.DeleteFunc(slices.Clone(differences), func(d SchemaDifference) bool { return d.Kind == "check" })
+	}
+	plan := planSchemaMigration(source, target, planned)
+	if len(planned) < len(differences) {
+		plan.notes = append(plan.notes, fmt.Sprintf("%d CHECK constraint differences were skipped: the target server (%s) does not enforce CHECK constraints", len(differences)-len(planned), server))
+	}
+
+	resultText := fmt.Sprintf("Migration bringing %s in line with %s\n",
+		describeSchemaLocation(args.TargetConnection, args.TargetDatabase), describeSchemaLocation(args.SourceConnection, args.SourceDatabase))
+	if len(differences) == 0 {
+		resultText += "Schemas are identical; nothing to do.\n"
+	}
+	if len(plan.safe) > 0 {
+		resultText += fmt.Sprintf("\n-- Statements (%d):\n%s", len(plan.safe), migrationScript(plan.safe))
+	}
+	if len(plan.destructive) > 0 {
+		resultText += fmt.Sprintf("\n-- DESTRUCTIVE statements (%d): these drop objects or may lose data.\n-- Review them separately; they are never executed by this server.\n%s",
+			len(plan.destructive), migrationScript(plan.destructive))
+	}
+	if len(plan.notes) > 0 {
+		resultText += "\nNotes:\n"
+		for _, note := range plan.notes {
+			resultText += "  - " + note + "\n"
+		}
+	}
+	resultText += "\nNothing was executed.\n"
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"source":      describeSchemaLocation(args.SourceConnection, args.SourceDatabase),
+		"target":      describeSchemaLocation(args.TargetConnection, args.TargetDatabase),
+		"statements":  migrationSQL(plan.safe),
+		"destructive": migrationSQL(plan.destructive),
+		"notes":       plan.notes,
+		"differences": differences,
+	}, nil
+}
+
+// planSchemaMigration turns differences into the statements that make target
+// match source. Objects are looked up in the schemas by name because the
+// differences only carry descriptions.
+func planSchemaMigration(source, target *databaseSchema, differences []SchemaDifference) *migrationPlan {
+	plan := &migrationPlan{}
+	sourceTables := make(map[string]schemaTable, len(source.Tables))
+	for _, t := range source.Tables {
+		sourceTables[t.Name] = t
+	}
+	targetTables := make(map[string]schemaTable, len(target.Tables))
+	for _, t := range target.Tables {
+		targetTables[t.Name] = t
+	}
+	safe := func(phase migrationPhase, stmt string) {
+		plan.safe = append(plan.safe, migrationStatement{phase: phase, sql: stmt})
+	}
+	destructive := func(phase migrationPhase, stmt string) {
+		plan.destructive = append(plan.destructive, migrationStatement{phase: phase, sql: stmt})
+	}
+	lossy := false
+	alter := func(table, clause string) string {
+		return fmt.Sprintf("ALTER TABLE %s %s", qualifiedTable(target.Database, table), clause)
+	}
+
+	for _, d := range differences {
+		switch d.Kind {
+		case "table":
+			if d.Change == "missing" {
+				t := sourceTables[d.Name]
+				safe(phaseCreateTables, createTableFromSchema(target.Database, t))
+				for _, fk := range t.ForeignKeys {
+					safe(phaseForeignKeys, alter(t.Name, "ADD "+foreignKeyDefinition(source, target, fk)))
+				}
+			} else {
+				destructive(phaseCreateTables, "DROP TABLE "+qualifiedTable(target.Database, d.Name))
+			}
+
+		case "table option":
+			t := sourceTables[d.Table]
+			switch d.Name {
+			case "engine":
+				safe(phaseAlterTables, alter(d.Table, "ENGINE="+t.Engine))
+			case "collation":
+				// Only the table default changes; existing columns are
+				// reported and converted individually.
+				safe(phaseAlterTables, alter(d.Table, "DEFAULT COLLATE="+t.Collation))
+			case "comment":
+				safe(phaseAlterTables, alter(d.Table, "COMMENT="+quoteString(t.Comment)))
+			}
+
+		case "column":
+			switch d.Change {
+			case "missing":
+				s := sourceTables[d.Table]
+				c, position := findColumn(s, d.Name)
+				safe(phaseAlterTables, alter(d.Table, "ADD COLUMN "+schemaColumnDefinition(c)+columnPosition(s, position)))
+			case "extra":
+				destructive(phaseAlterTables, alter(d.Table, "DROP COLUMN "+quoteIdentifier(d.Name)))
+			default:
+				c, _ := findColumn(sourceTables[d.Table], d.Name)
+				old, _ := findColumn(targetTables[d.Table], d.Name)
+				stmt := alter(d.Table, "MODIFY COLUMN "+schemaColumnDefinition(c))
+				if lossyColumnChange(old, c) {
+					destructive(phaseAlterTables, stmt)
+					lossy = true
+				} else {
+					safe(phaseAlterTables, stmt)
+				}
+			}
+
+		case "column order":
+			s := sourceTables[d.Table]
+			// Place each column after its source predecessor, tracking the
+			// order the earlier moves leave behind.
+			current := columnOrder(targetTables[d.Table], s)
+			for i, name := range columnOrder(s, targetTables[d.Table]) {
+				if current[i] == name {
+					continue
+				}
+				current = slices.DeleteFunc(current, func(n string) bool { return n == name })
+				current = slices.Insert(current, i, name)
+				c, position := findColumn(s, name)
+				safe(phaseAlterTables, alter(d.Table, "MODIFY COLUMN "+schemaColumnDefinition(c)+columnPosition(s, position)))
+			}
+
+		case "index":
+			switch d.Change {
+			case "missing":
+				safe(phaseAlterTables, alter(d.Table, "ADD "+indexDefinition(findIndex(sourceTables[d.Table], d.Name))))
+			case "extra":
+				destructive(phaseAlterTables, alter(d.Table, "DROP "+dropIndexClause(d.Name)))
+			default:
+				// Dropping and re-adding in one statement leaves no window
+				// without the index.
+				safe(phaseAlterTables, alter(d.Table, "DROP "+dropIndexClause(d.Name)+", ADD "+indexDefinition(findIndex(sourceTables[d.Table], d.Name))))
+			}
+
+		case "foreign key":
+			fk := findForeignKey(sourceTables[d.Table], d.Name)
+			switch d.Change {
+			case "missing":
+				safe(phaseForeignKeys, alter(d.Table, "ADD "+foreignKeyDefinition(source, target, fk)))
+			case "extra":
+				destructive(phaseDropReplaced, alter(d.Table, "DROP FOREIGN KEY "+quoteIdentifier(d.Name)))
+			default:
+				// A constraint name cannot be dropped and re-added in the
+				// same ALTER TABLE.
+				safe(phaseDropReplaced, alter(d.Table, "DROP FOREIGN KEY "+quoteIdentifier(d.Name)))
+				safe(phaseForeignKeys, alter(d.Table, "ADD "+foreignKeyDefinition(source, target, fk)))
+			}
+
+		case "check":
+			check := findCheck(sourceTables[d.Table], d.Name)
+			switch d.Change {
+			case "missing":
+				safe(phaseForeignKeys, alter(d.Table, fmt.Sprintf("ADD CONSTRAINT %s CHECK (%s)", quoteIdentifier(d.Name), check.Clause)))
+			case "extra":
+				destructive(phaseDropReplaced, alter(d.Table, "DROP CHECK "+quoteIdentifier(d.Name)))
+			default:
+				safe(phaseDropReplaced, alter(d.Table, "DROP CHECK "+quoteIdentifier(d.Name)))
+				safe(phaseForeignKeys, alter(d.Table, fmt.Sprintf("ADD CONSTRAINT %s CHECK (%s)", quoteIdentifier(d.Name), check.Clause)))
+			}
+
+		case "view":
+			if d.Change == "extra" {
+				destructive(phaseDropReplaced, "DROP VIEW "+qualifiedTable(target.Database, d.Name))
+				continue
+			}
+			for _, v := range source.Views {
+				if v.Name == d.Name {
+					safe(phaseViews, fmt.Sprintf("CREATE OR REPLACE VIEW %s AS %s", qualifiedTable(target.Database, v.Name), retargetDefinition(source, target, v.Definition)))
+				}
+			}
+
+		case "procedure", "function":
+			kind := strings.ToUpper(d.Kind)
+			drop := fmt.Sprintf("DROP %s %s", kind, qualifiedTable(target.Database, d.Name))
+			if d.Change == "extra" {
+				destructive(phaseDropReplaced, drop)
+				continue
+			}
+			if d.Change == "changed" {
+				safe(phaseDropReplaced, drop)
+			}
+			for _, r := range source.Routines {
+				if r.Type == kind && r.Name == d.Name {
+					plan.safe = append(plan.safe, migrationStatement{phase: phaseRoutines, sql: createRoutineFromSchema(source, target, r), compound: true})
+				}
+			}
+
+		case "trigger":
+			drop := "DROP TRIGGER " + qualifiedTable(target.Database, d.Name)
+			if d.Change == "extra" {
+				destructive(phaseDropReplaced, drop)
+				continue
+			}
+			if d.Change == "changed" {
+				safe(phaseDropReplaced, drop)
+			}
+			for _, t := range source.Triggers {
+				if t.Name == d.Name {
+					stmt := fmt.Sprintf("CREATE TRIGGER %s %s %s ON %s FOR EACH ROW %s",
+						qualifiedTable(target.Database, t.Name), t.Timing, t.Event, qualifiedTable(target.Database, t.Table), retargetDefinition(source, target, t.Statement))
+					plan.safe = append(plan.safe, migrationStatement{phase: phaseTriggers, sql: stmt, compound: true})
+				}
+			}
+		}
+	}
+
+	for _, t := range source.Tables {
+		for _, i := range t.Indexes {
+			for _, col := range i.Columns {
+				if col == "(expression)" && hasDifference(differences, t.Name, i.Name) {
+					plan.notes = append(plan.notes, fmt.Sprintf("Index %s on %s uses a functional key part whose expression is not available from information_schema; adjust it by hand", quoteIdentifier(i.Name), quoteIdentifier(t.Name)))
+					break
+				}
+			}
+		}
+	}
+	if lossy {
+		plan.notes = append(plan.notes, "Column type changes are treated as destructive unless they only widen a length or integer type")
+	}
+
+	sort.SliceStable(plan.safe, func(i, j int) bool { return plan.safe[i].phase < plan.safe[j].phase })
+	// Destructive statements run in the reverse order: constraints and
+	// dependent objects go before the columns and tables they use.
+	sort.SliceStable(plan.destructive, func(i, j int) bool { return plan.destructive[i].phase > plan.destructive[j].phase })
+	return plan
+}
+
+// createTableFromSchema renders a CREATE TABLE for t in database. Foreign
+// keys are left out and added once all tables exist.
+func createTableFromSchema(database string, t schemaTable) string {
+	var lines []string
+	for _, c := range t.Columns {
+		lines = append(lines, "  "+schemaColumnDefinition(c))
+	}
+	for _, i := range t.Indexes {
+		lines = append(lines, "  "+indexDefinition(i))
+	}
+	for _, c := range t.Checks {
+		lines = append(lines, fmt.Sprintf("  CONSTRAINT %s CHECK (%s)", quoteIdentifier(c.Name), c.Clause))
+	}
+	stmt := fmt.Sprintf("CREATE TABLE %s (\n%s\n)", qualifiedTable(database, t.Name), strings.Join(lines, ",\n"))
+	if t.Engine != "" {
+		stmt += " ENGINE=" + t.Engine
+	}
+	if t.Collation != "" {
+		stmt += " DEFAULT COLLATE=" + t.Collation
+	}
+	if t.Comment != "" {
+		stmt += " COMMENT=" + quoteString(t.Comment)
+	}
+	return stmt
+}
+
+// schemaColumnDefinition renders c as it appears in CREATE TABLE or ALTER TABLE.
+func schemaColumnDefinition(c schemaColumn) string {
+	def := quoteIdentifier(c.Name) + " " + c.Type
+	if c.Collation != "" {
+		def += " COLLATE " + c.Collation
+	}
+	extra := c.Extra
+	if c.Generated != "" {
+		// Generated columns report VIRTUAL GENERATED or STORED GENERATED.
+		storage := "VIRTUAL"
+		if strings.Contains(strings.ToUpper(extra), "STORED") {
+			storage = "STORED"
+		}
+		def += fmt.Sprintf(" GENERATED ALWAYS AS (%s) %s", c.Generated, storage)
+		extra = ""
+	}
+	if c.Nullable {
+		def += " NULL"
+	} else {
+		def += " NOT NULL"
+	}
+	if c.Default != nil && c.Generated == "" {
+		def += " DEFAULT " + columnDefault(c)
+	}
+	extra = strings.TrimSpace(strings.Replace(extra, "DEFAULT_GENERATED", "", 1))
+	if extra != "" {
+		def += " " + extra
+	}
+	if c.Comment != "" {
+		def += " COMMENT " + quoteString(c.Comment)
+	}
+	return def
+}
+
+// columnDefault renders the default as a literal or expression. MySQL 8.0
+// marks expression defaults with DEFAULT_GENERATED; earlier versions only
+// allow CURRENT_TIMESTAMP.
+func columnDefault(c schemaColumn) string {
+	value := *c.Default
+	upper := strings.ToUpper(value)
+	switch {
+	case strings.HasPrefix(upper, "CURRENT_TIMESTAMP") || strings.HasPrefix(upper, "NOW("):
+		return value
+	case strings.HasPrefix(value, "b'") || strings.HasPrefix(value, "0x"):
+		return value
+	case strings.Contains(c.Extra, "DEFAULT_GENERATED"):
+		return "(" + value + ")"
+	}
+	return quoteString(value)
+}
+
+// columnPosition returns the FIRST or AFTER clause placing the column at
+// position in t.
+func columnPosition(t schemaTable, position int) string {
+	if position == 0 {
+		return " FIRST"
+	}
+	return " AFTER " + quoteIdentifier(t.Columns[position-1].Name)
+}
+
+func findColumn(t schemaTable, name string) (schemaColumn, int) {
+	for i, c := range t.Columns {
+		if c.Name == name {
+			return c, i
+		}
+	}
+	return schemaColumn{Name: name}, len(t.Columns)
+}
+
+func findIndex(t schemaTable, name string) schemaIndex {
+	for _, i := range t.Indexes {
+		if i.Name == name {
+			return i
+		}
+	}
+	return schemaIndex{Name: name}
+}
+
+func findForeignKey(t schemaTable, name string) schemaForeignKey {
+	for _, fk := range t.ForeignKeys {
+		if fk.Name == name {
+			return fk
+		}
+	}
+	return schemaForeignKey{Name: name}
+}
+
+func findCheck(t schemaTable, name string) schemaCheck {
+	for _, c := range t.Checks {
+		if c.Name == name {
+			return c
+		}
+	}
+	return schemaCheck{Name: name}
+}
+
+func hasDifference(differences []SchemaDifference, table, name string) bool {
+	for _, d := range differences {
+		if (d.Table == table && d.Name == name) || (d.Kind == "table" && d.Name == table && d.Change == "missing") {
+			return true
+		}
+	}
+	return false
+}
+
+// indexDefinition renders i as a CREATE TABLE or ADD clause.
+func indexDefinition(i schemaIndex) string {
+	columns := make([]string, len(i.Columns))
+	for n, col := range i.Columns {
+		if m := indexColumnPattern.FindStringSubmatch(col); m != nil && m[1] != "" {
+			columns[n] = quoteIdentifier(m[1]) + "(" + m[2] + ")"
+		} else {
+			columns[n] = quoteIdentifier(col)
+		}
+	}
+	list := strings.Join(columns, ", ")
+	switch {
+	case i.Name == "PRIMARY":
+		return "PRIMARY KEY (" + list + ")"
+	case i.Kind == "FULLTEXT" || i.Kind == "SPATIAL":
+		return fmt.Sprintf("%s INDEX %s (%s)", i.Kind, quoteIdentifier(i.Name), list)
+	case i.Unique:
+		return fmt.Sprintf("UNIQUE INDEX %s (%s)", quoteIdentifier(i.Name), list)
+	}
+	return fmt.Sprintf("INDEX %s (%s)", quoteIdentifier(i.Name), list)
+}
+
+func dropIndexClause(name string) string {
+	if name == "PRIMARY" {
+		return "PRIMARY KEY"
+	}
+	return "INDEX " + quoteIdentifier(name)
+}
+
+// foreignKeyDefinition renders fk for the target database. References to
+// the source database itself are redirected to the target.
+func foreignKeyDefinition(source, target *databaseSchema, fk schemaForeignKey) string {
+	refDatabase := fk.RefDatabase
+	if refDatabase == source.Database {
+		refDatabase = target.Database
+	}
+	return fmt.Sprintf("CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON UPDATE %s ON DELETE %s",
+		quoteIdentifier(fk.Name), quoteIdentifierList(fk.Columns), qualifiedTable(refDatabase, fk.RefTable),
+		quoteIdentifierList(fk.RefColumns), fk.OnUpdate, fk.OnDelete)
+}
+
+func createRoutineFromSchema(source, target *databaseSchema, r schemaRoutine) string {
+	stmt := fmt.Sprintf("CREATE %s %s(%s)", r.Type, qualifiedTable(target.Database, r.Name), r.Parameters)
+	if r.Type == "FUNCTION" {
+		stmt += " RETURNS " + r.Returns
+	}
+	return stmt + " " + r.Characteristics + "\n" + retargetDefinition(source, target, r.Definition)
+}
+
+// retargetDefinition rewrites qualifications with the source database name
+// to the target's.
+func retargetDefinition(source, target *databaseSchema, definition string) string {
+	return strings.ReplaceAll(definition, quoteIdentifier(source.Database)+".", quoteIdentifier(target.Database)+".")
+}
+
+// lossyColumnChange reports whether changing a column from old to c could
+// truncate or reject existing data.
+func lossyColumnChange(old, c schemaColumn) bool {
+	if old.Nullable && !c.Nullable {
+		return true
+	}
+	if old.Collation != c.Collation {
+		return true
+	}
+	if old.Type == c.Type {
+		return false
+	}
+	oldMatch := typeLengthPattern.FindStringSubmatch(old.Type)
+	newMatch := typeLengthPattern.FindStringSubmatch(c.Type)
+	if oldMatch != nil && newMatch != nil && oldMatch[1] == newMatch[1] && oldMatch[3] == newMatch[3] {
+		oldLength, _ := strconv.Atoi(oldMatch[2])
+		newLength, _ := strconv.Atoi(newMatch[2])
+		// Integer display widths do not limit values.
+		return newLength < oldLength && integerRanks[oldMatch[1]] == 0
+	}
+	oldBase, oldRest := integerType(old.Type)
+	newBase, newRest := integerType(c.Type)
+	if integerRanks[oldBase] > 0 && integerRanks[newBase] > 0 && oldRest == newRest {
+		return integerRanks[newBase] < integerRanks[oldBase]
+	}
+	return true
+}
+
+// integerType splits an integer column type into its base name and
+// modifiers, ignoring any display width.
+func integerType(columnType string) (string, string) {
+	base, rest, _ := strings.Cut(columnType, " ")
+	if i := strings.IndexByte(base, '('); i >= 0 {
+		base = base[:i]
+	}
+	return base, rest
+}
+
+func migrationSQL(statements []migrationStatement) []string {
+	sqls := make([]string, len(statements))
+	for i, s := range statements {
+		sqls[i] = s.sql
+	}
+	return sqls
+}
+
+// migrationScript renders statements as a script the mysql client and
+// migrate_up can run, switching delimiters around compound statements.
+func migrationScript(statements []migrationStatement) string {
+	var b strings.Builder
+	for _, s := range statements {
+		if s.compound {
+			fmt.Fprintf(&b, "DELIMITER $$\n%s$$\nDELIMITER ;\n", s.sql)
+			continue
+		}
+		b.WriteString(s.sql + ";\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// migrationsFormat selects how applied migrations are tracked: native,
+// golang-migrate, goose, or auto to detect it from the database and files.
+var migrationsFormat = "auto"
+
+const gooseVersionTable = "goose_db_version"
+
+// migrationStore records which migrations are applied, in the version
+// table layout of one migration tool.
+type migrationStore interface {
+	// Name is the format name shown to the user.
+	Name() string
+	// Applied returns the applied migrations. files is passed for formats
+	// that only record the current version.
+	Applied(ctx context.Context, database string, files []migration) (map[int64]appliedMigration, error)
+	// Prepare creates the version table if needed.
+	Prepare(ctx context.Context, conn *sql.Conn, database string) error
+	// Start is called before a migration's statements run and Finish after
+	// they all succeeded. previous is the version that becomes current when
+	// m is rolled back, or -1 if none.
+	Start(ctx context.Context, conn *sql.Conn, database string, m migration, up bool, previous int64) error
+	Finish(ctx context.Context, conn *sql.Conn, database string, m migration, up bool, previous int64) error
+	// DirtyHint explains how to clear a dirty flag on version.
+	DirtyHint(database string, version int64) string
+}
+
+// openMigrationStore returns the store for format, detecting it when format
+// is "auto": an existing goose or golang-migrate version table wins, then
+// goose-style files, then the native format.
+func openMigrationStore(ctx context.Context, db *sql.DB, database, format string, files []migration) (migrationStore, error) {
+	if format == "" {
+		format = migrationsFormat
+	}
+	switch format {
+	case "native":
+		return nativeMigrationStore{}, nil
+	case "golang-migrate":
+		return golangMigrateStore{}, nil
+	case "goose":
+		return gooseMigrationStore{}, nil
+	case "auto":
+	default:
+		return nil, fmt.Errorf("unsupported migrations format %q (use auto, native, golang-migrate or goose)", format)
+	}
+
+	if ok, err := tableExists(ctx, database, gooseVersionTable); err != nil || ok {
+		return gooseMigrationStore{}, err
+	}
+	if ok, err := tableExists(ctx, database, migrationsTable); err != nil {
+		return nil, err
+	} else if ok {
+		// golang-migrate's table has only version and dirty columns.
+		cols, err := tableColumns(ctx, db, database, migrationsTable)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cols {
+			if c.ColumnName == "name" {
+				return nativeMigrationStore{}, nil
+			}
+		}
+		return golangMigrateStore{}, nil
+	}
+	for _, m := range files {
+		if m.GoosePath != "" || m.GoFile {
+			return gooseMigrationStore{}, nil
+		}
+	}
+	return nativeMigrationStore{}, nil
+}
+
+func tableExists(ctx context.Context, database, table string) (bool, error) {
+	db := currentDB()
+	var n int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+	`, database, table).Scan(&n)
+	return n > 0, err
+}
+
+// nativeMigrationStore keeps one schema_migrations row per applied
+// migration, with its name, dirty flag and time applied.
+type nativeMigrationStore struct{}
+
+func (nativeMigrationStore) Name() string { return "native" }
+
+func (nativeMigrationStore) Applied(ctx context.Context, database string, files []migration) (map[int64]appliedMigration, error) {
+	db := currentDB()
+	applied := make(map[int64]appliedMigration)
+	if ok, err := tableExists(ctx, database, migrationsTable); err != nil || !ok {
+		return applied, err
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT version, name, dirty, UNIX_TIMESTAMP(applied_at) FROM %s", qualifiedTable(database, migrationsTable)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a appliedMigration
+		var appliedAt int64
+		if err := rows.Scan(&a.Version, &a.Name, &a.Dirty, &appliedAt); err != nil {
+			return nil, err
+		}
+		a.AppliedAt = time.Unix(appliedAt, 0).UTC()
+		applied[a.Version] = a
+	}
+	return applied, rows.Err()
+}
+
+func (nativeMigrationStore) Prepare(ctx context.Context, conn *sql.Conn, database string) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		version BIGINT NOT NULL PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		dirty BOOLEAN NOT NULL DEFAULT FALSE,
+		applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`, qualifiedTable(database, migrationsTable)))
+	return err
+}
+
+func (nativeMigrationStore) Start(ctx context.Context, conn *sql.Conn, database string, m migration, up bool, previous int64) error {
+	table := qualifiedTable(database, migrationsTable)
+	var err error
+	if up {
+		_, err = conn.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (version, name, dirty) VALUES (?, ?, TRUE)", table), m.Version, m.Name)
+	} else {
+		_, err = conn.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET dirty = TRUE WHERE version = ?", table), m.Version)
+	}
+	return err
+}
+
+func (nativeMigrationStore) Finish(ctx context.Context, conn *sql.Conn, database string, m migration, up bool, previous int64) error {
+	table := qualifiedTable(database, migrationsTable)
+	var err error
+	if up {
+		_, err = conn.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET dirty = FALSE, applied_at = CURRENT_TIMESTAMP WHERE version = ?", table), m.Version)
+	} else {
+		_, err = conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE version = ?", table), m.Version)
+	}
+	return err
+}
+
+func (nativeMigrationStore) DirtyHint(database string, version int64) string {
+	return fmt.Sprintf("UPDATE %s SET dirty = 0 WHERE version = %d (or DELETE the row if the changes were undone)",
+		qualifiedTable(database, migrationsTable), version)
+}
+
+// golangMigrateStore uses golang-migrate's schema_migrations table, which
+// holds a single row: the current version and whether it is dirty. Every
+// migration up to the current version counts as applied.
+type golangMigrateStore struct{}
+
+func (golangMigrateStore) Name() string { return "golang-migrate" }
+
+func (golangMigrateStore) Applied(ctx context.Context, database string, files []migration) (map[int64]appliedMigration, error) {
+	db := currentDB()
+	applied := make(map[int64]appliedMigration)
+	if ok, err := tableExists(ctx, database, migrationsTable); err != nil || !ok {
+		return applied, err
+	}
+
+	var current int64
+	var dirty bool
+	err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT version, dirty FROM %s LIMIT 1", qualifiedTable(database, migrationsTable))).Scan(&current, &dirty)
+	if err == sql.ErrNoRows {
+		return applied, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range files {
+		if m.Version <= current {
+			applied[m.Version] = appliedMigration{Version: m.Version, Name: m.Name}
+		}
+	}
+	a := applied[current]
+	a.Version = current
+	a.Dirty = dirty
+	applied[current] = a
+	return applied, nil
+}
+
+func (golangMigrateStore) Prepare(ctx context.Context, conn *sql.Conn, database string) error {
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)",
+		qualifiedTable(database, migrationsTable)))
+	return err
+}
+
+// setVersion replaces the single version row the way golang-migrate does;
+// a negative version leaves the table empty.
+func (golangMigrateStore) setVersion(ctx context.Context, conn *sql.Conn, database string, version int64, dirty bool) error {
+	table := qualifiedTable(database, migrationsTable)
+	if _, err := conn.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+		return err
+	}
+	if version < 0 {
+		return nil
+	}
+	_, err := conn.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (version, dirty) VALUES (?, ?)", table), version, dirty)
+	return err
+}
+
+func (s golangMigrateStore) Start(ctx context.Context, conn *sql.Conn, database string, m migration, up bool, previous int64) error {
+	if up {
+		return s.setVersion(ctx, conn, database, m.Version, true)
+	}
+	// golang-migrate marks the target version dirty while rolling back.
+	if previous < 0 {
+		return s.setVersion(ctx, conn, database, m.Version, true)
+	}
+	return s.setVersion(ctx, conn, database, previous, true)
+}
+
+func (s golangMigrateStore) Finish(ctx context.Context, conn *sql.Conn, database string, m migration, up bool, previous int64) error {
+	if up {
+		return s.setVersion(ctx, conn, database, m.Version, false)
+	}
+	return s.setVersion(ctx, conn, database, previous, false)
+}
+
+func (golangMigrateStore) DirtyHint(database string, version int64) string {
+	return fmt.Sprintf("UPDATE %s SET dirty = 0 (the equivalent of `migrate force %d`)",
+		qualifiedTable(database, migrationsTable), version)
+}
+
+// gooseMigrationStore uses goose's goose_db_version log table. The latest
+// row for a version says whether it is applied. goose has no dirty flag, so
+// a failed migration simply stays unapplied.
+type gooseMigrationStore struct{}
+
+func (gooseMigrationStore) Name() string { return "goose" }
+
+func (gooseMigrationStore) Applied(ctx context.Context, database string, files []migration) (map[int64]appliedMigration, error) {
+	db := currentDB()
+	applied := make(map[int64]appliedMigration)
+	if ok, err := tableExists(ctx, database, gooseVersionTable); err != nil || !ok {
+		return applied, err
+	}
+
+	names := make(map[int64]string, len(files))
+	for _, m := range files {
+		names[m.Version] = m.Name
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT version_id, is_applied, COALESCE(UNIX_TIMESTAMP(tstamp), 0) FROM %s ORDER BY id",
+		qualifiedTable(database, gooseVersionTable)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version, tstamp int64
+		var isApplied bool
+		if err := rows.Scan(&version, &isApplied, &tstamp); err != nil {
+			return nil, err
+		}
+		// Version 0 is the marker row goose inserts when creating the table.
+		if version == 0 {
+			continue
+		}
+		if isApplied {
+			applied[version] = appliedMigration{Version: version, Name: names[version], AppliedAt: time.Unix(tstamp, 0).UTC()}
+		} else {
+			delete(applied, version)
+		}
+	}
+	return applied, rows.Err()
+}
+
+func (gooseMigrationStore) Prepare(ctx context.Context, conn *sql.Conn, database string) error {
+	table := qualifiedTable(database, gooseVersionTable)
+	_, err := conn.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+		version_id BIGINT NOT NULL,
+		is_applied BOOLEAN NOT NULL,
+		tstamp TIMESTAMP NULL DEFAULT CURRENT_TIMESTAMP
+	)`, table))
+	if err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (version_id, is_applied)
+		SELECT 0, TRUE FROM DUAL WHERE NOT EXISTS (SELECT 1 FROM %s)`, table, table))
+	return err
+}
+
+func (gooseMigrationStore) Start(ctx context.Context, conn *sql.Conn, database string, m migration, up bool, previous int64) error {
+	return nil
+}
+
+func (gooseMigrationStore) Finish(ctx context.Context, conn *sql.Conn, database string, m migration, up bool, previous int64) error {
+	table := qualifiedTable(database, gooseVersionTable)
+	var err error
+	if up {
+		_, err = conn.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (version_id, is_applied) VALUES (?, TRUE)", table), m.Version)
+	} else {
+		_, err = conn.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE version_id = ?", table), m.Version)
+	}
+	return err
+}
+
+func (gooseMigrationStore) DirtyHint(database string, version int64) string {
+	return "goose does not track partially applied migrations"
+}
+
+// gooseStatements extracts the Up or Down section of a goose SQL migration.
+// Statements between StatementBegin and StatementEnd annotations are kept
+// whole; the rest are split on semicolons.
+func gooseStatements(path string, up bool) ([]string, error) {
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	want := "up"
+	if !up {
+		want = "down"
+	}
+	var statements []string
+	var section string
+	var plain, block strings.Builder
+	inBlock := false
+	for _, line := range strings.Split(string(script), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if annotation, ok := strings.CutPrefix(trimmed, "-- +goose "); ok {
+			switch strings.ToLower(strings.TrimSpace(annotation)) {
+			case "up", "down":
+				statements = append(statements, splitSQLStatements(plain.String())...)
+				plain.Reset()
+				section = strings.ToLower(strings.TrimSpace(annotation))
+			case "statementbegin":
+				inBlock = true
+			case "statementend":
+				if section == want {
+					statements = append(statements, splitSQLStatements(plain.String())...)
+					plain.Reset()
+					if stmt := strings.TrimSuffix(strings.TrimSpace(block.String()), ";"); stmt != "" {
+						statements = append(statements, stmt)
+					}
+				}
+				block.Reset()
+				inBlock = false
+			}
+			continue
+		}
+		if section != want {
+			continue
+		}
+		if inBlock {
+			block.WriteString(line + "\n")
+		} else {
+			plain.WriteString(line + "\n")
+		}
+	}
+	if !strings.Contains(strings.ToLower(string(script)), "-- +goose up") {
+		return nil, fmt.Errorf("%s has no -- +goose Up annotation", path)
+	}
+	return append(statements, splitSQLStatements(plain.String())...), nil
+}
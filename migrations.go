@@ -0,0 +1,585 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// migrationsDir is the default directory of versioned migration files.
+var migrationsDir = "migrations"
+
+const migrationsTable = "schema_migrations"
+
+// migrationFilePattern matches <version>_<name>.up.sql and .down.sql, the
+// layout shared with golang-migrate.
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// gooseFilePattern matches goose's single-file <version>_<name>.sql and Go
+// migrations.
+var gooseFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(sql|go)$`)
+
+type MigrateStatusParams struct {
+	Database  string `json:"database"`
+	Directory string `json:"directory,omitempty"`
+	Format    string `json:"format,omitempty"`
+}
+
+type MigrateUpParams struct {
+	Database  string `json:"database"`
+	Directory string `json:"directory,omitempty"`
+	Format    string `json:"format,omitempty"`
+	Steps     int    `json:"steps,omitempty"`
+	DryRun    bool   `json:"dry_run,omitempty"`
+}
+
+type MigrateDownParams struct {
+	Database  string `json:"database"`
+	Directory string `json:"directory,omitempty"`
+	Format    string `json:"format,omitempty"`
+	Steps     int    `json:"steps,omitempty"`
+	Confirm   bool   `json:"confirm,omitempty"`
+}
+
+// migration is one versioned migration found on disk, either as separate
+// up and down files or as one goose file with both sections.
+type migration struct {
+	Version   int64
+	Name      string
+	UpPath    string
+	DownPath  string
+	GoosePath string
+	// GoFile marks a goose migration written in Go, which cannot be run
+	// from here.
+	GoFile bool
+}
+
+func (m migration) hasUp() bool   { return m.UpPath != "" || m.GoosePath != "" }
+func (m migration) hasDown() bool { return m.DownPath != "" || m.GoosePath != "" }
+
+// statements reads the statements of the up or down migration.
+func (m migration) statements(up bool) ([]string, error) {
+	if m.GoosePath != "" {
+		return gooseStatements(m.GoosePath, up)
+	}
+	path := m.UpPath
+	if !up {
+		path = m.DownPath
+	}
+	script, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return splitSQLStatements(string(script)), nil
+}
+
+// appliedMigration is one row of the migrations table.
+type appliedMigration struct {
+	Version   int64
+	Name      string
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// MigrationStatus describes one migration for migrate_status.
+type MigrationStatus struct {
+	Version   int64  `json:"version"`
+	Name      string `json:"name"`
+	State     string `json:"state"`
+	AppliedAt string `json:"appliedAt,omitempty"`
+}
+
+func MigrateStatus(ctx context.Context, req *mcp.CallToolRequest, args MigrateStatusParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	files, applied, store, err := migrationState(ctx, db, args.Database, args.Directory, args.Format)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	var statuses []MigrationStatus
+	seen := make(map[int64]bool)
+	pending := 0
+	for _, m := range files {
+		seen[m.Version] = true
+		s := MigrationStatus{Version: m.Version, Name: m.Name, State: "pending"}
+		if a, ok := applied[m.Version]; ok {
+			s.State = "applied"
+			if a.Dirty {
+				s.State = "dirty"
+			}
+			if !a.AppliedAt.IsZero() {
+				s.AppliedAt = a.AppliedAt.Format(time.RFC3339)
+			}
+		} else {
+			if m.GoFile {
+				s.State = "pending (Go migration, run it with goose)"
+			}
+			pending++
+		}
+		statuses = append(statuses, s)
+	}
+	for version, a := range applied {
+		if !seen[version] {
+			s := MigrationStatus{Version: version, Name: a.Name, State: "missing file"}
+			if !a.AppliedAt.IsZero() {
+				s.AppliedAt = a.AppliedAt.Format(time.RFC3339)
+			}
+			if a.Dirty {
+				s.State = "dirty, missing file"
+			}
+			statuses = append(statuses, s)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+
+	resultText := fmt.Sprintf("Migrations for %s (%s format): %d applied, %d pending\n", args.Database, store.Name(), len(applied), pending)
+	for _, s := range statuses {
+		resultText += fmt.Sprintf("  %-16d %-40s %s", s.Version, s.Name, s.State)
+		if s.AppliedAt != "" {
+			resultText += "  " + s.AppliedAt
+		}
+		resultText += "\n"
+	}
+	if dirty := dirtyMigration(applied); dirty != nil {
+		resultText += fmt.Sprintf("\nMigration %d failed partway and is marked dirty. Inspect the schema, finish or undo its changes by hand, then clear the flag with: %s",
+			dirty.Version, store.DirtyHint(args.Database, dirty.Version))
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"format":     store.Name(),
+		"migrations": statuses,
+		"pending":    pending,
+		"applied":    len(applied),
+	}, nil
+}
+
+func MigrateUp(ctx context.Context, req *mcp.CallToolRequest, args MigrateUpParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("migrate_up"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	files, applied, store, err := migrationState(ctx, db, args.Database, args.Directory, args.Format)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	if dirty := dirtyMigration(applied); dirty != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Migration %d (%s) is dirty; resolve it before migrating (see migrate_status)", dirty.Version, dirty.Name)},
+			},
+		}, nil, nil
+	}
+
+	var pending []migration
+	var latest int64
+	for version := range applied {
+		latest = max(latest, version)
+	}
+	var outOfOrder []int64
+	for _, m := range files {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if m.GoFile {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Migration %d (%s) is a Go migration; run it with goose itself", m.Version, m.Name)},
+				},
+			}, nil, nil
+		}
+		if !m.hasUp() {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Migration %d (%s) has no .up.sql file", m.Version, m.Name)},
+				},
+			}, nil, nil
+		}
+		if m.Version < latest {
+			if store.Name() == "golang-migrate" {
+				// golang-migrate only records the current version, so an
+				// older file can never be recorded as applied.
+				continue
+			}
+			outOfOrder = append(outOfOrder, m.Version)
+		}
+		pending = append(pending, m)
+	}
+	if args.Steps > 0 && len(pending) > args.Steps {
+		pending = pending[:args.Steps]
+	}
+
+	resultText := ""
+	if len(outOfOrder) > 0 {
+		resultText += fmt.Sprintf("Warning: migrations %v are older than the latest applied version %d and will be applied out of order\n", outOfOrder, latest)
+	}
+	if len(pending) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText + fmt.Sprintf("%s is up to date", args.Database)},
+			},
+		}, map[string]any{
+			"applied": []int64{},
+		}, nil
+	}
+
+	if args.DryRun {
+		resultText += fmt.Sprintf("Would apply %d migrations to %s:\n", len(pending), args.Database)
+		for _, m := range pending {
+			resultText += fmt.Sprintf("  %d %s\n", m.Version, m.Name)
+		}
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, map[string]any{
+			"pending": len(pending),
+		}, nil
+	}
+
+	done, err := runMigrations(ctx, "migrate_up", args.Database, store, pending, applied, true)
+	for _, m := range done {
+		resultText += fmt.Sprintf("Applied %d %s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText + err.Error()},
+			},
+		}, nil, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText + fmt.Sprintf("%d migrations applied to %s", len(done), args.Database)},
+		},
+	}, map[string]any{
+		"applied": migrationVersions(done),
+	}, nil
+}
+
+func MigrateDown(ctx context.Context, req *mcp.CallToolRequest, args MigrateDownParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("migrate_down"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	files, applied, store, err := migrationState(ctx, db, args.Database, args.Directory, args.Format)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	if dirty := dirtyMigration(applied); dirty != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Migration %d (%s) is dirty; resolve it before migrating (see migrate_status)", dirty.Version, dirty.Name)},
+			},
+		}, nil, nil
+	}
+
+	steps := args.Steps
+	if steps <= 0 {
+		steps = 1
+	}
+	byVersion := make(map[int64]migration, len(files))
+	for _, m := range files {
+		byVersion[m.Version] = m
+	}
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	var rollback []migration
+	for _, version := range versions {
+		if len(rollback) == steps {
+			break
+		}
+		m, ok := byVersion[version]
+		if !ok || !m.hasDown() {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Migration %d (%s) has no .down.sql file; it cannot be rolled back", version, applied[version].Name)},
+				},
+			}, nil, nil
+		}
+		rollback = append(rollback, m)
+	}
+	if len(rollback) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("No migrations are applied to %s", args.Database)},
+			},
+		}, nil, nil
+	}
+
+	resultText := ""
+	if !args.Confirm {
+		resultText = fmt.Sprintf("Would roll back %d migrations on %s:\n", len(rollback), args.Database)
+		for _, m := range rollback {
+			resultText += fmt.Sprintf("  %d %s\n", m.Version, m.Name)
+		}
+		resultText += "\nDown migrations usually drop data. Nothing was changed. Re-run with confirm: true to roll back."
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, map[string]any{
+			"pending": migrationVersions(rollback),
+		}, nil
+	}
+
+	done, err := runMigrations(ctx, "migrate_down", args.Database, store, rollback, applied, false)
+	for _, m := range done {
+		resultText += fmt.Sprintf("Rolled back %d %s\n", m.Version, m.Name)
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText + err.Error()},
+			},
+		}, nil, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText + fmt.Sprintf("%d migrations rolled back on %s", len(done), args.Database)},
+		},
+	}, map[string]any{
+		"rolledBack": migrationVersions(done),
+	}, nil
+}
+
+// migrationState loads the migration files, the version store and the
+// applied versions.
+func migrationState(ctx context.Context, db *sql.DB, database, dir, format string) ([]migration, map[int64]appliedMigration, migrationStore, error) {
+	if database == "" {
+		return nil, nil, nil, fmt.Errorf("database is required")
+	}
+	if dir == "" {
+		dir = migrationsDir
+	}
+	files, err := loadMigrations(dir)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Failed to read migrations from %s: %v", dir, err)
+	}
+	store, err := openMigrationStore(ctx, db, database, format, files)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	applied, err := store.Applied(ctx, database, files)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("Failed to read applied %s migrations in %s: %v", store.Name(), database, err)
+	}
+	return files, applied, store, nil
+}
+
+// loadMigrations returns the migrations in dir sorted by version.
+func loadMigrations(dir string) ([]migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			match = gooseFilePattern.FindStringSubmatch(entry.Name())
+		}
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version in %s: %v", entry.Name(), err)
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("version %d is used by both %s and %s", version, m.Name, match[2])
+		}
+		path := filepath.Join(dir, entry.Name())
+		switch match[3] {
+		case "up":
+			m.UpPath = path
+		case "down":
+			m.DownPath = path
+		case "sql":
+			m.GoosePath = path
+		case "go":
+			m.GoFile = true
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func dirtyMigration(applied map[int64]appliedMigration) *appliedMigration {
+	for _, a := range applied {
+		if a.Dirty {
+			return &a
+		}
+	}
+	return nil
+}
+
+// runMigrations applies (up) or rolls back (down) migrations in order. Where
+// the format supports it, each migration is marked dirty while it runs:
+// MySQL commits DDL implicitly, so a failure partway through cannot be rolled
+// back and must be resolved by hand. It returns the migrations that
+// completed.
+func runMigrations(ctx context.Context, tool, database string, store migrationStore, migrations []migration, applied map[int64]appliedMigration, up bool) ([]migration, error) {
+	db := currentDB()
+	// A dedicated connection is switched to the target database so the
+	// migration files can use unqualified table names.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get connection: %v", err)
+	}
+	defer discardConn(conn)
+
+	if err := store.Prepare(ctx, conn, database); err != nil {
+		return nil, fmt.Errorf("Failed to create the %s version table: %v", store.Name(), err)
+	}
+	if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(database)); err != nil {
+		return nil, fmt.Errorf("Failed to select database %s: %v", database, err)
+	}
+
+	remaining := make(map[int64]bool, len(applied))
+	for version := range applied {
+		remaining[version] = true
+	}
+
+	var done []migration
+	for _, m := range migrations {
+		statements, err := m.statements(up)
+		if err != nil {
+			return done, fmt.Errorf("Failed to read migration %d: %v", m.Version, err)
+		}
+
+		// previous is the version that is current once m is rolled back.
+		previous := int64(-1)
+		if !up {
+			delete(remaining, m.Version)
+			for version := range remaining {
+				previous = max(previous, version)
+			}
+		}
+
+		if err := store.Start(ctx, conn, database, m, up, previous); err != nil {
+			return done, fmt.Errorf("Failed to record migration %d: %v", m.Version, err)
+		}
+
+		for i, stmt := range statements {
+			_, err := conn.ExecContext(ctx, stmt)
+			audit(tool, stmt, 0, err)
+			if err != nil {
+				clearSchemaCache()
+				return done, fmt.Errorf("Migration %d (%s) failed at statement %d: %v\n%s\n\nEarlier statements may have been committed; check the schema before retrying (see migrate_status).",
+					m.Version, m.Name, i+1, err, stmt)
+			}
+		}
+
+		if err := store.Finish(ctx, conn, database, m, up, previous); err != nil {
+			return done, fmt.Errorf("Migration %d ran but could not be recorded: %v", m.Version, err)
+		}
+		done = append(done, m)
+	}
+	clearSchemaCache()
+	return done, nil
+}
+
+func migrationVersions(migrations []migration) []int64 {
+	versions := make([]int64, len(migrations))
+	for i, m := range migrations {
+		versions[i] = m.Version
+	}
+	return versions
+}
+
+// discardConn closes conn without returning it to the pool, for connections
+// whose session state (default database, variables) was changed.
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// releasePublicKey is the minisign public key release checksums are signed
+// with, in the base64 form minisign prints (RW...). Release builds set it
+// with -ldflags "-X main.releasePublicKey=...".
+var releasePublicKey = ""
+
+type minisignPublicKey struct {
+	keyID [8]byte
+	key   ed25519.PublicKey
+}
+
+type minisignSignature struct {
+	algorithm       string
+	keyID           [8]byte
+	signature       []byte
+	trustedComment  string
+	globalSignature []byte
+}
+
+// parseMinisignPublicKey parses a public key given either as the base64
+// line or as the contents of a minisign .pub file.
+func parseMinisignPublicKey(text string) (minisignPublicKey, error) {
+	var pk minisignPublicKey
+	line := ""
+	for _, l := range strings.Split(strings.TrimSpace(text), "\n") {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "untrusted comment:") {
+			line = l
+			break
+		}
+	}
+	raw, err := base64.StdEncoding.DecodeString(line)
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return pk, fmt.Errorf("invalid minisign public key")
+	}
+	copy(pk.keyID[:], raw[2:10])
+	pk.key = ed25519.PublicKey(raw[10:])
+	return pk, nil
+}
+
+// parseMinisignSignature parses a .minisig file.
+func parseMinisignSignature(data []byte) (minisignSignature, error) {
+	var sig minisignSignature
+	lines := strings.Split(strings.ReplaceAll(strings.TrimSpace(string(data)), "\r\n", "\n"), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "untrusted comment:") || !strings.HasPrefix(lines[2], "trusted comment: ") {
+		return sig, fmt.Errorf("malformed minisign signature")
+	}
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return sig, fmt.Errorf("malformed minisign signature")
+	}
+	sig.algorithm = string(raw[:2])
+	copy(sig.keyID[:], raw[2:10])
+	sig.signature = raw[10:]
+	sig.trustedComment = strings.TrimPrefix(lines[2], "trusted comment: ")
+	sig.globalSignature, err = base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || len(sig.globalSignature) != ed25519.SignatureSize {
+		return sig, fmt.Errorf("malformed minisign signature")
+	}
+	return sig, nil
+}
+
+// verify checks sig over message and over its trusted comment. Both the
+// legacy (Ed) and the default prehashed (ED) signature formats are accepted.
+func (pk minisignPublicKey) verify(message []byte, sig minisignSignature) error {
+	if sig.keyID != pk.keyID {
+		return fmt.Errorf("signed with key %X, expected key %X", reverse(sig.keyID[:]), reverse(pk.keyID[:]))
+	}
+	switch sig.algorithm {
+	case "Ed":
+	case "ED":
+		sum := blake2b.Sum512(message)
+		message = sum[:]
+	default:
+		return fmt.Errorf("unsupported signature algorithm %q", sig.algorithm)
+	}
+	if !ed25519.Verify(pk.key, message, sig.signature) {
+		return fmt.Errorf("signature does not match")
+	}
+	if !ed25519.Verify(pk.key, append(bytes.Clone(sig.signature), sig.trustedComment...), sig.globalSignature) {
+		return fmt.Errorf("trusted comment signature does not match")
+	}
+	return nil
+}
+
+// reverse returns b reversed; minisign prints key IDs little-endian.
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i, c := range b {
+		r[len(b)-1-i] = c
+	}
+	return r
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// signMinisign signs message the way minisign does and returns the public
+// key file and the .minisig file.
+func signMinisign(t *testing.T, algorithm string, message []byte, trustedComment string) (string, []byte) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	signed := message
+	if algorithm == "ED" {
+		sum := blake2b.Sum512(message)
+		signed = sum[:]
+	}
+	signature := ed25519.Sign(private, signed)
+	global := ed25519.Sign(private, append(bytes.Clone(signature), trustedComment...))
+
+	key := base64.StdEncoding.EncodeToString(append(append([]byte("Ed"), keyID...), public...))
+	sig := base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), keyID...), signature...))
+	file := fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		sig, trustedComment, base64.StdEncoding.EncodeToString(global))
+	return "untrusted comment: minisign public key 0807060504030201\n" + key + "\n", []byte(file)
+}
+
+func TestMinisignVerify(t *testing.T) {
+	message := []byte("3f2a...  mysql-mcp-server_linux_amd64.tar.gz\n")
+	for _, algorithm := range []string{"Ed", "ED"} {
+		keyFile, sigFile := signMinisign(t, algorithm, message, "timestamp:1760000000\tfile:checksums.txt")
+		pk, err := parseMinisignPublicKey(keyFile)
+		if err != nil {
+			t.Fatalf("parseMinisignPublicKey() = %v", err)
+		}
+		// The bare base64 line is accepted too.
+		if _, err := parseMinisignPublicKey(strings.Split(keyFile, "\n")[1]); err != nil {
+			t.Errorf("parseMinisignPublicKey(base64 line) = %v", err)
+		}
+		sig, err := parseMinisignSignature(sigFile)
+		if err != nil {
+			t.Fatalf("parseMinisignSignature() = %v", err)
+		}
+		if err := pk.verify(message, sig); err != nil {
+			t.Errorf("%s: verify() = %v", algorithm, err)
+		}
+
+		if err := pk.verify(append(bytes.Clone(message), 'x'), sig); err == nil {
+			t.Errorf("%s: verify() accepted a changed message", algorithm)
+		}
+		tampered := sig
+		tampered.trustedComment += " (edited)"
+		if err := pk.verify(message, tampered); err == nil {
+			t.Errorf("%s: verify() accepted a changed trusted comment", algorithm)
+		}
+		otherKey, _ := signMinisign(t, algorithm, message, "")
+		other, _ := parseMinisignPublicKey(otherKey)
+		if err := other.verify(message, sig); err == nil {
+			t.Errorf("%s: verify() accepted a signature from another key", algorithm)
+		}
+		other.keyID[0] ^= 0xff
+		if err := other.verify(message, sig); err == nil || !strings.Contains(err.Error(), "signed with key") {
+			t.Errorf("%s: verify() with another key ID = %v", algorithm, err)
+		}
+	}
+}
+
+func TestParseMinisignMalformed(t *testing.T) {
+	if _, err := parseMinisignPublicKey("RWQBAgMEBQYHCA=="); err == nil {
+		t.Error("parseMinisignPublicKey() accepted a short key")
+	}
+	_, sigFile := signMinisign(t, "ED", []byte("x"), "c")
+	lines := strings.Split(string(sigFile), "\n")
+	for _, data := range []string{
+		"",
+		strings.Join(lines[:3], "\n"),
+		strings.Join([]string{lines[0], lines[1], "comment: c", lines[3]}, "\n"),
+		strings.Join([]string{lines[0], "!!", lines[2], lines[3]}, "\n"),
+		strings.Join([]string{lines[0], lines[1], lines[2], "AAAA"}, "\n"),
+	} {
+		if _, err := parseMinisignSignature([]byte(data)); err == nil {
+			t.Errorf("parseMinisignSignature(%q) accepted a malformed signature", data)
+		}
+	}
+	// Windows line endings are fine.
+	if _, err := parseMinisignSignature([]byte(strings.ReplaceAll(string(sigFile), "\n", "\r\n"))); err != nil {
+		t.Errorf("parseMinisignSignature(CRLF) = %v", err)
+	}
+}
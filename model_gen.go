@@ -0,0 +1,420 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go/format"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type GenerateModelParams struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	// Lang is the language to generate; only go is supported.
+	Lang string `json:"lang,omitempty"`
+	// ORM adds gorm struct tags and a TableName method, or sqlc queries
+	// for the table; empty for a plain struct.
+	ORM     string `json:"orm,omitempty"`
+	Package string `json:"package,omitempty"`
+	// StructName defaults to the table name in singular PascalCase.
+	StructName string `json:"struct_name,omitempty"`
+	// Nullable is how NULL columns are typed: sql for the database/sql
+	// Null types or pointer for pointers. gorm defaults to pointer, the
+	// others to sql.
+	Nullable string `json:"nullable,omitempty"`
+}
+
+// modelColumn is a column as generate_model reads it.
+type modelColumn struct {
+	name     string
+	typ      string
+	nullable bool
+	key      string
+	extra    string
+	comment  string
+	// hasDefault is set for columns with a DEFAULT, which an insert may
+	// leave out.
+	hasDefault bool
+}
+
+// generated reports whether c is a generated column, which cannot be
+// written. DEFAULT_GENERATED columns only have an expression default.
+func (c modelColumn) generated() bool {
+	extra := strings.ToUpper(c.extra)
+	return strings.Contains(extra, "VIRTUAL") || strings.Contains(extra, "STORED") || strings.Contains(extra, "PERSISTENT")
+}
+
+// modelColumns reads the columns of a table in their order.
+func modelColumns(ctx context.Context, database, table string) ([]modelColumn, error) {
+	db := currentDB()
+	var columns []modelColumn
+	err := queryEach(ctx, db, `
+		SELECT COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_KEY, EXTRA, COLUMN_COMMENT, COLUMN_DEFAULT IS NOT NULL
+		FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`, []any{database, table}, func(rows *sql.Rows) error {
+		var c modelColumn
+		var nullable string
+		if err := rows.Scan(&c.name, &c.typ, &nullable, &c.key, &c.extra, &c.comment, &c.hasDefault); err != nil {
+			return err
+		}
+		c.nullable = nullable == "YES"
+		columns = append(columns, c)
+		return nil
+	})
+	if err == nil && len(columns) == 0 {
+		err = fmt.Errorf("table %s.%s does not exist", database, table)
+	}
+	return columns, err
+}
+
+// goInitialisms are the words Go names write in capitals.
+var goInitialisms = wordSet(`ACL API ASCII CPU CSS DNS EOF GUID HTML HTTP HTTPS ID IP JSON LHS QPS RAM RHS RPC SKU SLA SMTP SQL SSH TCP TLS TTL UDP UI UID URI URL UTF8 UUID VM XML XMPP XSRF XSS`)
+
+// goIdentifier turns a column or table name such as user_id into a Go
+// name such as UserID.
+func goIdentifier(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, w := range words {
+		if goInitialisms[strings.ToUpper(w)] {
+			b.WriteString(strings.ToUpper(w))
+			continue
+		}
+		r := []rune(w)
+		b.WriteString(strings.ToUpper(string(r[0])) + string(r[1:]))
+	}
+	id := b.String()
+	if id == "" || !unicode.IsLetter([]rune(id)[0]) {
+		id = "X" + id
+	}
+	return id
+}
+
+// singular makes a plural English table name singular, for struct names:
+// users becomes user and categories category.
+func singular(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, "ies") && len(name) > 3:
+		return name[:len(name)-3] + "y"
+	case strings.HasSuffix(lower, "sses"), strings.HasSuffix(lower, "xes"), strings.HasSuffix(lower, "ches"), strings.HasSuffix(lower, "shes"):
+		return name[:len(name)-2]
+	case strings.HasSuffix(lower, "s") && !strings.HasSuffix(lower, "ss") && !strings.HasSuffix(lower, "us") && len(name) > 1:
+		return name[:len(name)-1]
+	}
+	return name
+}
+
+// goFieldType returns the Go type database/sql scans column c into with
+// the MySQL driver's parseTime=true, and the package it needs. Nullable
+// columns get a database/sql Null type, or a pointer with pointers set.
+func goFieldType(c modelColumn, pointers bool) (string, string) {
+	base, params, unsigned := parseColumnType(c.typ)
+	typ, null, pkg := "[]byte", "[]byte", ""
+	switch base {
+	case "tinyint":
+		typ, null = "int8", "sql.NullInt16"
+		if params == "1" {
+			typ, null = "bool", "sql.NullBool"
+		} else if unsigned {
+			typ, null = "uint8", "sql.NullByte"
+		}
+	case "smallint", "year":
+		typ, null = "int16", "sql.NullInt16"
+		if unsigned {
+			typ, null = "uint16", "sql.NullInt32"
+		}
+	case "mediumint", "int", "integer":
+		typ, null = "int32", "sql.NullInt32"
+		if unsigned {
+			typ, null = "uint32", "sql.NullInt64"
+		}
+	case "bigint":
+		typ, null = "int64", "sql.NullInt64"
+		if unsigned {
+			// database/sql has no unsigned Null type.
+			typ, null = "uint64", "*uint64"
+		}
+	case "float":
+		typ, null = "float32", "sql.NullFloat64"
+	case "double", "real":
+		typ, null = "float64", "sql.NullFloat64"
+	case "decimal", "numeric", "dec", "fixed":
+		// Kept as text, since float64 would lose precision.
+		typ, null = "string", "sql.NullString"
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext", "enum", "set", "time":
+		typ, null = "string", "sql.NullString"
+	case "date", "datetime", "timestamp":
+		typ, null, pkg = "time.Time", "sql.NullTime", "time"
+	case "json":
+		typ, null, pkg = "json.RawMessage", "json.RawMessage", "encoding/json"
+	}
+	if !c.nullable {
+		return typ, pkg
+	}
+	if pointers && !strings.HasPrefix(typ, "[]") && typ != "json.RawMessage" {
+		return "*" + typ, pkg
+	}
+	if strings.HasPrefix(null, "sql.") {
+		pkg = "database/sql"
+	}
+	return null, pkg
+}
+
+// goModel renders the struct for table and, for gorm, its TableName method.
+func goModel(table, structName, orm string, columns []modelColumn, unique map[string][]string, pointers bool) string {
+	imports := make(map[string]bool)
+	var fields []string
+	used := make(map[string]int)
+	for _, c := range columns {
+		name := goIdentifier(c.name)
+		// Columns such as user_id and userId would clash.
+		if used[name]++; used[name] > 1 {
+			name = fmt.Sprintf("%s%d", name, used[name])
+		}
+		typ, pkg := goFieldType(c, pointers)
+		if pkg != "" {
+			imports[pkg] = true
+		}
+		tags := fmt.Sprintf(`db:"%s" json:"%s"`, c.name, c.name)
+		if orm == "gorm" {
+			parts := []string{"column:" + c.name}
+			if c.key == "PRI" {
+				parts = append(parts, "primaryKey")
+			}
+			if strings.Contains(c.extra, "auto_increment") {
+				parts = append(parts, "autoIncrement")
+			}
+			parts = append(parts, "type:"+c.typ)
+			if c.generated() {
+				// Generated columns are read-only.
+				parts = append(parts, "->")
+			}
+			if !c.nullable && c.key != "PRI" {
+				parts = append(parts, "not null")
+			}
+			for _, index := range sortedKeys(unique) {
+				if index != "PRIMARY" && slices.Equal(unique[index], []string{c.name}) {
+					parts = append(parts, "uniqueIndex:"+index)
+				}
+			}
+			tags += fmt.Sprintf(` gorm:"%s"`, strings.Join(parts, ";"))
+		}
+		field := fmt.Sprintf("\t%s %s `%s`", name, typ, tags)
+		if c.comment != "" {
+			field += " // " + strings.ReplaceAll(c.comment, "\n", " ")
+		}
+		fields = append(fields, field)
+	}
+
+	var b strings.Builder
+	if len(imports) > 0 {
+		b.WriteString("import (\n")
+		for _, pkg := range sortedKeys(imports) {
+			fmt.Fprintf(&b, "\t%q\n", pkg)
+		}
+		b.WriteString(")\n\n")
+	}
+	fmt.Fprintf(&b, "// %s is a row of the %s table.\n", structName, table)
+	fmt.Fprintf(&b, "type %s struct {\n%s\n}\n", structName, strings.Join(fields, "\n"))
+	if orm == "gorm" {
+		fmt.Fprintf(&b, "\n// TableName tells GORM the name of the table.\nfunc (%s) TableName() string {\n\treturn %q\n}\n", structName, table)
+	}
+	return b.String()
+}
+
+// sqlcQueries renders annotated queries for sqlc: get by primary key, list,
+// create and delete.
+func sqlcQueries(table, structName string, columns []modelColumn) string {
+	var pk, insert []string
+	for _, c := range columns {
+		if c.key == "PRI" {
+			pk = append(pk, quoteIdentifier(c.name)+" = ?")
+		}
+		if !strings.Contains(c.extra, "auto_increment") && !c.generated() {
+			insert = append(insert, quoteIdentifier(c.name))
+		}
+	}
+	name := quoteIdentifier(table)
+	plural := goIdentifier(table)
+	if plural == structName {
+		plural += "s"
+	}
+	var b strings.Builder
+	if len(pk) > 0 {
+		fmt.Fprintf(&b, "-- name: Get%s :one\nSELECT * FROM %s\nWHERE %s LIMIT 1;\n\n", structName, name, strings.Join(pk, " AND "))
+	}
+	fmt.Fprintf(&b, "-- name: List%s :many\nSELECT * FROM %s;\n\n", plural, name)
+	if len(insert) > 0 {
+		fmt.Fprintf(&b, "-- name: Create%s :execresult\nINSERT INTO %s (%s)\nVALUES (%s);\n", structName, name,
+			strings.Join(insert, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(insert)), ", "))
+	}
+	if len(pk) > 0 {
+		fmt.Fprintf(&b, "\n-- name: Delete%s :exec\nDELETE FROM %s\nWHERE %s;\n", structName, name, strings.Join(pk, " AND "))
+	}
+	return b.String()
+}
+
+func GenerateModel(ctx context.Context, req *mcp.CallToolRequest, args GenerateModelParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	lang := strings.ToLower(args.Lang)
+	switch lang {
+	case "", "go":
+		lang = "go"
+	case "ts":
+		lang = modelTypeScript
+	case "jsonschema", "json-schema":
+		lang = modelJSONSchema
+	case modelTypeScript, modelZod, modelJSONSchema:
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported lang %q; use go, typescript, zod or json_schema", args.Lang)},
+			},
+		}, nil, nil
+	}
+	orm := strings.ToLower(args.ORM)
+	switch {
+	case orm != "" && lang != "go":
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "orm only applies to lang go"},
+			},
+		}, nil, nil
+	case orm != "" && orm != "gorm" && orm != "sqlc":
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown orm %q; use gorm, sqlc or leave it empty for a plain struct", args.ORM)},
+			},
+		}, nil, nil
+	}
+	pointers := orm == "gorm"
+	switch strings.ToLower(args.Nullable) {
+	case "":
+	case "sql":
+		pointers = false
+	case "pointer":
+		pointers = true
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown nullable %q; use sql or pointer", args.Nullable)},
+			},
+		}, nil, nil
+	}
+	pkg := args.Package
+	if pkg == "" {
+		pkg = "models"
+	}
+
+	columns, err := modelColumns(ctx, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read table: %v", err)},
+			},
+		}, nil, nil
+	}
+	structName := args.StructName
+	if structName == "" {
+		structName = goIdentifier(singular(args.Table))
+	}
+	if lang != "go" {
+		return typedModel(lang, args.Database, args.Table, structName, columns)
+	}
+	unique, err := uniqueKeys(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read table: %v", err)},
+			},
+		}, nil, nil
+	}
+	source := fmt.Sprintf("package %s\n\n", pkg) + goModel(args.Table, structName, orm, columns, unique, pointers)
+	code, err := format.Source([]byte(source))
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Generated code does not parse (check struct_name and package): %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("Go model for %s.%s", quoteIdentifier(args.Database), quoteIdentifier(args.Table))
+	if orm != "" {
+		resultText += " with " + orm
+	}
+	resultText += ". Time columns scan into time.Time only with parseTime=true in the DSN.\n\n```go\n" + string(code) + "```\n"
+	structured := map[string]any{
+		"structName": structName,
+		"code":       string(code),
+	}
+	if orm == "sqlc" {
+		queries := sqlcQueries(args.Table, structName, columns)
+		resultText += "\nAnnotated queries for sqlc (e.g. query.sql); sqlc generates its own model from the schema, so the struct above is for code outside it:\n\n```sql\n" + queries + "```\n"
+		structured["queries"] = queries
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, structured, nil
+}
+
+// typedModel answers generate_model for TypeScript, Zod and JSON Schema,
+// which describe rows as JSON carries them.
+func typedModel(lang, database, table, name string, columns []modelColumn) (*mcp.CallToolResult, any, error) {
+	fields := make([]modelField, len(columns))
+	for i, c := range columns {
+		fields[i] = newModelField(c)
+	}
+	var code, fence, title string
+	switch lang {
+	case modelTypeScript:
+		code, fence, title = typeScriptModel(table, name, fields), "ts", "TypeScript interface"
+	case modelZod:
+		code, fence, title = zodModel(table, name, fields), "ts", "Zod schema"
+	case modelJSONSchema:
+		var err error
+		if code, err = jsonSchemaModel(table, name, fields); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to render JSON Schema: %v", err)},
+				},
+			}, nil, nil
+		}
+		fence, title = "json", "JSON Schema"
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("%s for %s.%s:\n\n```%s\n%s```\n", title, quoteIdentifier(database), quoteIdentifier(table), fence, code)},
+		},
+	}, map[string]any{
+		"name": name,
+		"code": code,
+	}, nil
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Languages generate_model writes besides Go.
+const (
+	modelTypeScript = "typescript"
+	modelZod        = "zod"
+	modelJSONSchema = "json_schema"
+)
+
+// jsIdentifierPattern matches property names TypeScript and Zod can write
+// without quotes.
+var jsIdentifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// modelField is a column's value as it appears in JSON, which the
+// TypeScript, Zod and JSON Schema generators describe.
+type modelField struct {
+	name string
+	// kind is integer, number, boolean, string, decimal, date, datetime,
+	// time, binary, json, enum or set.
+	kind      string
+	nullable  bool
+	min       int64
+	max       uint64
+	maxLength int
+	// values are the members of an ENUM or SET.
+	values  []string
+	comment string
+}
+
+// integerRanges are the signed and unsigned ranges of the integer types.
+var integerRanges = map[string][3]uint64{
+	// minimum magnitude (signed), maximum signed, maximum unsigned
+	"tinyint":   {1 << 7, 1<<7 - 1, 1<<8 - 1},
+	"smallint":  {1 << 15, 1<<15 - 1, 1<<16 - 1},
+	"mediumint": {1 << 23, 1<<23 - 1, 1<<24 - 1},
+	"int":       {1 << 31, 1<<31 - 1, 1<<32 - 1},
+	"integer":   {1 << 31, 1<<31 - 1, 1<<32 - 1},
+	"bigint":    {1 << 63, 1<<63 - 1, math.MaxUint64},
+}
+
+// newModelField describes the JSON value of column c.
+func newModelField(c modelColumn) modelField {
+	base, params, unsigned := parseColumnType(c.typ)
+	f := modelField{name: c.name, nullable: c.nullable, comment: c.comment}
+	switch base {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		if base == "tinyint" && params == "1" {
+			f.kind = "boolean"
+			break
+		}
+		r := integerRanges[base]
+		f.kind = "integer"
+		if unsigned {
+			f.max = r[2]
+		} else {
+			f.min, f.max = -int64(r[0]-1)-1, r[1]
+		}
+	case "year":
+		f.kind, f.min, f.max = "integer", 1901, 2155
+	case "float", "double", "real":
+		f.kind = "number"
+	case "decimal", "numeric", "dec", "fixed":
+		f.kind = "decimal"
+	case "char", "varchar", "tinytext", "text", "mediumtext", "longtext":
+		f.kind = "string"
+		if n, err := strconv.Atoi(params); err == nil {
+			f.maxLength = n
+		}
+	case "enum", "set":
+		f.kind, f.values = base, columnTypeValues(c.typ)
+	case "date", "datetime", "timestamp", "time", "json":
+		f.kind = base
+		if base == "timestamp" {
+			f.kind = "datetime"
+		}
+	default:
+		f.kind = "binary"
+	}
+	return f
+}
+
+// propertyName quotes name for a TypeScript or Zod object if it is not an
+// identifier.
+func propertyName(name string) string {
+	if jsIdentifierPattern.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// jsStrings renders values as a list of JavaScript string literals.
+func jsStrings(values []string, sep string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, sep)
+}
+
+// typeScriptModel renders an interface for the rows of a table.
+func typeScriptModel(table, name string, fields []modelField) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "/** A row of the %s table. */\nexport interface %s {\n", table, name)
+	for _, f := range fields {
+		var typ string
+		switch f.kind {
+		case "integer", "number":
+			typ = "number"
+		case "boolean":
+			typ = "boolean"
+		case "enum":
+			typ = jsStrings(f.values, " | ")
+		case "json":
+			typ = "unknown"
+		default:
+			typ = "string"
+		}
+		// unknown already takes in null.
+		if f.nullable && f.kind != "json" {
+			typ += " | null"
+		}
+		if note := fieldNote(f); note != "" {
+			fmt.Fprintf(&b, "  /** %s */\n", note)
+		}
+		fmt.Fprintf(&b, "  %s: %s;\n", propertyName(f.name), typ)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// fieldNote is the comment a generated property gets: the column comment
+// and what a string holds when that is not obvious from its type.
+func fieldNote(f modelField) string {
+	var notes []string
+	if f.comment != "" {
+		notes = append(notes, strings.ReplaceAll(f.comment, "*/", "* /"))
+	}
+	switch f.kind {
+	case "decimal":
+		notes = append(notes, "Decimal as a string, to keep its precision.")
+	case "date":
+		notes = append(notes, "Date as YYYY-MM-DD.")
+	case "datetime":
+		notes = append(notes, "Date and time as YYYY-MM-DD hh:mm:ss.")
+	case "time":
+		notes = append(notes, "Time as hh:mm:ss.")
+	case "binary":
+		notes = append(notes, "Binary data, base64-encoded.")
+	case "set":
+		notes = append(notes, "Comma-separated members of: "+strings.Join(f.values, ", ")+".")
+	}
+	return strings.Join(notes, " ")
+}
+
+// zodModel renders a Zod schema for the rows of a table and the type it
+// infers.
+func zodModel(table, name string, fields []modelField) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "import { z } from \"zod\";\n\n/** A row of the %s table. */\nexport const %sSchema = z.object({\n", table, name)
+	for _, f := range fields {
+		var schema string
+		switch f.kind {
+		case "integer":
+			schema = "z.number().int()"
+			// Bounds past 2^53 cannot be written exactly as numbers.
+			if f.min != 0 && f.min > -(1<<53) {
+				schema += fmt.Sprintf(".min(%d)", f.min)
+			} else if f.min == 0 {
+				schema += ".nonnegative()"
+			}
+			if f.max < 1<<53 {
+				schema += fmt.Sprintf(".max(%d)", f.max)
+			}
+		case "number":
+			schema = "z.number()"
+		case "boolean":
+			schema = "z.boolean()"
+		case "enum":
+			schema = "z.enum([" + jsStrings(f.values, ", ") + "])"
+		case "json":
+			schema = "z.unknown()"
+		case "date":
+			schema = `z.string().regex(/^\d{4}-\d{2}-\d{2}$/)`
+		default:
+			schema = "z.string()"
+			if f.maxLength > 0 {
+				schema += fmt.Sprintf(".max(%d)", f.maxLength)
+			}
+		}
+		if f.nullable && f.kind != "json" {
+			schema += ".nullable()"
+		}
+		if note := fieldNote(f); note != "" {
+			schema += fmt.Sprintf(".describe(%s)", strconv.Quote(note))
+		}
+		fmt.Fprintf(&b, "  %s: %s,\n", propertyName(f.name), schema)
+	}
+	fmt.Fprintf(&b, "});\n\nexport type %s = z.infer<typeof %sSchema>;\n", name, name)
+	return b.String()
+}
+
+// jsonSchemaModel renders a JSON Schema (draft 2020-12) document for the
+// rows of a table.
+func jsonSchemaModel(table, name string, fields []modelField) (string, error) {
+	properties := make(map[string]any, len(fields))
+	required := make([]string, 0, len(fields))
+	for _, f := range fields {
+		properties[f.name] = jsonSchemaProperty(f)
+		required = append(required, f.name)
+	}
+	data, err := json.MarshalIndent(map[string]any{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"title":                name,
+		"description":          fmt.Sprintf("A row of the %s table.", table),
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}, "", "  ")
+	return string(data) + "\n", err
+}
+
+// jsonSchemaProperty is the JSON Schema of field f's values.
+func jsonSchemaProperty(f modelField) map[string]any {
+	p := make(map[string]any)
+	typ := "string"
+	switch f.kind {
+	case "integer":
+		typ = "integer"
+		p["minimum"], p["maximum"] = f.min, f.max
+	case "number":
+		typ = "number"
+	case "boolean":
+		typ = "boolean"
+	case "enum":
+		values := make([]any, 0, len(f.values)+1)
+		for _, v := range f.values {
+			values = append(values, v)
+		}
+		if f.nullable {
+			values = append(values, nil)
+		}
+		p["enum"] = values
+	case "json":
+		typ = ""
+	case "date":
+		p["format"] = "date"
+	case "binary":
+		p["contentEncoding"] = "base64"
+	}
+	if f.maxLength > 0 {
+		p["maxLength"] = f.maxLength
+	}
+	switch {
+	case typ == "" || f.kind == "enum":
+	case f.nullable:
+		p["type"] = []string{typ, "null"}
+	default:
+		p["type"] = typ
+	}
+	if note := fieldNote(f); note != "" {
+		p["description"] = note
+	}
+	return p
+}
@@ -0,0 +1,11 @@
+package main
+
+import "errors"
+
+// offline guarantees the server only connects to the configured MySQL
+// servers: the updater, the update check, trace export, the MySQL Router
+// REST API and the AWS API calls of aurora_failover_history are all off.
+// It is set with -offline or "offline": true in the config file.
+var offline bool
+
+var errOffline = errors.New("outbound HTTP is disabled in offline mode")
@@ -0,0 +1,620 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const onlineAlterOutputLines = 20
+
+type OnlineAlterParams struct {
+	Database  string `json:"database"`
+	Table     string `json:"table"`
+	Alter     string `json:"alter,omitempty"`
+	Tool      string `json:"tool,omitempty"`
+	Action    string `json:"action,omitempty"`
+	ChunkSize int    `json:"chunk_size,omitempty"`
+	Confirm   bool   `json:"confirm,omitempty"`
+}
+
+// onlineMigration is a running gh-ost or pt-online-schema-change process.
+type onlineMigration struct {
+	mu       sync.Mutex
+	tool     string
+	database string
+	table    string
+	alter    string
+	cmd      *exec.Cmd
+	started  time.Time
+	percent  float64
+	state    string
+	output   []string
+	flagFile string
+	done     chan struct{}
+	err      error
+}
+
+var onlineMigrations = struct {
+	sync.Mutex
+	byTable map[string]*onlineMigration
+}{byTable: make(map[string]*onlineMigration)}
+
+var (
+	ghostProgressPattern = regexp.MustCompile(`Copy: \d+/\d+ ([\d.]+)%.*State: ([^;]+)`)
+	ptoscProgressPattern = regexp.MustCompile(`Copying .*:\s+(\d+)% `)
+)
+
+func OnlineAlter(ctx context.Context, req *mcp.CallToolRequest, args OnlineAlterParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	dbConfig := currentDBConfig()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	// gh-ost and pt-online-schema-change rely on binlogs and triggers that
+	// TiDB lacks; it runs every ALTER TABLE online by itself. Behind vtgate
+	// they would only reach one shard, and Vitess has its own online DDL.
+	if server := serverInfoFor(ctx, db); server.TiDB {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("online_alter is not supported on %s, which changes schemas online without copying tables; use the alter tools or execute_query and follow the job with tidb_ddl_jobs", server)},
+			},
+		}, nil, nil
+	} else if server.Vitess {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "online_alter is not supported through Vitess; use Vitess online DDL (set @@ddl_strategy, e.g. 'vitess') or a PlanetScale deploy request, and follow it with SHOW VITESS_MIGRATIONS"},
+			},
+		}, nil, nil
+	}
+
+	key := schemaCacheKey(args.Database, args.Table)
+	onlineMigrations.Lock()
+	m := onlineMigrations.byTable[key]
+	onlineMigrations.Unlock()
+
+	action := args.Action
+	if action == "" {
+		action = "check"
+	}
+	// The dry run of check and status change nothing, so only start,
+	// cut_over and abort are refused in read-only mode.
+	if action == "start" || action == "cut_over" || action == "abort" {
+		if err := checkWritable("online_alter"); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: err.Error()},
+				},
+			}, nil, nil
+		}
+	}
+	switch action {
+	case "check", "start":
+	case "status", "cut_over", "abort":
+		if m == nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("No online migration is running for %s.%s", args.Database, args.Table)},
+				},
+			}, nil, nil
+		}
+		return onlineMigrationAction(ctx, req, key, m, action, args.Confirm)
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported action %q (use check, start, status, cut_over or abort)", args.Action)},
+			},
+		}, nil, nil
+	}
+
+	if m != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("An online migration is already running for %s.%s; use action: status", args.Database, args.Table)},
+			},
+		}, nil, nil
+	}
+	if args.Alter == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "alter is required, e.g. \"ADD COLUMN shipped_at DATETIME NULL\""},
+			},
+		}, nil, nil
+	}
+	if dbConfig == nil || dbConfig.Net != "tcp" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "online_alter needs a TCP connection (tcp(host:port) in the DSN) to pass to the external tool"},
+			},
+		}, nil, nil
+	}
+
+	tool, problems, warnings, err := onlineAlterPrerequisites(ctx, args)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to check prerequisites: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("Online ALTER of %s.%s with %s: %s\n", args.Database, args.Table, tool, args.Alter)
+	for _, p := range problems {
+		resultText += "Blocker: " + p + "\n"
+	}
+	for _, w := range warnings {
+		resultText += "Warning: " + w + "\n"
+	}
+	structured := map[string]any{
+		"tool":     tool,
+		"blockers": problems,
+		"warnings": warnings,
+	}
+	if len(problems) > 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, structured, nil
+	}
+
+	if action == "check" {
+		// Both tools validate the ALTER and the table without copying any
+		// rows when run without --execute.
+		m, err := startOnlineMigration(args, tool, false)
+		if err == nil {
+			<-m.done
+			err = m.err
+		}
+		if m != nil {
+			resultText += "\nDry run output:\n" + strings.Join(m.output, "\n") + "\n"
+		}
+		if err != nil {
+			resultText += fmt.Sprintf("\nDry run failed: %v", err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: resultText},
+				},
+			}, structured, nil
+		}
+		resultText += "\nDry run succeeded. Re-run with action: start and confirm: true to copy the table."
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, structured, nil
+	}
+
+	if !args.Confirm {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText + "\naction: start requires confirm: true. Run action: check first."},
+			},
+		}, structured, nil
+	}
+
+	m, err = startOnlineMigration(args, tool, true)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to start %s: %v", tool, err)},
+			},
+		}, nil, nil
+	}
+	audit("online_alter", fmt.Sprintf("%s: ALTER TABLE %s %s", tool, qualifiedTable(args.Database, args.Table), args.Alter), 0, nil)
+
+	onlineMigrations.Lock()
+	onlineMigrations.byTable[key] = m
+	onlineMigrations.Unlock()
+
+	return waitOnlineMigration(ctx, req, key, m)
+}
+
+// onlineAlterPrerequisites picks the external tool and checks the server
+// settings and table features it depends on. problems block the migration;
+// warnings do not.
+func onlineAlterPrerequisites(ctx context.Context, args OnlineAlterParams) (string, []string, []string, error) {
+	db := currentDB()
+	var problems, warnings []string
+
+	tool := args.Tool
+	switch tool {
+	case "":
+		if _, err := exec.LookPath("gh-ost"); err == nil {
+			tool = "gh-ost"
+		} else if _, err := exec.LookPath("pt-online-schema-change"); err == nil {
+			tool = "pt-osc"
+		} else {
+			return "", []string{"neither gh-ost nor pt-online-schema-change is installed on the server's PATH"}, nil, nil
+		}
+	case "gh-ost":
+		if _, err := exec.LookPath("gh-ost"); err != nil {
+			problems = append(problems, "gh-ost is not installed on the server's PATH")
+		}
+	case "pt-osc":
+		if _, err := exec.LookPath("pt-online-schema-change"); err != nil {
+			problems = append(problems, "pt-online-schema-change is not installed on the server's PATH")
+		}
+	default:
+		return "", nil, nil, fmt.Errorf("unsupported tool %q (use gh-ost or pt-osc)", tool)
+	}
+	// pt-osc takes the table as a DSN of comma-separated key=value pairs,
+	// which has no way to escape either character.
+	if tool == "pt-osc" && (strings.ContainsAny(args.Database, ",=") || strings.ContainsAny(args.Table, ",=")) {
+		problems = append(problems, "pt-osc cannot name a database or table containing , or = in its DSN (use tool: gh-ost)")
+	}
+
+	cols, err := tableColumns(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(cols) == 0 {
+		return tool, append(problems, fmt.Sprintf("table %s.%s does not exist", args.Database, args.Table)), nil, nil
+	}
+	keys, err := uniqueKeys(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	if len(keys) == 0 {
+		problems = append(problems, "the table has no PRIMARY or UNIQUE key, which both tools need to copy rows in chunks")
+	}
+
+	var triggers int
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM information_schema.TRIGGERS
+		WHERE EVENT_OBJECT_SCHEMA = ? AND EVENT_OBJECT_TABLE = ?
+	`, args.Database, args.Table).Scan(&triggers); err != nil {
+		return "", nil, nil, err
+	}
+	var foreignKeys int
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM information_schema.REFERENTIAL_CONSTRAINTS
+		WHERE (CONSTRAINT_SCHEMA = ? AND TABLE_NAME = ?)
+		   OR (UNIQUE_CONSTRAINT_SCHEMA = ? AND REFERENCED_TABLE_NAME = ?)
+	`, args.Database, args.Table, args.Database, args.Table).Scan(&foreignKeys); err != nil {
+		return "", nil, nil, err
+	}
+	if foreignKeys > 0 {
+		problems = append(problems, fmt.Sprintf("the table has %d foreign keys to or from it, which neither tool migrates safely", foreignKeys))
+	}
+
+	switch tool {
+	case "gh-ost":
+		if triggers > 0 {
+			problems = append(problems, fmt.Sprintf("the table has %d triggers; gh-ost does not support tables with triggers (use tool: pt-osc)", triggers))
+		}
+		vars := make(map[string]string)
+		for _, name := range []string{"log_bin", "binlog_format", "binlog_row_image"} {
+			var value string
+			if err := db.QueryRowContext(ctx, "SELECT @@GLOBAL."+name).Scan(&value); err != nil {
+				return "", nil, nil, err
+			}
+			vars[name] = value
+		}
+		if vars["log_bin"] != "1" && !strings.EqualFold(vars["log_bin"], "ON") {
+			problems = append(problems, "binary logging is disabled; gh-ost reads row changes from the binlog")
+		}
+		if !strings.EqualFold(vars["binlog_format"], "ROW") {
+			problems = append(problems, fmt.Sprintf("binlog_format is %s; gh-ost requires ROW", vars["binlog_format"]))
+		}
+		if !strings.EqualFold(vars["binlog_row_image"], "FULL") {
+			problems = append(problems, fmt.Sprintf("binlog_row_image is %s; gh-ost requires FULL", vars["binlog_row_image"]))
+		}
+	case "pt-osc":
+		if triggers > 0 {
+			warnings = append(warnings, fmt.Sprintf("the table has %d triggers; they are recreated on the new table with --preserve-triggers (MySQL 5.7.2+)", triggers))
+		}
+		warnings = append(warnings, "pt-osc swaps the tables as soon as the copy finishes; the cut-over cannot be postponed")
+	}
+
+	return tool, problems, warnings, nil
+}
+
+// optionFileValue quotes a value for a MySQL option file, where an unquoted
+// value ends at # and loses leading and trailing spaces. It uses only the
+// escapes both MySQL's and gh-ost's option file parsers read.
+func optionFileValue(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// startOnlineMigration launches the external tool. Without execute it runs
+// the tool's own dry-run mode.
+func startOnlineMigration(args OnlineAlterParams, tool string, execute bool) (*onlineMigration, error) {
+	dbConfig := currentDBConfig()
+	// Credentials go into a private option file instead of the command line,
+	// where any local user could read them from the process list.
+	conf, err := os.CreateTemp("", "mysql-mcp-*.cnf")
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(conf, "[client]\nuser=%s\npassword=%s\n", optionFileValue(dbConfig.User), optionFileValue(dbConfig.Passwd))
+	conf.Close()
+
+	host, port := dbConfig.Addr, "3306"
+	if i := strings.LastIndex(dbConfig.Addr, ":"); i >= 0 {
+		host, port = dbConfig.Addr[:i], dbConfig.Addr[i+1:]
+	}
+
+	m := &onlineMigration{
+		tool:     tool,
+		database: args.Database,
+		table:    args.Table,
+		alter:    args.Alter,
+		started:  time.Now(),
+		state:    "starting",
+		done:     make(chan struct{}),
+	}
+
+	var cmd *exec.Cmd
+	switch tool {
+	case "gh-ost":
+		cmdArgs := []string{
+			"--conf=" + conf.Name(),
+			"--host=" + host,
+			"--port=" + port,
+			"--database=" + args.Database,
+			"--table=" + args.Table,
+			"--alter=" + args.Alter,
+			"--allow-on-master",
+			"--exact-rowcount",
+			"--concurrent-rowcount",
+			"--default-retries=120",
+		}
+		if args.ChunkSize > 0 {
+			cmdArgs = append(cmdArgs, "--chunk-size="+strconv.Itoa(args.ChunkSize))
+		}
+		if execute {
+			// gh-ost waits before swapping tables for as long as this file
+			// exists; removing it is the cut-over confirmation.
+			m.flagFile = conf.Name() + ".postpone"
+			if err := os.WriteFile(m.flagFile, nil, 0600); err != nil {
+				os.Remove(conf.Name())
+				return nil, err
+			}
+			cmdArgs = append(cmdArgs, "--postpone-cut-over-flag-file="+m.flagFile, "--execute")
+		}
+		cmd = exec.Command("gh-ost", cmdArgs...)
+	case "pt-osc":
+		cmdArgs := []string{
+			"--defaults-file=" + conf.Name(),
+			"--alter=" + args.Alter,
+			"--progress=percentage,1",
+			"--preserve-triggers",
+		}
+		if args.ChunkSize > 0 {
+			cmdArgs = append(cmdArgs, "--chunk-size="+strconv.Itoa(args.ChunkSize))
+		}
+		if execute {
+			cmdArgs = append(cmdArgs, "--execute")
+		} else {
+			cmdArgs = append(cmdArgs, "--dry-run")
+		}
+		cmdArgs = append(cmdArgs, fmt.Sprintf("D=%s,t=%s,h=%s,P=%s", args.Database, args.Table, host, port))
+		cmd = exec.Command("pt-online-schema-change", cmdArgs...)
+	}
+
+	pr, pw := io.Pipe()
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+	m.cmd = cmd
+	if err := cmd.Start(); err != nil {
+		os.Remove(conf.Name())
+		os.Remove(m.flagFile)
+		return nil, err
+	}
+
+	scanned := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			m.record(scanner.Text())
+		}
+		io.Copy(io.Discard, pr)
+		close(scanned)
+	}()
+	go func() {
+		err := cmd.Wait()
+		pw.Close()
+		<-scanned
+		os.Remove(conf.Name())
+		os.Remove(m.flagFile)
+		m.mu.Lock()
+		m.err = err
+		if err != nil {
+			m.state = "failed"
+		} else {
+			m.state = "completed"
+			m.percent = 100
+		}
+		m.mu.Unlock()
+		close(m.done)
+	}()
+	return m, nil
+}
+
+// record keeps the tail of the tool's output and parses progress from it.
+func (m *onlineMigration) record(line string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.output = append(m.output, line)
+	if len(m.output) > onlineAlterOutputLines {
+		m.output = m.output[len(m.output)-onlineAlterOutputLines:]
+	}
+	if match := ghostProgressPattern.FindStringSubmatch(line); match != nil {
+		m.percent, _ = strconv.ParseFloat(match[1], 64)
+		m.state = strings.TrimSpace(match[2])
+	} else if match := ptoscProgressPattern.FindStringSubmatch(line); match != nil {
+		m.percent, _ = strconv.ParseFloat(match[1], 64)
+		m.state = "copying"
+	}
+}
+
+func (m *onlineMigration) summary() (string, map[string]any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	elapsed := time.Since(m.started).Round(time.Second)
+	text := fmt.Sprintf("%s: %s, %.1f%% copied, running for %s\n", m.tool, m.state, m.percent, elapsed)
+	if len(m.output) > 0 {
+		text += "\nRecent output:\n" + strings.Join(m.output, "\n") + "\n"
+	}
+	return text, map[string]any{
+		"tool":    m.tool,
+		"state":   m.state,
+		"percent": m.percent,
+		"elapsed": elapsed.String(),
+	}
+}
+
+// waitOnlineMigration blocks until the migration finishes, reaches a
+// postponed cut-over, or the request is cancelled, sending progress
+// notifications while it waits.
+func waitOnlineMigration(ctx context.Context, req *mcp.CallToolRequest, key string, m *onlineMigration) (*mcp.CallToolResult, any, error) {
+	token := req.Params.GetProgressToken()
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.done:
+			onlineMigrations.Lock()
+			delete(onlineMigrations.byTable, key)
+			onlineMigrations.Unlock()
+			invalidateTable(m.database, m.table)
+			text, structured := m.summary()
+			if m.err != nil {
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("Online migration failed: %v\n%s", m.err, text)},
+					},
+				}, structured, nil
+			}
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "Online migration completed.\n" + text},
+				},
+			}, structured, nil
+		case <-ctx.Done():
+			text, structured := m.summary()
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "Stopped waiting; the migration keeps running in the background. Use action: status to follow it.\n" + text},
+				},
+			}, structured, nil
+		case <-ticker.C:
+			m.mu.Lock()
+			percent, state := m.percent, m.state
+			m.mu.Unlock()
+			if token != nil {
+				req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+					ProgressToken: token,
+					Progress:      percent,
+					Total:         100,
+					Message:       fmt.Sprintf("%s: %s", m.tool, state),
+				})
+			}
+			if strings.Contains(state, "postpon") {
+				text, structured := m.summary()
+				return &mcp.CallToolResult{
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: "Row copy finished; gh-ost is keeping the tables in sync and waiting for cut-over.\nUse action: cut_over with confirm: true to swap the tables, or action: abort.\n" + text},
+					},
+				}, structured, nil
+			}
+		}
+	}
+}
+
+func onlineMigrationAction(ctx context.Context, req *mcp.CallToolRequest, key string, m *onlineMigration, action string, confirm bool) (*mcp.CallToolResult, any, error) {
+	switch action {
+	case "cut_over":
+		if m.flagFile == "" {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "This migration swaps tables automatically when the copy finishes; there is no cut-over to trigger."},
+				},
+			}, nil, nil
+		}
+		if !confirm {
+			text, structured := m.summary()
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text + "\nCut-over briefly locks the table and swaps in the altered copy. Re-run with confirm: true to proceed."},
+				},
+			}, structured, nil
+		}
+		if err := os.Remove(m.flagFile); err != nil && !os.IsNotExist(err) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to release cut-over: %v", err)},
+				},
+			}, nil, nil
+		}
+		audit("online_alter", fmt.Sprintf("%s cut-over: %s", m.tool, m.alter), 0, nil)
+		return waitOnlineMigration(ctx, req, key, m)
+	case "abort":
+		if !confirm {
+			text, structured := m.summary()
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: text + "\nAborting stops the copy and leaves the original table untouched; helper tables may need cleanup. Re-run with confirm: true to abort."},
+				},
+			}, structured, nil
+		}
+		m.cmd.Process.Signal(os.Interrupt)
+		select {
+		case <-m.done:
+		case <-time.After(30 * time.Second):
+			m.cmd.Process.Kill()
+			<-m.done
+		}
+		onlineMigrations.Lock()
+		delete(onlineMigrations.byTable, key)
+		onlineMigrations.Unlock()
+		audit("online_alter", fmt.Sprintf("%s aborted: %s", m.tool, m.alter), 0, nil)
+		text, structured := m.summary()
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Online migration aborted.\n" + text},
+			},
+		}, structured, nil
+	default:
+		text, structured := m.summary()
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, structured, nil
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// maxOpenAPITables is the most tables generate_openapi describes when
+	// none are named.
+	maxOpenAPITables = 100
+	// defaultOpenAPIPageSize and maxOpenAPIPageSize are the limit
+	// parameter's default and maximum on list endpoints.
+	defaultOpenAPIPageSize = 100
+	maxOpenAPIPageSize     = 1000
+)
+
+type GenerateOpenAPIParams struct {
+	Database string `json:"database"`
+	// Tables defaults to every base table of the database.
+	Tables    []string `json:"tables,omitempty"`
+	Title     string   `json:"title,omitempty"`
+	Version   string   `json:"version,omitempty"`
+	ServerURL string   `json:"server_url,omitempty"`
+}
+
+// openAPIDocument collects the paths and component schemas of the tables
+// generate_openapi describes.
+type openAPIDocument struct {
+	paths   map[string]any
+	schemas map[string]any
+	notes   []string
+}
+
+// schemaName returns a component name based on name that no other schema
+// of the document uses.
+func (d *openAPIDocument) schemaName(name string) string {
+	unique := name
+	for n := 2; d.schemas[unique] != nil || d.schemas[unique+"Input"] != nil; n++ {
+		unique = fmt.Sprintf("%s%d", name, n)
+	}
+	return unique
+}
+
+// schemaRef refers to the component schema named name.
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// jsonContent is a request or response body holding schema as JSON.
+func jsonContent(description string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content":     map[string]any{"application/json": map[string]any{"schema": schema}},
+	}
+}
+
+// addTable adds the schemas of a table's rows and its CRUD endpoints:
+// list and create on the collection, and get, update and delete by
+// primary key, or by a unique key of NOT NULL columns when there is none.
+// Other single-column unique keys get a lookup endpoint of their own.
+func (d *openAPIDocument) addTable(table string, columns []modelColumn, unique map[string][]string) {
+	name := d.schemaName(goIdentifier(singular(table)))
+	plural := goIdentifier(table)
+	if plural == name {
+		plural += "s"
+	}
+
+	byName := make(map[string]modelField, len(columns))
+	rowProperties := make(map[string]any, len(columns))
+	inputProperties := make(map[string]any)
+	var required, inputRequired []string
+	for _, c := range columns {
+		f := newModelField(c)
+		byName[c.name] = f
+		p := jsonSchemaProperty(f)
+		required = append(required, c.name)
+		if strings.Contains(c.extra, "auto_increment") || c.generated() {
+			p["readOnly"] = true
+			rowProperties[c.name] = p
+			continue
+		}
+		rowProperties[c.name] = p
+		inputProperties[c.name] = p
+		if !c.nullable && !c.hasDefault {
+			inputRequired = append(inputRequired, c.name)
+		}
+	}
+	d.schemas[name] = map[string]any{
+		"type":        "object",
+		"description": fmt.Sprintf("A row of the %s table.", table),
+		"properties":  rowProperties,
+		"required":    required,
+	}
+	input := map[string]any{
+		"type":                 "object",
+		"description":          fmt.Sprintf("The columns of a new row of %s; those left out take their default.", table),
+		"properties":           inputProperties,
+		"additionalProperties": false,
+	}
+	if len(inputRequired) > 0 {
+		input["required"] = inputRequired
+	}
+	d.schemas[name+"Input"] = input
+	d.schemas[name+"Patch"] = map[string]any{
+		"type":                 "object",
+		"description":          fmt.Sprintf("The columns to change in a row of %s.", table),
+		"properties":           inputProperties,
+		"minProperties":        1,
+		"additionalProperties": false,
+	}
+
+	tag := []string{table}
+	collection := "/" + table
+	d.paths[collection] = map[string]any{
+		"get": map[string]any{
+			"tags":        tag,
+			"operationId": "list" + plural,
+			"summary":     fmt.Sprintf("List the rows of %s", table),
+			"parameters": []any{
+				map[string]any{
+					"name": "limit", "in": "query",
+					"schema": map[string]any{"type": "integer", "minimum": 1, "maximum": maxOpenAPIPageSize, "default": defaultOpenAPIPageSize},
+				},
+				map[string]any{
+					"name": "offset", "in": "query",
+					"schema": map[string]any{"type": "integer", "minimum": 0, "default": 0},
+				},
+			},
+			"responses": map[string]any{
+				"200": jsonContent("The rows", map[string]any{"type": "array", "items": schemaRef(name)}),
+			},
+		},
+		"post": map[string]any{
+			"tags":        tag,
+			"operationId": "create" + name,
+			"summary":     fmt.Sprintf("Insert a row into %s", table),
+			"requestBody": map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": schemaRef(name + "Input")}},
+			},
+			"responses": map[string]any{
+				"201": jsonContent("The inserted row", schemaRef(name)),
+				"400": map[string]any{"$ref": "#/components/responses/BadRequest"},
+			},
+		},
+	}
+
+	key := unique["PRIMARY"]
+	if len(key) == 0 {
+		for _, index := range sortedKeys(unique) {
+			if !slices.ContainsFunc(unique[index], func(col string) bool { return byName[col].nullable }) {
+				key = unique[index]
+				break
+			}
+		}
+	}
+	if len(key) == 0 {
+		d.notes = append(d.notes, fmt.Sprintf("%s has no primary key or unique key of NOT NULL columns, so it has no item endpoints", table))
+		return
+	}
+	path, params := collection, make([]any, 0, len(key))
+	for _, col := range key {
+		path += "/{" + col + "}"
+		params = append(params, pathParameter(byName[col]))
+	}
+	d.paths[path] = map[string]any{
+		"parameters": params,
+		"get": map[string]any{
+			"tags":        tag,
+			"operationId": "get" + name,
+			"summary":     fmt.Sprintf("Get a row of %s by %s", table, strings.Join(key, ", ")),
+			"responses": map[string]any{
+				"200": jsonContent("The row", schemaRef(name)),
+				"404": map[string]any{"$ref": "#/components/responses/NotFound"},
+			},
+		},
+		"patch": map[string]any{
+			"tags":        tag,
+			"operationId": "update" + name,
+			"summary":     fmt.Sprintf("Update a row of %s", table),
+			"requestBody": map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": schemaRef(name + "Patch")}},
+			},
+			"responses": map[string]any{
+				"200": jsonContent("The updated row", schemaRef(name)),
+				"400": map[string]any{"$ref": "#/components/responses/BadRequest"},
+				"404": map[string]any{"$ref": "#/components/responses/NotFound"},
+			},
+		},
+		"delete": map[string]any{
+			"tags":        tag,
+			"operationId": "delete" + name,
+			"summary":     fmt.Sprintf("Delete a row of %s", table),
+			"responses": map[string]any{
+				"204": map[string]any{"description": "The row was deleted"},
+				"404": map[string]any{"$ref": "#/components/responses/NotFound"},
+			},
+		},
+	}
+
+	for _, index := range sortedKeys(unique) {
+		cols := unique[index]
+		if len(cols) != 1 || slices.Equal(cols, key) || byName[cols[0]].nullable {
+			continue
+		}
+		col := cols[0]
+		d.paths[fmt.Sprintf("%s/by-%s/{%s}", collection, col, col)] = map[string]any{
+			"get": map[string]any{
+				"tags":        tag,
+				"operationId": "get" + name + "By" + goIdentifier(col),
+				"summary":     fmt.Sprintf("Get a row of %s by %s", table, col),
+				"parameters":  []any{pathParameter(byName[col])},
+				"responses": map[string]any{
+					"200": jsonContent("The row", schemaRef(name)),
+					"404": map[string]any{"$ref": "#/components/responses/NotFound"},
+				},
+			},
+		}
+	}
+}
+
+// pathParameter describes a key column as a path parameter.
+func pathParameter(f modelField) map[string]any {
+	f.nullable = false
+	return map[string]any{
+		"name":     f.name,
+		"in":       "path",
+		"required": true,
+		"schema":   jsonSchemaProperty(f),
+	}
+}
+
+func GenerateOpenAPI(ctx context.Context, req *mcp.CallToolRequest, args GenerateOpenAPIParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	if args.Database == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "database is required"},
+			},
+		}, nil, nil
+	}
+
+	tables := args.Tables
+	if len(tables) == 0 {
+		err := queryEach(ctx, db, `
+			SELECT TABLE_NAME FROM information_schema.TABLES
+			WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+			ORDER BY TABLE_NAME
+		`, []any{args.Database}, func(rows *sql.Rows) error {
+			var table string
+			if err := rows.Scan(&table); err != nil {
+				return err
+			}
+			tables = append(tables, table)
+			return nil
+		})
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to list tables: %v", err)},
+				},
+			}, nil, nil
+		}
+		if len(tables) == 0 {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Database %s has no tables", args.Database)},
+				},
+			}, nil, nil
+		}
+		if len(tables) > maxOpenAPITables {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Database %s has %d tables; name at most %d in tables", args.Database, len(tables), maxOpenAPITables)},
+				},
+			}, nil, nil
+		}
+	}
+
+	doc := openAPIDocument{paths: make(map[string]any), schemas: make(map[string]any)}
+	// Added first so that no table's schema takes the name.
+	doc.schemas["Error"] = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"message": map[string]any{"type": "string"}},
+		"required":   []string{"message"},
+	}
+	for _, table := range tables {
+		columns, err := modelColumns(ctx, args.Database, table)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to read table %s: %v", table, err)},
+				},
+			}, nil, nil
+		}
+		unique, err := uniqueKeys(ctx, db, args.Database, table)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to read keys of %s: %v", table, err)},
+				},
+			}, nil, nil
+		}
+		doc.addTable(table, columns, unique)
+	}
+
+	title := args.Title
+	if title == "" {
+		title = args.Database + " API"
+	}
+	version := args.Version
+	if version == "" {
+		version = "1.0.0"
+	}
+	document := map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       title,
+			"version":     version,
+			"description": fmt.Sprintf("CRUD endpoints for tables of the %s database.", args.Database),
+		},
+		"paths": doc.paths,
+		"components": map[string]any{
+			"schemas": doc.schemas,
+			"responses": map[string]any{
+				"NotFound":   jsonContent("No row has this key", schemaRef("Error")),
+				"BadRequest": jsonContent("The request body is not a valid row", schemaRef("Error")),
+			},
+		},
+	}
+	if args.ServerURL != "" {
+		document["servers"] = []any{map[string]any{"url": args.ServerURL}}
+	}
+	data, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to render the document: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("OpenAPI 3.1 document for %d table(s) of %s, %d path(s):\n", len(tables), quoteIdentifier(args.Database), len(doc.paths))
+	for _, note := range doc.notes {
+		resultText += "Note: " + note + "\n"
+	}
+	resultText += "\n```json\n" + string(data) + "\n```\n"
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"tables":   tables,
+		"notes":    doc.notes,
+		"document": document,
+	}, nil
+}
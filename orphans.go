@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const defaultOrphanSample = 5
+
+type FindOrphansParams struct {
+	Database        string   `json:"database"`
+	Tables          []string `json:"tables,omitempty"`
+	IncludeInferred bool     `json:"include_inferred,omitempty"`
+	SampleSize      int      `json:"sample_size,omitempty"`
+}
+
+// relationship is a reference from child columns to parent columns, either
+// a declared foreign key or one inferred from column naming.
+type relationship struct {
+	Constraint string   `json:"constraint,omitempty"`
+	Child      string   `json:"child"`
+	Columns    []string `json:"columns"`
+	Parent     string   `json:"parent"`
+	RefColumns []string `json:"refColumns"`
+	Inferred   bool     `json:"inferred,omitempty"`
+}
+
+// OrphanReport is the result of checking one relationship.
+type OrphanReport struct {
+	relationship
+	Orphans int64            `json:"orphans"`
+	Sample  []map[string]any `json:"sample,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
+
+func FindOrphans(ctx context.Context, req *mcp.CallToolRequest, args FindOrphansParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if args.Database == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "database is required"},
+			},
+		}, nil, nil
+	}
+	sampleSize := args.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultOrphanSample
+	}
+
+	schema, err := loadSchema(ctx, db, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read schema: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	relationships := schemaRelationships(schema, args.IncludeInferred)
+	if len(args.Tables) > 0 {
+		wanted := make(map[string]bool, len(args.Tables))
+		for _, t := range args.Tables {
+			wanted[t] = true
+		}
+		var filtered []relationship
+		for _, r := range relationships {
+			if wanted[r.Child] {
+				filtered = append(filtered, r)
+			}
+		}
+		relationships = filtered
+	}
+
+	reports := make([]OrphanReport, 0, len(relationships))
+	var withOrphans int
+	resultText := ""
+	for _, r := range relationships {
+		report := OrphanReport{relationship: r}
+		count, columns, sample, err := orphanedRows(ctx, args.Database, r, sampleSize)
+		if err != nil {
+			report.Error = err.Error()
+			resultText += fmt.Sprintf("\n%s: check failed: %v\n", describeRelationship(r), err)
+		} else if count > 0 {
+			report.Orphans, report.Sample = count, sample
+			withOrphans++
+			resultText += fmt.Sprintf("\n%s: %d orphaned row(s)\n%s", describeRelationship(r), count, formatRowTable(columns, sample))
+		}
+		reports = append(reports, report)
+	}
+
+	header := fmt.Sprintf("Checked %d relationship(s) in %s: %d with orphaned rows\n", len(relationships), args.Database, withOrphans)
+	if len(relationships) == 0 {
+		header = fmt.Sprintf("No foreign key relationships found in %s", args.Database)
+		if !args.IncludeInferred {
+			header += "; set include_inferred to guess them from column names like customer_id"
+		}
+		header += "\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: header + resultText},
+		},
+	}, map[string]any{
+		"checked":       len(relationships),
+		"withOrphans":   withOrphans,
+		"relationships": reports,
+	}, nil
+}
+
+// schemaRelationships lists the declared foreign keys within the database
+// and, if inferred is set, columns named <table>_id that refer to a table
+// with a single-column primary key and have no foreign key of their own.
+func schemaRelationships(schema *databaseSchema, inferred bool) []relationship {
+	var relationships []relationship
+	declared := make(map[string]bool)
+	for _, t := range schema.Tables {
+		for _, fk := range t.ForeignKeys {
+			if fk.RefDatabase != schema.Database {
+				continue
+			}
+			relationships = append(relationships, relationship{
+				Constraint: fk.Name, Child: t.Name, Columns: fk.Columns, Parent: fk.RefTable, RefColumns: fk.RefColumns,
+			})
+			if len(fk.Columns) == 1 {
+				declared[t.Name+"."+fk.Columns[0]] = true
+			}
+		}
+	}
+	if !inferred {
+		return relationships
+	}
+
+	primaryKeys := make(map[string]string)
+	for _, t := range schema.Tables {
+		if pk := findIndex(t, "PRIMARY").Columns; len(pk) == 1 {
+			primaryKeys[t.Name] = pk[0]
+		}
+	}
+	for _, t := range schema.Tables {
+		for _, c := range t.Columns {
+			base, ok := strings.CutSuffix(strings.ToLower(c.Name), "_id")
+			if !ok || base == "" || declared[t.Name+"."+c.Name] {
+				continue
+			}
+			for _, candidate := range []string{base, base + "s", base + "es", strings.TrimSuffix(base, "y") + "ies"} {
+				pk, ok := primaryKeys[candidate]
+				if !ok || (candidate == t.Name && pk == c.Name) {
+					continue
+				}
+				relationships = append(relationships, relationship{
+					Child: t.Name, Columns: []string{c.Name}, Parent: candidate, RefColumns: []string{pk}, Inferred: true,
+				})
+				break
+			}
+		}
+	}
+	return relationships
+}
+
+// orphanedRows counts the child rows of r whose referenced parent row does
+// not exist and returns a sample of them. As with foreign keys, rows with a
+// NULL in any referencing column are not checked.
+func orphanedRows(ctx context.Context, database string, r relationship, sampleSize int) (int64, []string, []map[string]any, error) {
+	db := currentDB()
+	joins := make([]string, len(r.Columns))
+	notNull := make([]string, len(r.Columns))
+	for i, col := range r.Columns {
+		joins[i] = fmt.Sprintf("p.%s = c.%s", quoteIdentifier(r.RefColumns[i]), quoteIdentifier(col))
+		notNull[i] = fmt.Sprintf("c.%s IS NOT NULL", quoteIdentifier(col))
+	}
+	from := fmt.Sprintf("FROM %s AS c LEFT JOIN %s AS p ON %s WHERE %s AND p.%s IS NULL",
+		qualifiedTable(database, r.Child), qualifiedTable(database, r.Parent), strings.Join(joins, " AND "),
+		strings.Join(notNull, " AND "), quoteIdentifier(r.RefColumns[0]))
+
+	var count int64
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) "+from).Scan(&count); err != nil {
+		return 0, nil, nil, err
+	}
+	if count == 0 {
+		return 0, nil, nil, nil
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT c.* %s LIMIT %d", from, sampleSize))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	defer rows.Close()
+	columns, sample, err := scanRowMaps(rows)
+	return count, columns, sample, err
+}
+
+func describeRelationship(r relationship) string {
+	s := fmt.Sprintf("%s(%s) -> %s(%s)", r.Child, strings.Join(r.Columns, ", "), r.Parent, strings.Join(r.RefColumns, ", "))
+	if r.Inferred {
+		return s + " [inferred]"
+	}
+	return s + " [" + r.Constraint + "]"
+}
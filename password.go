@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultPasswordLength = 32
+	minPasswordLength     = 16
+)
+
+// passwordAlphabet avoids quotes, backslashes and characters that commonly
+// need escaping in DSNs and shell configuration.
+const passwordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789-_.~!#%^*+="
+
+type RotatePasswordParams struct {
+	User          string `json:"user"`
+	Host          string `json:"host,omitempty"`
+	Source        string `json:"source,omitempty"`
+	Length        int    `json:"length,omitempty"`
+	RetainCurrent bool   `json:"retain_current,omitempty"`
+	DiscardOld    bool   `json:"discard_old,omitempty"`
+	Confirm       bool   `json:"confirm,omitempty"`
+}
+
+func RotatePassword(ctx context.Context, req *mcp.CallToolRequest, args RotatePasswordParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if args.User == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "user is required"},
+			},
+		}, nil, nil
+	}
+	account := accountName(args.User, args.Host)
+
+	if server := serverInfoFor(ctx, db); (args.RetainCurrent || args.DiscardOld) && (server.MariaDB || server.TiDB) {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Dual passwords (retain_current, discard_old) are a MySQL 8.0.14+ feature; the server is %s", server)},
+			},
+		}, nil, nil
+	}
+
+	// The second phase of a dual-password rollover: once every client uses
+	// the new password, the retained one is dropped.
+	if args.DiscardOld {
+		stmt := "ALTER USER " + account + " DISCARD OLD PASSWORD"
+		return runAccountStatement(ctx, "rotate_password", stmt, stmt, args.Confirm)
+	}
+
+	source := args.Source
+	if source == "" {
+		source = "generate"
+	}
+
+	stmt := "ALTER USER " + account + " IDENTIFIED BY "
+	suffix := ""
+	if args.RetainCurrent {
+		suffix = " RETAIN CURRENT PASSWORD"
+	}
+	display := stmt + "'<redacted>'" + suffix
+
+	if !args.Confirm {
+		return runAccountStatement(ctx, "rotate_password", "", display, false)
+	}
+
+	var password string
+	switch source {
+	case "generate":
+		length := args.Length
+		if length == 0 {
+			length = defaultPasswordLength
+		}
+		if length < minPasswordLength {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("length must be at least %d", minPasswordLength)},
+				},
+			}, nil, nil
+		}
+		var err error
+		password, err = generatePassword(length)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to generate password: %v", err)},
+				},
+			}, nil, nil
+		}
+	case "elicit":
+		// Ask the user directly so the password never passes through the
+		// model's context.
+		minLength := minPasswordLength
+		res, err := req.Session.Elicit(ctx, &mcp.ElicitParams{
+			Message: fmt.Sprintf("Enter the new password for %s", account),
+			RequestedSchema: &jsonschema.Schema{
+				Type: "object",
+				Properties: map[string]*jsonschema.Schema{
+					"password": {Type: "string", MinLength: &minLength, Description: "New password"},
+				},
+				Required: []string{"password"},
+			},
+		})
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to ask for a password (the client may not support elicitation; use source: generate): %v", err)},
+				},
+			}, nil, nil
+		}
+		if res.Action != "accept" {
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "Password rotation cancelled; nothing was changed."},
+				},
+			}, nil, nil
+		}
+		password, _ = res.Content["password"].(string)
+		if len(password) < minPasswordLength {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("The password must be at least %d characters; nothing was changed.", minPasswordLength)},
+				},
+			}, nil, nil
+		}
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported source %q (use generate or elicit)", args.Source)},
+			},
+		}, nil, nil
+	}
+
+	result, structured, err := runAccountStatement(ctx, "rotate_password", stmt+quoteString(password)+suffix, display, true)
+	if err != nil || result.IsError {
+		return result, structured, err
+	}
+
+	resultText := fmt.Sprintf("Password for %s rotated.", account)
+	if source == "generate" {
+		resultText += fmt.Sprintf("\nNew password: %s\nStore it in your secret manager now; it is not recorded anywhere else.", password)
+	}
+	if args.RetainCurrent {
+		resultText += "\nThe previous password keeps working until you call rotate_password with discard_old: true."
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, structured, nil
+}
+
+// generatePassword returns a random password of length characters drawn
+// uniformly from passwordAlphabet.
+func generatePassword(length int) (string, error) {
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(b), nil
+}
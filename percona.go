@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// runPerconaTool runs one of the Percona Toolkit scripts and returns its
+// standard output, turning a missing script or a failed run into a message
+// for the caller.
+func runPerconaTool(ctx context.Context, name string, cmdArgs ...string) ([]byte, string) {
+	if _, err := exec.LookPath(name); err != nil {
+		return nil, fmt.Sprintf("%s is not installed on the server's PATH; install Percona Toolkit to use this tool", name)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, cmdArgs...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(output) > 0 && stderr.Len() == 0 {
+			// Some Percona tools exit non-zero to signal findings.
+			return output, ""
+		}
+		return nil, fmt.Sprintf("%s failed: %v\n%s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, ""
+}
+
+// perconaDSN returns the host and port arguments for the current
+// connection and a private option file with its credentials, which the
+// caller must remove.
+func perconaDSN() (string, string, error) {
+	dbConfig := currentDBConfig()
+	conf, err := os.CreateTemp("", "mysql-mcp-*.cnf")
+	if err != nil {
+		return "", "", err
+	}
+	fmt.Fprintf(conf, "[client]\nuser=%s\npassword=%s\n", optionFileValue(dbConfig.User), optionFileValue(dbConfig.Passwd))
+	conf.Close()
+
+	host, port := dbConfig.Addr, "3306"
+	if i := strings.LastIndex(dbConfig.Addr, ":"); i >= 0 {
+		host, port = dbConfig.Addr[:i], dbConfig.Addr[i+1:]
+	}
+	return fmt.Sprintf("h=%s,P=%s", host, port), conf.Name(), nil
+}
+
+// QueryClass is one normalized query from a pt-query-digest report.
+type QueryClass struct {
+	Rank         int     `json:"rank"`
+	Checksum     string  `json:"checksum"`
+	Fingerprint  string  `json:"fingerprint"`
+	Distillate   string  `json:"distillate,omitempty"`
+	Database     string  `json:"database,omitempty"`
+	Count        int64   `json:"count"`
+	TotalTime    float64 `json:"totalTimeSeconds"`
+	AvgTime      float64 `json:"avgTimeSeconds"`
+	P95Time      float64 `json:"p95TimeSeconds"`
+	MaxTime      float64 `json:"maxTimeSeconds"`
+	RowsExamined float64 `json:"rowsExamined"`
+	RowsSent     float64 `json:"rowsSent"`
+	Example      string  `json:"example,omitempty"`
+}
+
+// digestMetric reads one statistic of a pt-query-digest metric, which the
+// JSON report writes as a string or a number depending on the version.
+func digestMetric(metrics map[string]map[string]any, metric, stat string) float64 {
+	value, _ := strconv.ParseFloat(fmt.Sprint(metrics[metric][stat]), 64)
+	return value
+}
+
+// parseQueryDigest reads the report of pt-query-digest --output json.
+func parseQueryDigest(output []byte) ([]QueryClass, int64, error) {
+	// The JSON document may be preceded by blank lines or notices.
+	if i := bytes.IndexByte(output, '{'); i > 0 {
+		output = output[i:]
+	}
+	var report struct {
+		Classes []struct {
+			Checksum    string                    `json:"checksum"`
+			Fingerprint string                    `json:"fingerprint"`
+			Distillate  string                    `json:"distillate"`
+			QueryCount  json.Number               `json:"query_count"`
+			Metrics     map[string]map[string]any `json:"metrics"`
+			Example     struct {
+				Query string `json:"query"`
+			} `json:"example"`
+		} `json:"classes"`
+		Global struct {
+			QueryCount json.Number `json:"query_count"`
+		} `json:"global"`
+	}
+	if err := json.Unmarshal(output, &report); err != nil {
+		return nil, 0, err
+	}
+
+	classes := make([]QueryClass, len(report.Classes))
+	for i, c := range report.Classes {
+		count, _ := c.QueryCount.Int64()
+		classes[i] = QueryClass{
+			Rank:         i + 1,
+			Checksum:     c.Checksum,
+			Fingerprint:  c.Fingerprint,
+			Distillate:   c.Distillate,
+			Count:        count,
+			TotalTime:    digestMetric(c.Metrics, "Query_time", "sum"),
+			AvgTime:      digestMetric(c.Metrics, "Query_time", "avg"),
+			P95Time:      digestMetric(c.Metrics, "Query_time", "pct_95"),
+			MaxTime:      digestMetric(c.Metrics, "Query_time", "max"),
+			RowsExamined: digestMetric(c.Metrics, "Rows_examined", "sum"),
+			RowsSent:     digestMetric(c.Metrics, "Rows_sent", "sum"),
+			Example:      c.Example.Query,
+		}
+		if database, ok := c.Metrics["db"]["value"].(string); ok {
+			classes[i].Database = database
+		}
+	}
+	total, _ := report.Global.QueryCount.Int64()
+	return classes, total, nil
+}
+
+type PerconaQueryDigestParams struct {
+	Path  string `json:"path,omitempty"`
+	Since string `json:"since,omitempty"`
+	Until string `json:"until,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+func PerconaQueryDigest(ctx context.Context, req *mcp.CallToolRequest, args PerconaQueryDigestParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	path := args.Path
+	if path == "" {
+		if db == nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "path is required when not connected; give the slow query log to analyze"},
+				},
+			}, nil, nil
+		}
+		if err := db.QueryRowContext(ctx, "SELECT @@GLOBAL.slow_query_log_file").Scan(&path); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to find the slow query log: %v", err)},
+				},
+			}, nil, nil
+		}
+	}
+	if _, err := os.Stat(path); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Cannot read the slow query log %s: %v. pt-query-digest reads the file locally, so pass path when the server runs on another host.", path, err)},
+			},
+		}, nil, nil
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	cmdArgs := []string{"--output", "json", "--limit", strconv.Itoa(limit)}
+	if args.Since != "" {
+		cmdArgs = append(cmdArgs, "--since", args.Since)
+	}
+	if args.Until != "" {
+		cmdArgs = append(cmdArgs, "--until", args.Until)
+	}
+	cmdArgs = append(cmdArgs, path)
+	output, failure := runPerconaTool(ctx, "pt-query-digest", cmdArgs...)
+	if failure != "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: failure},
+			},
+		}, nil, nil
+	}
+	classes, total, err := parseQueryDigest(output)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to parse the pt-query-digest report: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("%s: %d queries, top %d classes by total time\n\n", path, total, len(classes))
+	for _, c := range classes {
+		resultText += fmt.Sprintf("%d. %s  %d calls, %.3fs total, %.3fs avg, %.3fs p95, %.0f rows examined, %.0f sent\n   %s\n",
+			c.Rank, c.Checksum, c.Count, c.TotalTime, c.AvgTime, c.P95Time, c.RowsExamined, c.RowsSent, c.Fingerprint)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"path":       path,
+		"queryCount": total,
+		"classes":    classes,
+	}, nil
+}
+
+// DuplicateKey is one index pt-duplicate-key-checker found redundant.
+type DuplicateKey struct {
+	Database    string   `json:"database"`
+	Table       string   `json:"table"`
+	Reason      string   `json:"reason"`
+	Definitions []string `json:"definitions"`
+	Fix         string   `json:"fix"`
+}
+
+var duplicateKeyTablePattern = regexp.MustCompile("^# `?([^`.]+)`?\\.`?([^`]+)`?$")
+
+// parseDuplicateKeys reads the text report of pt-duplicate-key-checker.
+// Each table has a banner with its name, then one block per redundant
+// index ending in the ALTER TABLE that drops it.
+func parseDuplicateKeys(output []byte) ([]DuplicateKey, map[string]string) {
+	var duplicates []DuplicateKey
+	summary := make(map[string]string)
+	var database, table string
+	var current *DuplicateKey
+	section := ""
+	inSummary := false
+	afterBanner := false
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " ")
+		switch {
+		case strings.HasPrefix(line, "# ####"):
+			afterBanner = !afterBanner
+			continue
+		case afterBanner:
+			if line == "# Summary of indexes" {
+				inSummary = true
+			} else if m := duplicateKeyTablePattern.FindStringSubmatch(line); m != nil {
+				database, table = m[1], m[2]
+			}
+			continue
+		case inSummary:
+			// "# Total Duplicate Indexes  3"
+			if fields := strings.Fields(strings.TrimPrefix(line, "#")); len(fields) > 1 {
+				summary[strings.Join(fields[:len(fields)-1], " ")] = fields[len(fields)-1]
+			}
+			continue
+		case line == "":
+			section = ""
+		case strings.HasPrefix(line, "ALTER TABLE "):
+			if current != nil {
+				current.Fix = line
+				duplicates = append(duplicates, *current)
+				current = nil
+			}
+		case line == "# Key definitions:":
+			section = "definitions"
+		case line == "# Column types:" || strings.HasPrefix(line, "# To "):
+			section = "other"
+		case section == "definitions" && current != nil:
+			current.Definitions = append(current.Definitions, strings.TrimSuffix(strings.TrimSpace(strings.TrimPrefix(line, "#")), ","))
+		case section == "" && strings.HasPrefix(line, "# "):
+			current = &DuplicateKey{Database: database, Table: table, Reason: strings.TrimPrefix(line, "# ")}
+			section = "reason"
+		}
+	}
+	return duplicates, summary
+}
+
+type PerconaDuplicateKeysParams struct {
+	Database  string   `json:"database,omitempty"`
+	Tables    []string `json:"tables,omitempty"`
+	Clustered bool     `json:"clustered,omitempty"`
+}
+
+func PerconaDuplicateKeys(ctx context.Context, req *mcp.CallToolRequest, args PerconaDuplicateKeysParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	dsn, conf, err := perconaDSN()
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to write the credentials file: %v", err)},
+			},
+		}, nil, nil
+	}
+	defer os.Remove(conf)
+
+	// The defaults file must come first.
+	cmdArgs := []string{"--defaults-file=" + conf}
+	if args.Database != "" {
+		cmdArgs = append(cmdArgs, "--databases", args.Database)
+	}
+	if len(args.Tables) > 0 {
+		cmdArgs = append(cmdArgs, "--tables", strings.Join(args.Tables, ","))
+	}
+	if !args.Clustered {
+		cmdArgs = append(cmdArgs, "--noclustered")
+	}
+	cmdArgs = append(cmdArgs, dsn)
+	output, failure := runPerconaTool(ctx, "pt-duplicate-key-checker", cmdArgs...)
+	if failure != "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: failure},
+			},
+		}, nil, nil
+	}
+	duplicates, summary := parseDuplicateKeys(output)
+
+	resultText := fmt.Sprintf("%d redundant indexes", len(duplicates))
+	if size := summary["Size Duplicate Indexes"]; size != "" {
+		resultText += fmt.Sprintf(" (%s bytes)", size)
+	}
+	if len(duplicates) == 0 {
+		resultText += "\n"
+	} else {
+		resultText += ". Review each fix before running it; an index can look redundant yet be named in FORCE INDEX hints.\n"
+	}
+	for _, d := range duplicates {
+		resultText += fmt.Sprintf("\n%s.%s: %s\n", d.Database, d.Table, d.Reason)
+		for _, definition := range d.Definitions {
+			resultText += "  " + definition + "\n"
+		}
+		resultText += "  " + d.Fix + "\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"duplicates": duplicates,
+		"summary":    summary,
+	}, nil
+}
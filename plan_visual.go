@@ -0,0 +1,546 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// queryPlanURIPrefix starts the URIs of rendered plans; the plan's
+	// number follows it, with .mmd for the Mermaid flowchart.
+	queryPlanURIPrefix = "mysql://query-plan/"
+	// maxStoredPlans is how many rendered plans stay readable as
+	// resources.
+	maxStoredPlans = 20
+	// planScanRows is the row estimate above which a full scan is marked.
+	planScanRows = 1000
+)
+
+type VisualizePlanParams struct {
+	Query    string `json:"query"`
+	Database string `json:"database,omitempty"`
+	// Format is ascii (the default) or mermaid.
+	Format string `json:"format,omitempty"`
+}
+
+// PlanNode is an operation of a query plan: a query block, join, sort or
+// table access, with the operations feeding it.
+type PlanNode struct {
+	Label   string   `json:"label"`
+	Details []string `json:"details,omitempty"`
+	// Rows is the optimizer's row estimate for table accesses.
+	Rows     float64     `json:"rows,omitempty"`
+	FullScan bool        `json:"fullScan,omitempty"`
+	Children []*PlanNode `json:"children,omitempty"`
+}
+
+// storedPlan is a rendered plan kept for the query plan resources.
+type storedPlan struct {
+	ascii, mermaid string
+}
+
+var queryPlans = struct {
+	sync.Mutex
+	next  int
+	byID  map[int]storedPlan
+	order []int
+}{byID: make(map[int]storedPlan)}
+
+// storePlan keeps a rendered plan, dropping the oldest beyond
+// maxStoredPlans, and returns its number.
+func storePlan(plan storedPlan) int {
+	queryPlans.Lock()
+	defer queryPlans.Unlock()
+	queryPlans.next++
+	id := queryPlans.next
+	queryPlans.byID[id] = plan
+	queryPlans.order = append(queryPlans.order, id)
+	if len(queryPlans.order) > maxStoredPlans {
+		delete(queryPlans.byID, queryPlans.order[0])
+		queryPlans.order = queryPlans.order[1:]
+	}
+	return id
+}
+
+// planOperations are the wrappers of MySQL's and MariaDB's JSON plans that
+// stand for an operation on the rows of what they contain.
+var planOperations = []struct {
+	key   string
+	label func(op map[string]any) string
+}{
+	{"ordering_operation", func(op map[string]any) string {
+		if op["using_filesort"] == true {
+			return "Sort (filesort)"
+		}
+		return "Ordered by index"
+	}},
+	{"grouping_operation", func(op map[string]any) string { return "Group" }},
+	{"duplicates_removal", func(op map[string]any) string { return "Remove duplicates" }},
+	{"windowing", func(op map[string]any) string { return "Window functions" }},
+	{"filesort", func(op map[string]any) string { return "Sort (filesort)" }},
+	{"temporary_table", func(op map[string]any) string { return "Temporary table" }},
+	{"read_sorted_file", func(op map[string]any) string { return "Read sorted file" }},
+	{"buffer_result", func(op map[string]any) string { return "Buffer result" }},
+}
+
+// planSubqueryKeys are the keys under which JSON plans list subqueries.
+var planSubqueryKeys = []string{"attached_subqueries", "subqueries", "select_list_subqueries", "having_subqueries", "order_by_subqueries", "group_by_subqueries", "optimized_away_subqueries"}
+
+// planNumber reads a number from a JSON plan, where MySQL writes some as
+// strings.
+func planNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// planRows renders a row estimate.
+func planRows(n float64) string {
+	if n < 1 {
+		return "0 rows"
+	}
+	return rowMagnitude(int64(n)) + " rows"
+}
+
+// queryBlockNode builds the node of a JSON plan's query_block.
+func queryBlockNode(block map[string]any) *PlanNode {
+	label := "SELECT"
+	if id, ok := planNumber(block["select_id"]); ok {
+		label = fmt.Sprintf("SELECT #%g", id)
+	}
+	node := &PlanNode{Label: label}
+	if cost, ok := block["cost_info"].(map[string]any); ok {
+		if c, ok := planNumber(cost["query_cost"]); ok {
+			node.Details = append(node.Details, fmt.Sprintf("cost %g", c))
+		}
+	}
+	node.Children = planNodes(block)
+	return node
+}
+
+// planNodes builds the nodes of the operations in a JSON plan object.
+func planNodes(block map[string]any) []*PlanNode {
+	var nodes []*PlanNode
+	if message, ok := block["message"].(string); ok {
+		nodes = append(nodes, &PlanNode{Label: message})
+	}
+	if table, ok := block["table"].(map[string]any); ok {
+		nodes = append(nodes, tableNode(table))
+	}
+	if loop, ok := block["nested_loop"].([]any); ok {
+		join := &PlanNode{Label: "Nested loop join"}
+		for _, item := range loop {
+			if m, ok := item.(map[string]any); ok {
+				join.Children = append(join.Children, planNodes(m)...)
+			}
+		}
+		// The children are in join order.
+		for i, child := range join.Children {
+			child.Label = fmt.Sprintf("%d. %s", i+1, child.Label)
+		}
+		nodes = append(nodes, join)
+	}
+	for _, op := range planOperations {
+		inner, ok := block[op.key].(map[string]any)
+		if !ok {
+			continue
+		}
+		node := &PlanNode{Label: op.label(inner), Children: planNodes(inner)}
+		if inner["using_temporary_table"] == true {
+			node.Details = append(node.Details, "using a temporary table")
+		}
+		nodes = append(nodes, node)
+	}
+	if union, ok := block["union_result"].(map[string]any); ok {
+		kind := "Union"
+		if name, ok := union["table_name"].(string); ok {
+			kind += " " + name
+		}
+		node := &PlanNode{Label: kind}
+		if specs, ok := union["query_specifications"].([]any); ok {
+			for _, spec := range specs {
+				if m, ok := spec.(map[string]any); ok {
+					node.Children = append(node.Children, planSubquery(m))
+				}
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return append(nodes, subqueryNodes(block)...)
+}
+
+// subqueryNodes builds the nodes of the subqueries a JSON plan object
+// lists.
+func subqueryNodes(block map[string]any) []*PlanNode {
+	var nodes []*PlanNode
+	for _, key := range planSubqueryKeys {
+		list, _ := block[key].([]any)
+		for _, item := range list {
+			m, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			sub := planSubquery(m)
+			label := "Subquery"
+			if m["dependent"] == true {
+				label = "Dependent subquery"
+			}
+			if m["cacheable"] == false {
+				label += " (not cacheable)"
+			}
+			sub.Label = label + ": " + sub.Label
+			nodes = append(nodes, sub)
+		}
+	}
+	return nodes
+}
+
+// planSubquery builds the node of an entry holding a query block, as
+// subqueries and the members of a union do.
+func planSubquery(m map[string]any) *PlanNode {
+	if block, ok := m["query_block"].(map[string]any); ok {
+		return queryBlockNode(block)
+	}
+	// MariaDB wraps cached subqueries in expression_cache.
+	if cache, ok := m["expression_cache"].(map[string]any); ok {
+		return planSubquery(cache)
+	}
+	return &PlanNode{Label: "Query", Children: planNodes(m)}
+}
+
+// accessDescriptions explain EXPLAIN's access types.
+var accessDescriptions = map[string]string{
+	"system":          "single row",
+	"const":           "single row by key",
+	"eq_ref":          "one row per join by unique key",
+	"ref":             "index lookup",
+	"fulltext":        "fulltext index",
+	"ref_or_null":     "index lookup with NULLs",
+	"index_merge":     "several indexes merged",
+	"unique_subquery": "unique index subquery",
+	"index_subquery":  "index subquery",
+	"range":           "index range scan",
+	"index":           "full index scan",
+	"ALL":             "full table scan",
+}
+
+// tableNode builds the node of a table access in a JSON plan.
+func tableNode(t map[string]any) *PlanNode {
+	name, _ := t["table_name"].(string)
+	access, _ := t["access_type"].(string)
+	label := "Table " + name
+	if description, ok := accessDescriptions[access]; ok {
+		label += ": " + description
+	} else if access != "" {
+		label += ": " + access
+	}
+	node := &PlanNode{Label: label}
+	if key, ok := t["key"].(string); ok {
+		detail := "key " + key
+		if parts, ok := t["used_key_parts"].([]any); ok {
+			var names []string
+			for _, p := range parts {
+				names = append(names, fmt.Sprint(p))
+			}
+			detail += " (" + strings.Join(names, ", ") + ")"
+		}
+		node.Details = append(node.Details, detail)
+	}
+	// MySQL estimates rows per scan; MariaDB calls it rows.
+	rows, ok := planNumber(t["rows_examined_per_scan"])
+	if !ok {
+		rows, ok = planNumber(t["rows"])
+	}
+	if ok {
+		node.Rows = rows
+		detail := planRows(rows)
+		if filtered, ok := planNumber(t["filtered"]); ok && filtered < 100 {
+			detail += fmt.Sprintf(", %g%% kept", filtered)
+		}
+		node.Details = append(node.Details, detail)
+	}
+	if t["using_index"] == true {
+		node.Details = append(node.Details, "covering index")
+	}
+	if condition, ok := t["attached_condition"].(string); ok {
+		node.Details = append(node.Details, "filter "+truncateForLog(condition, 80))
+	}
+	if access == "ALL" && rows >= planScanRows {
+		node.FullScan = true
+	}
+	if sub, ok := t["materialized_from_subquery"].(map[string]any); ok {
+		derived := planSubquery(sub)
+		derived.Label = "Materialized: " + derived.Label
+		node.Children = append(node.Children, derived)
+	}
+	node.Children = append(node.Children, subqueryNodes(t)...)
+	return node
+}
+
+// tidbPlanNodes builds nodes from a TiDB plan, whose id column draws the
+// operator tree with two characters per level.
+func tidbPlanNodes(rows []map[string]any) []*PlanNode {
+	var roots []*PlanNode
+	var stack []*PlanNode
+	for _, row := range rows {
+		id := planText(row["id"])
+		name := strings.TrimLeft(id, " │├└─")
+		depth := len([]rune(id)) - len([]rune(name))
+		depth /= 2
+		node := &PlanNode{Label: name}
+		if task := planText(row["task"]); task != "" {
+			node.Label += " [" + task + "]"
+		}
+		if rows, ok := planNumber(planText(row["estRows"])); ok {
+			node.Rows = rows
+			node.Details = append(node.Details, planRows(rows))
+		}
+		if object := planText(row["access object"]); object != "" {
+			node.Details = append(node.Details, object)
+		}
+		if info := planText(row["operator info"]); info != "" {
+			node.Details = append(node.Details, truncateForLog(info, 80))
+		}
+		node.FullScan = strings.HasPrefix(name, "TableFullScan") && node.Rows >= planScanRows
+		if depth > len(stack) {
+			depth = len(stack)
+		}
+		stack = stack[:depth]
+		if depth == 0 {
+			roots = append(roots, node)
+		} else {
+			parent := stack[depth-1]
+			parent.Children = append(parent.Children, node)
+		}
+		stack = append(stack, node)
+	}
+	return roots
+}
+
+// renderPlanASCII draws plan nodes as an indented tree, with each node's
+// details on the lines below it.
+func renderPlanASCII(nodes []*PlanNode) string {
+	var b strings.Builder
+	var walk func(n *PlanNode, prefix string, last, root bool)
+	walk = func(n *PlanNode, prefix string, last, root bool) {
+		branch, child := "├─ ", "│  "
+		if last {
+			branch, child = "└─ ", "   "
+		}
+		if root {
+			branch, child = "", ""
+		}
+		b.WriteString(prefix + branch + n.Label)
+		if n.FullScan {
+			b.WriteString("  ⚠")
+		}
+		b.WriteByte('\n')
+		// Details hang under the label, beside the line to its children.
+		detailPrefix := prefix + child + "   "
+		if len(n.Children) > 0 {
+			detailPrefix = prefix + child + "│  "
+		}
+		for _, d := range n.Details {
+			b.WriteString(detailPrefix + d + "\n")
+		}
+		for i, c := range n.Children {
+			walk(c, prefix+child, i == len(n.Children)-1, false)
+		}
+	}
+	for _, n := range nodes {
+		walk(n, "", true, true)
+	}
+	return b.String()
+}
+
+// renderPlanMermaid draws plan nodes as a Mermaid flowchart, with rows
+// flowing up from the table accesses and full scans highlighted.
+func renderPlanMermaid(nodes []*PlanNode) string {
+	var b strings.Builder
+	b.WriteString("flowchart BT\n")
+	count := 0
+	var scans []string
+	var walk func(n *PlanNode) string
+	walk = func(n *PlanNode) string {
+		count++
+		id := fmt.Sprintf("n%d", count)
+		text := n.Label
+		for _, d := range n.Details {
+			text += "<br/>" + d
+		}
+		// Mermaid labels take HTML entities, not backslash escapes.
+		text = strings.NewReplacer(`"`, "#quot;", "<=", "#lt;=", "< ", "#lt; ", ">=", "#gt;=", "> ", "#gt; ").Replace(text)
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, text)
+		if n.FullScan {
+			scans = append(scans, id)
+		}
+		for _, c := range n.Children {
+			childID := walk(c)
+			edge := ""
+			if c.Rows > 0 {
+				edge = "|" + planRows(c.Rows) + "|"
+			}
+			fmt.Fprintf(&b, "  %s -->%s %s\n", childID, edge, id)
+		}
+		return id
+	}
+	for _, n := range nodes {
+		walk(n)
+	}
+	if len(scans) > 0 {
+		b.WriteString("  classDef scan fill:#fde2e2,stroke:#c0392b\n")
+		fmt.Fprintf(&b, "  class %s scan\n", strings.Join(scans, ","))
+	}
+	return b.String()
+}
+
+// queryPlanNodes explains query and builds its plan tree: from EXPLAIN
+// FORMAT=JSON on MySQL and MariaDB, and from the operator tree of plain
+// EXPLAIN on TiDB.
+func queryPlanNodes(ctx context.Context, database, query string) ([]*PlanNode, error) {
+	db := currentDB()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	if database == "" {
+		defer conn.Close()
+	} else {
+		defer discardConn(conn)
+		if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(database)); err != nil {
+			return nil, fmt.Errorf("failed to select database %s: %w", database, err)
+		}
+	}
+
+	if serverInfoFor(ctx, db).TiDB {
+		rows, err := conn.QueryContext(ctx, "EXPLAIN "+query)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		_, results, err := scanRowMaps(rows)
+		if err != nil {
+			return nil, err
+		}
+		return tidbPlanNodes(results), nil
+	}
+
+	var document string
+	if err := conn.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+query).Scan(&document); err != nil {
+		return nil, err
+	}
+	var plan map[string]any
+	if err := json.Unmarshal([]byte(document), &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse the JSON plan: %w", err)
+	}
+	block, ok := plan["query_block"].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("the JSON plan has no query_block")
+	}
+	return []*PlanNode{queryBlockNode(block)}, nil
+}
+
+func VisualizePlan(ctx context.Context, req *mcp.CallToolRequest, args VisualizePlanParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	query := strings.TrimSuffix(strings.TrimSpace(args.Query), ";")
+	format := strings.ToLower(args.Format)
+	if format == "" {
+		format = "ascii"
+	}
+	var err error
+	switch {
+	case query == "":
+		err = fmt.Errorf("query is required")
+	case len(splitSQLStatements(query)) != 1:
+		err = fmt.Errorf("query must be a single statement")
+	case format != "ascii" && format != "mermaid":
+		err = fmt.Errorf("format must be ascii or mermaid")
+	default:
+		err = checkExplainable(query)
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	nodes, err := queryPlanNodes(ctx, args.Database, query)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("EXPLAIN failed: %v", err)},
+			},
+		}, nil, nil
+	}
+	plan := storedPlan{ascii: renderPlanASCII(nodes), mermaid: renderPlanMermaid(nodes)}
+	id := storePlan(plan)
+
+	uri := fmt.Sprintf("%s%d", queryPlanURIPrefix, id)
+	text, resource := "```\n"+plan.ascii+"```", &mcp.ResourceContents{URI: uri, MIMEType: "text/plain", Text: plan.ascii}
+	if format == "mermaid" {
+		text = "```mermaid\n" + plan.mermaid + "```"
+		resource = &mcp.ResourceContents{URI: uri + ".mmd", MIMEType: "text/vnd.mermaid", Text: plan.mermaid}
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+			&mcp.EmbeddedResource{Resource: resource},
+		},
+	}, map[string]any{
+		"uri":     resource.URI,
+		"format":  format,
+		"plan":    nodes,
+		"ascii":   plan.ascii,
+		"mermaid": plan.mermaid,
+	}, nil
+}
+
+// ReadQueryPlan serves the plans visualize_plan rendered, as an ASCII tree
+// at mysql://query-plan/{n} and as a Mermaid flowchart at
+// mysql://query-plan/{n}.mmd.
+func ReadQueryPlan(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := req.Params.URI
+	name, ok := strings.CutPrefix(uri, queryPlanURIPrefix)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	name, mermaid := strings.CutSuffix(name, ".mmd")
+	id, err := strconv.Atoi(name)
+	if err != nil {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	queryPlans.Lock()
+	plan, ok := queryPlans.byID[id]
+	queryPlans.Unlock()
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	contents := &mcp.ResourceContents{URI: uri, MIMEType: "text/plain", Text: plan.ascii}
+	if mermaid {
+		contents = &mcp.ResourceContents{URI: uri, MIMEType: "text/vnd.mermaid", Text: plan.mermaid}
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{contents}}, nil
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type AuditPrivilegesParams struct {
+	IncludeLocked bool `json:"include_locked,omitempty"`
+}
+
+// PrivilegeFinding is one risky account setting or grant.
+type PrivilegeFinding struct {
+	Severity string `json:"severity"`
+	Account  string `json:"account"`
+	Issue    string `json:"issue"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+var severityRank = map[string]int{"critical": 0, "high": 1, "medium": 2, "low": 3}
+
+// riskyGlobalPrivileges maps global privileges to the severity and reason
+// they are reported with.
+var riskyGlobalPrivileges = map[string][2]string{
+	"SUPER":                  {"high", "can bypass read_only, kill any session and change global settings"},
+	"FILE":                   {"high", "can read and write files on the server host"},
+	"SHUTDOWN":               {"medium", "can stop the server"},
+	"PROCESS":                {"medium", "can see every session's statements, including literals"},
+	"RELOAD":                 {"medium", "can flush logs, privileges and caches"},
+	"CREATE USER":            {"medium", "can create, drop and rename any account"},
+	"SYSTEM_USER":            {"medium", "can modify other privileged accounts"},
+	"CONNECTION_ADMIN":       {"medium", "can kill other sessions and connect when max_connections is reached"},
+	"REPLICATION SLAVE":      {"low", "can stream the binary log, including all row data"},
+	"SYSTEM_VARIABLES_ADMIN": {"medium", "can change global system variables"},
+}
+
+type accountInfo struct {
+	User    string
+	Host    string
+	Locked  bool
+	NoPass  bool
+	Plugin  string
+	global  map[string]bool
+	granted map[string]bool
+	mysqlDB []string
+}
+
+func AuditPrivileges(ctx context.Context, req *mcp.CallToolRequest, args AuditPrivilegesParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	accounts := make(map[string]*accountInfo)
+	account := func(user, host string) *accountInfo {
+		key := accountName(user, host)
+		a, ok := accounts[key]
+		if !ok {
+			a = &accountInfo{User: user, Host: host, global: make(map[string]bool), granted: make(map[string]bool)}
+			accounts[key] = a
+		}
+		return a
+	}
+
+	var notes []string
+
+	// mysql.user is the only place passwords and locks are visible; without
+	// SELECT on it the report falls back to information_schema alone.
+	// MariaDB 10.4+ keeps accounts as JSON in mysql.global_priv, which
+	// mysql.user is a view of without the lock flag; earlier MariaDB has no
+	// account locking and keeps native hashes in Password. Roles are
+	// accounts there too and are left out.
+	accountsTable := "mysql.user"
+	accountsQuery := `
+		SELECT User, Host, COALESCE(authentication_string, ''), COALESCE(plugin, ''), COALESCE(account_locked, 'N')
+		FROM mysql.user
+	`
+	if server := serverInfoFor(ctx, db); server.MariaDB && server.atLeast(10, 4, 0) {
+		accountsTable = "mysql.global_priv"
+		accountsQuery = `
+			SELECT User, Host, COALESCE(JSON_VALUE(Priv, '$.authentication_string'), ''), COALESCE(JSON_VALUE(Priv, '$.plugin'), ''),
+			       IF(JSON_VALUE(Priv, '$.account_locked') = 'true', 'Y', 'N')
+			FROM mysql.global_priv
+			WHERE COALESCE(JSON_VALUE(Priv, '$.is_role'), 'false') <> 'true'
+		`
+	} else if server.MariaDB {
+		accountsQuery = `
+			SELECT User, Host, COALESCE(NULLIF(authentication_string, ''), Password, ''), COALESCE(plugin, ''), 'N'
+			FROM mysql.user WHERE is_role = 'N'
+		`
+	}
+	rows, err := db.QueryContext(ctx, accountsQuery)
+	if err != nil {
+		notes = append(notes, fmt.Sprintf("Could not read %s (%v); empty password and locked account checks were skipped", accountsTable, err))
+	} else {
+		for rows.Next() {
+			var user, host, auth, plugin, locked string
+			if err := rows.Scan(&user, &host, &auth, &plugin, &locked); err != nil {
+				rows.Close()
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("Failed to read accounts: %v", err)},
+					},
+				}, nil, nil
+			}
+			a := account(user, host)
+			a.Locked = locked == "Y"
+			a.Plugin = plugin
+			a.NoPass = auth == ""
+		}
+		rows.Close()
+	}
+
+	rows, err = db.QueryContext(ctx, `
+		SELECT GRANTEE, PRIVILEGE_TYPE, IS_GRANTABLE, '' FROM information_schema.USER_PRIVILEGES
+		UNION ALL
+		SELECT GRANTEE, PRIVILEGE_TYPE, IS_GRANTABLE, TABLE_SCHEMA FROM information_schema.SCHEMA_PRIVILEGES
+	`)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read privileges: %v", err)},
+			},
+		}, nil, nil
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var grantee, privilege, grantable, schema string
+		if err := rows.Scan(&grantee, &privilege, &grantable, &schema); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to read privileges: %v", err)},
+				},
+			}, nil, nil
+		}
+		user, host := parseGrantee(grantee)
+		a := account(user, host)
+		if schema == "" {
+			if privilege != "USAGE" {
+				a.global[privilege] = true
+			}
+			if grantable == "YES" {
+				a.granted["*.*"] = true
+			}
+			continue
+		}
+		if grantable == "YES" {
+			a.granted[schema] = true
+		}
+		if strings.EqualFold(schema, "mysql") {
+			a.mysqlDB = append(a.mysqlDB, privilege)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read privileges: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	var findings []PrivilegeFinding
+	skipped := 0
+	for name, a := range accounts {
+		if a.Locked && !args.IncludeLocked {
+			skipped++
+			continue
+		}
+		add := func(severity, issue, detail string) {
+			findings = append(findings, PrivilegeFinding{Severity: severity, Account: name, Issue: issue, Detail: detail})
+		}
+
+		if a.User == "" {
+			add("critical", "anonymous account", "anyone can connect from "+a.Host+" without knowing a user name")
+		}
+		if a.NoPass && a.Plugin != "auth_socket" && a.Plugin != "unix_socket" {
+			add("critical", "empty password", "the account can log in without a password")
+		}
+		if len(a.global) >= 25 {
+			add("high", "all global privileges", "the account is effectively a superuser")
+		} else {
+			for privilege := range a.global {
+				if risk, ok := riskyGlobalPrivileges[privilege]; ok {
+					add(risk[0], privilege+" privilege", risk[1])
+				}
+			}
+		}
+		for scope := range a.granted {
+			if scope == "*.*" {
+				add("high", "global GRANT OPTION", "can pass its privileges on to any account")
+			} else {
+				add("medium", "GRANT OPTION on "+scope, "can pass its privileges on "+scope+" to other accounts")
+			}
+		}
+		if len(a.mysqlDB) > 0 {
+			sort.Strings(a.mysqlDB)
+			add("high", "privileges on the mysql system schema", strings.Join(a.mysqlDB, ", ")+" on mysql.* allows editing grant tables directly")
+		}
+		if a.Host == "%" {
+			severity := "low"
+			if len(a.global) > 0 {
+				severity = "high"
+			}
+			add(severity, "connects from any host", "host '%' accepts connections from every address")
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if severityRank[findings[i].Severity] != severityRank[findings[j].Severity] {
+			return severityRank[findings[i].Severity] < severityRank[findings[j].Severity]
+		}
+		if findings[i].Account != findings[j].Account {
+			return findings[i].Account < findings[j].Account
+		}
+		return findings[i].Issue < findings[j].Issue
+	})
+
+	counts := make(map[string]int)
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+
+	resultText := fmt.Sprintf("Privilege audit: %d accounts, %d findings (critical: %d, high: %d, medium: %d, low: %d)\n",
+		len(accounts), len(findings), counts["critical"], counts["high"], counts["medium"], counts["low"])
+	if skipped > 0 {
+		resultText += fmt.Sprintf("%d locked accounts skipped (set include_locked to include them)\n", skipped)
+	}
+	for _, note := range notes {
+		resultText += "Note: " + note + "\n"
+	}
+	current := ""
+	for _, f := range findings {
+		if f.Severity != current {
+			current = f.Severity
+			resultText += fmt.Sprintf("\n%s:\n", strings.ToUpper(current))
+		}
+		resultText += fmt.Sprintf("- %s: %s", f.Account, f.Issue)
+		if f.Detail != "" {
+			resultText += " (" + f.Detail + ")"
+		}
+		resultText += "\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"accounts": len(accounts),
+		"findings": findings,
+		"counts":   counts,
+	}, nil
+}
+
+// parseGrantee splits information_schema's 'user'@'host' grantee format.
+func parseGrantee(grantee string) (string, string) {
+	i := strings.LastIndex(grantee, "@")
+	if i < 0 {
+		return strings.Trim(grantee, "'"), ""
+	}
+	user := strings.ReplaceAll(strings.Trim(grantee[:i], "'"), "''", "'")
+	host := strings.ReplaceAll(strings.Trim(grantee[i+1:], "'"), "''", "'")
+	return user, host
+}
@@ -0,0 +1,428 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	proxyProxySQL    = "ProxySQL"
+	proxyMySQLRouter = "MySQL Router"
+)
+
+// poolAddresses records the address each pool opened with openDatabase
+// connects to, for recognizing MySQL Router by its ports.
+var poolAddresses = struct {
+	sync.Mutex
+	byDB map[*sql.DB]string
+}{byDB: make(map[*sql.DB]string)}
+
+// mysqlRouterPorts are MySQL Router's default classic protocol ports:
+// read-write, read-only and, from 8.2, read-write splitting.
+var mysqlRouterPorts = map[string]bool{"6446": true, "6447": true, "6450": true}
+
+// detectProxy names the proxy between the server and database, or returns
+// "". ProxySQL answers this exact query itself, as it does for the mysql
+// client's banner; MySQL Router forwards everything and is only recognized
+// by its default ports.
+func detectProxy(ctx context.Context, database *sql.DB) string {
+	var comment string
+	if err := database.QueryRowContext(ctx, "select @@version_comment limit 1").Scan(&comment); err == nil && strings.Contains(comment, "ProxySQL") {
+		return proxyProxySQL
+	}
+	poolAddresses.Lock()
+	addr := poolAddresses.byDB[database]
+	poolAddresses.Unlock()
+	if _, port, err := net.SplitHostPort(addr); err == nil && mysqlRouterPorts[port] {
+		return proxyMySQLRouter
+	}
+	return ""
+}
+
+// sessionStatePatterns match statements that leave state in the session,
+// which a proxy multiplexing or sharing server connections has to keep
+// pinned to one backend connection.
+var sessionStatePatterns = []struct {
+	feature string
+	pattern *regexp.Regexp
+}{
+	{"a temporary table", regexp.MustCompile(`(?i)\bCREATE\s+TEMPORARY\s+TABLE\b`)},
+	{"user variables", regexp.MustCompile(`(^|[^@\w])@[A-Za-z_]`)},
+	{"named locks", regexp.MustCompile(`(?i)\bGET_LOCK\s*\(`)},
+	{"table locks", regexp.MustCompile(`(?i)^LOCK\s+TABLES?\b`)},
+	{"a prepared statement", regexp.MustCompile(`(?i)^(PREPARE|EXECUTE)\s`)},
+	{"FOUND_ROWS()", regexp.MustCompile(`(?i)\bSQL_CALC_FOUND_ROWS\b|\bFOUND_ROWS\s*\(`)},
+}
+
+// annotateProxyResult warns when an execute_query statement relies on
+// session state that does not survive behind the connection's proxy.
+func annotateProxyResult(server serverInfo, query string, result *mcp.CallToolResult, structured any) {
+	if result == nil || len(result.Content) == 0 {
+		return
+	}
+	text, ok := result.Content[0].(*mcp.TextContent)
+	if !ok {
+		return
+	}
+	sanitized := sanitizeStatement(query)
+	var features []string
+	for _, p := range sessionStatePatterns {
+		if p.pattern.MatchString(sanitized) {
+			features = append(features, p.feature)
+		}
+	}
+	if len(features) == 0 {
+		return
+	}
+
+	warning := fmt.Sprintf("Warning: this statement uses %s, which is session state. ", strings.Join(features, ", "))
+	if server.Proxy == proxyProxySQL {
+		warning += "ProxySQL stops multiplexing the client connection while it holds this state, pinning a backend connection, and query rules may still route later statements to another hostgroup where it does not exist."
+	} else {
+		warning += "MySQL Router cannot share the server connection while it holds this state, and a read-write splitting route may send later statements to another server."
+	}
+	warning += " Each tool call may also use a different pooled connection, so do the work in a single statement where possible."
+	text.Text = warning + "\n\n" + text.Text
+	if m, ok := structured.(map[string]any); ok {
+		m["warning"] = warning
+	}
+}
+
+type ProxySQLStatsParams struct {
+	Connection string `json:"connection"`
+}
+
+// ProxySQLBackend is a backend server of a hostgroup with its connection
+// pool counters.
+type ProxySQLBackend struct {
+	Hostgroup string `json:"hostgroup"`
+	Server    string `json:"server"`
+	Status    string `json:"status"`
+	ConnUsed  string `json:"connUsed"`
+	ConnFree  string `json:"connFree"`
+	ConnOK    string `json:"connOk"`
+	ConnErr   string `json:"connErr"`
+	Queries   string `json:"queries"`
+	LatencyUs string `json:"latencyUs"`
+}
+
+// ProxySQLQueryRule is an active query rule with the number of queries it
+// matched.
+type ProxySQLQueryRule struct {
+	RuleID               string `json:"ruleId"`
+	Match                string `json:"match"`
+	DestinationHostgroup string `json:"destinationHostgroup,omitempty"`
+	Multiplex            string `json:"multiplex,omitempty"`
+	Apply                bool   `json:"apply"`
+	Hits                 string `json:"hits"`
+}
+
+func ProxySQLStats(ctx context.Context, req *mcp.CallToolRequest, args ProxySQLStatsParams) (*mcp.CallToolResult, any, error) {
+	if args.Connection == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "connection is required: connect to the ProxySQL admin interface (port 6032 by default) with connect's name parameter and pass that name"},
+			},
+		}, nil, nil
+	}
+	admin, err := connectionFor(args.Connection)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: connectionHint(err)},
+			},
+		}, nil, nil
+	}
+
+	adminRows := func(query string) ([]map[string]any, error) {
+		rows, err := admin.QueryContext(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		_, results, err := scanRowMaps(rows)
+		return results, err
+	}
+
+	pool, err := adminRows(`
+		SELECT hostgroup, srv_host, srv_port, status, ConnUsed, ConnFree, ConnOK, ConnERR, Queries, Latency_us
+		FROM stats_mysql_connection_pool ORDER BY hostgroup, srv_host, srv_port`)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read ProxySQL stats from %q: %v (is it the admin interface, port 6032 by default?)", args.Connection, err)},
+			},
+		}, nil, nil
+	}
+	backends := make([]ProxySQLBackend, len(pool))
+	resultText := "Hostgroups:\n"
+	for i, row := range pool {
+		backends[i] = ProxySQLBackend{
+			Hostgroup: rowText(row, "hostgroup"),
+			Server:    net.JoinHostPort(rowText(row, "srv_host"), rowText(row, "srv_port")),
+			Status:    rowText(row, "status"),
+			ConnUsed:  rowText(row, "ConnUsed"),
+			ConnFree:  rowText(row, "ConnFree"),
+			ConnOK:    rowText(row, "ConnOK"),
+			ConnErr:   rowText(row, "ConnERR"),
+			Queries:   rowText(row, "Queries"),
+			LatencyUs: rowText(row, "Latency_us"),
+		}
+		b := backends[i]
+		resultText += fmt.Sprintf("  hostgroup %s  %s  %s  connections %s used / %s free (%s ok, %s errors)  %s queries  latency %s us\n",
+			b.Hostgroup, b.Server, b.Status, b.ConnUsed, b.ConnFree, b.ConnOK, b.ConnErr, b.Queries, b.LatencyUs)
+	}
+
+	rules, err := adminRows(`
+		SELECT r.rule_id, r.match_digest, r.match_pattern, r.destination_hostgroup, r.multiplex, r.apply, COALESCE(s.hits, 0) AS hits
+		FROM runtime_mysql_query_rules r LEFT JOIN stats_mysql_query_rules s ON s.rule_id = r.rule_id
+		WHERE r.active = 1 ORDER BY r.rule_id`)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read ProxySQL query rules: %v", err)},
+			},
+		}, nil, nil
+	}
+	queryRules := make([]ProxySQLQueryRule, len(rules))
+	resultText += fmt.Sprintf("\nActive query rules (%d):\n", len(rules))
+	for i, row := range rules {
+		queryRules[i] = ProxySQLQueryRule{
+			RuleID:               rowText(row, "rule_id"),
+			Match:                cmp.Or(rowText(row, "match_digest"), rowText(row, "match_pattern")),
+			DestinationHostgroup: rowText(row, "destination_hostgroup"),
+			Multiplex:            rowText(row, "multiplex"),
+			Apply:                rowText(row, "apply") == "1",
+			Hits:                 rowText(row, "hits"),
+		}
+		r := queryRules[i]
+		resultText += fmt.Sprintf("  rule %s  %q", r.RuleID, r.Match)
+		if r.DestinationHostgroup != "" {
+			resultText += " -> hostgroup " + r.DestinationHostgroup
+		}
+		if r.Multiplex != "" {
+			resultText += " multiplex=" + r.Multiplex
+		}
+		resultText += fmt.Sprintf("  %s hits\n", r.Hits)
+	}
+
+	global, err := adminRows(`
+		SELECT Variable_Name, Variable_Value FROM stats_mysql_global
+		WHERE Variable_Name IN ('Client_Connections_connected', 'Server_Connections_connected', 'Active_Transactions',
+			'Questions', 'ConnPool_get_conn_success', 'ConnPool_get_conn_immediate', 'ConnPool_get_conn_failure')`)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read ProxySQL global stats: %v", err)},
+			},
+		}, nil, nil
+	}
+	counters := make(map[string]string, len(global))
+	for _, row := range global {
+		counters[rowText(row, "Variable_Name")] = rowText(row, "Variable_Value")
+	}
+	multiplexing := "unknown"
+	if setting, err := adminRows(`SELECT variable_value FROM runtime_global_variables WHERE variable_name = 'mysql-multiplexing'`); err == nil && len(setting) == 1 {
+		multiplexing = rowText(setting[0], "variable_value")
+	}
+	resultText += fmt.Sprintf("\nMultiplexing: mysql-multiplexing=%s, %s client connections over %s backend connections\n",
+		multiplexing, cmp.Or(counters["Client_Connections_connected"], "?"), cmp.Or(counters["Server_Connections_connected"], "?"))
+	for _, name := range sortedKeys(counters) {
+		resultText += fmt.Sprintf("  %s = %s\n", name, counters[name])
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"backends":     backends,
+		"queryRules":   queryRules,
+		"multiplexing": multiplexing,
+		"global":       counters,
+	}, nil
+}
+
+type MySQLRouterStatusParams struct {
+	// User and Password default to those under mysqlRouter in the config
+	// file.
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// mysqlRouterConfig is the MySQL Router REST API mysql_router_status
+// queries. The address is never taken from the caller, so the tool cannot
+// be pointed at other hosts.
+type mysqlRouterConfig struct {
+	// URL defaults to port 8443 of the default connection's host, where
+	// the REST API listens by default.
+	URL         string `json:"url,omitempty"`
+	User        string `json:"user,omitempty"`
+	Password    string `json:"password,omitempty"`
+	PasswordEnv string `json:"passwordEnv,omitempty"`
+	// TLSSkipVerify accepts the self-signed certificate Router generates
+	// by default.
+	TLSSkipVerify bool `json:"tlsSkipVerify,omitempty"`
+}
+
+// MySQLRouterRoute is a route of MySQL Router with its connection counts
+// and destinations.
+type MySQLRouterRoute struct {
+	Name              string   `json:"name"`
+	Alive             bool     `json:"alive"`
+	ActiveConnections int      `json:"activeConnections"`
+	TotalConnections  int      `json:"totalConnections"`
+	BlockedHosts      int      `json:"blockedHosts"`
+	Destinations      []string `json:"destinations"`
+}
+
+// mysqlRouterAPI is the version path of MySQL Router's REST API.
+const mysqlRouterAPI = "/api/20190715"
+
+func MySQLRouterStatus(ctx context.Context, req *mcp.CallToolRequest, args MySQLRouterStatusParams) (*mcp.CallToolResult, any, error) {
+	if offline {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("mysql_router_status is unavailable: %v", errOffline)},
+			},
+		}, nil, nil
+	}
+	dbConfig := currentDBConfig()
+	router := config.MySQLRouter
+	base := router.URL
+	if base == "" && dbConfig != nil {
+		// The REST API listens on 8443 of the Router host by default.
+		if host, _, err := net.SplitHostPort(dbConfig.Addr); err == nil {
+			base = "https://" + net.JoinHostPort(host, "8443")
+		}
+	}
+	if base == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first, or set mysqlRouter.url in the config file."},
+			},
+		}, nil, nil
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	user, password := cmp.Or(args.User, router.User), cmp.Or(args.Password, router.Password)
+	if args.Password == "" && router.PasswordEnv != "" {
+		password = os.Getenv(router.PasswordEnv)
+	}
+
+	client := &http.Client{
+		Timeout: 10 * time.Second,
+		// A redirect could send the request, with its credentials, to
+		// another host.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if router.TLSSkipVerify {
+		client.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	get := func(path string, v any) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+mysqlRouterAPI+path, nil)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(user, password)
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s: HTTP %d", path, resp.StatusCode)
+		}
+		return json.NewDecoder(resp.Body).Decode(v)
+	}
+
+	var list struct {
+		Items []struct {
+			Name string `json:"name"`
+		} `json:"items"`
+	}
+	if err := get("/routes", &list); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to query the MySQL Router REST API at %s: %v (it needs the [http_server] and [rest_routing] sections in the Router configuration)", base, err)},
+			},
+		}, nil, nil
+	}
+
+	routes := make([]MySQLRouterRoute, 0, len(list.Items))
+	resultText := fmt.Sprintf("MySQL Router at %s, %d routes:\n", base, len(list.Items))
+	for _, item := range list.Items {
+		route := MySQLRouterRoute{Name: item.Name, Destinations: []string{}}
+		path := "/routes/" + url.PathEscape(item.Name)
+		var status struct {
+			ActiveConnections int `json:"activeConnections"`
+			TotalConnections  int `json:"totalConnections"`
+			BlockedHosts      int `json:"blockedHosts"`
+		}
+		if err := get(path+"/status", &status); err != nil {
+			slog.Debug("failed to read MySQL Router route status", "route", item.Name, "err", err)
+		}
+		route.ActiveConnections, route.TotalConnections, route.BlockedHosts = status.ActiveConnections, status.TotalConnections, status.BlockedHosts
+		var health struct {
+			IsAlive bool `json:"isAlive"`
+		}
+		if err := get(path+"/health", &health); err != nil {
+			slog.Debug("failed to read MySQL Router route health", "route", item.Name, "err", err)
+		}
+		route.Alive = health.IsAlive
+		var destinations struct {
+			Items []struct {
+				Address string `json:"address"`
+				Port    int    `json:"port"`
+			} `json:"items"`
+		}
+		if err := get(path+"/destinations", &destinations); err != nil {
+			slog.Debug("failed to read MySQL Router route destinations", "route", item.Name, "err", err)
+		}
+		for _, d := range destinations.Items {
+			route.Destinations = append(route.Destinations, net.JoinHostPort(d.Address, fmt.Sprint(d.Port)))
+		}
+		routes = append(routes, route)
+
+		state := "alive"
+		if !route.Alive {
+			state = "DOWN"
+		}
+		resultText += fmt.Sprintf("  %s (%s): %d active / %d total connections, %d blocked hosts, destinations %s\n",
+			route.Name, state, route.ActiveConnections, route.TotalConnections, route.BlockedHosts, strings.Join(route.Destinations, ", "))
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"url":    base,
+		"routes": routes,
+	}, nil
+}
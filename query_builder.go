@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultBuildSelectLimit = 100
+	maxBuildSelectLimit     = 10000
+)
+
+// selectAggregates are the functions build_select accepts in aggregates.
+var selectAggregates = []string{"COUNT", "SUM", "AVG", "MIN", "MAX", "GROUP_CONCAT"}
+
+// SelectJoin joins another table to the query. On pairs columns to be equal,
+// each named as in columns.
+type SelectJoin struct {
+	Database string          `json:"database,omitempty"`
+	Table    string          `json:"table"`
+	Alias    string          `json:"alias,omitempty"`
+	Type     string          `json:"type,omitempty"`
+	On       []JoinCondition `json:"on"`
+}
+
+type JoinCondition struct {
+	Left  string `json:"left"`
+	Right string `json:"right"`
+}
+
+type SelectAggregate struct {
+	Function string `json:"function"`
+	Column   string `json:"column,omitempty"`
+	Distinct bool   `json:"distinct,omitempty"`
+	As       string `json:"as,omitempty"`
+}
+
+type SelectOrder struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc,omitempty"`
+}
+
+type BuildSelectParams struct {
+	Database   string            `json:"database"`
+	Table      string            `json:"table"`
+	Alias      string            `json:"alias,omitempty"`
+	Columns    []string          `json:"columns,omitempty"`
+	Aggregates []SelectAggregate `json:"aggregates,omitempty"`
+	Joins      []SelectJoin      `json:"joins,omitempty"`
+	Filters    []Filter          `json:"filters,omitempty"`
+	GroupBy    []string          `json:"group_by,omitempty"`
+	Having     []Filter          `json:"having,omitempty"`
+	OrderBy    []SelectOrder     `json:"order_by,omitempty"`
+	Limit      int               `json:"limit,omitempty"`
+	Offset     int               `json:"offset,omitempty"`
+	DryRun     bool              `json:"dry_run,omitempty"`
+}
+
+// selectTable is a table in a built query's FROM clause.
+type selectTable struct {
+	alias   string
+	columns []ColumnInfo
+}
+
+// selectScope resolves column references against the tables of a query.
+type selectScope struct {
+	tables []selectTable
+}
+
+func (s *selectScope) has(alias, column string) bool {
+	for _, t := range s.tables {
+		if t.alias == alias {
+			return slices.ContainsFunc(t.columns, func(c ColumnInfo) bool { return c.ColumnName == column })
+		}
+	}
+	return false
+}
+
+// resolve finds the table of a column named either alone or as
+// alias.column, returning the table alias and column name. A column named
+// alone must be in only one table.
+func (s *selectScope) resolve(ref string) (string, string, error) {
+	if alias, column, ok := strings.Cut(ref, "."); ok && slices.ContainsFunc(s.tables, func(t selectTable) bool { return t.alias == alias }) {
+		if !s.has(alias, column) {
+			return "", "", fmt.Errorf("unknown column %q in %s", column, alias)
+		}
+		return alias, column, nil
+	}
+	var found []string
+	for _, t := range s.tables {
+		if s.has(t.alias, ref) {
+			found = append(found, t.alias)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", "", fmt.Errorf("unknown column %q", ref)
+	case 1:
+		return found[0], ref, nil
+	}
+	return "", "", fmt.Errorf("column %q is in %s; name it as alias.column", ref, strings.Join(found, " and "))
+}
+
+// sql renders a resolved column, qualified by its table alias when the
+// query has joins.
+func (s *selectScope) sql(alias, column string) string {
+	if len(s.tables) == 1 {
+		return quoteIdentifier(column)
+	}
+	return quoteIdentifier(alias) + "." + quoteIdentifier(column)
+}
+
+func (s *selectScope) column(ref string) (string, error) {
+	alias, column, err := s.resolve(ref)
+	if err != nil {
+		return "", err
+	}
+	return s.sql(alias, column), nil
+}
+
+// buildSelect generates a SELECT statement and its parameters from args,
+// checking every table and column against the schema.
+func buildSelect(ctx context.Context, db *sql.DB, args BuildSelectParams) (string, []any, error) {
+	if args.Database == "" || args.Table == "" {
+		return "", nil, fmt.Errorf("database and table are required")
+	}
+	scope := &selectScope{}
+	addTable := func(database, table, alias string) (string, error) {
+		if database == "" {
+			database = args.Database
+		}
+		if alias == "" {
+			alias = table
+		}
+		if slices.ContainsFunc(scope.tables, func(t selectTable) bool { return t.alias == alias }) {
+			return "", fmt.Errorf("alias %q is used twice; give the tables different aliases", alias)
+		}
+		cols, err := catalogTable(ctx, db, database, table)
+		if err != nil {
+			return "", err
+		}
+		scope.tables = append(scope.tables, selectTable{alias: alias, columns: cols})
+		from := qualifiedTable(database, table)
+		if alias != table || len(args.Joins) > 0 {
+			from += " AS " + quoteIdentifier(alias)
+		}
+		return from, nil
+	}
+
+	from, err := addTable(args.Database, args.Table, args.Alias)
+	if err != nil {
+		return "", nil, err
+	}
+	for i, j := range args.Joins {
+		kind := strings.ToUpper(j.Type)
+		switch kind {
+		case "":
+			kind = "INNER"
+		case "INNER", "LEFT", "RIGHT":
+		default:
+			return "", nil, fmt.Errorf("join %d: unknown type %q (use inner, left or right)", i, j.Type)
+		}
+		if len(j.On) == 0 {
+			return "", nil, fmt.Errorf("join %d: on needs at least one pair of columns", i)
+		}
+		table, err := addTable(j.Database, j.Table, j.Alias)
+		if err != nil {
+			return "", nil, fmt.Errorf("join %d: %w", i, err)
+		}
+		var conds []string
+		for _, on := range j.On {
+			left, err := scope.column(on.Left)
+			if err != nil {
+				return "", nil, fmt.Errorf("join %d: %w", i, err)
+			}
+			right, err := scope.column(on.Right)
+			if err != nil {
+				return "", nil, fmt.Errorf("join %d: %w", i, err)
+			}
+			conds = append(conds, left+" = "+right)
+		}
+		from += fmt.Sprintf(" %s JOIN %s ON %s", kind, table, strings.Join(conds, " AND "))
+	}
+
+	// Result rows are keyed by column name, so a name selected from two
+	// tables is returned as alias.column.
+	type selected struct{ alias, column string }
+	var columns []selected
+	for _, ref := range args.Columns {
+		alias, column, err := scope.resolve(ref)
+		if err != nil {
+			return "", nil, err
+		}
+		columns = append(columns, selected{alias, column})
+	}
+	if len(args.Columns) == 0 && len(args.Aggregates) == 0 {
+		for _, t := range scope.tables {
+			for _, c := range t.columns {
+				columns = append(columns, selected{t.alias, c.ColumnName})
+			}
+		}
+	}
+	names := make(map[string]int)
+	for _, c := range columns {
+		names[c.column]++
+	}
+	var exprs, plain []string
+	for _, c := range columns {
+		expr := scope.sql(c.alias, c.column)
+		plain = append(plain, expr)
+		if names[c.column] > 1 {
+			expr += " AS " + quoteIdentifier(c.alias+"."+c.column)
+		}
+		exprs = append(exprs, expr)
+	}
+
+	var aggregateNames []string
+	for i, a := range args.Aggregates {
+		function := strings.ToUpper(a.Function)
+		if !slices.Contains(selectAggregates, function) {
+			return "", nil, fmt.Errorf("aggregate %d: unknown function %q (use %s)", i, a.Function, strings.Join(selectAggregates, ", "))
+		}
+		arg := "*"
+		if a.Column == "" || a.Column == "*" {
+			if function != "COUNT" || a.Distinct {
+				return "", nil, fmt.Errorf("aggregate %d: %s needs a column", i, function)
+			}
+		} else if arg, err = scope.column(a.Column); err != nil {
+			return "", nil, fmt.Errorf("aggregate %d: %w", i, err)
+		}
+		if a.Distinct {
+			arg = "DISTINCT " + arg
+		}
+		name := a.As
+		if name == "" {
+			name = strings.ToLower(function)
+			if a.Column != "" && a.Column != "*" {
+				name += "_" + strings.ReplaceAll(a.Column, ".", "_")
+			}
+		}
+		if slices.Contains(aggregateNames, name) {
+			return "", nil, fmt.Errorf("aggregate %d: name %q is used twice; set as", i, name)
+		}
+		aggregateNames = append(aggregateNames, name)
+		exprs = append(exprs, fmt.Sprintf("%s(%s) AS %s", function, arg, quoteIdentifier(name)))
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(exprs, ", "), from)
+	var params []any
+	if len(args.Filters) > 0 {
+		where, filterParams, err := buildConditions(args.Filters, scope.column)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid filters: %w", err)
+		}
+		query += " WHERE " + where
+		params = append(params, filterParams...)
+	}
+
+	// Selected columns are grouped by when aggregates are asked for without
+	// group_by, which is what the query almost always means.
+	groupBy := plain
+	if len(args.GroupBy) > 0 {
+		groupBy = nil
+		for _, ref := range args.GroupBy {
+			expr, err := scope.column(ref)
+			if err != nil {
+				return "", nil, fmt.Errorf("group_by: %w", err)
+			}
+			groupBy = append(groupBy, expr)
+		}
+	}
+	if len(groupBy) > 0 && (len(args.Aggregates) > 0 || len(args.GroupBy) > 0) {
+		query += " GROUP BY " + strings.Join(groupBy, ", ")
+	}
+
+	// HAVING and ORDER BY may also name aggregates.
+	outputColumn := func(ref string) (string, error) {
+		if slices.Contains(aggregateNames, ref) {
+			return quoteIdentifier(ref), nil
+		}
+		return scope.column(ref)
+	}
+	if len(args.Having) > 0 {
+		having, havingParams, err := buildConditions(args.Having, outputColumn)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid having: %w", err)
+		}
+		query += " HAVING " + having
+		params = append(params, havingParams...)
+	}
+	if len(args.OrderBy) > 0 {
+		var order []string
+		for _, o := range args.OrderBy {
+			expr, err := outputColumn(o.Column)
+			if err != nil {
+				return "", nil, fmt.Errorf("order_by: %w", err)
+			}
+			if o.Desc {
+				expr += " DESC"
+			}
+			order = append(order, expr)
+		}
+		query += " ORDER BY " + strings.Join(order, ", ")
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultBuildSelectLimit
+	}
+	query += fmt.Sprintf(" LIMIT %d", min(limit, maxBuildSelectLimit))
+	if args.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", args.Offset)
+	}
+	return query, params, nil
+}
+
+func BuildSelect(ctx context.Context, req *mcp.CallToolRequest, args BuildSelectParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	query, params, err := buildSelect(ctx, db, args)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Cannot build the query: %v", err)},
+			},
+		}, nil, nil
+	}
+	statement := query
+	if len(params) > 0 {
+		var values []string
+		for _, p := range params {
+			values = append(values, sqlLiteral(p))
+		}
+		statement += "\n-- parameters: " + strings.Join(values, ", ")
+	}
+	if args.DryRun {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Generated SQL (not executed):\n\n" + statement},
+			},
+		}, map[string]any{"query": query, "params": params, "executed": false}, nil
+	}
+	if blocked := enforceCostPolicy(ctx, req, "build_select", db, query, params...); blocked != nil {
+		return blocked, nil, nil
+	}
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Query failed: %v\n\n%s", err, statement)},
+			},
+		}, nil, nil
+	}
+	defer rows.Close()
+	columns, results, err := scanRowMaps(rows)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Query failed: %v\n\n%s", err, statement)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("%s\n\n%d rows:\n\n%s", statement, len(results), formatRowTable(columns, results))
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"rows":     results,
+		"rowCount": len(results),
+		"columns":  columns,
+		"query":    query,
+		"params":   params,
+		"executed": true,
+	}, nil
+}
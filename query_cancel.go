@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+)
+
+// killQuery sends KILL QUERY for the server thread id over a new
+// connection from connector, since the statement's own connection is busy.
+func killQuery(ctx context.Context, connector driver.Connector, id uint64) error {
+	conn, err := connector.Connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	e, ok := conn.(driver.ExecerContext)
+	if !ok {
+		return fmt.Errorf("driver cannot execute statements directly")
+	}
+	_, err = e.ExecContext(ctx, fmt.Sprintf("KILL QUERY %d", id), nil)
+	return err
+}
+
+// queryKilledError is the error of a statement whose context ended while it
+// ran on the server, after KILL QUERY was sent for it.
+type queryKilledError struct {
+	id uint64
+	// cause is why the context ended, such as context.DeadlineExceeded.
+	cause error
+	// killErr is the failure of KILL QUERY, if it failed.
+	killErr error
+	// err is the error the driver returned for the statement.
+	err error
+}
+
+func (e *queryKilledError) Error() string {
+	if e.killErr != nil {
+		return fmt.Sprintf("%v; KILL QUERY %d failed (%v), so the statement may still be running on the server", e.cause, e.id, e.killErr)
+	}
+	return fmt.Sprintf("%v; the statement was terminated on the server with KILL QUERY %d", e.cause, e.id)
+}
+
+func (e *queryKilledError) Unwrap() []error {
+	return []error{e.err, e.cause}
+}
+
+// statementWatch kills a statement on the server when its context ends
+// before it does. The driver gives up on the connection when the context
+// ends, but without KILL QUERY the server would go on running the
+// statement.
+type statementWatch struct {
+	ctx context.Context
+	// killDone is closed once a kill the context's end started is over; it
+	// is nil when the statement is not watched. Until then the statement is
+	// not finished, so its connection cannot go back to the pool and run
+	// another statement the KILL would hit instead.
+	killDone chan struct{}
+	mu       sync.Mutex
+	killed   *queryKilledError
+	stop     func() bool
+}
+
+// watchStatement starts watching a statement c runs under ctx.
+func (c *instrumentedConn) watchStatement(ctx context.Context) *statementWatch {
+	w := &statementWatch{ctx: ctx, stop: func() bool { return false }}
+	if c.id == 0 || ctx.Done() == nil {
+		return w
+	}
+	w.killDone = make(chan struct{})
+	w.stop = context.AfterFunc(ctx, func() {
+		defer close(w.killDone)
+		killCtx, cancel := context.WithTimeout(context.Background(), killGrace)
+		defer cancel()
+		killed := &queryKilledError{id: c.id, cause: context.Cause(ctx), killErr: killQuery(killCtx, c.connector, c.id)}
+		w.mu.Lock()
+		w.killed = killed
+		w.mu.Unlock()
+	})
+	return w
+}
+
+// wrap returns err, the statement's error, as a queryKilledError if the
+// statement was killed. An error after the context ended waits for the
+// kill, which the driver does not.
+func (w *statementWatch) wrap(err error) error {
+	if err != nil && w.killDone != nil && w.ctx.Err() != nil {
+		<-w.killDone
+	}
+	return w.result(err)
+}
+
+// finish stops watching once the statement and its result are done,
+// waiting for a kill already under way, and wraps its error as wrap does.
+func (w *statementWatch) finish(err error) error {
+	if !w.stop() && w.killDone != nil {
+		<-w.killDone
+	}
+	return w.result(err)
+}
+
+// result is err, or a queryKilledError wrapping it once a kill was sent.
+func (w *statementWatch) result(err error) error {
+	if err == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.killed == nil {
+		return err
+	}
+	killed := *w.killed
+	killed.err = err
+	return &killed
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// killableServer is a fakeServer whose connections have thread ID 7 and
+// whose SELECT SLEEP runs for a second. Like the MySQL driver, it gives up
+// on a statement as soon as its context ends, without waiting for KILL.
+func killableServer(killErr error) *fakeServer {
+	return &fakeServer{handle: func(ctx context.Context, query string, args []driver.NamedValue) (*fakeResult, error) {
+		switch {
+		case query == "SELECT CONNECTION_ID()":
+			return &fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{int64(7)}}}, nil
+		case query == "KILL QUERY 7":
+			return nil, killErr
+		case strings.HasPrefix(query, "SELECT SLEEP"):
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Second):
+				return &fakeResult{columns: []string{"slept"}, rows: [][]driver.Value{{int64(0)}}}, nil
+			}
+		}
+		return nil, nil
+	}}
+}
+
+func TestStatementKilledOnTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		killErr error
+		want    string
+	}{
+		{"killed", nil, "terminated on the server with KILL QUERY 7"},
+		{"kill failed", errors.New("access denied"), "KILL QUERY 7 failed (access denied), so the statement may still be running"},
+	}
+	for _, tt := range tests {
+		server := killableServer(tt.killErr)
+		db := sql.OpenDB(instrumentedConnector{Connector: server})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		_, err := db.ExecContext(ctx, "SELECT SLEEP(10)")
+		cancel()
+		db.Close()
+
+		var killed *queryKilledError
+		if !errors.As(err, &killed) {
+			t.Errorf("%s: error %v is not a queryKilledError", tt.name, err)
+			continue
+		}
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("%s: error %v does not wrap the context's deadline", tt.name, err)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %q does not contain %q", tt.name, err, tt.want)
+		}
+		if !server.ran("KILL QUERY 7") {
+			t.Errorf("%s: KILL QUERY was not sent; statements: %q", tt.name, server.statements())
+		}
+	}
+}
+
+func TestStatementNotKilledWhenDone(t *testing.T) {
+	server := killableServer(nil)
+	db := sql.OpenDB(instrumentedConnector{Connector: server})
+	defer db.Close()
+
+	// The statement and its rows are done before the context ends.
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, err := db.QueryContext(ctx, "SELECT 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	if _, err := db.ExecContext(ctx, "DO 1"); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+
+	if server.ran("KILL") {
+		t.Errorf("KILL QUERY was sent for finished statements: %q", server.statements())
+	}
+}
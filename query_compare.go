@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultCompareRows = 10000
+	maxCompareRows     = 100000
+)
+
+type CompareQueryResultsParams struct {
+	Query            string `json:"query"`
+	SourceConnection string `json:"source_connection,omitempty"`
+	TargetConnection string `json:"target_connection,omitempty"`
+	// KeyColumns match rows between the results; without them whole rows
+	// are compared, so a changed row shows as one row only in each result.
+	KeyColumns []string `json:"key_columns,omitempty"`
+	// MaxRows is the number of rows read from each result.
+	MaxRows int `json:"max_rows,omitempty"`
+	// Limit is the number of differences listed.
+	Limit int `json:"limit,omitempty"`
+}
+
+// queryResultSide is the result of the compared query on one connection.
+type queryResultSide struct {
+	columns   []string
+	rows      []map[string]any
+	truncated bool
+	err       error
+}
+
+func CompareQueryResults(ctx context.Context, req *mcp.CallToolRequest, args CompareQueryResultsParams) (*mcp.CallToolResult, any, error) {
+	query := strings.TrimSpace(args.Query)
+	if query == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "query is required"},
+			},
+		}, nil, nil
+	}
+	if err := checkReadQuery(query); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Cannot compare this query: %v", err)},
+			},
+		}, nil, nil
+	}
+	sourceName, targetName := args.SourceConnection, args.TargetConnection
+	if sourceName == "" {
+		sourceName = defaultConnectionName
+	}
+	if targetName == "" {
+		targetName = defaultConnectionName
+	}
+	if sourceName == targetName {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "source_connection and target_connection are the same; open the second one with connect's name parameter"},
+			},
+		}, nil, nil
+	}
+	sourceConn, err := connectionFor(args.SourceConnection)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: connectionHint(err)},
+			},
+		}, nil, nil
+	}
+	targetConn, err := connectionFor(args.TargetConnection)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: connectionHint(err)},
+			},
+		}, nil, nil
+	}
+	maxRows := args.MaxRows
+	if maxRows <= 0 {
+		maxRows = defaultCompareRows
+	}
+	maxRows = min(maxRows, maxCompareRows)
+	limit := args.Limit
+	if limit <= 0 {
+		limit = defaultDataDiffLimit
+	}
+
+	// Both sides run at once, so that a replica is compared with the
+	// primary at nearly the same moment.
+	targetDone := make(chan queryResultSide, 1)
+	go func() {
+		targetDone <- runComparedQuery(ctx, targetConn, query, maxRows)
+	}()
+	source := runComparedQuery(ctx, sourceConn, query, maxRows)
+	target := <-targetDone
+	for _, side := range []struct {
+		name   string
+		result queryResultSide
+	}{{sourceName, source}, {targetName, target}} {
+		if side.result.err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Query failed on %s: %v", side.name, side.result.err)},
+				},
+			}, nil, nil
+		}
+	}
+
+	var columns, notes []string
+	for _, col := range source.columns {
+		if slices.Contains(target.columns, col) {
+			columns = append(columns, col)
+		} else {
+			notes = append(notes, fmt.Sprintf("column %s is only in the source result and is not compared", col))
+		}
+	}
+	for _, col := range target.columns {
+		if !slices.Contains(source.columns, col) {
+			notes = append(notes, fmt.Sprintf("column %s is only in the target result and is not compared", col))
+		}
+	}
+	for _, col := range args.KeyColumns {
+		if !slices.Contains(columns, col) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Key column %q is not in both results", col)},
+				},
+			}, nil, nil
+		}
+	}
+	if source.truncated || target.truncated {
+		notes = append(notes, fmt.Sprintf("only the first %d rows of each result were compared; rows past the cut may show as missing, so order the query and narrow it to compare the rest", maxRows))
+	}
+
+	var differences []RowDifference
+	if len(args.KeyColumns) > 0 {
+		differences, err = diffKeyedRows(source.rows, target.rows, args.KeyColumns, columns)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: err.Error()},
+				},
+			}, nil, nil
+		}
+	} else {
+		differences = diffRowMultisets(source.rows, target.rows, columns)
+	}
+
+	counts := map[string]int{}
+	for _, d := range differences {
+		counts[d.Change]++
+	}
+	resultText := fmt.Sprintf("Query results: source %s vs target %s\n", sourceName, targetName)
+	for _, note := range notes {
+		resultText += "Note: " + note + "\n"
+	}
+	resultText += fmt.Sprintf("Source returned %d row(s), target %d: %d only in target, %d only in source, %d changed\n",
+		len(source.rows), len(target.rows), counts["inserted"], counts["deleted"], counts["changed"])
+	key := args.KeyColumns
+	if len(key) == 0 {
+		key = columns
+	}
+	for _, d := range differences[:min(limit, len(differences))] {
+		if len(args.KeyColumns) == 0 {
+			// Keyless differences are whole rows, which describeRowDifference
+			// prints through their key.
+			d.Key = d.Row
+		}
+		resultText += describeRowDifference(key, d)
+	}
+	if len(differences) > limit {
+		resultText += fmt.Sprintf("... %d more difference(s); raise limit to list them\n", len(differences)-limit)
+	}
+	if len(differences) == 0 {
+		resultText += "The results hold the same rows.\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"sourceRows":  len(source.rows),
+		"targetRows":  len(target.rows),
+		"columns":     columns,
+		"keyColumns":  args.KeyColumns,
+		"truncated":   source.truncated || target.truncated,
+		"identical":   len(differences) == 0,
+		"differences": differences[:min(limit, len(differences))],
+	}, nil
+}
+
+// runComparedQuery reads up to maxRows rows of query's result on conn.
+func runComparedQuery(ctx context.Context, conn *sql.DB, query string, maxRows int) queryResultSide {
+	rows, done, err := queryReadOnly(ctx, conn, query)
+	if err != nil {
+		return queryResultSide{err: err}
+	}
+	defer done()
+	columns, results, truncated, err := scanRowMapsLimit(rows, maxRows)
+	return queryResultSide{columns: columns, rows: results, truncated: truncated, err: err}
+}
+
+// diffKeyedRows matches the rows of two results by key and reports the
+// rows only in one of them and the compared columns that differ.
+func diffKeyedRows(sourceRows, targetRows []map[string]any, key, columns []string) ([]RowDifference, error) {
+	index := func(rows []map[string]any, side string) (map[string]map[string]any, error) {
+		byKey := make(map[string]map[string]any, len(rows))
+		for _, row := range rows {
+			id := tupleIdentity(rowKey(row, key))
+			if _, ok := byKey[id]; ok {
+				return nil, fmt.Errorf("key_columns (%s) do not identify rows: %s appears twice in the %s result", strings.Join(key, ", "), describeKey(key, rowKey(row, key)), side)
+			}
+			byKey[id] = row
+		}
+		return byKey, nil
+	}
+	sourceByKey, err := index(sourceRows, "source")
+	if err != nil {
+		return nil, err
+	}
+	targetByKey, err := index(targetRows, "target")
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []RowDifference
+	for _, row := range sourceRows {
+		other, ok := targetByKey[tupleIdentity(rowKey(row, key))]
+		if !ok {
+			diffs = append(diffs, RowDifference{Key: keyMap(row, key), Change: "deleted", Row: row})
+			continue
+		}
+		changes := make(map[string]ColumnChange)
+		for _, col := range columns {
+			if !slices.Contains(key, col) && tupleIdentity([]any{row[col]}) != tupleIdentity([]any{other[col]}) {
+				changes[col] = ColumnChange{Source: row[col], Target: other[col]}
+			}
+		}
+		if len(changes) > 0 {
+			diffs = append(diffs, RowDifference{Key: keyMap(row, key), Change: "changed", Columns: changes})
+		}
+	}
+	for _, row := range targetRows {
+		if _, ok := sourceByKey[tupleIdentity(rowKey(row, key))]; !ok {
+			diffs = append(diffs, RowDifference{Key: keyMap(row, key), Change: "inserted", Row: row})
+		}
+	}
+	return diffs, nil
+}
+
+// diffRowMultisets compares two results as multisets of rows, ignoring
+// their order: a row returned more often by one side is reported once per
+// extra copy.
+func diffRowMultisets(sourceRows, targetRows []map[string]any, columns []string) []RowDifference {
+	remaining := make(map[string]int, len(targetRows))
+	for _, row := range targetRows {
+		remaining[tupleIdentity(rowKey(row, columns))]++
+	}
+	var diffs []RowDifference
+	for _, row := range sourceRows {
+		id := tupleIdentity(rowKey(row, columns))
+		if remaining[id] > 0 {
+			remaining[id]--
+			continue
+		}
+		diffs = append(diffs, RowDifference{Change: "deleted", Row: keyMap(row, columns)})
+	}
+	for _, row := range targetRows {
+		id := tupleIdentity(rowKey(row, columns))
+		if remaining[id] > 0 {
+			remaining[id]--
+			diffs = append(diffs, RowDifference{Change: "inserted", Row: keyMap(row, columns)})
+		}
+	}
+	return diffs
+}
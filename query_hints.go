@@ -0,0 +1,234 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// optimizerHintPattern matches one optimizer hint, such as
+// MAX_EXECUTION_TIME(5000), SET_VAR(sort_buffer_size = 16M) or
+// JOIN_ORDER(t1, t2).
+var optimizerHintPattern = regexp.MustCompile(`^([A-Za-z_]+)\s*\((.*)\)$`)
+
+// setStatementValuePattern matches the values a SET_VAR hint may pass to
+// MariaDB's SET STATEMENT, which takes expressions and is followed by the
+// statement itself: a number, a single-quoted string without quotes or
+// backslashes inside, or a bare word such as ON or DEFAULT.
+var setStatementValuePattern = regexp.MustCompile(`^(?:[+-]?(?:\d+(?:\.\d*)?|\.\d+)(?:[eE][+-]?\d+)?|'[^'\\]*'|[A-Za-z_][A-Za-z0-9_]*)$`)
+
+// optimizerHint is a hint execute_query adds to a SELECT.
+type optimizerHint struct {
+	name string
+	args string
+}
+
+// parseOptimizerHint checks one hint as given to execute_query's hints or
+// the config file.
+func parseOptimizerHint(text string) (optimizerHint, error) {
+	m := optimizerHintPattern.FindStringSubmatch(strings.TrimSpace(text))
+	if m == nil {
+		return optimizerHint{}, fmt.Errorf("hint %q must be written NAME(arguments), e.g. MAX_EXECUTION_TIME(5000)", text)
+	}
+	if strings.Contains(m[2], "*/") || strings.Contains(m[2], ";") {
+		return optimizerHint{}, fmt.Errorf("hint %q may not contain */ or ;", text)
+	}
+	depth := 0
+	for _, c := range m[2] {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth < 0 {
+			break
+		}
+	}
+	if depth != 0 {
+		return optimizerHint{}, fmt.Errorf("hint %q has unbalanced parentheses", text)
+	}
+	return optimizerHint{strings.ToUpper(m[1]), strings.TrimSpace(m[2])}, nil
+}
+
+// parseOptimizerHints checks a list of hints.
+func parseOptimizerHints(texts []string) ([]optimizerHint, error) {
+	hints := make([]optimizerHint, 0, len(texts))
+	for _, text := range texts {
+		hint, err := parseOptimizerHint(text)
+		if err != nil {
+			return nil, err
+		}
+		hints = append(hints, hint)
+	}
+	return hints, nil
+}
+
+// key identifies the setting a hint makes, so that a hint already in the
+// statement is not repeated: SET_VAR hints by their variable, table-level
+// hints by their arguments and the rest by name.
+func (h optimizerHint) key() string {
+	switch h.name {
+	case "MAX_EXECUTION_TIME", "JOIN_ORDER", "JOIN_PREFIX", "JOIN_SUFFIX", "JOIN_FIXED_ORDER", "RESOURCE_GROUP":
+		return h.name
+	case "SET_VAR":
+		variable, _, _ := strings.Cut(h.args, "=")
+		return "SET_VAR " + strings.ToLower(strings.TrimSpace(variable))
+	}
+	return h.name + " " + strings.ToLower(strings.Join(strings.Fields(h.args), " "))
+}
+
+func (h optimizerHint) String() string {
+	return h.name + "(" + h.args + ")"
+}
+
+// splitHintComment returns the hints of an optimizer hint comment's text,
+// without its /*+ and */.
+func splitHintComment(text string) []optimizerHint {
+	var hints []optimizerHint
+	for text = strings.TrimSpace(text); text != ""; {
+		open := strings.IndexByte(text, '(')
+		if open < 0 {
+			break
+		}
+		depth, end := 0, -1
+		for i := open; i < len(text) && end < 0; i++ {
+			switch text[i] {
+			case '(':
+				depth++
+			case ')':
+				if depth--; depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			break
+		}
+		hints = append(hints, optimizerHint{strings.ToUpper(strings.TrimSpace(text[:open])), strings.TrimSpace(text[open+1 : end])})
+		text = strings.TrimSpace(text[end+1:])
+	}
+	return hints
+}
+
+// injectHints adds hints to the outermost SELECT of query: into its
+// optimizer hint comment, or a new one directly after the SELECT keyword,
+// which is the only place MySQL reads them. Hints whose setting the
+// statement already makes are left out, as are later hints repeating an
+// earlier one. Statements other than a single SELECT are returned as they
+// are.
+//
+// MariaDB has no SET_VAR and reads hint comments only from 12.0, so there
+// MAX_EXECUTION_TIME and SET_VAR become a SET STATEMENT ... FOR prefix and
+// the other hints are returned as skipped.
+func injectHints(server serverInfo, query string, hints []optimizerHint) (string, []optimizerHint) {
+	if len(hints) == 0 || len(splitSQLStatements(query)) != 1 {
+		return query, nil
+	}
+	tokens := tokenizeSQL(query)
+	if len(tokens) == 0 {
+		return query, nil
+	}
+	switch strings.ToUpper(tokens[0].text) {
+	case "SELECT", "WITH", "(":
+	default:
+		return query, nil
+	}
+
+	// The outermost SELECT is the first one outside parentheses, after
+	// any WITH definitions; in (SELECT ...) UNION (SELECT ...) it is the
+	// first one.
+	at := -1
+	depth := 0
+	for i, t := range tokens {
+		switch {
+		case t.text == "(":
+			depth++
+		case t.text == ")":
+			depth--
+		case t.kind == tokenWord && strings.EqualFold(t.text, "SELECT") && (depth == 0 || tokens[0].text == "("):
+			at = i
+		}
+		if at >= 0 {
+			break
+		}
+	}
+	if at < 0 {
+		return query, nil
+	}
+
+	if server.MariaDB {
+		return mariaDBHints(query, hints)
+	}
+
+	start, end := tokens[at].end, tokens[at].end
+	var merged []optimizerHint
+	if at+1 < len(tokens) && tokens[at+1].kind == tokenComment && strings.HasPrefix(tokens[at+1].text, "/*+") {
+		end = tokens[at+1].end
+		merged = splitHintComment(strings.TrimSuffix(strings.TrimPrefix(tokens[at+1].text, "/*+"), "*/"))
+	}
+	seen := make(map[string]bool)
+	for _, h := range merged {
+		seen[h.key()] = true
+	}
+	added := false
+	for _, h := range hints {
+		if !seen[h.key()] {
+			seen[h.key()] = true
+			merged = append(merged, h)
+			added = true
+		}
+	}
+	if !added {
+		return query, nil
+	}
+	texts := make([]string, len(merged))
+	for i, h := range merged {
+		texts[i] = h.String()
+	}
+	return query[:start] + " /*+ " + strings.Join(texts, " ") + " */" + query[end:], nil
+}
+
+// mariaDBHints turns the hints MariaDB has a session variable for into a
+// SET STATEMENT prefix on query.
+func mariaDBHints(query string, hints []optimizerHint) (string, []optimizerHint) {
+	var settings, skipped []optimizerHint
+	seen := make(map[string]bool)
+	for _, h := range hints {
+		if seen[h.key()] {
+			continue
+		}
+		seen[h.key()] = true
+		switch h.name {
+		case "MAX_EXECUTION_TIME":
+			// max_statement_time is in seconds.
+			ms, err := strconv.ParseFloat(h.args, 64)
+			if err != nil {
+				skipped = append(skipped, h)
+				continue
+			}
+			settings = append(settings, optimizerHint{"max_statement_time", strconv.FormatFloat(ms/1000, 'f', -1, 64)})
+		case "SET_VAR":
+			// Unlike SET_VAR, SET STATEMENT takes expressions and the
+			// statement follows the value, so only literals are passed on.
+			variable, value, ok := strings.Cut(h.args, "=")
+			variable, value = strings.TrimSpace(variable), strings.TrimSpace(value)
+			if !ok || !variableNamePattern.MatchString(variable) || !setStatementValuePattern.MatchString(value) {
+				skipped = append(skipped, h)
+				continue
+			}
+			settings = append(settings, optimizerHint{variable, value})
+		default:
+			skipped = append(skipped, h)
+		}
+	}
+	if len(settings) == 0 {
+		return query, skipped
+	}
+	assignments := make([]string, len(settings))
+	for i, s := range settings {
+		assignments[i] = s.name + "=" + s.args
+	}
+	return "SET STATEMENT " + strings.Join(assignments, ", ") + " FOR " + query, skipped
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOptimizerHint(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    optimizerHint
+		wantErr bool
+	}{
+		{text: "MAX_EXECUTION_TIME(5000)", want: optimizerHint{"MAX_EXECUTION_TIME", "5000"}},
+		{text: " set_var( sort_buffer_size = 16M ) ", want: optimizerHint{"SET_VAR", "sort_buffer_size = 16M"}},
+		{text: "JOIN_ORDER(t1, t2)", want: optimizerHint{"JOIN_ORDER", "t1, t2"}},
+		{text: "NO_INDEX(t1 idx_a)", want: optimizerHint{"NO_INDEX", "t1 idx_a"}},
+		{text: "MAX_EXECUTION_TIME", wantErr: true},
+		{text: "MAX_EXECUTION_TIME(1) */ DELETE FROM t /*+ X(1)", wantErr: true},
+		{text: "SET_VAR(a=1); DELETE FROM t; SET_VAR(b=1)", wantErr: true},
+		{text: "BKA(t1))(", wantErr: true},
+		{text: "BKA((t1)", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseOptimizerHint(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseOptimizerHint(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("parseOptimizerHint(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestInjectHints(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		hints   []string
+		want    string
+		skipped int
+	}{
+		{
+			name:  "new comment",
+			query: "SELECT * FROM t",
+			hints: []string{"MAX_EXECUTION_TIME(5000)"},
+			want:  "SELECT /*+ MAX_EXECUTION_TIME(5000) */ * FROM t",
+		},
+		{
+			name:  "merged into existing comment",
+			query: "SELECT /*+ BKA(t) */ * FROM t",
+			hints: []string{"MAX_EXECUTION_TIME(5000)", "BKA(t)"},
+			want:  "SELECT /*+ BKA(t) MAX_EXECUTION_TIME(5000) */ * FROM t",
+		},
+		{
+			name:  "statement setting wins",
+			query: "SELECT /*+ MAX_EXECUTION_TIME(10) */ * FROM t",
+			hints: []string{"MAX_EXECUTION_TIME(5000)"},
+			want:  "SELECT /*+ MAX_EXECUTION_TIME(10) */ * FROM t",
+		},
+		{
+			name:  "after WITH definitions",
+			query: "WITH x AS (SELECT 1 AS a) SELECT a FROM x",
+			hints: []string{"MAX_EXECUTION_TIME(5000)"},
+			want:  "WITH x AS (SELECT 1 AS a) SELECT /*+ MAX_EXECUTION_TIME(5000) */ a FROM x",
+		},
+		{
+			name:  "not a SELECT",
+			query: "DELETE FROM t",
+			hints: []string{"MAX_EXECUTION_TIME(5000)"},
+			want:  "DELETE FROM t",
+		},
+		{
+			name:  "several statements",
+			query: "SELECT 1; SELECT 2",
+			hints: []string{"MAX_EXECUTION_TIME(5000)"},
+			want:  "SELECT 1; SELECT 2",
+		},
+	}
+	for _, tt := range tests {
+		hints, err := parseOptimizerHints(tt.hints)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, skipped := injectHints(serverInfo{}, tt.query, hints)
+		if got != tt.want || len(skipped) != tt.skipped {
+			t.Errorf("%s: injectHints() = %q, %d skipped; want %q, %d skipped", tt.name, got, len(skipped), tt.want, tt.skipped)
+		}
+	}
+}
+
+func TestInjectHintsMariaDB(t *testing.T) {
+	tests := []struct {
+		name    string
+		hints   []string
+		want    string
+		skipped int
+	}{
+		{
+			name:  "max execution time in seconds",
+			hints: []string{"MAX_EXECUTION_TIME(1500)"},
+			want:  "SET STATEMENT max_statement_time=1.5 FOR SELECT * FROM t",
+		},
+		{
+			name:  "literal values",
+			hints: []string{"SET_VAR(sort_buffer_size = 262144)", "SET_VAR(optimizer_switch = 'mrr=on')", "SET_VAR(big_tables=ON)"},
+			want:  "SET STATEMENT sort_buffer_size=262144, optimizer_switch='mrr=on', big_tables=ON FOR SELECT * FROM t",
+		},
+		{
+			// The value used to be copied into SET STATEMENT as written,
+			// which ran a DELETE in place of the SELECT.
+			name:    "statement in value",
+			hints:   []string{"SET_VAR(autocommit = 1 FOR DELETE FROM t WHERE 1 -- )"},
+			want:    "SELECT * FROM t",
+			skipped: 1,
+		},
+		{
+			name:    "expression",
+			hints:   []string{"SET_VAR(sort_buffer_size = 1 + 1)"},
+			want:    "SELECT * FROM t",
+			skipped: 1,
+		},
+		{
+			name:    "string with quote",
+			hints:   []string{`SET_VAR(sql_mode = 'a\' FOR DELETE FROM t -- ')`},
+			want:    "SELECT * FROM t",
+			skipped: 1,
+		},
+		{
+			name:    "subquery",
+			hints:   []string{"SET_VAR(x = (SELECT 1))"},
+			want:    "SELECT * FROM t",
+			skipped: 1,
+		},
+		{
+			name:    "no hint equivalent",
+			hints:   []string{"BKA(t)"},
+			want:    "SELECT * FROM t",
+			skipped: 1,
+		},
+	}
+	for _, tt := range tests {
+		hints, err := parseOptimizerHints(tt.hints)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		got, skipped := injectHints(serverInfo{MariaDB: true}, "SELECT * FROM t", hints)
+		if got != tt.want || len(skipped) != tt.skipped {
+			t.Errorf("%s: injectHints() = %q, %d skipped; want %q, %d skipped", tt.name, got, len(skipped), tt.want, tt.skipped)
+		}
+		if strings.Contains(got, "DELETE") {
+			t.Errorf("%s: injected a statement: %q", tt.name, got)
+		}
+	}
+}
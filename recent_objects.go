@@ -0,0 +1,239 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// recentObjectsKept caps the tables and the columns remembered; the
+	// least recently used are forgotten first.
+	recentObjectsKept = 200
+	// maxCompletionValues is the most values a completion may return.
+	maxCompletionValues = 100
+)
+
+// recentObjects remembers the tables and columns this session's queries and
+// describe_table calls touched and when, so completions and schema context
+// can list them first.
+var recentObjects = struct {
+	sync.Mutex
+	tables  map[[2]string]time.Time
+	columns map[[3]string]time.Time
+}{tables: make(map[[2]string]time.Time), columns: make(map[[3]string]time.Time)}
+
+// forgetOldest drops the least recently used entries of uses beyond
+// recentObjectsKept.
+func forgetOldest[K comparable](uses map[K]time.Time) {
+	if len(uses) <= recentObjectsKept {
+		return
+	}
+	keys := make([]K, 0, len(uses))
+	for k := range uses {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b K) int { return uses[a].Compare(uses[b]) })
+	for _, k := range keys[:len(keys)-recentObjectsKept] {
+		delete(uses, k)
+	}
+}
+
+// touchTable records a use of database.table and of the given columns.
+func touchTable(database, table string, columns ...string) {
+	if database == "" || table == "" {
+		return
+	}
+	now := time.Now()
+	recentObjects.Lock()
+	defer recentObjects.Unlock()
+	recentObjects.tables[[2]string{database, table}] = now
+	for _, col := range columns {
+		recentObjects.columns[[3]string{database, table, col}] = now
+	}
+	forgetOldest(recentObjects.tables)
+	forgetOldest(recentObjects.columns)
+}
+
+// touchQueryObjects records the tables a statement references, and those of
+// their columns it names. Unqualified tables are taken to be in the
+// connection's default database.
+func touchQueryObjects(ctx context.Context, db *sql.DB, query string) {
+	dbConfig := currentDBConfig()
+	tokens := tokenizeSQL(query)
+	words := make(map[string]bool)
+	for _, t := range tokens {
+		if isIdentifierToken(t) {
+			words[strings.ToLower(identifierText(t))] = true
+		}
+	}
+	for _, ref := range referencedTables(tokens) {
+		database := ref[0]
+		if database == "" && dbConfig != nil {
+			database = dbConfig.DBName
+		}
+		if database == "" {
+			continue
+		}
+		// The columns are usually cached by now; a table that does not
+		// exist has none and is not recorded.
+		columns, err := tableColumns(ctx, db, database, ref[1])
+		if err != nil || len(columns) == 0 {
+			continue
+		}
+		var used []string
+		for _, col := range columns {
+			if words[strings.ToLower(col.ColumnName)] {
+				used = append(used, col.ColumnName)
+			}
+		}
+		touchTable(database, ref[1], used...)
+	}
+}
+
+// recentTables returns when each table of database was last used.
+func recentTables(database string) map[string]time.Time {
+	recentObjects.Lock()
+	defer recentObjects.Unlock()
+	used := make(map[string]time.Time)
+	for k, t := range recentObjects.tables {
+		if k[0] == database {
+			used[k[1]] = t
+		}
+	}
+	return used
+}
+
+// recentColumns returns when each column of database.table was last used.
+func recentColumns(database, table string) map[string]time.Time {
+	recentObjects.Lock()
+	defer recentObjects.Unlock()
+	used := make(map[string]time.Time)
+	for k, t := range recentObjects.columns {
+		if k[0] == database && k[1] == table {
+			used[k[2]] = t
+		}
+	}
+	return used
+}
+
+// recentDatabases returns when a table of each database was last used.
+func recentDatabases() map[string]time.Time {
+	recentObjects.Lock()
+	defer recentObjects.Unlock()
+	used := make(map[string]time.Time)
+	for k, t := range recentObjects.tables {
+		if t.After(used[k[0]]) {
+			used[k[0]] = t
+		}
+	}
+	return used
+}
+
+// byRecentUse sorts names most recently used first and the unused ones by
+// name.
+func byRecentUse(names []string, used map[string]time.Time) {
+	slices.SortStableFunc(names, func(a, b string) int {
+		if c := used[b].Compare(used[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+}
+
+// CompleteArgument completes the arguments of the resource templates, and
+// of any template or prompt argument named database, table, column or
+// plan: names starting with what was typed, the objects this session used
+// most recently first.
+func CompleteArgument(ctx context.Context, req *mcp.CompleteRequest) (*mcp.CompleteResult, error) {
+	db := currentDB()
+	arg := req.Params.Argument
+	var resolved map[string]string
+	if req.Params.Context != nil {
+		resolved = req.Params.Context.Arguments
+	}
+	var names []string
+	switch arg.Name {
+	case "plan":
+		queryPlans.Lock()
+		for _, id := range slices.Backward(queryPlans.order) {
+			names = append(names, strconv.Itoa(id))
+		}
+		queryPlans.Unlock()
+	case "database":
+		if db == nil {
+			break
+		}
+		var err error
+		if names, err = completionNames(ctx, "SELECT SCHEMA_NAME FROM information_schema.SCHEMATA"); err != nil {
+			return nil, err
+		}
+		byRecentUse(names, recentDatabases())
+	case "table":
+		if db == nil {
+			break
+		}
+		database, err := contextDatabase(ctx, resolved["database"])
+		if err != nil {
+			break
+		}
+		names, err = completionNames(ctx, "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ?", database)
+		if err != nil {
+			return nil, err
+		}
+		byRecentUse(names, recentTables(database))
+	case "column":
+		if db == nil || resolved["table"] == "" {
+			break
+		}
+		database, err := contextDatabase(ctx, resolved["database"])
+		if err != nil {
+			break
+		}
+		columns, err := tableColumns(ctx, db, database, resolved["table"])
+		if err != nil {
+			return nil, err
+		}
+		for _, col := range columns {
+			names = append(names, col.ColumnName)
+		}
+		// Columns keep their table order after the recently used ones.
+		used := recentColumns(database, resolved["table"])
+		slices.SortStableFunc(names, func(a, b string) int { return used[b].Compare(used[a]) })
+	}
+
+	prefix := strings.ToLower(arg.Value)
+	names = slices.DeleteFunc(names, func(name string) bool {
+		return !strings.HasPrefix(strings.ToLower(name), prefix)
+	})
+	total := len(names)
+	return &mcp.CompleteResult{
+		Completion: mcp.CompletionResultDetails{
+			Values:  append([]string{}, names[:min(total, maxCompletionValues)]...),
+			Total:   total,
+			HasMore: total > maxCompletionValues,
+		},
+	}, nil
+}
+
+// completionNames reads the single column of names a query returns.
+func completionNames(ctx context.Context, query string, params ...any) ([]string, error) {
+	db := currentDB()
+	var names []string
+	err := queryEach(ctx, db, query, params, func(rows *sql.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		names = append(names, name)
+		return nil
+	})
+	return names, err
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// recoverMiddleware turns a panic in a tool handler into an error result
+// for that call, so one bad call does not take down the stdio session. The
+// stack trace goes to the log only.
+func recoverMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (result mcp.Result, err error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if method != "tools/call" || !ok {
+			return next(ctx, method, req)
+		}
+		defer func() {
+			if p := recover(); p != nil {
+				slog.Error("tool handler panicked", "tool", call.Params.Name, "panic", p, "stack", string(debug.Stack()))
+				errorsTotal.inc("panic")
+				result, err = &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("Internal error in %s: %v. The call was aborted; the server is still running.", call.Params.Name, p)},
+					},
+				}, nil
+			}
+		}()
+		return next(ctx, method, req)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// TableRename moves one table. Empty databases default to the request's
+// database.
+type TableRename struct {
+	FromDatabase string `json:"from_database,omitempty"`
+	From         string `json:"from"`
+	ToDatabase   string `json:"to_database,omitempty"`
+	To           string `json:"to"`
+}
+
+type RenameTableParams struct {
+	Database string        `json:"database"`
+	Renames  []TableRename `json:"renames"`
+	Confirm  bool          `json:"confirm,omitempty"`
+}
+
+func RenameTable(ctx context.Context, req *mcp.CallToolRequest, args RenameTableParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("rename_table"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	if len(args.Renames) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "renames is required"},
+			},
+		}, nil, nil
+	}
+
+	// Track the table names as they will exist partway through the RENAME,
+	// so chained renames (a -> tmp, b -> a, tmp -> b) validate correctly.
+	exists := make(map[string]bool)
+	lookup := func(database, table string) (bool, error) {
+		key := schemaCacheKey(database, table)
+		if v, ok := exists[key]; ok {
+			return v, nil
+		}
+		var n int
+		err := db.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM information_schema.TABLES
+			WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		`, database, table).Scan(&n)
+		if err != nil {
+			return false, err
+		}
+		exists[key] = n > 0
+		return n > 0, nil
+	}
+
+	clauses := make([]string, len(args.Renames))
+	for i := range args.Renames {
+		r := &args.Renames[i]
+		if r.FromDatabase == "" {
+			r.FromDatabase = args.Database
+		}
+		if r.ToDatabase == "" {
+			r.ToDatabase = r.FromDatabase
+		}
+		if r.FromDatabase == "" || r.From == "" || r.To == "" {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Rename %d: database, from and to are required", i+1)},
+				},
+			}, nil, nil
+		}
+		if err := checkIdentifier("table", r.To); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Rename %d: %v", i+1, err)},
+				},
+			}, nil, nil
+		}
+		for _, schema := range []string{r.FromDatabase, r.ToDatabase} {
+			if systemSchemas[strings.ToLower(schema)] {
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("Rename %d: refusing to rename tables in system schema %s", i+1, schema)},
+					},
+				}, nil, nil
+			}
+		}
+
+		src, err := lookup(r.FromDatabase, r.From)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to check tables: %v", err)},
+				},
+			}, nil, nil
+		}
+		if !src {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Rename %d: table %s.%s does not exist", i+1, r.FromDatabase, r.From)},
+				},
+			}, nil, nil
+		}
+		dst, err := lookup(r.ToDatabase, r.To)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to check tables: %v", err)},
+				},
+			}, nil, nil
+		}
+		if dst {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Rename %d: target %s.%s already exists", i+1, r.ToDatabase, r.To)},
+				},
+			}, nil, nil
+		}
+		exists[schemaCacheKey(r.FromDatabase, r.From)] = false
+		exists[schemaCacheKey(r.ToDatabase, r.To)] = true
+
+		clauses[i] = fmt.Sprintf("%s TO %s", qualifiedTable(r.FromDatabase, r.From), qualifiedTable(r.ToDatabase, r.To))
+	}
+
+	// A single RENAME TABLE statement is atomic: either every table is
+	// renamed or none are.
+	stmt := "RENAME TABLE " + strings.Join(clauses, ", ")
+
+	if !args.Confirm {
+		resultText := fmt.Sprintf("Preview:\n%s\n", stmt)
+		for _, r := range args.Renames {
+			if r.FromDatabase != r.ToDatabase {
+				resultText += fmt.Sprintf("Note: moving %s to schema %s fails if the table has triggers, and table-level grants are not carried over\n", r.From, r.ToDatabase)
+			}
+		}
+		resultText += "\nNothing was changed. Re-run with confirm: true to rename."
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, map[string]any{
+			"statement": stmt,
+			"executed":  false,
+		}, nil
+	}
+
+	_, err := db.ExecContext(ctx, stmt)
+	audit("rename_table", stmt, 0, err)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to rename tables: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	for _, r := range args.Renames {
+		invalidateTable(r.FromDatabase, r.From)
+		invalidateTable(r.ToDatabase, r.To)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Renamed %d tables:\n%s", len(args.Renames), stmt)},
+		},
+	}, map[string]any{
+		"statement": stmt,
+		"executed":  true,
+	}, nil
+}
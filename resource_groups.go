@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// managedResourceGroup is the group created for -thread-priority when no
+// group is named.
+const managedResourceGroup = "mysql_mcp"
+
+// resourceGroupSetting is the resource group the server's sessions join,
+// so that agent queries can be throttled against application traffic.
+type resourceGroupSetting struct {
+	name string
+	// priority, when set, is the THREAD_PRIORITY the group is created or
+	// altered with on first use.
+	priority *int
+
+	ensure sync.Once
+	// warned keeps a failing SET RESOURCE GROUP from being logged for
+	// every new connection.
+	warned atomic.Bool
+}
+
+// agentResourceGroup is the resource group the connections of newly
+// opened pools join; nil leaves them in the server's default group.
+var agentResourceGroup atomic.Pointer[resourceGroupSetting]
+
+// newResourceGroupSetting checks a resource group and thread priority as
+// given to -resource-group and -thread-priority or connect. It returns nil
+// when neither is set.
+func newResourceGroupSetting(name string, priority *int) (*resourceGroupSetting, error) {
+	if priority != nil && (*priority < 0 || *priority > 19) {
+		return nil, fmt.Errorf("thread priority must be between 0 (normal) and 19 (lowest) for a user resource group")
+	}
+	if name == "" && priority == nil {
+		return nil, nil
+	}
+	if name != "" {
+		if err := checkIdentifier("resource group", name); err != nil {
+			return nil, err
+		}
+	}
+	if name == "" {
+		name = managedResourceGroup
+	}
+	return &resourceGroupSetting{name: name, priority: priority}, nil
+}
+
+// threadPriorityFlag parses -thread-priority, which is unset by default.
+func threadPriorityFlag(priority **int) func(string) error {
+	return func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*priority = &n
+		return nil
+	}
+}
+
+// joinResourceGroup moves a new connection into group, the resource group
+// of its pool, creating or adjusting the group on first use when a thread
+// priority is set. Failures are logged once and leave the connection in
+// its default group.
+func joinResourceGroup(ctx context.Context, conn driver.Conn, group *resourceGroupSetting) {
+	if group == nil {
+		return
+	}
+	e, ok := conn.(driver.ExecerContext)
+	if !ok {
+		return
+	}
+	if group.priority != nil {
+		group.ensure.Do(func() {
+			// ALTER fails for a missing group, which is then created;
+			// both need RESOURCE_GROUP_ADMIN.
+			name := quoteIdentifier(group.name)
+			_, err := e.ExecContext(ctx, fmt.Sprintf("ALTER RESOURCE GROUP %s THREAD_PRIORITY = %d", name, *group.priority), nil)
+			if err != nil {
+				_, err = e.ExecContext(ctx, fmt.Sprintf("CREATE RESOURCE GROUP %s TYPE = USER THREAD_PRIORITY = %d", name, *group.priority), nil)
+			}
+			if err != nil {
+				slog.Warn("failed to create resource group", "group", group.name, "thread_priority", *group.priority, "err", err)
+			}
+		})
+	}
+	if _, err := e.ExecContext(ctx, "SET RESOURCE GROUP "+quoteIdentifier(group.name), nil); err != nil && group.warned.CompareAndSwap(false, true) {
+		slog.Warn("failed to join resource group; sessions run in the default group", "group", group.name, "err", err)
+	}
+}
+
+// resourceGroupNote describes group, the resource group database's
+// sessions run in, for connect's result, or "" when none is set.
+func resourceGroupNote(ctx context.Context, database *sql.DB, group *resourceGroupSetting) string {
+	if group == nil {
+		return ""
+	}
+	server := serverInfoFor(ctx, database)
+	if !server.supportsResourceGroups() {
+		return fmt.Sprintf("\nResource group %s is ignored: %s has no resource groups (MySQL 8.0.3+, TiDB 7.1+).", group.name, server)
+	}
+	var current sql.NullString
+	err := database.QueryRowContext(ctx, "SELECT RESOURCE_GROUP FROM performance_schema.threads WHERE PROCESSLIST_ID = CONNECTION_ID()").Scan(&current)
+	switch {
+	case err != nil:
+		// performance_schema may be off or unreadable; the group was
+		// joined unless a warning was logged.
+		return fmt.Sprintf("\nSessions join resource group %s.", group.name)
+	case current.String != group.name:
+		return fmt.Sprintf("\nWarning: sessions run in resource group %s, not %s; the log says why SET RESOURCE GROUP failed.", current.String, group.name)
+	}
+	var priority sql.NullInt64
+	if database.QueryRowContext(ctx, "SELECT THREAD_PRIORITY FROM information_schema.RESOURCE_GROUPS WHERE RESOURCE_GROUP_NAME = ?", group.name).Scan(&priority) == nil && priority.Valid {
+		return fmt.Sprintf("\nSessions run in resource group %s (thread priority %d).", group.name, priority.Int64)
+	}
+	return fmt.Sprintf("\nSessions run in resource group %s.", group.name)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewResourceGroupSetting(t *testing.T) {
+	priority := func(n int) *int { return &n }
+	tests := []struct {
+		name     string
+		priority *int
+		want     string
+		wantErr  string
+	}{
+		{},
+		{name: "batch", want: "batch"},
+		{priority: priority(10), want: managedResourceGroup},
+		{name: "batch", priority: priority(19), want: "batch"},
+		{priority: priority(20), wantErr: "thread priority"},
+		{priority: priority(-1), wantErr: "thread priority"},
+		{name: "batch ", wantErr: "resource group name"},
+		{name: strings.Repeat("g", maxIdentifierLength+1), wantErr: "resource group name"},
+	}
+	for _, tt := range tests {
+		got, err := newResourceGroupSetting(tt.name, tt.priority)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newResourceGroupSetting(%q) error = %v, want one about the %s", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("newResourceGroupSetting(%q) error = %v", tt.name, err)
+			continue
+		}
+		name := ""
+		if got != nil {
+			name = got.name
+		}
+		if name != tt.want {
+			t.Errorf("newResourceGroupSetting(%q) = %q, want %q", tt.name, name, tt.want)
+		}
+	}
+}
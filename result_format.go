@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ResultFormat controls how execute_query renders numbers and dates in the
+// text of its result. Structured content always keeps the raw values.
+type ResultFormat struct {
+	// Locale presets the separators and date format, e.g. en-US or de-DE;
+	// the other fields override it.
+	Locale             string `json:"locale,omitempty"`
+	ThousandsSeparator string `json:"thousands_separator,omitempty"`
+	DecimalSeparator   string `json:"decimal_separator,omitempty"`
+	// Decimals rounds or pads numbers to this many decimal places; 0
+	// leaves them as returned.
+	Decimals int `json:"decimals,omitempty"`
+	// DateFormat renders the date part of DATE, DATETIME and TIMESTAMP
+	// values with YYYY, MM and DD, e.g. DD.MM.YYYY.
+	DateFormat string `json:"date_format,omitempty"`
+}
+
+type localeFormat struct {
+	thousands, decimal, date string
+}
+
+// localeFormats are the locales ResultFormat.Locale accepts.
+var localeFormats = map[string]localeFormat{
+	"en-US": {",", ".", "MM/DD/YYYY"},
+	"en-GB": {",", ".", "DD/MM/YYYY"},
+	"en-IE": {",", ".", "DD/MM/YYYY"},
+	"en-AU": {",", ".", "DD/MM/YYYY"},
+	"en-CA": {",", ".", "YYYY-MM-DD"},
+	"de-DE": {".", ",", "DD.MM.YYYY"},
+	"de-AT": {".", ",", "DD.MM.YYYY"},
+	"de-CH": {"'", ".", "DD.MM.YYYY"},
+	"fr-FR": {" ", ",", "DD/MM/YYYY"},
+	"fr-CA": {" ", ",", "YYYY-MM-DD"},
+	"fr-CH": {" ", ".", "DD.MM.YYYY"},
+	"es-ES": {".", ",", "DD/MM/YYYY"},
+	"es-MX": {",", ".", "DD/MM/YYYY"},
+	"it-IT": {".", ",", "DD/MM/YYYY"},
+	"nl-NL": {".", ",", "DD-MM-YYYY"},
+	"pt-BR": {".", ",", "DD/MM/YYYY"},
+	"pt-PT": {" ", ",", "DD/MM/YYYY"},
+	"pl-PL": {" ", ",", "DD.MM.YYYY"},
+	"sv-SE": {" ", ",", "YYYY-MM-DD"},
+	"ja-JP": {",", ".", "YYYY/MM/DD"},
+	"zh-CN": {",", ".", "YYYY/MM/DD"},
+}
+
+// localeLanguages are the regions a bare language such as de stands for.
+var localeLanguages = []string{"en-US", "de-DE", "fr-FR", "es-ES", "it-IT", "nl-NL", "pt-BR", "pl-PL", "sv-SE", "ja-JP", "zh-CN"}
+
+// resolve fills in the fields the locale presets and checks the result.
+// It reports whether any formatting is asked for.
+func (f *ResultFormat) resolve() (bool, error) {
+	if f.Locale != "" {
+		tag := strings.ReplaceAll(f.Locale, "_", "-")
+		lang, region, _ := strings.Cut(tag, "-")
+		tag = strings.ToLower(lang)
+		if region != "" {
+			tag += "-" + strings.ToUpper(region)
+		} else if i := slices.IndexFunc(localeLanguages, func(l string) bool { return strings.HasPrefix(l, tag+"-") }); i >= 0 {
+			tag = localeLanguages[i]
+		}
+		locale, ok := localeFormats[tag]
+		if !ok {
+			return false, fmt.Errorf("unsupported locale %q (supported: %s)", f.Locale, strings.Join(sortedKeys(localeFormats), ", "))
+		}
+		if f.ThousandsSeparator == "" {
+			f.ThousandsSeparator = locale.thousands
+		}
+		if f.DecimalSeparator == "" {
+			f.DecimalSeparator = locale.decimal
+		}
+		if f.DateFormat == "" {
+			f.DateFormat = locale.date
+		}
+	}
+	if f.Decimals < 0 || f.Decimals > 30 {
+		return false, fmt.Errorf("decimals must be between 0 and 30")
+	}
+	if f.DateFormat != "" && !strings.Contains(f.DateFormat, "YYYY") && !strings.Contains(f.DateFormat, "MM") && !strings.Contains(f.DateFormat, "DD") {
+		return false, fmt.Errorf("date_format %q has none of YYYY, MM and DD", f.DateFormat)
+	}
+	return f.ThousandsSeparator != "" || f.DecimalSeparator != "" || f.Decimals > 0 || f.DateFormat != "", nil
+}
+
+// apply returns copies of rows with the numbers and dates of the columns
+// of the given kinds formatted.
+func (f ResultFormat) apply(columns, kinds []string, rows []map[string]any) []map[string]any {
+	formatted := make([]map[string]any, len(rows))
+	for r, row := range rows {
+		out := make(map[string]any, len(row))
+		for i, c := range columns {
+			v := row[c]
+			switch {
+			case v == nil:
+			case kinds[i] == "NUMBER":
+				v = f.number(v)
+			case kinds[i] == "DATE" || kinds[i] == "DATETIME" || kinds[i] == "TIMESTAMP":
+				if s, ok := v.(string); ok {
+					v = f.date(s)
+				}
+			}
+			out[c] = v
+		}
+		formatted[r] = out
+	}
+	return formatted
+}
+
+// number renders a numeric value with the format's separators and
+// decimals. Values it cannot parse as a decimal number are left alone.
+func (f ResultFormat) number(v any) any {
+	var s string
+	switch n := v.(type) {
+	case float64:
+		s = strconv.FormatFloat(n, 'f', -1, 64)
+	case float32:
+		s = strconv.FormatFloat(float64(n), 'f', -1, 32)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if f.Decimals > 0 {
+		r, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return v
+		}
+		// FloatString rounds half away from zero.
+		s = r.FloatString(f.Decimals)
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if whole == "" || strings.Trim(whole+frac, "0123456789") != "" {
+		return v
+	}
+	if f.ThousandsSeparator != "" {
+		var b strings.Builder
+		for i, d := range whole {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				b.WriteString(f.ThousandsSeparator)
+			}
+			b.WriteRune(d)
+		}
+		whole = b.String()
+	}
+	if !hasFrac {
+		return sign + whole
+	}
+	decimal := f.DecimalSeparator
+	if decimal == "" {
+		decimal = "."
+	}
+	return sign + whole + decimal + frac
+}
+
+// date renders the date part of a DATE, DATETIME or TIMESTAMP value with
+// the format's date_format, keeping the time part.
+func (f ResultFormat) date(s string) string {
+	if f.DateFormat == "" || len(s) < len("2006-01-02") || s[4] != '-' || s[7] != '-' {
+		return s
+	}
+	year, month, day := s[:4], s[5:7], s[8:10]
+	return strings.NewReplacer("YYYY", year, "MM", month, "DD", day).Replace(f.DateFormat) + s[10:]
+}
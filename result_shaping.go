@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// shapeSampleRows is the most rows shown from each end of a summarized
+	// result.
+	shapeSampleRows = 5
+	// shapeDistinctLimit caps the distinct values counted per column, so
+	// a large result is not held twice in memory.
+	shapeDistinctLimit = 1000
+	// summaryTopValues is how many of a column's most frequent values a
+	// summary lists.
+	summaryTopValues = 5
+)
+
+// ColumnStats summarizes one column of a result too large to return.
+type ColumnStats struct {
+	Column   string `json:"column"`
+	Nulls    int    `json:"nulls"`
+	Distinct int    `json:"distinct"`
+	// DistinctCapped is set when Distinct stopped counting at its limit.
+	DistinctCapped bool `json:"distinctCapped,omitempty"`
+	Min            any  `json:"min,omitempty"`
+	Max            any  `json:"max,omitempty"`
+	// TopValues are the most frequent values, listed by summarize.
+	TopValues []ValueCount `json:"topValues,omitempty"`
+}
+
+type ValueCount struct {
+	Value any   `json:"value"`
+	Count int64 `json:"count"`
+}
+
+// resultBudget returns the size in characters execute_query's result
+// should stay within, or 0 for no limit. Tokens are estimated at four
+// characters each, and the smaller budget wins when both are given.
+func resultBudget(maxTokens, maxChars int) int {
+	budget := 0
+	if maxTokens > 0 {
+		budget = maxTokens * 4
+	}
+	if maxChars > 0 && (budget == 0 || maxChars < budget) {
+		budget = maxChars
+	}
+	return budget
+}
+
+// columnStats computes the null count, distinct count and range of each
+// column. Values are ordered as filter conditions compare them.
+func columnStats(columns []string, rows []map[string]any) []ColumnStats {
+	stats := make([]ColumnStats, len(columns))
+	for i, c := range columns {
+		s := ColumnStats{Column: c}
+		seen := make(map[string]bool)
+		for _, row := range rows {
+			v := row[c]
+			if v == nil {
+				s.Nulls++
+				continue
+			}
+			if len(seen) < shapeDistinctLimit {
+				seen[fmt.Sprint(v)] = true
+			} else if !seen[fmt.Sprint(v)] {
+				s.DistinctCapped = true
+			}
+			if s.Min == nil || compareValues(v, s.Min) < 0 {
+				s.Min = v
+			}
+			if s.Max == nil || compareValues(v, s.Max) > 0 {
+				s.Max = v
+			}
+		}
+		s.Distinct = len(seen)
+		stats[i] = s
+	}
+	return stats
+}
+
+// shapeResult replaces a row result whose text is longer than budget with
+// a summary: the columns with their statistics, the first and last rows,
+// and how to page through the rest. Other results are returned unchanged.
+func shapeResult(result *mcp.CallToolResult, structured any, query string, budget int) (*mcp.CallToolResult, any) {
+	m, ok := structured.(map[string]any)
+	if !ok || budget <= 0 || len(result.Content) == 0 {
+		return result, structured
+	}
+	text, ok := result.Content[0].(*mcp.TextContent)
+	columns, _ := m["columns"].([]string)
+	rows, _ := m["rows"].([]map[string]any)
+	if !ok || len(text.Text) <= budget || len(rows) == 0 {
+		return result, structured
+	}
+
+	stats := columnStats(columns, rows)
+	var b strings.Builder
+	fmt.Fprintf(&b, "The result has %d rows (%d characters), more than the budget of %d characters, so it is summarized.\n\n", len(rows), len(text.Text), budget)
+	b.WriteString(formatColumnStats(stats))
+	summary := b.String()
+
+	// As many sample rows as fit after the statistics, leaving room for
+	// the table headers and paging advice, up to shapeSampleRows from
+	// each end.
+	perRow := max(len(text.Text)/len(rows), 1)
+	sample := min(shapeSampleRows, max(budget-len(summary)-600, 0)/perRow/2, len(rows)/2)
+	first := rows[:sample]
+	last := rows[len(rows)-sample:]
+	if sample > 0 {
+		fmt.Fprintf(&b, "\nFirst %d rows:\n\n%s", sample, formatRowTable(columns, first))
+		fmt.Fprintf(&b, "\nLast %d rows:\n\n%s", sample, formatRowTable(columns, last))
+	}
+
+	pageRows := max(budget/perRow-1, 1)
+	b.WriteString("\nTo see more, ")
+	if strings.HasPrefix(strings.ToUpper(strings.TrimSpace(query)), "SELECT") {
+		fmt.Fprintf(&b, "page with ORDER BY on a unique key and LIMIT %d OFFSET n (a page that fits the budget), select fewer columns, aggregate, or ", pageRows)
+	}
+	b.WriteString("raise max_tokens or max_chars.\n")
+	shaped := map[string]any{
+		"rowCount":    len(rows),
+		"columns":     columns,
+		"columnStats": stats,
+		"firstRows":   first,
+		"lastRows":    last,
+		"summarized":  true,
+	}
+	if note, ok := m["timeZones"].(TimeZoneNote); ok {
+		b.WriteString("\n" + note.Text() + "\n")
+		shaped["timeZones"] = note
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: b.String()},
+		},
+	}, shaped
+}
+
+// formatColumnStats lists column statistics one column per line.
+func formatColumnStats(stats []ColumnStats) string {
+	var b strings.Builder
+	b.WriteString("Columns (nulls, distinct values, range):\n")
+	for _, s := range stats {
+		distinct := fmt.Sprint(s.Distinct)
+		if s.DistinctCapped {
+			distinct += "+"
+		}
+		fmt.Fprintf(&b, "  %s: %d null, %s distinct", s.Column, s.Nulls, distinct)
+		if s.Min != nil {
+			fmt.Fprintf(&b, ", %s to %s", truncateForLog(fmt.Sprint(s.Min), 40), truncateForLog(fmt.Sprint(s.Max), 40))
+		}
+		if len(s.TopValues) > 0 {
+			var top []string
+			for _, v := range s.TopValues {
+				value := "NULL"
+				if v.Value != nil {
+					value = truncateForLog(fmt.Sprint(v.Value), 40)
+				}
+				top = append(top, fmt.Sprintf("%s (%d)", value, v.Count))
+			}
+			b.WriteString("; most frequent: " + strings.Join(top, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// summarizeQuery computes statistics of a SELECT's result on the server,
+// wrapping it in derived-table queries so no rows are transferred: the row
+// count and, for each column, its nulls, distinct values, range and most
+// frequent values. Spatial and vector columns only have their nulls
+// counted.
+func summarizeQuery(ctx context.Context, db *sql.DB, query string) (int64, []ColumnStats, error) {
+	query = strings.TrimRight(strings.TrimSpace(query), "; \t\n")
+	derived := "(" + query + ") AS summarized"
+	rows, done, err := queryRead(ctx, db, "SELECT * FROM "+derived+" LIMIT 0")
+	if err != nil {
+		return 0, nil, err
+	}
+	columns, err := rows.Columns()
+	kinds := resultColumnKinds(rows, len(columns))
+	done()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	exprs := []string{"COUNT(*) AS row_count"}
+	for i, c := range columns {
+		col := quoteIdentifier(c)
+		exprs = append(exprs, fmt.Sprintf("SUM(%s IS NULL) AS nulls_%d", col, i))
+		if kinds[i] == "GEOMETRY" || kinds[i] == "VECTOR" {
+			continue
+		}
+		exprs = append(exprs, fmt.Sprintf("COUNT(DISTINCT %s) AS distinct_%d, MIN(%s) AS min_%d, MAX(%s) AS max_%d", col, i, col, i, col, i))
+	}
+	rows, done, err = queryRead(ctx, db, "SELECT "+strings.Join(exprs, ", ")+" FROM "+derived)
+	if err != nil {
+		return 0, nil, err
+	}
+	_, results, err := scanRowMaps(rows)
+	done()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(results) == 0 {
+		return 0, nil, fmt.Errorf("the summary query returned no rows")
+	}
+	row := results[0]
+	count := func(v any) int64 {
+		n, _ := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+		return n
+	}
+
+	total := count(row["row_count"])
+	stats := make([]ColumnStats, len(columns))
+	for i, c := range columns {
+		s := ColumnStats{Column: c, Nulls: int(count(row[fmt.Sprintf("nulls_%d", i)]))}
+		if kinds[i] == "GEOMETRY" || kinds[i] == "VECTOR" {
+			stats[i] = s
+			continue
+		}
+		s.Distinct = int(count(row[fmt.Sprintf("distinct_%d", i)]))
+		s.Min, s.Max = row[fmt.Sprintf("min_%d", i)], row[fmt.Sprintf("max_%d", i)]
+
+		// Values of a unique column are all equally frequent.
+		if s.Distinct > 0 && int64(s.Distinct) < total-int64(s.Nulls) {
+			col := quoteIdentifier(c)
+			rows, done, err := queryRead(ctx, db, fmt.Sprintf("SELECT %s AS value, COUNT(*) AS n FROM %s GROUP BY %s ORDER BY n DESC, value LIMIT %d", col, derived, col, summaryTopValues))
+			if err != nil {
+				return 0, nil, err
+			}
+			_, top, err := scanRowMaps(rows)
+			done()
+			if err != nil {
+				return 0, nil, err
+			}
+			for _, t := range top {
+				s.TopValues = append(s.TopValues, ValueCount{Value: t["value"], Count: count(t["n"])})
+			}
+		}
+		stats[i] = s
+	}
+	return total, stats, nil
+}
+
+// summarizeResult answers execute_query with summarize set.
+func summarizeResult(ctx context.Context, db *sql.DB, query string) (*mcp.CallToolResult, any, error) {
+	err := checkReadQuery(query)
+	if word := strings.ToUpper(firstWord(sanitizeStatement(query))); err == nil && word != "SELECT" && word != "WITH" {
+		err = fmt.Errorf("only SELECT and WITH queries can be summarized")
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Cannot summarize this query: %v", err)},
+			},
+		}, nil, nil
+	}
+	total, stats, err := summarizeQuery(ctx, db, query)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to summarize query: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("The query returns %d rows (summarized on the server).\n\n%s", total, formatColumnStats(stats))
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"rowCount":    total,
+		"columnStats": stats,
+		"summarized":  true,
+	}, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// transientRetries is how many times a statement or transaction that hit a
+// deadlock or lock wait timeout is run again, set with -transient-retries.
+var transientRetries = 3
+
+// retryBackoff is the wait before the first retry, set with
+// -retry-backoff. Each further retry waits twice as long, with jitter so
+// writers that deadlocked each other do not collide again.
+var retryBackoff = 100 * time.Millisecond
+
+// transientErrors are the errors that abort a statement because of other
+// sessions' locks, after which running it again usually succeeds. A
+// deadlock rolls back the whole transaction; a lock wait timeout only the
+// statement, unless innodb_rollback_on_timeout is set.
+var transientErrors = map[uint16]string{
+	1205: "lock wait timeout",
+	1213: "deadlock",
+}
+
+// transientError reports whether err is a deadlock or lock wait timeout.
+func transientError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && transientErrors[mysqlErr.Number] != ""
+}
+
+// retryTransient runs fn, running it again while it fails with a transient
+// error, up to transientRetries times. fn must be safe to repeat: a single
+// autocommitted statement, or a whole transaction. It returns the number of
+// retries and fn's last error.
+func retryTransient(ctx context.Context, fn func() error) (int, error) {
+	err := fn()
+	retries := 0
+	for ; retries < transientRetries && transientError(err); retries++ {
+		wait := max(retryBackoff, 0) << retries
+		wait += rand.N(wait/2 + 1)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return retries, err
+		}
+		err = fn()
+	}
+	return retries, err
+}
+
+// retryNote describes the retries of a write for its result text.
+func retryNote(retries int) string {
+	if retries == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\nRetried %d time(s) after a deadlock or lock wait timeout", retries)
+}
+
+// execer runs statements on a pool or in a transaction.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// retryingExecer runs each autocommitted statement again when it fails
+// with a transient error, counting the retries.
+type retryingExecer struct {
+	execer
+	retries *int
+}
+
+func (r retryingExecer) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var res sql.Result
+	n, err := retryTransient(ctx, func() error {
+		var err error
+		res, err = r.execer.ExecContext(ctx, query, args...)
+		return err
+	})
+	*r.retries += n
+	return res, err
+}
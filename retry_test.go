@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// withFastRetries shortens the retry backoff for the test.
+func withFastRetries(t *testing.T) {
+	t.Helper()
+	saved := retryBackoff
+	retryBackoff = time.Microsecond
+	t.Cleanup(func() { retryBackoff = saved })
+}
+
+func TestTransientError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}, true},
+		{&mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}, true},
+		{fmt.Errorf("update rows: %w", &mysql.MySQLError{Number: 1213}), true},
+		{&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, false},
+		{&mysql.MySQLError{Number: 3024, Message: "Query execution was interrupted"}, false},
+		{errors.New("deadlock"), false},
+		{context.Canceled, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := transientError(tt.err); got != tt.want {
+			t.Errorf("transientError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryTransient(t *testing.T) {
+	withFastRetries(t)
+	deadlock := &mysql.MySQLError{Number: 1213}
+	lockWait := &mysql.MySQLError{Number: 1205}
+	duplicate := &mysql.MySQLError{Number: 1062}
+
+	tests := []struct {
+		name string
+		// errs are fn's results in turn; the last repeats.
+		errs        []error
+		wantCalls   int
+		wantRetries int
+		wantErr     error
+	}{
+		{"success", []error{nil}, 1, 0, nil},
+		{"deadlock then success", []error{deadlock, nil}, 2, 1, nil},
+		{"lock waits then success", []error{lockWait, lockWait, nil}, 3, 2, nil},
+		{"non-transient", []error{duplicate, nil}, 1, 0, duplicate},
+		{"transient then non-transient", []error{deadlock, duplicate, nil}, 2, 1, duplicate},
+		{"retry limit", []error{deadlock}, transientRetries + 1, transientRetries, deadlock},
+	}
+	for _, tt := range tests {
+		calls := 0
+		retries, err := retryTransient(context.Background(), func() error {
+			err := tt.errs[min(calls, len(tt.errs)-1)]
+			calls++
+			return err
+		})
+		if calls != tt.wantCalls || retries != tt.wantRetries || err != tt.wantErr {
+			t.Errorf("%s: %d calls, %d retries, err %v; want %d calls, %d retries, err %v",
+				tt.name, calls, retries, err, tt.wantCalls, tt.wantRetries, tt.wantErr)
+		}
+	}
+}
+
+func TestRetryTransientCancelled(t *testing.T) {
+	saved := retryBackoff
+	retryBackoff = time.Hour
+	defer func() { retryBackoff = saved }()
+	lockWait := &mysql.MySQLError{Number: 1205}
+
+	// A context that ends during the backoff stops the retries at once
+	// with the last error.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	calls := 0
+	retries, err := retryTransient(ctx, func() error {
+		calls++
+		return lockWait
+	})
+	if calls != 1 || retries != 0 || err != lockWait {
+		t.Errorf("%d calls, %d retries, err %v; want 1 call, 0 retries, err %v", calls, retries, err, lockWait)
+	}
+}
+
+func TestRetryTransientBackoff(t *testing.T) {
+	saved, savedRetries := retryBackoff, transientRetries
+	retryBackoff, transientRetries = 10*time.Millisecond, 2
+	defer func() { retryBackoff, transientRetries = saved, savedRetries }()
+
+	// Two retries wait 10ms and 20ms, each plus up to half again.
+	start := time.Now()
+	retries, _ := retryTransient(context.Background(), func() error { return &mysql.MySQLError{Number: 1213} })
+	elapsed := time.Since(start)
+	if retries != 2 {
+		t.Fatalf("retries = %d, want 2", retries)
+	}
+	if elapsed < 30*time.Millisecond {
+		t.Errorf("retries took %v, want at least 30ms of backoff", elapsed)
+	}
+	if elapsed > time.Second {
+		t.Errorf("retries took %v, want well under a second", elapsed)
+	}
+}
+
+// flakyExecer fails its first failures calls with err.
+type flakyExecer struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (e *flakyExecer) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	e.calls++
+	if e.calls <= e.failures {
+		return nil, e.err
+	}
+	return nil, nil
+}
+
+func TestRetryingExecer(t *testing.T) {
+	withFastRetries(t)
+	tests := []struct {
+		failures    int
+		err         error
+		wantCalls   int
+		wantRetries int
+		wantErr     bool
+	}{
+		{0, nil, 1, 0, false},
+		{1, &mysql.MySQLError{Number: 1213}, 2, 1, false},
+		{2, &mysql.MySQLError{Number: 1205}, 3, 2, false},
+		{1, &mysql.MySQLError{Number: 1452}, 1, 0, true},
+		{10, &mysql.MySQLError{Number: 1213}, transientRetries + 1, transientRetries, true},
+	}
+	for _, tt := range tests {
+		inner := &flakyExecer{failures: tt.failures, err: tt.err}
+		retries := 0
+		exec := retryingExecer{execer: inner, retries: &retries}
+		// Retries of separate statements add up.
+		for range 2 {
+			inner.calls = 0
+			_, err := exec.ExecContext(context.Background(), "INSERT INTO t VALUES (1)")
+			if (err != nil) != tt.wantErr || inner.calls != tt.wantCalls {
+				t.Errorf("%d failures with %v: %d calls, err %v; want %d calls, error %v",
+					tt.failures, tt.err, inner.calls, err, tt.wantCalls, tt.wantErr)
+			}
+		}
+		if retries != 2*tt.wantRetries {
+			t.Errorf("%d failures with %v: %d retries counted, want %d", tt.failures, tt.err, retries, 2*tt.wantRetries)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// scanRowMaps reads all remaining rows into column -> value maps, converting
+// byte slices to text the same way execute_query does.
+func scanRowMaps(rows *sql.Rows) ([]string, []map[string]any, error) {
+	columns, results, _, err := scanRowMapsLimit(rows, 0)
+	return columns, results, err
+}
+
+// queryRowMaps runs a query on db with queryRead, so in read-only mode it
+// runs in a read-only transaction, and reads its rows with scanRowMaps.
+func queryRowMaps(ctx context.Context, db *sql.DB, query string, params ...any) ([]string, []map[string]any, error) {
+	rows, done, err := queryRead(ctx, db, query, params...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer done()
+	return scanRowMaps(rows)
+}
+
+// scanRowMapsLimit is scanRowMaps reading at most limit rows, or all of
+// them if limit is 0. truncated reports whether more rows remained.
+func scanRowMapsLimit(rows *sql.Rows, limit int) (columns []string, results []map[string]any, truncated bool, err error) {
+	columns, err = rows.Columns()
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("failed to get columns: %w", err)
+	}
+	kinds := resultColumnKinds(rows, len(columns))
+
+	for rows.Next() {
+		if limit > 0 && len(results) == limit {
+			truncated = true
+			break
+		}
+		values := make([]any, len(columns))
+		valuePtrs := make([]any, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return nil, nil, false, fmt.Errorf("failed to scan row: %w", err)
+		}
+
+		row := make(map[string]any, len(columns))
+		for i, col := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[col] = displayBytes(kinds[i], b)
+			} else {
+				row[col] = values[i]
+			}
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, false, fmt.Errorf("row iteration error: %w", err)
+	}
+	return columns, results, truncated, nil
+}
+
+// resultColumnKinds returns, for each of n result columns, the driver type
+// name of the columns whose raw bytes are rendered specially (VECTOR,
+// GEOMETRY and JSON) or that results treat as dates (DATE, DATETIME and
+// TIMESTAMP), NUMBER for integer and decimal columns, and "" for the
+// others.
+func resultColumnKinds(rows *sql.Rows, n int) []string {
+	kinds := make([]string, n)
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return kinds
+	}
+	for i, ct := range types {
+		switch name := strings.TrimPrefix(ct.DatabaseTypeName(), "UNSIGNED "); name {
+		case "VECTOR", "GEOMETRY", "JSON", "DATE", "DATETIME", "TIMESTAMP":
+			kinds[i] = name
+		case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "DECIMAL", "FLOAT", "DOUBLE":
+			kinds[i] = "NUMBER"
+		}
+	}
+	return kinds
+}
+
+// displayBytes converts a byte slice value of a column of the given kind
+// to text for tool results.
+func displayBytes(kind string, b []byte) string {
+	switch kind {
+	case "VECTOR":
+		return vectorPreview(b)
+	case "GEOMETRY":
+		return geometryText(b)
+	case "JSON":
+		return jsonText(b)
+	}
+	return string(b)
+}
+
+// formatRowTable renders rows as the fixed-width text table used in tool
+// results. Rows with multi-line values, such as pretty-printed JSON, are
+// listed vertically instead, one column per line.
+func formatRowTable(columns []string, rows []map[string]any) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	for _, row := range rows {
+		for _, val := range row {
+			if s, ok := val.(string); ok && strings.Contains(s, "\n") {
+				return formatRowList(columns, rows)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for i, col := range columns {
+		sb.WriteString(fmt.Sprintf("%-20s", col))
+		if i < len(columns)-1 {
+			sb.WriteString(" | ")
+		}
+	}
+	sb.WriteString("\n" + strings.Repeat("-", len(columns)*23) + "\n")
+
+	for _, row := range rows {
+		for i, col := range columns {
+			val := row[col]
+			if val == nil {
+				val = "NULL"
+			}
+			sb.WriteString(fmt.Sprintf("%-20v", val))
+			if i < len(columns)-1 {
+				sb.WriteString(" | ")
+			}
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// formatRowList renders rows vertically, like the mysql client's \G.
+func formatRowList(columns []string, rows []map[string]any) string {
+	width := 0
+	for _, col := range columns {
+		width = max(width, len(col))
+	}
+	indent := "\n" + strings.Repeat(" ", width+2)
+
+	var sb strings.Builder
+	for n, row := range rows {
+		sb.WriteString(fmt.Sprintf("%s %d. row %s\n", strings.Repeat("*", 27), n+1, strings.Repeat("*", 27)))
+		for _, col := range columns {
+			val := row[col]
+			if val == nil {
+				val = "NULL"
+			}
+			text := strings.ReplaceAll(fmt.Sprint(val), "\n", indent)
+			sb.WriteString(fmt.Sprintf("%*s: %s\n", width, col, text))
+		}
+	}
+	return sb.String()
+}
+
+// rowText returns a column of a scanned row as text, or "" for NULL.
+func rowText(row map[string]any, col string) string {
+	if row[col] == nil {
+		return ""
+	}
+	return fmt.Sprint(row[col])
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	mathrand "math/rand/v2"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	// samplePKRangeMax is the largest sample auto draws by primary key
+	// hopping, which costs an index seek per row.
+	samplePKRangeMax = 1000
+	// samplePKBatch is how many seeks are sent in one UNION ALL query.
+	samplePKBatch = 100
+	// sampleKeyColumn carries the primary key of hopped rows so repeats
+	// can be dropped; it is removed from the result.
+	sampleKeyColumn = "_sample_key"
+)
+
+// sampleIdentifier matches a plain or backquoted identifier.
+const sampleIdentifier = "(?:`[^`]+`|[A-Za-z0-9_$]+)"
+
+// sampleSelectPattern matches the single-table SELECTs that sample can
+// rewrite: SELECT columns FROM table [[AS] alias] [WHERE condition].
+var sampleSelectPattern = regexp.MustCompile(`(?is)^SELECT\s+(.+?)\s+FROM\s+(?:(` + sampleIdentifier + `)\.)?(` + sampleIdentifier + `)(?:\s+(?:AS\s+)?(` + sampleIdentifier + `))?(?:\s+WHERE\s+(.+?))?\s*;?\s*$`)
+
+// sampleUnsupported are the clauses whose results a sample would change
+// the meaning of, or that the pattern cannot place a condition around.
+var sampleUnsupported = []string{" JOIN ", " GROUP BY ", " ORDER BY ", " LIMIT ", " UNION ", " HAVING ", "(SELECT ", " INTO ", " FOR UPDATE", " LOCK IN ", "SELECT DISTINCT ",
+	"COUNT(", "SUM(", "AVG(", "MIN(", "MAX(", "GROUP_CONCAT("}
+
+// sampleQuery is a SELECT taken apart for rewriting.
+type sampleQuery struct {
+	columns  string
+	database string
+	table    string
+	// name is the table's alias, or else its name, as written.
+	name  string
+	from  string
+	where string
+}
+
+// parseSampleQuery splits a single-table SELECT into its parts, or explains
+// why it cannot be sampled.
+func parseSampleQuery(ctx context.Context, query string) (*sampleQuery, error) {
+	upper := " " + strings.ToUpper(sanitizeStatement(query)) + " "
+	for _, clause := range sampleUnsupported {
+		if strings.Contains(upper, clause) {
+			return nil, fmt.Errorf("sample rewrites only plain single-table SELECT ... FROM table [WHERE ...] queries, and this one has %s", strings.TrimSpace(strings.TrimSuffix(clause, "(")))
+		}
+	}
+	m := sampleSelectPattern.FindStringSubmatch(strings.TrimSpace(query))
+	if m == nil {
+		return nil, fmt.Errorf("sample rewrites only plain single-table SELECT ... FROM table [WHERE ...] queries")
+	}
+	unquote := func(s string) string { return strings.ReplaceAll(strings.Trim(s, "`"), "``", "`") }
+	q := &sampleQuery{columns: m[1], database: unquote(m[2]), table: unquote(m[3]), name: m[3], from: m[3], where: m[5]}
+	if m[2] != "" {
+		q.from = m[2] + "." + m[3]
+	}
+	if m[4] != "" {
+		q.from += " AS " + m[4]
+		q.name = m[4]
+	}
+	database, err := contextDatabase(ctx, q.database)
+	if err != nil {
+		return nil, err
+	}
+	q.database = database
+	return q, nil
+}
+
+// filter returns the query's WHERE condition with extra ANDed to it.
+func (q *sampleQuery) filter(extra string) string {
+	if q.where == "" {
+		return " WHERE " + extra
+	}
+	return fmt.Sprintf(" WHERE (%s) AND %s", q.where, extra)
+}
+
+// keyRange returns the smallest and largest value of key in the query's
+// table, or 0 and -1 when it is empty. q.from may name a view, so the read
+// goes through queryRead like the sample itself.
+func (q *sampleQuery) keyRange(ctx context.Context, db *sql.DB, key string) (low, high int64, err error) {
+	rows, done, err := queryRead(ctx, db, fmt.Sprintf("SELECT COALESCE(MIN(%s), 0), COALESCE(MAX(%s), -1) FROM %s", key, key, q.from))
+	if err != nil {
+		return 0, 0, err
+	}
+	defer done()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, 0, err
+		}
+		return 0, 0, sql.ErrNoRows
+	}
+	err = rows.Scan(&low, &high)
+	return low, high, err
+}
+
+// integerPrimaryKey returns the table's primary key column when it is a
+// single integer column, the case key hopping needs.
+func integerPrimaryKey(ctx context.Context, db *sql.DB, database, table string) (string, error) {
+	keys, err := uniqueKeys(ctx, db, database, table)
+	if err != nil {
+		return "", err
+	}
+	pk := keys["PRIMARY"]
+	if len(pk) != 1 {
+		return "", nil
+	}
+	cols, err := tableColumns(ctx, db, database, table)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range cols {
+		if c.ColumnName == pk[0] {
+			switch c.DataType {
+			case "tinyint", "smallint", "mediumint", "int", "bigint":
+				return pk[0], nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// estimateRows returns the optimizer's estimate of the rows a query
+// returns, from EXPLAIN's rows and filtered columns.
+func estimateRows(ctx context.Context, db *sql.DB, query string, params ...any) (float64, error) {
+	_, plan, err := queryRowMaps(ctx, db, "EXPLAIN "+query, params...)
+	if err != nil || len(plan) == 0 {
+		return 0, err
+	}
+	estimate, _ := strconv.ParseFloat(fmt.Sprint(plan[0]["rows"]), 64)
+	if filtered, err := strconv.ParseFloat(fmt.Sprint(plan[0]["filtered"]), 64); err == nil {
+		estimate *= filtered / 100
+	}
+	return estimate, nil
+}
+
+// sampleByKeyHopping picks n random points between the smallest and
+// largest key and reads the first matching row at or after each, one
+// index seek per row. Rows after large gaps in the key are more likely to
+// be picked, and repeats are dropped, so fewer than n rows may come back.
+func sampleByKeyHopping(ctx context.Context, db *sql.DB, q *sampleQuery, pk string, n int) ([]string, []map[string]any, string, error) {
+	key := quoteIdentifier(pk)
+	low, high, err := q.keyRange(ctx, db, key)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if high < low {
+		return nil, nil, "", nil
+	}
+	// A bare * may not follow another column.
+	columns := q.columns
+	if strings.TrimSpace(columns) == "*" {
+		columns = q.name + ".*"
+	}
+	one := fmt.Sprintf("(SELECT %s AS %s, %s FROM %s%s ORDER BY %s LIMIT 1)", key, sampleKeyColumn, columns, q.from, q.filter(key+" >= ?"), key)
+
+	var resultColumns []string
+	var results []map[string]any
+	seen := make(map[string]bool)
+	for done := 0; done < n; done += samplePKBatch {
+		batch := min(samplePKBatch, n-done)
+		params := make([]any, batch)
+		for i := range params {
+			params[i] = low + mathrand.Int64N(high-low+1)
+		}
+		cols, batchRows, err := queryRowMaps(ctx, db, strings.TrimSuffix(strings.Repeat(one+" UNION ALL ", batch), " UNION ALL "), params...)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		resultColumns = cols[1:]
+		for _, row := range batchRows {
+			k := fmt.Sprint(row[sampleKeyColumn])
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			delete(row, sampleKeyColumn)
+			results = append(results, row)
+		}
+	}
+	return resultColumns, results, one + " UNION ALL ...", nil
+}
+
+// sampleByRange reads n consecutive rows by primary key from a random
+// starting key, wrapping around to the smallest key. It is the cheapest
+// method but the rows are neighbours, not independent.
+func sampleByRange(ctx context.Context, db *sql.DB, q *sampleQuery, pk string, n int) ([]string, []map[string]any, string, error) {
+	key := quoteIdentifier(pk)
+	low, high, err := q.keyRange(ctx, db, key)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if high < low {
+		return nil, nil, "", nil
+	}
+	start := low + mathrand.Int64N(high-low+1)
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s LIMIT %d", q.columns, q.from, q.filter(key+" >= ?"), key, n)
+	columns, results, err := queryRowMaps(ctx, db, query, start)
+	if err == nil && len(results) < n {
+		var rest []map[string]any
+		wrap := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s LIMIT %d", q.columns, q.from, q.filter(key+" < ?"), key, n-len(results))
+		if columns, rest, err = queryRowMaps(ctx, db, wrap, start); err == nil {
+			results = append(results, rest...)
+		}
+	}
+	return columns, results, query, err
+}
+
+// sampleByRand keeps each row with a probability sized from the
+// optimizer's row estimate to return about 1.5n rows, then picks n of
+// them at random. The table is still scanned, but only the kept rows are
+// sorted, unlike ORDER BY RAND().
+func sampleByRand(ctx context.Context, db *sql.DB, q *sampleQuery, query string, n int) ([]string, []map[string]any, string, error) {
+	estimate, err := estimateRows(ctx, db, query)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	fraction := 1.0
+	if estimate > 0 {
+		fraction = min(1.5*float64(n)/estimate, 1)
+	}
+	sampled := fmt.Sprintf("SELECT * FROM (SELECT %s FROM %s%s) AS sampled ORDER BY RAND() LIMIT %d",
+		q.columns, q.from, q.filter("RAND() < "+strconv.FormatFloat(fraction, 'f', 8, 64)), n)
+	columns, results, err := queryRowMaps(ctx, db, sampled)
+	return columns, results, sampled, err
+}
+
+// sampleResult answers execute_query with sample set, returning about n
+// random rows of a SELECT without sorting the whole result.
+func sampleResult(ctx context.Context, db *sql.DB, query string, n int, method string) (*mcp.CallToolResult, any, error) {
+	q, err := parseSampleQuery(ctx, query)
+	if err == nil {
+		err = checkReadQuery(query)
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Cannot sample this query: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	method = strings.ToLower(method)
+	var pk string
+	if method != "rand" {
+		if pk, err = integerPrimaryKey(ctx, db, q.database, q.table); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to read the primary key: %v", err)},
+				},
+			}, nil, nil
+		}
+	}
+	switch method {
+	case "", "auto":
+		method = "rand"
+		if pk != "" && n <= samplePKRangeMax {
+			method = "pk_hop"
+		}
+	case "pk_hop", "range":
+		if pk == "" {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("sample_method %s needs a single-column integer primary key, which %s.%s does not have; use rand", method, q.database, q.table)},
+				},
+			}, nil, nil
+		}
+	case "rand":
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown sample_method %q (use auto, pk_hop, range or rand)", method)},
+			},
+		}, nil, nil
+	}
+
+	var columns []string
+	var results []map[string]any
+	var sampleSQL, how string
+	switch method {
+	case "pk_hop":
+		columns, results, sampleSQL, err = sampleByKeyHopping(ctx, db, q, pk, n)
+		how = fmt.Sprintf("seeking to random %s values", pk)
+	case "range":
+		columns, results, sampleSQL, err = sampleByRange(ctx, db, q, pk, n)
+		how = fmt.Sprintf("reading consecutive rows from a random %s (rows are neighbours, not independent)", pk)
+	default:
+		columns, results, sampleSQL, err = sampleByRand(ctx, db, q, query, n)
+		how = "a random filter sized from the optimizer's row estimate"
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to sample: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("Sampled %d rows by %s:\n\n%s", len(results), how, formatRowTable(columns, results))
+	if len(results) < n {
+		resultText += fmt.Sprintf("\nFewer than the %d rows asked for: the query matches few rows, the estimate was low, or random keys landed on the same rows.\n", n)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"rows":         results,
+		"rowCount":     len(results),
+		"columns":      columns,
+		"sampleMethod": method,
+		"sampleQuery":  sampleSQL,
+	}, nil
+}
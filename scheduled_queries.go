@@ -0,0 +1,509 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// schedulesFile is where scheduled queries and their stored results are
+// kept across restarts, set with -schedules-file.
+var schedulesFile = "scheduled-queries.json"
+
+const (
+	// scheduledRunsKept is how many runs of each query are remembered.
+	scheduledRunsKept = 10
+	// scheduledRowsKept caps the rows stored per run, and
+	// scheduledRowsNotified the rows sent in a notification.
+	scheduledRowsKept     = 1000
+	scheduledRowsNotified = 100
+	scheduledQueryTimeout = 10 * time.Minute
+)
+
+// ScheduledQuery is a query run on a cron schedule, with what to do with
+// its result: store it for scheduled_query_results, export it to a file or
+// send it to connected clients as a notification.
+type ScheduledQuery struct {
+	Name      string         `json:"name"`
+	Schedule  string         `json:"schedule"`
+	Query     string         `json:"query"`
+	Action    string         `json:"action"`
+	Path      string         `json:"path,omitempty"`
+	Format    string         `json:"format,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Runs      []ScheduledRun `json:"runs,omitempty"`
+
+	cron    *cronSchedule
+	running bool
+}
+
+// ScheduledRun is the outcome of one run, newest last in Runs.
+type ScheduledRun struct {
+	Time       time.Time        `json:"time"`
+	DurationMs int64            `json:"durationMs"`
+	RowCount   int              `json:"rowCount"`
+	Columns    []string         `json:"columns,omitempty"`
+	Rows       []map[string]any `json:"rows,omitempty"`
+	Truncated  bool             `json:"truncated,omitempty"`
+	Path       string           `json:"path,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+var scheduledQueries = struct {
+	sync.Mutex
+	byName map[string]*ScheduledQuery
+}{byName: make(map[string]*ScheduledQuery)}
+
+// loadScheduledQueries reads the schedules file, if there is one.
+func loadScheduledQueries() error {
+	data, err := os.ReadFile(schedulesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var queries []*ScheduledQuery
+	if err := json.Unmarshal(data, &queries); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", schedulesFile, err)
+	}
+
+	scheduledQueries.Lock()
+	defer scheduledQueries.Unlock()
+	for _, q := range queries {
+		if q.cron, err = parseCron(q.Schedule); err != nil {
+			return fmt.Errorf("scheduled query %s: %w", q.Name, err)
+		}
+		scheduledQueries.byName[q.Name] = q
+	}
+	return nil
+}
+
+// saveScheduledQueries writes all scheduled queries to the schedules file.
+// The caller holds the lock.
+func saveScheduledQueries() error {
+	queries := make([]*ScheduledQuery, 0, len(scheduledQueries.byName))
+	for _, name := range sortedKeys(scheduledQueries.byName) {
+		queries = append(queries, scheduledQueries.byName[name])
+	}
+	data, err := json.MarshalIndent(queries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tempFile := schedulesFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tempFile, schedulesFile); err != nil {
+		os.Remove(tempFile)
+		return err
+	}
+	return nil
+}
+
+// runScheduler starts the scheduled queries that are due at the start of
+// every minute, for as long as the server runs.
+func runScheduler(server *mcp.Server) {
+	for {
+		now := time.Now()
+		tick := now.Truncate(time.Minute).Add(time.Minute)
+		time.Sleep(tick.Sub(now))
+
+		scheduledQueries.Lock()
+		for _, q := range scheduledQueries.byName {
+			// Due if the first firing after the previous minute is this one.
+			if q.running || !q.cron.next(tick.Add(-time.Minute)).Equal(tick) {
+				continue
+			}
+			q.running = true
+			go runScheduledQuery(server, q, tick)
+		}
+		scheduledQueries.Unlock()
+	}
+}
+
+func runScheduledQuery(server *mcp.Server, q *ScheduledQuery, started time.Time) {
+	db, release := holdDB()
+	defer release()
+	ctx, cancel := context.WithTimeout(context.Background(), scheduledQueryTimeout)
+	defer cancel()
+
+	run := ScheduledRun{Time: started}
+	begin := time.Now()
+	err := func() error {
+		if db == nil {
+			return errors.New("not connected to a database")
+		}
+		if q.Action == "export" {
+			path := strings.ReplaceAll(q.Path, "{time}", started.Format("20060102-150405"))
+			stats, err := exportQuery(ctx, db, q.Query, path, q.Format, "", true)
+			if err != nil {
+				return err
+			}
+			run.Path, run.RowCount, run.Columns = path, stats.Rows, stats.Columns
+			return nil
+		}
+		rows, done, err := queryReadOnly(ctx, db, q.Query)
+		if err != nil {
+			return err
+		}
+		defer done()
+		columns, results, err := scanRowMaps(rows)
+		if err != nil {
+			return err
+		}
+		run.RowCount, run.Columns = len(results), columns
+		kept := scheduledRowsKept
+		if q.Action == "notify" {
+			kept = scheduledRowsNotified
+		}
+		run.Rows, run.Truncated = results[:min(len(results), kept)], len(results) > kept
+		return nil
+	}()
+	run.DurationMs = time.Since(begin).Milliseconds()
+	if err != nil {
+		run.Error = err.Error()
+		slog.Warn("scheduled query failed", "name", q.Name, "err", err)
+	} else {
+		slog.Info("scheduled query ran", "name", q.Name, "action", q.Action, "rows", run.RowCount, "duration_ms", run.DurationMs)
+	}
+
+	if q.Action == "notify" {
+		level := mcp.LoggingLevel("info")
+		if err != nil {
+			level = "error"
+		}
+		for session := range server.Sessions() {
+			session.Log(context.Background(), &mcp.LoggingMessageParams{
+				Level:  level,
+				Logger: "scheduled-query",
+				Data: map[string]any{
+					"name":      q.Name,
+					"query":     q.Query,
+					"time":      run.Time,
+					"rowCount":  run.RowCount,
+					"columns":   run.Columns,
+					"rows":      run.Rows,
+					"truncated": run.Truncated,
+					"error":     run.Error,
+				},
+			})
+		}
+		// Notified rows are not stored.
+		run.Rows = nil
+	}
+
+	scheduledQueries.Lock()
+	defer scheduledQueries.Unlock()
+	q.running = false
+	q.Runs = append(q.Runs, run)
+	if len(q.Runs) > scheduledRunsKept {
+		q.Runs = q.Runs[len(q.Runs)-scheduledRunsKept:]
+	}
+	if scheduledQueries.byName[q.Name] == q {
+		if err := saveScheduledQueries(); err != nil {
+			slog.Warn("failed to save scheduled queries", "file", schedulesFile, "err", err)
+		}
+	}
+}
+
+type ScheduleQueryParams struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	Query    string `json:"query"`
+	Action   string `json:"action,omitempty"`
+	Path     string `json:"path,omitempty"`
+	Format   string `json:"format,omitempty"`
+}
+
+func ScheduleQuery(ctx context.Context, req *mcp.CallToolRequest, args ScheduleQueryParams) (*mcp.CallToolResult, any, error) {
+	query := strings.TrimSpace(args.Query)
+	if args.Name == "" || args.Schedule == "" || query == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "name, schedule and query are required"},
+			},
+		}, nil, nil
+	}
+	cron, err := parseCron(args.Schedule)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid schedule: %v", err)},
+			},
+		}, nil, nil
+	}
+	if err := checkReadQuery(query); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Cannot schedule this query: %v", err)},
+			},
+		}, nil, nil
+	}
+	// Runs happen without a client to ask, so the query is checked, and
+	// any override approved, when it is scheduled.
+	if blocked := enforceCostPolicy(ctx, req, "schedule_query", currentDB(), query); blocked != nil {
+		return blocked, nil, nil
+	}
+
+	q := &ScheduledQuery{
+		Name:      args.Name,
+		Schedule:  args.Schedule,
+		Query:     query,
+		Action:    strings.ToLower(args.Action),
+		CreatedAt: time.Now().UTC(),
+		cron:      cron,
+	}
+	switch q.Action {
+	case "":
+		q.Action = "store"
+	case "store", "notify":
+	case "export":
+		q.Format = strings.ToLower(args.Format)
+		if q.Format == "" {
+			q.Format = "csv"
+		}
+		if q.Format != "csv" && q.Format != "jsonl" && q.Format != "arrow" {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Unsupported export format %q (use csv, jsonl or arrow)", args.Format)},
+				},
+			}, nil, nil
+		}
+		if args.Path == "" {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "path is required for the export action"},
+				},
+			}, nil, nil
+		}
+		if q.Path, err = filepath.Abs(args.Path); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid export path: %v", err)},
+				},
+			}, nil, nil
+		}
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown action %q (use store, export or notify)", args.Action)},
+			},
+		}, nil, nil
+	}
+
+	scheduledQueries.Lock()
+	previous, replaced := scheduledQueries.byName[q.Name]
+	scheduledQueries.byName[q.Name] = q
+	err = saveScheduledQueries()
+	if err != nil {
+		if replaced {
+			scheduledQueries.byName[q.Name] = previous
+		} else {
+			delete(scheduledQueries.byName, q.Name)
+		}
+	}
+	scheduledQueries.Unlock()
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to save %s: %v", schedulesFile, err)},
+			},
+		}, nil, nil
+	}
+
+	var upcoming []time.Time
+	for t := time.Now(); len(upcoming) < 3; {
+		if t = cron.next(t); t.IsZero() {
+			break
+		}
+		upcoming = append(upcoming, t)
+	}
+	verb := "Scheduled"
+	if replaced {
+		verb = "Replaced"
+	}
+	resultText := fmt.Sprintf("%s %s (%s, action %s)\n", verb, q.Name, q.Schedule, q.Action)
+	if len(upcoming) == 0 {
+		resultText += "Warning: the schedule never fires.\n"
+	}
+	for _, t := range upcoming {
+		resultText += "Next run: " + t.Format(time.RFC3339) + "\n"
+	}
+	resultText += "Runs happen while this server is running; missed runs are not made up.\n"
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"name":     q.Name,
+		"replaced": replaced,
+		"nextRuns": upcoming,
+	}, nil
+}
+
+func ListScheduledQueries(ctx context.Context, req *mcp.CallToolRequest, args struct{}) (*mcp.CallToolResult, any, error) {
+	scheduledQueries.Lock()
+	defer scheduledQueries.Unlock()
+
+	resultText := fmt.Sprintf("%d scheduled queries\n", len(scheduledQueries.byName))
+	var queries []map[string]any
+	for _, name := range sortedKeys(scheduledQueries.byName) {
+		q := scheduledQueries.byName[name]
+		next := q.cron.next(time.Now())
+		nextText := "never"
+		if !next.IsZero() {
+			nextText = next.Format(time.RFC3339)
+		}
+		resultText += fmt.Sprintf("\n%s: %s, action %s, next run %s\n  %s\n", q.Name, q.Schedule, q.Action, nextText, q.Query)
+		entry := map[string]any{
+			"name":     q.Name,
+			"schedule": q.Schedule,
+			"query":    q.Query,
+			"action":   q.Action,
+			"nextRun":  next,
+			"running":  q.running,
+		}
+		if q.Path != "" {
+			entry["path"] = q.Path
+		}
+		if len(q.Runs) > 0 {
+			last := q.Runs[len(q.Runs)-1]
+			status := fmt.Sprintf("%d rows in %dms", last.RowCount, last.DurationMs)
+			if last.Error != "" {
+				status = "failed: " + last.Error
+			}
+			resultText += fmt.Sprintf("  last run %s: %s\n", last.Time.Format(time.RFC3339), status)
+			entry["lastRun"] = last.Time
+			entry["lastError"] = last.Error
+		}
+		queries = append(queries, entry)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"queries": queries,
+	}, nil
+}
+
+type ScheduledQueryResultsParams struct {
+	Name string `json:"name"`
+	Runs int    `json:"runs,omitempty"`
+}
+
+func ScheduledQueryResults(ctx context.Context, req *mcp.CallToolRequest, args ScheduledQueryResultsParams) (*mcp.CallToolResult, any, error) {
+	scheduledQueries.Lock()
+	q, ok := scheduledQueries.byName[args.Name]
+	var runs []ScheduledRun
+	kept := 0
+	if ok {
+		n := args.Runs
+		if n <= 0 {
+			n = 1
+		}
+		runs = append(runs, q.Runs[max(0, len(q.Runs)-n):]...)
+		kept = len(q.Runs)
+	}
+	scheduledQueries.Unlock()
+	if !ok {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("No scheduled query %q", args.Name)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("%s (%s, action %s): %d runs kept\n", q.Name, q.Schedule, q.Action, kept)
+	if len(runs) == 0 {
+		resultText += "It has not run yet.\n"
+	}
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		resultText += fmt.Sprintf("\nRun at %s: ", run.Time.Format(time.RFC3339))
+		switch {
+		case run.Error != "":
+			resultText += "failed: " + run.Error + "\n"
+		case run.Path != "":
+			resultText += fmt.Sprintf("exported %d rows to %s in %dms\n", run.RowCount, run.Path, run.DurationMs)
+		default:
+			resultText += fmt.Sprintf("%d rows in %dms", run.RowCount, run.DurationMs)
+			if run.Truncated {
+				resultText += fmt.Sprintf(" (first %d stored)", len(run.Rows))
+			}
+			resultText += "\n"
+			if len(run.Rows) > 0 {
+				resultText += formatRowTable(run.Columns, run.Rows)
+			}
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"name": q.Name,
+		"runs": runs,
+	}, nil
+}
+
+type UnscheduleQueryParams struct {
+	Name string `json:"name"`
+}
+
+func UnscheduleQuery(ctx context.Context, req *mcp.CallToolRequest, args UnscheduleQueryParams) (*mcp.CallToolResult, any, error) {
+	scheduledQueries.Lock()
+	q, ok := scheduledQueries.byName[args.Name]
+	var err error
+	if ok {
+		delete(scheduledQueries.byName, args.Name)
+		if err = saveScheduledQueries(); err != nil {
+			scheduledQueries.byName[args.Name] = q
+		}
+	}
+	scheduledQueries.Unlock()
+	if !ok {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("No scheduled query %q", args.Name)},
+			},
+		}, nil, nil
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to save %s: %v", schedulesFile, err)},
+			},
+		}, nil, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Removed scheduled query %s and its stored results", args.Name)},
+		},
+	}, nil, nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// schemaCache remembers table column definitions so structured tools can
+// validate their arguments without re-querying information_schema on every
+// call. Entries are kept per pool, so a lookup still running on a pool
+// that connect has replaced cannot fill in the new server's tables; a
+// pool's entries go when it is closed. Entries are dropped whenever this
+// server runs DDL.
+var schemaCache = struct {
+	sync.Mutex
+	columns map[*sql.DB]map[string][]ColumnInfo
+}{columns: make(map[*sql.DB]map[string][]ColumnInfo)}
+
+func schemaCacheKey(database, table string) string {
+	return database + "." + table
+}
+
+// catalogTable checks a database and table name given to a tool against
+// the catalog and returns the table's columns, so statements built from
+// them only ever name a table that exists. Its errors are meant for the
+// tool result.
+func catalogTable(ctx context.Context, db *sql.DB, database, table string) ([]ColumnInfo, error) {
+	if err := checkIdentifier("database", database); err != nil {
+		return nil, fmt.Errorf("Cannot use this table: %w", err)
+	}
+	if err := checkIdentifier("table", table); err != nil {
+		return nil, fmt.Errorf("Cannot use this table: %w", err)
+	}
+	columns, err := tableColumns(ctx, db, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read table columns: %v", err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("Table %s.%s does not exist", database, table)
+	}
+	return columns, nil
+}
+
+// tableColumns returns the columns of database.table on db in ordinal
+// order. An empty result means the table does not exist.
+func tableColumns(ctx context.Context, db *sql.DB, database, table string) ([]ColumnInfo, error) {
+	key := schemaCacheKey(database, table)
+
+	schemaCache.Lock()
+	cached, ok := schemaCache.columns[db][key]
+	schemaCache.Unlock()
+	if ok {
+		schemaCacheRequests.inc("hit")
+		return cached, nil
+	}
+	schemaCacheRequests.inc("miss")
+
+	query := `
+		SELECT COLUMN_NAME, IF(DATA_TYPE = 'vector', COLUMN_TYPE, DATA_TYPE), IS_NULLABLE, COLUMN_DEFAULT, EXTRA
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`
+	rows, err := db.QueryContext(ctx, query, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query table columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var col ColumnInfo
+		if err := rows.Scan(&col.ColumnName, &col.DataType, &col.IsNullable, &col.ColumnDefault, &col.Extra); err != nil {
+			return nil, fmt.Errorf("failed to scan column info: %w", err)
+		}
+		columns = append(columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(columns) > 0 {
+		cacheTableColumns(db, database, table, columns)
+	}
+	return columns, nil
+}
+
+// cacheTableColumns remembers the columns of database.table on db.
+func cacheTableColumns(db *sql.DB, database, table string, columns []ColumnInfo) {
+	schemaCache.Lock()
+	defer schemaCache.Unlock()
+	if schemaCache.columns[db] == nil {
+		schemaCache.columns[db] = make(map[string][]ColumnInfo)
+	}
+	schemaCache.columns[db][schemaCacheKey(database, table)] = columns
+}
+
+// invalidateTable drops the cached definition of database.table on every
+// pool.
+func invalidateTable(database, table string) {
+	schemaCache.Lock()
+	for _, tables := range schemaCache.columns {
+		delete(tables, schemaCacheKey(database, table))
+	}
+	schemaCache.Unlock()
+}
+
+// dropSchemaCache forgets the definitions cached for db, which is being
+// closed.
+func dropSchemaCache(db *sql.DB) {
+	schemaCache.Lock()
+	delete(schemaCache.columns, db)
+	schemaCache.Unlock()
+}
+
+// clearSchemaCache drops every cached definition.
+func clearSchemaCache() {
+	schemaCache.Lock()
+	schemaCache.columns = make(map[*sql.DB]map[string][]ColumnInfo)
+	schemaCache.Unlock()
+}
+
+// isDDL reports whether a statement may change table definitions.
+func isDDL(query string) bool {
+	upper := strings.ToUpper(strings.TrimSpace(query))
+	for _, prefix := range []string{"CREATE", "ALTER", "DROP", "RENAME", "TRUNCATE"} {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueKeys returns the PRIMARY and UNIQUE indexes of database.table on
+// db, mapping index name to its columns in index order.
+func uniqueKeys(ctx context.Context, db *sql.DB, database, table string) (map[string][]string, error) {
+	query := `
+		SELECT INDEX_NAME, COLUMN_NAME
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND NON_UNIQUE = 0
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX
+	`
+	rows, err := db.QueryContext(ctx, query, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make(map[string][]string)
+	for rows.Next() {
+		var index, column string
+		if err := rows.Scan(&index, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan index info: %w", err)
+		}
+		keys[index] = append(keys[index], column)
+	}
+	return keys, rows.Err()
+}
+
+// tableIndexes returns all indexes of database.table on db, unique or
+// not, mapping index name to its columns in index order. Expression parts
+// of functional indexes are left out.
+func tableIndexes(ctx context.Context, db *sql.DB, database, table string) (map[string][]string, error) {
+	query := `
+		SELECT INDEX_NAME, COLUMN_NAME
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME IS NOT NULL
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX
+	`
+	rows, err := db.QueryContext(ctx, query, database, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	indexes := make(map[string][]string)
+	for rows.Next() {
+		var index, column string
+		if err := rows.Scan(&index, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan index info: %w", err)
+		}
+		indexes[index] = append(indexes[index], column)
+	}
+	return indexes, rows.Err()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+)
+
+// columnServer answers information_schema.COLUMNS lookups with one int
+// column named column.
+func columnServer(column string) *fakeServer {
+	return &fakeServer{handle: func(ctx context.Context, query string, args []driver.NamedValue) (*fakeResult, error) {
+		if !strings.Contains(query, "information_schema.COLUMNS") {
+			return nil, nil
+		}
+		return &fakeResult{
+			columns: []string{"COLUMN_NAME", "DATA_TYPE", "IS_NULLABLE", "COLUMN_DEFAULT", "EXTRA"},
+			rows:    [][]driver.Value{{column, "int", "YES", nil, ""}},
+		}, nil
+	}}
+}
+
+func TestTableColumnsCachedPerPool(t *testing.T) {
+	ctx := context.Background()
+	oldServer, newServer := columnServer("old_id"), columnServer("new_id")
+	old := withFakeConnection(t, oldServer)
+	if _, err := tableColumns(ctx, old, "shop", "items"); err != nil {
+		t.Fatal(err)
+	}
+	// A tool call running on the old pool while connect replaces it.
+	_, release := holdDB()
+	current := withFakeConnection(t, newServer)
+	clearSchemaCache()
+
+	// A lookup on the replaced pool that finishes after connect cleared
+	// the cache does not answer for the new one.
+	if _, err := tableColumns(ctx, old, "shop", "items"); err != nil {
+		t.Fatal(err)
+	}
+	cols, err := tableColumns(ctx, current, "shop", "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cols) != 1 || cols[0].ColumnName != "new_id" {
+		t.Errorf("tableColumns() on the new pool = %+v, want new_id", cols)
+	}
+
+	// The old pool is closed when the call returns, and what is cached
+	// for it goes with it.
+	release()
+	schemaCache.Lock()
+	_, cached := schemaCache.columns[old]
+	schemaCache.Unlock()
+	if cached {
+		t.Error("the closed pool's columns are still cached")
+	}
+}
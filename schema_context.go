@@ -0,0 +1,291 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultSchemaContextTokens = 4000
+	// schemaContextURIPrefix starts the URIs of the schema context
+	// resources; the database name follows it.
+	schemaContextURIPrefix = "mysql://schema-context/"
+)
+
+// Detail levels of a schema context, from most to least verbose.
+const (
+	contextColumnTypes = iota
+	contextColumnNames
+	contextKeyColumns
+)
+
+// rowMagnitude renders an estimated row count roughly, since
+// information_schema's figures for InnoDB are only estimates.
+func rowMagnitude(n int64) string {
+	switch {
+	case n == 0:
+		return "empty"
+	case n < 1000:
+		return fmt.Sprintf("~%d", n)
+	}
+	value, unit := float64(n), ""
+	for _, u := range []string{"K", "M", "B"} {
+		// 999999 is ~1.0M, not ~1000K.
+		if value < 999.5 {
+			break
+		}
+		value, unit = value/1000, u
+	}
+	if value < 10 {
+		return fmt.Sprintf("~%.1f%s", value, unit)
+	}
+	return fmt.Sprintf("~%.0f%s", value, unit)
+}
+
+// schemaContextTable renders a table as one line at the given detail level:
+// its estimated rows, then its columns with * marking the primary key and
+// -> the table a foreign key references. Columns in used are kept at every
+// level.
+func schemaContextTable(t schemaTable, rows int64, level int, used map[string]time.Time) string {
+	var primary []string
+	indexed := make(map[string]bool)
+	for _, idx := range t.Indexes {
+		if idx.Name == "PRIMARY" {
+			primary = idx.Columns
+		}
+		indexed[idx.Columns[0]] = true
+	}
+	refs := make(map[string]string)
+	for _, fk := range t.ForeignKeys {
+		for i, c := range fk.Columns {
+			refs[c] = fk.RefTable + "." + fk.RefColumns[i]
+		}
+	}
+
+	var parts []string
+	omitted := 0
+	for _, c := range t.Columns {
+		key := slices.Contains(primary, c.Name) || refs[c.Name] != ""
+		if _, recent := used[c.Name]; level == contextKeyColumns && !key && !indexed[c.Name] && !recent {
+			omitted++
+			continue
+		}
+		part := c.Name
+		if slices.Contains(primary, c.Name) {
+			part = "*" + part
+		}
+		if level == contextColumnTypes {
+			typ := c.Type
+			// Long ENUM and SET value lists are cut short.
+			if open := strings.IndexByte(typ, '('); len(typ) > 40 && open > 0 {
+				typ = typ[:open] + "(...)"
+			}
+			part += " " + typ
+		}
+		if ref := refs[c.Name]; ref != "" {
+			part += "->" + ref
+		}
+		parts = append(parts, part)
+	}
+	if omitted > 0 {
+		parts = append(parts, fmt.Sprintf("+%d more", omitted))
+	}
+	return fmt.Sprintf("%s (%s): %s", t.Name, rowMagnitude(rows), strings.Join(parts, ", "))
+}
+
+// schemaContext summarizes a database for priming a model: a line per
+// table with its columns, keys, relationships and approximate size. Tables
+// this session used come first, most recent first, then the rest by size.
+// It drops column types, then non-key columns, then the tables at the end
+// until the summary fits within maxTokens, estimated at four characters
+// per token.
+func schemaContext(ctx context.Context, database string, maxTokens int) (string, error) {
+	db := currentDB()
+	schema, err := loadSchema(ctx, db, database)
+	if err != nil {
+		return "", err
+	}
+	rowCounts := make(map[string]int64)
+	err = queryEach(ctx, db, `
+		SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+	`, []any{database}, func(rows *sql.Rows) error {
+		var table string
+		var n int64
+		if err := rows.Scan(&table, &n); err != nil {
+			return err
+		}
+		rowCounts[table] = n
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// The tables in use and then the largest are kept when some must be
+	// left out.
+	recent := recentTables(database)
+	tables := slices.Clone(schema.Tables)
+	slices.SortStableFunc(tables, func(a, b schemaTable) int {
+		if c := recent[b.Name].Compare(recent[a.Name]); c != 0 {
+			return c
+		}
+		return cmp.Compare(rowCounts[b.Name], rowCounts[a.Name])
+	})
+	columns := make(map[string]map[string]time.Time)
+	for _, t := range tables {
+		if _, ok := recent[t.Name]; ok {
+			columns[t.Name] = recentColumns(database, t.Name)
+		}
+	}
+	var views []string
+	for _, v := range schema.Views {
+		views = append(views, v.Name)
+	}
+
+	budget := maxTokens * 4
+	header := fmt.Sprintf("Database %s: %d tables. * primary key, -> foreign key, ~ estimated rows.\n", database, len(tables))
+	if len(columns) > 0 {
+		header += fmt.Sprintf("The %d table(s) used this session come first.\n", len(columns))
+	}
+	for level := contextColumnTypes; level <= contextKeyColumns; level++ {
+		var b strings.Builder
+		b.WriteString(header)
+		for _, t := range tables {
+			b.WriteString(schemaContextTable(t, rowCounts[t.Name], level, columns[t.Name]) + "\n")
+		}
+		if len(views) > 0 {
+			b.WriteString("Views: " + strings.Join(views, ", ") + "\n")
+		}
+		if text := b.String(); len(text) <= budget {
+			return text, nil
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	kept := 0
+	for _, t := range tables {
+		line := schemaContextTable(t, rowCounts[t.Name], contextKeyColumns, columns[t.Name]) + "\n"
+		if b.Len()+len(line) > budget {
+			break
+		}
+		b.WriteString(line)
+		kept++
+	}
+	var rest []string
+	for _, t := range tables[kept:] {
+		rest = append(rest, t.Name)
+	}
+	rest = append(rest, views...)
+	if others := "Other tables and views: " + strings.Join(rest, ", ") + "\n"; b.Len()+len(others) <= budget {
+		b.WriteString(others)
+	} else if len(rest) > 0 {
+		fmt.Fprintf(&b, "...and %d more tables and views; raise max_tokens to list them.\n", len(rest))
+	}
+	return b.String(), nil
+}
+
+type SchemaContextParams struct {
+	Database  string `json:"database,omitempty"`
+	MaxTokens int    `json:"max_tokens,omitempty"`
+}
+
+func SchemaContext(ctx context.Context, req *mcp.CallToolRequest, args SchemaContextParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	database, err := contextDatabase(ctx, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	maxTokens := args.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = defaultSchemaContextTokens
+	}
+	text, err := schemaContext(ctx, database, maxTokens)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to summarize schema: %v", err)},
+			},
+		}, nil, nil
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: text},
+		},
+	}, map[string]any{
+		"database":        database,
+		"context":         text,
+		"estimatedTokens": (len(text) + 3) / 4,
+	}, nil
+}
+
+// contextDatabase returns database, or the connection's current database
+// when it is empty.
+func contextDatabase(ctx context.Context, database string) (string, error) {
+	db := currentDB()
+	if database != "" {
+		return database, nil
+	}
+	var current sql.NullString
+	if err := db.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&current); err != nil {
+		return "", err
+	}
+	if !current.Valid {
+		return "", fmt.Errorf("No database selected; give database or choose one with use_database")
+	}
+	return current.String, nil
+}
+
+// ReadSchemaContext serves the schema context resources: the current
+// database's at mysql://schema-context/ and any other's at
+// mysql://schema-context/{database}.
+func ReadSchemaContext(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	db := currentDB()
+	uri := req.Params.URI
+	name, ok := strings.CutPrefix(uri, schemaContextURIPrefix)
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	if db == nil {
+		return nil, fmt.Errorf("not connected to database; use the connect tool first")
+	}
+	database, err := url.PathUnescape(name)
+	if err != nil {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	if database, err = contextDatabase(ctx, database); err != nil {
+		return nil, err
+	}
+	text, err := schemaContext(ctx, database, defaultSchemaContextTokens)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{URI: uri, MIMEType: "text/plain", Text: text},
+		},
+	}, nil
+}
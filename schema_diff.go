@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type DiffSchemasParams struct {
+	SourceDatabase   string `json:"source_database"`
+	TargetDatabase   string `json:"target_database"`
+	SourceConnection string `json:"source_connection,omitempty"`
+	TargetConnection string `json:"target_connection,omitempty"`
+}
+
+// SchemaDifference is one object that differs between the source and target
+// schemas. Change is "missing" when the object exists only in the source,
+// "extra" when it exists only in the target and "changed" otherwise.
+type SchemaDifference struct {
+	Kind   string `json:"kind"`
+	Table  string `json:"table,omitempty"`
+	Name   string `json:"name"`
+	Change string `json:"change"`
+	Source string `json:"source,omitempty"`
+	Target string `json:"target,omitempty"`
+}
+
+func DiffSchemas(ctx context.Context, req *mcp.CallToolRequest, args DiffSchemasParams) (*mcp.CallToolResult, any, error) {
+	if args.SourceDatabase == "" || args.TargetDatabase == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "source_database and target_database are required"},
+			},
+		}, nil, nil
+	}
+
+	source, target, err := loadSchemaPair(ctx, args.SourceConnection, args.SourceDatabase, args.TargetConnection, args.TargetDatabase)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	differences := diffSchemas(source, target)
+	resultText := fmt.Sprintf("Schema diff: source %s vs target %s\n",
+		describeSchemaLocation(args.SourceConnection, args.SourceDatabase), describeSchemaLocation(args.TargetConnection, args.TargetDatabase))
+	resultText += formatSchemaDifferences(differences)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"source":      describeSchemaLocation(args.SourceConnection, args.SourceDatabase),
+		"target":      describeSchemaLocation(args.TargetConnection, args.TargetDatabase),
+		"identical":   len(differences) == 0,
+		"differences": differences,
+	}, nil
+}
+
+// loadSchemaPair loads the source and target schemas from their connections.
+func loadSchemaPair(ctx context.Context, sourceConnection, sourceDatabase, targetConnection, targetDatabase string) (*databaseSchema, *databaseSchema, error) {
+	sourceConn, err := connectionFor(sourceConnection)
+	if err != nil {
+		return nil, nil, errors.New(connectionHint(err))
+	}
+	targetConn, err := connectionFor(targetConnection)
+	if err != nil {
+		return nil, nil, errors.New(connectionHint(err))
+	}
+	source, err := loadSchema(ctx, sourceConn, sourceDatabase)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read source schema: %v", err)
+	}
+	target, err := loadSchema(ctx, targetConn, targetDatabase)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read target schema: %v", err)
+	}
+	return source, target, nil
+}
+
+func describeSchemaLocation(connection, database string) string {
+	if connection == "" {
+		connection = defaultConnectionName
+	}
+	return fmt.Sprintf("%s (%s)", quoteIdentifier(database), connection)
+}
+
+// formatSchemaDifferences renders differences grouped by table, or a note
+// that the schemas match.
+func formatSchemaDifferences(differences []SchemaDifference) string {
+	if len(differences) == 0 {
+		return "Schemas are identical.\n"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d difference(s):\n", len(differences))
+	table := ""
+	for _, d := range differences {
+		if d.Table != table {
+			table = d.Table
+			if table != "" {
+				fmt.Fprintf(&b, "Table %s:\n", quoteIdentifier(table))
+			}
+		}
+		indent := ""
+		if d.Table != "" {
+			indent = "  "
+		}
+		switch d.Change {
+		case "missing":
+			fmt.Fprintf(&b, "%s- %s %s missing in target: %s\n", indent, d.Kind, quoteIdentifier(d.Name), d.Source)
+		case "extra":
+			fmt.Fprintf(&b, "%s+ %s %s only in target: %s\n", indent, d.Kind, quoteIdentifier(d.Name), d.Target)
+		default:
+			fmt.Fprintf(&b, "%s~ %s %s differs\n%s    source: %s\n%s    target: %s\n", indent, d.Kind, quoteIdentifier(d.Name), indent, d.Source, indent, d.Target)
+		}
+	}
+	return b.String()
+}
+
+// diffSchemas compares target against source. Tables are compared object by
+// object; views, routines and triggers by definition.
+func diffSchemas(source, target *databaseSchema) []SchemaDifference {
+	var differences []SchemaDifference
+
+	sourceTables := make(map[string]schemaTable, len(source.Tables))
+	for _, t := range source.Tables {
+		sourceTables[t.Name] = t
+	}
+	targetTables := make(map[string]schemaTable, len(target.Tables))
+	for _, t := range target.Tables {
+		targetTables[t.Name] = t
+	}
+	for _, name := range unionKeys(sourceTables, targetTables) {
+		s, inSource := sourceTables[name]
+		t, inTarget := targetTables[name]
+		switch {
+		case !inTarget:
+			differences = append(differences, SchemaDifference{Kind: "table", Name: name, Change: "missing", Source: fmt.Sprintf("%d column(s)", len(s.Columns))})
+		case !inSource:
+			differences = append(differences, SchemaDifference{Kind: "table", Name: name, Change: "extra", Target: fmt.Sprintf("%d column(s)", len(t.Columns))})
+		default:
+			differences = append(differences, diffTables(source, target, s, t)...)
+		}
+	}
+
+	views := func(schema *databaseSchema) map[string]string {
+		m := make(map[string]string, len(schema.Views))
+		for _, v := range schema.Views {
+			m[v.Name] = normalizeDefinition(schema, v.Definition)
+		}
+		return m
+	}
+	differences = append(differences, diffDefinitions("view", "", views(source), views(target))...)
+
+	routines := func(schema *databaseSchema) map[string]string {
+		m := make(map[string]string, len(schema.Routines))
+		for _, r := range schema.Routines {
+			m[r.Type+" "+r.Name] = describeRoutine(schema, r)
+		}
+		return m
+	}
+	for _, d := range diffDefinitions("routine", "", routines(source), routines(target)) {
+		// Routine keys carry the type so a procedure and function of the
+		// same name are kept apart.
+		kind, name, _ := strings.Cut(d.Name, " ")
+		d.Kind, d.Name = strings.ToLower(kind), name
+		differences = append(differences, d)
+	}
+
+	triggers := func(schema *databaseSchema) map[string]string {
+		m := make(map[string]string, len(schema.Triggers))
+		for _, t := range schema.Triggers {
+			m[t.Name] = fmt.Sprintf("%s %s ON %s: %s", t.Timing, t.Event, quoteIdentifier(t.Table), normalizeDefinition(schema, t.Statement))
+		}
+		return m
+	}
+	differences = append(differences, diffDefinitions("trigger", "", triggers(source), triggers(target))...)
+
+	return differences
+}
+
+// diffTables compares two tables of the same name.
+func diffTables(sourceSchema, targetSchema *databaseSchema, source, target schemaTable) []SchemaDifference {
+	var differences []SchemaDifference
+	name := source.Name
+
+	options := func(t schemaTable) map[string]string {
+		return map[string]string{"engine": t.Engine, "collation": t.Collation, "comment": t.Comment}
+	}
+	sourceOptions, targetOptions := options(source), options(target)
+	for _, option := range sortedKeys(sourceOptions) {
+		if sourceOptions[option] != targetOptions[option] {
+			differences = append(differences, SchemaDifference{
+				Kind: "table option", Table: name, Name: option, Change: "changed",
+				Source: sourceOptions[option], Target: targetOptions[option],
+			})
+		}
+	}
+
+	columns := func(t schemaTable) map[string]string {
+		m := make(map[string]string, len(t.Columns))
+		for _, c := range t.Columns {
+			m[c.Name] = describeColumn(c)
+		}
+		return m
+	}
+	differences = append(differences, diffDefinitions("column", name, columns(source), columns(target))...)
+
+	// Column order only matters once the shared columns match.
+	if len(differences) == 0 {
+		sourceOrder := columnOrder(source, target)
+		targetOrder := columnOrder(target, source)
+		if strings.Join(sourceOrder, ",") != strings.Join(targetOrder, ",") {
+			differences = append(differences, SchemaDifference{
+				Kind: "column order", Table: name, Name: name, Change: "changed",
+				Source: strings.Join(sourceOrder, ", "), Target: strings.Join(targetOrder, ", "),
+			})
+		}
+	}
+
+	indexes := func(t schemaTable) map[string]string {
+		m := make(map[string]string, len(t.Indexes))
+		for _, i := range t.Indexes {
+			m[i.Name] = describeIndex(i)
+		}
+		return m
+	}
+	differences = append(differences, diffDefinitions("index", name, indexes(source), indexes(target))...)
+
+	foreignKeys := func(schema *databaseSchema, t schemaTable) map[string]string {
+		m := make(map[string]string, len(t.ForeignKeys))
+		for _, fk := range t.ForeignKeys {
+			m[fk.Name] = describeForeignKey(schema, fk)
+		}
+		return m
+	}
+	differences = append(differences, diffDefinitions("foreign key", name, foreignKeys(sourceSchema, source), foreignKeys(targetSchema, target))...)
+
+	checks := func(t schemaTable) map[string]string {
+		m := make(map[string]string, len(t.Checks))
+		for _, c := range t.Checks {
+			m[c.Name] = c.Clause
+		}
+		return m
+	}
+	differences = append(differences, diffDefinitions("check", name, checks(source), checks(target))...)
+
+	return differences
+}
+
+// diffDefinitions compares two sets of named objects by their description.
+func diffDefinitions(kind, table string, source, target map[string]string) []SchemaDifference {
+	var differences []SchemaDifference
+	for _, name := range unionKeys(source, target) {
+		s, inSource := source[name]
+		t, inTarget := target[name]
+		switch {
+		case !inTarget:
+			differences = append(differences, SchemaDifference{Kind: kind, Table: table, Name: name, Change: "missing", Source: s})
+		case !inSource:
+			differences = append(differences, SchemaDifference{Kind: kind, Table: table, Name: name, Change: "extra", Target: t})
+		case s != t:
+			differences = append(differences, SchemaDifference{Kind: kind, Table: table, Name: name, Change: "changed", Source: s, Target: t})
+		}
+	}
+	return differences
+}
+
+// unionKeys returns the keys present in either map, sorted.
+func unionKeys[V any](a, b map[string]V) []string {
+	merged := make(map[string]bool, len(a)+len(b))
+	for k := range a {
+		merged[k] = true
+	}
+	for k := range b {
+		merged[k] = true
+	}
+	return sortedKeys(merged)
+}
+
+// columnOrder lists the columns of t that other also has, in t's order.
+func columnOrder(t, other schemaTable) []string {
+	shared := make(map[string]bool, len(other.Columns))
+	for _, c := range other.Columns {
+		shared[c.Name] = true
+	}
+	var order []string
+	for _, c := range t.Columns {
+		if shared[c.Name] {
+			order = append(order, c.Name)
+		}
+	}
+	return order
+}
+
+func describeColumn(c schemaColumn) string {
+	parts := []string{c.Type}
+	if c.Nullable {
+		parts = append(parts, "NULL")
+	} else {
+		parts = append(parts, "NOT NULL")
+	}
+	if c.Default != nil {
+		parts = append(parts, "DEFAULT "+*c.Default)
+	}
+	if c.Extra != "" {
+		parts = append(parts, c.Extra)
+	}
+	if c.Generated != "" {
+		parts = append(parts, "AS ("+c.Generated+")")
+	}
+	if c.Collation != "" {
+		parts = append(parts, "COLLATE "+c.Collation)
+	}
+	if c.Comment != "" {
+		parts = append(parts, "COMMENT "+quoteString(c.Comment))
+	}
+	return strings.Join(parts, " ")
+}
+
+func describeIndex(i schemaIndex) string {
+	kind := "INDEX"
+	switch {
+	case i.Name == "PRIMARY":
+		kind = "PRIMARY KEY"
+	case i.Kind == "FULLTEXT" || i.Kind == "SPATIAL":
+		kind = i.Kind + " INDEX"
+	case i.Unique:
+		kind = "UNIQUE INDEX"
+	}
+	return fmt.Sprintf("%s (%s) %s", kind, strings.Join(i.Columns, ", "), i.Kind)
+}
+
+// describeForeignKey omits the referenced database when it is the table's
+// own, so the same key compares equal across differently named databases.
+func describeForeignKey(schema *databaseSchema, fk schemaForeignKey) string {
+	ref := quoteIdentifier(fk.RefTable)
+	if fk.RefDatabase != schema.Database {
+		ref = quoteIdentifier(fk.RefDatabase) + "." + ref
+	}
+	return fmt.Sprintf("(%s) REFERENCES %s (%s) ON UPDATE %s ON DELETE %s",
+		quoteIdentifierList(fk.Columns), ref, quoteIdentifierList(fk.RefColumns), fk.OnUpdate, fk.OnDelete)
+}
+
+func describeRoutine(schema *databaseSchema, r schemaRoutine) string {
+	s := fmt.Sprintf("(%s)", r.Parameters)
+	if r.Type == "FUNCTION" {
+		s += " RETURNS " + r.Returns
+	}
+	return s + " " + r.Characteristics + " " + normalizeDefinition(schema, r.Definition)
+}
+
+// normalizeDefinition removes qualifications with the schema's own name and
+// surrounding whitespace, which otherwise make identical views and routines
+// in differently named databases compare as different.
+func normalizeDefinition(schema *databaseSchema, definition string) string {
+	definition = strings.ReplaceAll(definition, quoteIdentifier(schema.Database)+".", "")
+	return strings.TrimSpace(definition)
+}
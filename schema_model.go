@@ -0,0 +1,345 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// databaseSchema is the full structure of one database, as compared by
+// diff_schemas and stored in schema snapshots.
+type databaseSchema struct {
+	Database string          `json:"database"`
+	Tables   []schemaTable   `json:"tables"`
+	Views    []schemaView    `json:"views,omitempty"`
+	Routines []schemaRoutine `json:"routines,omitempty"`
+	Triggers []schemaTrigger `json:"triggers,omitempty"`
+}
+
+type schemaTable struct {
+	Name        string             `json:"name"`
+	Engine      string             `json:"engine,omitempty"`
+	Collation   string             `json:"collation,omitempty"`
+	Comment     string             `json:"comment,omitempty"`
+	Columns     []schemaColumn     `json:"columns"`
+	Indexes     []schemaIndex      `json:"indexes,omitempty"`
+	ForeignKeys []schemaForeignKey `json:"foreignKeys,omitempty"`
+	Checks      []schemaCheck      `json:"checks,omitempty"`
+}
+
+type schemaColumn struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Nullable  bool    `json:"nullable"`
+	Default   *string `json:"default,omitempty"`
+	Extra     string  `json:"extra,omitempty"`
+	Generated string  `json:"generated,omitempty"`
+	Collation string  `json:"collation,omitempty"`
+	Comment   string  `json:"comment,omitempty"`
+}
+
+type schemaIndex struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Kind    string   `json:"kind"`
+	Columns []string `json:"columns"`
+}
+
+type schemaForeignKey struct {
+	Name        string   `json:"name"`
+	Columns     []string `json:"columns"`
+	RefDatabase string   `json:"refDatabase"`
+	RefTable    string   `json:"refTable"`
+	RefColumns  []string `json:"refColumns"`
+	OnUpdate    string   `json:"onUpdate"`
+	OnDelete    string   `json:"onDelete"`
+}
+
+type schemaCheck struct {
+	Name   string `json:"name"`
+	Clause string `json:"clause"`
+}
+
+type schemaView struct {
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+type schemaRoutine struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Parameters string `json:"parameters,omitempty"`
+	Returns    string `json:"returns,omitempty"`
+	Definition string `json:"definition"`
+	// Characteristics holds DETERMINISTIC, SQL data access and SQL
+	// SECURITY as they would appear in CREATE PROCEDURE or FUNCTION.
+	Characteristics string `json:"characteristics,omitempty"`
+}
+
+type schemaTrigger struct {
+	Name      string `json:"name"`
+	Table     string `json:"table"`
+	Timing    string `json:"timing"`
+	Event     string `json:"event"`
+	Statement string `json:"statement"`
+}
+
+// loadSchema reads the structure of database over conn from
+// information_schema.
+func loadSchema(ctx context.Context, conn *sql.DB, database string) (*databaseSchema, error) {
+	schema := &databaseSchema{Database: database}
+	tables := make(map[string]*schemaTable)
+
+	var exists int
+	if err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", database).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		return nil, fmt.Errorf("database %s does not exist", database)
+	}
+
+	err := queryEach(ctx, conn, `
+		SELECT TABLE_NAME, TABLE_TYPE, COALESCE(ENGINE, ''), COALESCE(TABLE_COLLATION, ''), COALESCE(TABLE_COMMENT, '')
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME
+	`, []any{database}, func(rows *sql.Rows) error {
+		var t schemaTable
+		var tableType string
+		if err := rows.Scan(&t.Name, &tableType, &t.Engine, &t.Collation, &t.Comment); err != nil {
+			return err
+		}
+		if tableType == "BASE TABLE" {
+			schema.Tables = append(schema.Tables, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range schema.Tables {
+		tables[schema.Tables[i].Name] = &schema.Tables[i]
+	}
+
+	mariaDB := serverInfoFor(ctx, conn).MariaDB
+	err = queryEach(ctx, conn, `
+		SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA,
+		       COALESCE(GENERATION_EXPRESSION, ''), COALESCE(COLLATION_NAME, ''), COLUMN_COMMENT
+		FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME, ORDINAL_POSITION
+	`, []any{database}, func(rows *sql.Rows) error {
+		var table, nullable string
+		var c schemaColumn
+		var def sql.NullString
+		if err := rows.Scan(&table, &c.Name, &c.Type, &nullable, &def, &c.Extra, &c.Generated, &c.Collation, &c.Comment); err != nil {
+			return err
+		}
+		c.Nullable = nullable == "YES"
+		if def.Valid && mariaDB && c.Generated == "" {
+			// Store defaults as MySQL reports them, so snapshots and
+			// migrations compare across the two.
+			var expression bool
+			c.Default, expression = normalizeMariaDBDefault(def.String)
+			if expression && !strings.Contains(c.Extra, "DEFAULT_GENERATED") {
+				c.Extra = strings.TrimSpace("DEFAULT_GENERATED " + c.Extra)
+			}
+		} else if def.Valid {
+			c.Default = &def.String
+		}
+		if t, ok := tables[table]; ok {
+			t.Columns = append(t.Columns, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(ctx, conn, `
+		SELECT TABLE_NAME, INDEX_NAME, NON_UNIQUE, INDEX_TYPE, COLUMN_NAME, SUB_PART
+		FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = ?
+		ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX
+	`, []any{database}, func(rows *sql.Rows) error {
+		var table, name, kind string
+		var column sql.NullString
+		var nonUnique int
+		var subPart sql.NullInt64
+		if err := rows.Scan(&table, &name, &nonUnique, &kind, &column, &subPart); err != nil {
+			return err
+		}
+		t, ok := tables[table]
+		if !ok {
+			return nil
+		}
+		// Functional index parts have no column name.
+		col := column.String
+		if !column.Valid {
+			col = "(expression)"
+		}
+		if subPart.Valid {
+			col = fmt.Sprintf("%s(%d)", col, subPart.Int64)
+		}
+		if n := len(t.Indexes); n > 0 && t.Indexes[n-1].Name == name {
+			t.Indexes[n-1].Columns = append(t.Indexes[n-1].Columns, col)
+			return nil
+		}
+		t.Indexes = append(t.Indexes, schemaIndex{Name: name, Unique: nonUnique == 0, Kind: kind, Columns: []string{col}})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(ctx, conn, `
+		SELECT k.TABLE_NAME, k.CONSTRAINT_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_SCHEMA,
+		       k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.UPDATE_RULE, r.DELETE_RULE
+		FROM information_schema.KEY_COLUMN_USAGE k
+		JOIN information_schema.REFERENTIAL_CONSTRAINTS r
+		  ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME AND r.TABLE_NAME = k.TABLE_NAME
+		WHERE k.TABLE_SCHEMA = ? AND k.REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION
+	`, []any{database}, func(rows *sql.Rows) error {
+		var table, name, column, refDatabase, refTable, refColumn, onUpdate, onDelete string
+		if err := rows.Scan(&table, &name, &column, &refDatabase, &refTable, &refColumn, &onUpdate, &onDelete); err != nil {
+			return err
+		}
+		t, ok := tables[table]
+		if !ok {
+			return nil
+		}
+		if n := len(t.ForeignKeys); n > 0 && t.ForeignKeys[n-1].Name == name {
+			fk := &t.ForeignKeys[n-1]
+			fk.Columns = append(fk.Columns, column)
+			fk.RefColumns = append(fk.RefColumns, refColumn)
+			return nil
+		}
+		t.ForeignKeys = append(t.ForeignKeys, schemaForeignKey{
+			Name: name, Columns: []string{column}, RefDatabase: refDatabase, RefTable: refTable,
+			RefColumns: []string{refColumn}, OnUpdate: onUpdate, OnDelete: onDelete,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// CHECK constraints exist from MySQL 8.0.16 and MariaDB 10.3.10; older
+	// servers have no CHECK_CONSTRAINTS table and simply report none.
+	queryEach(ctx, conn, `
+		SELECT t.TABLE_NAME, c.CONSTRAINT_NAME, c.CHECK_CLAUSE
+		FROM information_schema.CHECK_CONSTRAINTS c
+		JOIN information_schema.TABLE_CONSTRAINTS t
+		  ON t.CONSTRAINT_SCHEMA = c.CONSTRAINT_SCHEMA AND t.CONSTRAINT_NAME = c.CONSTRAINT_NAME
+		WHERE c.CONSTRAINT_SCHEMA = ? AND t.CONSTRAINT_TYPE = 'CHECK'
+		ORDER BY t.TABLE_NAME, c.CONSTRAINT_NAME
+	`, []any{database}, func(rows *sql.Rows) error {
+		var table string
+		var c schemaCheck
+		if err := rows.Scan(&table, &c.Name, &c.Clause); err != nil {
+			return err
+		}
+		if t, ok := tables[table]; ok {
+			t.Checks = append(t.Checks, c)
+		}
+		return nil
+	})
+
+	err = queryEach(ctx, conn, `
+		SELECT TABLE_NAME, COALESCE(VIEW_DEFINITION, '')
+		FROM information_schema.VIEWS WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME
+	`, []any{database}, func(rows *sql.Rows) error {
+		var v schemaView
+		if err := rows.Scan(&v.Name, &v.Definition); err != nil {
+			return err
+		}
+		schema.Views = append(schema.Views, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	routines := make(map[string]*schemaRoutine)
+	err = queryEach(ctx, conn, `
+		SELECT ROUTINE_NAME, ROUTINE_TYPE, COALESCE(DTD_IDENTIFIER, ''), COALESCE(ROUTINE_DEFINITION, ''),
+		       IS_DETERMINISTIC, SQL_DATA_ACCESS, SECURITY_TYPE
+		FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ? ORDER BY ROUTINE_TYPE, ROUTINE_NAME
+	`, []any{database}, func(rows *sql.Rows) error {
+		var r schemaRoutine
+		var deterministic, dataAccess, security string
+		if err := rows.Scan(&r.Name, &r.Type, &r.Returns, &r.Definition, &deterministic, &dataAccess, &security); err != nil {
+			return err
+		}
+		if deterministic != "YES" {
+			r.Characteristics = "NOT "
+		}
+		r.Characteristics += "DETERMINISTIC " + dataAccess + " SQL SECURITY " + security
+		schema.Routines = append(schema.Routines, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range schema.Routines {
+		routines[schema.Routines[i].Type+" "+schema.Routines[i].Name] = &schema.Routines[i]
+	}
+	err = queryEach(ctx, conn, `
+		SELECT SPECIFIC_NAME, ROUTINE_TYPE, COALESCE(PARAMETER_MODE, ''), PARAMETER_NAME, DTD_IDENTIFIER
+		FROM information_schema.PARAMETERS
+		WHERE SPECIFIC_SCHEMA = ? AND ORDINAL_POSITION > 0
+		ORDER BY SPECIFIC_NAME, ORDINAL_POSITION
+	`, []any{database}, func(rows *sql.Rows) error {
+		var name, routineType, mode, param, dtd string
+		if err := rows.Scan(&name, &routineType, &mode, &param, &dtd); err != nil {
+			return err
+		}
+		r, ok := routines[routineType+" "+name]
+		if !ok {
+			return nil
+		}
+		if r.Parameters != "" {
+			r.Parameters += ", "
+		}
+		r.Parameters += strings.TrimSpace(mode + " " + quoteIdentifier(param) + " " + dtd)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = queryEach(ctx, conn, `
+		SELECT TRIGGER_NAME, EVENT_OBJECT_TABLE, ACTION_TIMING, EVENT_MANIPULATION, ACTION_STATEMENT
+		FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = ? ORDER BY TRIGGER_NAME
+	`, []any{database}, func(rows *sql.Rows) error {
+		var t schemaTrigger
+		if err := rows.Scan(&t.Name, &t.Table, &t.Timing, &t.Event, &t.Statement); err != nil {
+			return err
+		}
+		schema.Triggers = append(schema.Triggers, t)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range schema.Tables {
+		sort.Slice(schema.Tables[i].Indexes, func(a, b int) bool {
+			return schema.Tables[i].Indexes[a].Name < schema.Tables[i].Indexes[b].Name
+		})
+	}
+	return schema, nil
+}
+
+// queryEach runs query and calls fn for every row.
+func queryEach(ctx context.Context, conn *sql.DB, query string, params []any, fn func(*sql.Rows) error) error {
+	rows, err := conn.QueryContext(ctx, query, params...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// seedManifestTable records which seed sources have been loaded into a
+// database, so load_seed only applies new or changed files.
+const seedManifestTable = "seed_manifest"
+
+// seedEnvironment is the seed data configured for one environment.
+type seedEnvironment struct {
+	Database string       `json:"database"`
+	Sources  []seedSource `json:"sources"`
+}
+
+// seedSource is a .sql script or a .csv file with a header row. CSV rows
+// are upserted into Table, which defaults to the file name without its
+// extension.
+type seedSource struct {
+	Path  string `json:"path"`
+	Table string `json:"table,omitempty"`
+}
+
+type LoadSeedParams struct {
+	Environment string `json:"environment"`
+	Force       bool   `json:"force,omitempty"`
+	DryRun      bool   `json:"dry_run,omitempty"`
+}
+
+// SeedResult reports what happened to one seed source.
+type SeedResult struct {
+	Source   string `json:"source"`
+	Checksum string `json:"checksum"`
+	Status   string `json:"status"`
+	Rows     int64  `json:"rows"`
+	Error    string `json:"error,omitempty"`
+}
+
+func LoadSeed(ctx context.Context, req *mcp.CallToolRequest, args LoadSeedParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("load_seed"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	env, ok := config.Seeds[args.Environment]
+	if !ok {
+		msg := fmt.Sprintf("No seed environment %q in the config file", args.Environment)
+		if len(config.Seeds) > 0 {
+			msg += fmt.Sprintf(". Available: %s", strings.Join(sortedKeys(config.Seeds), ", "))
+		}
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: msg},
+			},
+		}, nil, nil
+	}
+
+	manifest, err := seedManifest(ctx, env.Database, args.Environment, !args.DryRun)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read seed manifest: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	results := make([]SeedResult, 0, len(env.Sources))
+	failed := false
+	for _, source := range env.Sources {
+		path := config.configPath(source.Path)
+		result := SeedResult{Source: source.Path}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			result.Status, result.Error, failed = "failed", err.Error(), true
+			results = append(results, result)
+			break
+		}
+		sum := sha256.Sum256(data)
+		result.Checksum = hex.EncodeToString(sum[:])
+
+		if manifest[source.Path] == result.Checksum && !args.Force {
+			result.Status = "unchanged"
+			results = append(results, result)
+			continue
+		}
+		if args.DryRun {
+			result.Status = "pending"
+			if _, loaded := manifest[source.Path]; loaded {
+				result.Status = "changed"
+			}
+			results = append(results, result)
+			continue
+		}
+
+		result.Rows, err = loadSeedSource(ctx, db, env.Database, source, path, data)
+		if err == nil {
+			err = recordSeed(ctx, env.Database, args.Environment, source.Path, result.Checksum, result.Rows)
+		}
+		if err != nil {
+			// Later sources may depend on this one, so stop here.
+			result.Status, result.Error, failed = "failed", err.Error(), true
+			results = append(results, result)
+			break
+		}
+		result.Status = "loaded"
+		results = append(results, result)
+	}
+
+	resultText := fmt.Sprintf("Seed environment %s into %s:\n", args.Environment, quoteIdentifier(env.Database))
+	if args.DryRun {
+		resultText = fmt.Sprintf("Dry run of seed environment %s into %s (nothing loaded):\n", args.Environment, quoteIdentifier(env.Database))
+	}
+	for _, r := range results {
+		resultText += fmt.Sprintf("  %-9s %s", r.Status, r.Source)
+		if r.Status == "loaded" {
+			resultText += fmt.Sprintf(" (%d rows)", r.Rows)
+		}
+		if r.Error != "" {
+			resultText += ": " + r.Error
+		}
+		resultText += "\n"
+	}
+	if failed && len(results) < len(env.Sources) {
+		resultText += fmt.Sprintf("%d later source(s) were not attempted.\n", len(env.Sources)-len(results))
+	}
+
+	return &mcp.CallToolResult{
+		IsError: failed,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"environment": args.Environment,
+		"database":    env.Database,
+		"dryRun":      args.DryRun,
+		"sources":     results,
+	}, nil
+}
+
+// seedManifest returns the checksum of each source already loaded for
+// environment. The manifest table is created if create is set; otherwise a
+// missing table means nothing was loaded yet.
+func seedManifest(ctx context.Context, database, environment string, create bool) (map[string]string, error) {
+	db := currentDB()
+	manifest := make(map[string]string)
+	if !create {
+		exists, err := tableExists(ctx, database, seedManifestTable)
+		if err != nil || !exists {
+			return manifest, err
+		}
+	}
+	if create {
+		_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			environment VARCHAR(64) NOT NULL,
+			source VARCHAR(512) NOT NULL,
+			checksum CHAR(64) NOT NULL,
+			rows_loaded BIGINT NOT NULL,
+			loaded_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (environment, source)
+		)`, qualifiedTable(database, seedManifestTable)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT source, checksum FROM %s WHERE environment = ?", qualifiedTable(database, seedManifestTable)), environment)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var source, checksum string
+		if err := rows.Scan(&source, &checksum); err != nil {
+			return nil, err
+		}
+		manifest[source] = checksum
+	}
+	return manifest, rows.Err()
+}
+
+func recordSeed(ctx context.Context, database, environment, source, checksum string, rowsLoaded int64) error {
+	db := currentDB()
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (environment, source, checksum, rows_loaded) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE checksum = VALUES(checksum), rows_loaded = VALUES(rows_loaded), loaded_at = CURRENT_TIMESTAMP`,
+		qualifiedTable(database, seedManifestTable)), environment, source, checksum, rowsLoaded)
+	return err
+}
+
+// loadSeedSource applies one source and returns the number of rows it
+// affected.
+func loadSeedSource(ctx context.Context, db *sql.DB, database string, source seedSource, path string, data []byte) (int64, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".sql":
+		return runSeedScript(ctx, database, string(data))
+	case ".csv":
+		table := source.Table
+		if table == "" {
+			table = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		return loadSeedCSV(ctx, db, database, table, string(data))
+	}
+	return 0, fmt.Errorf("unsupported seed file type %q (use .sql or .csv)", filepath.Ext(path))
+}
+
+// runSeedScript runs a SQL script with database as the default database.
+// Scripts are expected to be idempotent themselves (INSERT IGNORE, ON
+// DUPLICATE KEY UPDATE); the manifest only keeps unchanged files from
+// running again.
+func runSeedScript(ctx context.Context, database, script string) (int64, error) {
+	db := currentDB()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer discardConn(conn)
+	if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(database)); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, stmt := range splitSQLStatements(script) {
+		res, err := conn.ExecContext(ctx, stmt)
+		var n int64
+		if err == nil {
+			n, _ = res.RowsAffected()
+			total += n
+		}
+		audit("load_seed", stmt, n, err)
+		if err != nil {
+			return total, err
+		}
+	}
+	clearSchemaCache()
+	return total, nil
+}
+
+// loadSeedCSV upserts the rows of a CSV file so loading it again leaves the
+// table unchanged. Empty fields and \N load as NULL.
+func loadSeedCSV(ctx context.Context, db *sql.DB, database, table, data string) (int64, error) {
+	reader := csv.NewReader(strings.NewReader(data))
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+	var input []map[string]any
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		row := make(map[string]any, len(header))
+		for i, name := range header {
+			if record[i] == "" || record[i] == `\N` {
+				row[name] = nil
+				continue
+			}
+			row[name] = record[i]
+		}
+		input = append(input, row)
+	}
+	if len(input) == 0 {
+		return 0, nil
+	}
+
+	result, err := writeRows(ctx, db, database, table, input, rowWriteOptions{
+		Tool:   "load_seed",
+		Atomic: true,
+		Suffix: func(columns []string) string {
+			updates := make([]string, len(columns))
+			for i, col := range columns {
+				updates[i] = fmt.Sprintf("%s = VALUES(%s)", quoteIdentifier(col), quoteIdentifier(col))
+			}
+			return "ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(result.Errors) > 0 {
+		// Row numbers are zero-based; the header is line 1.
+		e := result.Errors[0]
+		return 0, fmt.Errorf("%s line %d: %s", table, e.Row+2, e.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// releasesURL is the GitHub API endpoint for this project's releases.
+const releasesURL = "https://api.github.com/repos/josiah-hester/mysql-mcp/releases"
+
+// updateChannel selects which releases -update considers latest, set with
+// -update-channel: "stable" skips prereleases, "prerelease" includes them.
+var updateChannel = "stable"
+
+// allowUnsignedUpdate lets -update install a release without a checksums
+// signature, such as one published before releases were signed, verifying
+// only its SHA-256 checksum. Set with -allow-unsigned.
+var allowUnsignedUpdate bool
+
+// githubClient makes the updater's requests. Its transport takes
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY from the environment.
+var githubClient = &http.Client{
+	Timeout:   5 * time.Minute,
+	Transport: &http.Transport{Proxy: http.ProxyFromEnvironment},
+}
+
+func validateUpdateChannel() error {
+	switch updateChannel {
+	case "stable", "prerelease":
+		return nil
+	}
+	return fmt.Errorf("invalid -update-channel %q (use stable or prerelease)", updateChannel)
+}
+
+type GitHubRelease struct {
+	TagName     string `json:"tag_name"`
+	PublishedAt string `json:"published_at"`
+	Prerelease  bool   `json:"prerelease"`
+	Draft       bool   `json:"draft"`
+	Assets      []struct {
+		Name               string `json:"name"`
+		BrowserDownloadURL string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// updateTarget is the value of -update: empty when not updating, "latest",
+// or the tag of the release to install. It is a boolean flag, so a bare
+// -update means the latest release and -update=v1.2.3 pins a version.
+type updateTarget string
+
+func (t *updateTarget) String() string { return string(*t) }
+
+func (t *updateTarget) IsBoolFlag() bool { return true }
+
+func (t *updateTarget) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "true", "latest":
+		*t = "latest"
+	case "false", "":
+		*t = ""
+	default:
+		if !strings.HasPrefix(value, "v") {
+			value = "v" + value
+		}
+		*t = updateTarget(value)
+	}
+	return nil
+}
+
+// updateSelf replaces the running binary with the release tagged target,
+// or with the latest release when target is "latest". Installing an older
+// tag is how a broken release is rolled back.
+func updateSelf(target string) error {
+	if releasePublicKey == "" && !allowUnsignedUpdate {
+		return fmt.Errorf("this build has no release signing key, so updates cannot be verified; install a release build, update manually or pass -allow-unsigned to check checksums only")
+	}
+
+	var release GitHubRelease
+	if target == "latest" {
+		fmt.Printf("Checking for updates (%s channel)...\n", updateChannel)
+		var err error
+		if release, err = latestRelease(context.Background()); err != nil {
+			return fmt.Errorf("failed to check for updates: %w", err)
+		}
+		if release.TagName == "v"+version {
+			fmt.Printf("Already up to date (version %s)\n", version)
+			return nil
+		}
+		fmt.Printf("Found newer version: %s (current: %s)\n", release.TagName, version)
+	} else {
+		if err := getRelease(context.Background(), releasesURL+"/tags/"+url.PathEscape(target), &release); err != nil {
+			return fmt.Errorf("failed to get release %s: %w", target, err)
+		}
+		if release.TagName == "v"+version {
+			fmt.Printf("Version %s is already installed\n", version)
+			return nil
+		}
+		fmt.Printf("Installing %s (current: %s)\n", release.TagName, version)
+	}
+
+	// Find the correct asset for current OS and architecture
+	osName := runtime.GOOS
+	if osName == "darwin" {
+		osName = "Darwin"
+	} else if osName == "linux" {
+		osName = "Linux"
+	} else if osName == "windows" {
+		osName = "Windows"
+	}
+
+	archName := runtime.GOARCH
+	if archName == "amd64" {
+		archName = "x86_64"
+	}
+
+	var downloadURL, assetName string
+	expectedName := fmt.Sprintf("mysql-mcp_%s_%s", osName, archName)
+	for _, asset := range release.Assets {
+		if strings.Contains(asset.Name, expectedName) {
+			downloadURL = asset.BrowserDownloadURL
+			assetName = asset.Name
+			break
+		}
+	}
+	checksumsURL, checksumsName, signatureURL := releaseChecksumAssets(release)
+
+	if downloadURL == "" {
+		return fmt.Errorf("no compatible release found for %s %s", osName, archName)
+	}
+	if checksumsURL == "" {
+		return fmt.Errorf("release %s has no checksums file; refusing to install an unverified binary", release.TagName)
+	}
+	verifySignature := signatureURL != "" && releasePublicKey != ""
+	if !verifySignature && !allowUnsignedUpdate {
+		return fmt.Errorf("release %s has no signature for %s; refusing to install an unverified binary (pass -allow-unsigned to check its checksum only)", release.TagName, checksumsName)
+	}
+
+	fmt.Printf("Downloading %s...\n", downloadURL)
+
+	// Download the release and verify it against the published checksum
+	archive, err := downloadAsset(downloadURL)
+	if err != nil {
+		return fmt.Errorf("failed to download update: %w", err)
+	}
+	checksums, err := downloadAsset(checksumsURL)
+	if err != nil {
+		return fmt.Errorf("failed to download checksums: %w", err)
+	}
+	if verifySignature {
+		signature, err := downloadAsset(signatureURL)
+		if err != nil {
+			return fmt.Errorf("failed to download checksums signature: %w", err)
+		}
+		if err := verifyReleaseSignature(checksums, signature, release.TagName); err != nil {
+			return fmt.Errorf("signature verification of %s failed: %w; update aborted", checksumsName, err)
+		}
+		fmt.Printf("Verified signature of %s\n", checksumsName)
+	} else {
+		fmt.Printf("Warning: not verifying a signature of %s (-allow-unsigned); only its SHA-256 checksum is checked\n", checksumsName)
+	}
+	expected, err := releaseChecksum(checksums, assetName)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(archive)
+	if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("checksum mismatch for %s: expected %s, got %s; the download is corrupt or has been tampered with, update aborted", assetName, expected, actual)
+	}
+	fmt.Printf("Verified SHA-256 checksum of %s\n", assetName)
+
+	// Get current executable path
+	currentExe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get current executable path: %w", err)
+	}
+
+	// Create temporary file for the new binary
+	tempFile := currentExe + ".new"
+
+	// Extract and save the new binary; Windows releases are zip archives
+	if strings.HasSuffix(assetName, ".zip") {
+		err = extractZipBinary(archive, tempFile)
+	} else {
+		err = extractBinary(bytes.NewReader(archive), tempFile)
+	}
+	if err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to extract update: %w", err)
+	}
+
+	// Make the new binary executable
+	if err := os.Chmod(tempFile, 0755); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to make new binary executable: %w", err)
+	}
+
+	// Replace the current binary
+	if err := replaceExecutable(tempFile, currentExe); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("failed to replace binary: %w", err)
+	}
+
+	fmt.Printf("Successfully updated to version %s\n", release.TagName)
+	fmt.Println("Please restart the application to use the new version.")
+
+	return nil
+}
+
+// releaseChecksumAssets returns the download URL and name of a release's
+// checksums file and the URL of its minisign signature, each empty when
+// the release does not have it. Releases published before signing was
+// added have no signature.
+func releaseChecksumAssets(release GitHubRelease) (checksumsURL, checksumsName, signatureURL string) {
+	for _, asset := range release.Assets {
+		if strings.HasSuffix(asset.Name, "checksums.txt") {
+			checksumsURL, checksumsName = asset.BrowserDownloadURL, asset.Name
+		}
+	}
+	for _, asset := range release.Assets {
+		if checksumsName != "" && asset.Name == checksumsName+".minisig" {
+			signatureURL = asset.BrowserDownloadURL
+		}
+	}
+	return checksumsURL, checksumsName, signatureURL
+}
+
+// latestRelease returns the newest release on updateChannel.
+func latestRelease(ctx context.Context) (GitHubRelease, error) {
+	var release GitHubRelease
+	if updateChannel == "stable" {
+		// GitHub's latest release is never a prerelease or a draft.
+		err := getRelease(ctx, releasesURL+"/latest", &release)
+		return release, err
+	}
+	var releases []GitHubRelease
+	if err := getRelease(ctx, releasesURL+"?per_page=30", &releases); err != nil {
+		return release, err
+	}
+	for _, r := range releases {
+		if !r.Draft {
+			return r, nil
+		}
+	}
+	return release, fmt.Errorf("no releases published")
+}
+
+// githubGet sends a GET request to GitHub. A token in GITHUB_TOKEN or
+// GH_TOKEN is sent with it, which raises the API rate limit and allows
+// access through proxies that require authenticated requests.
+func githubGet(ctx context.Context, endpoint string) (*http.Response, error) {
+	if offline {
+		return nil, errOffline
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "mysql-mcp/"+version)
+	if strings.HasPrefix(endpoint, "https://api.github.com/") {
+		req.Header.Set("Accept", "application/vnd.github+json")
+	}
+	if token := cmp.Or(os.Getenv("GITHUB_TOKEN"), os.Getenv("GH_TOKEN")); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := githubClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) && resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		resp.Body.Close()
+		return nil, fmt.Errorf("GitHub API rate limit exceeded; set GITHUB_TOKEN to a GitHub token to raise it")
+	}
+	return resp, nil
+}
+
+// getRelease decodes the GitHub API response at endpoint into v.
+func getRelease(ctx context.Context, endpoint string, v any) error {
+	resp, err := githubGet(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("release not found (see -list-releases)")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get release info: HTTP %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse release info: %w", err)
+	}
+	return nil
+}
+
+// listReleases prints the most recent releases, marking the installed one.
+func listReleases() error {
+	var releases []GitHubRelease
+	if err := getRelease(context.Background(), releasesURL+"?per_page=30", &releases); err != nil {
+		return err
+	}
+	for _, r := range releases {
+		if r.Draft {
+			continue
+		}
+		line := r.TagName
+		if published, err := time.Parse(time.RFC3339, r.PublishedAt); err == nil {
+			line += "  " + published.Format("2006-01-02")
+		}
+		if r.Prerelease {
+			line += "  (prerelease)"
+		}
+		if r.TagName == "v"+version {
+			line += "  (installed)"
+		}
+		switch checksums, _, signature := releaseChecksumAssets(r); {
+		case checksums == "":
+			line += "  (no checksums, cannot be installed)"
+		case signature == "":
+			line += "  (unsigned, needs -allow-unsigned)"
+		}
+		fmt.Println(line)
+	}
+	fmt.Println("Install one with -update=<tag>.")
+	return nil
+}
+
+// replaceExecutable moves the new binary at src over the running one at
+// dst. Windows will not overwrite or delete a running executable but will
+// rename it, so there the old binary is first moved aside to dst+".old",
+// which removeOldExecutable deletes on the next start.
+func replaceExecutable(src, dst string) error {
+	if runtime.GOOS != "windows" {
+		return os.Rename(src, dst)
+	}
+	old := dst + ".old"
+	// Left over from an update whose process was still running at the last
+	// start; failing here just means it is still locked.
+	os.Remove(old)
+	if err := os.Rename(dst, old); err != nil {
+		return fmt.Errorf("failed to move running binary aside: %w", err)
+	}
+	if err := os.Rename(src, dst); err != nil {
+		if restoreErr := os.Rename(old, dst); restoreErr != nil {
+			return fmt.Errorf("%w (and restoring the old binary from %s failed: %v)", err, old, restoreErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// removeOldExecutable deletes the binary a Windows update moved aside.
+func removeOldExecutable() {
+	currentExe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	old := currentExe + ".old"
+	if err := os.Remove(old); err != nil && !os.IsNotExist(err) {
+		slog.Debug("failed to remove old binary", "path", old, "err", err)
+	}
+}
+
+// verifyReleaseSignature checks the minisign signature of a release's
+// checksums file against releasePublicKey. The signature's trusted comment
+// names the release tag, so the signed checksums of an older release cannot
+// be passed off as a newer one.
+func verifyReleaseSignature(checksums, signature []byte, tag string) error {
+	pk, err := parseMinisignPublicKey(releasePublicKey)
+	if err != nil {
+		return err
+	}
+	sig, err := parseMinisignSignature(signature)
+	if err != nil {
+		return err
+	}
+	if err := pk.verify(checksums, sig); err != nil {
+		return err
+	}
+	if sig.trustedComment != "mysql-mcp "+tag {
+		return fmt.Errorf("signature is for %q, not release %s", sig.trustedComment, tag)
+	}
+	return nil
+}
+
+// downloadAsset fetches a release asset into memory.
+func downloadAsset(url string) ([]byte, error) {
+	resp, err := githubGet(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// releaseChecksum finds the SHA-256 of name in a checksums file of
+// "<sha256>  <file name>" lines.
+func releaseChecksum(checksums []byte, name string) (string, error) {
+	for _, line := range strings.Split(string(checksums), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			if len(fields[0]) != sha256.Size*2 {
+				return "", fmt.Errorf("malformed checksum for %s: %q", name, fields[0])
+			}
+			return fields[0], nil
+		}
+	}
+	return "", fmt.Errorf("no checksum for %s in the release's checksums file; refusing to install an unverified binary", name)
+}
+
+func extractBinary(src io.Reader, destPath string) error {
+	// Create destination file
+	destFile, err := os.Create(destPath)
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+
+	// Check if it's a gzipped tar archive
+	gzReader, err := gzip.NewReader(src)
+	if err != nil {
+		// If it's not gzipped, assume it's a raw binary and copy directly
+		_, copyErr := io.Copy(destFile, src)
+		return copyErr
+	}
+	defer gzReader.Close()
+
+	tarReader := tar.NewReader(gzReader)
+
+	// Find the binary file in the tar archive
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		// Look for the binary (usually the file without extension or with .exe)
+		filename := filepath.Base(header.Name)
+		if strings.HasPrefix(filename, "mysql-mcp") && header.Typeflag == tar.TypeReg {
+			// Copy the binary content
+			_, err := io.Copy(destFile, tarReader)
+			return err
+		}
+	}
+
+	return fmt.Errorf("binary not found in archive")
+}
+
+// extractZipBinary writes the mysql-mcp executable in a zip archive to
+// destPath.
+func extractZipBinary(archive []byte, destPath string) error {
+	zipReader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return err
+	}
+	for _, f := range zipReader.File {
+		if !strings.HasPrefix(filepath.Base(f.Name), "mysql-mcp") || !f.Mode().IsRegular() {
+			continue
+		}
+		src, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		destFile, err := os.Create(destPath)
+		if err != nil {
+			return err
+		}
+		defer destFile.Close()
+		_, err = io.Copy(destFile, src)
+		return err
+	}
+	return fmt.Errorf("binary not found in archive")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestReleaseChecksum(t *testing.T) {
+	sum := strings.Repeat("ab", 32)
+	other := strings.Repeat("cd", 32)
+	checksums := sum + "  mysql-mcp-server_linux_amd64.tar.gz\n" +
+		other + " *mysql-mcp-server_windows_amd64.zip\n" +
+		strings.Repeat("ef", 32) + "  mysql-mcp-server_linux_amd64.tar.gz.sbom.json\n" +
+		"abc123  mysql-mcp-server_darwin_arm64.tar.gz\n" +
+		"\n"
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{"mysql-mcp-server_linux_amd64.tar.gz", sum, ""},
+		// sha256sum marks binary-mode entries with *.
+		{"mysql-mcp-server_windows_amd64.zip", other, ""},
+		{"mysql-mcp-server_darwin_arm64.tar.gz", "", "malformed checksum"},
+		{"mysql-mcp-server_linux_arm64.tar.gz", "", "no checksum for mysql-mcp-server_linux_arm64.tar.gz"},
+		// A name is matched whole, not as a prefix of a longer one.
+		{"mysql-mcp-server_linux_amd64.tar", "", "no checksum"},
+	}
+	for _, tt := range tests {
+		got, err := releaseChecksum([]byte(checksums), tt.name)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("releaseChecksum(%q) error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("releaseChecksum(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestReleaseChecksumAssets(t *testing.T) {
+	tests := []struct {
+		assets        string
+		wantChecksums string
+		wantSignature string
+	}{
+		{`[{"name": "app.tar.gz", "browser_download_url": "u/app"},
+		   {"name": "checksums.txt", "browser_download_url": "u/sums"},
+		   {"name": "checksums.txt.minisig", "browser_download_url": "u/sig"}]`, "u/sums", "u/sig"},
+		// Releases published before signing have no .minisig.
+		{`[{"name": "app.tar.gz", "browser_download_url": "u/app"},
+		   {"name": "app_checksums.txt", "browser_download_url": "u/sums"}]`, "u/sums", ""},
+		// A signature is only taken for the checksums file it signs.
+		{`[{"name": "app.tar.gz.minisig", "browser_download_url": "u/other"}]`, "", ""},
+	}
+	for _, tt := range tests {
+		var release GitHubRelease
+		if err := json.Unmarshal([]byte(`{"assets": `+tt.assets+`}`), &release); err != nil {
+			t.Fatal(err)
+		}
+		checksums, _, signature := releaseChecksumAssets(release)
+		if checksums != tt.wantChecksums || signature != tt.wantSignature {
+			t.Errorf("releaseChecksumAssets(%s) = %q, %q; want %q, %q",
+				tt.assets, checksums, signature, tt.wantChecksums, tt.wantSignature)
+		}
+	}
+}
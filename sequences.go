@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ListSequencesParams struct {
+	Database string `json:"database"`
+}
+
+// SequenceInfo is the definition and state of a sequence. TiDB does not
+// expose the state, so NextValue is nil and Cycles zero there.
+type SequenceInfo struct {
+	Name      string `json:"name"`
+	NextValue *int64 `json:"nextValue,omitempty"`
+	MinValue  int64  `json:"minValue"`
+	MaxValue  int64  `json:"maxValue"`
+	Start     int64  `json:"start"`
+	Increment int64  `json:"increment"`
+	Cache     int64  `json:"cache"`
+	Cycle     bool   `json:"cycle"`
+	Cycles    int64  `json:"cycles,omitempty"`
+}
+
+func ListSequences(ctx context.Context, req *mcp.CallToolRequest, args ListSequencesParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	server := serverInfoFor(ctx, db)
+	if !server.supportsSequences() {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Sequences need MariaDB 10.3+ or TiDB 4.0+; the server is %s", server)},
+			},
+		}, nil, nil
+	}
+
+	var sequences []SequenceInfo
+	var err error
+	if server.TiDB {
+		sequences, err = tidbSequences(ctx, args.Database)
+	} else {
+		sequences, err = mariaDBSequences(ctx, args.Database)
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read sequences: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("Found %d sequences in database '%s':\n", len(sequences), args.Database)
+	for _, s := range sequences {
+		resultText += "- " + s.Name + ": "
+		if s.NextValue != nil {
+			resultText += fmt.Sprintf("next %d (not yet cached), ", *s.NextValue)
+		}
+		resultText += fmt.Sprintf("start %d, increment %d, range %d..%d, cache %d",
+			s.Start, s.Increment, s.MinValue, s.MaxValue, s.Cache)
+		if s.Cycle {
+			resultText += ", cycles"
+			if s.NextValue != nil {
+				resultText += fmt.Sprintf(" (%d so far)", s.Cycles)
+			}
+		}
+		resultText += "\n"
+	}
+	if len(sequences) > 0 {
+		resultText += "Use SELECT NEXTVAL(seq) or NEXT VALUE FOR seq with execute_query to take a value.\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"sequences": sequences,
+	}, nil
+}
+
+// mariaDBSequences reads the sequences of a MariaDB database. Selecting
+// from a sequence reads its state without advancing it.
+func mariaDBSequences(ctx context.Context, database string) ([]SequenceInfo, error) {
+	db := currentDB()
+	var names []string
+	err := queryEach(ctx, db, `
+		SELECT TABLE_NAME FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'SEQUENCE'
+		ORDER BY TABLE_NAME
+	`, []any{database}, func(rows *sql.Rows) error {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		names = append(names, name)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sequences := []SequenceInfo{}
+	for _, name := range names {
+		s := SequenceInfo{Name: name}
+		var next int64
+		var cycle int
+		err := db.QueryRowContext(ctx, fmt.Sprintf(`
+			SELECT next_not_cached_value, minimum_value, maximum_value, start_value,
+			       increment, cache_size, cycle_option, cycle_count
+			FROM %s.%s`, quoteIdentifier(database), quoteIdentifier(name)),
+		).Scan(&next, &s.MinValue, &s.MaxValue, &s.Start, &s.Increment, &s.Cache, &cycle, &s.Cycles)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		s.NextValue = &next
+		s.Cycle = cycle != 0
+		sequences = append(sequences, s)
+	}
+	return sequences, nil
+}
+
+// tidbSequences reads the sequence definitions of a TiDB database.
+func tidbSequences(ctx context.Context, database string) ([]SequenceInfo, error) {
+	db := currentDB()
+	sequences := []SequenceInfo{}
+	err := queryEach(ctx, db, `
+		SELECT SEQUENCE_NAME, MIN_VALUE, MAX_VALUE, START, INCREMENT, IF(CACHE, CACHE_VALUE, 0), CYCLE
+		FROM information_schema.SEQUENCES
+		WHERE SEQUENCE_SCHEMA = ?
+		ORDER BY SEQUENCE_NAME
+	`, []any{database}, func(rows *sql.Rows) error {
+		var s SequenceInfo
+		if err := rows.Scan(&s.Name, &s.MinValue, &s.MaxValue, &s.Start, &s.Increment, &s.Cache, &s.Cycle); err != nil {
+			return err
+		}
+		sequences = append(sequences, s)
+		return nil
+	})
+	return sequences, err
+}
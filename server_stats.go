@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// latencySamples is the number of recent call durations kept per tool for
+// percentiles.
+const latencySamples = 1000
+
+var serverStarted = time.Now()
+
+// toolStats holds per-tool usage since the server started, recorded by
+// toolMetricsMiddleware.
+var toolStats = struct {
+	sync.Mutex
+	byTool map[string]*toolUsage
+}{byTool: make(map[string]*toolUsage)}
+
+type toolUsage struct {
+	calls    int64
+	errors   int64
+	total    time.Duration
+	max      time.Duration
+	last     time.Time
+	samples  []time.Duration
+	nextSlot int
+}
+
+func recordToolCall(tool string, d time.Duration, failed bool) {
+	toolStats.Lock()
+	defer toolStats.Unlock()
+	u, ok := toolStats.byTool[tool]
+	if !ok {
+		u = &toolUsage{}
+		toolStats.byTool[tool] = u
+	}
+	u.calls++
+	if failed {
+		u.errors++
+	}
+	u.total += d
+	u.max = max(u.max, d)
+	u.last = time.Now()
+	if len(u.samples) < latencySamples {
+		u.samples = append(u.samples, d)
+	} else {
+		u.samples[u.nextSlot] = d
+		u.nextSlot = (u.nextSlot + 1) % latencySamples
+	}
+}
+
+type ServerStatsParams struct {
+	Tool  string `json:"tool,omitempty"`
+	Reset bool   `json:"reset,omitempty"`
+}
+
+// ToolStats is the usage of one tool. Durations are in milliseconds; the
+// percentiles cover the tool's most recent calls.
+type ToolStats struct {
+	Tool      string  `json:"tool"`
+	Calls     int64   `json:"calls"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	TotalMs   float64 `json:"totalMs"`
+	AvgMs     float64 `json:"avgMs"`
+	P50Ms     float64 `json:"p50Ms"`
+	P90Ms     float64 `json:"p90Ms"`
+	P99Ms     float64 `json:"p99Ms"`
+	MaxMs     float64 `json:"maxMs"`
+	LastCall  string  `json:"lastCall"`
+}
+
+func ServerStats(ctx context.Context, req *mcp.CallToolRequest, args ServerStatsParams) (*mcp.CallToolResult, any, error) {
+	toolStats.Lock()
+	var stats []ToolStats
+	var calls, errors int64
+	var total time.Duration
+	for name, u := range toolStats.byTool {
+		if args.Tool != "" && name != args.Tool {
+			continue
+		}
+		calls += u.calls
+		errors += u.errors
+		total += u.total
+		stats = append(stats, u.summary(name))
+	}
+	if args.Reset && args.Tool != "" {
+		delete(toolStats.byTool, args.Tool)
+	} else if args.Reset {
+		toolStats.byTool = make(map[string]*toolUsage)
+	}
+	toolStats.Unlock()
+
+	if args.Tool != "" && len(stats) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("No calls to %s recorded", args.Tool)},
+			},
+		}, map[string]any{"tools": []ToolStats{}}, nil
+	}
+	// Busiest first: where the session's time went.
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].TotalMs != stats[j].TotalMs {
+			return stats[i].TotalMs > stats[j].TotalMs
+		}
+		return stats[i].Tool < stats[j].Tool
+	})
+
+	uptime := time.Since(serverStarted).Round(time.Second)
+	resultText := fmt.Sprintf("Uptime %s: %d tool call(s), %d error(s), %s in tools\n", uptime, calls, errors, total.Round(time.Millisecond))
+	for _, s := range stats {
+		resultText += fmt.Sprintf("  %s: %d call(s), %d error(s) (%.1f%%), total %.0fms, avg %.1fms, p50 %.1fms, p90 %.1fms, p99 %.1fms, max %.1fms, last %s\n",
+			s.Tool, s.Calls, s.Errors, s.ErrorRate*100, s.TotalMs, s.AvgMs, s.P50Ms, s.P90Ms, s.P99Ms, s.MaxMs, s.LastCall)
+	}
+	if len(stats) > 0 {
+		resultText += fmt.Sprintf("Percentiles cover each tool's last %d calls.\n", latencySamples)
+	}
+	if args.Reset {
+		resultText += "Statistics reset.\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"uptimeSeconds": int64(uptime.Seconds()),
+		"calls":         calls,
+		"errors":        errors,
+		"totalMs":       milliseconds(total),
+		"tools":         stats,
+		"reset":         args.Reset,
+	}, nil
+}
+
+// summary computes the statistics of u; the caller holds toolStats.
+func (u *toolUsage) summary(name string) ToolStats {
+	sorted := append([]time.Duration{}, u.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	percentile := func(p float64) float64 {
+		if len(sorted) == 0 {
+			return 0
+		}
+		rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return milliseconds(sorted[max(rank, 0)])
+	}
+	s := ToolStats{
+		Tool:     name,
+		Calls:    u.calls,
+		Errors:   u.errors,
+		TotalMs:  milliseconds(u.total),
+		P50Ms:    percentile(0.50),
+		P90Ms:    percentile(0.90),
+		P99Ms:    percentile(0.99),
+		MaxMs:    milliseconds(u.max),
+		LastCall: u.last.UTC().Format(time.RFC3339),
+	}
+	if u.calls > 0 {
+		s.ErrorRate = float64(u.errors) / float64(u.calls)
+		s.AvgMs = s.TotalMs / float64(u.calls)
+	}
+	return s
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
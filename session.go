@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// sessionVariableNames are the settings reported by default, in order. The
+// transaction isolation variable is tx_isolation before MySQL 8.0.
+var sessionVariableNames = []string{
+	"sql_mode",
+	"time_zone",
+	"max_execution_time",
+	"transaction_isolation",
+	"autocommit",
+	"character_set_client",
+	"collation_connection",
+}
+
+// defaultSessionVariables returns sessionVariableNames with the statement
+// timeout variable the server actually has.
+func defaultSessionVariables(server serverInfo) []string {
+	names := slices.Clone(sessionVariableNames)
+	for i, name := range names {
+		if name == "max_execution_time" {
+			names[i] = server.statementTimeoutVariable()
+		}
+	}
+	return names
+}
+
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type GetSessionVariablesParams struct {
+	Names []string `json:"names,omitempty"`
+}
+
+type SetSessionVariablesParams struct {
+	Variables map[string]any `json:"variables"`
+}
+
+func GetSessionVariables(ctx context.Context, req *mcp.CallToolRequest, args GetSessionVariablesParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	names := args.Names
+	if len(names) == 0 {
+		names = defaultSessionVariables(serverInfoFor(ctx, db))
+	}
+	for _, name := range names {
+		if !variableNamePattern.MatchString(name) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid variable name %q", name)},
+				},
+			}, nil, nil
+		}
+	}
+
+	values, order := sessionVariables(ctx, names)
+	resultText := "Session variables:\n"
+	for _, name := range order {
+		resultText += fmt.Sprintf("  %s = %s\n", name, values[name])
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"variables": values,
+	}, nil
+}
+
+func SetSessionVariables(ctx context.Context, req *mcp.CallToolRequest, args SetSessionVariablesParams) (*mcp.CallToolResult, any, error) {
+	// Settings such as autocommit and sql_mode change what every later
+	// statement does, so read-only mode refuses them all.
+	if err := checkWritable("set_session_variables"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	db := currentDB()
+	dbConfig := currentDBConfig()
+	if db == nil || dbConfig == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if len(args.Variables) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "variables is required"},
+			},
+		}, nil, nil
+	}
+
+	assignments := make(map[string]string, len(args.Variables))
+	for _, name := range sortedKeys(args.Variables) {
+		if !variableNamePattern.MatchString(name) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid variable name %q", name)},
+				},
+			}, nil, nil
+		}
+		switch v := args.Variables[name].(type) {
+		case string:
+			assignments[name] = quoteString(v)
+		case float64:
+			assignments[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			assignments[name] = "OFF"
+			if v {
+				assignments[name] = "ON"
+			}
+		case nil:
+			assignments[name] = "DEFAULT"
+		default:
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Unsupported value for %s: use a string, number, boolean or null", name)},
+				},
+			}, nil, nil
+		}
+	}
+
+	// The pool opens connections on demand, so a SET on one connection
+	// would silently not apply to the next query. Instead the settings are
+	// added to the DSN, which the driver applies to every new connection,
+	// and the pool is replaced. They are tried on a single connection first
+	// so invalid values are reported before anything changes; that
+	// connection is discarded rather than returned to the pool with them.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to get connection: %v", err)},
+			},
+		}, nil, nil
+	}
+	for _, name := range sortedKeys(assignments) {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET SESSION %s = %s", name, assignments[name])); err != nil {
+			discardConn(conn)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to set %s: %v", name, err)},
+				},
+			}, nil, nil
+		}
+	}
+	discardConn(conn)
+
+	cfg := dbConfig.Clone()
+	if cfg.Params == nil {
+		cfg.Params = make(map[string]string)
+	}
+	for name, value := range assignments {
+		if value == "DEFAULT" {
+			delete(cfg.Params, name)
+			continue
+		}
+		cfg.Params[name] = value
+	}
+	if err := reopenDatabase(ctx, cfg); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to reconnect with new settings: %v", err)},
+			},
+		}, nil, nil
+	}
+	// quoteString escapes for the default connection's sql_mode.
+	if _, ok := assignments["sql_mode"]; ok {
+		recordSQLMode(ctx, currentDB())
+	}
+
+	values, order := sessionVariables(ctx, sortedKeys(assignments))
+	resultText := "Session variables set for all connections of this server:\n"
+	for _, name := range order {
+		resultText += fmt.Sprintf("  %s = %s\n", name, values[name])
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"variables": values,
+	}, nil
+}
+
+func ConnectionStatus(ctx context.Context, req *mcp.CallToolRequest, args struct{}) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	dbConfig := currentDBConfig()
+	if db == nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected. Use connect tool first."},
+			},
+		}, map[string]any{
+			"connected": false,
+		}, nil
+	}
+
+	var version, user, database sql.NullString
+	err := db.QueryRowContext(ctx, "SELECT VERSION(), CURRENT_USER(), DATABASE()").Scan(&version, &user, &database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Connection check failed: %v", err)},
+			},
+		}, map[string]any{
+			"connected": false,
+			"error":     err.Error(),
+		}, nil
+	}
+
+	address := ""
+	if dbConfig != nil {
+		address = dbConfig.Net + "(" + dbConfig.Addr + ")"
+	}
+	stats := db.Stats()
+	server := serverInfoFor(ctx, db)
+	values, order := sessionVariables(ctx, defaultSessionVariables(server))
+
+	resultText := fmt.Sprintf("Connected to %s as %s\nServer version: %s (%s)\nDefault database: %s\nRead-only mode: %t\n",
+		address, user.String, version.String, server.Flavor(), database.String, readOnly)
+	if server.Proxy != "" {
+		resultText += fmt.Sprintf("Proxy: %s\n", server.Proxy)
+	}
+	resultText += fmt.Sprintf("Pool: %d open (%d in use, %d idle)\n", stats.OpenConnections, stats.InUse, stats.Idle)
+	resultText += "Session settings:\n"
+	for _, name := range order {
+		resultText += fmt.Sprintf("  %s = %s\n", name, values[name])
+	}
+	names := connectionNames()
+	if len(names) > 0 {
+		resultText += fmt.Sprintf("Named connections: %s\n", strings.Join(names, ", "))
+	}
+	var cluster []TiDBInstance
+	if server.TiDB {
+		var err error
+		if cluster, err = tidbCluster(ctx); err != nil {
+			resultText += fmt.Sprintf("Cluster topology unavailable: %v\n", err)
+		} else {
+			resultText += "Cluster:\n"
+			for _, i := range cluster {
+				resultText += fmt.Sprintf("  %s %s (%s, up %s)\n", i.Type, i.Instance, i.Version, i.Uptime)
+			}
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"connected":        true,
+		"address":          address,
+		"user":             user.String,
+		"serverVersion":    version.String,
+		"serverFlavor":     server.Flavor(),
+		"proxy":            server.Proxy,
+		"database":         database.String,
+		"readOnly":         readOnly,
+		"openConnections":  stats.OpenConnections,
+		"inUse":            stats.InUse,
+		"idle":             stats.Idle,
+		"session":          values,
+		"namedConnections": names,
+		"cluster":          cluster,
+	}, nil
+}
+
+type UseDatabaseParams struct {
+	Database string `json:"database"`
+}
+
+func UseDatabase(ctx context.Context, req *mcp.CallToolRequest, args UseDatabaseParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	dbConfig := currentDBConfig()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	if args.Database == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "database is required"},
+			},
+		}, nil, nil
+	}
+	var exists int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?", args.Database).Scan(&exists); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to look up database: %v", err)},
+			},
+		}, nil, nil
+	}
+	if exists == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Database %s does not exist", args.Database)},
+			},
+		}, nil, nil
+	}
+
+	// Like session variables, a USE would only reach one connection of the
+	// pool, so the database goes into the DSN and the pool is replaced.
+	previous := dbConfig.DBName
+	cfg := dbConfig.Clone()
+	cfg.DBName = args.Database
+	if err := reopenDatabase(ctx, cfg); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to reconnect to %s: %v", args.Database, err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("Default database is now %s", quoteIdentifier(args.Database))
+	if previous != "" && previous != args.Database {
+		resultText += fmt.Sprintf(" (was %s)", quoteIdentifier(previous))
+	}
+	resultText += ". list_tables, describe_table and other tools use it when database is left out, and queries resolve unqualified table names in it.\n"
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"database": args.Database,
+		"previous": previous,
+	}, nil
+}
+
+// sessionVariables reads the session value of each variable. Variables the
+// server does not know are skipped, except that transaction_isolation falls
+// back to its pre-8.0 name tx_isolation. order lists the names found.
+func sessionVariables(ctx context.Context, names []string) (map[string]string, []string) {
+	db := currentDB()
+	values := make(map[string]string, len(names))
+	var order []string
+	for _, name := range names {
+		var value sql.NullString
+		err := db.QueryRowContext(ctx, "SELECT @@SESSION."+name).Scan(&value)
+		if err != nil && name == "transaction_isolation" {
+			name = "tx_isolation"
+			err = db.QueryRowContext(ctx, "SELECT @@SESSION."+name).Scan(&value)
+		}
+		if err != nil {
+			continue
+		}
+		values[name] = value.String
+		if !value.Valid {
+			values[name] = "NULL"
+		}
+		order = append(order, name)
+	}
+	return values, order
+}
+
+// reopenDatabase replaces the connection pool with one built from cfg.
+func reopenDatabase(ctx context.Context, cfg *mysql.Config) error {
+	database, err := openDatabase(cfg, agentResourceGroup.Load())
+	if err != nil {
+		return err
+	}
+	if err := database.PingContext(ctx); err != nil {
+		closePool(database)
+		return err
+	}
+	setDefaultConnection(database, cfg)
+	return nil
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// sessionActivity accumulates what this server has done since it started,
+// for session_summary.
+var sessionActivity = struct {
+	sync.Mutex
+	connections []SessionConnection
+	statements  map[string]int64
+	rowsRead    int64
+	rowsWritten int64
+	tables      map[string]int64
+	blocked     map[string]int64
+}{
+	statements: make(map[string]int64),
+	tables:     make(map[string]int64),
+	blocked:    make(map[string]int64),
+}
+
+// SessionConnection is a connection opened during the session.
+type SessionConnection struct {
+	Name    string `json:"name"`
+	User    string `json:"user"`
+	Address string `json:"address"`
+	Time    string `json:"time"`
+}
+
+func recordConnection(name, user, address string) {
+	sessionActivity.Lock()
+	defer sessionActivity.Unlock()
+	sessionActivity.connections = append(sessionActivity.connections, SessionConnection{
+		Name:    name,
+		User:    user,
+		Address: address,
+		Time:    time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// recordStatementActivity counts a statement sent to the database under
+// its category and the rows it returned.
+func recordStatementActivity(query string, rows int64) {
+	category := statementCategory(query)
+	sessionActivity.Lock()
+	defer sessionActivity.Unlock()
+	sessionActivity.statements[category]++
+	sessionActivity.rowsRead += rows
+}
+
+func recordRowsWritten(rows int64) {
+	sessionActivity.Lock()
+	defer sessionActivity.Unlock()
+	sessionActivity.rowsWritten += rows
+}
+
+func recordPolicyBlock(tool string) {
+	sessionActivity.Lock()
+	defer sessionActivity.Unlock()
+	sessionActivity.blocked[tool]++
+}
+
+// statementCategory classifies a statement as read, write, ddl or other by
+// its first keyword.
+func statementCategory(query string) string {
+	if len(query) > 256 {
+		query = query[:256]
+	}
+	switch strings.ToUpper(firstWord(sanitizeStatement(query))) {
+	case "SELECT", "SHOW", "DESCRIBE", "DESC", "EXPLAIN", "WITH", "TABLE", "VALUES", "CHECKSUM":
+		return "read"
+	case "INSERT", "UPDATE", "DELETE", "REPLACE", "LOAD":
+		return "write"
+	case "CREATE", "ALTER", "DROP", "RENAME", "TRUNCATE":
+		return "ddl"
+	}
+	return "other"
+}
+
+// activityMiddleware records the tables named in tool call arguments.
+func activityMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if call, ok := req.(*mcp.CallToolRequest); ok && method == "tools/call" {
+			tables := argumentTables(call.Params.Arguments)
+			if len(tables) > 0 {
+				sessionActivity.Lock()
+				for _, table := range tables {
+					sessionActivity.tables[table]++
+				}
+				sessionActivity.Unlock()
+			}
+		}
+		return next(ctx, method, req)
+	}
+}
+
+// argumentTables returns the database.table names in a tool's arguments,
+// using the parameter names the tools share.
+func argumentTables(raw json.RawMessage) []string {
+	var args map[string]any
+	if json.Unmarshal(raw, &args) != nil {
+		return nil
+	}
+	str := func(key string) string {
+		s, _ := args[key].(string)
+		return s
+	}
+	var tables []string
+	add := func(database, table string) {
+		if table == "" {
+			return
+		}
+		if database == "" {
+			tables = append(tables, table)
+		} else {
+			tables = append(tables, database+"."+table)
+		}
+	}
+	add(str("database"), str("table"))
+	if list, ok := args["tables"].([]any); ok {
+		for _, t := range list {
+			if name, ok := t.(string); ok {
+				add(str("database"), name)
+			}
+		}
+	}
+	add(str("source_database"), str("source_table"))
+	targetDatabase, targetTable := str("target_database"), str("target_table")
+	if targetTable != "" || targetDatabase != "" {
+		add(cmp.Or(targetDatabase, str("source_database")), cmp.Or(targetTable, str("source_table")))
+	}
+	return tables
+}
+
+func SessionSummary(ctx context.Context, req *mcp.CallToolRequest, args struct{}) (*mcp.CallToolResult, any, error) {
+	sessionActivity.Lock()
+	connections := append([]SessionConnection{}, sessionActivity.connections...)
+	statements := make(map[string]int64, len(sessionActivity.statements))
+	var statementTotal int64
+	for category, n := range sessionActivity.statements {
+		statements[category] = n
+		statementTotal += n
+	}
+	rowsRead, rowsWritten := sessionActivity.rowsRead, sessionActivity.rowsWritten
+	tables := make(map[string]int64, len(sessionActivity.tables))
+	for table, n := range sessionActivity.tables {
+		tables[table] = n
+	}
+	blocked := make(map[string]int64, len(sessionActivity.blocked))
+	for tool, n := range sessionActivity.blocked {
+		blocked[tool] = n
+	}
+	sessionActivity.Unlock()
+
+	var toolCallCount, toolErrors int64
+	toolStats.Lock()
+	for _, u := range toolStats.byTool {
+		toolCallCount += u.calls
+		toolErrors += u.errors
+	}
+	toolStats.Unlock()
+
+	client.Lock()
+	clientName, clientVersion := client.name, client.version
+	client.Unlock()
+
+	resultText := fmt.Sprintf("Session started %s (%s ago)\n", serverStarted.UTC().Format(time.RFC3339), time.Since(serverStarted).Round(time.Second))
+	if clientName != "" {
+		resultText += fmt.Sprintf("Client: %s %s\n", clientName, clientVersion)
+	}
+	if len(connections) == 0 {
+		resultText += "Connections: none\n"
+	} else {
+		resultText += "Connections:\n"
+		for _, c := range connections {
+			resultText += fmt.Sprintf("  %s: %s@%s at %s\n", c.Name, c.User, c.Address, c.Time)
+		}
+	}
+	resultText += fmt.Sprintf("Tool calls: %d (%d failed)\n", toolCallCount, toolErrors)
+	resultText += fmt.Sprintf("Statements: %d (%d read, %d write, %d DDL, %d other)\n",
+		statementTotal, statements["read"], statements["write"], statements["ddl"], statements["other"])
+	resultText += fmt.Sprintf("Rows: %d read, %d written\n", rowsRead, rowsWritten)
+	if len(tables) > 0 {
+		// Most used first.
+		names := sortedKeys(tables)
+		sort.SliceStable(names, func(i, j int) bool { return tables[names[i]] > tables[names[j]] })
+		resultText += "Tables touched:\n"
+		for _, name := range names {
+			resultText += fmt.Sprintf("  %s (%d call(s))\n", name, tables[name])
+		}
+	}
+	if len(blocked) > 0 {
+		resultText += "Blocked by read-only mode:\n"
+		for _, tool := range sortedKeys(blocked) {
+			resultText += fmt.Sprintf("  %s (%d time(s))\n", tool, blocked[tool])
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"started":       serverStarted.UTC().Format(time.RFC3339),
+		"client":        clientName,
+		"clientVersion": clientVersion,
+		"connections":   connections,
+		"toolCalls":     toolCallCount,
+		"toolErrors":    toolErrors,
+		"statements":    statements,
+		"rowsRead":      rowsRead,
+		"rowsWritten":   rowsWritten,
+		"tables":        tables,
+		"blocked":       blocked,
+	}, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// shutdownTimeout is how long a SIGINT or SIGTERM waits for running tool
+// calls before their statements are killed, set with -shutdown-timeout.
+var shutdownTimeout = 30 * time.Second
+
+// killGrace is how long tool calls get to return once their statements
+// have been killed.
+const killGrace = 5 * time.Second
+
+// toolCalls tracks the tool calls in progress and refuses new ones once
+// shutdown has begun.
+var toolCalls = struct {
+	sync.Mutex
+	active   int
+	closing  bool
+	finished chan struct{}
+}{}
+
+// shutdownMiddleware rejects tool calls that arrive during shutdown and
+// counts the others, so shutdown can wait for them.
+func shutdownMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		if method != "tools/call" {
+			return next(ctx, method, req)
+		}
+		toolCalls.Lock()
+		if toolCalls.closing {
+			toolCalls.Unlock()
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "The server is shutting down and no longer accepts tool calls."},
+				},
+			}, nil
+		}
+		toolCalls.active++
+		toolCalls.Unlock()
+
+		defer func() {
+			toolCalls.Lock()
+			toolCalls.active--
+			if toolCalls.active == 0 && toolCalls.finished != nil {
+				close(toolCalls.finished)
+				toolCalls.finished = nil
+			}
+			toolCalls.Unlock()
+		}()
+		return next(ctx, method, req)
+	}
+}
+
+// waitForToolCalls stops new tool calls and waits up to timeout for the
+// running ones. It reports whether they all finished.
+func waitForToolCalls(timeout time.Duration) bool {
+	toolCalls.Lock()
+	toolCalls.closing = true
+	if toolCalls.active == 0 {
+		toolCalls.Unlock()
+		return true
+	}
+	if toolCalls.finished == nil {
+		toolCalls.finished = make(chan struct{})
+	}
+	finished := toolCalls.finished
+	toolCalls.Unlock()
+
+	select {
+	case <-finished:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// killRunningQueries sends KILL QUERY for every connection with a statement
+// in progress, each over a new connection to the same server. Tool calls
+// then see the statement fail and roll back their transactions.
+func killRunningQueries(ctx context.Context) int {
+	liveConns.Lock()
+	var busy []*instrumentedConn
+	for c := range liveConns.conns {
+		if c.busy.Load() > 0 && c.id != 0 {
+			busy = append(busy, c)
+		}
+	}
+	liveConns.Unlock()
+
+	killed := 0
+	for _, c := range busy {
+		if err := killQuery(ctx, c.connector, c.id); err != nil {
+			slog.Error("failed to kill query", "connection_id", c.id, "err", err)
+			continue
+		}
+		slog.Warn("killed running query", "connection_id", c.id)
+		killed++
+	}
+	return killed
+}
+
+// handleShutdownSignals waits for SIGINT or SIGTERM, drains tool calls and
+// then calls stop to end the server. A second signal exits immediately.
+func handleShutdownSignals(stop context.CancelFunc) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	sig := <-signals
+	signal.Stop(signals)
+	slog.Info("shutting down", "signal", sig.String(), "timeout", shutdownTimeout)
+
+	if !waitForToolCalls(shutdownTimeout) {
+		ctx, cancel := context.WithTimeout(context.Background(), killGrace)
+		killed := killRunningQueries(ctx)
+		cancel()
+		slog.Warn("tool calls still running after shutdown timeout", "killed_queries", killed)
+		if !waitForToolCalls(killGrace) {
+			slog.Warn("abandoning tool calls that did not return")
+		}
+	}
+	stop()
+}
+
+// closeDatabases closes the default and named connection pools.
+// Transactions left open by abandoned tool calls are rolled back by the
+// server when their connections close.
+func closeDatabases() {
+	db := currentDB()
+	if db != nil {
+		if err := db.Close(); err != nil {
+			slog.Error("failed to close database", "err", err)
+		}
+	}
+	for _, name := range connectionNames() {
+		if conn, err := connectionFor(name); err == nil {
+			if err := conn.Close(); err != nil {
+				slog.Error("failed to close database", "connection", name, "err", err)
+			}
+		}
+	}
+	// Replaced pools still waiting for their last tool call.
+	for _, p := range takeRetiredPools() {
+		if err := p.Close(); err != nil {
+			slog.Error("failed to close replaced connection pool", "err", err)
+		}
+	}
+}
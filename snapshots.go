@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// snapshotsDir is the default directory schema snapshots are stored in.
+var snapshotsDir = "schema-snapshots"
+
+var snapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]*$`)
+
+type SnapshotSchemaParams struct {
+	Database   string `json:"database"`
+	Name       string `json:"name,omitempty"`
+	Connection string `json:"connection,omitempty"`
+}
+
+type ListSnapshotsParams struct {
+	Database string `json:"database,omitempty"`
+}
+
+type DiffSnapshotParams struct {
+	Snapshot   string `json:"snapshot"`
+	Database   string `json:"database,omitempty"`
+	Connection string `json:"connection,omitempty"`
+}
+
+// schemaSnapshot is the JSON file written for each snapshot. A .sql file
+// with the same name holds the equivalent DDL for reading and replaying.
+type schemaSnapshot struct {
+	Name       string          `json:"name"`
+	Database   string          `json:"database"`
+	Connection string          `json:"connection"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	Schema     *databaseSchema `json:"schema"`
+}
+
+type SnapshotInfo struct {
+	Name       string    `json:"name"`
+	Database   string    `json:"database"`
+	Connection string    `json:"connection"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Tables     int       `json:"tables"`
+}
+
+func SnapshotSchema(ctx context.Context, req *mcp.CallToolRequest, args SnapshotSchemaParams) (*mcp.CallToolResult, any, error) {
+	conn, err := connectionFor(args.Connection)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: connectionHint(err)},
+			},
+		}, nil, nil
+	}
+	if args.Database == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "database is required"},
+			},
+		}, nil, nil
+	}
+
+	now := time.Now().UTC()
+	name := args.Name
+	if name == "" {
+		name = args.Database + "-" + now.Format("20060102-150405")
+	}
+	if !snapshotNamePattern.MatchString(name) {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid snapshot name %q: use letters, digits, '_', '.' and '-'", name)},
+			},
+		}, nil, nil
+	}
+	jsonPath := filepath.Join(snapshotsDir, name+".json")
+	if _, err := os.Stat(jsonPath); err == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Snapshot %s already exists", name)},
+			},
+		}, nil, nil
+	}
+
+	schema, err := loadSchema(ctx, conn, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read schema: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	connection := args.Connection
+	if connection == "" {
+		connection = defaultConnectionName
+	}
+	snapshot := schemaSnapshot{Name: name, Database: args.Database, Connection: connection, CreatedAt: now, Schema: schema}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(snapshotsDir, 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(jsonPath, data, 0o644)
+	}
+	sqlPath := filepath.Join(snapshotsDir, name+".sql")
+	if err == nil {
+		ddl := fmt.Sprintf("-- Schema snapshot %s of %s (%s), %s\n", name, quoteIdentifier(args.Database), connection, now.Format(time.RFC3339))
+		err = os.WriteFile(sqlPath, []byte(ddl+schemaDDL(schema)), 0o644)
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to write snapshot: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Saved snapshot %s of %s: %d tables, %d views, %d routines, %d triggers\nFiles: %s, %s",
+				name, quoteIdentifier(args.Database), len(schema.Tables), len(schema.Views), len(schema.Routines), len(schema.Triggers), jsonPath, sqlPath)},
+		},
+	}, map[string]any{
+		"name":     name,
+		"database": args.Database,
+		"json":     jsonPath,
+		"sql":      sqlPath,
+		"tables":   len(schema.Tables),
+	}, nil
+}
+
+func ListSnapshots(ctx context.Context, req *mcp.CallToolRequest, args ListSnapshotsParams) (*mcp.CallToolResult, any, error) {
+	entries, err := os.ReadDir(snapshotsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read %s: %v", snapshotsDir, err)},
+			},
+		}, nil, nil
+	}
+
+	snapshots := []SnapshotInfo{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		snapshot, err := readSnapshot(name)
+		if err != nil {
+			continue
+		}
+		if args.Database != "" && snapshot.Database != args.Database {
+			continue
+		}
+		snapshots = append(snapshots, SnapshotInfo{
+			Name:       snapshot.Name,
+			Database:   snapshot.Database,
+			Connection: snapshot.Connection,
+			CreatedAt:  snapshot.CreatedAt,
+			Tables:     len(snapshot.Schema.Tables),
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.After(snapshots[j].CreatedAt) })
+
+	resultText := fmt.Sprintf("%d snapshot(s) in %s:\n", len(snapshots), snapshotsDir)
+	for _, s := range snapshots {
+		resultText += fmt.Sprintf("  %s  %s  %s (%s), %d tables\n", s.CreatedAt.Format(time.RFC3339), s.Name, quoteIdentifier(s.Database), s.Connection, s.Tables)
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"snapshots": snapshots,
+	}, nil
+}
+
+func DiffSnapshot(ctx context.Context, req *mcp.CallToolRequest, args DiffSnapshotParams) (*mcp.CallToolResult, any, error) {
+	if !snapshotNamePattern.MatchString(args.Snapshot) {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "snapshot is required"},
+			},
+		}, nil, nil
+	}
+	snapshot, err := readSnapshot(args.Snapshot)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read snapshot %s: %v", args.Snapshot, err)},
+			},
+		}, nil, nil
+	}
+	conn, err := connectionFor(args.Connection)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: connectionHint(err)},
+			},
+		}, nil, nil
+	}
+
+	database := args.Database
+	if database == "" {
+		database = snapshot.Database
+	}
+	live, err := loadSchema(ctx, conn, database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read schema: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	// The snapshot is the reference, so "missing" objects were dropped
+	// since it was taken and "extra" ones were added.
+	differences := diffSchemas(snapshot.Schema, live)
+	resultText := fmt.Sprintf("Changes in %s since snapshot %s (%s)\n(missing = removed since the snapshot, only in target = added since)\n",
+		describeSchemaLocation(args.Connection, database), snapshot.Name, snapshot.CreatedAt.Format(time.RFC3339))
+	resultText += formatSchemaDifferences(differences)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"snapshot":    snapshot.Name,
+		"takenAt":     snapshot.CreatedAt,
+		"target":      describeSchemaLocation(args.Connection, database),
+		"identical":   len(differences) == 0,
+		"differences": differences,
+	}, nil
+}
+
+func readSnapshot(name string) (*schemaSnapshot, error) {
+	return readSnapshotFile(filepath.Join(snapshotsDir, name+".json"))
+}
+
+// readSnapshotFile reads a snapshot from path, which may also be a baseline
+// kept outside the snapshots directory.
+func readSnapshotFile(path string) (*schemaSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var snapshot schemaSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.Schema == nil {
+		return nil, fmt.Errorf("no schema in snapshot file")
+	}
+	return &snapshot, nil
+}
+
+// schemaDDL renders the statements that recreate schema in an empty
+// database of the same name.
+func schemaDDL(schema *databaseSchema) string {
+	empty := &databaseSchema{Database: schema.Database}
+	plan := planSchemaMigration(schema, empty, diffSchemas(schema, empty))
+	return migrationScript(plan.safe)
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// spatialTypes are the DATA_TYPE values of spatial columns.
+var spatialTypes = map[string]bool{
+	"geometry": true, "point": true, "linestring": true, "polygon": true,
+	"multipoint": true, "multilinestring": true, "multipolygon": true,
+	"geometrycollection": true, "geomcollection": true,
+}
+
+// spatialPredicates maps the predicate names spatial_search accepts to the
+// function testing a row's geometry against the given one.
+var spatialPredicates = map[string]string{
+	"intersects": "ST_Intersects",
+	"within":     "ST_Within",
+	"contains":   "ST_Contains",
+}
+
+type SpatialSearchParams struct {
+	Database  string   `json:"database"`
+	Table     string   `json:"table"`
+	Column    string   `json:"column"`
+	Predicate string   `json:"predicate"`
+	Geometry  string   `json:"geometry"`
+	Distance  float64  `json:"distance,omitempty"`
+	SRID      *int     `json:"srid,omitempty"`
+	Columns   []string `json:"columns,omitempty"`
+	Filters   []Filter `json:"filters,omitempty"`
+	Limit     int      `json:"limit,omitempty"`
+	Format    string   `json:"format,omitempty"`
+}
+
+func SpatialSearch(ctx context.Context, req *mcp.CallToolRequest, args SpatialSearchParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	server := serverInfoFor(ctx, db)
+	if server.TiDB {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("%s has no spatial types or functions", server)},
+			},
+		}, nil, nil
+	}
+
+	predicate := strings.ToLower(args.Predicate)
+	if _, ok := spatialPredicates[predicate]; !ok && predicate != "within_distance" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown predicate %q (use intersects, within, contains or within_distance)", args.Predicate)},
+			},
+		}, nil, nil
+	}
+	if predicate == "within_distance" && args.Distance <= 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "within_distance requires a positive distance"},
+			},
+		}, nil, nil
+	}
+	format := strings.ToLower(args.Format)
+	if format == "" {
+		format = "geojson"
+	}
+	if format != "geojson" && format != "wkt" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown format %q (use geojson or wkt)", args.Format)},
+			},
+		}, nil, nil
+	}
+	if strings.TrimSpace(args.Geometry) == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "geometry is required (WKT or GeoJSON)"},
+			},
+		}, nil, nil
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	i := slices.IndexFunc(tableCols, func(c ColumnInfo) bool { return c.ColumnName == args.Column })
+	if i < 0 || !spatialTypes[strings.ToLower(tableCols[i].DataType)] {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("%s.%s has no spatial column %q", args.Database, args.Table, args.Column)},
+			},
+		}, nil, nil
+	}
+
+	// The given geometry must be in the column's SRID for MySQL to compare
+	// them.
+	srid := columnSRID(ctx, server, args.Database, args.Table, args.Column)
+	if args.SRID != nil {
+		srid = *args.SRID
+	}
+
+	selected := []string{}
+	for _, name := range args.Columns {
+		if !slices.ContainsFunc(tableCols, func(c ColumnInfo) bool { return c.ColumnName == name }) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Column %q does not exist in %s.%s", name, args.Database, args.Table)},
+				},
+			}, nil, nil
+		}
+		if name != args.Column {
+			selected = append(selected, quoteIdentifier(name))
+		}
+	}
+	if len(args.Columns) == 0 {
+		for _, c := range tableCols {
+			if !spatialTypes[strings.ToLower(c.DataType)] && vectorDimension(c.DataType) == 0 {
+				selected = append(selected, quoteIdentifier(c.ColumnName))
+			}
+		}
+	}
+
+	column := quoteIdentifier(args.Column)
+	shape := spatialGeometryExpression(server, args.Geometry, srid)
+	query := "SELECT " + strings.Join(append(selected, column), ", ")
+	var params []any
+	var condition string
+	if predicate == "within_distance" {
+		distance := fmt.Sprintf("ST_Distance(%s, %s)", column, shape)
+		// MariaDB and MySQL 5.7 measure in degrees on SRID 4326; the
+		// spherical distance is in meters there, as on MySQL 8.0.
+		if srid == 4326 && (server.MariaDB || !server.atLeast(8, 0, 0)) {
+			distance = fmt.Sprintf("ST_Distance_Sphere(%s, %s)", column, shape)
+		}
+		query += ", " + distance + " AS distance"
+		params = append(params, args.Geometry)
+		condition = distance + " <= ?"
+		params = append(params, args.Geometry, args.Distance)
+	} else {
+		condition = fmt.Sprintf("%s(%s, %s)", spatialPredicates[predicate], column, shape)
+		params = append(params, args.Geometry)
+	}
+	query += " FROM " + qualifiedTable(args.Database, args.Table) + " WHERE " + condition
+	if len(args.Filters) > 0 {
+		where, filterParams, err := buildWhere(args.Filters, tableCols)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid filters: %v", err)},
+				},
+			}, nil, nil
+		}
+		query += " AND " + where
+		params = append(params, filterParams...)
+	}
+	if predicate == "within_distance" {
+		query += " ORDER BY distance"
+	}
+	query += fmt.Sprintf(" LIMIT %d", limit)
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Spatial search failed: %v", err)},
+			},
+		}, nil, nil
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to get columns: %v", err)},
+			},
+		}, nil, nil
+	}
+	kinds := resultColumnKinds(rows, len(columns))
+	geometryIndex := len(selected)
+
+	var results []map[string]any
+	features := []map[string]any{}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		valuePtrs := make([]any, len(columns))
+		for i := range values {
+			valuePtrs[i] = &values[i]
+		}
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to scan row: %v", err)},
+				},
+			}, nil, nil
+		}
+		properties := make(map[string]any, len(columns))
+		for i, col := range columns {
+			if i == geometryIndex {
+				continue
+			}
+			if b, ok := values[i].([]byte); ok {
+				properties[col] = displayBytes(kinds[i], b)
+			} else {
+				properties[col] = values[i]
+			}
+		}
+		raw, _ := values[geometryIndex].([]byte)
+		if format == "wkt" {
+			properties[args.Column] = geometryText(raw)
+			results = append(results, properties)
+			continue
+		}
+		var feature any
+		if _, g, err := decodeGeometry(raw); err == nil {
+			feature = g.geoJSON()
+		}
+		features = append(features, map[string]any{"type": "Feature", "geometry": feature, "properties": properties})
+	}
+	if err := rows.Err(); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Row iteration error: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	if format == "wkt" {
+		resultText := fmt.Sprintf("%d rows match:\n\n", len(results)) + formatRowTable(columns, results)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, map[string]any{
+			"rows":     results,
+			"rowCount": len(results),
+			"columns":  columns,
+			"query":    query,
+		}, nil
+	}
+
+	collection := map[string]any{"type": "FeatureCollection", "features": features}
+	data, err := json.Marshal(collection)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to encode GeoJSON: %v", err)},
+			},
+		}, nil, nil
+	}
+	resultText := fmt.Sprintf("%d rows match", len(features))
+	// GeoJSON coordinates are WGS 84 longitude and latitude.
+	if srid != 0 && srid != 4326 {
+		resultText += fmt.Sprintf(" (coordinates are in SRID %d, not WGS 84 as GeoJSON assumes)", srid)
+	}
+	resultText += ":\n" + string(data)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"featureCollection": collection,
+		"rowCount":          len(features),
+		"query":             query,
+	}, nil
+}
+
+// spatialGeometryExpression returns the SQL reading a geometry given as
+// WKT or GeoJSON text from a parameter. WKT coordinates are taken as
+// longitude, latitude, like GeoJSON, rather than MySQL 8.0's default
+// latitude-longitude order for geographic SRIDs.
+func spatialGeometryExpression(server serverInfo, input string, srid int) string {
+	geoJSON := strings.HasPrefix(strings.TrimSpace(input), "{")
+	switch {
+	case geoJSON && server.MariaDB:
+		return "ST_GeomFromGeoJSON(?)"
+	case geoJSON:
+		return fmt.Sprintf("ST_GeomFromGeoJSON(?, 1, %d)", srid)
+	case server.MariaDB || !server.atLeast(8, 0, 0):
+		return fmt.Sprintf("ST_GeomFromText(?, %d)", srid)
+	}
+	return fmt.Sprintf("ST_GeomFromText(?, %d, 'axis-order=long-lat')", srid)
+}
+
+// columnSRID returns the SRID a spatial column is restricted to, or 0 if
+// it has none or the catalog does not say.
+func columnSRID(ctx context.Context, server serverInfo, database, table, column string) int {
+	db := currentDB()
+	query := `
+		SELECT SRS_ID FROM information_schema.ST_GEOMETRY_COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?`
+	if server.MariaDB {
+		query = `
+			SELECT SRID FROM information_schema.GEOMETRY_COLUMNS
+			WHERE G_TABLE_SCHEMA = ? AND G_TABLE_NAME = ? AND G_GEOMETRY_COLUMN = ?`
+	}
+	var srid *int
+	if err := db.QueryRowContext(ctx, query, database, table, column).Scan(&srid); err != nil || srid == nil {
+		return 0
+	}
+	return *srid
+}
@@ -0,0 +1,637 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	defaultFormatIndent = 2
+	defaultFormatWidth  = 80
+)
+
+type FormatSQLParams struct {
+	SQL string `json:"sql"`
+	// KeywordCase is upper (the default), lower or preserve.
+	KeywordCase string `json:"keyword_case,omitempty"`
+	Indent      int    `json:"indent,omitempty"`
+	LineWidth   int    `json:"line_width,omitempty"`
+}
+
+// Kinds of SQL tokens.
+const (
+	tokenWord = iota
+	tokenQuoted
+	tokenNumber
+	tokenComment
+	tokenPunct
+)
+
+type sqlToken struct {
+	kind int
+	text string
+	// space is set when whitespace preceded the token in the input.
+	space bool
+	// end is the offset in the input just past the token.
+	end int
+}
+
+// sqlKeywords are the words format_sql changes the case of: MySQL's
+// reserved words, data types and the non-reserved words of common clauses.
+var sqlKeywords = wordSet(`
+	ACCESSIBLE ADD ALL ALTER ANALYZE AND AS ASC ASENSITIVE BEFORE BETWEEN BIGINT
+	BINARY BLOB BOTH BY CALL CASCADE CASE CHANGE CHAR CHARACTER CHECK COLLATE
+	COLUMN CONDITION CONSTRAINT CONTINUE CONVERT CREATE CROSS CUBE CUME_DIST
+	CURRENT_DATE CURRENT_TIME CURRENT_TIMESTAMP CURRENT_USER CURSOR DATABASE
+	DATABASES DAY_HOUR DAY_MICROSECOND DAY_MINUTE DAY_SECOND DEC DECIMAL
+	DECLARE DEFAULT DELAYED DELETE DENSE_RANK DESC DESCRIBE DETERMINISTIC
+	DISTINCT DISTINCTROW DIV DOUBLE DROP DUAL EACH ELSE ELSEIF EMPTY ENCLOSED
+	ESCAPED EXCEPT EXISTS EXIT EXPLAIN FALSE FETCH FIRST_VALUE FLOAT FOR FORCE
+	FOREIGN FROM FULLTEXT FUNCTION GENERATED GET GRANT GROUP GROUPING GROUPS
+	HAVING HIGH_PRIORITY HOUR_MICROSECOND HOUR_MINUTE HOUR_SECOND IF IGNORE IN
+	INDEX INFILE INNER INOUT INSENSITIVE INSERT INT INTEGER INTERSECT INTERVAL
+	INTO IS ITERATE JOIN JSON_TABLE KEY KEYS KILL LAG LAST_VALUE LATERAL LEAD
+	LEADING LEAVE LEFT LIKE LIMIT LINEAR LINES LOAD LOCALTIME LOCALTIMESTAMP
+	LOCK LONG LONGBLOB LONGTEXT LOOP LOW_PRIORITY MATCH MEDIUMBLOB MEDIUMINT
+	MEDIUMTEXT MIDDLEINT MINUTE_MICROSECOND MINUTE_SECOND MOD MODIFIES NATURAL
+	NOT NO_WRITE_TO_BINLOG NTH_VALUE NTILE NULL NUMERIC OF ON OPTIMIZE OPTION
+	OPTIONALLY OR ORDER OUT OUTER OUTFILE OVER PARTITION PERCENT_RANK PRECISION
+	PRIMARY PROCEDURE PURGE RANGE RANK READ READS REAL RECURSIVE REFERENCES
+	REGEXP RELEASE RENAME REPEAT REPLACE REQUIRE RESIGNAL RESTRICT RETURN
+	RETURNING REVOKE RIGHT RLIKE ROW ROWS ROW_NUMBER SCHEMA SCHEMAS
+	SECOND_MICROSECOND SELECT SENSITIVE SEPARATOR SET SHOW SIGNAL SMALLINT
+	SPATIAL SPECIFIC SQL SQL_BIG_RESULT SQL_CALC_FOUND_ROWS SQL_SMALL_RESULT
+	SQLEXCEPTION SQLSTATE SQLWARNING SSL STARTING STORED STRAIGHT_JOIN TABLE
+	TERMINATED THEN TINYBLOB TINYINT TINYTEXT TO TRAILING TRIGGER TRUE UNDO
+	UNION UNIQUE UNLOCK UNSIGNED UPDATE USAGE USE USING UTC_DATE UTC_TIME
+	UTC_TIMESTAMP VALUES VARBINARY VARCHAR VARCHARACTER VARYING VIRTUAL WHEN
+	WHERE WHILE WINDOW WITH WRITE XOR YEAR_MONTH ZEROFILL
+	AUTO_INCREMENT BEGIN BOOL BOOLEAN CHARSET COMMENT COMMIT DATE DATETIME DUPLICATE END ENGINE ENUM
+	FOLLOWING JSON OFFSET PRECEDING ROLLBACK ROLLUP SHARE START TEMPORARY TEXT
+	TIME TIMESTAMP TRANSACTION TRUNCATE UNBOUNDED VIEW YEAR
+`)
+
+// sqlFunctions are the built-in functions format_sql changes the case of
+// when they are called.
+var sqlFunctions = wordSet(`
+	ABS AVG CAST CEIL CEILING COALESCE CONCAT CONCAT_WS COUNT DATE_ADD
+	DATE_FORMAT DATE_SUB DATEDIFF EXTRACT FLOOR FROM_UNIXTIME GREATEST
+	GROUP_CONCAT IFNULL ISNULL JSON_ARRAY JSON_ARRAYAGG JSON_CONTAINS
+	JSON_EXTRACT JSON_OBJECT JSON_OBJECTAGG JSON_UNQUOTE LEAST LENGTH LOWER
+	LPAD LTRIM MAX MIN NOW NULLIF POSITION RAND ROUND RPAD RTRIM STR_TO_DATE
+	SUBSTR SUBSTRING SUBSTRING_INDEX SUM TIMESTAMPDIFF TRIM TRUNCATE
+	UNIX_TIMESTAMP UPPER
+`)
+
+// tableNameWords are the words a table name follows; a table named like
+// a keyword keeps its case, since table names can be case sensitive.
+var tableNameWords = wordSet("FROM JOIN INTO UPDATE TABLE STRAIGHT_JOIN")
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// Kinds of clauses, which decide how a clause too long for a line is
+// broken.
+const (
+	clausePlain = iota
+	clauseList
+	clauseCondition
+)
+
+// sqlClause is a keyword phrase that starts a clause on its own line, and
+// the words that may follow it as part of its head.
+type sqlClause struct {
+	words     []string
+	kind      int
+	modifiers map[string]bool
+}
+
+var sqlClauses = func() []sqlClause {
+	var clauses []sqlClause
+	add := func(phrase string, kind int, modifiers string) {
+		clauses = append(clauses, sqlClause{strings.Fields(phrase), kind, wordSet(modifiers)})
+	}
+	// Longer phrases come first, so they win over their prefixes.
+	add("ON DUPLICATE KEY UPDATE", clauseList, "")
+	add("LOCK IN SHARE MODE", clausePlain, "")
+	for _, join := range []string{"NATURAL LEFT OUTER", "NATURAL RIGHT OUTER", "NATURAL LEFT", "NATURAL RIGHT", "LEFT OUTER", "RIGHT OUTER", "NATURAL", "LEFT", "RIGHT", "INNER", "CROSS"} {
+		add(join+" JOIN", clauseCondition, "")
+	}
+	add("GROUP BY", clauseList, "")
+	add("ORDER BY", clauseList, "")
+	add("FOR UPDATE", clausePlain, "")
+	add("FOR SHARE", clausePlain, "")
+	add("SELECT", clauseList, "ALL DISTINCT DISTINCTROW HIGH_PRIORITY STRAIGHT_JOIN SQL_SMALL_RESULT SQL_BIG_RESULT SQL_BUFFER_RESULT SQL_NO_CACHE SQL_CALC_FOUND_ROWS")
+	add("FROM", clauseList, "")
+	add("JOIN", clauseCondition, "")
+	add("STRAIGHT_JOIN", clauseCondition, "")
+	add("WHERE", clauseCondition, "")
+	add("HAVING", clauseCondition, "")
+	add("WINDOW", clauseList, "")
+	add("LIMIT", clausePlain, "")
+	add("UNION", clausePlain, "ALL DISTINCT")
+	add("EXCEPT", clausePlain, "ALL DISTINCT")
+	add("INTERSECT", clausePlain, "ALL DISTINCT")
+	add("WITH", clauseList, "RECURSIVE")
+	add("INSERT", clausePlain, "LOW_PRIORITY DELAYED HIGH_PRIORITY IGNORE INTO")
+	add("REPLACE", clausePlain, "LOW_PRIORITY DELAYED INTO")
+	add("UPDATE", clausePlain, "LOW_PRIORITY IGNORE")
+	add("DELETE", clausePlain, "LOW_PRIORITY QUICK IGNORE FROM")
+	add("VALUES", clauseList, "")
+	add("VALUE", clauseList, "")
+	add("SET", clauseList, "")
+	add("RETURNING", clauseList, "")
+	return clauses
+}()
+
+// tokenizeSQL splits a statement into tokens, dropping whitespace. Line
+// comments become block comments, so that the statement can be rejoined
+// on fewer lines.
+func tokenizeSQL(sql string) []sqlToken {
+	var tokens []sqlToken
+	space := false
+	add := func(kind int, text string) {
+		tokens = append(tokens, sqlToken{kind: kind, text: text, space: space})
+		space = false
+	}
+	isWordByte := func(c byte) bool {
+		return c == '_' || c == '$' || c == '@' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+	}
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for end < len(sql) {
+				if sql[end] == '\\' && c != '`' {
+					end += 2
+					continue
+				}
+				if sql[end] == c {
+					if end+1 < len(sql) && sql[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, len(sql))
+			// Introducers such as _utf8mb4'x' and literals such as X'0F'
+			// stay one token.
+			if n := len(tokens); c == '\'' && n > 0 && !space && tokens[n-1].kind == tokenWord && isIntroducer(tokens[n-1].text) {
+				tokens[n-1] = sqlToken{kind: tokenQuoted, text: tokens[n-1].text + sql[i:end], space: tokens[n-1].space}
+			} else {
+				add(tokenQuoted, sql[i:end])
+			}
+			i = end
+		case c == '#' || c == '-' && strings.HasPrefix(sql[i:], "--") && (i+2 == len(sql) || strings.IndexByte(" \t\r\n", sql[i+2]) >= 0):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			text := strings.TrimSpace(strings.TrimLeft(sql[i:i+end], "#-"))
+			add(tokenComment, "/* "+strings.ReplaceAll(text, "*/", "* /")+" */")
+			i += end
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				end = len(sql) - i
+			} else {
+				end += 4
+			}
+			add(tokenComment, sql[i:i+end])
+			i += end
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9' && (len(tokens) == 0 || space || tokens[len(tokens)-1].kind == tokenPunct):
+			end := i + 1
+			for end < len(sql) && (isWordByte(sql[end]) || sql[end] == '.' ||
+				(sql[end] == '-' || sql[end] == '+') && (sql[end-1] == 'e' || sql[end-1] == 'E') && !strings.HasPrefix(strings.ToLower(sql[i:end]), "0x")) {
+				end++
+			}
+			add(tokenNumber, sql[i:end])
+			i = end
+		case isWordByte(c):
+			end := i + 1
+			for end < len(sql) && isWordByte(sql[end]) {
+				end++
+			}
+			add(tokenWord, sql[i:end])
+			i = end
+		default:
+			end := i + 1
+			for _, op := range []string{"<=>", "->>", "<=", ">=", "<>", "!=", ":=", "||", "&&", "<<", ">>", "->"} {
+				if strings.HasPrefix(sql[i:], op) {
+					end = i + len(op)
+					break
+				}
+			}
+			add(tokenPunct, sql[i:end])
+			i = end
+		}
+		if n := len(tokens); n > 0 && tokens[n-1].end == 0 {
+			tokens[n-1].end = i
+		}
+	}
+	return tokens
+}
+
+// isIntroducer reports whether a word directly before a string literal
+// belongs to it: a charset introducer or the prefix of a hex, bit or
+// national string.
+func isIntroducer(word string) bool {
+	switch strings.ToUpper(word) {
+	case "X", "B", "N":
+		return true
+	}
+	return strings.HasPrefix(word, "_")
+}
+
+type sqlFormatter struct {
+	keywordCase string
+	indent      int
+	width       int
+}
+
+func (f *sqlFormatter) pad(indent int) string {
+	return strings.Repeat(" ", indent)
+}
+
+// word renders tokens[i] with the keyword case applied.
+func (f *sqlFormatter) word(tokens []sqlToken, i int) string {
+	t := tokens[i]
+	if t.kind != tokenWord || f.keywordCase == "preserve" {
+		return t.text
+	}
+	upper := strings.ToUpper(t.text)
+	called := i+1 < len(tokens) && tokens[i+1].text == "(" && !tokens[i+1].space
+	qualified := i > 0 && tokens[i-1].text == "." || i+1 < len(tokens) && tokens[i+1].text == "."
+	tableName := i > 0 && tableNameWords[strings.ToUpper(tokens[i-1].text)]
+	if qualified || tableName || !sqlKeywords[upper] && !(called && sqlFunctions[upper]) {
+		return t.text
+	}
+	if f.keywordCase == "lower" {
+		return strings.ToLower(t.text)
+	}
+	return upper
+}
+
+// isKeyword reports whether t is a word format_sql treats as a keyword.
+func isKeyword(t sqlToken) bool {
+	return t.kind == tokenWord && sqlKeywords[strings.ToUpper(t.text)]
+}
+
+// spaceBefore reports whether tokens[i] is separated from the token before
+// it.
+func spaceBefore(tokens []sqlToken, i int) bool {
+	prev, cur := tokens[i-1], tokens[i]
+	switch {
+	case cur.text == "," || cur.text == ";" || cur.text == ")" || cur.text == ".":
+		return false
+	case prev.text == "(" || prev.text == "." || prev.text == "!" || prev.text == "~":
+		return false
+	case (prev.text == "-" || prev.text == "+") && !cur.space:
+		// A sign stays attached at the start or after an operator, comma
+		// or keyword.
+		if i < 2 {
+			return false
+		}
+		before := tokens[i-2]
+		return !(before.kind == tokenPunct && before.text != ")" || isKeyword(before))
+	case cur.text == "(":
+		// A parenthesis stays next to a name it followed, as function
+		// calls need unless IGNORE_SPACE is set.
+		return cur.space || prev.kind != tokenWord && prev.kind != tokenQuoted
+	}
+	return true
+}
+
+// matchParen returns the index of the parenthesis closing tokens[open],
+// or len(tokens)-1 when it is unclosed.
+func matchParen(tokens []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		switch tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens) - 1
+}
+
+// isSubquery reports whether the tokens inside a parenthesis are a query.
+func isSubquery(tokens []sqlToken) bool {
+	for _, t := range tokens {
+		if t.kind == tokenComment {
+			continue
+		}
+		upper := strings.ToUpper(t.text)
+		return upper == "SELECT" || upper == "WITH" || upper == "VALUES" || upper == "TABLE"
+	}
+	return false
+}
+
+// inline renders tokens on one line, except that subqueries are laid out
+// as indented blocks and, with breakParens, so are the items of
+// parenthesized lists such as CREATE TABLE's column definitions.
+func (f *sqlFormatter) inline(tokens []sqlToken, indent int, breakParens bool) string {
+	var b strings.Builder
+	for i := 0; i < len(tokens); i++ {
+		if i > 0 && spaceBefore(tokens, i) {
+			b.WriteByte(' ')
+		}
+		if tokens[i].text == "(" {
+			end := matchParen(tokens, i)
+			if inner := tokens[i+1 : max(end, i+1)]; end > i && tokens[end].text == ")" && (isSubquery(inner) || breakParens && len(inner) > 0) {
+				b.WriteString("(\n")
+				if isSubquery(inner) {
+					b.WriteString(f.statement(inner, indent+f.indent))
+				} else {
+					var lines []string
+					for _, item := range splitTopLevel(inner, false) {
+						lines = append(lines, f.pad(indent+f.indent)+f.inline(item, indent+f.indent, false))
+					}
+					b.WriteString(strings.Join(lines, ",\n"))
+				}
+				b.WriteString("\n" + f.pad(indent) + ")")
+				i = end
+				continue
+			}
+		}
+		b.WriteString(f.word(tokens, i))
+	}
+	return b.String()
+}
+
+// splitTopLevel splits tokens at commas outside parentheses or, with
+// conditions, before the AND, OR and XOR that join conditions, leaving
+// those of BETWEEN and inside CASE alone.
+func splitTopLevel(tokens []sqlToken, conditions bool) [][]sqlToken {
+	var parts [][]sqlToken
+	start, depth, cases := 0, 0, 0
+	between := false
+	for i, t := range tokens {
+		upper := strings.ToUpper(t.text)
+		switch {
+		case t.text == "(":
+			depth++
+		case t.text == ")":
+			depth--
+		case depth > 0 || t.kind != tokenWord && t.kind != tokenPunct:
+		case upper == "CASE":
+			cases++
+		case upper == "END" && cases > 0:
+			cases--
+		case upper == "BETWEEN":
+			between = true
+		case !conditions && t.text == ",":
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		case conditions && cases == 0 && (upper == "AND" || upper == "OR" || upper == "XOR" || upper == "&&" || upper == "||"):
+			if upper == "AND" && between {
+				between = false
+				continue
+			}
+			if i > start {
+				parts = append(parts, tokens[start:i])
+				start = i
+			}
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// clauseAt returns the clause starting at tokens[i] and the number of
+// tokens in its head, or nil when no clause starts there.
+func clauseAt(tokens []sqlToken, i int) (*sqlClause, int) {
+	if tokens[i].kind != tokenWord {
+		return nil, 0
+	}
+	if i > 0 {
+		prev := tokens[i-1]
+		switch strings.ToUpper(prev.text) {
+		case "=", ",", "(", ".", "ON", "CHARACTER":
+			// VALUES(col), ON DELETE, ON UPDATE and CHARACTER SET.
+			return nil, 0
+		}
+	}
+	for c := range sqlClauses {
+		clause := &sqlClauses[c]
+		if i+len(clause.words) > len(tokens) {
+			continue
+		}
+		matched := true
+		for j, w := range clause.words {
+			if tokens[i+j].kind != tokenWord || !strings.EqualFold(tokens[i+j].text, w) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		n := len(clause.words)
+		first := clause.words[0]
+		next := func() string {
+			if i+n < len(tokens) {
+				return strings.ToUpper(tokens[i+n].text)
+			}
+			return ""
+		}
+		switch {
+		// REPLACE() and INSERT() are also string functions, and WITH
+		// ROLLUP ends a GROUP BY.
+		case (first == "REPLACE" || first == "INSERT") && next() == "(" && !tokens[i+n].space:
+			return nil, 0
+		case first == "WITH" && i > 0:
+			return nil, 0
+		}
+		for i+n < len(tokens) && clause.modifiers[next()] {
+			n++
+		}
+		return clause, n
+	}
+	return nil, 0
+}
+
+// sqlPart is a clause of a statement: its head, such as LEFT JOIN or
+// SELECT DISTINCT, and the tokens up to the next clause. The part before
+// the first clause has no clause or head.
+type sqlPart struct {
+	clause *sqlClause
+	head   []sqlToken
+	body   []sqlToken
+}
+
+// name returns the clause's phrase, such as "GROUP BY", or "" for the part
+// before the first clause.
+func (p sqlPart) name() string {
+	if p.clause == nil {
+		return ""
+	}
+	return strings.Join(p.clause.words, " ")
+}
+
+// splitClauses splits a statement into its clauses, leaving those of
+// subqueries in their parentheses.
+func splitClauses(tokens []sqlToken) []sqlPart {
+	var parts []sqlPart
+	current := sqlPart{}
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+		if depth == 0 {
+			if clause, n := clauseAt(tokens, i); clause != nil {
+				if current.clause != nil || len(current.body) > 0 {
+					parts = append(parts, current)
+				}
+				current = sqlPart{clause: clause, head: tokens[i : i+n]}
+				i += n - 1
+				continue
+			}
+		}
+		current.body = append(current.body, tokens[i])
+	}
+	return append(parts, current)
+}
+
+// statement lays out one statement, a clause per line at indent.
+func (f *sqlFormatter) statement(tokens []sqlToken, indent int) string {
+	parts := splitClauses(tokens)
+
+	createTable := len(tokens) > 2 && strings.EqualFold(tokens[0].text, "CREATE") && containsWord(tokens[:min(len(tokens), 4)], "TABLE")
+	pad := f.pad(indent)
+	var lines []string
+	for _, p := range parts {
+		if p.clause == nil {
+			lines = append(lines, pad+f.inline(p.body, indent, createTable))
+			continue
+		}
+		head := f.inline(p.head, indent, false)
+		if len(p.body) == 0 {
+			lines = append(lines, pad+head)
+			continue
+		}
+		body := f.inline(p.body, indent, false)
+		if line := pad + head + " " + body; !strings.Contains(body, "\n") && len(line) <= f.width || p.clause.kind == clausePlain {
+			lines = append(lines, line)
+			continue
+		}
+
+		items := splitTopLevel(p.body, p.clause.kind == clauseCondition)
+		if len(items) == 1 {
+			lines = append(lines, pad+head+" "+body)
+			continue
+		}
+		switch p.clause.kind {
+		case clauseList:
+			lines = append(lines, pad+head)
+			for j, item := range items {
+				line := f.pad(indent+f.indent) + f.inline(item, indent+f.indent, false)
+				if j < len(items)-1 {
+					line += ","
+				}
+				lines = append(lines, line)
+			}
+		case clauseCondition:
+			lines = append(lines, pad+head+" "+f.inline(items[0], indent, false))
+			for _, cond := range items[1:] {
+				lines = append(lines, f.pad(indent+f.indent)+f.inline(cond, indent+f.indent, false))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// containsWord reports whether tokens include the word.
+func containsWord(tokens []sqlToken, word string) bool {
+	for _, t := range tokens {
+		if t.kind == tokenWord && strings.EqualFold(t.text, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSQL pretty-prints a script of one or more statements. Compound
+// statements, such as procedure bodies, are kept as written.
+func formatSQL(script string, f *sqlFormatter) (string, int) {
+	statements := splitSQLStatements(script)
+	terminated := len(statements) > 1 || strings.HasSuffix(strings.TrimSpace(script), ";")
+	var out []string
+	for _, stmt := range statements {
+		tokens := tokenizeSQL(stmt)
+		if containsWord(tokens, "BEGIN") && strings.Contains(stmt, ";") {
+			out = append(out, "DELIMITER //\n"+stmt+"\n//\nDELIMITER ;")
+			continue
+		}
+		formatted := f.statement(tokens, 0)
+		if terminated {
+			formatted += ";"
+		}
+		out = append(out, formatted)
+	}
+	return strings.Join(out, "\n\n"), len(statements)
+}
+
+func FormatSQL(ctx context.Context, req *mcp.CallToolRequest, args FormatSQLParams) (*mcp.CallToolResult, any, error) {
+	f := &sqlFormatter{keywordCase: strings.ToLower(args.KeywordCase), indent: args.Indent, width: args.LineWidth}
+	switch f.keywordCase {
+	case "":
+		f.keywordCase = "upper"
+	case "upper", "lower", "preserve":
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Invalid keyword_case %q: use upper, lower or preserve", args.KeywordCase)},
+			},
+		}, nil, nil
+	}
+	if f.indent <= 0 {
+		f.indent = defaultFormatIndent
+	}
+	f.indent = min(f.indent, 8)
+	if f.width <= 0 {
+		f.width = defaultFormatWidth
+	}
+
+	formatted, count := formatSQL(args.SQL, f)
+	if count == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "No SQL to format"},
+			},
+		}, nil, nil
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: formatted},
+		},
+	}, map[string]any{
+		"sql":        formatted,
+		"statements": count,
+	}, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import "testing"
+
+func TestFormatSQL(t *testing.T) {
+	tests := []struct {
+		sql, keywordCase, want string
+		count                  int
+	}{
+		{
+			sql: "select a, count(*) from t join u on t.id = u.id where a = 1 and b in (select b from v) group by a order by 2 desc limit 10",
+			want: "SELECT a, COUNT(*)\nFROM t\nJOIN u ON t.id = u.id\nWHERE a = 1\n  AND b IN (\n    SELECT b\n    FROM v\n  )\n" +
+				"GROUP BY a\nORDER BY 2 DESC\nLIMIT 10",
+			count: 1,
+		},
+		{
+			// Quoted names and strings are left alone; line comments
+			// become block comments.
+			sql:   "select `select`, 'from' from t -- note\n where x=1;",
+			want:  "SELECT `select`, 'from'\nFROM t /* note */\nWHERE x = 1;",
+			count: 1,
+		},
+		{
+			sql:   "insert into t (a,b) values (1,'x');update t set a=1 where b=2",
+			want:  "INSERT INTO t (a, b)\nVALUES (1, 'x');\n\nUPDATE t\nSET a = 1\nWHERE b = 2;",
+			count: 2,
+		},
+		{
+			sql:   "select x'0F', _utf8mb4'a', 1.5e-3, -2 from dual",
+			want:  "SELECT x'0F', _utf8mb4'a', 1.5e-3, -2\nFROM DUAL",
+			count: 1,
+		},
+		{
+			// Table and column names that are also keywords or functions
+			// keep their case.
+			sql:         "SELECT COUNT(*) FROM Orders WHERE Status = 'x'",
+			keywordCase: "lower",
+			want:        "select count(*)\nfrom Orders\nwhere Status = 'x'",
+			count:       1,
+		},
+		{
+			sql:         "select  A from T",
+			keywordCase: "preserve",
+			want:        "select A\nfrom T",
+			count:       1,
+		},
+		{sql: "-- nothing\n", want: "", count: 0},
+	}
+	for _, tt := range tests {
+		f := &sqlFormatter{keywordCase: tt.keywordCase, indent: 2, width: 80}
+		if f.keywordCase == "" {
+			f.keywordCase = "upper"
+		}
+		got, count := formatSQL(tt.sql, f)
+		if got != tt.want || count != tt.count {
+			t.Errorf("formatSQL(%q) = %d statements\n%s\nwant %d\n%s", tt.sql, count, got, tt.count, tt.want)
+			continue
+		}
+		if again, _ := formatSQL(got, f); again != got {
+			t.Errorf("formatSQL() is not stable on its own output:\n%s\nbecame\n%s", got, again)
+		}
+	}
+}
+
+func TestTokenizeSQL(t *testing.T) {
+	tests := []struct {
+		sql   string
+		kinds []int
+	}{
+		{"SELECT a<=>b", []int{tokenWord, tokenWord, tokenPunct, tokenWord}},
+		{`'it''s' "a\"b" ` + "`x``y`", []int{tokenQuoted, tokenQuoted, tokenQuoted}},
+		{"_latin1'x' N'y' X'0F'", []int{tokenQuoted, tokenQuoted, tokenQuoted}},
+		{"1e-5 .5 0x1F a.b", []int{tokenNumber, tokenNumber, tokenNumber, tokenWord, tokenPunct, tokenWord}},
+		{"a -- c\n# d\n/* e */ b", []int{tokenWord, tokenComment, tokenComment, tokenComment, tokenWord}},
+		// "--" without a following space is two minus signs.
+		{"1--2", []int{tokenNumber, tokenPunct, tokenPunct, tokenNumber}},
+		{"'unterminated", []int{tokenQuoted}},
+	}
+	for _, tt := range tests {
+		tokens := tokenizeSQL(tt.sql)
+		kinds := make([]int, len(tokens))
+		for i, tok := range tokens {
+			kinds[i] = tok.kind
+		}
+		if len(kinds) != len(tt.kinds) {
+			t.Errorf("tokenizeSQL(%q) = %+v, want kinds %v", tt.sql, tokens, tt.kinds)
+			continue
+		}
+		for i := range kinds {
+			if kinds[i] != tt.kinds[i] {
+				t.Errorf("tokenizeSQL(%q) = %+v, want kinds %v", tt.sql, tokens, tt.kinds)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+)
+
+// splitSQLStatements splits a script into individual statements on
+// semicolons outside of quotes and comments. mysql client style DELIMITER
+// lines are honoured so scripts can define procedures and triggers.
+// Comments are kept as part of the statement that follows them.
+func splitSQLStatements(script string) []string {
+	var statements []string
+	delimiter := ";"
+	var current strings.Builder
+
+	flush := func() {
+		stmt := strings.TrimSpace(current.String())
+		current.Reset()
+		if stmt != "" && !onlyComments(stmt) {
+			statements = append(statements, stmt)
+		}
+	}
+
+	for i := 0; i < len(script); {
+		// DELIMITER is a client command and only valid at the start of a
+		// statement.
+		if strings.TrimSpace(current.String()) == "" && (i == 0 || script[i-1] == '\n') {
+			line := script[i:]
+			if end := strings.IndexByte(line, '\n'); end >= 0 {
+				line = line[:end]
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 2 && strings.EqualFold(fields[0], "DELIMITER") {
+				delimiter = fields[1]
+				i += len(line)
+				continue
+			}
+		}
+
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for end < len(script) {
+				if script[end] == '\\' && c != '`' {
+					end += 2
+					continue
+				}
+				if script[end] == c {
+					// A doubled quote is an escaped quote.
+					if end+1 < len(script) && script[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, len(script))
+			current.WriteString(script[i:end])
+			i = end
+		case c == '#' || (c == '-' && strings.HasPrefix(script[i:], "--") && (i+2 == len(script) || script[i+2] == ' ' || script[i+2] == '\t' || script[i+2] == '\n' || script[i+2] == '\r')):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			current.WriteString(script[i : i+end])
+			i += end
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script) - i
+			} else {
+				end += 4
+			}
+			current.WriteString(script[i : i+end])
+			i += end
+		case strings.HasPrefix(script[i:], delimiter):
+			flush()
+			i += len(delimiter)
+		default:
+			current.WriteByte(c)
+			i++
+		}
+	}
+	flush()
+	return statements
+}
+
+// onlyComments reports whether stmt consists of nothing but comments.
+func onlyComments(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "--") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "/*") && strings.HasSuffix(line, "*/") && !strings.HasPrefix(line, "/*!") {
+			continue
+		}
+		return false
+	}
+	return true
+}
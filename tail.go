@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// tailTimestampNames are the timestamp columns tail_table prefers, in order,
+// when a table has no auto-increment key and none defaults to the current
+// time.
+var tailTimestampNames = []string{"created_at", "created", "inserted_at", "created_on", "timestamp", "ts", "updated_at"}
+
+// tailCursorKey identifies a follow cursor. Cursors are kept per client
+// session, so two clients following the same table do not steal each
+// other's rows.
+type tailCursorKey struct {
+	session *mcp.ServerSession
+	table   string
+}
+
+// tailCursor is the last ordering value a follower has seen. Rows that
+// share it are remembered so that rows added later with the same
+// timestamp are returned without repeating the earlier ones.
+type tailCursor struct {
+	value any
+	seen  map[string]bool
+}
+
+var tailCursors = struct {
+	sync.Mutex
+	byKey map[tailCursorKey]*tailCursor
+}{byKey: make(map[tailCursorKey]*tailCursor)}
+
+// tailColumn picks the column that orders a table's rows by insertion: the
+// auto-increment key, else a timestamp column defaulting to the current
+// time, else a conventionally named or the first date and time column.
+func tailColumn(tableCols []ColumnInfo) (string, bool) {
+	var temporal []ColumnInfo
+	for _, c := range tableCols {
+		if strings.Contains(strings.ToLower(c.Extra), "auto_increment") {
+			return c.ColumnName, false
+		}
+		if c.DataType == "timestamp" || c.DataType == "datetime" {
+			temporal = append(temporal, c)
+		}
+	}
+	for _, c := range temporal {
+		if c.ColumnDefault != nil && strings.Contains(strings.ToUpper(*c.ColumnDefault), "CURRENT_TIMESTAMP") &&
+			!strings.Contains(strings.ToLower(c.Extra), "on update") {
+			return c.ColumnName, true
+		}
+	}
+	for _, name := range tailTimestampNames {
+		for _, c := range temporal {
+			if strings.EqualFold(c.ColumnName, name) {
+				return c.ColumnName, true
+			}
+		}
+	}
+	if len(temporal) > 0 {
+		return temporal[0].ColumnName, true
+	}
+	return "", false
+}
+
+type TailTableParams struct {
+	Database    string `json:"database"`
+	Table       string `json:"table"`
+	Column      string `json:"column,omitempty"`
+	Limit       int    `json:"limit,omitempty"`
+	Follow      bool   `json:"follow,omitempty"`
+	WaitSeconds int    `json:"wait_seconds,omitempty"`
+}
+
+func TailTable(ctx context.Context, req *mcp.CallToolRequest, args TailTableParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	column := args.Column
+	byTime := false
+	if column == "" {
+		column, byTime = tailColumn(tableCols)
+		if column == "" {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("%s.%s has no auto-increment key or timestamp column to order recent rows by; name one with column", args.Database, args.Table)},
+				},
+			}, nil, nil
+		}
+	} else {
+		i := slices.IndexFunc(tableCols, func(c ColumnInfo) bool { return c.ColumnName == column })
+		if i < 0 {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Column %q does not exist in %s.%s", column, args.Database, args.Table)},
+				},
+			}, nil, nil
+		}
+		byTime = tableCols[i].DataType == "timestamp" || tableCols[i].DataType == "datetime" || tableCols[i].DataType == "date"
+	}
+
+	key := tailCursorKey{session: req.Session, table: args.Database + "." + args.Table + "." + column}
+	tailCursors.Lock()
+	cursor := tailCursors.byKey[key]
+	tailCursors.Unlock()
+	if !args.Follow {
+		cursor = nil
+	}
+
+	table := qualifiedTable(args.Database, args.Table)
+	quoted := quoteIdentifier(column)
+	var columns []string
+	var results []map[string]any
+	if cursor == nil {
+		// The newest rows, returned oldest first like tail.
+		query := fmt.Sprintf("SELECT * FROM %s ORDER BY %s DESC LIMIT %d", table, quoted, limit)
+		columns, results, err = queryRowMaps(ctx, db, query)
+		slices.Reverse(results)
+	} else {
+		// Rows at the cursor value are read again when ordering by time,
+		// since more may have been added in the same second.
+		op := ">"
+		if byTime {
+			op = ">="
+		}
+		query := fmt.Sprintf("SELECT * FROM %s WHERE %s %s ? ORDER BY %s LIMIT %d", table, quoted, op, quoted, limit+len(cursor.seen))
+		deadline := time.Now().Add(time.Duration(min(args.WaitSeconds, 60)) * time.Second)
+		for {
+			columns, results, err = queryRowMaps(ctx, db, query, cursor.value)
+			if err == nil {
+				results = slices.DeleteFunc(results, func(row map[string]any) bool {
+					return cursor.seen[tailRowKey(columns, row)]
+				})
+				results = results[:min(len(results), limit)]
+			}
+			if err != nil || len(results) > 0 || time.Now().After(deadline) {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+			case <-time.After(time.Second):
+			}
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read recent rows: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	if args.Follow && len(results) > 0 {
+		last := results[len(results)-1][column]
+		next := &tailCursor{value: last, seen: make(map[string]bool)}
+		if cursor != nil && fmt.Sprint(cursor.value) == fmt.Sprint(last) {
+			next.seen = cursor.seen
+		}
+		for _, row := range results {
+			if fmt.Sprint(row[column]) == fmt.Sprint(last) {
+				next.seen[tailRowKey(columns, row)] = true
+			}
+		}
+		tailCursors.Lock()
+		tailCursors.byKey[key] = next
+		tailCursors.Unlock()
+	}
+
+	var resultText string
+	switch {
+	case cursor != nil && len(results) == 0:
+		resultText = fmt.Sprintf("No new rows in %s.%s since %s = %v\n", args.Database, args.Table, column, cursor.value)
+	case cursor != nil:
+		resultText = fmt.Sprintf("%d new rows in %s.%s by %s:\n\n", len(results), args.Database, args.Table, column)
+	default:
+		resultText = fmt.Sprintf("Last %d rows of %s.%s by %s:\n\n", len(results), args.Database, args.Table, column)
+	}
+	if len(results) > 0 {
+		resultText += formatRowTable(columns, results)
+	}
+	if args.Follow {
+		resultText += "\nCall again with follow to get only the rows added since.\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"rows":     results,
+		"rowCount": len(results),
+		"columns":  columns,
+		"orderBy":  column,
+	}, nil
+}
+
+// tailRowKey identifies a row by all its values, for rows that share the
+// cursor's timestamp.
+func tailRowKey(columns []string, row map[string]any) string {
+	var b strings.Builder
+	for _, c := range columns {
+		fmt.Fprintf(&b, "%v\x00", row[c])
+	}
+	return b.String()
+}
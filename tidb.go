@@ -0,0 +1,450 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// TiDBInstance is one TiDB, TiKV, PD or TiFlash instance of the cluster.
+type TiDBInstance struct {
+	Type     string `json:"type"`
+	Instance string `json:"instance"`
+	Version  string `json:"version"`
+	Uptime   string `json:"uptime"`
+}
+
+// tidbCluster lists the instances of the cluster from the CLUSTER_INFO
+// table, which exists since TiDB 4.0.
+func tidbCluster(ctx context.Context) ([]TiDBInstance, error) {
+	db := currentDB()
+	var instances []TiDBInstance
+	err := queryEach(ctx, db, `
+		SELECT TYPE, INSTANCE, COALESCE(VERSION, ''), COALESCE(UPTIME, '')
+		FROM information_schema.CLUSTER_INFO
+		ORDER BY TYPE, INSTANCE
+	`, nil, func(rows *sql.Rows) error {
+		var i TiDBInstance
+		if err := rows.Scan(&i.Type, &i.Instance, &i.Version, &i.Uptime); err != nil {
+			return err
+		}
+		instances = append(instances, i)
+		return nil
+	})
+	return instances, err
+}
+
+// requireTiDB returns an error result for the TiDB-only tools when the
+// server is something else.
+func requireTiDB(ctx context.Context, tool string) *mcp.CallToolResult {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}
+	}
+	if server := serverInfoFor(ctx, db); !server.TiDB {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("%s needs a TiDB server; the server is %s", tool, server)},
+			},
+		}
+	}
+	return nil
+}
+
+// isTiDBPlan reports whether a result set is a TiDB EXPLAIN or EXPLAIN
+// ANALYZE plan, whose first column is the operator tree.
+func isTiDBPlan(columns []string) bool {
+	return len(columns) > 1 && columns[0] == "id" && slices.Contains(columns, "estRows")
+}
+
+// formatTiDBPlan renders a TiDB plan with every column as wide as its
+// longest value, so the operator tree in the id column stays aligned. The
+// last column, operator info, is left unpadded.
+func formatTiDBPlan(columns []string, rows []map[string]any) string {
+	cell := func(row map[string]any, col string) string {
+		if row[col] == nil {
+			return "NULL"
+		}
+		return fmt.Sprint(row[col])
+	}
+	widths := make([]int, len(columns))
+	for i, col := range columns {
+		widths[i] = len([]rune(col))
+		for _, row := range rows {
+			widths[i] = max(widths[i], len([]rune(cell(row, col))))
+		}
+	}
+
+	var sb strings.Builder
+	line := func(values []string) {
+		for i, v := range values {
+			if i == len(values)-1 {
+				sb.WriteString(v)
+				break
+			}
+			sb.WriteString(v + strings.Repeat(" ", widths[i]-len([]rune(v))) + " | ")
+		}
+		sb.WriteString("\n")
+	}
+	line(columns)
+	total := 0
+	for _, w := range widths {
+		total += w + 3
+	}
+	sb.WriteString(strings.Repeat("-", total-3) + "\n")
+	for _, row := range rows {
+		values := make([]string, len(columns))
+		for i, col := range columns {
+			values[i] = cell(row, col)
+		}
+		line(values)
+	}
+	return sb.String()
+}
+
+type TiDBDDLJobsParams struct {
+	Database string  `json:"database,omitempty"`
+	Limit    int     `json:"limit,omitempty"`
+	Cancel   []int64 `json:"cancel,omitempty"`
+}
+
+// TiDBDDLJob is one entry of ADMIN SHOW DDL JOBS.
+type TiDBDDLJob struct {
+	JobID       string `json:"jobId"`
+	Database    string `json:"database"`
+	Table       string `json:"table,omitempty"`
+	JobType     string `json:"jobType"`
+	SchemaState string `json:"schemaState"`
+	State       string `json:"state"`
+	RowCount    int64  `json:"rowCount"`
+	CreateTime  string `json:"createTime,omitempty"`
+	StartTime   string `json:"startTime,omitempty"`
+	EndTime     string `json:"endTime,omitempty"`
+}
+
+func TiDBDDLJobs(ctx context.Context, req *mcp.CallToolRequest, args TiDBDDLJobsParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if result := requireTiDB(ctx, "tidb_ddl_jobs"); result != nil {
+		return result, nil, nil
+	}
+
+	// Cancelling rolls a job back; a job that already finished is reported
+	// as an error by TiDB.
+	var cancelled []string
+	if len(args.Cancel) > 0 {
+		if err := checkWritable("tidb_ddl_jobs"); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: err.Error()},
+				},
+			}, nil, nil
+		}
+		ids := make([]string, len(args.Cancel))
+		for i, id := range args.Cancel {
+			ids[i] = fmt.Sprint(id)
+		}
+		stmt := "ADMIN CANCEL DDL JOBS " + strings.Join(ids, ", ")
+		rows, err := db.QueryContext(ctx, stmt)
+		if err == nil {
+			var results []map[string]any
+			_, results, err = scanRowMaps(rows)
+			rows.Close()
+			for _, row := range results {
+				cancelled = append(cancelled, fmt.Sprintf("job %v: %v", row["JOB_ID"], row["RESULT"]))
+			}
+		}
+		audit("tidb_ddl_jobs", stmt, int64(len(cancelled)), err)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to cancel DDL jobs: %v", err)},
+				},
+			}, nil, nil
+		}
+	}
+
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	// The limit applies to finished jobs; running and queued jobs are
+	// always listed.
+	stmt := fmt.Sprintf("ADMIN SHOW DDL JOBS %d", limit)
+	if args.Database != "" {
+		stmt += " WHERE DB_NAME = " + quoteString(args.Database)
+	}
+	rows, err := db.QueryContext(ctx, stmt)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to list DDL jobs: %v", err)},
+			},
+		}, nil, nil
+	}
+	defer rows.Close()
+	// The columns of ADMIN SHOW DDL JOBS vary between TiDB versions, so
+	// they are read by name.
+	_, results, err := scanRowMaps(rows)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read DDL jobs: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	jobs := make([]TiDBDDLJob, 0, len(results))
+	running := 0
+	for _, row := range results {
+		job := TiDBDDLJob{
+			JobID:       rowText(row, "JOB_ID"),
+			Database:    rowText(row, "DB_NAME"),
+			Table:       rowText(row, "TABLE_NAME"),
+			JobType:     rowText(row, "JOB_TYPE"),
+			SchemaState: rowText(row, "SCHEMA_STATE"),
+			State:       rowText(row, "STATE"),
+			RowCount:    int64(numericValue(row["ROW_COUNT"])),
+			CreateTime:  rowText(row, "CREATE_TIME"),
+			StartTime:   rowText(row, "START_TIME"),
+			EndTime:     rowText(row, "END_TIME"),
+		}
+		if job.State == "running" || job.State == "queueing" {
+			running++
+		}
+		jobs = append(jobs, job)
+	}
+
+	resultText := ""
+	if len(cancelled) > 0 {
+		resultText += "Cancel requested: " + strings.Join(cancelled, "; ") + "\n\n"
+	}
+	resultText += fmt.Sprintf("%d DDL jobs (%d running or queued):\n", len(jobs), running)
+	for _, j := range jobs {
+		target := j.Database
+		if j.Table != "" {
+			target += "." + j.Table
+		}
+		resultText += fmt.Sprintf("- Job %s: %s on %s, %s (schema state: %s)", j.JobID, j.JobType, target, j.State, j.SchemaState)
+		if j.RowCount > 0 {
+			resultText += fmt.Sprintf(", %d rows", j.RowCount)
+		}
+		if j.StartTime != "" {
+			resultText += ", started " + j.StartTime
+		}
+		if j.EndTime != "" {
+			resultText += ", ended " + j.EndTime
+		}
+		resultText += "\n"
+	}
+	if running > 0 && len(args.Cancel) == 0 {
+		resultText += "Pass the job IDs in cancel to roll running jobs back.\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"jobs":      jobs,
+		"cancelled": cancelled,
+	}, nil
+}
+
+type TiDBRegionsParams struct {
+	Database string `json:"database,omitempty"`
+	Table    string `json:"table,omitempty"`
+	Limit    int    `json:"limit,omitempty"`
+}
+
+// TiDBRegion is one TiKV region of a table, from SHOW TABLE ... REGIONS.
+type TiDBRegion struct {
+	RegionID      int64 `json:"regionId"`
+	LeaderStoreID int64 `json:"leaderStoreId"`
+	WrittenBytes  int64 `json:"writtenBytes"`
+	ReadBytes     int64 `json:"readBytes"`
+	SizeMB        int64 `json:"sizeMb"`
+	Keys          int64 `json:"keys"`
+}
+
+// TiDBHotRegion is a region PD currently reports as a read or write hot
+// spot.
+type TiDBHotRegion struct {
+	Database  string `json:"database"`
+	Table     string `json:"table"`
+	Index     string `json:"index,omitempty"`
+	RegionID  int64  `json:"regionId"`
+	Type      string `json:"type"`
+	HotDegree int64  `json:"hotDegree"`
+	FlowBytes int64  `json:"flowBytes"`
+}
+
+func TiDBRegions(ctx context.Context, req *mcp.CallToolRequest, args TiDBRegionsParams) (*mcp.CallToolResult, any, error) {
+	if result := requireTiDB(ctx, "tidb_regions"); result != nil {
+		return result, nil, nil
+	}
+	if args.Table != "" && args.Database == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "database is required with table"},
+			},
+		}, nil, nil
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	structured := map[string]any{}
+	resultText := ""
+	if args.Table != "" {
+		regions, err := tidbTableRegions(ctx, args.Database, args.Table)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to read regions of %s.%s: %v", args.Database, args.Table, err)},
+				},
+			}, nil, nil
+		}
+		var sizeMB, keys int64
+		leaders := map[int64]int{}
+		for _, r := range regions {
+			sizeMB += r.SizeMB
+			keys += r.Keys
+			leaders[r.LeaderStoreID]++
+		}
+		resultText += fmt.Sprintf("Table %s.%s has %d regions, about %d MB and %d keys\n", args.Database, args.Table, len(regions), sizeMB, keys)
+		resultText += "Leaders by TiKV store:"
+		for _, store := range slices.Sorted(maps.Keys(leaders)) {
+			resultText += fmt.Sprintf(" %d: %d", store, leaders[store])
+		}
+		resultText += "\n"
+
+		// The busiest regions by traffic in PD's last reporting interval.
+		busiest := slices.Clone(regions)
+		slices.SortFunc(busiest, func(a, b TiDBRegion) int {
+			return cmp.Compare(b.WrittenBytes+b.ReadBytes, a.WrittenBytes+a.ReadBytes)
+		})
+		if len(busiest) > limit {
+			busiest = busiest[:limit]
+		}
+		if len(busiest) > 0 && busiest[0].WrittenBytes+busiest[0].ReadBytes > 0 {
+			resultText += "Busiest regions:\n"
+			for _, r := range busiest {
+				if r.WrittenBytes+r.ReadBytes == 0 {
+					break
+				}
+				resultText += fmt.Sprintf("- Region %d (leader on store %d): %d bytes written, %d bytes read, %d MB\n",
+					r.RegionID, r.LeaderStoreID, r.WrittenBytes, r.ReadBytes, r.SizeMB)
+			}
+		}
+		structured["regions"] = regions
+		structured["leadersByStore"] = leaders
+	}
+
+	hot, err := tidbHotRegions(ctx, args.Database, args.Table, limit)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to query information_schema.TIDB_HOT_REGIONS: %v", err)},
+			},
+		}, nil, nil
+	}
+	if len(hot) == 0 {
+		resultText += "No hot regions reported\n"
+	} else {
+		resultText += fmt.Sprintf("%d hot regions:\n", len(hot))
+		for _, h := range hot {
+			target := h.Database + "." + h.Table
+			if h.Index != "" {
+				target += " index " + h.Index
+			}
+			resultText += fmt.Sprintf("- Region %d of %s: %s hot spot, degree %d, %d bytes/s\n", h.RegionID, target, h.Type, h.HotDegree, h.FlowBytes)
+		}
+	}
+	structured["hotRegions"] = hot
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, structured, nil
+}
+
+// tidbTableRegions reads SHOW TABLE ... REGIONS, whose columns vary
+// between TiDB versions.
+func tidbTableRegions(ctx context.Context, database, table string) ([]TiDBRegion, error) {
+	db := currentDB()
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SHOW TABLE %s.%s REGIONS", quoteIdentifier(database), quoteIdentifier(table)))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	_, results, err := scanRowMaps(rows)
+	if err != nil {
+		return nil, err
+	}
+	regions := make([]TiDBRegion, 0, len(results))
+	for _, row := range results {
+		regions = append(regions, TiDBRegion{
+			RegionID:      int64(numericValue(row["REGION_ID"])),
+			LeaderStoreID: int64(numericValue(row["LEADER_STORE_ID"])),
+			WrittenBytes:  int64(numericValue(row["WRITTEN_BYTES"])),
+			ReadBytes:     int64(numericValue(row["READ_BYTES"])),
+			SizeMB:        int64(numericValue(row["APPROXIMATE_SIZE(MB)"])),
+			Keys:          int64(numericValue(row["APPROXIMATE_KEYS"])),
+		})
+	}
+	return regions, nil
+}
+
+// tidbHotRegions lists the hottest regions, optionally of one database or
+// table, by traffic.
+func tidbHotRegions(ctx context.Context, database, table string, limit int) ([]TiDBHotRegion, error) {
+	db := currentDB()
+	query := `
+		SELECT COALESCE(DB_NAME, ''), COALESCE(TABLE_NAME, ''), COALESCE(INDEX_NAME, ''), REGION_ID, TYPE,
+		       COALESCE(MAX_HOT_DEGREE, 0), COALESCE(FLOW_BYTES, 0)
+		FROM information_schema.TIDB_HOT_REGIONS
+		WHERE 1 = 1`
+	var params []any
+	if database != "" {
+		query += " AND DB_NAME = ?"
+		params = append(params, database)
+	}
+	if table != "" {
+		query += " AND TABLE_NAME = ?"
+		params = append(params, table)
+	}
+	query += " ORDER BY FLOW_BYTES DESC LIMIT ?"
+	params = append(params, limit)
+
+	hot := []TiDBHotRegion{}
+	err := queryEach(ctx, db, query, params, func(rows *sql.Rows) error {
+		var h TiDBHotRegion
+		if err := rows.Scan(&h.Database, &h.Table, &h.Index, &h.RegionID, &h.Type, &h.HotDegree, &h.FlowBytes); err != nil {
+			return err
+		}
+		hot = append(hot, h)
+		return nil
+	})
+	return hot, err
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	// Display time zones are loaded from the embedded database, since
+	// Windows has none and minimal containers often lack one.
+	_ "time/tzdata"
+)
+
+// connectionTimeZone is the session time_zone set on connections whose DSN
+// does not set one; empty leaves the server's default.
+var connectionTimeZone string
+
+// displayTimeZone is the zone execute_query shows TIMESTAMP values in; nil
+// shows them as the server returns them, in the session time zone.
+var displayTimeZone atomic.Pointer[time.Location]
+
+// zoneOffset matches the "+HH:MM" offsets MySQL accepts as time zones.
+var zoneOffset = regexp.MustCompile(`^([+-])(\d{1,2}):(\d{2})$`)
+
+// sessionZone is the time zone a connection pool's sessions use.
+type sessionZone struct {
+	// Name is @@session.time_zone, with SYSTEM followed by the server's
+	// system time zone.
+	Name string
+	loc  *time.Location
+}
+
+// sessionZones caches sessionZone per connection pool. Setting time_zone
+// reopens the pool, so a cached zone never goes stale.
+var sessionZones = struct {
+	sync.Mutex
+	byDB map[*sql.DB]sessionZone
+}{byDB: make(map[*sql.DB]sessionZone)}
+
+// setConnectionTimeZone sets the session time_zone of connections opened
+// with cfg.
+func setConnectionTimeZone(cfg *mysql.Config, zone string) {
+	if cfg.Params == nil {
+		cfg.Params = make(map[string]string)
+	}
+	cfg.Params["time_zone"] = quoteString(zone)
+}
+
+// parseTimeZone loads a display time zone: UTC, Local, an offset such as
+// +05:30, or an IANA name such as Europe/Berlin.
+func parseTimeZone(name string) (*time.Location, error) {
+	if m := zoneOffset.FindStringSubmatch(name); m != nil {
+		hours, _ := strconv.Atoi(m[2])
+		minutes, _ := strconv.Atoi(m[3])
+		if hours > 14 || minutes > 59 {
+			return nil, fmt.Errorf("invalid time zone offset %q", name)
+		}
+		offset := hours*3600 + minutes*60
+		if m[1] == "-" {
+			offset = -offset
+		}
+		return time.FixedZone(name, offset), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// sessionZoneFor returns the time zone of database's sessions, querying it
+// on first use. Zones Go cannot load, such as the abbreviations servers
+// report for SYSTEM, are taken as the current offset from UTC.
+func sessionZoneFor(ctx context.Context, database *sql.DB) (sessionZone, error) {
+	sessionZones.Lock()
+	zone, ok := sessionZones.byDB[database]
+	sessionZones.Unlock()
+	if ok {
+		return zone, nil
+	}
+
+	var session, system string
+	var offset int
+	err := database.QueryRowContext(ctx, "SELECT @@session.time_zone, @@system_time_zone, TIMESTAMPDIFF(SECOND, UTC_TIMESTAMP(), NOW())").Scan(&session, &system, &offset)
+	if err != nil {
+		return sessionZone{}, err
+	}
+	name := session
+	if strings.EqualFold(session, "SYSTEM") {
+		name = system
+		zone.Name = fmt.Sprintf("SYSTEM (%s)", system)
+	} else {
+		zone.Name = session
+	}
+	if zone.loc, err = parseTimeZone(name); err != nil || name == "Local" {
+		zone.loc = time.FixedZone(name, offset)
+	}
+
+	sessionZones.Lock()
+	sessionZones.byDB[database] = zone
+	sessionZones.Unlock()
+	return zone, nil
+}
+
+// convertTimestamp converts a TIMESTAMP value as MySQL renders it from one
+// zone to another, keeping its fractional seconds. Zero dates and values it
+// cannot parse are returned unchanged.
+func convertTimestamp(value string, from, to *time.Location) string {
+	const layout = "2006-01-02 15:04:05"
+	if len(value) < len(layout) {
+		return value
+	}
+	t, err := time.ParseInLocation(layout, value[:len(layout)], from)
+	if err != nil {
+		return value
+	}
+	return t.In(to).Format(layout) + value[len(layout):]
+}
+
+// TimeZoneNote says which time zone a result's temporal columns are in.
+type TimeZoneNote struct {
+	TimestampColumns []string `json:"timestampColumns,omitempty"`
+	// TimestampZone is the zone TIMESTAMP values are shown in.
+	TimestampZone string `json:"timestampZone,omitempty"`
+	// SessionZone is set when TIMESTAMP values were converted from it.
+	SessionZone     string   `json:"sessionZone,omitempty"`
+	DatetimeColumns []string `json:"datetimeColumns,omitempty"`
+}
+
+// Text renders the note as a line of a tool result.
+func (n TimeZoneNote) Text() string {
+	var parts []string
+	switch {
+	case len(n.TimestampColumns) == 0:
+	case n.SessionZone != "":
+		parts = append(parts, fmt.Sprintf("TIMESTAMP columns (%s) are shown in %s, converted from the session time zone %s.", strings.Join(n.TimestampColumns, ", "), n.TimestampZone, n.SessionZone))
+	case n.TimestampZone != "":
+		parts = append(parts, fmt.Sprintf("TIMESTAMP columns (%s) are in the session time zone %s.", strings.Join(n.TimestampColumns, ", "), n.TimestampZone))
+	default:
+		parts = append(parts, fmt.Sprintf("TIMESTAMP columns (%s) are in the session time zone, which could not be read.", strings.Join(n.TimestampColumns, ", ")))
+	}
+	if len(n.DatetimeColumns) > 0 {
+		parts = append(parts, fmt.Sprintf("DATETIME columns (%s) carry no time zone and are shown as stored; values computed by NOW() and similar functions are in the session time zone.", strings.Join(n.DatetimeColumns, ", ")))
+	}
+	return "Time zones: " + strings.Join(parts, " ")
+}
+
+// applyTimeZones converts the TIMESTAMP values of a result read from db to
+// the display time zone, if one is set, and describes the zones its
+// temporal columns are in. ok is false when it has none.
+func applyTimeZones(ctx context.Context, columns, kinds []string, results []map[string]any) (note TimeZoneNote, ok bool) {
+	db := currentDB()
+	for i, c := range columns {
+		switch kinds[i] {
+		case "TIMESTAMP":
+			note.TimestampColumns = append(note.TimestampColumns, c)
+		case "DATETIME":
+			note.DatetimeColumns = append(note.DatetimeColumns, c)
+		}
+	}
+	if len(note.TimestampColumns) == 0 && len(note.DatetimeColumns) == 0 {
+		return note, false
+	}
+	if len(note.TimestampColumns) == 0 {
+		return note, true
+	}
+
+	session, err := sessionZoneFor(ctx, db)
+	if err != nil {
+		slog.Debug("failed to read session time zone", "err", err)
+		return note, true
+	}
+	display := displayTimeZone.Load()
+	if display == nil {
+		note.TimestampZone = session.Name
+		return note, true
+	}
+	note.TimestampZone = display.String()
+	note.SessionZone = session.Name
+	for _, row := range results {
+		for _, c := range note.TimestampColumns {
+			if s, ok := row[c].(string); ok {
+				row[c] = convertTimestamp(s, session.loc, display)
+			}
+		}
+	}
+	return note, true
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// otlpEndpoint is the OTLP/HTTP collector URL traces are exported to, set
+// with -otlp-endpoint. Tracing is also enabled by the standard
+// OTEL_EXPORTER_OTLP_ENDPOINT and OTEL_EXPORTER_OTLP_TRACES_ENDPOINT
+// variables, which configure the exporter when the flag is not given.
+var otlpEndpoint string
+
+// tracing is set once setupTracing has installed an exporting provider.
+var tracing bool
+
+const tracerName = "github.com/josiah-hester/mysql-mcp"
+
+// setupTracing installs an OTLP exporting tracer provider when an endpoint
+// is configured. The returned function flushes pending spans; it is a no-op
+// when tracing is off.
+func setupTracing(ctx context.Context) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+	if otlpEndpoint == "" && os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return noop, nil
+	}
+	if offline {
+		if otlpEndpoint != "" {
+			return nil, fmt.Errorf("-otlp-endpoint cannot be used in offline mode")
+		}
+		slog.Warn("ignoring OTEL_EXPORTER_OTLP_* variables in offline mode; traces are not exported")
+		return noop, nil
+	}
+	var opts []otlptracehttp.Option
+	if otlpEndpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(otlpEndpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", "mysql-mcp-server"),
+		attribute.String("service.version", version),
+	))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	tracing = true
+	return provider.Shutdown, nil
+}
+
+// toolTracingMiddleware wraps every tools/call request in a span. SQL
+// statements the tool runs with the request context become its children.
+func toolTracingMiddleware(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		call, ok := req.(*mcp.CallToolRequest)
+		if method != "tools/call" || !ok {
+			return next(ctx, method, req)
+		}
+		ctx, span := otel.Tracer(tracerName).Start(ctx, "tools/call "+call.Params.Name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("mcp.method.name", method),
+				attribute.String("gen_ai.tool.name", call.Params.Name),
+			))
+		defer span.End()
+		result, err := next(ctx, method, req)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		} else if r, ok := result.(*mcp.CallToolResult); ok && r.IsError {
+			span.SetStatus(codes.Error, toolErrorText(r))
+		}
+		return result, err
+	}
+}
+
+// toolErrorText returns the text of an error result, for span status.
+func toolErrorText(r *mcp.CallToolResult) string {
+	for _, c := range r.Content {
+		if text, ok := c.(*mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return "tool error"
+}
+
+// startStatementSpan starts a span for a SQL statement that began at start.
+// The statement text is recorded with its literals removed.
+func startStatementSpan(ctx context.Context, kind, query string, start time.Time) trace.Span {
+	if !tracing {
+		return trace.SpanFromContext(context.Background())
+	}
+	text := sanitizeStatement(query)
+	operation := strings.ToUpper(firstWord(text))
+	name := operation
+	if name == "" {
+		name = kind
+	}
+	_, span := otel.Tracer(tracerName).Start(ctx, name,
+		trace.WithTimestamp(start),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "mysql"),
+			attribute.String("db.operation.name", operation),
+			attribute.String("db.query.text", text),
+		))
+	return span
+}
+
+func firstWord(s string) string {
+	if fields := strings.Fields(s); len(fields) > 0 {
+		return strings.TrimLeft(fields[0], "(")
+	}
+	return ""
+}
+
+// sanitizeStatement replaces the string and numeric literals in query with
+// ?, drops comments and collapses whitespace, so statement text can be
+// exported without the values it carried.
+func sanitizeStatement(query string) string {
+	var b strings.Builder
+	space := false
+	emit := func(s string) {
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteString(s)
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for end < len(query) {
+				if query[end] == '\\' && c != '`' {
+					end += 2
+					continue
+				}
+				if query[end] == c {
+					if end+1 < len(query) && query[end+1] == c {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end+1, len(query))
+			if c == '`' {
+				emit(query[i:end])
+			} else {
+				// A hex or bit literal prefix (X'..', B'..') or a charset
+				// introducer (_utf8mb4'..') is part of the literal.
+				s := b.String()
+				j := len(s)
+				for j > 0 && isIdentByte(s[j-1]) {
+					j--
+				}
+				if prefix := strings.ToLower(s[j:]); !space && (prefix == "x" || prefix == "b" || prefix == "n" || strings.HasPrefix(prefix, "_")) {
+					b.Reset()
+					b.WriteString(s[:j])
+				}
+				emit("?")
+			}
+			i = end
+		case c == '#' || (c == '-' && strings.HasPrefix(query[i:], "--") && (i+2 == len(query) || query[i+2] == ' ' || query[i+2] == '\t' || query[i+2] == '\n' || query[i+2] == '\r')):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			space = true
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*") && !strings.HasPrefix(query[i:], "/*!"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i
+			} else {
+				end += 4
+			}
+			space = true
+			i += end
+		case c >= '0' && c <= '9' && (i == 0 || !isIdentByte(query[i-1])):
+			end := i + 1
+			for end < len(query) && (isIdentByte(query[end]) || query[end] == '.' ||
+				((query[end] == '+' || query[end] == '-') && (query[end-1] == 'e' || query[end-1] == 'E'))) {
+				end++
+			}
+			emit("?")
+			i = end
+		case isIdentByte(c):
+			end := i + 1
+			for end < len(query) && isIdentByte(query[end]) {
+				end++
+			}
+			emit(query[i:end])
+			i = end
+		default:
+			emit(query[i : i+1])
+			i++
+		}
+	}
+	return b.String()
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// endStatementSpan ends a span from startStatementSpan.
+func endStatementSpan(span trace.Span, kind string, rows int64, err error) {
+	if !span.IsRecording() {
+		return
+	}
+	if kind == "query" {
+		span.SetAttributes(attribute.Int64("db.response.returned_rows", rows))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(attribute.String("error.type", errorType(err)))
+	}
+	span.End()
+}
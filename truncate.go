@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type TruncateTablesParams struct {
+	Database          string   `json:"database"`
+	Tables            []string `json:"tables,omitempty"`
+	DisableForeignKey bool     `json:"disable_foreign_key_checks,omitempty"`
+	Confirm           bool     `json:"confirm,omitempty"`
+}
+
+// foreignKeyEdge is a foreign key from Child to Parent.
+type foreignKeyEdge struct {
+	Constraint     string
+	ChildDatabase  string
+	Child          string
+	ParentDatabase string
+	Parent         string
+}
+
+// truncateStep is one statement of a truncation plan.
+type truncateStep struct {
+	Table     string `json:"table"`
+	Statement string `json:"statement"`
+	Rows      int64  `json:"approxRows"`
+}
+
+func TruncateTables(ctx context.Context, req *mcp.CallToolRequest, args TruncateTablesParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("truncate_tables"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	if systemSchemas[strings.ToLower(args.Database)] {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Refusing to truncate tables in system schema %s", args.Database)},
+			},
+		}, nil, nil
+	}
+
+	approxRows, err := baseTableRows(ctx, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to list tables: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	tables := args.Tables
+	if len(tables) == 0 {
+		tables = sortedKeys(approxRows)
+	}
+	for _, t := range tables {
+		if _, ok := approxRows[t]; !ok {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Table %s.%s does not exist", args.Database, t)},
+				},
+			}, nil, nil
+		}
+	}
+	if len(tables) == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Database %s has no tables", args.Database)},
+			},
+		}, nil, nil
+	}
+
+	edges, err := foreignKeyEdges(ctx, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read foreign keys: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	var steps []truncateStep
+	var warnings []string
+	if args.DisableForeignKey {
+		// With FOREIGN_KEY_CHECKS=0 every table can be truncated in any order.
+		for _, t := range tables {
+			steps = append(steps, truncateStep{Table: t, Statement: "TRUNCATE TABLE " + qualifiedTable(args.Database, t), Rows: approxRows[t]})
+		}
+		warnings = append(warnings, "Foreign key checks are disabled for the session: rows in other tables that reference these tables are left dangling")
+	} else {
+		order, err := truncateOrder(args.Database, tables, edges)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("%v\nUse disable_foreign_key_checks: true to truncate these tables regardless of foreign keys.", err)},
+				},
+			}, nil, nil
+		}
+		for _, t := range order {
+			// InnoDB refuses TRUNCATE on a table referenced by another
+			// table's foreign key, even if the child is empty, so parents are
+			// emptied with DELETE after their children.
+			stmt := "TRUNCATE TABLE " + qualifiedTable(args.Database, t)
+			for _, e := range edges {
+				if e.ParentDatabase != args.Database || e.Parent != t || (e.ChildDatabase == e.ParentDatabase && e.Child == t) {
+					continue
+				}
+				stmt = "DELETE FROM " + qualifiedTable(args.Database, t)
+				if e.ChildDatabase != args.Database || !slices.Contains(tables, e.Child) {
+					warnings = append(warnings, fmt.Sprintf("%s.%s references %s through %s and is not being truncated; the delete may fail or cascade into it",
+						e.ChildDatabase, e.Child, t, e.Constraint))
+				}
+			}
+			steps = append(steps, truncateStep{Table: t, Statement: stmt, Rows: approxRows[t]})
+		}
+	}
+
+	resultText := ""
+	for i, step := range steps {
+		resultText += fmt.Sprintf("%d. %s (~%d rows)\n", i+1, step.Statement, step.Rows)
+	}
+	for _, w := range warnings {
+		resultText += "Warning: " + w + "\n"
+	}
+
+	if !args.Confirm {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Preview: %d tables in %s would be emptied in this order:\n%s\nNothing was changed. Re-run with confirm: true to execute.", len(steps), args.Database, resultText)},
+			},
+		}, map[string]any{
+			"steps":    steps,
+			"warnings": warnings,
+			"executed": false,
+		}, nil
+	}
+
+	// FOREIGN_KEY_CHECKS is a session variable, so every statement must run
+	// on the same connection, which is discarded afterwards rather than
+	// returned to the pool with the checks still off.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to get connection: %v", err)},
+			},
+		}, nil, nil
+	}
+	defer discardConn(conn)
+
+	if args.DisableForeignKey {
+		if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to disable foreign key checks: %v", err)},
+				},
+			}, nil, nil
+		}
+	}
+
+	var total int64
+	for i, step := range steps {
+		res, err := conn.ExecContext(ctx, step.Statement)
+		if err != nil {
+			audit("truncate_tables", step.Statement, 0, err)
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Step %d failed: %s: %v\n%d of %d tables were emptied before the failure.", i+1, step.Statement, err, i, len(steps))},
+				},
+			}, nil, nil
+		}
+		rowsAffected, _ := res.RowsAffected()
+		total += rowsAffected
+		audit("truncate_tables", step.Statement, rowsAffected, nil)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Emptied %d tables in %s:\n%s", len(steps), args.Database, resultText)},
+		},
+	}, map[string]any{
+		"steps":    steps,
+		"warnings": warnings,
+		"executed": true,
+	}, nil
+}
+
+// baseTableRows returns the approximate row count of every base table in
+// database, keyed by table name.
+func baseTableRows(ctx context.Context, database string) (map[string]int64, error) {
+	db := currentDB()
+	rows, err := db.QueryContext(ctx, `
+		SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+	`, database)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]int64)
+	for rows.Next() {
+		var name string
+		var count int64
+		if err := rows.Scan(&name, &count); err != nil {
+			return nil, err
+		}
+		result[name] = count
+	}
+	return result, rows.Err()
+}
+
+// foreignKeyEdges returns every foreign key that starts or ends in database.
+func foreignKeyEdges(ctx context.Context, database string) ([]foreignKeyEdge, error) {
+	db := currentDB()
+	rows, err := db.QueryContext(ctx, `
+		SELECT DISTINCT CONSTRAINT_NAME, TABLE_SCHEMA, TABLE_NAME, REFERENCED_TABLE_SCHEMA, REFERENCED_TABLE_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE REFERENCED_TABLE_NAME IS NOT NULL
+		  AND (TABLE_SCHEMA = ? OR REFERENCED_TABLE_SCHEMA = ?)
+		ORDER BY TABLE_SCHEMA, TABLE_NAME, CONSTRAINT_NAME
+	`, database, database)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var edges []foreignKeyEdge
+	for rows.Next() {
+		var e foreignKeyEdge
+		if err := rows.Scan(&e.Constraint, &e.ChildDatabase, &e.Child, &e.ParentDatabase, &e.Parent); err != nil {
+			return nil, err
+		}
+		edges = append(edges, e)
+	}
+	return edges, rows.Err()
+}
+
+// truncateOrder sorts tables so that every table comes before the tables
+// it references. Self-references are ignored; any other cycle is an error.
+func truncateOrder(database string, tables []string, edges []foreignKeyEdge) ([]string, error) {
+	// pending counts, per table, the not-yet-emptied tables referencing it.
+	pending := make(map[string]int, len(tables))
+	for _, t := range tables {
+		pending[t] = 0
+	}
+	for _, e := range edges {
+		if e.ChildDatabase != database || e.ParentDatabase != database || e.Child == e.Parent {
+			continue
+		}
+		if _, ok := pending[e.Child]; !ok {
+			continue
+		}
+		if _, ok := pending[e.Parent]; ok {
+			pending[e.Parent]++
+		}
+	}
+
+	var order []string
+	for len(pending) > 0 {
+		var ready []string
+		for t, n := range pending {
+			if n == 0 {
+				ready = append(ready, t)
+			}
+		}
+		if len(ready) == 0 {
+			cycle := sortedKeys(pending)
+			return nil, fmt.Errorf("foreign keys form a cycle between: %s", strings.Join(cycle, ", "))
+		}
+		sort.Strings(ready)
+		for _, t := range ready {
+			delete(pending, t)
+			order = append(order, t)
+			for _, e := range edges {
+				if e.ChildDatabase == database && e.ParentDatabase == database && e.Child == t && e.Parent != t {
+					if _, ok := pending[e.Parent]; ok {
+						pending[e.Parent]--
+					}
+				}
+			}
+		}
+	}
+	return order, nil
+}
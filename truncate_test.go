@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTruncateTablesDiscardsConnection(t *testing.T) {
+	tests := []struct {
+		name    string
+		failing bool
+	}{
+		{"all tables emptied", false},
+		{"step failed", true},
+	}
+	for _, tt := range tests {
+		server := &fakeServer{handle: func(ctx context.Context, query string, args []driver.NamedValue) (*fakeResult, error) {
+			switch {
+			case strings.Contains(query, "information_schema.TABLES"):
+				return &fakeResult{columns: []string{"TABLE_NAME", "TABLE_ROWS"}, rows: [][]driver.Value{{"a", int64(1)}, {"b", int64(2)}}}, nil
+			case tt.failing && strings.HasPrefix(query, "TRUNCATE TABLE `shop`.`b`"):
+				return nil, errors.New("lock wait timeout")
+			}
+			return nil, nil
+		}}
+		db := withFakeConnection(t, server)
+
+		res, _, err := TruncateTables(context.Background(), nil, TruncateTablesParams{Database: "shop", DisableForeignKey: true, Confirm: true})
+		if err != nil {
+			t.Fatalf("%s: TruncateTables() error = %v", tt.name, err)
+		}
+		if res.IsError != tt.failing {
+			t.Errorf("%s: result %q (error %v)", tt.name, toolErrorText(res), res.IsError)
+		}
+		if !server.ran("SET FOREIGN_KEY_CHECKS = 0") {
+			t.Errorf("%s: foreign key checks were not disabled; statements %q", tt.name, server.statements())
+		}
+
+		// The connection that ran with the checks off is not handed out again.
+		server.mu.Lock()
+		before := server.connects
+		server.mu.Unlock()
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conn.Close()
+		server.mu.Lock()
+		after := server.connects
+		server.mu.Unlock()
+		if after == before {
+			t.Errorf("%s: the truncate connection was returned to the pool", tt.name)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// confirmThreshold is the number of rows a structured update may touch
+// before it has to be re-run with confirm set.
+var confirmThreshold = 1
+
+type UpdateRowsParams struct {
+	Database string         `json:"database"`
+	Table    string         `json:"table"`
+	Key      map[string]any `json:"key"`
+	Changes  map[string]any `json:"changes"`
+	Confirm  bool           `json:"confirm,omitempty"`
+	// ExpectedCount is the number of matching rows the preview showed,
+	// required with Confirm.
+	ExpectedCount *int64 `json:"expected_count,omitempty"`
+}
+
+func UpdateRows(ctx context.Context, req *mcp.CallToolRequest, args UpdateRowsParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("update_rows"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	if len(args.Key) == 0 || len(args.Changes) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Both key and changes must contain at least one column"},
+			},
+		}, nil, nil
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	if unknown := unknownColumns(tableCols, args.Key, args.Changes); len(unknown) > 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown column(s): %s", strings.Join(unknown, ", "))},
+			},
+		}, nil, nil
+	}
+
+	if args.Confirm && args.ExpectedCount == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "expected_count is required with confirm: true. Run update_rows without confirm first to preview the matching rows."},
+			},
+		}, nil, nil
+	}
+
+	where, whereParams := equalityClause(args.Key)
+
+	changeCols := sortedKeys(args.Changes)
+	assignments := make([]string, len(changeCols))
+	var params []any
+	for i, col := range changeCols {
+		assignments[i] = quoteIdentifier(col) + " = ?"
+		params = append(params, sqlValue(args.Changes[col]))
+	}
+	params = append(params, whereParams...)
+
+	table := qualifiedTable(args.Database, args.Table)
+	updateSQL := fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(assignments, ", "), where)
+
+	// The count, the update and the commit are run again as a whole after
+	// a deadlock, which rolls back the transaction. Errors name the step
+	// that failed.
+	var matched, rowsAffected int64
+	var preview, mismatch bool
+	retries, err := retryTransient(ctx, func() error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Lock the matching rows so the previewed count is the count updated.
+		countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s FOR UPDATE", table, where)
+		if err := tx.QueryRowContext(ctx, countSQL, whereParams...).Scan(&matched); err != nil {
+			return fmt.Errorf("count matching rows: %w", err)
+		}
+		preview = matched > int64(confirmThreshold) && !args.Confirm
+		if matched == 0 || preview {
+			return nil
+		}
+		// The data may have changed since the preview; never update a
+		// different number of rows than the caller confirmed.
+		if mismatch = args.Confirm && matched != *args.ExpectedCount; mismatch {
+			return nil
+		}
+
+		res, err := tx.ExecContext(ctx, updateSQL, params...)
+		if err != nil {
+			audit("update_rows", updateSQL, 0, err)
+			return fmt.Errorf("update rows: %w", err)
+		}
+		rowsAffected, _ = res.RowsAffected()
+
+		if err := tx.Commit(); err != nil {
+			audit("update_rows", updateSQL, 0, err)
+			return fmt.Errorf("commit update: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Failed to " + err.Error() + retryNote(retries)},
+			},
+		}, nil, nil
+	}
+
+	if mismatch {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Update rolled back: %d rows match but expected_count was %d. Preview again before confirming.", matched, *args.ExpectedCount)},
+			},
+		}, nil, nil
+	}
+
+	if matched == 0 {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "No rows match the given key; nothing was updated."},
+			},
+		}, map[string]any{"matchedRows": 0, "rowsAffected": 0}, nil
+	}
+
+	if preview {
+		resultText := fmt.Sprintf("Preview: %d rows match and would be updated (more than the confirmation threshold of %d).\n\n%s\n\nNothing was updated. Re-run with confirm: true and expected_count: %d to apply.",
+			matched, confirmThreshold, updateSQL, matched)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: resultText},
+			},
+		}, map[string]any{
+			"matchedRows":          matched,
+			"requiresConfirmation": true,
+			"sql":                  updateSQL,
+		}, nil
+	}
+
+	audit("update_rows", updateSQL, rowsAffected, nil)
+
+	resultText := fmt.Sprintf("Updated %s.%s\nRows matched: %d\nRows changed: %d", args.Database, args.Table, matched, rowsAffected)
+	structured := map[string]any{
+		"matchedRows":  matched,
+		"rowsAffected": rowsAffected,
+	}
+	if retries > 0 {
+		structured["retries"] = retries
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText + retryNote(retries)},
+		},
+	}, structured, nil
+}
+
+// unknownColumns returns the names used in any of maps that are not columns
+// of the table, sorted.
+func unknownColumns(tableCols []ColumnInfo, maps ...map[string]any) []string {
+	known := make(map[string]bool, len(tableCols))
+	for _, col := range tableCols {
+		known[col.ColumnName] = true
+	}
+	var unknown []string
+	for _, m := range maps {
+		for name := range m {
+			if !known[name] {
+				unknown = append(unknown, name)
+			}
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// equalityClause builds "a = ? AND b IS NULL ..." for the given column
+// values, with columns in sorted order.
+func equalityClause(values map[string]any) (string, []any) {
+	var conds []string
+	var params []any
+	for _, col := range sortedKeys(values) {
+		if values[col] == nil {
+			conds = append(conds, quoteIdentifier(col)+" IS NULL")
+			continue
+		}
+		conds = append(conds, quoteIdentifier(col)+" = ?")
+		params = append(params, sqlValue(values[col]))
+	}
+	return strings.Join(conds, " AND "), params
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
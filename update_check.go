@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// noUpdateCheck turns off the check for a newer release at startup and in
+// -version, set with -no-update-check.
+var noUpdateCheck bool
+
+// updateCheckTimeout bounds the update check, so a slow or unreachable
+// GitHub never delays anything.
+const updateCheckTimeout = 5 * time.Second
+
+// updateNotice holds the newer release found at startup until the client
+// can be told about it. Log notifications are only delivered once the
+// client has set a log level.
+var updateNotice = struct {
+	sync.Mutex
+	available string
+	levelSet  bool
+	sent      bool
+}{}
+
+// newerRelease returns the tag of the latest release on updateChannel if it
+// is newer than this build, or "" if there is none. Builds from source are
+// not checked.
+func newerRelease(ctx context.Context) (string, error) {
+	if commit == "dev" {
+		return "", nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+	defer cancel()
+	release, err := latestRelease(ctx)
+	if err != nil {
+		return "", err
+	}
+	if !versionNewer(release.TagName, version) {
+		return "", nil
+	}
+	return release.TagName, nil
+}
+
+// checkForUpdate looks for a newer release in the background and tells the
+// client about it. It never installs anything.
+func checkForUpdate(server *mcp.Server) {
+	tag, err := newerRelease(context.Background())
+	if err != nil {
+		slog.Debug("update check failed", "err", err)
+		return
+	}
+	if tag == "" {
+		return
+	}
+	slog.Info("a newer release is available", "version", tag, "current", version)
+	updateNotice.Lock()
+	updateNotice.available = tag
+	updateNotice.Unlock()
+	notifyUpdate(server)
+}
+
+// notifyUpdate sends the update notice to the client once, when both the
+// check has found a release and the client accepts log messages.
+func notifyUpdate(server *mcp.Server) {
+	updateNotice.Lock()
+	if updateNotice.available == "" || !updateNotice.levelSet || updateNotice.sent {
+		updateNotice.Unlock()
+		return
+	}
+	updateNotice.sent = true
+	tag := updateNotice.available
+	updateNotice.Unlock()
+
+	for session := range server.Sessions() {
+		session.Log(context.Background(), &mcp.LoggingMessageParams{
+			Level:  "notice",
+			Logger: "update",
+			Data: map[string]any{
+				"message": fmt.Sprintf("mysql-mcp %s is available (running %s); run mysql-mcp-server -update to install it", tag, version),
+				"version": tag,
+				"current": version,
+			},
+		})
+	}
+}
+
+// updateNoticeMiddleware delivers a pending update notice once the client
+// sets its log level.
+func updateNoticeMiddleware(server *mcp.Server) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			result, err := next(ctx, method, req)
+			if method == "logging/setLevel" && err == nil {
+				updateNotice.Lock()
+				updateNotice.levelSet = true
+				updateNotice.Unlock()
+				go notifyUpdate(server)
+			}
+			return result, err
+		}
+	}
+}
+
+// versionNewer reports whether the release tag is a later version than
+// current, comparing major.minor.patch and treating a prerelease as
+// earlier than its release.
+func versionNewer(tag, current string) bool {
+	t, tagPre, ok := parseVersion(tag)
+	if !ok {
+		return false
+	}
+	c, currentPre, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range t {
+		if t[i] != c[i] {
+			return t[i] > c[i]
+		}
+	}
+	switch {
+	case tagPre == currentPre:
+		return false
+	case tagPre == "":
+		return true
+	case currentPre == "":
+		return false
+	}
+	return tagPre > currentPre
+}
+
+func parseVersion(v string) (numbers [3]int, prerelease string, ok bool) {
+	v = strings.TrimPrefix(v, "v")
+	v, prerelease, _ = strings.Cut(v, "-")
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return numbers, "", false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return numbers, "", false
+		}
+		numbers[i] = n
+	}
+	return numbers, prerelease, true
+}
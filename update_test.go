@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// matchServer is a fakeServer on which matched rows match every key or
+// filter: it counts them, returns them as a sample and updates or deletes
+// them all. deadlocks is the number of writes that fail with a deadlock
+// before any succeeds.
+func matchServer(matched int64, deadlocks int) *fakeServer {
+	return &fakeServer{handle: func(ctx context.Context, query string, args []driver.NamedValue) (*fakeResult, error) {
+		switch {
+		case strings.HasPrefix(query, "SELECT COUNT(*)"):
+			return &fakeResult{columns: []string{"COUNT(*)"}, rows: [][]driver.Value{{matched}}}, nil
+		case strings.HasPrefix(query, "SELECT *"):
+			res := &fakeResult{columns: []string{"id"}}
+			for i := range min(matched, deletePreviewSampleSize) {
+				res.rows = append(res.rows, []driver.Value{i + 1})
+			}
+			return res, nil
+		case strings.HasPrefix(query, "UPDATE"), strings.HasPrefix(query, "DELETE"):
+			if deadlocks > 0 {
+				deadlocks--
+				return nil, &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+			}
+			return &fakeResult{affected: matched}, nil
+		}
+		return nil, nil
+	}}
+}
+
+// countStatements returns how many statements starting with prefix server
+// ran.
+func countStatements(server *fakeServer, prefix string) int {
+	n := 0
+	for _, statement := range server.statements() {
+		if strings.HasPrefix(statement, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestUpdateRows(t *testing.T) {
+	withFastRetries(t)
+	count := func(n int64) *int64 { return &n }
+
+	tests := []struct {
+		name          string
+		matched       int64
+		deadlocks     int
+		confirm       bool
+		expectedCount *int64
+
+		wantError   string
+		wantText    string
+		wantUpdates int
+		wantCommit  bool
+	}{
+		{name: "one row", matched: 1, wantText: "Rows changed: 1", wantUpdates: 1, wantCommit: true},
+		{name: "no rows", matched: 0, wantText: "nothing was updated"},
+		{name: "preview", matched: 5, wantText: "Re-run with confirm: true and expected_count: 5"},
+		{name: "confirm without expected_count", matched: 5, confirm: true, wantError: "expected_count is required"},
+		{
+			name: "confirmed", matched: 5, confirm: true, expectedCount: count(5),
+			wantText: "Rows changed: 5", wantUpdates: 1, wantCommit: true,
+		},
+		{
+			name: "count changed since preview", matched: 6, confirm: true, expectedCount: count(5),
+			wantError: "Update rolled back: 6 rows match but expected_count was 5",
+		},
+		{
+			name: "deadlock retried", matched: 1, deadlocks: 1,
+			wantText: "Retried 1 time(s)", wantUpdates: 2, wantCommit: true,
+		},
+		{
+			name: "retry limit", matched: 1, deadlocks: transientRetries + 1,
+			wantError: "Failed to update rows: Error 1213", wantUpdates: transientRetries + 1,
+		},
+	}
+	for _, tt := range tests {
+		server := matchServer(tt.matched, tt.deadlocks)
+		withTableColumns(t, withFakeConnection(t, server), "shop", "items", "id", "qty")
+
+		res, _, err := UpdateRows(context.Background(), nil, UpdateRowsParams{
+			Database: "shop", Table: "items",
+			Key:     map[string]any{"id": float64(1)},
+			Changes: map[string]any{"qty": float64(2)},
+			Confirm: tt.confirm, ExpectedCount: tt.expectedCount,
+		})
+		if err != nil {
+			t.Fatalf("%s: UpdateRows() error = %v", tt.name, err)
+		}
+		text := toolErrorText(res)
+		if res.IsError != (tt.wantError != "") || !strings.Contains(text, tt.wantError+tt.wantText) {
+			t.Errorf("%s: result %q (error %v); want %q", tt.name, text, res.IsError, tt.wantError+tt.wantText)
+		}
+		if updates := countStatements(server, "UPDATE `shop`.`items` SET `qty` = ? WHERE `id` = ?"); updates != tt.wantUpdates {
+			t.Errorf("%s: %d updates run, want %d; statements %q", tt.name, updates, tt.wantUpdates, server.statements())
+		}
+		if server.ran("COMMIT") != tt.wantCommit {
+			t.Errorf("%s: committed %v, want %v; statements %q", tt.name, server.ran("COMMIT"), tt.wantCommit, server.statements())
+		}
+	}
+}
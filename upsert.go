@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type UpsertRowsParams struct {
+	Database      string           `json:"database"`
+	Table         string           `json:"table"`
+	Rows          []map[string]any `json:"rows"`
+	KeyColumns    []string         `json:"key_columns"`
+	UpdateColumns []string         `json:"update_columns,omitempty"`
+	Mode          string           `json:"mode,omitempty"`
+	BatchSize     int              `json:"batch_size,omitempty"`
+	Atomic        bool             `json:"atomic,omitempty"`
+}
+
+func UpsertRows(ctx context.Context, req *mcp.CallToolRequest, args UpsertRowsParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable("upsert_rows"); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	mode := strings.ToLower(args.Mode)
+	if mode == "" {
+		mode = "update"
+	}
+	if mode != "update" && mode != "replace" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unsupported mode %q (use update or replace)", args.Mode)},
+			},
+		}, nil, nil
+	}
+
+	if len(args.KeyColumns) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "key_columns is required"},
+			},
+		}, nil, nil
+	}
+
+	// ON DUPLICATE KEY UPDATE and REPLACE only deduplicate on a PRIMARY or
+	// UNIQUE index, so the key columns must match one exactly.
+	keys, err := uniqueKeys(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read table keys: %v", err)},
+			},
+		}, nil, nil
+	}
+	wanted := slices.Clone(args.KeyColumns)
+	sort.Strings(wanted)
+	matched := ""
+	var available []string
+	for name, cols := range keys {
+		sorted := slices.Clone(cols)
+		sort.Strings(sorted)
+		if slices.Equal(sorted, wanted) {
+			matched = name
+		}
+		available = append(available, fmt.Sprintf("%s (%s)", name, strings.Join(cols, ", ")))
+	}
+	if matched == "" {
+		sort.Strings(available)
+		msg := fmt.Sprintf("key_columns (%s) do not match a PRIMARY or UNIQUE index on %s.%s",
+			strings.Join(args.KeyColumns, ", "), args.Database, args.Table)
+		if len(available) > 0 {
+			msg += "\nUnique indexes: " + strings.Join(available, "; ")
+		}
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: msg},
+			},
+		}, nil, nil
+	}
+
+	opts := rowWriteOptions{
+		Tool:      "upsert_rows",
+		BatchSize: args.BatchSize,
+		Atomic:    args.Atomic,
+		Replace:   mode == "replace",
+		Required:  args.KeyColumns,
+	}
+
+	if mode == "update" {
+		tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: err.Error()},
+				},
+			}, nil, nil
+		}
+		for _, name := range args.UpdateColumns {
+			if !slices.ContainsFunc(tableCols, func(c ColumnInfo) bool { return c.ColumnName == name }) {
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("Unknown update column %q", name)},
+					},
+				}, nil, nil
+			}
+		}
+
+		opts.Suffix = func(columns []string) string {
+			update := args.UpdateColumns
+			if len(update) == 0 {
+				for _, col := range columns {
+					if !slices.Contains(args.KeyColumns, col) {
+						update = append(update, col)
+					}
+				}
+			}
+			if len(update) == 0 {
+				// Only key columns were supplied: keep existing rows as-is.
+				key := quoteIdentifier(args.KeyColumns[0])
+				return "ON DUPLICATE KEY UPDATE " + key + " = " + key
+			}
+			assignments := make([]string, len(update))
+			for i, col := range update {
+				quoted := quoteIdentifier(col)
+				assignments[i] = fmt.Sprintf("%s = VALUES(%s)", quoted, quoted)
+			}
+			return "ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+		}
+	}
+
+	result, err := writeRows(ctx, db, args.Database, args.Table, args.Rows, opts)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to upsert rows: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("Upserted %d of %d rows into %s.%s using %s (mode: %s)\nRows affected: %d",
+		result.Succeeded, result.Attempted, args.Database, args.Table, matched, mode, result.RowsAffected)
+	if mode == "update" {
+		resultText += " (MySQL counts 1 per inserted row, 2 per updated row, 0 per unchanged row)"
+	} else {
+		resultText += " (REPLACE counts the deleted and re-inserted row separately)"
+	}
+
+	return &mcp.CallToolResult{
+		IsError: result.Succeeded == 0 && len(result.Errors) > 0,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText + retryNote(result.Retries) + formatRowErrors(result.Errors)},
+		},
+	}, result, nil
+}
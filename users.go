@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// staticPrivileges are the privilege names accepted by GRANT and REVOKE.
+// MySQL 8 dynamic privileges (BACKUP_ADMIN, ...) are matched by
+// dynamicPrivilegePattern instead.
+var staticPrivileges = []string{
+	"ALL", "ALL PRIVILEGES", "ALTER", "ALTER ROUTINE", "CREATE", "CREATE ROLE",
+	"CREATE ROUTINE", "CREATE TABLESPACE", "CREATE TEMPORARY TABLES",
+	"CREATE USER", "CREATE VIEW", "DELETE", "DROP", "DROP ROLE", "EVENT",
+	"EXECUTE", "FILE", "INDEX", "INSERT", "LOCK TABLES", "PROCESS", "PROXY",
+	"REFERENCES", "RELOAD", "REPLICATION CLIENT", "REPLICATION SLAVE", "SELECT",
+	"SHOW DATABASES", "SHOW VIEW", "SHUTDOWN", "SUPER", "TRIGGER", "UPDATE",
+	"USAGE",
+}
+
+var dynamicPrivilegePattern = regexp.MustCompile(`^[A-Z]+(_[A-Z]+)+$`)
+
+// columnPrivileges may be restricted to individual columns.
+var columnPrivileges = []string{"SELECT", "INSERT", "UPDATE", "REFERENCES"}
+
+type CreateUserParams struct {
+	User        string `json:"user"`
+	Host        string `json:"host,omitempty"`
+	Password    string `json:"password"`
+	IfNotExists bool   `json:"if_not_exists,omitempty"`
+	Confirm     bool   `json:"confirm,omitempty"`
+}
+
+type DropUserParams struct {
+	User    string `json:"user"`
+	Host    string `json:"host,omitempty"`
+	Confirm bool   `json:"confirm,omitempty"`
+}
+
+// PrivilegeParams is shared by grant and revoke. Database and Table default
+// to "*", so an empty spec targets every database.
+type PrivilegeParams struct {
+	User        string   `json:"user"`
+	Host        string   `json:"host,omitempty"`
+	Privileges  []string `json:"privileges"`
+	Database    string   `json:"database,omitempty"`
+	Table       string   `json:"table,omitempty"`
+	Columns     []string `json:"columns,omitempty"`
+	GrantOption bool     `json:"grant_option,omitempty"`
+	Confirm     bool     `json:"confirm,omitempty"`
+}
+
+func CreateUser(ctx context.Context, req *mcp.CallToolRequest, args CreateUserParams) (*mcp.CallToolResult, any, error) {
+	if args.User == "" || args.Password == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "user and password are required"},
+			},
+		}, nil, nil
+	}
+
+	stmt := "CREATE USER "
+	if args.IfNotExists {
+		stmt += "IF NOT EXISTS "
+	}
+	stmt += accountName(args.User, args.Host) + " IDENTIFIED BY "
+
+	// The password must never reach the preview, the audit log or the
+	// result text.
+	return runAccountStatement(ctx, "create_user", stmt+quoteString(args.Password), stmt+"'<redacted>'", args.Confirm)
+}
+
+func DropUser(ctx context.Context, req *mcp.CallToolRequest, args DropUserParams) (*mcp.CallToolResult, any, error) {
+	if args.User == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "user is required"},
+			},
+		}, nil, nil
+	}
+	if strings.EqualFold(args.User, "root") || strings.HasPrefix(args.User, "mysql.") {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Refusing to drop built-in account %s", args.User)},
+			},
+		}, nil, nil
+	}
+
+	stmt := "DROP USER " + accountName(args.User, args.Host)
+	return runAccountStatement(ctx, "drop_user", stmt, stmt, args.Confirm)
+}
+
+func Grant(ctx context.Context, req *mcp.CallToolRequest, args PrivilegeParams) (*mcp.CallToolResult, any, error) {
+	privs, target, err := privilegeClause(args)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	stmt := fmt.Sprintf("GRANT %s ON %s TO %s", privs, target, accountName(args.User, args.Host))
+	if args.GrantOption {
+		stmt += " WITH GRANT OPTION"
+	}
+	return runAccountStatement(ctx, "grant", stmt, stmt, args.Confirm)
+}
+
+func Revoke(ctx context.Context, req *mcp.CallToolRequest, args PrivilegeParams) (*mcp.CallToolResult, any, error) {
+	privs, target, err := privilegeClause(args)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	if args.GrantOption {
+		privs += ", GRANT OPTION"
+	}
+	stmt := fmt.Sprintf("REVOKE %s ON %s FROM %s", privs, target, accountName(args.User, args.Host))
+	return runAccountStatement(ctx, "revoke", stmt, stmt, args.Confirm)
+}
+
+// accountName renders 'user'@'host'. The host defaults to '%'.
+func accountName(user, host string) string {
+	if host == "" {
+		host = "%"
+	}
+	return quoteString(user) + "@" + quoteString(host)
+}
+
+// privilegeClause validates a privilege spec and returns the privilege list
+// and the ON target of a GRANT or REVOKE statement.
+func privilegeClause(args PrivilegeParams) (string, string, error) {
+	if args.User == "" {
+		return "", "", fmt.Errorf("user is required")
+	}
+	if len(args.Privileges) == 0 {
+		return "", "", fmt.Errorf("privileges is required")
+	}
+
+	database := args.Database
+	if database == "" {
+		database = "*"
+	}
+	table := args.Table
+	if table == "" {
+		table = "*"
+	}
+	if database == "*" && table != "*" {
+		return "", "", fmt.Errorf("table requires a database")
+	}
+	if len(args.Columns) > 0 && table == "*" {
+		return "", "", fmt.Errorf("columns require a table")
+	}
+
+	target := "*.*"
+	switch {
+	case table != "*":
+		target = qualifiedTable(database, table)
+	case database != "*":
+		target = quoteIdentifier(database) + ".*"
+	}
+
+	privs := make([]string, len(args.Privileges))
+	for i, p := range args.Privileges {
+		name := strings.Join(strings.Fields(strings.ToUpper(p)), " ")
+		if !slices.Contains(staticPrivileges, name) && !dynamicPrivilegePattern.MatchString(name) {
+			return "", "", fmt.Errorf("unknown privilege %q", p)
+		}
+		if len(args.Columns) > 0 {
+			if !slices.Contains(columnPrivileges, name) {
+				return "", "", fmt.Errorf("privilege %s cannot be limited to columns (only %s)", name, strings.Join(columnPrivileges, ", "))
+			}
+			name += " (" + quoteIdentifierList(args.Columns) + ")"
+		}
+		privs[i] = name
+	}
+	return strings.Join(privs, ", "), target, nil
+}
+
+// runAccountStatement previews an account management statement, or runs and
+// audits it when confirm is set. display is the statement with any secret
+// redacted.
+func runAccountStatement(ctx context.Context, tool, stmt, display string, confirm bool) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if err := checkWritable(tool); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+
+	// vtgate authenticates clients itself and does not pass account
+	// statements on to the shards.
+	if server := serverInfoFor(ctx, db); server.Vitess {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("%s is not supported through Vitess; manage users in vtgate's authentication configuration or, on PlanetScale, as passwords and roles in the dashboard", tool)},
+			},
+		}, nil, nil
+	}
+
+	if !confirm {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Preview:\n%s\n\nNothing was changed. Re-run with confirm: true to execute.", display)},
+			},
+		}, map[string]any{
+			"statement": display,
+			"executed":  false,
+		}, nil
+	}
+
+	_, err := db.ExecContext(ctx, stmt)
+	audit(tool, display, 0, err)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Statement failed: %v\n\n%s", err, display)},
+			},
+		}, nil, nil
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: "Executed: " + display},
+		},
+	}, map[string]any{
+		"statement": display,
+		"executed":  true,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ValidateConstraintsParams struct {
+	Database   string   `json:"database"`
+	Tables     []string `json:"tables,omitempty"`
+	SampleSize int      `json:"sample_size,omitempty"`
+}
+
+// ConstraintViolation reports the rows violating one constraint.
+type ConstraintViolation struct {
+	Table      string           `json:"table"`
+	Constraint string           `json:"constraint"`
+	Kind       string           `json:"kind"`
+	Definition string           `json:"definition"`
+	Violations int64            `json:"violations"`
+	Sample     []map[string]any `json:"sample,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+func ValidateConstraints(ctx context.Context, req *mcp.CallToolRequest, args ValidateConstraintsParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	if args.Database == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "database is required"},
+			},
+		}, nil, nil
+	}
+	sampleSize := args.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultOrphanSample
+	}
+
+	schema, err := loadSchema(ctx, db, args.Database)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to read schema: %v", err)},
+			},
+		}, nil, nil
+	}
+	wanted := make(map[string]bool, len(args.Tables))
+	for _, t := range args.Tables {
+		wanted[t] = true
+	}
+
+	var results []ConstraintViolation
+	resultText := ""
+	checked, violated := 0, 0
+	record := func(v ConstraintViolation, columns []string) {
+		checked++
+		switch {
+		case v.Error != "":
+			resultText += fmt.Sprintf("\n%s.%s (%s): check failed: %s\n", v.Table, v.Constraint, v.Kind, v.Error)
+		case v.Violations > 0:
+			violated++
+			resultText += fmt.Sprintf("\n%s.%s (%s %s): %d violating row(s)\n%s", v.Table, v.Constraint, v.Kind, v.Definition, v.Violations, formatRowTable(columns, v.Sample))
+		}
+		results = append(results, v)
+	}
+
+	for _, t := range schema.Tables {
+		if len(wanted) > 0 && !wanted[t.Name] {
+			continue
+		}
+		// A CHECK constraint is only violated when its condition is false;
+		// NULL passes, so NOT (...) selects exactly the violating rows.
+		for _, c := range t.Checks {
+			v := ConstraintViolation{Table: t.Name, Constraint: c.Name, Kind: "CHECK", Definition: "(" + c.Clause + ")"}
+			from := fmt.Sprintf("FROM %s WHERE NOT (%s)", qualifiedTable(args.Database, t.Name), c.Clause)
+			var columns []string
+			err := db.QueryRowContext(ctx, "SELECT COUNT(*) "+from).Scan(&v.Violations)
+			if err == nil && v.Violations > 0 {
+				rows, qerr := db.QueryContext(ctx, fmt.Sprintf("SELECT * %s LIMIT %d", from, sampleSize))
+				err = qerr
+				if err == nil {
+					columns, v.Sample, err = scanRowMaps(rows)
+					rows.Close()
+				}
+			}
+			if err != nil {
+				v.Error = err.Error()
+			}
+			record(v, columns)
+		}
+	}
+
+	for _, r := range schemaRelationships(schema, false) {
+		if len(wanted) > 0 && !wanted[r.Child] {
+			continue
+		}
+		v := ConstraintViolation{Table: r.Child, Constraint: r.Constraint, Kind: "FOREIGN KEY", Definition: describeRelationship(r)}
+		count, columns, sample, err := orphanedRows(ctx, args.Database, r, sampleSize)
+		v.Violations, v.Sample = count, sample
+		if err != nil {
+			v.Error = err.Error()
+		}
+		record(v, columns)
+	}
+
+	header := fmt.Sprintf("Validated %d constraint(s) in %s: %d violated\n", checked, args.Database, violated)
+	if violated == 0 && checked > 0 {
+		header += "All existing rows satisfy their CHECK and FOREIGN KEY constraints.\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: header + resultText},
+		},
+	}, map[string]any{
+		"checked":     checked,
+		"violated":    violated,
+		"constraints": results,
+	}, nil
+}
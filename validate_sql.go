@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type ValidateSQLParams struct {
+	SQL string `json:"sql"`
+	// Database resolves unqualified table names; the connection's current
+	// database by default.
+	Database string `json:"database,omitempty"`
+}
+
+// SQLProblem is an error found in a statement without running it.
+type SQLProblem struct {
+	// Kind is syntax, reference (an unknown table or column) or another
+	// error PREPARE reported.
+	Kind    string `json:"kind"`
+	Code    uint16 `json:"code,omitempty"`
+	Message string `json:"message"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Near    string `json:"near,omitempty"`
+}
+
+// StatementCheck is validate_sql's verdict on one statement.
+type StatementCheck struct {
+	Statement string       `json:"statement"`
+	Valid     bool         `json:"valid"`
+	Prepared  bool         `json:"prepared"`
+	Tables    []string     `json:"tables,omitempty"`
+	Problems  []SQLProblem `json:"problems,omitempty"`
+}
+
+// syntaxErrorNear matches the position MySQL and MariaDB give in syntax
+// errors.
+var syntaxErrorNear = regexp.MustCompile(`(?s)near '(.*)' at line (\d+)$`)
+
+// locateSyntaxError finds where in stmt the text a syntax error quotes
+// starts, as a 1-based line and column. MySQL quotes the statement from
+// the error to its end, cut to 80 characters.
+func locateSyntaxError(stmt, near string, line int) (int, int) {
+	lines := strings.Split(stmt, "\n")
+	if line < 1 || line > len(lines) {
+		return 0, 0
+	}
+	if near == "" {
+		last := lines[len(lines)-1]
+		return len(lines), len(last) + 1
+	}
+	offset := 0
+	for _, l := range lines[:line-1] {
+		offset += len(l) + 1
+	}
+	if i := strings.Index(stmt[offset:], near); i >= 0 {
+		pos := offset + i
+		before := stmt[:pos]
+		return strings.Count(before, "\n") + 1, pos - strings.LastIndexByte(before, '\n')
+	}
+	return line, 0
+}
+
+// referencedTables lists the tables a statement reads or writes, as
+// database.table with database empty when unqualified: the names after
+// FROM, JOIN, INTO, UPDATE and ALTER or TRUNCATE TABLE, and the other
+// entries of a FROM list. Common table expressions are left out.
+func referencedTables(tokens []sqlToken) [][2]string {
+	ctes := make(map[string]bool)
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i].kind == tokenWord && strings.EqualFold(tokens[i+1].text, "AS") && tokens[i+2].text == "(" {
+			ctes[strings.ToLower(identifierText(tokens[i]))] = true
+		}
+	}
+
+	var tables [][2]string
+	seen := make(map[[2]string]bool)
+	// readTable reads a possibly qualified name at tokens[i] and returns
+	// the index after it. After FROM and JOIN, a name followed by a
+	// parenthesis is a table function such as JSON_TABLE.
+	readTable := func(i int, functions bool) int {
+		if i >= len(tokens) || !isIdentifierToken(tokens[i]) {
+			return i
+		}
+		ref := [2]string{"", identifierText(tokens[i])}
+		i++
+		if i+1 < len(tokens) && tokens[i].text == "." && isIdentifierToken(tokens[i+1]) {
+			ref = [2]string{ref[1], identifierText(tokens[i+1])}
+			i += 2
+		}
+		if functions && i < len(tokens) && tokens[i].text == "(" || ref[0] == "" && (ctes[strings.ToLower(ref[1])] || strings.EqualFold(ref[1], "DUAL")) {
+			return i
+		}
+		if !seen[ref] {
+			seen[ref] = true
+			tables = append(tables, ref)
+		}
+		return i
+	}
+
+	// subquery records, for each open parenthesis, whether it holds a
+	// query; FROM inside a function call, as in EXTRACT(YEAR FROM d),
+	// names no table.
+	var subquery []bool
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch t.text {
+		case "(":
+			subquery = append(subquery, isSubquery(tokens[i+1:]))
+			continue
+		case ")":
+			if len(subquery) > 0 {
+				subquery = subquery[:len(subquery)-1]
+			}
+			continue
+		}
+		if t.kind != tokenWord || len(subquery) > 0 && !subquery[len(subquery)-1] {
+			continue
+		}
+		prev := ""
+		if i > 0 {
+			prev = strings.ToUpper(tokens[i-1].text)
+		}
+		switch strings.ToUpper(t.text) {
+		case "FROM":
+			// Later entries of a FROM list follow commas at this level.
+			for j := readTable(i+1, true); j < len(tokens); {
+				for j < len(tokens) && tokens[j].text != "," && tokens[j].text != "(" && tokens[j].text != ")" && !isClauseWord(tokens[j]) {
+					j++
+				}
+				if j >= len(tokens) || tokens[j].text != "," {
+					break
+				}
+				j = readTable(j+1, true)
+			}
+		case "JOIN", "STRAIGHT_JOIN":
+			readTable(i+1, true)
+		case "INTO":
+			readTable(i+1, false)
+		case "UPDATE":
+			if prev != "KEY" && prev != "FOR" && prev != "ON" {
+				j := i + 1
+				for j < len(tokens) && (strings.EqualFold(tokens[j].text, "LOW_PRIORITY") || strings.EqualFold(tokens[j].text, "IGNORE")) {
+					j++
+				}
+				readTable(j, false)
+			}
+		case "TABLE":
+			if prev == "ALTER" || prev == "TRUNCATE" {
+				readTable(i+1, false)
+			}
+		}
+	}
+	return tables
+}
+
+// isIdentifierToken reports whether t can name a table: a word that is not
+// a keyword or user variable, or a backquoted name.
+func isIdentifierToken(t sqlToken) bool {
+	switch t.kind {
+	case tokenWord:
+		return !strings.HasPrefix(t.text, "@") && !isKeyword(t) && !strings.EqualFold(t.text, "OUTFILE") && !strings.EqualFold(t.text, "DUMPFILE")
+	case tokenQuoted:
+		return strings.HasPrefix(t.text, "`")
+	}
+	return false
+}
+
+// identifierText returns the name an identifier token stands for.
+func identifierText(t sqlToken) string {
+	if t.kind == tokenQuoted && strings.HasPrefix(t.text, "`") {
+		return strings.ReplaceAll(strings.Trim(t.text, "`"), "``", "`")
+	}
+	return t.text
+}
+
+// isClauseWord reports whether t starts a clause that ends a FROM list.
+func isClauseWord(t sqlToken) bool {
+	if t.kind != tokenWord {
+		return false
+	}
+	switch strings.ToUpper(t.text) {
+	case "WHERE", "GROUP", "HAVING", "ORDER", "LIMIT", "UNION", "EXCEPT", "INTERSECT", "WINDOW", "FOR", "LOCK", "INTO", "SET", "JOIN", "LEFT", "RIGHT", "INNER", "CROSS", "NATURAL", "STRAIGHT_JOIN", "ON", "USING":
+		return true
+	}
+	return false
+}
+
+// validateStatement prepares stmt on conn without executing it, and checks
+// the tables it references exist.
+func validateStatement(ctx context.Context, db *sql.DB, conn *sql.Conn, stmt, database string) StatementCheck {
+	check := StatementCheck{Statement: stmt, Valid: true}
+
+	// The statement is passed in a user variable, so it needs no quoting.
+	_, err := conn.ExecContext(ctx, "SET @mcp_validate_sql = ?", stmt)
+	if err == nil {
+		_, err = conn.ExecContext(ctx, "PREPARE mcp_validate FROM @mcp_validate_sql")
+	}
+	if err == nil {
+		check.Prepared = true
+		conn.ExecContext(ctx, "DEALLOCATE PREPARE mcp_validate")
+	}
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case err == nil:
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == 1295:
+		// ER_UNSUPPORTED_PS: the statement cannot be prepared, so only
+		// its references are checked.
+	case errors.As(err, &mysqlErr):
+		problem := SQLProblem{Kind: "error", Code: mysqlErr.Number, Message: mysqlErr.Message}
+		switch mysqlErr.Number {
+		case 1064, 1149:
+			problem.Kind = "syntax"
+			if m := syntaxErrorNear.FindStringSubmatch(mysqlErr.Message); m != nil {
+				line, _ := strconv.Atoi(m[2])
+				problem.Near = m[1]
+				problem.Line, problem.Column = locateSyntaxError(stmt, m[1], line)
+			}
+		case 1054, 1046, 1049, 1146, 1051, 1109, 1305:
+			problem.Kind = "reference"
+		}
+		check.Valid = false
+		check.Problems = append(check.Problems, problem)
+	default:
+		check.Valid = false
+		check.Problems = append(check.Problems, SQLProblem{Kind: "error", Message: err.Error()})
+	}
+
+	for _, ref := range referencedTables(tokenizeSQL(stmt)) {
+		schema := ref[0]
+		if schema == "" {
+			schema = database
+		}
+		name := ref[1]
+		if ref[0] != "" {
+			name = ref[0] + "." + ref[1]
+		}
+		check.Tables = append(check.Tables, name)
+		if schema == "" {
+			continue
+		}
+		cols, err := tableColumns(ctx, db, schema, ref[1])
+		if err != nil || len(cols) > 0 {
+			continue
+		}
+		// PREPARE already names a missing table it resolved.
+		if len(check.Problems) > 0 && check.Problems[0].Code == 1146 && strings.Contains(check.Problems[0].Message, ref[1]) {
+			continue
+		}
+		message := fmt.Sprintf("Table %s.%s does not exist", schema, ref[1])
+		if check.Prepared {
+			message += " in information_schema (a temporary table, or a table the statement creates?)"
+		} else {
+			check.Valid = false
+		}
+		check.Problems = append(check.Problems, SQLProblem{Kind: "reference", Message: message})
+	}
+	return check
+}
+
+// formatStatementCheck renders a check for the text result, with a caret
+// under the position of a syntax error.
+func formatStatementCheck(n int, check StatementCheck) string {
+	var b strings.Builder
+	switch {
+	case check.Valid && check.Prepared && len(check.Problems) == 0:
+		fmt.Fprintf(&b, "Statement %d: valid (prepared by the server, not executed).\n", n)
+	case check.Valid && len(check.Problems) == 0:
+		fmt.Fprintf(&b, "Statement %d: cannot be prepared, so its syntax was not checked; the tables it references exist.\n", n)
+	case check.Valid:
+		fmt.Fprintf(&b, "Statement %d: valid, with warnings:\n", n)
+	default:
+		fmt.Fprintf(&b, "Statement %d: invalid.\n", n)
+	}
+	for _, p := range check.Problems {
+		switch {
+		case p.Kind == "syntax" && p.Line > 0:
+			fmt.Fprintf(&b, "  Syntax error at line %d, column %d:\n", p.Line, p.Column)
+			lines := strings.Split(check.Statement, "\n")
+			fmt.Fprintf(&b, "    %s\n", lines[p.Line-1])
+			if p.Column > 0 {
+				fmt.Fprintf(&b, "    %s^\n", strings.Repeat(" ", p.Column-1))
+			}
+			fmt.Fprintf(&b, "  %s\n", p.Message)
+		case p.Code != 0:
+			fmt.Fprintf(&b, "  Error %d: %s\n", p.Code, p.Message)
+		default:
+			fmt.Fprintf(&b, "  %s\n", p.Message)
+		}
+	}
+	return b.String()
+}
+
+func ValidateSQL(ctx context.Context, req *mcp.CallToolRequest, args ValidateSQLParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	statements := splitSQLStatements(args.SQL)
+	if len(statements) == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "No SQL to validate"},
+			},
+		}, nil, nil
+	}
+
+	// PREPARE, the variable holding the statement and the database are
+	// session state, so everything runs on one connection that is not
+	// returned to the pool.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to get connection: %v", err)},
+			},
+		}, nil, nil
+	}
+	defer discardConn(conn)
+	database := args.Database
+	if database != "" {
+		if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(database)); err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to select database %s: %v", database, err)},
+				},
+			}, nil, nil
+		}
+	} else {
+		var current sql.NullString
+		if err := conn.QueryRowContext(ctx, "SELECT DATABASE()").Scan(&current); err == nil {
+			database = current.String
+		}
+	}
+
+	var checks []StatementCheck
+	var b strings.Builder
+	invalid := 0
+	for i, stmt := range statements {
+		check := validateStatement(ctx, db, conn, stmt, database)
+		if !check.Valid {
+			invalid++
+		}
+		checks = append(checks, check)
+		b.WriteString(formatStatementCheck(i+1, check))
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: b.String()},
+		},
+	}, map[string]any{
+		"valid":      invalid == 0,
+		"invalid":    invalid,
+		"statements": checks,
+	}, nil
+}
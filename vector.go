@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// vectorPreviewSize is how many leading components a vector shows in query
+// results.
+const vectorPreviewSize = 5
+
+// decodeVector reads a VECTOR value.
+func decodeVector(b []byte) ([]float32, bool) {
+	if len(b)%4 != 0 {
+		return nil, false
+	}
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v, true
+}
+
+// vectorText renders a VECTOR value in full in the text form
+// STRING_TO_VECTOR accepts, e.g. "[0.5,-1.25]", for exports.
+func vectorText(b []byte) string {
+	v, ok := decodeVector(b)
+	if !ok {
+		return string(b)
+	}
+	parts := make([]string, len(v))
+	for i, f := range v {
+		parts[i] = strconv.FormatFloat(float64(f), 'g', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
+
+// vectorPreview renders a VECTOR value for query results as its dimension
+// and first few components, e.g. "VECTOR(768) [0.0123, -0.4, ...]"; the
+// full values are rarely useful to read and would swamp the output.
+func vectorPreview(b []byte) string {
+	v, ok := decodeVector(b)
+	if !ok {
+		return string(b)
+	}
+	n := min(len(v), vectorPreviewSize)
+	parts := make([]string, n)
+	for i := range n {
+		parts[i] = strconv.FormatFloat(float64(v[i]), 'g', 4, 32)
+	}
+	preview := strings.Join(parts, ", ")
+	if len(v) > n {
+		preview += fmt.Sprintf(", ... %d more", len(v)-n)
+	}
+	return fmt.Sprintf("VECTOR(%d) [%s]", len(v), preview)
+}
+
+// vectorDimension returns N for a column type reported as "vector(N)", or
+// 0 if the type is not a vector.
+func vectorDimension(dataType string) int {
+	params, ok := strings.CutPrefix(strings.ToLower(dataType), "vector(")
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSuffix(params, ")"))
+	return n
+}
+
+type VectorSearchParams struct {
+	Database    string    `json:"database"`
+	Table       string    `json:"table"`
+	Column      string    `json:"column"`
+	Vector      []float64 `json:"vector"`
+	Metric      string    `json:"metric,omitempty"`
+	Limit       int       `json:"limit,omitempty"`
+	Columns     []string  `json:"columns,omitempty"`
+	Filters     []Filter  `json:"filters,omitempty"`
+	MaxDistance *float64  `json:"max_distance,omitempty"`
+}
+
+func VectorSearch(ctx context.Context, req *mcp.CallToolRequest, args VectorSearchParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+
+	server := serverInfoFor(ctx, db)
+	if !server.supportsVectors() {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("The VECTOR type needs MySQL 9.0+, MariaDB 11.7+ or TiDB 8.4+; the server is %s", server)},
+			},
+		}, nil, nil
+	}
+
+	metric := strings.ToLower(args.Metric)
+	if metric == "" {
+		metric = "cosine"
+	}
+	if metric != "cosine" && metric != "euclidean" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown metric %q (use cosine or euclidean)", args.Metric)},
+			},
+		}, nil, nil
+	}
+	limit := args.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	tableCols, err := catalogTable(ctx, db, args.Database, args.Table)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	i := slices.IndexFunc(tableCols, func(c ColumnInfo) bool { return c.ColumnName == args.Column })
+	if i < 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Column %q does not exist in %s.%s", args.Column, args.Database, args.Table)},
+			},
+		}, nil, nil
+	}
+	dimension := vectorDimension(tableCols[i].DataType)
+	if dimension == 0 {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Column %q is %s, not a VECTOR", args.Column, tableCols[i].DataType)},
+			},
+		}, nil, nil
+	}
+	if len(args.Vector) != dimension {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("The query vector has %d dimensions; %s is VECTOR(%d)", len(args.Vector), args.Column, dimension)},
+			},
+		}, nil, nil
+	}
+
+	// By default every column but the vectors is returned.
+	selected := args.Columns
+	if len(selected) == 0 {
+		for _, c := range tableCols {
+			if vectorDimension(c.DataType) == 0 {
+				selected = append(selected, c.ColumnName)
+			}
+		}
+	}
+	quoted := make([]string, len(selected))
+	for i, name := range selected {
+		if !slices.ContainsFunc(tableCols, func(c ColumnInfo) bool { return c.ColumnName == name }) {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Column %q does not exist in %s.%s", name, args.Database, args.Table)},
+				},
+			}, nil, nil
+		}
+		quoted[i] = quoteIdentifier(name)
+	}
+
+	components := make([]string, len(args.Vector))
+	for i, f := range args.Vector {
+		components[i] = strconv.FormatFloat(f, 'g', -1, 32)
+	}
+	vector := "[" + strings.Join(components, ",") + "]"
+
+	distance := server.vectorDistance(metric, quoteIdentifier(args.Column))
+	query := fmt.Sprintf("SELECT %s, %s AS distance FROM %s", strings.Join(quoted, ", "), distance, qualifiedTable(args.Database, args.Table))
+	params := []any{vector}
+	if len(args.Filters) > 0 {
+		where, filterParams, err := buildWhere(args.Filters, tableCols)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Invalid filters: %v", err)},
+				},
+			}, nil, nil
+		}
+		query += " WHERE " + where
+		params = append(params, filterParams...)
+	}
+	if args.MaxDistance != nil {
+		query += " HAVING distance <= ?"
+		params = append(params, *args.MaxDistance)
+	}
+	query += fmt.Sprintf(" ORDER BY distance LIMIT %d", limit)
+
+	rows, err := db.QueryContext(ctx, query, params...)
+	if err != nil {
+		text := fmt.Sprintf("Vector search failed: %v", err)
+		if !server.MariaDB && !server.TiDB && strings.Contains(err.Error(), "DISTANCE") {
+			text += "\nDISTANCE() is only available in MySQL HeatWave and Enterprise Edition."
+		}
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: text},
+			},
+		}, nil, nil
+	}
+	defer rows.Close()
+	columns, results, err := scanRowMaps(rows)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Vector search failed: %v", err)},
+			},
+		}, nil, nil
+	}
+
+	resultText := fmt.Sprintf("%d nearest rows by %s distance (smaller is closer):\n\n", len(results), metric)
+	resultText += formatRowTable(columns, results)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"rows":     results,
+		"rowCount": len(results),
+		"columns":  columns,
+		"query":    query,
+	}, nil
+}
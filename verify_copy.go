@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const defaultChecksumChunk = 10000
+
+type VerifyCopyParams struct {
+	SourceDatabase   string         `json:"source_database"`
+	SourceTable      string         `json:"source_table"`
+	TargetDatabase   string         `json:"target_database,omitempty"`
+	TargetTable      string         `json:"target_table,omitempty"`
+	SourceConnection string         `json:"source_connection,omitempty"`
+	TargetConnection string         `json:"target_connection,omitempty"`
+	Columns          []string       `json:"columns,omitempty"`
+	IgnoreColumns    []string       `json:"ignore_columns,omitempty"`
+	ChunkSize        int            `json:"chunk_size,omitempty"`
+	After            map[string]any `json:"after,omitempty"`
+}
+
+// ChunkChecksum is the comparison of one primary key range. After is the
+// key the range starts after (nil for the first chunk) and UpTo the last
+// key in it (nil for the open-ended last chunk).
+type ChunkChecksum struct {
+	After          map[string]any `json:"after,omitempty"`
+	UpTo           map[string]any `json:"upTo,omitempty"`
+	SourceRows     int64          `json:"sourceRows"`
+	TargetRows     int64          `json:"targetRows"`
+	SourceChecksum int64          `json:"sourceChecksum"`
+	TargetChecksum int64          `json:"targetChecksum"`
+}
+
+func VerifyCopy(ctx context.Context, req *mcp.CallToolRequest, args VerifyCopyParams) (*mcp.CallToolResult, any, error) {
+	if args.SourceDatabase == "" || args.SourceTable == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "source_database and source_table are required"},
+			},
+		}, nil, nil
+	}
+	targetDatabase, targetTable := args.TargetDatabase, args.TargetTable
+	if targetDatabase == "" {
+		targetDatabase = args.SourceDatabase
+	}
+	if targetTable == "" {
+		targetTable = args.SourceTable
+	}
+
+	sourceConn, err := connectionFor(args.SourceConnection)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: connectionHint(err)},
+			},
+		}, nil, nil
+	}
+	targetConn, err := connectionFor(args.TargetConnection)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: connectionHint(err)},
+			},
+		}, nil, nil
+	}
+	source := dataDiffSide{conn: sourceConn, database: args.SourceDatabase, table: args.SourceTable}
+	target := dataDiffSide{conn: targetConn, database: targetDatabase, table: targetTable}
+
+	pk, columns, notes, err := comparableColumns(ctx, source, target, args.Columns, args.IgnoreColumns)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: err.Error()},
+			},
+		}, nil, nil
+	}
+	var cursor []any
+	if len(args.After) > 0 {
+		for _, col := range pk {
+			v, ok := args.After[col]
+			if !ok {
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("after is missing primary key column %q", col)},
+					},
+				}, nil, nil
+			}
+			cursor = append(cursor, sqlValue(v))
+		}
+	}
+	chunkSize := args.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChecksumChunk
+	}
+
+	var estimate int64
+	sourceConn.QueryRowContext(ctx, "SELECT COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+		args.SourceDatabase, args.SourceTable).Scan(&estimate)
+	token := req.Params.GetProgressToken()
+
+	var mismatched []ChunkChecksum
+	var chunks, sourceRows, targetRows int64
+	for {
+		boundary, err := chunkBoundary(ctx, source, pk, cursor, chunkSize)
+		if err != nil {
+			return verifyFailure(chunks, mismatched, err)
+		}
+		c := ChunkChecksum{}
+		if cursor != nil {
+			c.After = keyMapOf(pk, cursor)
+		}
+		if boundary != nil {
+			c.UpTo = keyMapOf(pk, boundary)
+		}
+		c.SourceRows, c.SourceChecksum, err = rangeChecksum(ctx, source, pk, columns, cursor, boundary)
+		if err != nil {
+			return verifyFailure(chunks, mismatched, fmt.Errorf("source: %v", err))
+		}
+		c.TargetRows, c.TargetChecksum, err = rangeChecksum(ctx, target, pk, columns, cursor, boundary)
+		if err != nil {
+			return verifyFailure(chunks, mismatched, fmt.Errorf("target: %v", err))
+		}
+		chunks++
+		sourceRows += c.SourceRows
+		targetRows += c.TargetRows
+		if c.SourceRows != c.TargetRows || c.SourceChecksum != c.TargetChecksum {
+			mismatched = append(mismatched, c)
+		}
+		if token != nil {
+			req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+				ProgressToken: token,
+				Progress:      float64(sourceRows),
+				Total:         float64(max(estimate, sourceRows)),
+				Message:       fmt.Sprintf("%d chunk(s) checked, %d mismatched", chunks, len(mismatched)),
+			})
+		}
+		if boundary == nil {
+			break
+		}
+		cursor = boundary
+	}
+
+	resultText := fmt.Sprintf("Checksummed %s.%s against %s.%s: %d chunk(s) of up to %d rows, %d source and %d target row(s)\n",
+		describeSchemaLocation(args.SourceConnection, args.SourceDatabase), quoteIdentifier(args.SourceTable),
+		describeSchemaLocation(args.TargetConnection, targetDatabase), quoteIdentifier(targetTable),
+		chunks, chunkSize, sourceRows, targetRows)
+	for _, note := range notes {
+		resultText += "Note: " + note + "\n"
+	}
+	if len(mismatched) == 0 {
+		resultText += "All chunks match.\n"
+	} else {
+		resultText += fmt.Sprintf("%d chunk(s) differ; inspect them with diff_data, passing the chunk's after key:\n", len(mismatched))
+		for _, c := range mismatched {
+			resultText += fmt.Sprintf("  %s: %d vs %d rows, checksum %d vs %d\n", describeChunk(pk, c), c.SourceRows, c.TargetRows, c.SourceChecksum, c.TargetChecksum)
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"primaryKey": pk,
+		"columns":    columns,
+		"chunks":     chunks,
+		"sourceRows": sourceRows,
+		"targetRows": targetRows,
+		"match":      len(mismatched) == 0,
+		"mismatched": mismatched,
+	}, nil
+}
+
+func verifyFailure(chunks int64, mismatched []ChunkChecksum, err error) (*mcp.CallToolResult, any, error) {
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Verification failed after %d chunk(s) (%d mismatched so far): %v", chunks, len(mismatched), err)},
+		},
+	}, map[string]any{"chunks": chunks, "mismatched": mismatched}, nil
+}
+
+// chunkBoundary returns the key of the chunkSize-th source row after
+// cursor, or nil when fewer rows remain and the chunk is the last one.
+// Chunks are cut on the source only; the target is checksummed over the
+// same key ranges.
+func chunkBoundary(ctx context.Context, side dataDiffSide, pk []string, cursor []any, chunkSize int) ([]any, error) {
+	keyList := quoteIdentifierList(pk)
+	query := fmt.Sprintf("SELECT %s FROM %s", keyList, qualifiedTable(side.database, side.table))
+	if cursor != nil {
+		query += fmt.Sprintf(" WHERE (%s) > (%s)", keyList, strings.TrimSuffix(strings.Repeat("?, ", len(pk)), ", "))
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT 1 OFFSET %d", keyList, chunkSize-1)
+	rows, err := side.conn.QueryContext(ctx, query, cursor...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	_, found, err := scanRowMaps(rows)
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	return rowKey(found[0], pk), nil
+}
+
+// rangeChecksum returns the row count and an order-independent checksum of
+// the rows with keys in (after, upTo]: the XOR of a CRC32 over each row's
+// values, with a NULL marker per column so NULL and an empty string differ.
+func rangeChecksum(ctx context.Context, side dataDiffSide, pk, columns []string, after, upTo []any) (int64, int64, error) {
+	all := append(append([]string{}, pk...), columns...)
+	values := make([]string, len(all))
+	nulls := make([]string, len(all))
+	for i, col := range all {
+		values[i] = quoteIdentifier(col)
+		nulls[i] = fmt.Sprintf("ISNULL(%s)", quoteIdentifier(col))
+	}
+	tuple := "(" + quoteIdentifierList(pk) + ")"
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(pk)), ", ") + ")"
+	var conds []string
+	var params []any
+	if after != nil {
+		conds = append(conds, fmt.Sprintf("%s > %s", tuple, placeholders))
+		params = append(params, after...)
+	}
+	if upTo != nil {
+		conds = append(conds, fmt.Sprintf("%s <= %s", tuple, placeholders))
+		params = append(params, upTo...)
+	}
+	query := fmt.Sprintf("SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(CONCAT_WS('#', %s, CONCAT(%s)))), 0) FROM %s",
+		strings.Join(values, ", "), strings.Join(nulls, ", "), qualifiedTable(side.database, side.table))
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	var count, checksum int64
+	err := side.conn.QueryRowContext(ctx, query, params...).Scan(&count, &checksum)
+	return count, checksum, err
+}
+
+func keyMapOf(pk []string, key []any) map[string]any {
+	m := make(map[string]any, len(pk))
+	for i, col := range pk {
+		m[col] = key[i]
+	}
+	return m
+}
+
+func describeChunk(pk []string, c ChunkChecksum) string {
+	from, to := "from the start", "to the end"
+	if c.After != nil {
+		from = "after " + describeKey(pk, rowKey(c.After, pk))
+	}
+	if c.UpTo != nil {
+		to = "up to " + describeKey(pk, rowKey(c.UpTo, pk))
+	}
+	return from + ", " + to
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// vitessUnsupportedCode prefixes the errors vtgate returns for queries it
+// cannot plan, most often because they would need cross-shard work such as
+// correlated subqueries or aggregates it cannot merge.
+const vitessUnsupportedCode = "VT12001"
+
+// annotateVitessResult adds Vitess advice to an execute_query result: a
+// hint when vtgate refused the query, or a warning when the query was sent
+// to every shard.
+func annotateVitessResult(ctx context.Context, query string, result *mcp.CallToolResult, structured any) {
+	if result == nil || len(result.Content) == 0 {
+		return
+	}
+	text, ok := result.Content[0].(*mcp.TextContent)
+	if !ok {
+		return
+	}
+	if result.IsError {
+		if strings.Contains(text.Text, vitessUnsupportedCode) {
+			text.Text += "\nVitess cannot run this query across shards. Filter on the sharding key so it routes to a single shard, or split it into simpler queries and combine the results."
+		}
+		return
+	}
+
+	keyspaces := scatterKeyspaces(ctx, query)
+	if len(keyspaces) == 0 {
+		return
+	}
+	warning := fmt.Sprintf("Warning: this query scatters across all shards of %s. Filter on the sharding key (see vitess_vschema) so vtgate can route it to one shard.",
+		strings.Join(keyspaces, ", "))
+	text.Text = warning + "\n\n" + text.Text
+	if m, ok := structured.(map[string]any); ok {
+		m["warning"] = warning
+		m["scatterKeyspaces"] = keyspaces
+	}
+}
+
+// scatterKeyspaces asks vtgate for the query plan with VEXPLAIN PLAN
+// (Vitess 16+), which does not run the query, and returns the keyspaces it
+// would scatter across. Only SELECT, UPDATE and DELETE are checked; INSERT
+// always routes by its sharding key.
+func scatterKeyspaces(ctx context.Context, query string) []string {
+	db := currentDB()
+	switch strings.ToUpper(firstWord(sanitizeStatement(query))) {
+	case "SELECT", "UPDATE", "DELETE":
+	default:
+		return nil
+	}
+	var plan string
+	if err := db.QueryRowContext(ctx, "VEXPLAIN PLAN "+query).Scan(&plan); err != nil {
+		slog.Debug("failed to read the Vitess query plan", "err", err)
+		return nil
+	}
+	var tree any
+	if err := json.Unmarshal([]byte(plan), &tree); err != nil {
+		slog.Debug("failed to parse the Vitess query plan", "err", err)
+		return nil
+	}
+	var keyspaces []string
+	var walk func(node any)
+	walk = func(node any) {
+		switch n := node.(type) {
+		case map[string]any:
+			if n["Variant"] == "Scatter" {
+				name := "a sharded keyspace"
+				if keyspace, ok := n["Keyspace"].(map[string]any); ok {
+					if s, ok := keyspace["Name"].(string); ok {
+						name = "keyspace " + s
+					}
+				}
+				if !slices.Contains(keyspaces, name) {
+					keyspaces = append(keyspaces, name)
+				}
+			}
+			for _, child := range n {
+				walk(child)
+			}
+		case []any:
+			for _, child := range n {
+				walk(child)
+			}
+		}
+	}
+	walk(tree)
+	slices.Sort(keyspaces)
+	return keyspaces
+}
+
+type VitessVSchemaParams struct {
+	Keyspace string `json:"keyspace,omitempty"`
+	Table    string `json:"table,omitempty"`
+}
+
+// VitessKeyspace is a keyspace known to vtgate. Sharded is nil when
+// vtgate is too old to report it.
+type VitessKeyspace struct {
+	Name    string `json:"name"`
+	Sharded *bool  `json:"sharded,omitempty"`
+}
+
+// VitessVindex is a vindex of a table. The first one listed is the
+// primary vindex, whose columns are the sharding key.
+type VitessVindex struct {
+	Columns string `json:"columns"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Owner   string `json:"owner,omitempty"`
+}
+
+func VitessVSchema(ctx context.Context, req *mcp.CallToolRequest, args VitessVSchemaParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	if server := serverInfoFor(ctx, db); !server.Vitess {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("vitess_vschema needs a Vitess (vtgate) connection; the server is %s", server)},
+			},
+		}, nil, nil
+	}
+	if args.Table != "" && args.Keyspace == "" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "keyspace is required with table"},
+			},
+		}, nil, nil
+	}
+
+	keyspaces, err := vitessKeyspaces(ctx)
+	if err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to list keyspaces: %v", err)},
+			},
+		}, nil, nil
+	}
+	resultText := fmt.Sprintf("%d keyspaces:\n", len(keyspaces))
+	for _, k := range keyspaces {
+		resultText += "- " + k.Name
+		if k.Sharded != nil && *k.Sharded {
+			resultText += " (sharded)"
+		} else if k.Sharded != nil {
+			resultText += " (unsharded)"
+		}
+		resultText += "\n"
+	}
+	structured := map[string]any{"keyspaces": keyspaces}
+
+	if args.Keyspace != "" {
+		tables, err := vitessTables(ctx, args.Keyspace)
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to list the VSchema tables of %s: %v", args.Keyspace, err)},
+				},
+			}, nil, nil
+		}
+		resultText += fmt.Sprintf("\nKeyspace %s has %d tables in its VSchema: %s\n", args.Keyspace, len(tables), strings.Join(tables, ", "))
+		structured["tables"] = tables
+	}
+
+	if args.Table != "" {
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SHOW VSCHEMA VINDEXES ON %s.%s", quoteIdentifier(args.Keyspace), quoteIdentifier(args.Table)))
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to read the vindexes of %s.%s: %v", args.Keyspace, args.Table, err)},
+				},
+			}, nil, nil
+		}
+		_, results, err := scanRowMaps(rows)
+		rows.Close()
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Failed to read the vindexes of %s.%s: %v", args.Keyspace, args.Table, err)},
+				},
+			}, nil, nil
+		}
+		vindexes := make([]VitessVindex, 0, len(results))
+		for _, row := range results {
+			vindexes = append(vindexes, VitessVindex{
+				Columns: rowText(row, "Columns"),
+				Name:    rowText(row, "Name"),
+				Type:    rowText(row, "Type"),
+				Owner:   rowText(row, "Owner"),
+			})
+		}
+		if len(vindexes) == 0 {
+			resultText += fmt.Sprintf("\n%s.%s has no vindexes; its keyspace is unsharded or the table is a reference table.\n", args.Keyspace, args.Table)
+		} else {
+			resultText += fmt.Sprintf("\nSharding key of %s.%s: %s (primary vindex %s, %s)\n", args.Keyspace, args.Table, vindexes[0].Columns, vindexes[0].Name, vindexes[0].Type)
+			for _, v := range vindexes[1:] {
+				resultText += fmt.Sprintf("Secondary vindex %s (%s) on %s\n", v.Name, v.Type, v.Columns)
+			}
+			resultText += "Queries with an equality or IN condition on the sharding key are routed to the matching shards; others scatter across all shards.\n"
+			structured["shardingKey"] = vindexes[0].Columns
+		}
+		structured["vindexes"] = vindexes
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, structured, nil
+}
+
+// vitessKeyspaces lists keyspaces with SHOW VSCHEMA KEYSPACES (Vitess 16+),
+// falling back to SHOW KEYSPACES, which does not say which are sharded.
+func vitessKeyspaces(ctx context.Context) ([]VitessKeyspace, error) {
+	db := currentDB()
+	keyspaces := []VitessKeyspace{}
+	rows, err := db.QueryContext(ctx, "SHOW VSCHEMA KEYSPACES")
+	if err == nil {
+		_, results, err := scanRowMaps(rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range results {
+			sharded := strings.EqualFold(rowText(row, "Sharded"), "true")
+			keyspaces = append(keyspaces, VitessKeyspace{Name: rowText(row, "Keyspace"), Sharded: &sharded})
+		}
+		return keyspaces, nil
+	}
+
+	rows, err = db.QueryContext(ctx, "SHOW KEYSPACES")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		keyspaces = append(keyspaces, VitessKeyspace{Name: name})
+	}
+	return keyspaces, rows.Err()
+}
+
+// vitessTables lists the tables in a keyspace's VSchema. SHOW VSCHEMA
+// TABLES reads the current keyspace, so it runs on a dedicated connection.
+func vitessTables(ctx context.Context, keyspace string) ([]string, error) {
+	db := currentDB()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer discardConn(conn)
+	if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(keyspace)); err != nil {
+		return nil, err
+	}
+	rows, err := conn.QueryContext(ctx, "SHOW VSCHEMA TABLES")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tables := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		// vtgate lists the dual table in every keyspace.
+		if name != "dual" {
+			tables = append(tables, name)
+		}
+	}
+	return tables, rows.Err()
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	watchMaxDuration = time.Hour
+	// watchChangesKept caps the result changes returned, keeping the latest.
+	watchChangesKept = 50
+)
+
+// WatchChange is a run of a watched query whose result differed from the
+// run before.
+type WatchChange struct {
+	Time     string           `json:"time"`
+	Run      int              `json:"run"`
+	RowCount int              `json:"rowCount"`
+	Rows     []map[string]any `json:"rows"`
+}
+
+type WatchQueryParams struct {
+	Query           string   `json:"query"`
+	IntervalSeconds int      `json:"interval_seconds,omitempty"`
+	DurationSeconds int      `json:"duration_seconds,omitempty"`
+	Until           string   `json:"until,omitempty"`
+	StableRuns      int      `json:"stable_runs,omitempty"`
+	Condition       []Filter `json:"condition,omitempty"`
+}
+
+func WatchQuery(ctx context.Context, req *mcp.CallToolRequest, args WatchQueryParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	if db == nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Not connected to database. Use connect tool first."},
+			},
+		}, nil, nil
+	}
+	query := strings.TrimSpace(args.Query)
+	if err := checkReadQuery(query); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Cannot watch this query: %v", err)},
+			},
+		}, nil, nil
+	}
+	until := strings.ToLower(args.Until)
+	switch until {
+	case "", "change", "stable", "condition":
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Unknown until %q (use change, stable or condition)", args.Until)},
+			},
+		}, nil, nil
+	}
+	if len(args.Condition) > 0 {
+		until = "condition"
+	} else if until == "condition" {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "until: condition needs condition filters"},
+			},
+		}, nil, nil
+	}
+	interval := time.Duration(args.IntervalSeconds) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	duration := time.Duration(args.DurationSeconds) * time.Second
+	if duration <= 0 {
+		duration = time.Minute
+	}
+	duration = min(duration, watchMaxDuration)
+	stableRuns := args.StableRuns
+	if stableRuns <= 0 {
+		stableRuns = 3
+	}
+
+	if blocked := enforceCostPolicy(ctx, req, "watch_query", db, query); blocked != nil {
+		return blocked, nil, nil
+	}
+
+	token := req.Params.GetProgressToken()
+	deadline := time.Now().Add(duration)
+	var changes []WatchChange
+	var columns []string
+	var results []map[string]any
+	var previous string
+	unchanged, run := 0, 0
+	met := false
+	for {
+		run++
+		rows, done, err := queryReadOnly(ctx, db, query)
+		if err == nil {
+			columns, results, err = scanRowMaps(rows)
+			done()
+		}
+		if err != nil {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: fmt.Sprintf("Run %d failed: %v", run, err)},
+				},
+			}, nil, nil
+		}
+		if run == 1 && until == "condition" {
+			// The columns are known only once the query has run.
+			cols := make([]ColumnInfo, len(columns))
+			for i, c := range columns {
+				cols[i].ColumnName = c
+			}
+			if _, _, err := buildWhere(args.Condition, cols); err != nil {
+				return &mcp.CallToolResult{
+					IsError: true,
+					Content: []mcp.Content{
+						&mcp.TextContent{Text: fmt.Sprintf("Invalid condition: %v", err)},
+					},
+				}, nil, nil
+			}
+		}
+
+		// Rows are compared as JSON, which orders map keys.
+		encoded, _ := json.Marshal(results)
+		current := string(encoded)
+		if run == 1 || current != previous {
+			unchanged = 0
+			changes = append(changes, WatchChange{Time: time.Now().UTC().Format(time.RFC3339), Run: run, RowCount: len(results), Rows: results})
+			if len(changes) > watchChangesKept {
+				changes = changes[1:]
+			}
+			if run > 1 && token != nil {
+				req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+					ProgressToken: token,
+					Progress:      float64(run),
+					Message:       fmt.Sprintf("run %d: result changed (%d rows): %s", run, len(results), watchSummary(columns, results)),
+				})
+			}
+		} else {
+			unchanged++
+		}
+		previous = current
+
+		switch until {
+		case "change":
+			met = run > 1 && unchanged == 0
+		case "stable":
+			met = unchanged >= stableRuns
+		case "condition":
+			met = slices.ContainsFunc(results, func(row map[string]any) bool {
+				for _, f := range args.Condition {
+					if !filterMatches(f, row) {
+						return false
+					}
+				}
+				return true
+			})
+		}
+		if met || time.Now().Add(interval).After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	var resultText string
+	switch {
+	case met && until == "change":
+		resultText = fmt.Sprintf("The result changed on run %d.\n", run)
+	case met && until == "stable":
+		resultText = fmt.Sprintf("The result has not changed for %d runs (%s), as of run %d.\n", unchanged, time.Duration(unchanged)*interval, run)
+	case met:
+		resultText = fmt.Sprintf("The condition became true on run %d.\n", run)
+	case ctx.Err() != nil:
+		resultText = fmt.Sprintf("Watch cancelled after %d runs.\n", run)
+	case until != "":
+		resultText = fmt.Sprintf("Stopped after %d runs over %s without the %s being reached.\n", run, duration, map[string]string{"change": "result changing", "stable": "result settling", "condition": "condition"}[until])
+	default:
+		resultText = fmt.Sprintf("Watched for %s (%d runs).\n", duration, run)
+	}
+	resultText += fmt.Sprintf("The result changed %d times", len(changes)-1)
+	if len(changes) > 1 {
+		resultText += ":\n"
+		for _, c := range changes {
+			resultText += fmt.Sprintf("  run %d at %s: %s\n", c.Run, c.Time, watchSummary(columns, c.Rows))
+		}
+	} else {
+		resultText += ".\n"
+	}
+	resultText += "\nLatest result:\n" + formatRowTable(columns, results)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"conditionMet": met,
+		"runs":         run,
+		"changes":      changes,
+		"columns":      columns,
+		"rows":         results,
+	}, nil
+}
+
+// watchSummary renders a result compactly for change lists: the value of
+// a single-cell result, otherwise the row count and first row.
+func watchSummary(columns []string, rows []map[string]any) string {
+	switch {
+	case len(rows) == 1 && len(columns) == 1:
+		return rowText(rows[0], columns[0])
+	case len(rows) == 0:
+		return "no rows"
+	}
+	var parts []string
+	for _, c := range columns {
+		parts = append(parts, fmt.Sprintf("%s=%s", c, rowText(rows[0], c)))
+	}
+	return fmt.Sprintf("%d rows, first: %s", len(rows), strings.Join(parts, ", "))
+}
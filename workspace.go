@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// workspaceFile is where bookmarks are kept across sessions, set with
+// -workspace-file.
+var workspaceFile = "workspace.json"
+
+// workspaceProfile names the set of bookmarks this server uses, set with
+// -profile, so that one file can serve several projects or clients.
+var workspaceProfile = "default"
+
+// Bookmark is a table or query saved with a note, so a later session can
+// start from it instead of exploring the schema again.
+type Bookmark struct {
+	Name string `json:"name"`
+	// Kind is table or query.
+	Kind      string    `json:"kind"`
+	Database  string    `json:"database,omitempty"`
+	Table     string    `json:"table,omitempty"`
+	Query     string    `json:"query,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// workspaces holds the bookmarks of every profile in the workspace file,
+// by profile and then by name.
+var workspaces = struct {
+	sync.Mutex
+	profiles map[string]map[string]*Bookmark
+}{profiles: make(map[string]map[string]*Bookmark)}
+
+// loadWorkspace reads the workspace file, if there is one.
+func loadWorkspace() error {
+	data, err := os.ReadFile(workspaceFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var file struct {
+		Profiles map[string][]*Bookmark `json:"profiles"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", workspaceFile, err)
+	}
+
+	workspaces.Lock()
+	defer workspaces.Unlock()
+	for profile, bookmarks := range file.Profiles {
+		byName := make(map[string]*Bookmark, len(bookmarks))
+		for _, b := range bookmarks {
+			byName[b.Name] = b
+		}
+		workspaces.profiles[profile] = byName
+	}
+	return nil
+}
+
+// saveWorkspace writes the bookmarks of every profile to the workspace
+// file. The caller holds the lock.
+func saveWorkspace() error {
+	profiles := make(map[string][]*Bookmark, len(workspaces.profiles))
+	for profile, byName := range workspaces.profiles {
+		if len(byName) == 0 {
+			continue
+		}
+		bookmarks := make([]*Bookmark, 0, len(byName))
+		for _, name := range sortedKeys(byName) {
+			bookmarks = append(bookmarks, byName[name])
+		}
+		profiles[profile] = bookmarks
+	}
+	data, err := json.MarshalIndent(map[string]any{"profiles": profiles}, "", "  ")
+	if err != nil {
+		return err
+	}
+	tempFile := workspaceFile + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tempFile, workspaceFile); err != nil {
+		os.Remove(tempFile)
+		return err
+	}
+	return nil
+}
+
+type BookmarkParams struct {
+	// Name defaults to database.table for table bookmarks.
+	Name     string `json:"name,omitempty"`
+	Database string `json:"database,omitempty"`
+	Table    string `json:"table,omitempty"`
+	Query    string `json:"query,omitempty"`
+	Note     string `json:"note,omitempty"`
+}
+
+func BookmarkObject(ctx context.Context, req *mcp.CallToolRequest, args BookmarkParams) (*mcp.CallToolResult, any, error) {
+	query := strings.TrimSpace(args.Query)
+	var b Bookmark
+	switch {
+	case args.Table != "" && query != "":
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "Bookmark either a table or a query, not both"},
+			},
+		}, nil, nil
+	case args.Table != "":
+		if args.Database == "" {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "database is required to bookmark a table"},
+				},
+			}, nil, nil
+		}
+		b = Bookmark{Name: args.Name, Kind: "table", Database: args.Database, Table: args.Table}
+		if b.Name == "" {
+			b.Name = args.Database + "." + args.Table
+		}
+	case query != "":
+		if args.Name == "" {
+			return &mcp.CallToolResult{
+				IsError: true,
+				Content: []mcp.Content{
+					&mcp.TextContent{Text: "name is required to bookmark a query"},
+				},
+			}, nil, nil
+		}
+		b = Bookmark{Name: args.Name, Kind: "query", Database: args.Database, Query: query}
+	default:
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: "table or query is required"},
+			},
+		}, nil, nil
+	}
+	b.Note = args.Note
+
+	workspaces.Lock()
+	defer workspaces.Unlock()
+	byName := workspaces.profiles[workspaceProfile]
+	if byName == nil {
+		byName = make(map[string]*Bookmark)
+		workspaces.profiles[workspaceProfile] = byName
+	}
+	now := time.Now().UTC()
+	b.CreatedAt, b.UpdatedAt = now, now
+	verb := "Bookmarked"
+	if old, ok := byName[b.Name]; ok {
+		b.CreatedAt = old.CreatedAt
+		// Bookmarking again without a note keeps the one already written.
+		if b.Note == "" {
+			b.Note = old.Note
+		}
+		verb = "Updated bookmark"
+	}
+	byName[b.Name] = &b
+	if err := saveWorkspace(); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to save %s: %v", workspaceFile, err)},
+			},
+		}, nil, nil
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("%s %q in profile %s (%d bookmark(s))", verb, b.Name, workspaceProfile, len(byName))},
+		},
+	}, map[string]any{"bookmark": b}, nil
+}
+
+type RemoveBookmarkParams struct {
+	Name string `json:"name"`
+}
+
+func RemoveBookmark(ctx context.Context, req *mcp.CallToolRequest, args RemoveBookmarkParams) (*mcp.CallToolResult, any, error) {
+	workspaces.Lock()
+	defer workspaces.Unlock()
+	byName := workspaces.profiles[workspaceProfile]
+	if _, ok := byName[args.Name]; !ok {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("No bookmark named %q in profile %s", args.Name, workspaceProfile)},
+			},
+		}, nil, nil
+	}
+	delete(byName, args.Name)
+	if err := saveWorkspace(); err != nil {
+		return &mcp.CallToolResult{
+			IsError: true,
+			Content: []mcp.Content{
+				&mcp.TextContent{Text: fmt.Sprintf("Failed to save %s: %v", workspaceFile, err)},
+			},
+		}, nil, nil
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: fmt.Sprintf("Removed bookmark %q", args.Name)},
+		},
+	}, nil, nil
+}
+
+type WorkspaceParams struct {
+	// Search keeps bookmarks whose name, table, query or note contains it.
+	Search string `json:"search,omitempty"`
+}
+
+// WorkspaceBookmark is a bookmark as workspace lists it, with the current
+// state of a bookmarked table when connected.
+type WorkspaceBookmark struct {
+	Bookmark
+	Exists       *bool  `json:"exists,omitempty"`
+	RowsEstimate *int64 `json:"rowsEstimate,omitempty"`
+}
+
+func Workspace(ctx context.Context, req *mcp.CallToolRequest, args WorkspaceParams) (*mcp.CallToolResult, any, error) {
+	db := currentDB()
+	workspaces.Lock()
+	var bookmarks []Bookmark
+	for _, b := range workspaces.profiles[workspaceProfile] {
+		bookmarks = append(bookmarks, *b)
+	}
+	workspaces.Unlock()
+
+	search := strings.ToLower(args.Search)
+	bookmarks = slices.DeleteFunc(bookmarks, func(b Bookmark) bool {
+		return search != "" && !strings.Contains(strings.ToLower(strings.Join([]string{b.Name, b.Database, b.Table, b.Query, b.Note}, "\n")), search)
+	})
+	// Tables first, then queries, each by name.
+	slices.SortFunc(bookmarks, func(a, b Bookmark) int {
+		if a.Kind != b.Kind {
+			return strings.Compare(b.Kind, a.Kind)
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	resultText := fmt.Sprintf("Workspace profile %s: %d bookmark(s)", workspaceProfile, len(bookmarks))
+	if search != "" {
+		resultText += fmt.Sprintf(" matching %q", args.Search)
+	}
+	resultText += "\n"
+	if len(bookmarks) == 0 {
+		resultText += "Use bookmark to save tables and queries worth coming back to.\n"
+	}
+
+	listed := make([]WorkspaceBookmark, len(bookmarks))
+	for i, b := range bookmarks {
+		listed[i].Bookmark = b
+		if b.Kind == "table" {
+			resultText += fmt.Sprintf("\n[table] %s: %s", b.Name, qualifiedTable(b.Database, b.Table))
+			if db != nil {
+				// The estimate doubles as the existence check.
+				rows, _, _, err := tableRowEstimate(ctx, b.Database, b.Table)
+				exists := err == nil
+				listed[i].Exists = &exists
+				if exists {
+					listed[i].RowsEstimate = &rows
+					resultText += fmt.Sprintf(" (about %d rows)", rows)
+				} else {
+					resultText += " (not found on this connection)"
+				}
+			}
+		} else {
+			resultText += fmt.Sprintf("\n[query] %s", b.Name)
+			if b.Database != "" {
+				resultText += fmt.Sprintf(" in %s", quoteIdentifier(b.Database))
+			}
+			resultText += ":\n  " + strings.ReplaceAll(b.Query, "\n", "\n  ")
+		}
+		if b.Note != "" {
+			resultText += "\n  Note: " + strings.ReplaceAll(b.Note, "\n", "\n  ")
+		}
+		resultText += "\n"
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{Text: resultText},
+		},
+	}, map[string]any{
+		"profile":   workspaceProfile,
+		"bookmarks": listed,
+	}, nil
+}